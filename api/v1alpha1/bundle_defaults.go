@@ -0,0 +1,125 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// defaultGitDirectory is the directory of the manifests of git sources that
+// set neither a directory nor directories.
+const defaultGitDirectory = "./manifests"
+
+// bundleDefaulter defaults the provisioner class of Bundles, and normalizes
+// their sources, so that the unpackers of the provisioners do not each have
+// to.
+type bundleDefaulter struct {
+	provisionerClassDefaulter
+}
+
+var _ admission.CustomDefaulter = bundleDefaulter{}
+
+// Default implements admission.CustomDefaulter.
+func (d bundleDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	if err := d.provisionerClassDefaulter.Default(ctx, obj); err != nil {
+		return err
+	}
+	b, ok := obj.(*Bundle)
+	if !ok {
+		return fmt.Errorf("unexpected object type %T", obj)
+	}
+	defaultSource(&b.Spec.Source)
+	setImageTagWarning(b)
+	return nil
+}
+
+// defaultSource normalizes source: the untagged image refs of image sources
+// get the latest tag that they implicitly refer to, git sources without
+// directories get the ./manifests directory, and the trailing slashes of
+// repository URLs, directories, local paths and path patterns are trimmed.
+func defaultSource(source *BundleSource) {
+	if image := source.Image; image != nil {
+		image.Ref = strings.TrimSpace(image.Ref)
+		if image.Ref != "" && !hasImageTag(image.Ref) {
+			image.Ref += ":latest"
+		}
+		trimPaths(image.Paths)
+	}
+	if git := source.Git; git != nil {
+		git.Repository = trimTrailingSlashes(git.Repository)
+		git.Directory = trimTrailingSlashes(git.Directory)
+		for i := range git.Directories {
+			git.Directories[i] = trimTrailingSlashes(git.Directories[i])
+		}
+		if git.Directory == "" && len(git.Directories) == 0 {
+			git.Directory = defaultGitDirectory
+		}
+		trimPaths(git.Paths)
+	}
+	if local := source.Local; local != nil {
+		local.Path = trimTrailingSlashes(local.Path)
+	}
+	if helm := source.Helm; helm != nil {
+		helm.Repository = trimTrailingSlashes(helm.Repository)
+	}
+}
+
+// setImageTagWarning sets the AnnotationImageTagWarning annotation of b when
+// its image source refers to the latest tag, and removes it otherwise.
+func setImageTagWarning(b *Bundle) {
+	image := b.Spec.Source.Image
+	if image == nil || !strings.HasSuffix(image.Ref, ":latest") || strings.Contains(image.Ref, "@") {
+		delete(b.Annotations, AnnotationImageTagWarning)
+		return
+	}
+	if b.Annotations == nil {
+		b.Annotations = map[string]string{}
+	}
+	b.Annotations[AnnotationImageTagWarning] = fmt.Sprintf("image %q refers to the latest tag, whose content may change without the Bundle changing: refer to a versioned tag or a digest instead", image.Ref)
+}
+
+// hasImageTag returns whether ref has a tag or a digest. The port of a
+// registry host is not a tag.
+func hasImageTag(ref string) bool {
+	return strings.Contains(ref, "@") || strings.LastIndex(ref, ":") > strings.LastIndex(ref, "/")
+}
+
+func trimPaths(paths *BundlePaths) {
+	if paths == nil {
+		return
+	}
+	for i := range paths.Include {
+		paths.Include[i] = trimTrailingSlashes(paths.Include[i])
+	}
+	for i := range paths.Exclude {
+		paths.Exclude[i] = trimTrailingSlashes(paths.Exclude[i])
+	}
+}
+
+// trimTrailingSlashes trims the trailing slashes of s, unless it only has
+// slashes.
+func trimTrailingSlashes(s string) string {
+	if trimmed := strings.TrimRight(s, "/"); trimmed != "" {
+		return trimmed
+	}
+	return s
+}
@@ -0,0 +1,82 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestBundleDefaulter(t *testing.T) {
+	for _, tt := range []struct {
+		name            string
+		source          BundleSource
+		annotations     map[string]string
+		wantSource      BundleSource
+		wantAnnotations map[string]string
+	}{
+		{
+			name:       "versioned image",
+			source:     BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: " quay.io/operator-framework/combo:v0.0.1 "}},
+			wantSource: BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/operator-framework/combo:v0.0.1"}},
+		},
+		{
+			name:        "image with a digest",
+			source:      BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "localhost:5000/combo@sha256:8e8b1c6d8ff4b7b1a5a3a2e5bb7d4c2b9f9c1f0e8f5d3b4a2c1d0e9f8a7b6c5d"}},
+			annotations: map[string]string{AnnotationImageTagWarning: "stale"},
+			wantSource:  BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "localhost:5000/combo@sha256:8e8b1c6d8ff4b7b1a5a3a2e5bb7d4c2b9f9c1f0e8f5d3b4a2c1d0e9f8a7b6c5d"}},
+		},
+		{
+			name:       "untagged image",
+			source:     BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "localhost:5000/combo", Paths: &BundlePaths{Include: []string{"crds/"}}}},
+			wantSource: BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "localhost:5000/combo:latest", Paths: &BundlePaths{Include: []string{"crds"}}}},
+			wantAnnotations: map[string]string{
+				AnnotationImageTagWarning: `image "localhost:5000/combo:latest" refers to the latest tag, whose content may change without the Bundle changing: refer to a versioned tag or a digest instead`,
+			},
+		},
+		{
+			name:   "git without a directory",
+			source: BundleSource{Type: SourceTypeGit, Git: &GitSource{Repository: "https://github.com/operator-framework/combo/", Ref: GitRef{Tag: "v0.0.1"}}},
+			wantSource: BundleSource{Type: SourceTypeGit, Git: &GitSource{
+				Repository: "https://github.com/operator-framework/combo",
+				Directory:  "./manifests",
+				Ref:        GitRef{Tag: "v0.0.1"},
+			}},
+		},
+		{
+			name: "git with directories",
+			source: BundleSource{Type: SourceTypeGit, Git: &GitSource{
+				Repository:  "https://github.com/operator-framework/combo",
+				Directories: []string{"crds/", "deploy//"},
+				Ref:         GitRef{Branch: "main"},
+			}},
+			wantSource: BundleSource{Type: SourceTypeGit, Git: &GitSource{
+				Repository:  "https://github.com/operator-framework/combo",
+				Directories: []string{"crds", "deploy"},
+				Ref:         GitRef{Branch: "main"},
+			}},
+		},
+		{
+			name:       "local path and helm repository",
+			source:     BundleSource{Type: SourceTypeLocal, Local: &LocalSource{Path: "my-bundle/"}, Helm: &HelmSource{Repository: "https://charts.example.com/"}},
+			wantSource: BundleSource{Type: SourceTypeLocal, Local: &LocalSource{Path: "my-bundle"}, Helm: &HelmSource{Repository: "https://charts.example.com"}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			d := bundleDefaulter{provisionerClassDefaulter{client: fake.NewClientBuilder().WithScheme(testScheme()).Build()}}
+			b := &Bundle{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: tt.annotations},
+				Spec:       BundleSpec{ProvisionerClassName: "core.rukpak.io/plain", Source: tt.source},
+			}
+			require.NoError(t, d.Default(context.Background(), b))
+			require.Equal(t, tt.wantSource, b.Spec.Source)
+			if len(tt.wantAnnotations) == 0 {
+				require.Empty(t, b.Annotations)
+			} else {
+				require.Equal(t, tt.wantAnnotations, b.Annotations)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,13 +26,41 @@ type BundleConditionType string
 const (
 	SourceTypeImage = "image"
 	SourceTypeGit   = "git"
+	SourceTypeLocal = "local"
+	// SourceTypeUpload is the source type of Bundles whose content is
+	// uploaded to the upload endpoint of the rukpak core.
+	SourceTypeUpload = "upload"
+	// SourceTypeHelm is the source type of Bundles whose content is a chart
+	// of a Helm chart repository.
+	SourceTypeHelm = "helm"
+
+	// AnnotationUploadedDigest is set on a Bundle with an upload source by the
+	// rukpak core once its content has been uploaded, to the digest of the
+	// uploaded tarball.
+	AnnotationUploadedDigest = "core.rukpak.io/uploaded-digest"
+	// AnnotationImageTagWarning is set on a Bundle with an image source by the
+	// rukpak core when its image refers to the latest tag, explicitly or by
+	// omitting the tag, to a warning that its content may change.
+	AnnotationImageTagWarning = "core.rukpak.io/image-tag-warning"
 
 	TypeUnpacked = "Unpacked"
 
-	ReasonUnpackPending    = "UnpackPending"
-	ReasonUnpacking        = "Unpacking"
-	ReasonUnpackSuccessful = "UnpackSuccessful"
-	ReasonUnpackFailed     = "UnpackFailed"
+	ReasonUnpackPending          = "UnpackPending"
+	ReasonUnpacking              = "Unpacking"
+	ReasonUnpackSuccessful       = "UnpackSuccessful"
+	ReasonUnpackFailed           = "UnpackFailed"
+	ReasonUnpackRetriesExhausted = "UnpackRetriesExhausted"
+	ReasonUnpackTimedOut         = "UnpackTimedOut"
+	ReasonWaitingForUpload       = "WaitingForUpload"
+
+	ReasonUnpackVerificationFailed    = "UnpackVerificationFailed"
+	ReasonManifestVerificationFailed  = "ManifestVerificationFailed"
+	ReasonSignatureVerificationFailed = "SignatureVerificationFailed"
+	ReasonDigestRequired              = "DigestRequired"
+	ReasonUnsupportedFormat           = "UnsupportedFormat"
+	ReasonBundleTooLarge              = "BundleTooLarge"
+	ReasonInvalidBundleContent        = "InvalidBundleContent"
+	ReasonUnresolvedChartDependencies = "UnresolvedChartDependencies"
 
 	PhasePending   = "Pending"
 	PhaseUnpacking = "Unpacking"
@@ -41,10 +70,35 @@ const (
 
 // BundleSpec defines the desired state of Bundle
 type BundleSpec struct {
-	// ProvisionerClassName sets the name of the provisioner that should reconcile this BundleInstance.
-	ProvisionerClassName string `json:"provisionerClassName"`
+	// ProvisionerClassName sets the name of the provisioner that should reconcile this Bundle.
+	// ProvisionerClassName is optional and if not set defaults to the provisioner of the
+	// default ProvisionerClass.
+	ProvisionerClassName string `json:"provisionerClassName,omitempty"`
 	// Source defines the configuration for the underlying Bundle content.
 	Source BundleSource `json:"source"`
+	// UnpackRetryPolicy configures how failed unpacks of image and git
+	// sources are retried. UnpackRetryPolicy is optional and if not set
+	// failed unpacks are retried indefinitely.
+	UnpackRetryPolicy *UnpackRetryPolicy `json:"unpackRetryPolicy,omitempty"`
+}
+
+// UnpackRetryPolicy limits the retries of failed unpacks. Once a limit is
+// reached, the Bundle stays in the Failing phase with the
+// UnpackRetriesExhausted reason and is not unpacked again.
+type UnpackRetryPolicy struct {
+	// MaxRetries is the number of times a failed unpack is retried.
+	// MaxRetries is optional and if not set failed unpacks are retried until
+	// the deadline.
+	//+kubebuilder:validation:Minimum=0
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+	// Backoff is the delay before the first retry, which doubles with every
+	// subsequent retry up to five minutes, or to Backoff if it is longer.
+	// Backoff defaults to 10s.
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+	// Deadline is the time since the creation of the Bundle after which
+	// failed unpacks are no longer retried. Deadline is optional and if not
+	// set failed unpacks are retried until MaxRetries is reached.
+	Deadline *metav1.Duration `json:"deadline,omitempty"`
 }
 
 type BundleSource struct {
@@ -54,11 +108,122 @@ type BundleSource struct {
 	Image *ImageSource `json:"image,omitempty"`
 	// Git is the git repository that backs the content of this Bundle.
 	Git *GitSource `json:"git,omitempty"`
+	// Local is the ConfigMap or local path that backs the content of this
+	// Bundle.
+	Local *LocalSource `json:"local,omitempty"`
+	// Helm is the chart of a Helm chart repository that backs the content of
+	// this Bundle.
+	Helm *HelmSource `json:"helm,omitempty"`
+	// PollInterval configures how often a mutable image tag, git branch,
+	// chart version or local path is resolved again, so that the Bundle is
+	// unpacked again when it refers to new content. PollInterval is optional and if not set the source is only
+	// resolved when the Bundle is first unpacked. It has no effect on sources
+	// that are pinned to an image digest, git tag or git commit.
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+	// UnpackPodOverrides configure the pod that unpacks an image or git
+	// source, overriding the defaults of the RukpakConfig field by field.
+	// UnpackPodOverrides is optional, and is only applied when the unpack pod
+	// is created.
+	UnpackPodOverrides *UnpackPodOverrides `json:"unpackPodOverrides,omitempty"`
+	// CABundle refers to the certificate authorities that are trusted to
+	// verify the registry of an image source or the repository of a git
+	// source. They are trusted along with the system ones when sources are
+	// resolved, and instead of them when git repositories are cloned.
+	// CABundle is optional, and overrides the CABundle of the RukpakConfig.
+	CABundle *CABundleReference `json:"caBundle,omitempty"`
+	// Timeout is the time after which the pod that unpacks an image or git
+	// source is failed when it has not completed, for example when the image
+	// cannot be pulled or the repository does not respond. Timeout is
+	// optional and if not set the unpack pod runs until it completes or fails.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// DefaultCABundleKey is the key of the certificates of a CABundleReference
+// that does not set one.
+const DefaultCABundleKey = "ca.crt"
+
+// CABundleReference refers to PEM-encoded certificate authorities in a
+// ConfigMap or Secret of the namespace of the provisioner. Exactly one of
+// ConfigMap and Secret must be set.
+type CABundleReference struct {
+	// ConfigMap is the name of the ConfigMap with the certificates.
+	ConfigMap string `json:"configMap,omitempty"`
+	// Secret is the name of the Secret with the certificates.
+	Secret string `json:"secret,omitempty"`
+	// Key is the key of the certificates in the ConfigMap or Secret. Key is
+	// optional and defaults to ca.crt.
+	Key string `json:"key,omitempty"`
+}
+
+// UnpackPodOverrides configure the pod that unpacks the source of a Bundle.
+type UnpackPodOverrides struct {
+	// Resources are the compute resources of the containers of the pod.
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector constrains the pod to the nodes with these labels.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations are the tolerations of the pod.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// SecurityContext is the security context of the pod.
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
 }
 
 type ImageSource struct {
 	// Ref contains the reference to a container image containing Bundle contents.
 	Ref string `json:"ref"`
+	// PullSecret is the name of a Secret of type kubernetes.io/dockerconfigjson,
+	// in the namespace of the provisioner, that is used to pull the image.
+	// PullSecret is optional and if not set the image is pulled anonymously.
+	PullSecret string `json:"pullSecret,omitempty"`
+	// Verification configures the verification of the cosign signatures of
+	// the image. Verification is optional and if not set the image is not
+	// verified.
+	Verification *ImageVerification `json:"verification,omitempty"`
+	// Paths selects the files of the manifests directory of the image that
+	// are manifests of the bundle. Paths is optional and if not set every
+	// file is a manifest.
+	Paths *BundlePaths `json:"paths,omitempty"`
+}
+
+// BundlePaths selects the files of the manifests directory of a source that
+// are manifests of the bundle, so that bundles can be unpacked from sources
+// that mix manifests with other files, such as READMEs, tests or the
+// manifests of other environments.
+//
+// Patterns are globs in the syntax of Go's path.Match, such as "*.yaml" or
+// "overlays/*/kustomization.yaml". A pattern matches a file when it matches
+// the path of the file relative to the manifests directory, or the path of
+// any of its parent directories, so that "docs" matches every file of the
+// docs directory. A pattern without a slash also matches files and
+// directories by their names, at any depth, so that "*.md" matches every
+// markdown file and "tests" every file of a tests directory.
+type BundlePaths struct {
+	// Include are the patterns of the files that are manifests. Include is
+	// optional and if not set every file is included.
+	Include []string `json:"include,omitempty"`
+	// Exclude are the patterns of the included files that are not
+	// manifests. Exclude is optional.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// ImageVerification configures the signers that are trusted to sign a bundle
+// image. An image is verified when it has a valid signature of at least one
+// of the trusted signers.
+type ImageVerification struct {
+	// PublicKeys are the PEM-encoded public keys that are trusted to sign the
+	// image.
+	PublicKeys []string `json:"publicKeys,omitempty"`
+	// Keyless are the identities that are trusted to sign the image with a
+	// short-lived certificate issued by Fulcio.
+	Keyless []KeylessIdentity `json:"keyless,omitempty"`
+}
+
+type KeylessIdentity struct {
+	// Issuer is the URL of the OIDC issuer that authenticated the identity,
+	// for example https://token.actions.githubusercontent.com.
+	Issuer string `json:"issuer"`
+	// Subject is the email address or URI of the identity, as it appears in
+	// the subject alternative names of the signing certificate.
+	Subject string `json:"subject"`
 }
 
 type GitSource struct {
@@ -68,11 +233,61 @@ type GitSource struct {
 	// Directory refers to the location of the bundle within the git repository.
 	// Directory is optional and if not set defaults to ./manifests.
 	Directory string `json:"directory,omitempty"`
+	// Directories refer to several locations of the bundle within the git
+	// repository, such as separate directories for CRDs, RBAC and workloads,
+	// that are merged into one bundle. The manifests of each directory are in
+	// a nested directory of the manifests of the bundle with the same path.
+	// Directories is optional, and cannot be set along with Directory or
+	// Kustomize.
+	Directories []string `json:"directories,omitempty"`
 	// Ref configures the git source to clone a specific branch, tag, or commit
 	// from the specified repo. Ref is required, and exactly one field within Ref
 	// is required. Setting more than one field or zero fields will result in an
 	// error.
 	Ref GitRef `json:"ref"`
+	// Auth configures the credentials used to clone the repository. Auth is
+	// optional and if not set the repository is cloned anonymously.
+	Auth *GitAuth `json:"auth,omitempty"`
+	// Kustomize configures the git source to render the kustomization.yaml in
+	// Directory with kustomize when the bundle is unpacked, so that overlays
+	// can be unpacked directly. The kustomization may refer to bases anywhere
+	// in the repository, but not to remote bases. The rendered objects are the
+	// manifests of the bundle.
+	Kustomize bool `json:"kustomize,omitempty"`
+	// CloneOptions configure how the repository is cloned. CloneOptions is
+	// optional and if not set branches and tags are cloned with a depth of 1,
+	// commits with their full history, and submodules are not cloned.
+	CloneOptions *GitCloneOptions `json:"cloneOptions,omitempty"`
+	// Paths selects the files of Directory that are manifests of the bundle.
+	// Paths is optional and if not set every file is a manifest. It cannot
+	// be used with Kustomize, whose kustomization selects its own files.
+	Paths *BundlePaths `json:"paths,omitempty"`
+}
+
+// GitCloneOptions configure how the repository of a GitSource is cloned, for
+// example to unpack a directory of a large monorepo quickly.
+type GitCloneOptions struct {
+	// Depth is the number of commits of history that are cloned. Depth is
+	// optional and if not set defaults to 1 for branches and tags, and to the
+	// full history for commits.
+	//+kubebuilder:validation:Minimum=1
+	Depth *int32 `json:"depth,omitempty"`
+	// RecurseSubmodules clones the submodules of the repository, recursively,
+	// for repositories that vendor manifests with submodules. Submodules are
+	// cloned with the credentials of the repository.
+	RecurseSubmodules bool `json:"recurseSubmodules,omitempty"`
+	// SparseCheckout only fetches and checks out the files of Directory, or
+	// of Directories. It cannot be used with Kustomize, whose kustomization
+	// may refer to bases outside of Directory.
+	SparseCheckout bool `json:"sparseCheckout,omitempty"`
+}
+
+// GitAuth configures basic authentication to a git repository over HTTPS.
+type GitAuth struct {
+	// SecretName is the name of a Secret, in the namespace of the
+	// provisioner, that contains the username and password used to clone the
+	// repository in its username and password keys.
+	SecretName string `json:"secretName"`
 }
 
 type GitRef struct {
@@ -87,24 +302,106 @@ type GitRef struct {
 	Commit string `json:"commit,omitempty"`
 }
 
+// LocalSource is bundle content that is stored in the cluster itself, for
+// example by rukpakctl run.
+// HelmSource refers to a chart of a Helm chart repository.
+type HelmSource struct {
+	// Repository is the URL of the chart repository, which serves the
+	// index.yaml of its charts.
+	Repository string `json:"repository"`
+	// Chart is the name of the chart in the repository.
+	Chart string `json:"chart"`
+	// Version is the version of the chart, or a semver constraint such as
+	// ~1.2.0 for which the latest matching version is chosen. Version is
+	// optional and if not set the latest stable version is chosen.
+	Version string `json:"version,omitempty"`
+	// Auth configures the credentials used to download the chart. Auth is
+	// optional and if not set the chart is downloaded anonymously.
+	Auth *HelmAuth `json:"auth,omitempty"`
+}
+
+// HelmAuth refers to the credentials of a Helm chart repository.
+type HelmAuth struct {
+	// SecretName is the name of a Secret in the namespace of the provisioner
+	// with the username and password keys of the basic auth credentials of
+	// the repository.
+	SecretName string `json:"secretName"`
+}
+
+// LocalSource is a source of bundle content in the cluster. Exactly one of
+// ConfigMap and Path is required.
+type LocalSource struct {
+	// ConfigMap is the ConfigMap that contains the manifests of the bundle.
+	// Each key of its data and binary data is the name of a file in the
+	// manifests directory of the bundle. The ConfigMap is read when the Bundle
+	// is unpacked, so it should not be changed afterwards.
+	ConfigMap *ConfigMapSource `json:"configMap,omitempty"`
+	// Path is the path of a bundle directory, whose manifests directory has
+	// the manifests of the bundle, relative to the local path root that is
+	// mounted into the provisioner from a hostPath or PersistentVolumeClaim.
+	// Path sources are meant for development clusters, such as kind
+	// clusters, and are only unpacked by provisioners that enable them with
+	// a local path root. The directory is read again at the poll interval of
+	// the source, so that changes to its manifests are unpacked without
+	// pushing images or commits.
+	Path string `json:"path,omitempty"`
+}
+
+type ConfigMapSource struct {
+	// Name is the name of the ConfigMap.
+	Name string `json:"name"`
+	// Namespace is the namespace of the ConfigMap.
+	Namespace string `json:"namespace"`
+}
+
 type ProvisionerID string
 
 // BundleStatus defines the observed state of Bundle
 type BundleStatus struct {
+	// Info describes the unpacked content of the Bundle, and lists the
+	// objects of its manifests. It is unset until the Bundle is unpacked.
 	Info               *BundleInfo        `json:"info,omitempty"`
 	Phase              string             `json:"phase,omitempty"`
 	Digest             string             `json:"digest,omitempty"`
 	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
 	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	// UnpackRetries is the number of times a failed unpack has been retried
+	// under the UnpackRetryPolicy of the Bundle since it was last unpacked.
+	UnpackRetries int32 `json:"unpackRetries,omitempty"`
+	// ResolvedSource is the source of the Bundle pinned to the image digest,
+	// or the commit of a polled git branch, that was unpacked. It is only set
+	// when that revision is known.
+	ResolvedSource *BundleSource `json:"resolvedSource,omitempty"`
+	// ValidationErrors are the errors found in the manifests of the Bundle
+	// when it was last unpacked, by manifest file. They are unset when its
+	// manifests are valid.
+	ValidationErrors []BundleValidationError `json:"validationErrors,omitempty"`
+}
+
+// BundleValidationError is an error found in a manifest file of a Bundle.
+type BundleValidationError struct {
+	// File is the name of the manifest file with the error.
+	File string `json:"file"`
+	// Object identifies the object of the file with the error, such as
+	// "Deployment my-namespace/my-deployment". It is empty for errors that
+	// concern the file as a whole.
+	Object string `json:"object,omitempty"`
+	// Message describes the error.
+	Message string `json:"message"`
 }
 
 type BundleInfo struct {
-	Package string         `json:"package"`
-	Name    string         `json:"name"`
-	Version string         `json:"version"`
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Objects are the objects of the manifests of the Bundle, in the order
+	// of its manifests, which a BundleInstance of the Bundle installs.
 	Objects []BundleObject `json:"objects,omitempty"`
 }
 
+// BundleObject identifies an object of the manifests of a Bundle. Namespace is
+// empty for cluster-scoped objects, and for namespaced objects that are
+// installed in the target namespace of their BundleInstance.
 type BundleObject struct {
 	Group     string `json:"group"`
 	Version   string `json:"version"`
@@ -115,9 +412,11 @@ type BundleObject struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:resource:scope=Cluster
+//+kubebuilder:storageversion
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name=Image,type=string,JSONPath=`.spec.source.image.ref`
 //+kubebuilder:printcolumn:name=Phase,type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name=Ready,type=string,JSONPath=`.status.conditions[?(.type=="Ready")].status`
 //+kubebuilder:printcolumn:name=Age,type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Bundle is the Schema for the bundles API
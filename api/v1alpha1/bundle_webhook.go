@@ -17,12 +17,21 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"path"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
-	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 // Maximum length of bundle name
@@ -31,37 +40,272 @@ const maxNameLength = 40
 // log is for logging in this package.
 var bundlelog = logf.Log.WithName("bundle-resource")
 
-func (r *Bundle) SetupWebhookWithManager(mgr ctrl.Manager) error {
+// SetupWebhookWithManager registers the validating webhook of Bundles, and
+// the mutating webhook that defaults and labels their provisioner class and
+// normalizes their sources, with mgr. The Secrets that Bundle sources reference are looked up in
+// secretNamespace, the namespace in which the provisioners unpack bundles.
+func (r *Bundle) SetupWebhookWithManager(mgr ctrl.Manager, secretNamespace string) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
+		WithDefaulter(bundleDefaulter{provisionerClassDefaulter{client: mgr.GetAPIReader()}}).
+		WithValidator(&bundleValidator{client: mgr.GetAPIReader(), secretNamespace: secretNamespace}).
 		Complete()
 }
 
 //+kubebuilder:webhook:path=/validate-core-rukpak-io-v1alpha1-bundle,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.rukpak.io,resources=bundles,verbs=create;update,versions=v1alpha1,name=core.rukpak.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/mutate-core-rukpak-io-v1alpha1-bundle,mutating=true,failurePolicy=fail,sideEffects=None,groups=core.rukpak.io,resources=bundles,verbs=create;update,versions=v1alpha1,name=mbundle.core.rukpak.io,admissionReviewVersions=v1
 
-var _ webhook.Validator = &Bundle{}
+// bundleValidator validates Bundles, including the Secrets their sources
+// reference, so that a Bundle that cannot be unpacked is rejected up front
+// rather than failing in an unpack pod.
+type bundleValidator struct {
+	client          client.Reader
+	secretNamespace string
+}
+
+var _ admission.CustomValidator = &bundleValidator{}
 
-// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
-func (r *Bundle) ValidateCreate() error {
+// ValidateCreate implements admission.CustomValidator.
+func (v *bundleValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	r := obj.(*Bundle)
 	bundlelog.V(1).Info("validate create", "name", r.Name)
 
-	return checkNameLength(r)
+	if err := checkProvisionerDeclared(ctx, v.client, r.Spec.ProvisionerClassName); err != nil {
+		return err
+	}
+	return v.validate(ctx, r)
 }
 
-// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
-func (r *Bundle) ValidateUpdate(old runtime.Object) error {
+// ValidateUpdate implements admission.CustomValidator.
+func (v *bundleValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	r, old := newObj.(*Bundle), oldObj.(*Bundle)
 	bundlelog.V(1).Info("validate update", "name", r.Name)
 
-	return checkNameLength(r)
+	if err := checkProvisionerClassUnchanged(old.Spec.ProvisionerClassName, r.Spec.ProvisionerClassName); err != nil {
+		return err
+	}
+	// The spec is only validated again when it changes, and not once the
+	// Bundle is being deleted, so that the finalizer and label patches of the
+	// provisioners are not rejected because a Secret of the source was
+	// deleted or a validation rule was added since the Bundle was created.
+	// The old spec is normalized the way the mutating webhook normalizes
+	// the new one.
+	oldSpec := old.Spec.DeepCopy()
+	defaultSource(&oldSpec.Source)
+	if r.DeletionTimestamp != nil || equality.Semantic.DeepEqual(*oldSpec, r.Spec) {
+		return nil
+	}
+	return v.validate(ctx, r)
 }
 
-// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
-func (r *Bundle) ValidateDelete() error {
+// ValidateDelete implements admission.CustomValidator.
+func (v *bundleValidator) ValidateDelete(_ context.Context, obj runtime.Object) error {
+	r := obj.(*Bundle)
 	bundlelog.V(1).Info("validate delete", "name", r.Name)
 
 	return nil
 }
 
+func (v *bundleValidator) validate(ctx context.Context, r *Bundle) error {
+	if err := checkNameLength(r); err != nil {
+		return err
+	}
+	if err := checkSource(r.Spec.Source); err != nil {
+		return err
+	}
+	if err := checkUnpackRetryPolicy(r); err != nil {
+		return err
+	}
+	return v.checkSecrets(ctx, r)
+}
+
+// checkSource checks that the type of source is known, that the field of that
+// type is set, that its poll interval and timeout are positive, that its CA
+// bundle refers to exactly one object, and that git sources with kustomize
+// are not sparse.
+func checkSource(source BundleSource) error {
+	if interval := source.PollInterval; interval != nil && interval.Duration <= 0 {
+		return fmt.Errorf("source poll interval %s must be positive", interval.Duration)
+	}
+	if timeout := source.Timeout; timeout != nil && timeout.Duration <= 0 {
+		return fmt.Errorf("source timeout %s must be positive", timeout.Duration)
+	}
+	if ref := source.CABundle; ref != nil && (ref.ConfigMap == "") == (ref.Secret == "") {
+		return errors.New("source CA bundle must set exactly one of configMap and secret")
+	}
+	var missing bool
+	switch source.Type {
+	case SourceTypeImage:
+		if source.Image == nil {
+			missing = true
+			break
+		}
+		return checkBundlePaths(source.Image.Paths)
+	case SourceTypeGit:
+		if source.Git == nil {
+			missing = true
+			break
+		}
+		if opts := source.Git.CloneOptions; opts != nil && opts.SparseCheckout && source.Git.Kustomize {
+			return errors.New("git source cannot use a sparse checkout with kustomize")
+		}
+		if len(source.Git.Directories) > 0 && source.Git.Directory != "" {
+			return errors.New("git source cannot set both directory and directories")
+		}
+		if len(source.Git.Directories) > 0 && source.Git.Kustomize {
+			return errors.New("git source cannot use directories with kustomize")
+		}
+		if source.Git.Paths != nil && source.Git.Kustomize {
+			return errors.New("git source cannot select paths with kustomize")
+		}
+		if err := checkBundlePaths(source.Git.Paths); err != nil {
+			return err
+		}
+		return checkGitRef(source.Git.Ref)
+	case SourceTypeLocal:
+		if source.Local == nil {
+			missing = true
+			break
+		}
+		return checkLocalSource(*source.Local)
+	case SourceTypeHelm:
+		missing = source.Helm == nil
+	case SourceTypeUpload:
+	default:
+		return fmt.Errorf("unknown source type %q: must be one of %q, %q, %q, %q or %q",
+			source.Type, SourceTypeImage, SourceTypeGit, SourceTypeLocal, SourceTypeHelm, SourceTypeUpload)
+	}
+	if missing {
+		return fmt.Errorf("source of type %q must set the source.%s field", source.Type, source.Type)
+	}
+	return nil
+}
+
+// checkLocalSource checks that source sets exactly one of configMap and path,
+// and that its path is within the local path root.
+func checkLocalSource(source LocalSource) error {
+	if (source.ConfigMap == nil) == (source.Path == "") {
+		return errors.New("local source must set exactly one of configMap and path")
+	}
+	if source.Path == "" {
+		return nil
+	}
+	if clean := path.Clean(source.Path); path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("local source path %q must be relative to the local path root", source.Path)
+	}
+	return nil
+}
+
+// checkBundlePaths checks that the patterns of paths are valid globs.
+func checkBundlePaths(paths *BundlePaths) error {
+	if paths == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, paths.Include...), paths.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("source path pattern %q is not a valid glob", pattern)
+		}
+	}
+	return nil
+}
+
+// checkGitRef checks that exactly one of the fields of ref is set.
+func checkGitRef(ref GitRef) error {
+	set := 0
+	for _, field := range []string{ref.Branch, ref.Tag, ref.Commit} {
+		if field != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("git source ref sets %d of branch, tag and commit: exactly one is required", set)
+	}
+	return nil
+}
+
+// checkProvisionerClassUnchanged checks that an update does not change the
+// provisioner class of a Bundle or BundleInstance, which would hand it over to
+// another provisioner that knows nothing of what the previous one unpacked or
+// installed.
+func checkProvisionerClassUnchanged(oldClass, newClass string) error {
+	if oldClass != newClass {
+		return fmt.Errorf("provisionerClassName is immutable: cannot change it from %q to %q", oldClass, newClass)
+	}
+	return nil
+}
+
+// checkUnpackRetryPolicy checks that the durations of the unpack retry policy
+// of r are positive.
+func checkUnpackRetryPolicy(r *Bundle) error {
+	policy := r.Spec.UnpackRetryPolicy
+	if policy == nil {
+		return nil
+	}
+	if policy.Backoff != nil && policy.Backoff.Duration <= 0 {
+		return fmt.Errorf("unpack retry backoff %s must be positive", policy.Backoff.Duration)
+	}
+	if policy.Deadline != nil && policy.Deadline.Duration <= 0 {
+		return fmt.Errorf("unpack retry deadline %s must be positive", policy.Deadline.Duration)
+	}
+	return nil
+}
+
+// checkSecrets checks that the Secrets referenced by the source of r exist
+// and contain the keys that the unpack pod reads.
+func (v *bundleValidator) checkSecrets(ctx context.Context, r *Bundle) error {
+	if image := r.Spec.Source.Image; image != nil && image.PullSecret != "" {
+		secret, err := v.getSecret(ctx, "image pull secret", image.PullSecret)
+		if err != nil {
+			return err
+		}
+		if secret.Type != corev1.SecretTypeDockerConfigJson {
+			return fmt.Errorf("image pull secret %q has type %q: expected type %q", image.PullSecret, secret.Type, corev1.SecretTypeDockerConfigJson)
+		}
+		if err := checkSecretKeys("image pull secret", secret, corev1.DockerConfigJsonKey); err != nil {
+			return err
+		}
+	}
+	if git := r.Spec.Source.Git; git != nil && git.Auth != nil {
+		secret, err := v.getSecret(ctx, "git auth secret", git.Auth.SecretName)
+		if err != nil {
+			return err
+		}
+		if err := checkSecretKeys("git auth secret", secret, "username", "password"); err != nil {
+			return err
+		}
+	}
+	if helm := r.Spec.Source.Helm; helm != nil && helm.Auth != nil {
+		secret, err := v.getSecret(ctx, "helm auth secret", helm.Auth.SecretName)
+		if err != nil {
+			return err
+		}
+		if err := checkSecretKeys("helm auth secret", secret, "username", "password"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *bundleValidator) getSecret(ctx context.Context, kind, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := v.client.Get(ctx, types.NamespacedName{Namespace: v.secretNamespace, Name: name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%s %q not found in namespace %q", kind, name, v.secretNamespace)
+		}
+		return nil, fmt.Errorf("get %s %q: %w", kind, name, err)
+	}
+	return secret, nil
+}
+
+func checkSecretKeys(kind string, secret *corev1.Secret, keys ...string) error {
+	for _, key := range keys {
+		if len(secret.Data[key]) == 0 {
+			return fmt.Errorf("%s %q is missing key %q", kind, secret.Name, key)
+		}
+	}
+	return nil
+}
+
 func checkNameLength(r *Bundle) error {
 	if len(r.Name) > maxNameLength {
 		return fmt.Errorf("bundle name %s is too long: maximum allowed name length is %d", r.GetName(), maxNameLength)
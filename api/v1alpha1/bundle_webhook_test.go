@@ -0,0 +1,402 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// testScheme returns a scheme with the types of this package, which are only
+// registered once the package is initialized.
+func testScheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(s))
+	utilruntime.Must(AddToScheme(s))
+	return s
+}
+
+func TestBundleValidatorSecrets(t *testing.T) {
+	secret := func(name string, secretType corev1.SecretType, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: name},
+			Type:       secretType,
+			Data:       data,
+		}
+	}
+	cl := fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(
+		secret("pull", corev1.SecretTypeDockerConfigJson, map[string][]byte{corev1.DockerConfigJsonKey: []byte("{}")}),
+		secret("opaque", corev1.SecretTypeOpaque, map[string][]byte{corev1.DockerConfigJsonKey: []byte("{}")}),
+		secret("git", corev1.SecretTypeOpaque, map[string][]byte{"username": []byte("u"), "password": []byte("p")}),
+		secret("git-no-password", corev1.SecretTypeOpaque, map[string][]byte{"username": []byte("u")}),
+	).Build()
+	v := &bundleValidator{client: cl, secretNamespace: "rukpak-system"}
+
+	for _, tt := range []struct {
+		name    string
+		source  BundleSource
+		wantErr string
+	}{
+		{
+			name:   "no secrets",
+			source: BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c"}},
+		},
+		{
+			name:   "pull secret",
+			source: BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c", PullSecret: "pull"}},
+		},
+		{
+			name:    "missing pull secret",
+			source:  BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c", PullSecret: "missing"}},
+			wantErr: `image pull secret "missing" not found in namespace "rukpak-system"`,
+		},
+		{
+			name:    "pull secret of wrong type",
+			source:  BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c", PullSecret: "opaque"}},
+			wantErr: `image pull secret "opaque" has type "Opaque": expected type "kubernetes.io/dockerconfigjson"`,
+		},
+		{
+			name:   "git auth",
+			source: BundleSource{Type: SourceTypeGit, Git: &GitSource{Repository: "https://example.com/a.git", Ref: GitRef{Branch: "main"}, Auth: &GitAuth{SecretName: "git"}}},
+		},
+		{
+			name:    "git auth missing key",
+			source:  BundleSource{Type: SourceTypeGit, Git: &GitSource{Repository: "https://example.com/a.git", Ref: GitRef{Branch: "main"}, Auth: &GitAuth{SecretName: "git-no-password"}}},
+			wantErr: `git auth secret "git-no-password" is missing key "password"`,
+		},
+		{
+			name:   "helm auth",
+			source: BundleSource{Type: SourceTypeHelm, Helm: &HelmSource{Repository: "https://charts.example.com", Chart: "a", Auth: &HelmAuth{SecretName: "git"}}},
+		},
+		{
+			name:    "missing helm auth secret",
+			source:  BundleSource{Type: SourceTypeHelm, Helm: &HelmSource{Repository: "https://charts.example.com", Chart: "a", Auth: &HelmAuth{SecretName: "missing"}}},
+			wantErr: `helm auth secret "missing" not found in namespace "rukpak-system"`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bundle{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: BundleSpec{ProvisionerClassName: "core.rukpak.io/plain", Source: tt.source}}
+			err := v.ValidateCreate(context.Background(), b)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBundleValidatorPollInterval(t *testing.T) {
+	v := &bundleValidator{client: fake.NewClientBuilder().WithScheme(testScheme()).Build(), secretNamespace: "rukpak-system"}
+	for _, tt := range []struct {
+		name     string
+		interval *metav1.Duration
+		wantErr  string
+	}{
+		{
+			name: "no poll interval",
+		},
+		{
+			name:     "valid poll interval",
+			interval: &metav1.Duration{Duration: 5 * time.Minute},
+		},
+		{
+			name:     "zero poll interval",
+			interval: &metav1.Duration{},
+			wantErr:  "source poll interval 0s must be positive",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bundle{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: BundleSpec{
+				ProvisionerClassName: "core.rukpak.io/plain",
+				Source:               BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c"}, PollInterval: tt.interval},
+			}}
+			err := v.ValidateCreate(context.Background(), b)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBundleValidatorTimeout(t *testing.T) {
+	v := &bundleValidator{client: fake.NewClientBuilder().WithScheme(testScheme()).Build(), secretNamespace: "rukpak-system"}
+	for _, tt := range []struct {
+		name    string
+		timeout *metav1.Duration
+		wantErr string
+	}{
+		{
+			name: "no timeout",
+		},
+		{
+			name:    "valid timeout",
+			timeout: &metav1.Duration{Duration: 10 * time.Minute},
+		},
+		{
+			name:    "negative timeout",
+			timeout: &metav1.Duration{Duration: -time.Minute},
+			wantErr: "source timeout -1m0s must be positive",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bundle{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: BundleSpec{
+				ProvisionerClassName: "core.rukpak.io/plain",
+				Source:               BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c"}, Timeout: tt.timeout},
+			}}
+			err := v.ValidateCreate(context.Background(), b)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBundleValidatorSourcePaths(t *testing.T) {
+	v := &bundleValidator{client: fake.NewClientBuilder().WithScheme(testScheme()).Build(), secretNamespace: "rukpak-system"}
+	for _, tt := range []struct {
+		name    string
+		source  BundleSource
+		wantErr string
+	}{
+		{
+			name:   "valid image paths",
+			source: BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c", Paths: &BundlePaths{Include: []string{"*.yaml"}, Exclude: []string{"tests"}}}},
+		},
+		{
+			name:    "invalid pattern",
+			source:  BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c", Paths: &BundlePaths{Exclude: []string{"[a-"}}}},
+			wantErr: `source path pattern "[a-" is not a valid glob`,
+		},
+		{
+			name: "git directories with a directory",
+			source: BundleSource{Type: SourceTypeGit, Git: &GitSource{
+				Repository: "https://github.com/a/b", Ref: GitRef{Branch: "main"}, Directory: "./deploy", Directories: []string{"./crds"},
+			}},
+			wantErr: "git source cannot set both directory and directories",
+		},
+		{
+			name: "git directories with kustomize",
+			source: BundleSource{Type: SourceTypeGit, Git: &GitSource{
+				Repository: "https://github.com/a/b", Ref: GitRef{Branch: "main"}, Kustomize: true, Directories: []string{"./crds"},
+			}},
+			wantErr: "git source cannot use directories with kustomize",
+		},
+		{
+			name: "git paths with kustomize",
+			source: BundleSource{Type: SourceTypeGit, Git: &GitSource{
+				Repository: "https://github.com/a/b", Ref: GitRef{Branch: "main"}, Kustomize: true, Paths: &BundlePaths{Include: []string{"*.yaml"}},
+			}},
+			wantErr: "git source cannot select paths with kustomize",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bundle{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: BundleSpec{ProvisionerClassName: "core.rukpak.io/plain", Source: tt.source}}
+			err := v.ValidateCreate(context.Background(), b)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBundleValidatorUnpackRetryPolicy(t *testing.T) {
+	v := &bundleValidator{client: fake.NewClientBuilder().WithScheme(testScheme()).Build(), secretNamespace: "rukpak-system"}
+	maxRetries := int32(3)
+	for _, tt := range []struct {
+		name    string
+		policy  *UnpackRetryPolicy
+		wantErr string
+	}{
+		{
+			name: "no policy",
+		},
+		{
+			name: "valid policy",
+			policy: &UnpackRetryPolicy{
+				MaxRetries: &maxRetries,
+				Backoff:    &metav1.Duration{Duration: time.Second},
+				Deadline:   &metav1.Duration{Duration: time.Hour},
+			},
+		},
+		{
+			name:    "zero backoff",
+			policy:  &UnpackRetryPolicy{Backoff: &metav1.Duration{}},
+			wantErr: "unpack retry backoff 0s must be positive",
+		},
+		{
+			name:    "negative deadline",
+			policy:  &UnpackRetryPolicy{Deadline: &metav1.Duration{Duration: -time.Minute}},
+			wantErr: "unpack retry deadline -1m0s must be positive",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bundle{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: BundleSpec{
+				ProvisionerClassName: "core.rukpak.io/plain",
+				Source:               BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c"}},
+				UnpackRetryPolicy:    tt.policy,
+			}}
+			err := v.ValidateCreate(context.Background(), b)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBundleValidatorSource(t *testing.T) {
+	v := &bundleValidator{client: fake.NewClientBuilder().WithScheme(testScheme()).Build(), secretNamespace: "rukpak-system"}
+	for _, tt := range []struct {
+		name    string
+		source  BundleSource
+		wantErr string
+	}{
+		{
+			name:   "image source",
+			source: BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c"}},
+		},
+		{
+			name:   "upload source",
+			source: BundleSource{Type: SourceTypeUpload},
+		},
+		{
+			name:    "unknown source type",
+			source:  BundleSource{Type: "http"},
+			wantErr: `unknown source type "http": must be one of "image", "git", "local", "helm" or "upload"`,
+		},
+		{
+			name:    "missing image source",
+			source:  BundleSource{Type: SourceTypeImage, Git: &GitSource{Repository: "https://example.com/a.git", Ref: GitRef{Branch: "main"}}},
+			wantErr: `source of type "image" must set the source.image field`,
+		},
+		{
+			name:   "helm source",
+			source: BundleSource{Type: SourceTypeHelm, Helm: &HelmSource{Repository: "https://charts.example.com", Chart: "a", Version: "1.0.0"}},
+		},
+		{
+			name:    "missing helm source",
+			source:  BundleSource{Type: SourceTypeHelm},
+			wantErr: `source of type "helm" must set the source.helm field`,
+		},
+		{
+			name:    "missing local source",
+			source:  BundleSource{Type: SourceTypeLocal},
+			wantErr: `source of type "local" must set the source.local field`,
+		},
+		{
+			name:    "local source with a configmap and a path",
+			source:  BundleSource{Type: SourceTypeLocal, Local: &LocalSource{ConfigMap: &ConfigMapSource{Name: "a", Namespace: "b"}, Path: "my-bundle"}},
+			wantErr: "local source must set exactly one of configMap and path",
+		},
+		{
+			name:   "local path source",
+			source: BundleSource{Type: SourceTypeLocal, Local: &LocalSource{Path: "./bundles/my-bundle"}},
+		},
+		{
+			name:    "local path source outside of the root",
+			source:  BundleSource{Type: SourceTypeLocal, Local: &LocalSource{Path: "bundles/../../etc"}},
+			wantErr: `local source path "bundles/../../etc" must be relative to the local path root`,
+		},
+		{
+			name:   "git commit",
+			source: BundleSource{Type: SourceTypeGit, Git: &GitSource{Repository: "https://example.com/a.git", Ref: GitRef{Commit: "abc"}}},
+		},
+		{
+			name:    "git ref without fields",
+			source:  BundleSource{Type: SourceTypeGit, Git: &GitSource{Repository: "https://example.com/a.git"}},
+			wantErr: "git source ref sets 0 of branch, tag and commit: exactly one is required",
+		},
+		{
+			name:    "git ref with several fields",
+			source:  BundleSource{Type: SourceTypeGit, Git: &GitSource{Repository: "https://example.com/a.git", Ref: GitRef{Branch: "main", Tag: "v1"}}},
+			wantErr: "git source ref sets 2 of branch, tag and commit: exactly one is required",
+		},
+		{
+			name:    "sparse checkout with kustomize",
+			source:  BundleSource{Type: SourceTypeGit, Git: &GitSource{Repository: "https://example.com/a.git", Ref: GitRef{Branch: "main"}, Kustomize: true, CloneOptions: &GitCloneOptions{SparseCheckout: true}}},
+			wantErr: "git source cannot use a sparse checkout with kustomize",
+		},
+		{
+			name:   "CA bundle configmap",
+			source: BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c"}, CABundle: &CABundleReference{ConfigMap: "ca"}},
+		},
+		{
+			name:    "CA bundle without object",
+			source:  BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c"}, CABundle: &CABundleReference{Key: "ca.pem"}},
+			wantErr: "source CA bundle must set exactly one of configMap and secret",
+		},
+		{
+			name:    "CA bundle with configmap and secret",
+			source:  BundleSource{Type: SourceTypeGit, Git: &GitSource{Repository: "https://example.com/a.git", Ref: GitRef{Commit: "abc"}}, CABundle: &CABundleReference{ConfigMap: "ca", Secret: "ca"}},
+			wantErr: "source CA bundle must set exactly one of configMap and secret",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bundle{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: BundleSpec{ProvisionerClassName: "core.rukpak.io/plain", Source: tt.source}}
+			err := v.ValidateCreate(context.Background(), b)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBundleValidatorProvisionerClassImmutable(t *testing.T) {
+	v := &bundleValidator{client: fake.NewClientBuilder().WithScheme(testScheme()).Build(), secretNamespace: "rukpak-system"}
+	bundle := func(class string) *Bundle {
+		return &Bundle{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: BundleSpec{
+			ProvisionerClassName: class,
+			Source:               BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c"}},
+		}}
+	}
+	require.NoError(t, v.ValidateUpdate(context.Background(), bundle("core.rukpak.io/plain"), bundle("core.rukpak.io/plain")))
+	require.EqualError(t, v.ValidateUpdate(context.Background(), bundle("core.rukpak.io/plain"), bundle("core.rukpak.io/helm")),
+		`provisionerClassName is immutable: cannot change it from "core.rukpak.io/plain" to "core.rukpak.io/helm"`)
+}
+
+func TestBundleValidatorUnchangedSpec(t *testing.T) {
+	v := &bundleValidator{client: fake.NewClientBuilder().WithScheme(testScheme()).Build(), secretNamespace: "rukpak-system"}
+	bundle := func(pullSecret string) *Bundle {
+		return &Bundle{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: BundleSpec{
+			ProvisionerClassName: "core.rukpak.io/plain",
+			Source:               BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b", PullSecret: pullSecret}},
+		}}
+	}
+	// The finalizer patch of the provisioner is normalized by the mutating
+	// webhook, unlike the Bundle created before normalization was added.
+	withFinalizer := func(b *Bundle) *Bundle {
+		b.Finalizers = []string{"core.rukpak.io/delete-stored-content"}
+		defaultSource(&b.Spec.Source)
+		return b
+	}
+	deleting := func(b *Bundle) *Bundle {
+		now := metav1.Now()
+		b.DeletionTimestamp = &now
+		return b
+	}
+
+	// The pull secret of the Bundle was deleted after it was created.
+	require.NoError(t, v.ValidateUpdate(context.Background(), bundle("missing"), withFinalizer(bundle("missing"))),
+		"updates of the metadata of a Bundle should not validate its spec again")
+	require.NoError(t, v.ValidateUpdate(context.Background(), withFinalizer(bundle("missing")), deleting(bundle("missing"))),
+		"updates of a Bundle that is being deleted should not validate its spec again")
+	require.EqualError(t, v.ValidateUpdate(context.Background(), bundle(""), bundle("missing")),
+		`image pull secret "missing" not found in namespace "rukpak-system"`)
+}
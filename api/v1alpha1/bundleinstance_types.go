@@ -27,26 +27,297 @@ const (
 	TypeHasValidBundle       = "HasValidBundle"
 	TypeInvalidBundleContent = "InvalidBundleContent"
 	TypeInstalled            = "Installed"
+	TypeRolledBack           = "RolledBack"
+	TypeHealthy              = "Healthy"
+	TypePivoting             = "Pivoting"
+	TypePreflightSucceeded   = "PreflightSucceeded"
+	TypeUninstalling         = "Uninstalling"
+	TypeDrifted              = "Drifted"
+	// TypeReady is the condition that aggregates the others. For a
+	// BundleInstance, it is only True once the content of its valid bundle
+	// is installed and healthy, and no pivot or uninstall is in progress.
+	// For a Bundle, it is only True once its current content is unpacked.
+	TypeReady = "Ready"
 
-	ReasonBundleLookupFailed       = "BundleLookupFailed"
-	ReasonBundleLoadFailed         = "BundleLoadFailed"
-	ReasonReadingContentFailed     = "ReadingContentFailed"
-	ReasonErrorGettingClient       = "ErrorGettingClient"
-	ReasonErrorGettingReleaseState = "ErrorGettingReleaseState"
-	ReasonInstallFailed            = "InstallFailed"
-	ReasonUpgradeFailed            = "UpgradeFailed"
-	ReasonReconcileFailed          = "ReconcileFailed"
-	ReasonCreateDynamicWatchFailed = "CreateDynamicWatchFailed"
-	ReasonInstallationSucceeded    = "InstallationSucceeded"
+	ReasonBundleLookupFailed         = "BundleLookupFailed"
+	ReasonBundleLoadFailed           = "BundleLoadFailed"
+	ReasonBundleLoaded               = "BundleLoaded"
+	ReasonTemplateBundleFailed       = "TemplateBundleFailed"
+	ReasonReadingContentFailed       = "ReadingContentFailed"
+	ReasonIntegrityCheckFailed       = "IntegrityCheckFailed"
+	ReasonValidationFailed           = "ValidationFailed"
+	ReasonErrorGettingClient         = "ErrorGettingClient"
+	ReasonErrorGettingReleaseState   = "ErrorGettingReleaseState"
+	ReasonInstallFailed              = "InstallFailed"
+	ReasonInstallTimedOut            = "InstallTimedOut"
+	ReasonInsufficientPermissions    = "InsufficientPermissions"
+	ReasonReleaseNamespaceNotAllowed = "ReleaseNamespaceNotAllowed"
+	ReasonServiceAccountNotAllowed   = "ServiceAccountNotAllowed"
+	ReasonUpgradeFailed              = "UpgradeFailed"
+	ReasonUpgradeTimedOut            = "UpgradeTimedOut"
+	ReasonReconcileFailed            = "ReconcileFailed"
+	ReasonCreateDynamicWatchFailed   = "CreateDynamicWatchFailed"
+	ReasonInstallationSucceeded      = "InstallationSucceeded"
+	ReasonReadOnlyMode               = "ReadOnlyMode"
+	ReasonRollbackSucceeded          = "RollbackSucceeded"
+	ReasonRollbackFailed             = "RollbackFailed"
+	ReasonHealthy                    = "Healthy"
+	ReasonUnhealthy                  = "Unhealthy"
+	ReasonHealthCheckFailed          = "HealthCheckFailed"
+	ReasonPivotInProgress            = "PivotInProgress"
+	ReasonPivotFailed                = "PivotFailed"
+	ReasonPivotSucceeded             = "PivotSucceeded"
+	ReasonPermissionsGranted         = "PermissionsGranted"
+	ReasonMissingPermissions         = "MissingPermissions"
+	ReasonPreflightCheckFailed       = "PreflightCheckFailed"
+	ReasonDryRunMode                 = "DryRunMode"
+	ReasonCRDsNotEstablished         = "CRDsNotEstablished"
+	ReasonCRDUpgradeUnsafe           = "CRDUpgradeUnsafe"
+	ReasonPolicyViolation            = "PolicyViolation"
+	ReasonPolicyCheckFailed          = "PolicyCheckFailed"
+	ReasonWaveInProgress             = "WaveInProgress"
+	ReasonHookInProgress             = "HookInProgress"
+	ReasonHookFailed                 = "HookFailed"
+	ReasonObjectConflict             = "ObjectConflict"
+	ReasonUninstallInProgress        = "UninstallInProgress"
+	ReasonUninstallFailed            = "UninstallFailed"
+	ReasonDriftDetected              = "DriftDetected"
+	ReasonNoDrift                    = "NoDrift"
+	ReasonDriftCheckFailed           = "DriftCheckFailed"
+	ReasonReady                      = "Ready"
+	ReasonNotReady                   = "NotReady"
+
+	// InstallModeApply installs, upgrades and reconciles the content of a
+	// BundleInstance.
+	InstallModeApply = "Apply"
+	// InstallModeDryRun only reports the changes that would be made to the
+	// content of a BundleInstance, without making them.
+	InstallModeDryRun = "DryRun"
+
+	// DeletionPolicyDelete uninstalls the objects of a BundleInstance when it
+	// is deleted.
+	DeletionPolicyDelete = "Delete"
+	// DeletionPolicyOrphan leaves the objects of a BundleInstance in place
+	// when it is deleted, and removes its release, so that the objects are no
+	// longer managed by rukpak.
+	DeletionPolicyOrphan = "Orphan"
+	// DeletionPolicyRetain leaves the objects and the release of a
+	// BundleInstance in place when it is deleted, so that they are adopted by a
+	// BundleInstance of the same name that is created later.
+	DeletionPolicyRetain = "Retain"
+
+	// EngineHelm installs the objects of a BundleInstance as a Helm release.
+	EngineHelm = "Helm"
+	// EngineServerSideApply applies the objects of a BundleInstance with
+	// server-side apply, without a Helm release.
+	EngineServerSideApply = "ServerSideApply"
+
+	// DriftPolicyCorrect reapplies the installed objects of a BundleInstance
+	// that have drifted from the content of its bundle.
+	DriftPolicyCorrect = "Correct"
+	// DriftPolicyDetect reports the installed objects of a BundleInstance
+	// that have drifted from the content of its bundle, without reapplying
+	// them.
+	DriftPolicyDetect = "Detect"
+	// DriftPolicyIgnore neither watches nor reapplies the installed objects
+	// of a BundleInstance once they are installed.
+	DriftPolicyIgnore = "Ignore"
+
+	// ConflictPolicyFail reports the objects of a BundleInstance that already
+	// exist but are not installed by its release, and leaves them as they are.
+	ConflictPolicyFail = "Fail"
+	// ConflictPolicyAdopt takes over the objects of a BundleInstance that
+	// already exist and are not installed by any release.
+	ConflictPolicyAdopt = "Adopt"
+	// ConflictPolicyForce takes over the objects of a BundleInstance that
+	// already exist, including those installed by other releases.
+	ConflictPolicyForce = "Force"
+
+	// ReasonAPIWarning is the reason of the events that report the warnings of
+	// the API server.
+	ReasonAPIWarning = "APIWarning"
+	// ReasonInstalled and ReasonUpgraded are the reasons of the events that
+	// report the install and the upgrades of the content of a BundleInstance.
+	// Failed installs and upgrades, rollbacks and health transitions are
+	// reported with the reasons of the corresponding conditions.
+	ReasonInstalled = "Installed"
+	ReasonUpgraded  = "Upgraded"
 )
 
 // BundleInstanceSpec defines the desired state of BundleInstance
 type BundleInstanceSpec struct {
 	// ProvisionerClassName sets the name of the provisioner that should reconcile this BundleInstance.
-	ProvisionerClassName string `json:"provisionerClassName"`
+	// ProvisionerClassName is optional and if not set defaults to the provisioner of the
+	// default ProvisionerClass.
+	ProvisionerClassName string `json:"provisionerClassName,omitempty"`
 
 	// BundleName is the name of the bundle that this instance is managing on the cluster.
-	BundleName string `json:"bundleName"`
+	// Exactly one of BundleName and Template is required.
+	BundleName string `json:"bundleName,omitempty"`
+
+	// Template declares the Bundle that this instance manages on the cluster,
+	// so that the content and its installation are declared in one object.
+	// The provisioner creates a Bundle from the template, owned by this
+	// BundleInstance, and replaces it with a new Bundle whenever the template
+	// changes. Bundles of previous templates are deleted once a newer one is
+	// installed. Exactly one of BundleName and Template is required.
+	Template *BundleTemplate `json:"template,omitempty"`
+
+	// TargetNamespace is the namespace that namespace-scoped objects in the referenced
+	// bundle, which do not already declare a namespace, are installed into. Cluster-scoped
+	// objects are unaffected by this field. The namespace must already exist.
+	// TargetNamespace is optional and if not set defaults to the release namespace.
+	//+kubebuilder:validation:MaxLength=63
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+
+	// ReleaseNamespace is the namespace in which the release state of this
+	// BundleInstance is stored, so that it can be colocated with the content
+	// that it manages. It is also the default of TargetNamespace. The
+	// namespace must already exist, and be allowed by the provisioner.
+	// ReleaseNamespace is optional and if not set defaults to the
+	// provisioner's release namespace. It cannot be changed once the
+	// BundleInstance is created.
+	//+kubebuilder:validation:MaxLength=63
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	ReleaseNamespace string `json:"releaseNamespace,omitempty"`
+
+	// UpgradePolicy configures how the provisioner handles upgrades of this BundleInstance.
+	UpgradePolicy *UpgradePolicy `json:"upgradePolicy,omitempty"`
+
+	// ServiceAccountName is the name of the ServiceAccount that the provisioner
+	// impersonates to install, upgrade and reconcile the objects of the bundle,
+	// so that they are limited to the permissions granted to the ServiceAccount.
+	// The ServiceAccount is looked up in the target namespace, or in the
+	// release namespace when no target namespace is set.
+	// Only the ServiceAccounts that the serviceAccounts of the RukpakConfig
+	// select can be impersonated.
+	// ServiceAccountName is optional and if not set the provisioner's own
+	// permissions are used.
+	//+kubebuilder:validation:MaxLength=253
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// InstallMode configures whether the provisioner applies the content of
+	// the bundle, or only performs a dry-run and reports the changes that it
+	// would make in the pendingChanges status field. In the DryRun mode, the
+	// installed objects are left as they are, including the objects of a
+	// previously installed bundle, so that changes can be reviewed before
+	// InstallMode is set back to Apply.
+	// InstallMode is optional and if not set defaults to Apply.
+	//+kubebuilder:validation:Enum=Apply;DryRun
+	InstallMode string `json:"installMode,omitempty"`
+
+	// DeletionPolicy configures what happens to the installed objects when
+	// the BundleInstance is deleted. Delete uninstalls them. Orphan and Retain
+	// both leave them in place without the BundleInstance as their owner, and
+	// Retain also keeps the release of the BundleInstance, so that a
+	// BundleInstance of the same name that is created later takes the objects
+	// over again.
+	// DeletionPolicy is optional and if not set defaults to the
+	// defaultDeletionPolicy of the RukpakConfig, which defaults to Delete.
+	//+kubebuilder:validation:Enum=Delete;Orphan;Retain
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// RetainCRDs configures the Delete deletion policy to leave the
+	// CustomResourceDefinitions of the bundle in place, so that deleting the
+	// BundleInstance does not delete the custom resources that users created
+	// for them. The custom resources of the CustomResourceDefinitions that
+	// are part of the bundle itself are deleted either way, before any other
+	// object. RetainCRDs defaults to true, and CustomResourceDefinitions are
+	// only deleted when it is explicitly set to false.
+	//+kubebuilder:default=true
+	RetainCRDs *bool `json:"retainCRDs,omitempty"`
+
+	// Engine configures how the provisioner installs the objects of the
+	// bundle. Helm installs them as a Helm release, whose revisions are
+	// stored in the release namespace. ServerSideApply applies them with
+	// server-side apply, with a field manager of their own for each
+	// BundleInstance, records them in status.inventory rather than in a
+	// release, and deletes the objects that are removed from the bundle.
+	// ServerSideApply does not support hooks, rollbacks and install reports.
+	// Engine is optional, defaults to Helm, and cannot be changed.
+	//+kubebuilder:validation:Enum=Helm;ServerSideApply
+	Engine string `json:"engine,omitempty"`
+
+	// ConflictPolicy configures how objects of the bundle that already exist,
+	// but are not installed by the release of this BundleInstance, are
+	// handled when it is installed or upgraded. Fail reports them in the
+	// Installed condition with the ObjectConflict reason. Adopt takes over
+	// the objects that no other release installed by labeling and annotating
+	// them as objects of the release. Force also takes over the objects of
+	// other releases, and applies the content of every existing object with
+	// server-side apply, taking over the fields set by other field managers.
+	// ConflictPolicy is optional and if not set defaults to Fail.
+	//+kubebuilder:validation:Enum=Fail;Adopt;Force
+	ConflictPolicy string `json:"conflictPolicy,omitempty"`
+
+	// DriftPolicy configures how installed objects that are changed or
+	// deleted in the cluster, while the content of the bundle is unchanged,
+	// are handled. Correct reapplies them whenever they change and on every
+	// resync. Detect only reports them in the Drifted condition, along with
+	// their changed fields. Ignore does not watch the installed objects at
+	// all, so that they are only reconciled when the BundleInstance or its
+	// bundle change. DriftPolicy is optional and if not set defaults to
+	// Correct.
+	//+kubebuilder:validation:Enum=Correct;Detect;Ignore
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+
+	// ResyncInterval is the interval at which the installed content of this
+	// BundleInstance is reconciled in the absence of any changes, overriding
+	// the resync interval of the RukpakConfig and of the provisioner. A zero
+	// ResyncInterval only reconciles the content when it changes.
+	// ResyncInterval is optional.
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+
+	// SubstituteVariables opts in to the substitution of ${NAME} variables in
+	// the string values of the manifests of a plain bundle before they are
+	// installed, so that one Bundle can be installed by several
+	// BundleInstances with different names and namespaces. The
+	// BUNDLE_INSTANCE_NAME and TARGET_NAMESPACE variables are the name of the
+	// BundleInstance and the namespace that its namespace-scoped objects are
+	// installed into, and the other variables are defined by Config.
+	// $${NAME} is replaced by a literal ${NAME}, and manifests that refer to
+	// undefined variables fail to install.
+	SubstituteVariables bool `json:"substituteVariables,omitempty"`
+
+	// Config defines the values of the variables substituted in the manifests
+	// of the bundle when SubstituteVariables is set. Its keys must be
+	// letters, digits and underscores that do not start with a digit, and
+	// cannot redefine BUNDLE_INSTANCE_NAME and TARGET_NAMESPACE.
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// The variables that are always defined when the manifests of the bundle of a
+// BundleInstance with SubstituteVariables are substituted.
+const (
+	VariableBundleInstanceName = "BUNDLE_INSTANCE_NAME"
+	VariableTargetNamespace    = "TARGET_NAMESPACE"
+)
+
+// BundleTemplate is the template of the Bundle of a BundleInstance.
+type BundleTemplate struct {
+	// Metadata is the metadata of the Bundles created from the template.
+	Metadata BundleTemplateMetadata `json:"metadata,omitempty"`
+	// Spec is the spec of the Bundles created from the template. Its
+	// provisionerClassName is optional and if not set defaults to the one of
+	// the BundleInstance. It must otherwise be the same.
+	Spec BundleSpec `json:"spec"`
+}
+
+// BundleTemplateMetadata is the metadata of the Bundles created from a
+// BundleTemplate.
+type BundleTemplateMetadata struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// UpgradePolicy defines how a BundleInstance is upgraded between bundle contents.
+type UpgradePolicy struct {
+	// RollbackOnFailure configures the provisioner to roll the installed content back to
+	// the last successfully deployed release when an upgrade fails. The outcome of the
+	// rollback is reported in the RolledBack condition.
+	RollbackOnFailure bool `json:"rollbackOnFailure,omitempty"`
 }
 
 // BundleInstanceStatus defines the observed state of BundleInstance
@@ -55,15 +326,204 @@ type BundleInstanceStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
+	// ObservedGeneration is the generation of the BundleInstance that was
+	// last reconciled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	InstalledBundleName string `json:"installedBundleName,omitempty"`
+
+	// ReleaseNamespace is the namespace in which the release state of the
+	// BundleInstance is stored, and into which its namespace-scoped content
+	// without a namespace is installed. It is recorded when the
+	// BundleInstance is first reconciled, so that its release is still
+	// upgraded, and uninstalled, in that namespace once the default release
+	// namespace of the provisioner changes.
+	ReleaseNamespace string `json:"releaseNamespace,omitempty"`
+
+	// Release is the Helm release that the content of the installed bundle
+	// was last installed or upgraded to. It is unset for the ServerSideApply
+	// engine, which does not install releases.
+	Release *ReleaseStatus `json:"release,omitempty"`
+
+	// ObjectHealth reports the health of the installed objects whose kind
+	// supports health assessment.
+	ObjectHealth []ObjectHealth `json:"objectHealth,omitempty"`
+
+	// Warnings are the warnings, such as deprecation notices and the warnings
+	// of admission webhooks, that the API server returned while the content
+	// was last installed, upgraded or reconciled.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// ReconcileProgress records the progress of a reconciliation of the
+	// installed objects that did not complete within a single reconcile. It
+	// is unset once all installed objects have been reconciled.
+	ReconcileProgress *ReconcileProgress `json:"reconcileProgress,omitempty"`
+
+	// Inventory are the objects applied by the ServerSideApply engine. The
+	// objects that are removed from the bundle are deleted, and the
+	// objects in the inventory are uninstalled along with the
+	// BundleInstance according to its deletion policy. Only the objects that
+	// the BundleInstance created are deleted.
+	Inventory []InventoryReference `json:"inventory,omitempty"`
+
+	// InstalledObjects report the outcome of the last apply of each object of
+	// the bundle, so that the objects that failed to install can be located
+	// without reading the release. Objects that a failed apply did not reach
+	// keep the outcome of their previous apply.
+	InstalledObjects []InstalledObject `json:"installedObjects,omitempty"`
+
+	// Hooks report the last run of each hook of the installed content.
+	Hooks []HookStatus `json:"hooks,omitempty"`
+
+	// PendingChanges are the changes to the installed objects that the
+	// provisioner would make if the install mode was Apply. It is only set in
+	// the DryRun install mode, while the installed release differs from the
+	// content of the bundle.
+	PendingChanges *PendingChanges `json:"pendingChanges,omitempty"`
+
+	// LastAttemptedAt is the time at which the BundleInstance was last
+	// reconciled. When a reconcile changes nothing else in the status, it is
+	// only updated once it is more than 10 minutes old, and so is
+	// LastSuccessfulInstallAt.
+	LastAttemptedAt *metav1.Time `json:"lastAttemptedAt,omitempty"`
+	// LastSuccessfulInstallAt is the time of the last reconcile that left the
+	// content of the bundle installed.
+	LastSuccessfulInstallAt *metav1.Time `json:"lastSuccessfulInstallAt,omitempty"`
+	// LastFailedAt is the time of the last reconcile that failed.
+	LastFailedAt *metav1.Time `json:"lastFailedAt,omitempty"`
+	// ConsecutiveFailures is the number of reconciles that failed since the
+	// content was last installed successfully.
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+	// TotalFailures is the number of reconciles that failed since the
+	// BundleInstance was created.
+	TotalFailures int64 `json:"totalFailures,omitempty"`
+}
+
+// PendingChanges is the object-level diff from the installed release of a
+// BundleInstance to the content of its bundle.
+type PendingChanges struct {
+	// BundleName is the name of the bundle whose content would be installed.
+	BundleName string `json:"bundleName"`
+	// Added are the objects that would be created.
+	Added []ObjectReference `json:"added,omitempty"`
+	// Removed are the objects that would be deleted.
+	Removed []ObjectReference `json:"removed,omitempty"`
+	// Changed are the objects that would be updated.
+	Changed []ObjectReference `json:"changed,omitempty"`
+}
+
+// ObjectReference identifies an object by its group, kind, namespace and name.
+type ObjectReference struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// InventoryReference is an object in the inventory of a BundleInstance.
+type InventoryReference struct {
+	ObjectReference `json:",inline"`
+	// Created is set when the object did not exist before the BundleInstance
+	// applied it. The objects that existed before, and were adopted according
+	// to the conflict policy, are left in place without the BundleInstance as
+	// their owner instead of being deleted.
+	Created bool `json:"created,omitempty"`
+}
+
+// ReconcileProgress records how many objects of a release revision have been
+// reconciled so far.
+type ReconcileProgress struct {
+	// Revision is the release revision that is being reconciled.
+	Revision int `json:"revision"`
+	// Reconciled is the number of objects that have been reconciled.
+	Reconciled int `json:"reconciled"`
+	// Total is the number of objects in the release revision.
+	Total int `json:"total"`
+}
+
+// ObjectHealth describes the health of a single installed object.
+type ObjectHealth struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Healthy   bool   `json:"healthy"`
+	Message   string `json:"message,omitempty"`
+}
+
+// The states of the objects in the InstalledObjects of the status of a
+// BundleInstance.
+const (
+	InstalledObjectCreated   = "Created"
+	InstalledObjectUpdated   = "Updated"
+	InstalledObjectUnchanged = "Unchanged"
+	InstalledObjectFailed    = "Failed"
+)
+
+// InstalledObject describes the outcome of the last apply of an object of the
+// bundle of a BundleInstance. Namespace is the namespace that a namespaced
+// object is installed into.
+type InstalledObject struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// State is Created or Updated when the apply created or changed the
+	// object, Unchanged when the object already matched the bundle, and
+	// Failed when the object could not be applied.
+	//+kubebuilder:validation:Enum=Created;Updated;Unchanged;Failed
+	State string `json:"state"`
+	// Message is the error of a Failed object.
+	Message string `json:"message,omitempty"`
+}
+
+// ReleaseStatus identifies the Helm release of a BundleInstance, which is
+// stored in a Secret in the system namespace of the provisioner.
+type ReleaseStatus struct {
+	// Name is the name of the release.
+	Name string `json:"name"`
+	// Namespace is the namespace in which the release is stored.
+	Namespace string `json:"namespace"`
+	// Version is the revision of the release.
+	Version int `json:"version"`
+	// Status is the status of the release, such as deployed.
+	Status string `json:"status"`
+	// LastDeployed is the time at which the revision was deployed.
+	LastDeployed *metav1.Time `json:"lastDeployed,omitempty"`
+	// Notes are the rendered notes of the release, if it has any.
+	Notes string `json:"notes,omitempty"`
+}
+
+// HookStatus describes the last run of a Job or Pod of a plain bundle that
+// runs as a hook.
+type HookStatus struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Events []string `json:"events"`
+	// Phase is the phase of the last run of the hook: Running, Succeeded or
+	// Failed.
+	Phase string `json:"phase,omitempty"`
+	// Event is the event of the last run of the hook.
+	Event string `json:"event,omitempty"`
+	// ContentHash is the hash of the content that the install or upgrade of
+	// the last run of the hook installed. A hook runs once for each event of
+	// the content that is installed.
+	ContentHash string       `json:"contentHash,omitempty"`
+	StartedAt   *metav1.Time `json:"startedAt,omitempty"`
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:resource:scope=Cluster
 //+kubebuilder:subresource:status
-//+kubebuilder:printcolumn:name="Desired Bundle",type=string,JSONPath=`.spec.bundleName`
+//+kubebuilder:printcolumn:name=Bundle,type=string,JSONPath=`.spec.bundleName`
 //+kubebuilder:printcolumn:name="Installed Bundle",type=string,JSONPath=`.status.installedBundleName`
-//+kubebuilder:printcolumn:name="Install State",type=string,JSONPath=`.status.conditions[?(.type=="Installed")].reason`
+//+kubebuilder:printcolumn:name=Installed,type=string,JSONPath=`.status.conditions[?(.type=="Installed")].status`
+//+kubebuilder:printcolumn:name=Healthy,type=string,JSONPath=`.status.conditions[?(.type=="Healthy")].status`
+//+kubebuilder:printcolumn:name=Ready,type=string,JSONPath=`.status.conditions[?(.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Install State",type=string,JSONPath=`.status.conditions[?(.type=="Installed")].reason`,priority=1
 //+kubebuilder:printcolumn:name=Age,type=date,JSONPath=`.metadata.creationTimestamp`
 
 // BundleInstance is the Schema for the bundleinstances API
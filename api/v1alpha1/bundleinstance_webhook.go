@@ -0,0 +1,188 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var bundleinstancelog = logf.Log.WithName("bundleinstance-resource")
+
+// SetupWebhookWithManager registers the validating webhook of
+// BundleInstances, and the mutating webhook that defaults and labels their
+// provisioner class, with mgr.
+func (r *BundleInstance) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(provisionerClassDefaulter{client: mgr.GetAPIReader()}).
+		WithValidator(&bundleInstanceValidator{client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-core-rukpak-io-v1alpha1-bundleinstance,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.rukpak.io,resources=bundleinstances,verbs=create;update,versions=v1alpha1,name=vbundleinstance.core.rukpak.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/mutate-core-rukpak-io-v1alpha1-bundleinstance,mutating=true,failurePolicy=fail,sideEffects=None,groups=core.rukpak.io,resources=bundleinstances,verbs=create;update,versions=v1alpha1,name=mbundleinstance.core.rukpak.io,admissionReviewVersions=v1
+
+// bundleInstanceValidator validates BundleInstances, including the provisioner
+// class of the Bundle they reference, so that a BundleInstance is not left
+// waiting for a Bundle that its provisioner never unpacks.
+type bundleInstanceValidator struct {
+	client client.Reader
+}
+
+var _ admission.CustomValidator = &bundleInstanceValidator{}
+
+// ValidateCreate implements admission.CustomValidator.
+func (v *bundleInstanceValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	r := obj.(*BundleInstance)
+	bundleinstancelog.V(1).Info("validate create", "name", r.Name)
+
+	if err := checkProvisionerDeclared(ctx, v.client, r.Spec.ProvisionerClassName); err != nil {
+		return err
+	}
+	return v.validate(ctx, r)
+}
+
+// ValidateUpdate implements admission.CustomValidator.
+func (v *bundleInstanceValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	r := newObj.(*BundleInstance)
+	bundleinstancelog.V(1).Info("validate update", "name", r.Name)
+
+	if err := checkProvisionerClassUnchanged(oldObj.(*BundleInstance).Spec.ProvisionerClassName, r.Spec.ProvisionerClassName); err != nil {
+		return err
+	}
+	if err := checkEngineUnchanged(oldObj.(*BundleInstance).Spec.Engine, r.Spec.Engine); err != nil {
+		return err
+	}
+	if err := checkReleaseNamespaceUnchanged(oldObj.(*BundleInstance).Spec.ReleaseNamespace, r.Spec.ReleaseNamespace); err != nil {
+		return err
+	}
+	return v.validate(ctx, r)
+}
+
+// ValidateDelete implements admission.CustomValidator.
+func (v *bundleInstanceValidator) ValidateDelete(_ context.Context, obj runtime.Object) error {
+	r := obj.(*BundleInstance)
+	bundleinstancelog.V(1).Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+func (v *bundleInstanceValidator) validate(ctx context.Context, r *BundleInstance) error {
+	if err := checkConfig(r.Spec.Config); err != nil {
+		return err
+	}
+	if interval := r.Spec.ResyncInterval; interval != nil && interval.Duration < 0 {
+		return fmt.Errorf("resync interval %s must not be negative", interval.Duration)
+	}
+	switch template := r.Spec.Template; {
+	case template == nil && r.Spec.BundleName == "":
+		return errors.New("one of bundleName and template is required")
+	case template != nil && r.Spec.BundleName != "":
+		return errors.New("bundleName and template are mutually exclusive: set only one of them")
+	case template != nil:
+		return checkTemplate(r)
+	}
+	return v.checkBundleProvisionerClass(ctx, r)
+}
+
+// checkEngineUnchanged checks that the engine of a BundleInstance is not
+// changed, since the objects installed by one engine are not known to the
+// other.
+func checkEngineUnchanged(oldEngine, newEngine string) error {
+	if oldEngine == "" {
+		oldEngine = EngineHelm
+	}
+	if newEngine == "" {
+		newEngine = EngineHelm
+	}
+	if oldEngine != newEngine {
+		return fmt.Errorf("engine is immutable: cannot change it from %q to %q", oldEngine, newEngine)
+	}
+	return nil
+}
+
+// checkReleaseNamespaceUnchanged checks that the release namespace of a
+// BundleInstance is not changed, since the release stored in the old namespace
+// would be left behind, along with the objects that it manages.
+func checkReleaseNamespaceUnchanged(oldNamespace, newNamespace string) error {
+	if oldNamespace != newNamespace {
+		return fmt.Errorf("releaseNamespace is immutable: cannot change it from %q to %q", oldNamespace, newNamespace)
+	}
+	return nil
+}
+
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkConfig checks that the keys of config are variable names that do not
+// redefine the variables that are always defined.
+func checkConfig(config map[string]string) error {
+	for key := range config {
+		if !variableName.MatchString(key) {
+			return fmt.Errorf("config key %q is not a valid variable name: it must consist of letters, digits and underscores, and not start with a digit", key)
+		}
+		if key == VariableBundleInstanceName || key == VariableTargetNamespace {
+			return fmt.Errorf("config key %q cannot redefine a built-in variable", key)
+		}
+	}
+	return nil
+}
+
+// checkTemplate checks the Bundle template of r like the spec of a Bundle,
+// and that its provisioner class, if set, is the one of r.
+func checkTemplate(r *BundleInstance) error {
+	spec := r.Spec.Template.Spec
+	if spec.ProvisionerClassName != "" && spec.ProvisionerClassName != r.Spec.ProvisionerClassName {
+		return fmt.Errorf("template has provisioner class %q: it must be unset or %q, the provisioner class of the bundleinstance",
+			spec.ProvisionerClassName, r.Spec.ProvisionerClassName)
+	}
+	if err := checkSource(spec.Source); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	if err := checkUnpackRetryPolicy(&Bundle{Spec: spec}); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	return nil
+}
+
+// checkBundleProvisionerClass checks that the Bundle referenced by r, if it
+// exists, has the provisioner class of r. A Bundle that does not exist yet is
+// allowed, so that a BundleInstance can be created before its Bundle.
+func (v *bundleInstanceValidator) checkBundleProvisionerClass(ctx context.Context, r *BundleInstance) error {
+	b := &Bundle{}
+	if err := v.client.Get(ctx, types.NamespacedName{Name: r.Spec.BundleName}, b); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("get bundle %q: %w", r.Spec.BundleName, err)
+	}
+	if b.Spec.ProvisionerClassName != r.Spec.ProvisionerClassName {
+		return fmt.Errorf("bundle %q has provisioner class %q: it cannot be installed by a bundleinstance of provisioner class %q",
+			b.Name, b.Spec.ProvisionerClassName, r.Spec.ProvisionerClassName)
+	}
+	return nil
+}
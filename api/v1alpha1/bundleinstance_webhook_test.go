@@ -0,0 +1,162 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestBundleInstanceValidator(t *testing.T) {
+	cl := fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(
+		&Bundle{ObjectMeta: metav1.ObjectMeta{Name: "plain"}, Spec: BundleSpec{ProvisionerClassName: "core.rukpak.io/plain"}},
+	).Build()
+	v := &bundleInstanceValidator{client: cl}
+	bundleInstance := func(class, bundleName string) *BundleInstance {
+		return &BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: BundleInstanceSpec{ProvisionerClassName: class, BundleName: bundleName}}
+	}
+	withTemplate := func(bi *BundleInstance, spec BundleSpec) *BundleInstance {
+		bi.Spec.Template = &BundleTemplate{Spec: spec}
+		return bi
+	}
+	withConfig := func(bi *BundleInstance, config map[string]string) *BundleInstance {
+		bi.Spec.SubstituteVariables = true
+		bi.Spec.Config = config
+		return bi
+	}
+	withEngine := func(bi *BundleInstance, engine string) *BundleInstance {
+		bi.Spec.Engine = engine
+		return bi
+	}
+	withReleaseNamespace := func(bi *BundleInstance, namespace string) *BundleInstance {
+		bi.Spec.ReleaseNamespace = namespace
+		return bi
+	}
+	withResyncInterval := func(bi *BundleInstance, interval time.Duration) *BundleInstance {
+		bi.Spec.ResyncInterval = &metav1.Duration{Duration: interval}
+		return bi
+	}
+	imageSource := BundleSource{Type: SourceTypeImage, Image: &ImageSource{Ref: "quay.io/a/b:c"}}
+
+	for _, tt := range []struct {
+		name    string
+		old     *BundleInstance
+		new     *BundleInstance
+		wantErr string
+	}{
+		{
+			name: "bundle of the same provisioner class",
+			new:  bundleInstance("core.rukpak.io/plain", "plain"),
+		},
+		{
+			name: "bundle that does not exist",
+			new:  bundleInstance("core.rukpak.io/helm", "missing"),
+		},
+		{
+			name:    "bundle of another provisioner class",
+			new:     bundleInstance("core.rukpak.io/helm", "plain"),
+			wantErr: `bundle "plain" has provisioner class "core.rukpak.io/plain": it cannot be installed by a bundleinstance of provisioner class "core.rukpak.io/helm"`,
+		},
+		{
+			name: "pivot to another bundle",
+			old:  bundleInstance("core.rukpak.io/plain", "missing"),
+			new:  bundleInstance("core.rukpak.io/plain", "plain"),
+		},
+		{
+			name:    "no bundle",
+			new:     bundleInstance("core.rukpak.io/plain", ""),
+			wantErr: "one of bundleName and template is required",
+		},
+		{
+			name: "template",
+			new:  withTemplate(bundleInstance("core.rukpak.io/plain", ""), BundleSpec{Source: imageSource}),
+		},
+		{
+			name:    "template and bundle name",
+			new:     withTemplate(bundleInstance("core.rukpak.io/plain", "plain"), BundleSpec{Source: imageSource}),
+			wantErr: "bundleName and template are mutually exclusive: set only one of them",
+		},
+		{
+			name:    "template of another provisioner class",
+			new:     withTemplate(bundleInstance("core.rukpak.io/plain", ""), BundleSpec{ProvisionerClassName: "core.rukpak.io/helm", Source: imageSource}),
+			wantErr: `template has provisioner class "core.rukpak.io/helm": it must be unset or "core.rukpak.io/plain", the provisioner class of the bundleinstance`,
+		},
+		{
+			name:    "template with an invalid source",
+			new:     withTemplate(bundleInstance("core.rukpak.io/plain", ""), BundleSpec{Source: BundleSource{Type: SourceTypeGit}}),
+			wantErr: `template: source of type "git" must set the source.git field`,
+		},
+		{
+			name: "config",
+			new:  withConfig(bundleInstance("core.rukpak.io/plain", "plain"), map[string]string{"REPLICAS": "3"}),
+		},
+		{
+			name:    "config with an invalid variable name",
+			new:     withConfig(bundleInstance("core.rukpak.io/plain", "plain"), map[string]string{"1-replicas": "3"}),
+			wantErr: `config key "1-replicas" is not a valid variable name: it must consist of letters, digits and underscores, and not start with a digit`,
+		},
+		{
+			name:    "config redefining a built-in variable",
+			new:     withConfig(bundleInstance("core.rukpak.io/plain", "plain"), map[string]string{VariableTargetNamespace: "other"}),
+			wantErr: `config key "TARGET_NAMESPACE" cannot redefine a built-in variable`,
+		},
+		{
+			name: "resync interval",
+			new:  withResyncInterval(bundleInstance("core.rukpak.io/plain", "plain"), 10*time.Minute),
+		},
+		{
+			name: "zero resync interval",
+			new:  withResyncInterval(bundleInstance("core.rukpak.io/plain", "plain"), 0),
+		},
+		{
+			name:    "negative resync interval",
+			new:     withResyncInterval(bundleInstance("core.rukpak.io/plain", "plain"), -time.Minute),
+			wantErr: "resync interval -1m0s must not be negative",
+		},
+		{
+			name:    "changed provisioner class",
+			old:     bundleInstance("core.rukpak.io/plain", "missing"),
+			new:     bundleInstance("core.rukpak.io/helm", "missing"),
+			wantErr: `provisionerClassName is immutable: cannot change it from "core.rukpak.io/plain" to "core.rukpak.io/helm"`,
+		},
+		{
+			name: "unset engine",
+			old:  bundleInstance("core.rukpak.io/plain", "missing"),
+			new:  withEngine(bundleInstance("core.rukpak.io/plain", "missing"), EngineHelm),
+		},
+		{
+			name:    "changed engine",
+			old:     bundleInstance("core.rukpak.io/plain", "missing"),
+			new:     withEngine(bundleInstance("core.rukpak.io/plain", "missing"), EngineServerSideApply),
+			wantErr: `engine is immutable: cannot change it from "Helm" to "ServerSideApply"`,
+		},
+		{
+			name: "unchanged release namespace",
+			old:  withReleaseNamespace(bundleInstance("core.rukpak.io/plain", "missing"), "workloads"),
+			new:  withReleaseNamespace(bundleInstance("core.rukpak.io/plain", "missing"), "workloads"),
+		},
+		{
+			name:    "changed release namespace",
+			old:     bundleInstance("core.rukpak.io/plain", "missing"),
+			new:     withReleaseNamespace(bundleInstance("core.rukpak.io/plain", "missing"), "workloads"),
+			wantErr: `releaseNamespace is immutable: cannot change it from "" to "workloads"`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.old == nil {
+				err = v.ValidateCreate(context.Background(), tt.new)
+			} else {
+				err = v.ValidateUpdate(context.Background(), tt.old, tt.new)
+			}
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// LabelProvisionerClass is set by the rukpak core on every Bundle and
+// BundleInstance to the ProvisionerClassLabelValue of its provisioner class,
+// so that a provisioner can list and watch only the objects of its class.
+const LabelProvisionerClass = "core.rukpak.io/provisioner-class"
+
+// ProvisionerClassLabelValue returns the value of the LabelProvisionerClass
+// label of objects with the provisioner class className. Provisioner class
+// names contain a slash, which is not valid in label values, so it is replaced
+// with an underscore. Names that are still not valid label values are hashed.
+func ProvisionerClassLabelValue(className string) string {
+	value := strings.ReplaceAll(className, "/", "_")
+	if len(validation.IsValidLabelValue(value)) == 0 {
+		return value
+	}
+	sum := sha256.Sum256([]byte(className))
+	return hex.EncodeToString(sum[:])[:32]
+}
+
+// provisionerClassDefaulter defaults the provisioner class of Bundles and
+// BundleInstances to the provisioner of the default ProvisionerClass, and sets
+// their LabelProvisionerClass label to the value of their provisioner class,
+// regardless of the label that is requested.
+type provisionerClassDefaulter struct {
+	client client.Reader
+}
+
+var _ admission.CustomDefaulter = provisionerClassDefaulter{}
+
+// Default implements admission.CustomDefaulter.
+func (d provisionerClassDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	var (
+		meta      *metav1.ObjectMeta
+		className *string
+	)
+	switch o := obj.(type) {
+	case *Bundle:
+		meta, className = &o.ObjectMeta, &o.Spec.ProvisionerClassName
+	case *BundleInstance:
+		meta, className = &o.ObjectMeta, &o.Spec.ProvisionerClassName
+	default:
+		return fmt.Errorf("unexpected object type %T", obj)
+	}
+	if *className == "" {
+		provisioner, err := defaultProvisioner(ctx, d.client)
+		if err != nil {
+			return err
+		}
+		*className = provisioner
+	}
+	setProvisionerClassLabel(meta, *className)
+	return nil
+}
+
+// defaultProvisioner returns the provisioner of the ProvisionerClass that is
+// annotated as the default, or an empty string when there is none.
+func defaultProvisioner(ctx context.Context, c client.Reader) (string, error) {
+	classes := &ProvisionerClassList{}
+	if err := c.List(ctx, classes); err != nil {
+		return "", fmt.Errorf("list provisioner classes: %w", err)
+	}
+	var defaults []ProvisionerClass
+	for _, class := range classes.Items {
+		if class.Annotations[AnnotationDefaultProvisionerClass] == "true" {
+			defaults = append(defaults, class)
+		}
+	}
+	switch len(defaults) {
+	case 0:
+		return "", nil
+	case 1:
+		return defaults[0].Spec.Provisioner, nil
+	}
+	names := make([]string, 0, len(defaults))
+	for _, class := range defaults {
+		names = append(names, class.Name)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("provisioner classes %s are all annotated as the default: annotate only one of them", strings.Join(names, ", "))
+}
+
+// checkProvisionerDeclared checks that className is set, and that it is the
+// provisioner of a ProvisionerClass. Any className is accepted while no
+// ProvisionerClass exists, so that clusters that declare none keep working.
+func checkProvisionerDeclared(ctx context.Context, c client.Reader, className string) error {
+	if className == "" {
+		return errors.New("provisionerClassName is required: set it, or annotate a ProvisionerClass as the default")
+	}
+	classes := &ProvisionerClassList{}
+	if err := c.List(ctx, classes); err != nil {
+		return fmt.Errorf("list provisioner classes: %w", err)
+	}
+	if len(classes.Items) == 0 {
+		return nil
+	}
+	provisioners := make([]string, 0, len(classes.Items))
+	for _, class := range classes.Items {
+		if class.Spec.Provisioner == className {
+			return nil
+		}
+		provisioners = append(provisioners, class.Spec.Provisioner)
+	}
+	sort.Strings(provisioners)
+	return fmt.Errorf("unknown provisioner class %q: no ProvisionerClass declares it, declared provisioners are %s", className, strings.Join(provisioners, ", "))
+}
+
+func setProvisionerClassLabel(obj *metav1.ObjectMeta, className string) {
+	if obj.Labels == nil {
+		obj.Labels = map[string]string{}
+	}
+	obj.Labels[LabelProvisionerClass] = ProvisionerClassLabelValue(className)
+}
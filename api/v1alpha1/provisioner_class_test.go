@@ -0,0 +1,168 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestProvisionerClassLabelValue(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		className string
+		want      string
+	}{
+		{
+			name:      "domain and name",
+			className: "core.rukpak.io/plain",
+			want:      "core.rukpak.io_plain",
+		},
+		{
+			name:      "name only",
+			className: "plain",
+			want:      "plain",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ProvisionerClassLabelValue(tt.className))
+		})
+	}
+
+	t.Run("too long", func(t *testing.T) {
+		a, b := ProvisionerClassLabelValue("example.com/"+strings.Repeat("a", 60)), ProvisionerClassLabelValue("example.com/"+strings.Repeat("b", 60))
+		require.Len(t, a, 32)
+		require.NotEqual(t, a, b)
+	})
+}
+
+func provisionerClass(name, provisioner string, isDefault bool) *ProvisionerClass {
+	class := &ProvisionerClass{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: ProvisionerClassSpec{Provisioner: provisioner}}
+	if isDefault {
+		class.Annotations = map[string]string{AnnotationDefaultProvisionerClass: "true"}
+	}
+	return class
+}
+
+func TestProvisionerClassDefaulter(t *testing.T) {
+	d := provisionerClassDefaulter{client: fake.NewClientBuilder().WithScheme(testScheme()).Build()}
+	b := &Bundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{LabelProvisionerClass: "other", "app": "test"}},
+		Spec:       BundleSpec{ProvisionerClassName: "core.rukpak.io/plain"},
+	}
+	require.NoError(t, d.Default(context.Background(), b))
+	require.Equal(t, map[string]string{LabelProvisionerClass: "core.rukpak.io_plain", "app": "test"}, b.Labels)
+
+	bi := &BundleInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec:       BundleInstanceSpec{ProvisionerClassName: "core.rukpak.io/plain"},
+	}
+	require.NoError(t, d.Default(context.Background(), bi))
+	require.Equal(t, map[string]string{LabelProvisionerClass: "core.rukpak.io_plain"}, bi.Labels)
+
+	require.Error(t, d.Default(context.Background(), &RukpakConfig{}))
+}
+
+func TestProvisionerClassDefaulterDefaultClass(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		classes   []client.Object
+		want      string
+		wantLabel string
+		wantErr   string
+	}{
+		{
+			name: "no provisioner classes",
+		},
+		{
+			name:    "no default provisioner class",
+			classes: []client.Object{provisionerClass("plain", "core.rukpak.io/plain", false)},
+		},
+		{
+			name: "default provisioner class",
+			classes: []client.Object{
+				provisionerClass("plain", "core.rukpak.io/plain", true),
+				provisionerClass("helm", "core.rukpak.io/helm", false),
+			},
+			want:      "core.rukpak.io/plain",
+			wantLabel: "core.rukpak.io_plain",
+		},
+		{
+			name: "several default provisioner classes",
+			classes: []client.Object{
+				provisionerClass("plain", "core.rukpak.io/plain", true),
+				provisionerClass("helm", "core.rukpak.io/helm", true),
+			},
+			wantErr: "provisioner classes helm, plain are all annotated as the default: annotate only one of them",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			d := provisionerClassDefaulter{client: fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(tt.classes...).Build()}
+			bi := &BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			err := d.Default(context.Background(), bi)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, bi.Spec.ProvisionerClassName)
+			require.Equal(t, tt.wantLabel, bi.Labels[LabelProvisionerClass])
+		})
+	}
+}
+
+func TestCheckProvisionerDeclared(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		classes   []client.Object
+		className string
+		wantErr   string
+	}{
+		{
+			name:      "no provisioner classes",
+			className: "example.com/other",
+		},
+		{
+			name:      "declared provisioner",
+			classes:   []client.Object{provisionerClass("plain", "core.rukpak.io/plain", false)},
+			className: "core.rukpak.io/plain",
+		},
+		{
+			name: "undeclared provisioner",
+			classes: []client.Object{
+				provisionerClass("plain", "core.rukpak.io/plain", false),
+				provisionerClass("helm", "core.rukpak.io/helm", false),
+			},
+			className: "example.com/other",
+			wantErr:   `unknown provisioner class "example.com/other": no ProvisionerClass declares it, declared provisioners are core.rukpak.io/helm, core.rukpak.io/plain`,
+		},
+		{
+			name:    "no provisioner class name",
+			wantErr: "provisionerClassName is required: set it, or annotate a ProvisionerClass as the default",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(tt.classes...).Build()
+			err := checkProvisionerDeclared(context.Background(), cl, tt.className)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestProvisionerClassValidator(t *testing.T) {
+	v := &provisionerClassValidator{client: fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(
+		provisionerClass("plain", "core.rukpak.io/plain", true),
+	).Build()}
+	require.NoError(t, v.ValidateCreate(context.Background(), provisionerClass("helm", "core.rukpak.io/helm", false)))
+	require.NoError(t, v.ValidateUpdate(context.Background(), provisionerClass("plain", "core.rukpak.io/plain", true), provisionerClass("plain", "core.rukpak.io/plain", false)))
+	require.EqualError(t, v.ValidateCreate(context.Background(), provisionerClass("plain-copy", "core.rukpak.io/plain", false)),
+		`provisioner "core.rukpak.io/plain" is already declared by provisioner class "plain"`)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AnnotationDefaultProvisionerClass marks the ProvisionerClass whose
+// provisioner is set as the provisionerClassName of Bundles and
+// BundleInstances that do not set one, when its value is "true".
+const AnnotationDefaultProvisionerClass = "provisionerclass.core.rukpak.io/is-default-class"
+
+// ProvisionerClassSpec declares a provisioner that Bundles and
+// BundleInstances can refer to.
+type ProvisionerClassSpec struct {
+	// Provisioner is the ID of the provisioner, which Bundles and
+	// BundleInstances set as their provisionerClassName to be reconciled by
+	// it, for example core.rukpak.io/plain. Each provisioner is declared by
+	// at most one ProvisionerClass.
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?/[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`
+	Provisioner string `json:"provisioner"`
+
+	// Parameters configure the provisioner. They are interpreted by the
+	// provisioner rather than by the rukpak core.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Provisioner",type=string,JSONPath=`.spec.provisioner`
+//+kubebuilder:printcolumn:name=Age,type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ProvisionerClass is the Schema for the provisionerclasses API. Like a
+// StorageClass, it declares a provisioner that is available in the cluster.
+// Once any ProvisionerClass exists, Bundles and BundleInstances are only
+// created with the provisionerClassName of a declared provisioner.
+type ProvisionerClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ProvisionerClassSpec `json:"spec"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProvisionerClassList contains a list of ProvisionerClass
+type ProvisionerClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProvisionerClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProvisionerClass{}, &ProvisionerClassList{})
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var provisionerclasslog = logf.Log.WithName("provisionerclass-resource")
+
+// SetupWebhookWithManager registers the validating webhook of
+// ProvisionerClasses with mgr.
+func (r *ProvisionerClass) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&provisionerClassValidator{client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-core-rukpak-io-v1alpha1-provisionerclass,mutating=false,failurePolicy=ignore,sideEffects=None,groups=core.rukpak.io,resources=provisionerclasses,verbs=create;update,versions=v1alpha1,name=vprovisionerclass.core.rukpak.io,admissionReviewVersions=v1
+
+// provisionerClassValidator validates ProvisionerClasses, so that every
+// provisioner is declared by a single ProvisionerClass whose parameters apply
+// to it. Its webhook ignores failures, so that provisioners can declare their
+// ProvisionerClass while the rukpak core is still starting.
+type provisionerClassValidator struct {
+	client client.Reader
+}
+
+var _ admission.CustomValidator = &provisionerClassValidator{}
+
+// ValidateCreate implements admission.CustomValidator.
+func (v *provisionerClassValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	r := obj.(*ProvisionerClass)
+	provisionerclasslog.V(1).Info("validate create", "name", r.Name)
+
+	return v.checkUnique(ctx, r)
+}
+
+// ValidateUpdate implements admission.CustomValidator.
+func (v *provisionerClassValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) error {
+	r := newObj.(*ProvisionerClass)
+	provisionerclasslog.V(1).Info("validate update", "name", r.Name)
+
+	return v.checkUnique(ctx, r)
+}
+
+// ValidateDelete implements admission.CustomValidator.
+func (v *provisionerClassValidator) ValidateDelete(_ context.Context, obj runtime.Object) error {
+	r := obj.(*ProvisionerClass)
+	provisionerclasslog.V(1).Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+// checkUnique checks that no other ProvisionerClass declares the provisioner
+// of r.
+func (v *provisionerClassValidator) checkUnique(ctx context.Context, r *ProvisionerClass) error {
+	classes := &ProvisionerClassList{}
+	if err := v.client.List(ctx, classes); err != nil {
+		return fmt.Errorf("list provisioner classes: %w", err)
+	}
+	for _, class := range classes.Items {
+		if class.Name != r.Name && class.Spec.Provisioner == r.Spec.Provisioner {
+			return fmt.Errorf("provisioner %q is already declared by provisioner class %q", r.Spec.Provisioner, class.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RukpakConfigName is the name of the RukpakConfig that configures the
+// defaults of every provisioner. RukpakConfigs with other names are ignored.
+const RukpakConfigName = "cluster"
+
+// RukpakConfigSpec defines the cluster-wide defaults of the provisioners.
+type RukpakConfigSpec struct {
+	// UnpackResources are the compute resources of the containers of the pods
+	// that unpack Bundles. The resources of unpack pods that already exist
+	// are left unchanged.
+	UnpackResources *corev1.ResourceRequirements `json:"unpackResources,omitempty"`
+
+	// UnpackNodeSelector constrains the pods that unpack Bundles to the nodes
+	// with these labels. Unpack pods that already exist are left unchanged.
+	UnpackNodeSelector map[string]string `json:"unpackNodeSelector,omitempty"`
+
+	// UnpackTolerations are the tolerations of the pods that unpack Bundles.
+	// Unpack pods that already exist are left unchanged.
+	UnpackTolerations []corev1.Toleration `json:"unpackTolerations,omitempty"`
+
+	// UnpackSecurityContext is the security context of the pods that unpack
+	// Bundles. Unpack pods that already exist are left unchanged.
+	UnpackSecurityContext *corev1.PodSecurityContext `json:"unpackSecurityContext,omitempty"`
+
+	// ResyncInterval is the interval at which the installed content of every
+	// BundleInstance is reconciled in the absence of any changes. ResyncInterval
+	// is optional and if not set content is only reconciled when it changes,
+	// and at the sync period of the provisioner.
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+
+	// DefaultDeletionPolicy is the deletion policy of the BundleInstances
+	// that do not set their own deletionPolicy. It is applied when a
+	// BundleInstance is deleted, so that changes to it also apply to the
+	// BundleInstances that already exist. DefaultDeletionPolicy is optional
+	// and defaults to Delete.
+	//+kubebuilder:validation:Enum=Delete;Orphan;Retain
+	//+kubebuilder:default=Delete
+	DefaultDeletionPolicy string `json:"defaultDeletionPolicy,omitempty"`
+
+	// Metrics configures the metrics of BundleInstances that the
+	// provisioners expose. Metrics is optional.
+	Metrics *MetricsConfig `json:"metrics,omitempty"`
+
+	// ServiceAccounts are the ServiceAccounts that BundleInstances may
+	// install their content as with their serviceAccountName. The
+	// provisioners only impersonate the ServiceAccounts that one of them
+	// selects, so that their permission to impersonate ServiceAccounts cannot
+	// be used to install content with the permissions of any other
+	// ServiceAccount. ServiceAccounts is optional and if not set no
+	// BundleInstance can set a serviceAccountName.
+	ServiceAccounts []ServiceAccountSelector `json:"serviceAccounts,omitempty"`
+
+	// CABundle refers to the certificate authorities that are trusted to
+	// verify the registries and repositories of the image and git sources of
+	// Bundles that do not set their own CABundle.
+	CABundle *CABundleReference `json:"caBundle,omitempty"`
+
+	// DigestOnly refuses to unpack Bundles with an image source that refers
+	// to a tag rather than a digest, so that only reproducible content is
+	// unpacked. Bundles that are already unpacked are left unchanged.
+	DigestOnly bool `json:"digestOnly,omitempty"`
+
+	// BundleGC configures the garbage collection of the Bundles that no
+	// BundleInstance references. BundleGC is optional and if not set Bundles
+	// are never garbage collected.
+	BundleGC *BundleGCPolicy `json:"bundleGC,omitempty"`
+
+	// PolicyWebhooks are the external policy services that admit the objects
+	// of a bundle before a BundleInstance installs or upgrades to them. A
+	// bundle with objects that violate the policies of any webhook is not
+	// installed.
+	PolicyWebhooks []PolicyWebhook `json:"policyWebhooks,omitempty"`
+}
+
+const (
+	// PolicyFailurePolicyFail blocks installs when a policy webhook errors.
+	PolicyFailurePolicyFail = "Fail"
+	// PolicyFailurePolicyIgnore installs bundles regardless of the errors of
+	// a policy webhook.
+	PolicyFailurePolicyIgnore = "Ignore"
+)
+
+// PolicyWebhook is an external policy service, such as OPA, that the objects
+// of bundles are posted to before they are installed, and that responds with
+// the objects that violate its policies.
+type PolicyWebhook struct {
+	// Name identifies the webhook in the Installed conditions of the
+	// BundleInstances whose objects violate its policies.
+	Name string `json:"name"`
+
+	// URL is the https URL that the objects of bundles are posted to.
+	//+kubebuilder:validation:Pattern=`^https://`
+	URL string `json:"url"`
+
+	// CAData are the PEM-encoded certificate authorities that are trusted to
+	// verify the serving certificate of the webhook, along with the system
+	// ones. CAData is optional.
+	CAData []byte `json:"caData,omitempty"`
+
+	// Timeout is how long a response of the webhook is waited for. Timeout
+	// is optional and defaults to 10 seconds.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// FailurePolicy is what happens to the installs of bundles when the
+	// webhook cannot be reached or responds with an error: Fail blocks them
+	// until the webhook responds, and Ignore installs the bundles. It is
+	// optional and defaults to Fail.
+	//+kubebuilder:validation:Enum=Fail;Ignore
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+// ServiceAccountSelector selects the ServiceAccounts of a namespace that
+// BundleInstances may install their content as.
+type ServiceAccountSelector struct {
+	// Namespace is the namespace of the ServiceAccounts.
+	//+kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// Name is the name of the ServiceAccount. Name is optional and if not set
+	// every ServiceAccount of the namespace is selected.
+	Name string `json:"name,omitempty"`
+}
+
+// MetricsConfig configures the metrics of BundleInstances that the
+// provisioners expose on their metrics endpoint.
+type MetricsConfig struct {
+	// PerBundleInstance labels the metrics of BundleInstances with their
+	// name, so that the installs and upgrades of each BundleInstance can be
+	// told apart. As it adds time series for every BundleInstance,
+	// PerBundleInstance is optional and defaults to false, which aggregates
+	// the metrics of all BundleInstances.
+	//+kubebuilder:default=false
+	PerBundleInstance bool `json:"perBundleInstance,omitempty"`
+}
+
+// BundleGCPolicy configures which of the Bundles that no BundleInstance
+// references are deleted by their provisioners. When both RetentionPeriod and
+// KeepLatest are set, a Bundle is deleted once neither of them keeps it.
+type BundleGCPolicy struct {
+	// RetentionPeriod is how long after their creation unreferenced Bundles
+	// are kept. RetentionPeriod is optional and if not set unreferenced
+	// Bundles are only deleted by KeepLatest.
+	RetentionPeriod *metav1.Duration `json:"retentionPeriod,omitempty"`
+
+	// KeepLatest is the number of the most recently created unreferenced
+	// Bundles that are kept in each group of Bundles. KeepLatest is optional
+	// and if not set unreferenced Bundles are only deleted by
+	// RetentionPeriod.
+	//+kubebuilder:validation:Minimum=0
+	KeepLatest *int32 `json:"keepLatest,omitempty"`
+
+	// GroupByLabel is the key of the label by whose value Bundles are grouped
+	// for KeepLatest, for example the label of the application a pipeline
+	// builds Bundles for. Bundles without the label form a group of their
+	// own. GroupByLabel is optional and if not set all the Bundles of a
+	// provisioner form one group.
+	GroupByLabel string `json:"groupByLabel,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// RukpakConfig is the Schema for the rukpakconfigs API. The provisioners read
+// the RukpakConfig named "cluster" whenever they reconcile, so changes to it
+// take effect without restarting them.
+type RukpakConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RukpakConfigSpec `json:"spec"`
+}
+
+//+kubebuilder:object:root=true
+
+// RukpakConfigList contains a list of RukpakConfig
+type RukpakConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RukpakConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RukpakConfig{}, &RukpakConfigList{})
+}
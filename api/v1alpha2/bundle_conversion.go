@@ -0,0 +1,106 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+var _ conversion.Convertible = &Bundle{}
+
+// ConvertTo converts r to the v1alpha1 Bundle hub, in which Bundles are
+// stored.
+func (r *Bundle) ConvertTo(hub conversion.Hub) error {
+	dst := hub.(*v1alpha1.Bundle)
+	dst.ObjectMeta = r.ObjectMeta
+	dst.Spec = v1alpha1.BundleSpec{
+		ProvisionerClassName: r.Spec.ProvisionerClassName,
+		Source:               r.Spec.Source.toHub(),
+		UnpackRetryPolicy:    r.Spec.UnpackRetryPolicy,
+	}
+	dst.Status = v1alpha1.BundleStatus{
+		Info:               r.Status.Info,
+		Phase:              r.Status.Phase,
+		Digest:             r.Status.Digest,
+		ObservedGeneration: r.Status.ObservedGeneration,
+		Conditions:         r.Status.Conditions,
+		UnpackRetries:      r.Status.UnpackRetries,
+		ValidationErrors:   r.Status.ValidationErrors,
+	}
+	if src := r.Status.ResolvedSource; src != nil {
+		resolved := src.toHub()
+		dst.Status.ResolvedSource = &resolved
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 Bundle hub to r. The source type of hub
+// is kept as-is, even when it is not one of the source types of v1alpha2, so
+// that the conversion is lossless.
+func (r *Bundle) ConvertFrom(hub conversion.Hub) error {
+	src := hub.(*v1alpha1.Bundle)
+	r.ObjectMeta = src.ObjectMeta
+	r.Spec = BundleSpec{
+		ProvisionerClassName: src.Spec.ProvisionerClassName,
+		Source:               sourceFromHub(src.Spec.Source),
+		UnpackRetryPolicy:    src.Spec.UnpackRetryPolicy,
+	}
+	r.Status = BundleStatus{
+		Info:               src.Status.Info,
+		Phase:              src.Status.Phase,
+		Digest:             src.Status.Digest,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         src.Status.Conditions,
+		UnpackRetries:      src.Status.UnpackRetries,
+		ValidationErrors:   src.Status.ValidationErrors,
+	}
+	if hubSource := src.Status.ResolvedSource; hubSource != nil {
+		resolved := sourceFromHub(*hubSource)
+		r.Status.ResolvedSource = &resolved
+	}
+	return nil
+}
+
+func (s BundleSource) toHub() v1alpha1.BundleSource {
+	return v1alpha1.BundleSource{
+		Type:               string(s.Type),
+		Image:              s.Image,
+		Git:                s.Git,
+		Local:              s.Local,
+		Helm:               s.Helm,
+		PollInterval:       s.PollInterval,
+		UnpackPodOverrides: s.UnpackPodOverrides,
+		CABundle:           s.CABundle,
+		Timeout:            s.Timeout,
+	}
+}
+
+func sourceFromHub(s v1alpha1.BundleSource) BundleSource {
+	return BundleSource{
+		Type:               SourceType(s.Type),
+		Image:              s.Image,
+		Git:                s.Git,
+		Local:              s.Local,
+		Helm:               s.Helm,
+		PollInterval:       s.PollInterval,
+		UnpackPodOverrides: s.UnpackPodOverrides,
+		CABundle:           s.CABundle,
+		Timeout:            s.Timeout,
+	}
+}
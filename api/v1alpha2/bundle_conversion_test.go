@@ -0,0 +1,66 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestBundleConversion(t *testing.T) {
+	interval := &metav1.Duration{Duration: time.Minute}
+	retries := int32(3)
+	hub := &v1alpha1.Bundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 2, Labels: map[string]string{"a": "b"}},
+		Spec: v1alpha1.BundleSpec{
+			ProvisionerClassName: "core.rukpak.io/plain",
+			Source: v1alpha1.BundleSource{
+				Type:         v1alpha1.SourceTypeGit,
+				Git:          &v1alpha1.GitSource{Repository: "https://github.com/a/b", Ref: v1alpha1.GitRef{Branch: "main"}},
+				PollInterval: interval,
+				Timeout:      &metav1.Duration{Duration: 10 * time.Minute},
+			},
+			UnpackRetryPolicy: &v1alpha1.UnpackRetryPolicy{MaxRetries: &retries},
+		},
+		Status: v1alpha1.BundleStatus{
+			Phase:              v1alpha1.PhaseUnpacked,
+			Digest:             "abc",
+			ObservedGeneration: 2,
+			Conditions:         []metav1.Condition{{Type: v1alpha1.TypeUnpacked, Status: metav1.ConditionTrue, Reason: v1alpha1.ReasonUnpackSuccessful}},
+			ResolvedSource: &v1alpha1.BundleSource{
+				Type: v1alpha1.SourceTypeGit,
+				Git:  &v1alpha1.GitSource{Repository: "https://github.com/a/b", Ref: v1alpha1.GitRef{Commit: "123"}},
+			},
+		},
+	}
+
+	spoke := &Bundle{}
+	require.NoError(t, spoke.ConvertFrom(hub.DeepCopy()))
+	require.Equal(t, SourceTypeGit, spoke.Spec.Source.Type)
+	require.Equal(t, hub.Spec.Source.Git, spoke.Spec.Source.Git)
+	require.Equal(t, SourceTypeGit, spoke.Status.ResolvedSource.Type)
+	require.Equal(t, "123", spoke.Status.ResolvedSource.Git.Ref.Commit)
+
+	roundTripped := &v1alpha1.Bundle{}
+	require.NoError(t, spoke.ConvertTo(roundTripped))
+	require.Equal(t, hub, roundTripped)
+}
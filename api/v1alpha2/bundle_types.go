@@ -0,0 +1,141 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+// SourceType is the kind of content that a BundleSource refers to, and names
+// the field of the BundleSource that is set.
+// +kubebuilder:validation:Enum=image;git;local;upload;helm
+type SourceType string
+
+const (
+	SourceTypeImage SourceType = v1alpha1.SourceTypeImage
+	SourceTypeGit   SourceType = v1alpha1.SourceTypeGit
+	SourceTypeLocal SourceType = v1alpha1.SourceTypeLocal
+	// SourceTypeUpload is the source type of Bundles whose content is
+	// uploaded to the upload endpoint of the rukpak core. It has no field.
+	SourceTypeUpload SourceType = v1alpha1.SourceTypeUpload
+	SourceTypeHelm   SourceType = v1alpha1.SourceTypeHelm
+)
+
+// BundleSpec defines the desired state of Bundle
+type BundleSpec struct {
+	// ProvisionerClassName sets the name of the provisioner that should reconcile this Bundle.
+	// ProvisionerClassName is optional and if not set defaults to the provisioner of the
+	// default ProvisionerClass.
+	ProvisionerClassName string `json:"provisionerClassName,omitempty"`
+	// Source defines the configuration for the underlying Bundle content.
+	Source BundleSource `json:"source"`
+	// UnpackRetryPolicy configures how failed unpacks of image and git
+	// sources are retried. UnpackRetryPolicy is optional and if not set
+	// failed unpacks are retried indefinitely.
+	UnpackRetryPolicy *v1alpha1.UnpackRetryPolicy `json:"unpackRetryPolicy,omitempty"`
+}
+
+// BundleSource is the content of a Bundle. Type is the discriminator of its
+// members: exactly the field that it names is set, and no field is set for
+// the upload type. The members are unchanged from v1alpha1.
+type BundleSource struct {
+	// Type defines the kind of Bundle content being sourced.
+	Type SourceType `json:"type"`
+	// Image is the bundle image that backs the content of this bundle.
+	Image *v1alpha1.ImageSource `json:"image,omitempty"`
+	// Git is the git repository that backs the content of this Bundle.
+	Git *v1alpha1.GitSource `json:"git,omitempty"`
+	// Local is the ConfigMap that backs the content of this Bundle.
+	Local *v1alpha1.LocalSource `json:"local,omitempty"`
+	// Helm is the chart of a Helm chart repository that backs the content of
+	// this Bundle.
+	Helm *v1alpha1.HelmSource `json:"helm,omitempty"`
+	// PollInterval configures how often a mutable image tag, git branch or
+	// chart version is resolved again, so that the Bundle is unpacked again
+	// when it refers to new content. PollInterval is optional and if not set the source is only
+	// resolved when the Bundle is first unpacked. It has no effect on sources
+	// that are pinned to an image digest, git tag or git commit.
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+	// UnpackPodOverrides configure the pod that unpacks an image or git
+	// source, overriding the defaults of the RukpakConfig field by field.
+	// UnpackPodOverrides is optional, and is only applied when the unpack pod
+	// is created.
+	UnpackPodOverrides *v1alpha1.UnpackPodOverrides `json:"unpackPodOverrides,omitempty"`
+	// CABundle refers to the certificate authorities that are trusted to
+	// verify the registry of an image source or the repository of a git
+	// source. CABundle is optional, and overrides the CABundle of the
+	// RukpakConfig.
+	CABundle *v1alpha1.CABundleReference `json:"caBundle,omitempty"`
+	// Timeout is the time after which the pod that unpacks an image or git
+	// source is failed when it has not completed. Timeout is optional and if
+	// not set the unpack pod runs until it completes or fails.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// BundleStatus defines the observed state of Bundle
+type BundleStatus struct {
+	// Info describes the unpacked content of the Bundle, and lists the
+	// objects of its manifests. It is unset until the Bundle is unpacked.
+	Info               *v1alpha1.BundleInfo `json:"info,omitempty"`
+	Phase              string               `json:"phase,omitempty"`
+	Digest             string               `json:"digest,omitempty"`
+	ObservedGeneration int64                `json:"observedGeneration,omitempty"`
+	Conditions         []metav1.Condition   `json:"conditions,omitempty"`
+	// UnpackRetries is the number of times a failed unpack has been retried
+	// under the UnpackRetryPolicy of the Bundle since it was last unpacked.
+	UnpackRetries int32 `json:"unpackRetries,omitempty"`
+	// ResolvedSource is the source of the Bundle pinned to the image digest,
+	// or the commit of a polled git branch, that was unpacked. It is only set
+	// when that revision is known.
+	ResolvedSource *BundleSource `json:"resolvedSource,omitempty"`
+	// ValidationErrors are the errors found in the manifests of the Bundle
+	// when it was last unpacked, by manifest file. They are unset when its
+	// manifests are valid.
+	ValidationErrors []v1alpha1.BundleValidationError `json:"validationErrors,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name=Type,type=string,JSONPath=`.spec.source.type`
+//+kubebuilder:printcolumn:name=Phase,type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name=Ready,type=string,JSONPath=`.status.conditions[?(.type=="Ready")].status`
+//+kubebuilder:printcolumn:name=Age,type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Bundle is the Schema for the bundles API
+type Bundle struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BundleSpec   `json:"spec"`
+	Status BundleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BundleList contains a list of Bundle
+type BundleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Bundle `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Bundle{}, &BundleList{})
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the validating webhook of v1alpha2
+// Bundles with mgr. Bundles are also validated and defaulted by the webhooks
+// of v1alpha1 Bundles, which the API server converts them to. This one only
+// checks the rules of v1alpha2 that v1alpha1 does not have.
+func (r *Bundle) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(bundleValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-core-rukpak-io-v1alpha2-bundle,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.rukpak.io,resources=bundles,verbs=create;update,versions=v1alpha2,name=v1alpha2.core.rukpak.io,admissionReviewVersions=v1
+
+// bundleValidator validates that the source type of a Bundle is the
+// discriminator of its source.
+type bundleValidator struct{}
+
+var _ admission.CustomValidator = bundleValidator{}
+
+// ValidateCreate implements admission.CustomValidator.
+func (bundleValidator) ValidateCreate(_ context.Context, obj runtime.Object) error {
+	return checkSource(obj.(*Bundle).Spec.Source)
+}
+
+// ValidateUpdate implements admission.CustomValidator.
+func (bundleValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) error {
+	return checkSource(newObj.(*Bundle).Spec.Source)
+}
+
+// ValidateDelete implements admission.CustomValidator.
+func (bundleValidator) ValidateDelete(context.Context, runtime.Object) error {
+	return nil
+}
+
+// checkSource checks that source sets exactly the field that its type names,
+// and no field for the upload type.
+func checkSource(source BundleSource) error {
+	set := map[SourceType]bool{
+		SourceTypeImage: source.Image != nil,
+		SourceTypeGit:   source.Git != nil,
+		SourceTypeLocal: source.Local != nil,
+		SourceTypeHelm:  source.Helm != nil,
+	}
+	var others []string
+	for _, t := range []SourceType{SourceTypeImage, SourceTypeGit, SourceTypeLocal, SourceTypeHelm} {
+		if set[t] && t != source.Type {
+			others = append(others, fmt.Sprintf("source.%s", t))
+		}
+	}
+	if source.Type != SourceTypeUpload && !set[source.Type] {
+		return fmt.Errorf("source of type %q must set the source.%s field", source.Type, source.Type)
+	}
+	if len(others) > 0 {
+		return fmt.Errorf("source of type %q must not set %s", source.Type, strings.Join(others, ", "))
+	}
+	return nil
+}
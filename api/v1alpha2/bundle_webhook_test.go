@@ -0,0 +1,69 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestCheckSource(t *testing.T) {
+	image := &v1alpha1.ImageSource{Ref: "quay.io/a/b:c"}
+	git := &v1alpha1.GitSource{Repository: "https://github.com/a/b", Ref: v1alpha1.GitRef{Branch: "main"}}
+
+	for _, tt := range []struct {
+		name    string
+		source  BundleSource
+		wantErr string
+	}{
+		{
+			name:   "image",
+			source: BundleSource{Type: SourceTypeImage, Image: image},
+		},
+		{
+			name:   "upload",
+			source: BundleSource{Type: SourceTypeUpload},
+		},
+		{
+			name:    "missing member",
+			source:  BundleSource{Type: SourceTypeGit, Image: image},
+			wantErr: `source of type "git" must set the source.git field`,
+		},
+		{
+			name:    "other member",
+			source:  BundleSource{Type: SourceTypeImage, Image: image, Git: git},
+			wantErr: `source of type "image" must not set source.git`,
+		},
+		{
+			name:    "upload with member",
+			source:  BundleSource{Type: SourceTypeUpload, Image: image},
+			wantErr: `source of type "upload" must not set source.image`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSource(tt.source)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -28,10 +30,17 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/internal/content"
+	"github.com/operator-framework/rukpak/internal/digest"
+	"github.com/operator-framework/rukpak/internal/features"
+	"github.com/operator-framework/rukpak/internal/storage"
+	"github.com/operator-framework/rukpak/internal/util"
 	"github.com/operator-framework/rukpak/internal/version"
 )
 
@@ -43,26 +52,33 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(rukpakv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(rukpakv1alpha2.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
-	var enableLeaderElection bool
+	var leaderElectionOpts util.LeaderElectionOptions
 	var probeAddr string
 	var systemNamespace string
+	var webhookCertDir string
 	var rukpakVersion bool
+	var storageOpts storage.Options
+	var digestOpts digest.Options
+	var kubeAPILimit util.ClientRateLimit
+	featureGate := features.NewGate()
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&systemNamespace, "system-namespace", "rukpak-system", "Configures the namespace that gets used to deploy system resources.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
-		"Enable leader election for controller manager. "+
-			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs"),
+		"The directory of the serving certificate of the webhook server.")
+	leaderElectionOpts.BindFlags(flag.CommandLine, "core.rukpak.io")
 	flag.BoolVar(&rukpakVersion, "version", false, "Displays rukpak version information")
-	opts := zap.Options{
-		Development: true,
-	}
-	opts.BindFlags(flag.CommandLine)
+	storageOpts.BindFlags(flag.CommandLine)
+	digestOpts.BindFlags(flag.CommandLine)
+	kubeAPILimit.BindFlags(flag.CommandLine, "kube-api", "all clients of the core")
+	featureGate.BindFlags(flag.CommandLine)
+	logOpts := util.BindLogFlags(flag.CommandLine)
 	flag.Parse()
 
 	if rukpakVersion {
@@ -70,23 +86,33 @@ func main() {
 		os.Exit(0)
 	}
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(logOpts)))
 	setupLog.Info("starting up the rukpak core webhook", "Git commit", version.String())
 
-	cfg := ctrl.GetConfigOrDie()
+	digestAlgorithm, err := digestOpts.New()
+	if err != nil {
+		setupLog.Error(err, "unable to configure digest algorithm")
+		os.Exit(1)
+	}
+	storageOpts.Algorithm = digestAlgorithm
+
+	cfg := util.RateLimitedConfig(ctrl.GetConfigOrDie(), kubeAPILimit)
 	dependentRequirement, err := labels.NewRequirement("api.core.rukpak.io/owner-kind", selection.In, []string{"Bundle"})
 	if err != nil {
 		setupLog.Error(err, "unable to create dependent label selector for cache")
 		os.Exit(1)
 	}
 	dependentSelector := labels.NewSelector().Add(*dependentRequirement)
-	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+	if err := leaderElectionOpts.Validate(); err != nil {
+		setupLog.Error(err, "invalid leader election options")
+		os.Exit(1)
+	}
+	mgrOpts := ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
 		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "510f803c.olm.operatorframework.io",
+		CertDir:                webhookCertDir,
 		NewCache: cache.BuilderWithOptions(cache.Options{
 			SelectorsByObject: cache.SelectorsByObject{
 				&rukpakv1alpha1.Bundle{}: {},
@@ -95,16 +121,69 @@ func main() {
 				Label: dependentSelector,
 			},
 		}),
-	})
+	}
+	leaderElectionOpts.Apply(&mgrOpts)
+	mgr, err := ctrl.NewManager(cfg, mgrOpts)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if err = (&rukpakv1alpha1.Bundle{}).SetupWebhookWithManager(mgr); err != nil {
+	ns := util.PodNamespace(systemNamespace)
+	if err = (&rukpakv1alpha1.Bundle{}).SetupWebhookWithManager(mgr, ns); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Bundle")
 		os.Exit(1)
 	}
+	if err = (&rukpakv1alpha2.Bundle{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "Bundle", "version", rukpakv1alpha2.GroupVersion.Version)
+		os.Exit(1)
+	}
+	if err = (&rukpakv1alpha1.BundleInstance{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "BundleInstance")
+		os.Exit(1)
+	}
+	if err = (&rukpakv1alpha1.ProvisionerClass{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ProvisionerClass")
+		os.Exit(1)
+	}
+
+	// Bundle content is only read on demand, so it is read directly from the
+	// API server rather than being cached.
+	apiClient, err := client.New(cfg, client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		setupLog.Error(err, "unable to create client for content server")
+		os.Exit(1)
+	}
+	bundleStorage, err := storageOpts.New(context.Background(), apiClient, apiClient, ns, "bundle-")
+	if err != nil {
+		setupLog.Error(err, "unable to create bundle storage")
+		os.Exit(1)
+	}
+	reportStorage, err := storageOpts.New(context.Background(), apiClient, apiClient, ns, "install-report-")
+	if err != nil {
+		setupLog.Error(err, "unable to create install report storage")
+		os.Exit(1)
+	}
+	// Without upload storage, the content server rejects uploads.
+	var uploadStorage storage.Storage
+	if featureGate.Enabled(features.UploadSource) {
+		uploadStorage, err = storageOpts.New(context.Background(), apiClient, apiClient, ns, "upload-")
+		if err != nil {
+			setupLog.Error(err, "unable to create upload storage")
+			os.Exit(1)
+		}
+	}
+	contentServer := &content.Server{
+		Client:          apiClient,
+		BundleStorage:   bundleStorage,
+		ReportStorage:   reportStorage,
+		UploadStorage:   uploadStorage,
+		DigestAlgorithm: digestAlgorithm,
+		SecretNamespace: ns,
+		Authorizer:      &content.DelegatingAuthorizer{Client: apiClient},
+	}
+	mgr.GetWebhookServer().Register(content.BundlesPath, contentServer)
+	mgr.GetWebhookServer().Register(content.BundleInstancesPath, contentServer)
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
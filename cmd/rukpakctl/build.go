@@ -0,0 +1,128 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/rukpak/internal/manifests"
+)
+
+// bundleDockerfile builds a bundle image that contains only its manifests,
+// like the testdata bundle images.
+var bundleDockerfile = fmt.Sprintf("FROM scratch\nCOPY %s /%s\n", manifests.Dir, manifests.Dir)
+
+func newBuildCmd() *cobra.Command {
+	var output, tag, containerRuntime string
+	cmd := &cobra.Command{
+		Use:   "build <bundle-dir>",
+		Short: "Package a plain bundle directory into a bundle image or a tarball",
+		Long: "Package a plain bundle directory into a bundle image or a tarball. " +
+			"The bundle is validated before it is packaged.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundleDir := args[0]
+			if (output == "") == (tag == "") {
+				return errors.New("exactly one of --output or --tag is required")
+			}
+			if _, err := manifests.Validate(os.DirFS(bundleDir)); err != nil {
+				return err
+			}
+			if output != "" {
+				if err := writeTarball(output, bundleDir); err != nil {
+					return fmt.Errorf("write bundle tarball: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "wrote bundle %q to %s\n", bundleDir, output)
+				return nil
+			}
+			build := exec.CommandContext(cmd.Context(), containerRuntime, "build", "-f", "-", "-t", tag, bundleDir)
+			build.Stdin = strings.NewReader(bundleDockerfile)
+			build.Stdout = cmd.OutOrStdout()
+			build.Stderr = cmd.ErrOrStderr()
+			if err := build.Run(); err != nil {
+				return fmt.Errorf("build bundle image %q: %w", tag, err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "path of a gzip-compressed tarball to write the bundle to")
+	cmd.Flags().StringVarP(&tag, "tag", "t", "", "reference of a bundle image to build")
+	cmd.Flags().StringVar(&containerRuntime, "container-runtime", "docker", "container runtime used to build bundle images")
+	return cmd
+}
+
+// writeTarball writes the manifests of the bundle in bundleDir to a
+// gzip-compressed tarball at path, in the layout of the unpacked content of
+// a bundle image.
+func writeTarball(path, bundleDir string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return encodeTarball(f, bundleDir)
+}
+
+// encodeTarball writes the manifests of the bundle in bundleDir to w as a
+// gzip-compressed tarball.
+func encodeTarball(w io.Writer, bundleDir string) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+
+	bundleFS := os.DirFS(bundleDir)
+	if err := fs.WalkDir(bundleFS, manifests.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&os.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("get file info for %q: %w", path, err)
+		}
+		h, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return fmt.Errorf("build tar file info header for %q: %w", path, err)
+		}
+		h.Uid = 0
+		h.Gid = 0
+		h.Uname = ""
+		h.Gname = ""
+		h.Name = filepath.ToSlash(path)
+		if err := tw.WriteHeader(h); err != nil {
+			return fmt.Errorf("write tar header for %q: %w", path, err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+		src, err := bundleFS.Open(path)
+		if err != nil {
+			return fmt.Errorf("open file %q: %w", path, err)
+		}
+		defer src.Close()
+		if _, err := io.Copy(tw, src); err != nil {
+			return fmt.Errorf("write tar data for %q: %w", path, err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
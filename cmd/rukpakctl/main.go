@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/version"
+)
+
+func main() {
+	var rukpakVersion bool
+	cmd := &cobra.Command{
+		Use:          "rukpakctl",
+		Short:        "Build, validate and inspect rukpak bundles",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if rukpakVersion {
+				fmt.Printf("Git commit: %s\n", version.String())
+				return nil
+			}
+			return cmd.Help()
+		},
+	}
+	cmd.Flags().BoolVar(&rukpakVersion, "version", false, "displays rukpak version information")
+	// Register the --kubeconfig flag of controller-runtime.
+	cmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
+	cmd.AddCommand(newBuildCmd(), newValidateCmd(), newStatusCmd(), newRunCmd(), newUploadCmd(), newPromoteCmd())
+
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newClient returns a client of the cluster of the current kubeconfig context.
+func newClient() (client.Client, error) {
+	return newClientForContext("")
+}
+
+// newClientForContext returns a client of the cluster of the named kubeconfig
+// context, or of the current context when kubeContext is empty.
+func newClientForContext(kubeContext string) (client.Client, error) {
+	cfg, err := config.GetConfigWithContext(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := rukpakv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
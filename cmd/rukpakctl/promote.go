@@ -0,0 +1,233 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+// promotedFromAnnotation is set on promoted Bundles to the name and digest of
+// the Bundle whose content they promote.
+const promotedFromAnnotation = "core.rukpak.io/promoted-from"
+
+type promoteOptions struct {
+	name                  string
+	toContext             string
+	provisionerClassName  string
+	targetNamespace       string
+	namespace             string
+	url                   string
+	token                 string
+	insecureSkipTLSVerify bool
+	timeout               time.Duration
+}
+
+func newPromoteCmd() *cobra.Command {
+	o := promoteOptions{}
+	cmd := &cobra.Command{
+		Use:   "promote <bundleinstance>",
+		Short: "Install the exact content of an installed BundleInstance with another provisioner class or in another cluster",
+		Long: "Install the exact content that a BundleInstance has installed with another provisioner class, or in the cluster of " +
+			"another kubeconfig context, and wait until it is installed. The promoted Bundle has the resolved source of the installed Bundle, " +
+			"which is pinned to the unpacked image digest or git commit. The content of Bundles without a resolved source is " +
+			"downloaded from the content server of the rukpak core at --url, and copied to a Bundle with a local source.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.name == "" {
+				o.name = args[0]
+			}
+			if o.toContext == "" && o.name == args[0] {
+				return errors.New("promoting a BundleInstance within the same cluster requires a different --name")
+			}
+			if o.token == "" {
+				cfg, err := ctrl.GetConfig()
+				if err != nil {
+					return err
+				}
+				o.token = cfg.BearerToken
+			}
+			from, err := newClient()
+			if err != nil {
+				return err
+			}
+			to, err := newClientForContext(o.toContext)
+			if err != nil {
+				return err
+			}
+			return promote(cmd.Context(), cmd.OutOrStdout(), from, to, args[0], o)
+		},
+	}
+	cmd.Flags().StringVar(&o.name, "name", "", "name of the promoted BundleInstance, and prefix of the name of its Bundle; defaults to the name of the promoted BundleInstance")
+	cmd.Flags().StringVar(&o.toContext, "to-context", "", "kubeconfig context of the cluster to promote to; defaults to the current context")
+	cmd.Flags().StringVar(&o.provisionerClassName, "to-provisioner-class", "", "provisioner class of the promoted Bundle and BundleInstance; defaults to the provisioner class of the BundleInstance")
+	cmd.Flags().StringVar(&o.targetNamespace, "target-namespace", "", "target namespace of the promoted BundleInstance; defaults to the target namespace of the BundleInstance")
+	cmd.Flags().StringVar(&o.namespace, "namespace", "rukpak-system", "namespace of the ConfigMap that content without a pinnable source is copied to")
+	cmd.Flags().StringVar(&o.url, "url", "", "URL of the content server of the rukpak core of the current context, required to promote content without a pinnable source")
+	cmd.Flags().StringVar(&o.token, "token", "", "bearer token used to download content; defaults to the token of the current kubeconfig context")
+	cmd.Flags().BoolVar(&o.insecureSkipTLSVerify, "insecure-skip-tls-verify", false, "skip the verification of the certificate of the content server")
+	cmd.Flags().DurationVar(&o.timeout, "timeout", 2*time.Minute, "how long to wait for the promoted bundle to be installed")
+	return cmd
+}
+
+func promote(ctx context.Context, out io.Writer, from, to client.Client, name string, o promoteOptions) error {
+	bi := &rukpakv1alpha1.BundleInstance{}
+	if err := from.Get(ctx, types.NamespacedName{Name: name}, bi); err != nil {
+		return err
+	}
+	if bi.Status.InstalledBundleName == "" {
+		return fmt.Errorf("bundleinstance %q has not installed a bundle", name)
+	}
+	b := &rukpakv1alpha1.Bundle{}
+	if err := from.Get(ctx, types.NamespacedName{Name: bi.Status.InstalledBundleName}, b); err != nil {
+		return fmt.Errorf("get installed bundle: %w", err)
+	}
+	if b.Status.Phase != rukpakv1alpha1.PhaseUnpacked || b.Status.Digest == "" {
+		return fmt.Errorf("installed bundle %q is not unpacked", b.Name)
+	}
+	if o.provisionerClassName == "" {
+		o.provisionerClassName = bi.Spec.ProvisionerClassName
+	}
+	if o.targetNamespace == "" {
+		o.targetNamespace = bi.Spec.TargetNamespace
+	}
+
+	// The name of the promoted Bundle depends on the promoted content and the
+	// provisioner class, so that promoting the same content again reuses it.
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(o.provisionerClassName+"\n"+b.Status.Digest)))[:10]
+	bundleName := fmt.Sprintf("%s-%s", o.name, hash)
+	promoted := &rukpakv1alpha1.Bundle{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        bundleName,
+			Annotations: map[string]string{promotedFromAnnotation: fmt.Sprintf("%s@%s", b.Name, b.Status.Digest)},
+		},
+		Spec: rukpakv1alpha1.BundleSpec{ProvisionerClassName: o.provisionerClassName},
+	}
+	var cm *corev1.ConfigMap
+	if b.Status.ResolvedSource != nil {
+		promoted.Spec.Source = *b.Status.ResolvedSource
+	} else {
+		if o.url == "" {
+			return fmt.Errorf("the unpacked revision of the %q source of bundle %q is not known: set --url to copy its content", b.Spec.Source.Type, b.Name)
+		}
+		var err error
+		if cm, err = contentConfigMap(ctx, b.Name, o); err != nil {
+			return err
+		}
+		cm.Name, cm.Namespace = bundleName, o.namespace
+		promoted.Spec.Source = rukpakv1alpha1.BundleSource{
+			Type:  rukpakv1alpha1.SourceTypeLocal,
+			Local: &rukpakv1alpha1.LocalSource{ConfigMap: &rukpakv1alpha1.ConfigMapSource{Name: cm.Name, Namespace: cm.Namespace}},
+		}
+		if err := to.Create(ctx, cm); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("create bundle configmap: %w", err)
+		}
+	}
+	if err := to.Create(ctx, promoted); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create bundle: %w", err)
+	}
+	fmt.Fprintf(out, "bundle %q created from bundle %q with digest %s\n", bundleName, b.Name, b.Status.Digest)
+
+	if cm != nil {
+		// The ConfigMap is garbage collected along with its Bundle.
+		if err := to.Get(ctx, client.ObjectKeyFromObject(promoted), promoted); err != nil {
+			return fmt.Errorf("get bundle: %w", err)
+		}
+		if err := to.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
+			return fmt.Errorf("get bundle configmap: %w", err)
+		}
+		base := cm.DeepCopy()
+		if err := controllerutil.SetOwnerReference(promoted, cm, to.Scheme()); err != nil {
+			return err
+		}
+		if err := to.Patch(ctx, cm, client.MergeFrom(base)); err != nil {
+			return fmt.Errorf("set owner of bundle configmap: %w", err)
+		}
+	}
+
+	target := &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: o.name}}
+	if _, err := controllerutil.CreateOrPatch(ctx, to, target, func() error {
+		target.Spec.ProvisionerClassName = o.provisionerClassName
+		target.Spec.BundleName = bundleName
+		target.Spec.TargetNamespace = o.targetNamespace
+		return nil
+	}); err != nil {
+		return fmt.Errorf("create bundleinstance: %w", err)
+	}
+	fmt.Fprintf(out, "bundleinstance %q configured\n", target.Name)
+	return waitForInstalled(ctx, out, to, target.Name, bundleName, o.timeout)
+}
+
+// contentConfigMap downloads the content of the named Bundle from the content
+// server, and returns an immutable ConfigMap with one manifest per object that
+// can back a local source.
+func contentConfigMap(ctx context.Context, name string, o promoteOptions) (*corev1.ConfigMap, error) {
+	url := fmt.Sprintf("%s/bundles/%s/content", strings.TrimSuffix(o.url, "/"), name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if o.token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.token)
+	}
+	httpClient := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: o.insecureSkipTLSVerify}, //nolint:gosec
+	}}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download content of bundle %q: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("download content of bundle %q: %s: %s", name, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	gzr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read content of bundle %q: %w", name, err)
+	}
+	immutable := true
+	cm := &corev1.ConfigMap{Immutable: &immutable, Data: map[string]string{}, BinaryData: map[string][]byte{}}
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read content of bundle %q: %w", name, err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("read content of bundle %q: %w", name, err)
+		}
+		if utf8.Valid(data) {
+			cm.Data[hdr.Name] = string(data)
+		} else {
+			cm.BinaryData[hdr.Name] = data
+		}
+	}
+	return cm, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/manifests"
+)
+
+type runOptions struct {
+	name                 string
+	namespace            string
+	targetNamespace      string
+	provisionerClassName string
+	timeout              time.Duration
+}
+
+func newRunCmd() *cobra.Command {
+	o := runOptions{}
+	cmd := &cobra.Command{
+		Use:   "run <bundle-dir>",
+		Short: "Install a local plain bundle directory and wait until it is installed",
+		Long: "Install a local plain bundle directory and wait until it is installed. " +
+			"The manifests of the bundle are uploaded to a ConfigMap, from which a Bundle with a local source is created. " +
+			"A BundleInstance of the same name is created, or pivoted to the new Bundle when it already exists.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.name == "" {
+				abs, err := filepath.Abs(args[0])
+				if err != nil {
+					return err
+				}
+				o.name = filepath.Base(abs)
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			return run(cmd.Context(), cmd.OutOrStdout(), cl, args[0], o)
+		},
+	}
+	cmd.Flags().StringVar(&o.name, "name", "", "name of the BundleInstance, and prefix of the name of the Bundle; defaults to the name of the bundle directory")
+	cmd.Flags().StringVar(&o.namespace, "namespace", "rukpak-system", "namespace of the ConfigMap that the manifests are uploaded to")
+	cmd.Flags().StringVar(&o.targetNamespace, "target-namespace", "", "namespace that namespace-scoped objects without a namespace are installed into")
+	cmd.Flags().StringVar(&o.provisionerClassName, "provisioner-class", "", "provisioner class of the Bundle and the BundleInstance; defaults to the provisioner of the default ProvisionerClass")
+	cmd.Flags().DurationVar(&o.timeout, "timeout", 2*time.Minute, "how long to wait for the bundle to be installed")
+	return cmd
+}
+
+func run(ctx context.Context, out io.Writer, cl client.Client, bundleDir string, o runOptions) error {
+	bundleFS := os.DirFS(bundleDir)
+	if _, err := manifests.Validate(bundleFS); err != nil {
+		return err
+	}
+	cm, hash, err := manifestsConfigMap(bundleFS)
+	if err != nil {
+		return fmt.Errorf("read bundle manifests: %w", err)
+	}
+	// Bundles are immutable, so the content hash is part of the name of the
+	// Bundle, and a changed directory results in a new Bundle.
+	bundleName := fmt.Sprintf("%s-%s", o.name, hash)
+	cm.Name = bundleName
+	cm.Namespace = o.namespace
+
+	if err := cl.Create(ctx, cm); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create bundle configmap: %w", err)
+	}
+	bundle := &rukpakv1alpha1.Bundle{
+		ObjectMeta: metav1.ObjectMeta{Name: bundleName},
+		Spec: rukpakv1alpha1.BundleSpec{
+			ProvisionerClassName: o.provisionerClassName,
+			Source: rukpakv1alpha1.BundleSource{
+				Type: rukpakv1alpha1.SourceTypeLocal,
+				Local: &rukpakv1alpha1.LocalSource{
+					ConfigMap: &rukpakv1alpha1.ConfigMapSource{Name: cm.Name, Namespace: cm.Namespace},
+				},
+			},
+		},
+	}
+	if err := cl.Create(ctx, bundle); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create bundle: %w", err)
+	}
+	fmt.Fprintf(out, "bundle %q created\n", bundleName)
+
+	// The ConfigMap is garbage collected along with its Bundle.
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(bundle), bundle); err != nil {
+		return fmt.Errorf("get bundle: %w", err)
+	}
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
+		return fmt.Errorf("get bundle configmap: %w", err)
+	}
+	base := cm.DeepCopy()
+	if err := controllerutil.SetOwnerReference(bundle, cm, cl.Scheme()); err != nil {
+		return err
+	}
+	if err := cl.Patch(ctx, cm, client.MergeFrom(base)); err != nil {
+		return fmt.Errorf("set owner of bundle configmap: %w", err)
+	}
+
+	bi := &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: o.name}}
+	if _, err := controllerutil.CreateOrPatch(ctx, cl, bi, func() error {
+		// An unset provisioner class is defaulted when the BundleInstance is
+		// created, and is left as-is afterwards.
+		if o.provisionerClassName != "" {
+			bi.Spec.ProvisionerClassName = o.provisionerClassName
+		}
+		bi.Spec.BundleName = bundleName
+		bi.Spec.TargetNamespace = o.targetNamespace
+		return nil
+	}); err != nil {
+		return fmt.Errorf("create bundleinstance: %w", err)
+	}
+	fmt.Fprintf(out, "bundleinstance %q configured\n", bi.Name)
+	return waitForInstalled(ctx, out, cl, bi.Name, bundleName, o.timeout)
+}
+
+// waitForInstalled waits until the named BundleInstance has installed the
+// Bundle bundleName.
+func waitForInstalled(ctx context.Context, out io.Writer, cl client.Client, name, bundleName string, timeout time.Duration) error {
+	bi := &rukpakv1alpha1.BundleInstance{}
+	var installed *metav1.Condition
+	if err := wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		if err := cl.Get(ctx, types.NamespacedName{Name: name}, bi); err != nil {
+			return false, err
+		}
+		installed = meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeInstalled)
+		return installed != nil && installed.Status == metav1.ConditionTrue && bi.Status.InstalledBundleName == bundleName, nil
+	}); err != nil {
+		if installed != nil && installed.Status != metav1.ConditionTrue {
+			return fmt.Errorf("bundleinstance %q is not installed: %s: %s", name, installed.Reason, installed.Message)
+		}
+		return fmt.Errorf("wait for bundleinstance %q to be installed: %w", name, err)
+	}
+	fmt.Fprintf(out, "bundleinstance %q installed bundle %q\n", name, bundleName)
+	return nil
+}
+
+// manifestsConfigMap returns an immutable ConfigMap with the files in the
+// manifests directory of bundleFS, and a short hash of their contents. The
+// keys of ConfigMaps cannot be paths, so the manifests directory must not have
+// nested directories.
+func manifestsConfigMap(bundleFS fs.FS) (*corev1.ConfigMap, string, error) {
+	entries, err := fs.ReadDir(bundleFS, manifests.Dir)
+	if err != nil {
+		return nil, "", err
+	}
+	immutable := true
+	cm := &corev1.ConfigMap{
+		Immutable:  &immutable,
+		Data:       map[string]string{},
+		BinaryData: map[string][]byte{},
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			if strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			return nil, "", fmt.Errorf("manifests directory %q cannot be stored in a ConfigMap: move its manifests to the manifests directory, or push the bundle as an image", filepath.Join(manifests.Dir, e.Name()))
+		}
+		data, err := fs.ReadFile(bundleFS, manifests.Dir+"/"+e.Name())
+		if err != nil {
+			return nil, "", err
+		}
+		if utf8.Valid(data) {
+			cm.Data[e.Name()] = string(data)
+		} else {
+			cm.BinaryData[e.Name()] = data
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\n%d\n%s%s", name, len(cm.Data[name])+len(cm.BinaryData[name]), cm.Data[name], cm.BinaryData[name])
+	}
+	return cm, fmt.Sprintf("%x", h.Sum(nil))[:10], nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+func newStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status <bundleinstance>",
+		Short: "Print the conditions, installed objects and health of a BundleInstance",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			return printStatus(cmd.Context(), cmd.OutOrStdout(), cl, args[0])
+		},
+	}
+}
+
+func printStatus(ctx context.Context, out io.Writer, cl client.Reader, name string) error {
+	bi := &rukpakv1alpha1.BundleInstance{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: name}, bi); err != nil {
+		return err
+	}
+	var objects []rukpakv1alpha1.BundleObject
+	if bi.Status.InstalledBundleName != "" {
+		b := &rukpakv1alpha1.Bundle{}
+		err := cl.Get(ctx, types.NamespacedName{Name: bi.Status.InstalledBundleName}, b)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && b.Status.Info != nil {
+			objects = b.Status.Info.Objects
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", bi.Name)
+	fmt.Fprintf(w, "Desired Bundle:\t%s\n", util.DesiredBundleName(bi))
+	fmt.Fprintf(w, "Installed Bundle:\t%s\n", valueOrNone(bi.Status.InstalledBundleName))
+
+	fmt.Fprintln(w, "\nCONDITION\tSTATUS\tREASON\tMESSAGE")
+	for _, c := range bi.Status.Conditions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Type, c.Status, c.Reason, c.Message)
+	}
+
+	type objectKey struct{ group, kind, namespace, name string }
+	health := map[objectKey]rukpakv1alpha1.ObjectHealth{}
+	for _, h := range bi.Status.ObjectHealth {
+		health[objectKey{h.Group, h.Kind, h.Namespace, h.Name}] = h
+	}
+	fmt.Fprintln(w, "\nKIND\tNAMESPACE\tNAME\tHEALTH\tMESSAGE")
+	for _, obj := range objects {
+		kind := obj.Kind
+		if obj.Group != "" {
+			kind = fmt.Sprintf("%s.%s", obj.Kind, obj.Group)
+		}
+		state, message := "-", ""
+		if h, ok := health[objectKey{obj.Group, obj.Kind, obj.Namespace, obj.Name}]; ok {
+			state, message = "Unhealthy", h.Message
+			if h.Healthy {
+				state = "Healthy"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", kind, valueOrNone(obj.Namespace), obj.Name, state, message)
+	}
+	return w.Flush()
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
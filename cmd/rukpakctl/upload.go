@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/manifests"
+)
+
+type uploadOptions struct {
+	url                   string
+	token                 string
+	insecureSkipTLSVerify bool
+	provisionerClassName  string
+}
+
+func newUploadCmd() *cobra.Command {
+	o := uploadOptions{}
+	cmd := &cobra.Command{
+		Use:   "upload <bundle-name> <bundle-dir>",
+		Short: "Upload a local plain bundle directory as the content of a Bundle",
+		Long: "Upload a local plain bundle directory as the content of a Bundle with an upload source. " +
+			"The Bundle is created when it does not exist, and the bundle is validated before it is uploaded " +
+			"to the upload endpoint of the rukpak core.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := ctrl.GetConfig()
+			if err != nil {
+				return err
+			}
+			if o.token == "" {
+				o.token = cfg.BearerToken
+			}
+			if o.token == "" {
+				return fmt.Errorf("a bearer token is required to upload bundles: set --token")
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			return upload(cmd.Context(), cmd.OutOrStdout(), cl, args[0], args[1], o)
+		},
+	}
+	cmd.Flags().StringVar(&o.url, "url", "https://localhost:9443", "URL of the content server of the rukpak core, for example forwarded from the rukpak-webhook service")
+	cmd.Flags().StringVar(&o.token, "token", "", "bearer token used to authenticate the upload; defaults to the token of the current kubeconfig context")
+	cmd.Flags().BoolVar(&o.insecureSkipTLSVerify, "insecure-skip-tls-verify", false, "skip the verification of the certificate of the content server")
+	cmd.Flags().StringVar(&o.provisionerClassName, "provisioner-class", "", "provisioner class of the Bundle when it is created; defaults to the provisioner of the default ProvisionerClass")
+	return cmd
+}
+
+func upload(ctx context.Context, out io.Writer, cl client.Client, bundleName, bundleDir string, o uploadOptions) error {
+	if _, err := manifests.Validate(os.DirFS(bundleDir)); err != nil {
+		return err
+	}
+	body := &bytes.Buffer{}
+	if err := encodeTarball(body, bundleDir); err != nil {
+		return fmt.Errorf("write bundle tarball: %w", err)
+	}
+
+	bundle := &rukpakv1alpha1.Bundle{
+		ObjectMeta: metav1.ObjectMeta{Name: bundleName},
+		Spec: rukpakv1alpha1.BundleSpec{
+			ProvisionerClassName: o.provisionerClassName,
+			Source:               rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeUpload},
+		},
+	}
+	if err := cl.Create(ctx, bundle); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create bundle %q: %w", bundleName, err)
+	}
+
+	url := fmt.Sprintf("%s/bundles/%s/upload", strings.TrimSuffix(o.url, "/"), bundleName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+o.token)
+	req.Header.Set("Content-Type", "application/gzip")
+	httpClient := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: o.insecureSkipTLSVerify}, //nolint:gosec
+	}}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload bundle %q: %w", bundleName, err)
+	}
+	defer resp.Body.Close()
+	msg, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read upload response: %w", err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("upload bundle %q: %s: %s", bundleName, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	fmt.Fprintf(out, "uploaded bundle %q with digest %s\n", bundleName, strings.TrimSpace(string(msg)))
+	return nil
+}
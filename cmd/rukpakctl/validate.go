@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/operator-framework/rukpak/internal/manifests"
+	"github.com/operator-framework/rukpak/internal/validation"
+)
+
+func newValidateCmd() *cobra.Command {
+	var disallowedKinds string
+	var cluster bool
+	cmd := &cobra.Command{
+		Use:   "validate <bundle-dir>",
+		Short: "Validate a plain bundle directory as the plain provisioner does when unpacking it",
+		Long: "Validate a plain bundle directory as the plain provisioner does when unpacking it. With --cluster, the namespaces and " +
+			"schemas of its objects are validated against the cluster of the current kubeconfig context, as the provisioner does.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundleFS := os.DirFS(args[0])
+			objects, err := manifests.Validate(bundleFS)
+			if err != nil {
+				return err
+			}
+			opts := validation.Options{DisallowedKinds: validation.ParseKinds(disallowedKinds)}
+			if cluster {
+				cfg, err := config.GetConfig()
+				if err != nil {
+					return err
+				}
+				if opts.Mapper, err = apiutil.NewDynamicRESTMapper(cfg); err != nil {
+					return err
+				}
+				if opts.Schemas, err = discovery.NewDiscoveryClientForConfig(cfg); err != nil {
+					return err
+				}
+			}
+			if _, err := validation.Validate(cmd.Context(), bundleFS, opts.Validators()...); err != nil {
+				var errs validation.Errors
+				if errors.As(err, &errs) {
+					for _, e := range errs {
+						fmt.Fprintln(cmd.ErrOrStderr(), e.Error())
+					}
+					return fmt.Errorf("bundle %q is invalid", args[0])
+				}
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "bundle %q is valid: found %d objects\n", args[0], len(objects))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&disallowedKinds, "disallowed-kinds", "", "comma-separated list of the kinds, qualified by their groups, of the objects that the bundle must not contain")
+	cmd.Flags().BoolVar(&cluster, "cluster", false, "validate the namespaces and schemas of the objects against the cluster of the current kubeconfig context")
+	return cmd
+}
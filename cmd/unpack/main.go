@@ -8,18 +8,22 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
 
+	"github.com/operator-framework/rukpak/internal/manifests"
 	"github.com/operator-framework/rukpak/internal/version"
 )
 
 func main() {
 	var bundleDir string
+	var kustomizationDir string
 	var rukpakVersion bool
 
 	skipRootPaths := sets.NewString(
@@ -39,6 +43,14 @@ func main() {
 				fmt.Printf("Git commit: %s\n", version.String())
 				os.Exit(0)
 			}
+			if kustomizationDir != "" {
+				dir, err := renderKustomization(kustomizationDir)
+				if err != nil {
+					log.Fatalf("render kustomization %q: %v", kustomizationDir, err)
+				}
+				defer os.RemoveAll(dir)
+				bundleDir = dir
+			}
 			var err error
 			bundleDir, err = filepath.Abs(bundleDir)
 			if err != nil {
@@ -114,9 +126,30 @@ func main() {
 		},
 	}
 	cmd.Flags().StringVar(&bundleDir, "bundle-dir", "", "directory in which the bundle can be found")
+	cmd.Flags().StringVar(&kustomizationDir, "kustomize", "", "directory of a kustomization that is rendered as the manifests of the bundle, instead of unpacking --bundle-dir")
 	cmd.Flags().BoolVar(&rukpakVersion, "version", false, "displays rukpak version information")
 
 	if err := cmd.Execute(); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// renderKustomization renders the kustomization in dir into the manifests of
+// a plain bundle in a temporary directory, and returns that directory.
+func renderKustomization(dir string) (string, error) {
+	data, err := manifests.Kustomize(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return "", err
+	}
+	bundleDir, err := ioutil.TempDir("", "bundle-")
+	if err != nil {
+		return "", err
+	}
+	if err := os.Mkdir(filepath.Join(bundleDir, manifests.Dir), 0755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(bundleDir, manifests.Dir, "objects.yaml"), data, 0600); err != nil {
+		return "", err
+	}
+	return bundleDir, nil
+}
@@ -0,0 +1,88 @@
+// crd-patch merges the patches of a directory into the CustomResourceDefinitions
+// that controller-gen generates, for the fields of the CRDs that controller-gen
+// has no markers for, such as their conversion webhooks. Each patch is merged
+// into the manifest of the same name in the manifests directory. The manifests
+// are formatted again by the schemapatch generator of controller-gen, which
+// keeps the patched fields.
+//
+// Usage:
+//
+//	go run ./hack/crd-patch <manifests dir> <patches dir>
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: crd-patch <manifests dir> <patches dir>")
+		os.Exit(2)
+	}
+	if err := patchAll(os.Args[1], os.Args[2]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// patchAll merges every patch in patchesDir into the manifest of the same
+// name in manifestsDir.
+func patchAll(manifestsDir, patchesDir string) error {
+	patches, err := filepath.Glob(filepath.Join(patchesDir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, patchFile := range patches {
+		manifestFile := filepath.Join(manifestsDir, filepath.Base(patchFile))
+		if err := patch(manifestFile, patchFile); err != nil {
+			return fmt.Errorf("patch %s with %s: %w", manifestFile, patchFile, err)
+		}
+	}
+	return nil
+}
+
+func patch(manifestFile, patchFile string) error {
+	var manifest, patch map[string]interface{}
+	if err := readYAML(manifestFile, &manifest); err != nil {
+		return err
+	}
+	if err := readYAML(patchFile, &patch); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(merge(manifest, patch))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifestFile, data, 0644)
+}
+
+func readYAML(file string, v interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, v)
+}
+
+// merge merges patch into obj, recursively for the fields of both that are
+// objects. Other fields of patch replace those of obj.
+func merge(obj, patch map[string]interface{}) map[string]interface{} {
+	if obj == nil {
+		obj = map[string]interface{}{}
+	}
+	for k, v := range patch {
+		patchValue, isObject := v.(map[string]interface{})
+		objValue, _ := obj[k].(map[string]interface{})
+		if isObject {
+			obj[k] = merge(objValue, patchValue)
+			continue
+		}
+		obj[k] = v
+	}
+	return obj
+}
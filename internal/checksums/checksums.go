@@ -0,0 +1,137 @@
+// Package checksums verifies the content of an unpacked bundle against the
+// list of files and checksums declared by the bundle author.
+package checksums
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// FileName is the name of the checksums file in a manifests directory. It
+// uses the format of the output of `sha256sum`, so it can be generated for the
+// manifests of a flat directory with:
+//
+//	sha256sum *.yaml > SHA256SUMS
+//
+// and for those of nested directories with:
+//
+//	find . -type f ! -name SHA256SUMS ! -path '*/.*' | sort | xargs sha256sum > SHA256SUMS
+const FileName = "SHA256SUMS"
+
+// Verify verifies the files of dir in fsys, including those of its nested
+// directories, against the checksums file in dir. Bundles without a checksums
+// file are not verified. Otherwise, every file in dir must be listed in the
+// checksums file by its path relative to dir and match its checksum, and
+// every listed file must be present. Files and directories whose names start
+// with a dot, and the checksums files of nested directories, are not
+// verified.
+func Verify(fsys fs.FS, dir string) error {
+	data, err := fs.ReadFile(fsys, path.Join(dir, FileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", FileName, err)
+	}
+	expected, err := parse(data)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", FileName, err)
+	}
+
+	var missing, extra, modified []string
+	found := map[string]struct{}{}
+	err = fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		name := strings.TrimPrefix(p, dir+"/")
+		if d.IsDir() || d.Name() == FileName {
+			return nil
+		}
+		found[name] = struct{}{}
+		sum, ok := expected[name]
+		if !ok {
+			extra = append(extra, name)
+			return nil
+		}
+		fileData, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		if fmt.Sprintf("%x", sha256.Sum256(fileData)) != sum {
+			modified = append(modified, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for name := range expected {
+		if _, ok := found[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	var problems []string
+	for _, p := range []struct {
+		desc  string
+		names []string
+	}{
+		{"missing files", missing},
+		{"unexpected files", extra},
+		{"modified files", modified},
+	} {
+		if len(p.names) == 0 {
+			continue
+		}
+		sort.Strings(p.names)
+		problems = append(problems, fmt.Sprintf("%s %s", p.desc, strings.Join(p.names, ", ")))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("bundle content does not match %s: %s", FileName, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// parse parses the lines of a checksums file into a map of file names to
+// hex-encoded SHA-256 checksums.
+func parse(data []byte) (map[string]string, error) {
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			return nil, fmt.Errorf("line %d: expected a SHA-256 checksum followed by a file name", line)
+		}
+		// sha256sum prefixes the file name with '*' in binary mode.
+		name := path.Clean(strings.TrimPrefix(fields[1], "*"))
+		if name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return nil, fmt.Errorf("line %d: file %q is not in the manifests directory", line, name)
+		}
+		if _, ok := sums[name]; ok {
+			return nil, fmt.Errorf("line %d: duplicate file %q", line, name)
+		}
+		sums[name] = strings.ToLower(fields[0])
+	}
+	return sums, scanner.Err()
+}
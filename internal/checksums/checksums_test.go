@@ -0,0 +1,82 @@
+package checksums
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sum(data string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(data)))
+}
+
+func TestVerify(t *testing.T) {
+	sums := fmt.Sprintf("%s  a.yaml\n%s *b.yaml\n", sum("a"), sum("b"))
+	type testCase struct {
+		name   string
+		fsys   fstest.MapFS
+		errMsg string
+	}
+	for _, tc := range []testCase{
+		{
+			name: "no checksums file",
+			fsys: fstest.MapFS{"manifests/a.yaml": {Data: []byte("a")}},
+		},
+		{
+			name: "matching content",
+			fsys: fstest.MapFS{
+				"manifests/SHA256SUMS": {Data: []byte(sums)},
+				"manifests/a.yaml":     {Data: []byte("a")},
+				"manifests/b.yaml":     {Data: []byte("b")},
+			},
+		},
+		{
+			name: "missing, extra and modified files",
+			fsys: fstest.MapFS{
+				"manifests/SHA256SUMS": {Data: []byte(sums)},
+				"manifests/a.yaml":     {Data: []byte("tampered")},
+				"manifests/c.yaml":     {Data: []byte("c")},
+			},
+			errMsg: "bundle content does not match SHA256SUMS: missing files b.yaml; unexpected files c.yaml; modified files a.yaml",
+		},
+		{
+			name: "nested directories",
+			fsys: fstest.MapFS{
+				"manifests/SHA256SUMS":      {Data: []byte(sum("a") + "  ./a.yaml\n" + sum("b") + "  crds/b.yaml\n")},
+				"manifests/a.yaml":          {Data: []byte("a")},
+				"manifests/crds/b.yaml":     {Data: []byte("tampered")},
+				"manifests/crds/c/c.yaml":   {Data: []byte("c")},
+				"manifests/.git/HEAD":       {Data: []byte("ref: refs/heads/main")},
+				"manifests/crds/.gitignore": {Data: []byte("*.bak")},
+			},
+			errMsg: "bundle content does not match SHA256SUMS: unexpected files crds/c/c.yaml; modified files crds/b.yaml",
+		},
+		{
+			name: "malformed checksums file",
+			fsys: fstest.MapFS{
+				"manifests/SHA256SUMS": {Data: []byte("abc a.yaml\n")},
+				"manifests/a.yaml":     {Data: []byte("a")},
+			},
+			errMsg: "parse SHA256SUMS: line 1: expected a SHA-256 checksum followed by a file name",
+		},
+		{
+			name: "file outside of the manifests directory",
+			fsys: fstest.MapFS{
+				"manifests/SHA256SUMS": {Data: []byte(sum("a") + "  ../a.yaml\n")},
+			},
+			errMsg: `parse SHA256SUMS: line 1: file "../a.yaml" is not in the manifests directory`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.fsys, "manifests")
+			if tc.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.errMsg)
+		})
+	}
+}
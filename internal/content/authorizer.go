@@ -0,0 +1,77 @@
+package content
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrUnauthenticated is returned by an Authorizer when the client of a request
+// could not be authenticated.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authorizer decides whether the client of a request is permitted to access
+// the resource described by attrs.
+type Authorizer interface {
+	Authorize(ctx context.Context, r *http.Request, attrs authorizationv1.ResourceAttributes) (allowed bool, reason string, err error)
+}
+
+var _ Authorizer = &DelegatingAuthorizer{}
+
+// DelegatingAuthorizer delegates authentication and authorization to the
+// Kubernetes API server. The bearer token of a request is authenticated with a
+// TokenReview, and the resulting user is authorized with a SubjectAccessReview,
+// so that access to the content server is granted with regular RBAC rules.
+type DelegatingAuthorizer struct {
+	Client client.Client
+}
+
+func (a *DelegatingAuthorizer) Authorize(ctx context.Context, r *http.Request, attrs authorizationv1.ResourceAttributes) (bool, string, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return false, "", ErrUnauthenticated
+	}
+	tr := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	if err := a.Client.Create(ctx, tr); err != nil {
+		return false, "", fmt.Errorf("create token review: %w", err)
+	}
+	if !tr.Status.Authenticated {
+		return false, "", ErrUnauthenticated
+	}
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range tr.Status.User.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &attrs,
+			User:               tr.Status.User.Username,
+			Groups:             tr.Status.User.Groups,
+			UID:                tr.Status.User.UID,
+			Extra:              extra,
+		},
+	}
+	if err := a.Client.Create(ctx, sar); err != nil {
+		return false, "", fmt.Errorf("create subject access review: %w", err)
+	}
+	return sar.Status.Allowed, sar.Status.Reason, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(auth, prefix))
+	return token, token != ""
+}
@@ -0,0 +1,154 @@
+package content
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// BundleDiff is an object-level diff between the contents of two Bundles.
+type BundleDiff struct {
+	From    string       `json:"from,omitempty"`
+	To      string       `json:"to,omitempty"`
+	Added   []ObjectRef  `json:"added,omitempty"`
+	Removed []ObjectRef  `json:"removed,omitempty"`
+	Changed []ObjectDiff `json:"changed,omitempty"`
+}
+
+// ObjectRef identifies an object in the contents of a Bundle. Objects are
+// matched by group, kind, namespace and name, so that a change of the version
+// of an object is reported as a change rather than as a removal and addition.
+type ObjectRef struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (r ObjectRef) String() string {
+	gk := r.Kind
+	if r.Group != "" {
+		gk = fmt.Sprintf("%s.%s", r.Kind, r.Group)
+	}
+	if r.Namespace != "" {
+		return fmt.Sprintf("%s %s/%s", gk, r.Namespace, r.Name)
+	}
+	return fmt.Sprintf("%s %s", gk, r.Name)
+}
+
+// ObjectDiff describes the fields of an object that differ between the
+// contents of two Bundles.
+type ObjectDiff struct {
+	ObjectRef `json:",inline"`
+	Fields    []FieldDiff `json:"fields"`
+}
+
+// FieldDiff describes a single field that differs between two versions of an
+// object. From is unset when the field was added, and To is unset when the
+// field was removed.
+type FieldDiff struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// Diff computes the object-level diff from the objects in from to the objects
+// in to. The added, removed and changed objects are sorted by identity, and
+// the field diffs of each object are sorted by path.
+func Diff(from, to []unstructured.Unstructured) BundleDiff {
+	fromObjs, toObjs := indexObjects(from), indexObjects(to)
+
+	d := BundleDiff{}
+	for ref, fromObj := range fromObjs {
+		toObj, ok := toObjs[ref]
+		if !ok {
+			d.Removed = append(d.Removed, ref)
+			continue
+		}
+		var fields []FieldDiff
+		diffValues("", fromObj.Object, toObj.Object, &fields)
+		if len(fields) > 0 {
+			sort.Slice(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+			d.Changed = append(d.Changed, ObjectDiff{ObjectRef: ref, Fields: fields})
+		}
+	}
+	for ref := range toObjs {
+		if _, ok := fromObjs[ref]; !ok {
+			d.Added = append(d.Added, ref)
+		}
+	}
+
+	sortRefs(d.Added)
+	sortRefs(d.Removed)
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].String() < d.Changed[j].String() })
+	return d
+}
+
+func indexObjects(objs []unstructured.Unstructured) map[ObjectRef]unstructured.Unstructured {
+	idx := make(map[ObjectRef]unstructured.Unstructured, len(objs))
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		idx[ObjectRef{
+			Group:     gvk.Group,
+			Kind:      gvk.Kind,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+		}] = obj
+	}
+	return idx
+}
+
+func sortRefs(refs []ObjectRef) {
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+}
+
+// diffValues appends the differences between from and to, found at path, to
+// diffs. Maps are compared key by key, and lists of equal length are compared
+// element by element; any other difference is reported for the value as a
+// whole.
+func diffValues(path string, from, to interface{}, diffs *[]FieldDiff) {
+	switch fromV := from.(type) {
+	case map[string]interface{}:
+		toV, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]struct{}{}
+		for k := range fromV {
+			keys[k] = struct{}{}
+		}
+		for k := range toV {
+			keys[k] = struct{}{}
+		}
+		for k := range keys {
+			diffValues(joinPath(path, k), fromV[k], toV[k], diffs)
+		}
+		return
+	case []interface{}:
+		toV, ok := to.([]interface{})
+		if !ok || len(fromV) != len(toV) {
+			break
+		}
+		for i := range fromV {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), fromV[i], toV[i], diffs)
+		}
+		return
+	}
+	if !reflect.DeepEqual(from, to) {
+		*diffs = append(*diffs, FieldDiff{Path: path, From: from, To: to})
+	}
+}
+
+func joinPath(path, key string) string {
+	switch {
+	case strings.ContainsAny(key, ".[]"):
+		return fmt.Sprintf("%s[%q]", path, key)
+	case path == "":
+		return key
+	default:
+		return path + "." + key
+	}
+}
@@ -0,0 +1,104 @@
+package content
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDiff(t *testing.T) {
+	withSpec := func(u unstructured.Unstructured, spec map[string]interface{}) unstructured.Unstructured {
+		u.Object["spec"] = spec
+		return u
+	}
+	deploymentRef := ObjectRef{Group: "apps", Kind: "Deployment", Namespace: "ns", Name: "operator"}
+	namespaceRef := ObjectRef{Kind: "Namespace", Name: "ns"}
+	serviceRef := ObjectRef{Kind: "Service", Namespace: "ns", Name: "operator"}
+
+	for _, tt := range []struct {
+		description string
+		from        []unstructured.Unstructured
+		to          []unstructured.Unstructured
+		result      BundleDiff
+	}{
+		{
+			description: "identical contents - no differences",
+			from:        []unstructured.Unstructured{newObject("v1", "Namespace", "", "ns")},
+			to:          []unstructured.Unstructured{newObject("v1", "Namespace", "", "ns")},
+			result:      BundleDiff{},
+		},
+		{
+			description: "added and removed objects",
+			from:        []unstructured.Unstructured{newObject("v1", "Namespace", "", "ns")},
+			to:          []unstructured.Unstructured{newObject("v1", "Service", "ns", "operator")},
+			result: BundleDiff{
+				Added:   []ObjectRef{serviceRef},
+				Removed: []ObjectRef{namespaceRef},
+			},
+		},
+		{
+			description: "changed fields",
+			from: []unstructured.Unstructured{withSpec(newObject("apps/v1", "Deployment", "ns", "operator"), map[string]interface{}{
+				"replicas": int64(1),
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{map[string]interface{}{"image": "operator:v1"}},
+					},
+				},
+			})},
+			to: []unstructured.Unstructured{withSpec(newObject("apps/v1", "Deployment", "ns", "operator"), map[string]interface{}{
+				"paused": true,
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{map[string]interface{}{"image": "operator:v2"}},
+					},
+				},
+			})},
+			result: BundleDiff{
+				Changed: []ObjectDiff{{
+					ObjectRef: deploymentRef,
+					Fields: []FieldDiff{
+						{Path: "spec.paused", To: true},
+						{Path: "spec.replicas", From: int64(1)},
+						{Path: "spec.template.spec.containers[0].image", From: "operator:v1", To: "operator:v2"},
+					},
+				}},
+			},
+		},
+		{
+			description: "changed version - reported as a change",
+			from:        []unstructured.Unstructured{newObject("apps/v1beta1", "Deployment", "ns", "operator")},
+			to:          []unstructured.Unstructured{newObject("apps/v1", "Deployment", "ns", "operator")},
+			result: BundleDiff{
+				Changed: []ObjectDiff{{
+					ObjectRef: deploymentRef,
+					Fields:    []FieldDiff{{Path: "apiVersion", From: "apps/v1beta1", To: "apps/v1"}},
+				}},
+			},
+		},
+		{
+			description: "keys containing dots are quoted",
+			from: []unstructured.Unstructured{func() unstructured.Unstructured {
+				u := newObject("v1", "Namespace", "", "ns")
+				u.SetLabels(map[string]string{"app.kubernetes.io/name": "a"})
+				return u
+			}()},
+			to: []unstructured.Unstructured{func() unstructured.Unstructured {
+				u := newObject("v1", "Namespace", "", "ns")
+				u.SetLabels(map[string]string{"app.kubernetes.io/name": "b"})
+				return u
+			}()},
+			result: BundleDiff{
+				Changed: []ObjectDiff{{
+					ObjectRef: namespaceRef,
+					Fields:    []FieldDiff{{Path: `metadata.labels["app.kubernetes.io/name"]`, From: "a", To: "b"}},
+				}},
+			},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			require.Equal(t, tt.result, Diff(tt.from, tt.to))
+		})
+	}
+}
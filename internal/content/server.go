@@ -0,0 +1,381 @@
+package content
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/digest"
+	"github.com/operator-framework/rukpak/internal/storage"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+const (
+	// BundlesPath is the path prefix under which bundle content is served.
+	BundlesPath = "/bundles/"
+	// BundleInstancesPath is the path prefix under which install reports are
+	// served.
+	BundleInstancesPath = "/bundleinstances/"
+
+	// SubresourceContent is the authorization subresource of Bundles that
+	// grants access to their unpacked content.
+	SubresourceContent = "content"
+	// SubresourceReport is the authorization subresource of BundleInstances
+	// that grants access to their install report.
+	SubresourceReport = "report"
+	// SubresourceUpload is the authorization subresource of Bundles that
+	// grants the permission to upload their content.
+	SubresourceUpload = "upload"
+)
+
+// Server serves the unpacked content of Bundles, diffs between the content of
+// Bundles, what the sources of Bundles currently resolve to, and the install
+// reports of BundleInstances over HTTP, and accepts the content of Bundles
+// with an upload source.
+//
+// Every read is authorized with the Authorizer as a "get" of the content
+// subresource of the named Bundles, or of the report subresource of the named
+// BundleInstance. Uploads are authorized as a "create" of the upload
+// subresource of the named Bundle.
+type Server struct {
+	// Client is used to look up Bundles and BundleInstances, and to annotate
+	// Bundles whose content has been uploaded.
+	Client        client.Client
+	BundleStorage storage.Storage
+	// ReportStorage is the storage in which install reports are persisted.
+	// Install reports are not served when ReportStorage is nil.
+	ReportStorage storage.Storage
+	// UploadStorage is the storage in which uploaded content is staged until
+	// the provisioner of its Bundle unpacks it. Uploads are not accepted when
+	// UploadStorage is nil.
+	UploadStorage storage.Storage
+	// DigestAlgorithm is the algorithm of the digests of uploaded content. It
+	// defaults to SHA256.
+	DigestAlgorithm digest.Algorithm
+	// SecretNamespace is the namespace of the Secrets referenced by Bundle
+	// sources, which are used to resolve them, and HTTPClient is the client
+	// with which they are resolved. HTTPClient defaults to
+	// util.DefaultHTTPClient.
+	SecretNamespace string
+	HTTPClient      *http.Client
+	Authorizer      Authorizer
+}
+
+var _ http.Handler = &Server{}
+
+// ServeHTTP implements http.Handler for requests under BundlesPath and
+// BundleInstancesPath.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, BundlesPath):
+		s.serveBundle(w, r)
+	case strings.HasPrefix(r.URL.Path, BundleInstancesPath):
+		s.serveBundleInstance(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveBundle(w http.ResponseWriter, r *http.Request) {
+	name, action, ok := parsePath(r.URL.Path, BundlesPath)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch action {
+	case SubresourceContent:
+		s.serveBundleContent(w, r, name)
+	case "diff":
+		s.serveBundleDiff(w, r, name)
+	case SubresourceUpload:
+		s.serveBundleUpload(w, r, name)
+	case "source":
+		s.serveBundleSource(w, r, name)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveBundleContent(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.authorize(w, r, "bundles", SubresourceContent, name) {
+		return
+	}
+	ctx := r.Context()
+	b, ok := s.getUnpackedBundle(ctx, w, name)
+	if !ok {
+		return
+	}
+	// The content is streamed from the storage rather than loaded, so that
+	// large bundles are not held in memory while they are served.
+	content, err := s.BundleStorage.LoadStream(ctx, b)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".tar.gz"))
+	if b.Status.Digest != "" {
+		w.Header().Set("ETag", fmt.Sprintf("%q", b.Status.Digest))
+	}
+	if err := WriteTarballStream(w, content); err != nil {
+		// The response has already been started, so the error can only be
+		// logged. The tarball is left unterminated, so that clients do not
+		// mistake the content that was served for the whole bundle.
+		log.FromContext(ctx).Error(err, "failed to write bundle content", util.LogKeyBundle, name)
+	}
+}
+
+// serveBundleDiff serves the Diff from the content of the named Bundle to the
+// content of the Bundle named by the "to" query parameter. Access to the
+// content of both Bundles is required.
+func (s *Server) serveBundleDiff(w http.ResponseWriter, r *http.Request, name string) {
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		http.Error(w, `missing required query parameter "to"`, http.StatusBadRequest)
+		return
+	}
+	if !s.authorize(w, r, "bundles", SubresourceContent, name) || !s.authorize(w, r, "bundles", SubresourceContent, to) {
+		return
+	}
+	ctx := r.Context()
+	_, fromObjects, ok := s.loadBundle(ctx, w, name)
+	if !ok {
+		return
+	}
+	_, toObjects, ok := s.loadBundle(ctx, w, to)
+	if !ok {
+		return
+	}
+
+	d := Diff(fromObjects, toObjects)
+	d.From, d.To = name, to
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		log.FromContext(ctx).Error(err, "failed to write bundle diff", util.LogKeyBundle, name)
+	}
+}
+
+// loadBundle loads the named Bundle and its unpacked content, and writes an
+// error response when either cannot be loaded.
+func (s *Server) loadBundle(ctx context.Context, w http.ResponseWriter, name string) (*rukpakv1alpha1.Bundle, []unstructured.Unstructured, bool) {
+	b, ok := s.getUnpackedBundle(ctx, w, name)
+	if !ok {
+		return nil, nil, false
+	}
+	objects, err := s.BundleStorage.Load(ctx, b)
+	if err != nil {
+		writeError(ctx, w, err)
+		return nil, nil, false
+	}
+	return b, objects, true
+}
+
+// getUnpackedBundle gets the named Bundle, and writes an error response when it
+// cannot be found or is not unpacked.
+func (s *Server) getUnpackedBundle(ctx context.Context, w http.ResponseWriter, name string) (*rukpakv1alpha1.Bundle, bool) {
+	b := &rukpakv1alpha1.Bundle{}
+	if err := s.Client.Get(ctx, types.NamespacedName{Name: name}, b); err != nil {
+		writeError(ctx, w, err)
+		return nil, false
+	}
+	if b.Status.Phase != rukpakv1alpha1.PhaseUnpacked {
+		http.Error(w, fmt.Sprintf("bundle %q is not unpacked", name), http.StatusConflict)
+		return nil, false
+	}
+	return b, true
+}
+
+func (s *Server) serveBundleInstance(w http.ResponseWriter, r *http.Request) {
+	name, action, ok := parsePath(r.URL.Path, BundleInstancesPath)
+	if !ok || action != SubresourceReport || s.ReportStorage == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.authorize(w, r, "bundleinstances", SubresourceReport, name) {
+		return
+	}
+
+	ctx := r.Context()
+	bi := &rukpakv1alpha1.BundleInstance{}
+	if err := s.Client.Get(ctx, types.NamespacedName{Name: name}, bi); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+	reports, err := s.ReportStorage.Load(ctx, bi)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+	if len(reports) != 1 {
+		http.Error(w, fmt.Sprintf("expected 1 install report for bundleinstance %q, found %d", name, len(reports)), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reports[0].Object); err != nil {
+		log.FromContext(ctx).Error(err, "failed to write install report", util.LogKeyBundleInstance, name)
+	}
+}
+
+// authorize authorizes r to get the subresource of the named resource, and
+// writes an error response when it is not. It returns whether the request may
+// proceed.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, resource, subresource, name string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return false
+	}
+	return s.authorizeVerb(w, r, "get", resource, subresource, name)
+}
+
+// authorizeVerb authorizes r for verb on the subresource of the named
+// resource, and writes an error response when it is not. It returns whether
+// the request may proceed.
+func (s *Server) authorizeVerb(w http.ResponseWriter, r *http.Request, verb, resource, subresource, name string) bool {
+	allowed, reason, err := s.Authorizer.Authorize(r.Context(), r, authorizationv1.ResourceAttributes{
+		Verb:        verb,
+		Group:       rukpakv1alpha1.GroupVersion.Group,
+		Version:     rukpakv1alpha1.GroupVersion.Version,
+		Resource:    resource,
+		Subresource: subresource,
+		Name:        name,
+	})
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return false
+	case err != nil:
+		log.FromContext(r.Context()).Error(err, "failed to authorize request")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return false
+	case !allowed:
+		msg := fmt.Sprintf("not permitted to %s %s/%s of %q", verb, resource, subresource, name)
+		if reason != "" {
+			msg = fmt.Sprintf("%s: %s", msg, reason)
+		}
+		http.Error(w, msg, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// parsePath returns the name and action in a path of the form
+// <prefix><name>/<action>.
+func parsePath(path, prefix string) (string, string, bool) {
+	parts := strings.Split(strings.TrimPrefix(path, prefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func writeError(ctx context.Context, w http.ResponseWriter, err error) {
+	if apierrors.IsNotFound(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log.FromContext(ctx).Error(err, "failed to serve request")
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// WriteTarball writes objects to w as a gzipped tarball containing one YAML
+// manifest per object.
+func WriteTarball(w io.Writer, objects []unstructured.Unstructured) error {
+	return writeTarball(w, func(fn func(obj unstructured.Unstructured) error) error {
+		for _, obj := range objects {
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteTarballStream writes the objects in r, a stream of YAML or JSON
+// documents, to w as WriteTarball does. Objects are decoded and written one at
+// a time, and the tarball is only terminated once r has been read to its end
+// without an error.
+func WriteTarballStream(w io.Writer, r io.Reader) error {
+	return writeTarball(w, func(fn func(obj unstructured.Unstructured) error) error {
+		decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+		for {
+			obj := map[string]interface{}{}
+			if err := decoder.Decode(&obj); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return fmt.Errorf("decode bundle content: %w", err)
+			}
+			if len(obj) == 0 {
+				continue
+			}
+			if err := fn(unstructured.Unstructured{Object: obj}); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// writeTarball writes the objects that each calls fn with to w as a gzipped
+// tarball.
+func writeTarball(w io.Writer, each func(fn func(obj unstructured.Unstructured) error) error) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	modTime := time.Unix(0, 0)
+	if err := each(func(obj unstructured.Unstructured) error {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return fmt.Errorf("marshal %s %q: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    ObjectFileName(obj),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: modTime,
+		}); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// ObjectFileName returns the name of the file that obj is written to in a
+// bundle content tarball.
+func ObjectFileName(obj unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	parts := []string{strings.ToLower(gvk.Kind)}
+	if gvk.Group != "" {
+		parts[0] = fmt.Sprintf("%s.%s", parts[0], gvk.Group)
+	}
+	if obj.GetNamespace() != "" {
+		parts = append(parts, obj.GetNamespace())
+	}
+	parts = append(parts, obj.GetName())
+	return strings.Join(parts, "_") + ".yaml"
+}
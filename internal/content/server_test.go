@@ -0,0 +1,456 @@
+package content
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+type fakeStorage map[string][]unstructured.Unstructured
+
+func (s fakeStorage) Load(_ context.Context, owner client.Object) ([]unstructured.Unstructured, error) {
+	objs, ok := s[owner.GetName()]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, owner.GetName())
+	}
+	return objs, nil
+}
+
+func (s fakeStorage) Store(_ context.Context, _ client.Object, _ []client.Object) error {
+	return nil
+}
+
+func (s fakeStorage) LoadStream(ctx context.Context, owner client.Object) (io.ReadCloser, error) {
+	objs, err := s.Load(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	stream := &bytes.Buffer{}
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, err
+		}
+		stream.WriteString("---\n")
+		stream.Write(data)
+	}
+	return ioutil.NopCloser(stream), nil
+}
+
+func (s fakeStorage) StoreStream(_ context.Context, _ client.Object, _ io.Reader) error {
+	return nil
+}
+
+func (s fakeStorage) Delete(_ context.Context, owner client.Object) error {
+	delete(s, owner.GetName())
+	return nil
+}
+
+func (s fakeStorage) Exists(_ context.Context, owner client.Object) (bool, error) {
+	_, ok := s[owner.GetName()]
+	return ok, nil
+}
+
+type fakeAuthorizer struct {
+	allowed bool
+	err     error
+	attrs   authorizationv1.ResourceAttributes
+}
+
+func (a *fakeAuthorizer) Authorize(_ context.Context, _ *http.Request, attrs authorizationv1.ResourceAttributes) (bool, string, error) {
+	a.attrs = attrs
+	return a.allowed, "", a.err
+}
+
+func newObject(apiVersion, kind, namespace, name string) unstructured.Unstructured {
+	u := unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func newTestServer(t *testing.T, authorizer Authorizer) *Server {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, rukpakv1alpha1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&rukpakv1alpha1.Bundle{
+			ObjectMeta: metav1.ObjectMeta{Name: "unpacked"},
+			Status:     rukpakv1alpha1.BundleStatus{Phase: rukpakv1alpha1.PhaseUnpacked, Digest: "abc"},
+		},
+		&rukpakv1alpha1.Bundle{
+			ObjectMeta: metav1.ObjectMeta{Name: "pending"},
+			Status:     rukpakv1alpha1.BundleStatus{Phase: rukpakv1alpha1.PhasePending},
+		},
+		&rukpakv1alpha1.Bundle{
+			ObjectMeta: metav1.ObjectMeta{Name: "upload"},
+			Spec: rukpakv1alpha1.BundleSpec{
+				Source: rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeUpload},
+			},
+		},
+		&rukpakv1alpha1.BundleInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "installed"},
+		},
+	).Build()
+
+	return &Server{
+		Client: cl,
+		BundleStorage: fakeStorage{
+			"unpacked": {
+				newObject("apps/v1", "Deployment", "ns", "operator"),
+				newObject("v1", "Namespace", "", "ns"),
+			},
+		},
+		ReportStorage: fakeStorage{
+			"installed": {newObject("core.rukpak.io/v1alpha1", "InstallReport", "", "installed")},
+		},
+		UploadStorage: fakeStorage{},
+		Authorizer:    authorizer,
+	}
+}
+
+func TestServerStatusCodes(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		method      string
+		path        string
+		authorizer  *fakeAuthorizer
+		status      int
+	}{
+		{
+			description: "unauthenticated requests are rejected",
+			path:        "/bundles/unpacked/content",
+			authorizer:  &fakeAuthorizer{err: ErrUnauthenticated},
+			status:      http.StatusUnauthorized,
+		},
+		{
+			description: "unauthorized requests are rejected",
+			path:        "/bundles/unpacked/content",
+			authorizer:  &fakeAuthorizer{allowed: false},
+			status:      http.StatusForbidden,
+		},
+		{
+			description: "only reads are permitted",
+			method:      http.MethodPost,
+			path:        "/bundles/unpacked/content",
+			authorizer:  &fakeAuthorizer{allowed: true},
+			status:      http.StatusMethodNotAllowed,
+		},
+		{
+			description: "unknown paths are not found",
+			path:        "/bundles/unpacked/other",
+			authorizer:  &fakeAuthorizer{allowed: true},
+			status:      http.StatusNotFound,
+		},
+		{
+			description: "missing bundles are not found",
+			path:        "/bundles/missing/content",
+			authorizer:  &fakeAuthorizer{allowed: true},
+			status:      http.StatusNotFound,
+		},
+		{
+			description: "bundles that have not been unpacked are a conflict",
+			path:        "/bundles/pending/content",
+			authorizer:  &fakeAuthorizer{allowed: true},
+			status:      http.StatusConflict,
+		},
+		{
+			description: "unpacked bundles are served",
+			path:        "/bundles/unpacked/content",
+			authorizer:  &fakeAuthorizer{allowed: true},
+			status:      http.StatusOK,
+		},
+		{
+			description: "diffs require a bundle to diff to",
+			path:        "/bundles/unpacked/diff",
+			authorizer:  &fakeAuthorizer{allowed: true},
+			status:      http.StatusBadRequest,
+		},
+		{
+			description: "diffs to bundles that have not been unpacked are a conflict",
+			path:        "/bundles/unpacked/diff?to=pending",
+			authorizer:  &fakeAuthorizer{allowed: true},
+			status:      http.StatusConflict,
+		},
+		{
+			description: "diffs between unpacked bundles are served",
+			path:        "/bundles/unpacked/diff?to=unpacked",
+			authorizer:  &fakeAuthorizer{allowed: true},
+			status:      http.StatusOK,
+		},
+		{
+			description: "install reports are served",
+			path:        "/bundleinstances/installed/report",
+			authorizer:  &fakeAuthorizer{allowed: true},
+			status:      http.StatusOK,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			newTestServer(t, tt.authorizer).ServeHTTP(rec, httptest.NewRequest(method, tt.path, nil))
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestServerBundleContent(t *testing.T) {
+	authorizer := &fakeAuthorizer{allowed: true}
+	rec := httptest.NewRecorder()
+	newTestServer(t, authorizer).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bundles/unpacked/content", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `"abc"`, rec.Header().Get("ETag"))
+	require.Equal(t, authorizationv1.ResourceAttributes{
+		Verb:        "get",
+		Group:       "core.rukpak.io",
+		Version:     "v1alpha1",
+		Resource:    "bundles",
+		Subresource: "content",
+		Name:        "unpacked",
+	}, authorizer.attrs)
+
+	gzr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	tr := tar.NewReader(gzr)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+	}
+	require.Equal(t, []string{"deployment.apps_ns_operator.yaml", "namespace_ns.yaml"}, names)
+}
+
+func TestServerInstallReport(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer(t, &fakeAuthorizer{allowed: true}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bundleinstances/installed/report", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rpt := map[string]interface{}{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&rpt))
+	require.Equal(t, "InstallReport", rpt["kind"])
+}
+
+func uploadTarball(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "manifests/", Mode: 0755}))
+	for name, data := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "manifests/" + name, Mode: 0644, Size: int64(len(data))}))
+		_, err := tw.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	return buf
+}
+
+func TestServerBundleUpload(t *testing.T) {
+	valid := map[string]string{"ns.yaml": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: ns\n"}
+	for _, tt := range []struct {
+		description string
+		method      string
+		path        string
+		files       map[string]string
+		status      int
+	}{
+		{
+			description: "only uploads are permitted",
+			method:      http.MethodGet,
+			path:        "/bundles/upload/upload",
+			files:       valid,
+			status:      http.StatusMethodNotAllowed,
+		},
+		{
+			description: "missing bundles are not found",
+			path:        "/bundles/missing/upload",
+			files:       valid,
+			status:      http.StatusNotFound,
+		},
+		{
+			description: "bundles without an upload source are a conflict",
+			path:        "/bundles/pending/upload",
+			files:       valid,
+			status:      http.StatusConflict,
+		},
+		{
+			description: "invalid bundles are rejected",
+			path:        "/bundles/upload/upload",
+			files:       map[string]string{},
+			status:      http.StatusUnprocessableEntity,
+		},
+		{
+			description: "valid bundles are accepted",
+			path:        "/bundles/upload/upload",
+			files:       valid,
+			status:      http.StatusAccepted,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			rec := httptest.NewRecorder()
+			newTestServer(t, &fakeAuthorizer{allowed: true}).ServeHTTP(rec, httptest.NewRequest(method, tt.path, uploadTarball(t, tt.files)))
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestServerBundleUploadOnce(t *testing.T) {
+	authorizer := &fakeAuthorizer{allowed: true}
+	s := newTestServer(t, authorizer)
+	files := map[string]string{"ns.yaml": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: ns\n"}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/bundles/upload/upload", uploadTarball(t, files)))
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	require.Equal(t, authorizationv1.ResourceAttributes{
+		Verb:        "create",
+		Group:       "core.rukpak.io",
+		Version:     "v1alpha1",
+		Resource:    "bundles",
+		Subresource: "upload",
+		Name:        "upload",
+	}, authorizer.attrs)
+
+	b := &rukpakv1alpha1.Bundle{}
+	require.NoError(t, s.Client.Get(context.Background(), types.NamespacedName{Name: "upload"}, b))
+	uploadedDigest := b.Annotations[rukpakv1alpha1.AnnotationUploadedDigest]
+	require.True(t, strings.HasPrefix(uploadedDigest, "sha256:"), uploadedDigest)
+	require.Equal(t, uploadedDigest, strings.TrimSpace(rec.Body.String()))
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/bundles/upload/upload", uploadTarball(t, files)))
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+}
+
+func TestServerBundleSource(t *testing.T) {
+	manifest := []byte(`{"mediaType":"application/vnd.oci.image.manifest.v1+json","layers":[]}`)
+	manifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+	remote := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/bundles/test/manifests/latest":
+			_, _ = w.Write(manifest)
+		case "/combo/info/refs":
+			line := "1111111111111111111111111111111111111111 refs/heads/main\n"
+			fmt.Fprintf(w, "001e# service=git-upload-pack\n0000%04x%s0000", len(line)+4, line)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(remote.Close)
+	host := strings.TrimPrefix(remote.URL, "https://")
+
+	s := newTestServer(t, &fakeAuthorizer{allowed: true})
+	s.HTTPClient = remote.Client()
+	for _, b := range []*rukpakv1alpha1.Bundle{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "current"},
+			Spec: rukpakv1alpha1.BundleSpec{Source: rukpakv1alpha1.BundleSource{
+				Type:  rukpakv1alpha1.SourceTypeImage,
+				Image: &rukpakv1alpha1.ImageSource{Ref: host + "/bundles/test:latest"},
+			}},
+			Status: rukpakv1alpha1.BundleStatus{Digest: host + "/bundles/test@" + manifestDigest},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "outdated"},
+			Spec: rukpakv1alpha1.BundleSpec{Source: rukpakv1alpha1.BundleSource{
+				Type:  rukpakv1alpha1.SourceTypeImage,
+				Image: &rukpakv1alpha1.ImageSource{Ref: host + "/bundles/test"},
+			}},
+			Status: rukpakv1alpha1.BundleStatus{Digest: host + "/bundles/test@sha256:0123"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "missing-image"},
+			Spec: rukpakv1alpha1.BundleSpec{Source: rukpakv1alpha1.BundleSource{
+				Type:  rukpakv1alpha1.SourceTypeImage,
+				Image: &rukpakv1alpha1.ImageSource{Ref: host + "/bundles/test:v2"},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "git"},
+			Spec: rukpakv1alpha1.BundleSpec{Source: rukpakv1alpha1.BundleSource{
+				Type: rukpakv1alpha1.SourceTypeGit,
+				Git:  &rukpakv1alpha1.GitSource{Repository: remote.URL + "/combo", Ref: rukpakv1alpha1.GitRef{Branch: "main"}},
+			}},
+		},
+	} {
+		require.NoError(t, s.Client.Create(context.Background(), b))
+	}
+
+	upToDate, outdated := true, false
+	for _, tt := range []struct {
+		name     string
+		status   int
+		expected SourceCheck
+	}{
+		{
+			name:   "current",
+			status: http.StatusOK,
+			expected: SourceCheck{Bundle: "current", Type: rukpakv1alpha1.SourceTypeImage, Resolved: manifestDigest,
+				Unpacked: host + "/bundles/test@" + manifestDigest, UpToDate: &upToDate},
+		},
+		{
+			name:   "outdated",
+			status: http.StatusOK,
+			expected: SourceCheck{Bundle: "outdated", Type: rukpakv1alpha1.SourceTypeImage, Resolved: manifestDigest,
+				Unpacked: host + "/bundles/test@sha256:0123", UpToDate: &outdated},
+		},
+		{
+			name:     "git",
+			status:   http.StatusOK,
+			expected: SourceCheck{Bundle: "git", Type: rukpakv1alpha1.SourceTypeGit, Resolved: "1111111111111111111111111111111111111111"},
+		},
+		{name: "missing-image", status: http.StatusBadGateway},
+		{name: "upload", status: http.StatusConflict},
+		{name: "missing", status: http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bundles/"+tt.name+"/source", nil))
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+			if tt.status != http.StatusOK {
+				return
+			}
+			check := SourceCheck{}
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&check))
+			require.Equal(t, tt.expected, check)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package content
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/git"
+	"github.com/operator-framework/rukpak/internal/registry"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+// SourceCheck reports what the source of a Bundle resolves to now, compared to
+// what its provisioner unpacked.
+type SourceCheck struct {
+	Bundle string `json:"bundle"`
+	Type   string `json:"type"`
+	// Resolved is the digest of the image, or the commit of the git ref, that
+	// the source currently resolves to.
+	Resolved string `json:"resolved"`
+	// Unpacked is the digest of the unpacked content in the status of the
+	// Bundle.
+	Unpacked string `json:"unpacked,omitempty"`
+	// UpToDate reports whether the unpacked content is what the source
+	// resolves to. It is unset when this cannot be determined, as for git
+	// sources, whose unpacked commit is only recorded when they are polled.
+	UpToDate *bool `json:"upToDate,omitempty"`
+}
+
+// serveBundleSource re-resolves the image or git source of the named Bundle,
+// without fetching or storing its content, and serves a SourceCheck of it.
+func (s *Server) serveBundleSource(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.authorize(w, r, "bundles", SubresourceContent, name) {
+		return
+	}
+	ctx := r.Context()
+	b := &rukpakv1alpha1.Bundle{}
+	if err := s.Client.Get(ctx, types.NamespacedName{Name: name}, b); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	check := SourceCheck{Bundle: name, Type: b.Spec.Source.Type, Unpacked: b.Status.Digest}
+	httpClient, err := s.httpClientFor(ctx, b.Spec.Source)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("resolve source of bundle %q: %v", name, err), http.StatusBadGateway)
+		return
+	}
+	switch {
+	case b.Spec.Source.Type == rukpakv1alpha1.SourceTypeImage && b.Spec.Source.Image != nil:
+		check.Resolved, err = s.resolveImage(ctx, httpClient, *b.Spec.Source.Image)
+		if err == nil && check.Unpacked != "" {
+			upToDate := imageDigest(check.Unpacked) == check.Resolved
+			check.UpToDate = &upToDate
+		}
+	case b.Spec.Source.Type == rukpakv1alpha1.SourceTypeGit && b.Spec.Source.Git != nil:
+		check.Resolved, err = s.resolveGit(ctx, httpClient, *b.Spec.Source.Git)
+		// Only the digest of a polled branch is the commit that was unpacked.
+		if err == nil && check.Unpacked != "" && b.Spec.Source.PollInterval != nil && b.Spec.Source.Git.Ref.Branch != "" {
+			upToDate := check.Unpacked == check.Resolved
+			check.UpToDate = &upToDate
+		}
+	default:
+		http.Error(w, fmt.Sprintf("bundle %q has a %q source, which cannot be resolved", name, b.Spec.Source.Type), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("resolve source of bundle %q: %v", name, err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(check); err != nil {
+		log.FromContext(ctx).Error(err, "failed to write source check", util.LogKeyBundle, name)
+	}
+}
+
+// httpClientFor returns the HTTP client with which source is resolved, which
+// trusts the certificates of its CA bundle, or of the CA bundle of the
+// RukpakConfig.
+func (s *Server) httpClientFor(ctx context.Context, source rukpakv1alpha1.BundleSource) (*http.Client, error) {
+	config, err := util.GetRukpakConfig(ctx, s.Client)
+	if err != nil {
+		return nil, err
+	}
+	caBundle := util.CABundleFor(config, source)
+	if caBundle == nil {
+		return s.HTTPClient, nil
+	}
+	data, err := util.LoadCABundle(ctx, s.Client, s.SecretNamespace, *caBundle)
+	if err != nil {
+		return nil, err
+	}
+	return util.HTTPClientWithCABundle(s.HTTPClient, data)
+}
+
+func (s *Server) resolveImage(ctx context.Context, httpClient *http.Client, source rukpakv1alpha1.ImageSource) (string, error) {
+	rc, err := registry.NewClient(httpClient, source.Ref)
+	if err != nil {
+		return "", err
+	}
+	if source.PullSecret != "" {
+		secret, err := s.getSecret(ctx, source.PullSecret)
+		if err != nil {
+			return "", err
+		}
+		rc.Username, rc.Password, err = registry.DockerConfigCredentials(secret.Data[corev1.DockerConfigJsonKey], rc.Registry())
+		if err != nil {
+			return "", fmt.Errorf("image pull secret %q: %w", source.PullSecret, err)
+		}
+	}
+	return rc.ResolveDigest(ctx, registry.ParseReference(source.Ref))
+}
+
+func (s *Server) resolveGit(ctx context.Context, httpClient *http.Client, source rukpakv1alpha1.GitSource) (string, error) {
+	var username, password string
+	if source.Auth != nil {
+		secret, err := s.getSecret(ctx, source.Auth.SecretName)
+		if err != nil {
+			return "", err
+		}
+		username, password = string(secret.Data[corev1.BasicAuthUsernameKey]), string(secret.Data[corev1.BasicAuthPasswordKey])
+	}
+	return git.ResolveRef(ctx, httpClient, source, username, password)
+}
+
+func (s *Server) getSecret(ctx context.Context, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := s.Client.Get(ctx, types.NamespacedName{Namespace: s.SecretNamespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("get secret %q: %w", name, err)
+	}
+	return secret, nil
+}
+
+// imageDigest returns the digest of an image ID, as reported in the status of
+// a container.
+func imageDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	return imageID
+}
@@ -0,0 +1,110 @@
+package content
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/nlepage/go-tarfs"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/digest"
+	"github.com/operator-framework/rukpak/internal/manifests"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+// MaxUploadSize is the maximum size of the gzipped tarball of an upload.
+const MaxUploadSize = 32 << 20
+
+// serveBundleUpload accepts a gzipped tarball of a plain bundle as the content
+// of the named Bundle, which must have an upload source. The validated objects
+// of the bundle are staged in the UploadStorage, and the Bundle is annotated
+// with the digest of the tarball so that its provisioner unpacks them. The
+// content of a Bundle can only be uploaded once.
+func (s *Server) serveBundleUpload(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if s.UploadStorage == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.authorizeVerb(w, r, "create", "bundles", SubresourceUpload, name) {
+		return
+	}
+
+	ctx := r.Context()
+	b := &rukpakv1alpha1.Bundle{}
+	if err := s.Client.Get(ctx, types.NamespacedName{Name: name}, b); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+	if b.Spec.Source.Type != rukpakv1alpha1.SourceTypeUpload {
+		http.Error(w, fmt.Sprintf("bundle %q does not have an %q source", name, rukpakv1alpha1.SourceTypeUpload), http.StatusConflict)
+		return
+	}
+	if _, ok := b.Annotations[rukpakv1alpha1.AnnotationUploadedDigest]; ok {
+		http.Error(w, fmt.Sprintf("the content of bundle %q has already been uploaded", name), http.StatusConflict)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxUploadSize+1))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("read upload: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(body) > MaxUploadSize {
+		http.Error(w, fmt.Sprintf("upload exceeds the maximum size of %d bytes", MaxUploadSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	objects, err := uploadedObjects(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// The kind of the owner labels the stored content, and is not set on
+	// objects read with a typed client.
+	b.SetGroupVersionKind(rukpakv1alpha1.GroupVersion.WithKind("Bundle"))
+	if err := s.UploadStorage.Store(ctx, b, objects); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+	uploadedDigest := digest.Digest(digest.OrDefault(s.DigestAlgorithm), body)
+	patch := client.MergeFrom(b.DeepCopy())
+	if b.Annotations == nil {
+		b.Annotations = map[string]string{}
+	}
+	b.Annotations[rukpakv1alpha1.AnnotationUploadedDigest] = uploadedDigest
+	if err := s.Client.Patch(ctx, b, patch); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	log.FromContext(ctx).V(util.LogLevelDebug).Info("accepted bundle upload", util.LogKeyBundle, name, util.LogKeyDigest, uploadedDigest)
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, uploadedDigest)
+}
+
+// uploadedObjects validates the plain bundle in the gzipped tarball data, and
+// returns its objects.
+func uploadedObjects(data []byte) ([]client.Object, error) {
+	gzr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("read upload gzip: %w", err)
+	}
+	bundleFS, err := tarfs.New(gzr)
+	if err != nil {
+		return nil, fmt.Errorf("read upload tarball: %w", err)
+	}
+	return manifests.Validate(bundleFS)
+}
@@ -0,0 +1,106 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/rukpak/internal/manifests"
+)
+
+// helmHookAnnotations are the annotations of Helm hooks, which are removed
+// from the hooks that are converted to plain hooks.
+var helmHookAnnotations = []string{release.HookAnnotation, release.HookWeightAnnotation, release.HookDeleteAnnotation}
+
+// HelmChart renders chrt with its default values, as a release named
+// releaseName for kubeVersion, into the objects of a plain bundle. Its CRDs
+// are included. Hooks of Jobs and Pods that run on installs and upgrades are
+// converted to plain hooks, and other hooks, such as tests, are dropped.
+func HelmChart(chrt *chart.Chart, releaseName string, kubeVersion *chartutil.KubeVersion) (*Plain, error) {
+	install := action.NewInstall(&action.Configuration{Log: func(string, ...interface{}) {}})
+	install.ReleaseName = releaseName
+	install.DryRun = true
+	install.ClientOnly = true
+	install.Replace = true
+	install.IncludeCRDs = true
+	install.KubeVersion = kubeVersion
+	rel, err := install.Run(chrt, nil)
+	if err != nil {
+		return nil, fmt.Errorf("render chart %q: %w", chrt.Name(), err)
+	}
+
+	objs, err := decodeManifest(rel.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("decode rendered chart %q: %w", chrt.Name(), err)
+	}
+	for _, hook := range rel.Hooks {
+		if hook.Kind != "Job" && hook.Kind != "Pod" {
+			continue
+		}
+		events := plainHookEvents(hook.Events)
+		if len(events) == 0 {
+			continue
+		}
+		hookObjs, err := decodeManifest(hook.Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("decode hook %q of rendered chart %q: %w", hook.Name, chrt.Name(), err)
+		}
+		for _, obj := range hookObjs {
+			annotations := obj.GetAnnotations()
+			for _, key := range helmHookAnnotations {
+				delete(annotations, key)
+			}
+			annotations[manifests.HookAnnotation] = strings.Join(events, ",")
+			obj.SetAnnotations(annotations)
+			objs = append(objs, obj)
+		}
+	}
+	if len(objs) == 0 {
+		return nil, manifests.ErrNoObjects
+	}
+	return &Plain{Objects: objs}, nil
+}
+
+// plainHookEvents returns the Helm hook events that plain bundles run hooks
+// on, in a stable order.
+func plainHookEvents(events []release.HookEvent) []string {
+	var plain []string
+	for _, event := range events {
+		switch event {
+		case release.HookPreInstall, release.HookPostInstall, release.HookPreUpgrade, release.HookPostUpgrade:
+			plain = append(plain, event.String())
+		}
+	}
+	sort.Strings(plain)
+	return plain
+}
+
+// decodeManifest returns the objects of the YAML documents of manifest,
+// skipping empty documents.
+func decodeManifest(manifest string) ([]client.Object, error) {
+	var objs []client.Object
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+}
@@ -0,0 +1,354 @@
+// Package cosign verifies the cosign signatures of bundle images, as stored
+// by `cosign sign` in the registry of the signed image.
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/registry"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+const (
+	annotationSignature   = "dev.cosignproject.cosign/signature"
+	annotationCertificate = "dev.sigstore.cosign/certificate"
+	annotationChain       = "dev.sigstore.cosign/chain"
+	annotationBundle      = "dev.sigstore.cosign/bundle"
+
+	signaturePayloadType = "cosign container image signature"
+)
+
+var (
+	// oidIssuer and oidIssuerV2 are the Fulcio certificate extensions that
+	// contain the OIDC issuer of the signing identity.
+	oidIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Verifier verifies that a bundle image is signed by a trusted signer.
+type Verifier struct {
+	// HTTPClient is the client with which signatures are fetched from
+	// registries. util.DefaultHTTPClient is used when HTTPClient is nil.
+	HTTPClient *http.Client
+
+	// FulcioRoots are the root certificates of the Fulcio certificate
+	// authority, and RekorPublicKeys are the public keys of the Rekor
+	// transparency log. Both are required to verify keyless signatures.
+	FulcioRoots     *x509.CertPool
+	RekorPublicKeys []crypto.PublicKey
+}
+
+// Verify verifies that the image with the given digest in the repository of
+// ref has a valid signature by at least one of the signers trusted by policy.
+func (v *Verifier) Verify(ctx context.Context, ref, digest string, policy rukpakv1alpha1.ImageVerification) error {
+	if len(policy.PublicKeys) == 0 && len(policy.Keyless) == 0 {
+		return errors.New("no trusted public keys or keyless identities are configured")
+	}
+	keys := make([]crypto.PublicKey, 0, len(policy.PublicKeys))
+	for i, data := range policy.PublicKeys {
+		key, err := ParsePublicKey([]byte(data))
+		if err != nil {
+			return fmt.Errorf("parse public key %d: %w", i, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(policy.Keyless) > 0 && (v.FulcioRoots == nil || len(v.RekorPublicKeys) == 0) {
+		return errors.New("keyless verification requires the Fulcio roots and Rekor public keys to be configured")
+	}
+
+	httpClient := v.HTTPClient
+	if httpClient == nil {
+		httpClient = util.DefaultHTTPClient
+	}
+	rc, err := registry.NewClient(httpClient, ref)
+	if err != nil {
+		return err
+	}
+
+	sigTag := strings.Replace(digest, ":", "-", 1) + ".sig"
+	m, err := rc.GetManifest(ctx, sigTag)
+	if errors.Is(err, registry.ErrNotFound) {
+		return fmt.Errorf("no signatures found for %s@%s", ref, digest)
+	}
+	if err != nil {
+		return fmt.Errorf("get signatures: %w", err)
+	}
+
+	var errs []string
+	for _, layer := range m.Layers {
+		err := v.verifyLayer(ctx, rc, layer, digest, keys, policy.Keyless)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("signature %s: %v", layer.Digest, err))
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no signatures found for %s@%s", ref, digest)
+	}
+	return fmt.Errorf("no valid signature by a trusted signer: %s", strings.Join(errs, "; "))
+}
+
+func (v *Verifier) verifyLayer(ctx context.Context, rc *registry.Client, layer registry.Descriptor, digest string, keys []crypto.PublicKey, identities []rukpakv1alpha1.KeylessIdentity) error {
+	sig, err := base64.StdEncoding.DecodeString(layer.Annotations[annotationSignature])
+	if err != nil || len(sig) == 0 {
+		return errors.New("missing or malformed signature")
+	}
+	payload, err := rc.GetBlob(ctx, layer.Digest)
+	if err != nil {
+		return fmt.Errorf("get payload: %w", err)
+	}
+	if err := verifyPayload(payload, digest); err != nil {
+		return err
+	}
+
+	if cert := layer.Annotations[annotationCertificate]; cert != "" && len(identities) > 0 {
+		return v.verifyKeyless(payload, sig, layer.Annotations, identities)
+	}
+	for _, key := range keys {
+		if verifySignature(key, payload, sig) == nil {
+			return nil
+		}
+	}
+	return errors.New("not signed by a trusted key")
+}
+
+// verifyPayload verifies that a simple signing payload refers to the image
+// with the given digest.
+func verifyPayload(payload []byte, digest string) error {
+	p := struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+			Type string `json:"type"`
+		} `json:"critical"`
+	}{}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("parse payload: %w", err)
+	}
+	if p.Critical.Type != signaturePayloadType {
+		return fmt.Errorf("unexpected payload type %q", p.Critical.Type)
+	}
+	if p.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("payload refers to image %s", p.Critical.Image.DockerManifestDigest)
+	}
+	return nil
+}
+
+// verifyKeyless verifies a signature made with a Fulcio certificate of one of
+// the trusted identities. The certificate must have been valid when the
+// signature was recorded in the Rekor transparency log.
+func (v *Verifier) verifyKeyless(payload, sig []byte, annotations map[string]string, identities []rukpakv1alpha1.KeylessIdentity) error {
+	certs, err := parseCertificates([]byte(annotations[annotationCertificate]))
+	if err != nil || len(certs) != 1 {
+		return errors.New("missing or malformed certificate")
+	}
+	cert := certs[0]
+	intermediates := x509.NewCertPool()
+	chain, err := parseCertificates([]byte(annotations[annotationChain]))
+	if err != nil {
+		return fmt.Errorf("parse certificate chain: %w", err)
+	}
+	for _, c := range chain {
+		intermediates.AddCert(c)
+	}
+
+	integratedTime, err := v.verifyBundle([]byte(annotations[annotationBundle]), cert, payload, sig)
+	if err != nil {
+		return fmt.Errorf("verify transparency log entry: %w", err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.FulcioRoots,
+		Intermediates: intermediates,
+		CurrentTime:   integratedTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return fmt.Errorf("verify certificate: %w", err)
+	}
+	if err := verifySignature(cert.PublicKey, payload, sig); err != nil {
+		return err
+	}
+
+	issuer := certificateIssuer(cert)
+	for _, id := range identities {
+		if id.Issuer == issuer && certificateHasSubject(cert, id.Subject) {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate identity %q from issuer %q is not trusted", certificateSubjects(cert), issuer)
+}
+
+// rekorBundle is the offline proof of inclusion of a signature in the Rekor
+// transparency log.
+type rekorBundle struct {
+	SignedEntryTimestamp []byte       `json:"SignedEntryTimestamp"`
+	Payload              rekorPayload `json:"Payload"`
+}
+
+// rekorPayload is the signed content of a Rekor entry. The order of its
+// fields is that of its canonical JSON encoding, which is signed by Rekor.
+type rekorPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// verifyBundle verifies that the Rekor bundle is signed by a trusted Rekor
+// key and records the given signature, and returns the time at which the
+// signature was recorded.
+func (v *Verifier) verifyBundle(data []byte, cert *x509.Certificate, payload, sig []byte) (time.Time, error) {
+	if len(data) == 0 {
+		return time.Time{}, errors.New("missing transparency log bundle")
+	}
+	b := rekorBundle{}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return time.Time{}, fmt.Errorf("parse bundle: %w", err)
+	}
+	signed, err := json.Marshal(b.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	verified := false
+	for _, key := range v.RekorPublicKeys {
+		if verifySignature(key, signed, b.SignedEntryTimestamp) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return time.Time{}, errors.New("bundle is not signed by a trusted Rekor key")
+	}
+
+	body, err := base64.StdEncoding.DecodeString(b.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decode entry body: %w", err)
+	}
+	entry := struct {
+		Spec struct {
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+			Signature struct {
+				Content   []byte `json:"content"`
+				PublicKey struct {
+					Content []byte `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return time.Time{}, fmt.Errorf("parse entry body: %w", err)
+	}
+	if !bytes.Equal(entry.Spec.Signature.Content, sig) {
+		return time.Time{}, errors.New("entry does not record the signature")
+	}
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != fmt.Sprintf("%x", sha256.Sum256(payload)) {
+		return time.Time{}, errors.New("entry does not record the signed payload")
+	}
+	entryCerts, err := parseCertificates(entry.Spec.Signature.PublicKey.Content)
+	if err != nil || len(entryCerts) != 1 || !entryCerts[0].Equal(cert) {
+		return time.Time{}, errors.New("entry does not record the signing certificate")
+	}
+	return time.Unix(b.Payload.IntegratedTime, 0), nil
+}
+
+// verifySignature verifies a signature of the SHA-256 digest of data, or of
+// data itself for ed25519 keys.
+func verifySignature(key crypto.PublicKey, data, sig []byte) error {
+	digest := sha256.Sum256(data)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(k, digest[:], sig) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(k, data, sig) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return errors.New("invalid signature")
+}
+
+// ParsePublicKey parses a PEM-encoded ECDSA, RSA or ed25519 public key.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidIssuer):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+func certificateSubjects(cert *x509.Certificate) []string {
+	subjects := append([]string{}, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		subjects = append(subjects, u.String())
+	}
+	return subjects
+}
+
+func certificateHasSubject(cert *x509.Certificate, subject string) bool {
+	for _, s := range certificateSubjects(cert) {
+		if s == subject {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package cosign
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/registry"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// fakeRegistry serves the signature manifests and blobs of images in the
+// repository "bundles/test", and requires a bearer token to do so.
+type fakeRegistry struct {
+	manifests map[string][]byte
+	blobs     map[string][]byte
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		_, _ = w.Write([]byte(`{"token":"anonymous"}`))
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer anonymous" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="https://%s/token",service="test"`, r.Host))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var data []byte
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v2/bundles/test/manifests/"):
+		data = f.manifests[strings.TrimPrefix(r.URL.Path, "/v2/bundles/test/manifests/")]
+	case strings.HasPrefix(r.URL.Path, "/v2/bundles/test/blobs/"):
+		data = f.blobs[strings.TrimPrefix(r.URL.Path, "/v2/bundles/test/blobs/")]
+	}
+	if data == nil {
+		http.NotFound(w, r)
+		return
+	}
+	_, _ = w.Write(data)
+}
+
+// addSignature adds a signature of the image with the given digest to the
+// registry, with the given additional layer annotations.
+func (f *fakeRegistry) addSignature(t *testing.T, digest string, sign func(payload []byte) []byte, annotations map[string]string) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"bundles/test"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+	payloadDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(payload))
+	f.blobs[payloadDigest] = payload
+
+	layer := registry.Descriptor{
+		MediaType:   "application/vnd.dev.cosign.simplesigning.v1+json",
+		Digest:      payloadDigest,
+		Size:        int64(len(payload)),
+		Annotations: map[string]string{annotationSignature: base64.StdEncoding.EncodeToString(sign(payload))},
+	}
+	for k, v := range annotations {
+		layer.Annotations[k] = v
+	}
+	data, err := json.Marshal(registry.Manifest{MediaType: registry.MediaTypeOCIManifest, Layers: []registry.Descriptor{layer}})
+	require.NoError(t, err)
+	f.manifests[strings.Replace(digest, ":", "-", 1)+".sig"] = data
+}
+
+func newKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func signer(t *testing.T, key *ecdsa.PrivateKey) func([]byte) []byte {
+	return func(data []byte) []byte {
+		digest := sha256.Sum256(data)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		return sig
+	}
+}
+
+func newRegistry(t *testing.T) (*fakeRegistry, *httptest.Server, string) {
+	reg := &fakeRegistry{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	server := httptest.NewTLSServer(reg)
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	return reg, server, u.Host + "/bundles/test:latest"
+}
+
+func TestVerifyPublicKey(t *testing.T) {
+	reg, server, ref := newRegistry(t)
+	key, pubKey := newKey(t)
+	_, otherPubKey := newKey(t)
+	reg.addSignature(t, testDigest, signer(t, key), nil)
+	otherDigest := "sha256:" + strings.Repeat("f", 64)
+	reg.addSignature(t, otherDigest, func([]byte) []byte { return []byte("invalid") }, nil)
+
+	v := &Verifier{HTTPClient: server.Client()}
+	type testCase struct {
+		name   string
+		digest string
+		policy rukpakv1alpha1.ImageVerification
+		errMsg string
+	}
+	for _, tc := range []testCase{
+		{
+			name:   "signed by trusted key",
+			digest: testDigest,
+			policy: rukpakv1alpha1.ImageVerification{PublicKeys: []string{otherPubKey, pubKey}},
+		},
+		{
+			name:   "signed by untrusted key",
+			digest: testDigest,
+			policy: rukpakv1alpha1.ImageVerification{PublicKeys: []string{otherPubKey}},
+			errMsg: "not signed by a trusted key",
+		},
+		{
+			name:   "invalid signature",
+			digest: otherDigest,
+			policy: rukpakv1alpha1.ImageVerification{PublicKeys: []string{pubKey}},
+			errMsg: "not signed by a trusted key",
+		},
+		{
+			name:   "unsigned image",
+			digest: "sha256:" + strings.Repeat("0", 64),
+			policy: rukpakv1alpha1.ImageVerification{PublicKeys: []string{pubKey}},
+			errMsg: "no signatures found",
+		},
+		{
+			name:   "no trusted signers",
+			digest: testDigest,
+			errMsg: "no trusted public keys or keyless identities are configured",
+		},
+		{
+			name:   "keyless without roots",
+			digest: testDigest,
+			policy: rukpakv1alpha1.ImageVerification{Keyless: []rukpakv1alpha1.KeylessIdentity{{Issuer: "https://issuer", Subject: "a@b.c"}}},
+			errMsg: "keyless verification requires the Fulcio roots and Rekor public keys to be configured",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.Verify(context.Background(), ref, tc.digest, tc.policy)
+			if tc.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
+
+func TestVerifyPayloadDigestMismatch(t *testing.T) {
+	reg, server, ref := newRegistry(t)
+	key, pubKey := newKey(t)
+	reg.addSignature(t, testDigest, signer(t, key), nil)
+	// Serve the signature of testDigest as the signature of another image.
+	otherDigest := "sha256:" + strings.Repeat("f", 64)
+	reg.manifests[strings.Replace(otherDigest, ":", "-", 1)+".sig"] = reg.manifests[strings.Replace(testDigest, ":", "-", 1)+".sig"]
+
+	v := &Verifier{HTTPClient: server.Client()}
+	err := v.Verify(context.Background(), ref, otherDigest, rukpakv1alpha1.ImageVerification{PublicKeys: []string{pubKey}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "payload refers to image "+testDigest)
+}
+
+func TestVerifyKeyless(t *testing.T) {
+	reg, server, ref := newRegistry(t)
+
+	rootKey, _ := newKey(t)
+	root := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, root, root, &rootKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	root, err = x509.ParseCertificate(rootDER)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	signingKey, _ := newKey(t)
+	issuer, err := asn1.Marshal("https://token.actions.githubusercontent.com")
+	require.NoError(t, err)
+	signedAt := time.Now().Add(-30 * time.Minute)
+	leaf := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       signedAt.Add(-time.Minute),
+		NotAfter:        signedAt.Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses:  []string{"signer@example.com"},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, root, &signingKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+
+	rekorKey, rekorPubKey := newKey(t)
+	rekorPub, err := ParsePublicKey([]byte(rekorPubKey))
+	require.NoError(t, err)
+
+	var bundle []byte
+	reg.addSignature(t, testDigest, func(payload []byte) []byte {
+		sig := signer(t, signingKey)(payload)
+		body, err := json.Marshal(map[string]interface{}{
+			"apiVersion": "0.0.1",
+			"kind":       "hashedrekord",
+			"spec": map[string]interface{}{
+				"data":      map[string]interface{}{"hash": map[string]string{"algorithm": "sha256", "value": fmt.Sprintf("%x", sha256.Sum256(payload))}},
+				"signature": map[string]interface{}{"content": sig, "publicKey": map[string]interface{}{"content": leafPEM}},
+			},
+		})
+		require.NoError(t, err)
+		p := rekorPayload{Body: base64.StdEncoding.EncodeToString(body), IntegratedTime: signedAt.Unix(), LogID: "log", LogIndex: 1}
+		signed, err := json.Marshal(p)
+		require.NoError(t, err)
+		bundle, err = json.Marshal(rekorBundle{SignedEntryTimestamp: signer(t, rekorKey)(signed), Payload: p})
+		require.NoError(t, err)
+		return sig
+	}, map[string]string{annotationCertificate: string(leafPEM)})
+	// The bundle is only known once the payload is signed.
+	m := registry.Manifest{}
+	sigTag := strings.Replace(testDigest, ":", "-", 1) + ".sig"
+	require.NoError(t, json.Unmarshal(reg.manifests[sigTag], &m))
+	m.Layers[0].Annotations[annotationBundle] = string(bundle)
+	reg.manifests[sigTag], err = json.Marshal(m)
+	require.NoError(t, err)
+
+	v := &Verifier{HTTPClient: server.Client(), FulcioRoots: roots, RekorPublicKeys: []crypto.PublicKey{rekorPub}}
+	type testCase struct {
+		name     string
+		identity rukpakv1alpha1.KeylessIdentity
+		verifier *Verifier
+		errMsg   string
+	}
+	for _, tc := range []testCase{
+		{
+			name:     "trusted identity",
+			identity: rukpakv1alpha1.KeylessIdentity{Issuer: "https://token.actions.githubusercontent.com", Subject: "signer@example.com"},
+			verifier: v,
+		},
+		{
+			name:     "untrusted subject",
+			identity: rukpakv1alpha1.KeylessIdentity{Issuer: "https://token.actions.githubusercontent.com", Subject: "other@example.com"},
+			verifier: v,
+			errMsg:   "is not trusted",
+		},
+		{
+			name:     "untrusted issuer",
+			identity: rukpakv1alpha1.KeylessIdentity{Issuer: "https://accounts.google.com", Subject: "signer@example.com"},
+			verifier: v,
+			errMsg:   "is not trusted",
+		},
+		{
+			name:     "untrusted Rekor key",
+			identity: rukpakv1alpha1.KeylessIdentity{Issuer: "https://token.actions.githubusercontent.com", Subject: "signer@example.com"},
+			verifier: &Verifier{HTTPClient: server.Client(), FulcioRoots: roots, RekorPublicKeys: []crypto.PublicKey{&signingKey.PublicKey}},
+			errMsg:   "bundle is not signed by a trusted Rekor key",
+		},
+		{
+			name:     "untrusted Fulcio root",
+			identity: rukpakv1alpha1.KeylessIdentity{Issuer: "https://token.actions.githubusercontent.com", Subject: "signer@example.com"},
+			verifier: &Verifier{HTTPClient: server.Client(), FulcioRoots: x509.NewCertPool(), RekorPublicKeys: []crypto.PublicKey{rekorPub}},
+			errMsg:   "verify certificate",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.verifier.Verify(context.Background(), ref, testDigest, rukpakv1alpha1.ImageVerification{Keyless: []rukpakv1alpha1.KeylessIdentity{tc.identity}})
+			if tc.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Package crdvalidator validates the updates of the CRDs installed by rukpak
+// against the custom resources stored for them, so that the install of a
+// bundle cannot make existing custom resources unreadable.
+package crdvalidator
+
+import (
+	"context"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+// Path is the path of the webhook server at which the Handler is served.
+const Path = "/validate-crd"
+
+// ownerKindLabel is the label of the objects installed by rukpak, whose value
+// is the kind of the object that installed them.
+const ownerKindLabel = "core.rukpak.io/owner-kind"
+
+var _ admission.Handler = &Handler{}
+var _ admission.DecoderInjector = &Handler{}
+
+// Handler denies the updates of CRDs installed by rukpak that remove a stored
+// version, or whose changed schemas do not validate the existing custom
+// resources of the CRD. The updates of other CRDs are allowed.
+type Handler struct {
+	// Client lists the existing custom resources of the updated CRDs.
+	Client client.Client
+
+	decoder *admission.Decoder
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (h *Handler) InjectDecoder(d *admission.Decoder) error {
+	h.decoder = d
+	return nil
+}
+
+// Handle implements admission.Handler.
+func (h *Handler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+	oldCRD, newCRD := &apiextensionsv1.CustomResourceDefinition{}, &apiextensionsv1.CustomResourceDefinition{}
+	if err := h.decoder.DecodeRaw(req.OldObject, oldCRD); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if err := h.decoder.DecodeRaw(req.Object, newCRD); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !installedByRukpak(oldCRD) && !installedByRukpak(newCRD) {
+		return admission.Allowed("")
+	}
+	if err := util.ValidateCRDUpgrade(ctx, h.Client, oldCRD, newCRD); err != nil {
+		log.FromContext(ctx).Info("denied unsafe upgrade of CRD", "crd", newCRD.Name, "reason", err.Error())
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// installedByRukpak returns whether crd is labeled as installed by rukpak.
+func installedByRukpak(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	_, ok := crd.GetLabels()[ownerKindLabel]
+	return ok
+}
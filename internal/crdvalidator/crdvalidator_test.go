@@ -0,0 +1,116 @@
+package crdvalidator
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+	widget := &unstructured.Unstructured{}
+	widget.SetAPIVersion("example.com/v1")
+	widget.SetKind("Widget")
+	widget.SetNamespace("default")
+	widget.SetName("small")
+	widget.Object["spec"] = map[string]interface{}{"size": int64(1)}
+	h := &Handler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(widget).Build()}
+	decoder, err := admission.NewDecoder(scheme)
+	require.NoError(t, err)
+	require.NoError(t, h.InjectDecoder(decoder))
+
+	crd := func(installed bool, required ...string) *apiextensionsv1.CustomResourceDefinition {
+		crd := &apiextensionsv1.CustomResourceDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition"},
+			ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Group: "example.com",
+				Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "widgets", Kind: "Widget", ListKind: "WidgetList"},
+				Scope: apiextensionsv1.NamespaceScoped,
+				Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+					Name:    "v1",
+					Served:  true,
+					Storage: true,
+					Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"spec": {
+								Type:     "object",
+								Required: required,
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"size":  {Type: "integer"},
+									"color": {Type: "string"},
+								},
+							},
+						},
+					}},
+				}},
+			},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: []string{"v1"}},
+		}
+		if installed {
+			crd.Labels = map[string]string{ownerKindLabel: "BundleInstance"}
+		}
+		return crd
+	}
+	raw := func(crd *apiextensionsv1.CustomResourceDefinition) runtime.RawExtension {
+		data, err := json.Marshal(crd)
+		require.NoError(t, err)
+		return runtime.RawExtension{Raw: data}
+	}
+
+	for _, tt := range []struct {
+		name        string
+		operation   admissionv1.Operation
+		oldCRD      *apiextensionsv1.CustomResourceDefinition
+		newCRD      *apiextensionsv1.CustomResourceDefinition
+		wantAllowed bool
+	}{
+		{
+			name:        "create",
+			operation:   admissionv1.Create,
+			newCRD:      crd(true, "color"),
+			wantAllowed: true,
+		},
+		{
+			name:        "compatible update",
+			operation:   admissionv1.Update,
+			oldCRD:      crd(true),
+			newCRD:      crd(true, "size"),
+			wantAllowed: true,
+		},
+		{
+			name:      "incompatible update",
+			operation: admissionv1.Update,
+			oldCRD:    crd(true),
+			newCRD:    crd(true, "color"),
+		},
+		{
+			name:        "incompatible update of a CRD not installed by rukpak",
+			operation:   admissionv1.Update,
+			oldCRD:      crd(false),
+			newCRD:      crd(false, "color"),
+			wantAllowed: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: tt.operation, Object: raw(tt.newCRD)}}
+			if tt.oldCRD != nil {
+				req.OldObject = raw(tt.oldCRD)
+			}
+			resp := h.Handle(context.Background(), req)
+			require.Equal(t, tt.wantAllowed, resp.Allowed, resp.Result)
+		})
+	}
+}
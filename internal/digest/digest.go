@@ -0,0 +1,127 @@
+// Package digest computes the digests that identify bundle content, such as
+// the names of the chart templates of a release and the keys under which
+// content is stored, with a configurable hash algorithm.
+package digest
+
+import (
+	"crypto"
+	// Register the implementations of the supported algorithms.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// Algorithm is a hash algorithm that digests are computed with.
+type Algorithm interface {
+	// Name returns the name of the algorithm, which prefixes its digests.
+	Name() string
+	// New returns a hash.Hash that computes digests with the algorithm.
+	New() hash.Hash
+}
+
+type cryptoAlgorithm struct {
+	name string
+	hash crypto.Hash
+}
+
+func (a cryptoAlgorithm) Name() string   { return a.name }
+func (a cryptoAlgorithm) New() hash.Hash { return a.hash.New() }
+
+var (
+	// SHA256 is the default algorithm.
+	SHA256 Algorithm = cryptoAlgorithm{name: "sha256", hash: crypto.SHA256}
+	SHA384 Algorithm = cryptoAlgorithm{name: "sha384", hash: crypto.SHA384}
+	SHA512 Algorithm = cryptoAlgorithm{name: "sha512", hash: crypto.SHA512}
+)
+
+// algorithms are the supported algorithms by name. Each of them is approved
+// by FIPS 140.
+var algorithms = map[string]Algorithm{
+	SHA256.Name(): SHA256,
+	SHA384.Name(): SHA384,
+	SHA512.Name(): SHA512,
+}
+
+// OrDefault returns a, or SHA256 when a is nil.
+func OrDefault(a Algorithm) Algorithm {
+	if a == nil {
+		return SHA256
+	}
+	return a
+}
+
+// Sum returns the hex-encoded digest of data computed with a.
+func Sum(a Algorithm, data []byte) string {
+	h := a.New()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Digest returns the digest of data computed with a, in the form
+// "<algorithm>:<hex>".
+func Digest(a Algorithm, data []byte) string {
+	return a.Name() + ":" + Sum(a, data)
+}
+
+// Parse splits digest into its algorithm and hex-encoded sum.
+func Parse(digest string) (Algorithm, string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 {
+		return nil, "", fmt.Errorf("invalid digest %q: expected <algorithm>:<hex>", digest)
+	}
+	a, ok := algorithms[parts[0]]
+	if !ok {
+		return nil, "", fmt.Errorf("invalid digest %q: unsupported algorithm %q", digest, parts[0])
+	}
+	return a, parts[1], nil
+}
+
+// Verify returns an error if data does not match digest, which may be
+// computed with any supported algorithm.
+func Verify(digest string, data []byte) error {
+	a, sum, err := Parse(digest)
+	if err != nil {
+		return err
+	}
+	if Sum(a, data) != sum {
+		return fmt.Errorf("digest mismatch: expected %s", digest)
+	}
+	return nil
+}
+
+// Options selects the algorithm of a manager at startup.
+type Options struct {
+	Algorithm string
+	// FIPS requires the algorithm to be implemented by a FIPS 140 validated
+	// cryptographic module, which is only available in binaries built with
+	// GOEXPERIMENT=boringcrypto.
+	FIPS bool
+}
+
+// BindFlags binds the digest options to flags in fs.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Algorithm, "digest-algorithm", SHA256.Name(),
+		fmt.Sprintf("The hash algorithm of the digests of bundle content. One of %s.", strings.Join(names(), ", ")))
+	fs.BoolVar(&o.FIPS, "fips", false,
+		"Require content to be hashed by a FIPS 140 validated cryptographic module, and fail at startup if the binary does not provide one.")
+}
+
+// New returns the configured Algorithm.
+func (o *Options) New() (Algorithm, error) {
+	a, ok := algorithms[o.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest algorithm %q: must be one of %s", o.Algorithm, strings.Join(names(), ", "))
+	}
+	if o.FIPS && !fipsValidated() {
+		return nil, fmt.Errorf("FIPS mode requires a binary built with GOEXPERIMENT=boringcrypto")
+	}
+	return a, nil
+}
+
+func names() []string {
+	return []string{SHA256.Name(), SHA384.Name(), SHA512.Name()}
+}
@@ -0,0 +1,63 @@
+package digest
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigest(t *testing.T) {
+	data := []byte("hello\n")
+	for _, tt := range []struct {
+		algorithm Algorithm
+		expected  string
+	}{
+		{SHA256, "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+		{SHA512, "sha512:e7c22b994c59d9cf2b48e549b1e24666636045930d3da7c1acb299d1c3b7f931f94aae41edda2c2b207a36e10f8bcb8d45223e54878f5b316e7ce3b6bc019629"},
+	} {
+		t.Run(tt.algorithm.Name(), func(t *testing.T) {
+			d := Digest(tt.algorithm, data)
+			require.Equal(t, tt.expected, d)
+			require.NoError(t, Verify(d, data))
+			require.EqualError(t, Verify(d, []byte("tampered")), "digest mismatch: expected "+tt.expected)
+		})
+	}
+	require.EqualError(t, Verify("md5:b1946ac92492d2347c6235b4d2611184", data), `invalid digest "md5:b1946ac92492d2347c6235b4d2611184": unsupported algorithm "md5"`)
+	require.EqualError(t, Verify("5891b5b5", data), `invalid digest "5891b5b5": expected <algorithm>:<hex>`)
+}
+
+func TestOptions(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		args     []string
+		expected Algorithm
+		err      string
+	}{
+		{name: "defaults to sha256", expected: SHA256},
+		{name: "selects an algorithm", args: []string{"--digest-algorithm=sha384"}, expected: SHA384},
+		{name: "rejects unsupported algorithms", args: []string{"--digest-algorithm=md5"}, err: `unsupported digest algorithm "md5": must be one of sha256, sha384, sha512`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			o := Options{}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			o.BindFlags(fs)
+			require.NoError(t, fs.Parse(tt.args))
+			a, err := o.New()
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, a)
+		})
+	}
+
+	o := Options{Algorithm: "sha256", FIPS: true}
+	_, err := o.New()
+	if fipsValidated() {
+		require.NoError(t, err)
+	} else {
+		require.EqualError(t, err, "FIPS mode requires a binary built with GOEXPERIMENT=boringcrypto")
+	}
+}
@@ -0,0 +1,12 @@
+//go:build goexperiment.boringcrypto
+// +build goexperiment.boringcrypto
+
+package digest
+
+import "crypto/boring"
+
+// fipsValidated returns true if the hash algorithms are implemented by the
+// FIPS 140 validated BoringCrypto module.
+func fipsValidated() bool {
+	return boring.Enabled()
+}
@@ -0,0 +1,10 @@
+//go:build !goexperiment.boringcrypto
+// +build !goexperiment.boringcrypto
+
+package digest
+
+// fipsValidated returns false: the hash algorithms are implemented by the
+// standard library, which is not FIPS 140 validated.
+func fipsValidated() bool {
+	return false
+}
@@ -0,0 +1,71 @@
+// Package features defines the feature gates of rukpak, so that experimental
+// capabilities ship as alpha or beta toggles of the --feature-gates flag of
+// its binaries rather than in separate builds.
+package features
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/component-base/featuregate"
+)
+
+const (
+	// UploadSource accepts the content uploaded to Bundles with an upload
+	// source, and unpacks it.
+	UploadSource featuregate.Feature = "UploadSource"
+
+	// ServerSideApply installs the BundleInstances that select the
+	// ServerSideApply engine. BundleInstances that select it fail to install
+	// when it is disabled, but are still uninstalled.
+	ServerSideApply featuregate.Feature = "ServerSideApply"
+
+	// DriftCorrection reapplies the drifted objects of BundleInstances with
+	// the Correct drift policy. When it is disabled, their drift is only
+	// detected, as with the Detect drift policy.
+	DriftCorrection featuregate.Feature = "DriftCorrection"
+
+	// LocalPathSource unpacks Bundles with a local source that reads a path
+	// mounted into the provisioner, which is meant for development clusters.
+	LocalPathSource featuregate.Feature = "LocalPathSource"
+)
+
+// defaultFeatureGates are the features of rukpak, their maturity and whether
+// they are enabled by default.
+var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	UploadSource:    {Default: true, PreRelease: featuregate.Beta},
+	ServerSideApply: {Default: true, PreRelease: featuregate.Beta},
+	DriftCorrection: {Default: true, PreRelease: featuregate.Beta},
+	LocalPathSource: {Default: false, PreRelease: featuregate.Alpha},
+}
+
+// Gate is the feature gate of a rukpak binary, which enables the features of
+// defaultFeatureGates by default, unless its flag configures them otherwise.
+type Gate struct {
+	featuregate.MutableFeatureGate
+}
+
+// NewGate returns a Gate with the default features of rukpak.
+func NewGate() Gate {
+	g := featuregate.NewFeatureGate()
+	utilruntime.Must(g.Add(defaultFeatureGates))
+	return Gate{MutableFeatureGate: g}
+}
+
+// String returns the features that the flag of the gate configures, so that
+// the gate is a flag.Value.
+func (g Gate) String() string {
+	if g.MutableFeatureGate == nil {
+		return ""
+	}
+	return g.MutableFeatureGate.(fmt.Stringer).String()
+}
+
+// BindFlags binds the gate to the --feature-gates flag in fs.
+func (g Gate) BindFlags(fs *flag.FlagSet) {
+	fs.Var(g, "feature-gates", fmt.Sprintf(
+		"A comma-separated list of key=value pairs that enable or disable alpha and beta features. Options are: %s.",
+		strings.Join(g.KnownFeatures(), ", ")))
+}
@@ -0,0 +1,60 @@
+package features
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/component-base/featuregate"
+)
+
+func TestGate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		enabled map[featuregate.Feature]bool
+		errMsg  string
+	}{
+		{
+			name: "defaults",
+			enabled: map[featuregate.Feature]bool{
+				UploadSource:    true,
+				ServerSideApply: true,
+				DriftCorrection: true,
+				LocalPathSource: false,
+			},
+		},
+		{
+			name: "toggled features",
+			args: []string{"--feature-gates=LocalPathSource=true,DriftCorrection=false"},
+			enabled: map[featuregate.Feature]bool{
+				UploadSource:    true,
+				ServerSideApply: true,
+				DriftCorrection: false,
+				LocalPathSource: true,
+			},
+		},
+		{
+			name:   "unknown feature",
+			args:   []string{"--feature-gates=Teleport=true"},
+			errMsg: `invalid value "Teleport=true" for flag -feature-gates: unrecognized feature gate: Teleport`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGate()
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			g.BindFlags(fs)
+			err := fs.Parse(tc.args)
+			if tc.errMsg != "" {
+				require.EqualError(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			for feature, enabled := range tc.enabled {
+				require.Equal(t, enabled, g.Enabled(feature), string(feature))
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package git
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"path"
+	"strings"
 
 	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
 )
@@ -10,6 +13,16 @@ import (
 const (
 	defaultDirectory = "./manifests"
 	repositoryName   = "repo"
+
+	// kustomizeRepository is where the repositories of GitSources with
+	// Kustomize are cloned to, so that the unpacker can render the
+	// kustomization of their directory along with the bases it refers to.
+	kustomizeRepository = "/manifests/repo"
+
+	// UsernameEnv and PasswordEnv are the environment variables of the clone
+	// command that hold the credentials of a GitSource with Auth.
+	UsernameEnv = "GIT_USERNAME"
+	PasswordEnv = "GIT_PASSWORD"
 )
 
 type checkoutCmd struct {
@@ -25,33 +38,126 @@ func CloneCommandFor(s rukpakv1alpha1.GitSource) (string, error) {
 	return cmd.String(), nil
 }
 
+// KustomizationDir returns the directory of the kustomization that the
+// unpacker renders for a GitSource with Kustomize.
+func KustomizationDir(s rukpakv1alpha1.GitSource) string {
+	directory := s.Directory
+	if directory == "" {
+		directory = defaultDirectory
+	}
+	return path.Join(kustomizeRepository, directory)
+}
+
 func (c *checkoutCmd) String() string {
-	var checkoutCommand string
 	var repository = c.Repository
 	var directory = c.Directory
 	var branch = c.Ref.Branch
 	var commit = c.Ref.Commit
 	var tag = c.Ref.Tag
+	var git = "git"
+	var submoduleGit = "git"
+	var target = repositoryName
+	var opts rukpakv1alpha1.GitCloneOptions
+	if c.CloneOptions != nil {
+		opts = *c.CloneOptions
+	}
 
 	if directory == "" {
 		directory = defaultDirectory
 	}
+	var directories = []string{directory}
+	// The whole repository of a kustomization is kept rather than only its
+	// directory, since the kustomization may refer to bases outside of it.
+	var copyManifests = fmt.Sprintf(" && cp -r %s/* /manifests", directory)
+	if len(c.Directories) > 0 {
+		// The manifests of each directory are copied to a nested directory of
+		// the same path, so that files of the same name do not collide.
+		directories = c.Directories
+		copyManifests = ""
+		for _, d := range directories {
+			d = path.Clean(d)
+			nested := path.Join("/manifests", d)
+			copyManifests += fmt.Sprintf(" && mkdir -p %s && cp -r %s/* %s", nested, d, nested)
+		}
+	}
+	if c.Kustomize {
+		target = kustomizeRepository
+		copyManifests = ""
+	}
+	if c.Auth != nil {
+		// The credential helper reads the credentials from the environment,
+		// so that they are not part of the command. It is scoped to the host
+		// of the repository, as submodules are fetched with the configuration
+		// of the command, and must not receive the credentials when they are
+		// hosted elsewhere. Submodules are fetched without credentials when
+		// the repository has no host to scope them to.
+		helper := fmt.Sprintf(`'!f() { echo username=$%s; echo password=$%s; }; f'`, UsernameEnv, PasswordEnv)
+		if origin := credentialOrigin(repository); origin != "" {
+			git = fmt.Sprintf("git -c credential.%s.helper=%s", origin, helper)
+			submoduleGit = git
+		} else {
+			git = "git -c credential.helper=" + helper
+		}
+	}
 
-	if commit != "" {
-		checkoutCommand = fmt.Sprintf("git clone %s %s && cd %s && git checkout %s && cp -r %s/* /manifests",
-			repository, repositoryName, repositoryName, commit, directory)
-		return checkoutCommand
+	// Sparse checkouts only fetch the contents of the directory, once the
+	// sparse checkout is configured, rather than checking out every file.
+	var filter, sparseFlags string
+	if opts.SparseCheckout {
+		filter = " --filter=blob:none"
+		sparseFlags = filter + " --no-checkout"
+	}
+	var depth = 1
+	if opts.Depth != nil {
+		depth = int(*opts.Depth)
 	}
 
-	if tag != "" {
-		checkoutCommand = fmt.Sprintf("git clone --depth 1 --branch %s %s %s && cd %s && git checkout tags/%s && cp -r %s/* /manifests",
-			tag, repository, repositoryName, repositoryName, tag, directory)
-		return checkoutCommand
+	var cloneCommand, checkout string
+	switch {
+	case commit != "" && opts.Depth != nil:
+		// A shallow clone only has the history of a branch or tag, so the
+		// commit is fetched on its own.
+		cloneCommand = fmt.Sprintf("git init %s && cd %s && git remote add origin %s && %s fetch --depth %d%s origin %s",
+			target, target, repository, git, depth, filter, commit)
+		checkout = "FETCH_HEAD"
+	case commit != "":
+		cloneCommand = fmt.Sprintf("%s clone%s %s %s && cd %s", git, sparseFlags, repository, target, target)
+		checkout = commit
+	case tag != "":
+		cloneCommand = fmt.Sprintf("%s clone --depth %d%s --branch %s %s %s && cd %s", git, depth, sparseFlags, tag, repository, target, target)
+		checkout = "tags/" + tag
+	default:
+		cloneCommand = fmt.Sprintf("%s clone --depth %d%s --branch %s %s %s && cd %s", git, depth, sparseFlags, branch, repository, target, target)
+		checkout = branch
 	}
 
-	checkoutCommand = fmt.Sprintf("git clone --depth 1 --branch %s %s %s && cd %s && git checkout %s && cp -r %s/* /manifests",
-		branch, repository, repositoryName, repositoryName, branch, directory)
-	return checkoutCommand
+	checkoutCommand := cloneCommand
+	if opts.SparseCheckout {
+		var cleaned []string
+		for _, d := range directories {
+			cleaned = append(cleaned, path.Clean(d))
+		}
+		checkoutCommand += fmt.Sprintf(" && git sparse-checkout set %s", strings.Join(cleaned, " "))
+	}
+	checkoutCommand += fmt.Sprintf(" && git checkout %s", checkout)
+	if opts.RecurseSubmodules {
+		checkoutCommand += fmt.Sprintf(" && %s submodule update --init --recursive", submoduleGit)
+		if opts.Depth != nil {
+			checkoutCommand += fmt.Sprintf(" --depth %d", depth)
+		}
+	}
+	return checkoutCommand + copyManifests
+}
+
+// credentialOrigin returns the scheme and host of the http or https
+// repository, which scope its credentials, or an empty string for other
+// repositories.
+func credentialOrigin(repository string) string {
+	u, err := url.Parse(repository)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
 }
 
 func (c *checkoutCmd) Validate() error {
@@ -79,5 +185,23 @@ func (c *checkoutCmd) Validate() error {
 		return errors.New("cannot specify both commit and tag: only one is allowed")
 	}
 
+	if len(c.Directories) > 0 && c.Directory != "" {
+		return errors.New("cannot specify both directory and directories: only one is allowed")
+	}
+
+	if len(c.Directories) > 0 && c.Kustomize {
+		return errors.New("cannot use directories with kustomize: only the kustomization of directory is rendered")
+	}
+
+	for _, d := range c.Directories {
+		if clean := path.Clean(d); path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("directory %q is not in the repository", d)
+		}
+	}
+
+	if c.CloneOptions != nil && c.CloneOptions.SparseCheckout && c.Kustomize {
+		return errors.New("cannot use a sparse checkout with kustomize: the kustomization may refer to bases outside of the directory")
+	}
+
 	return nil
 }
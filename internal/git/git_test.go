@@ -1,14 +1,18 @@
 package git
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
 )
 
 func TestCheckoutCommand(t *testing.T) {
+	var depth int32 = 10
 	var gitSources = []struct {
 		source   rukpakv1alpha1.GitSource
 		expected string
@@ -46,6 +50,124 @@ func TestCheckoutCommand(t *testing.T) {
 			expected: fmt.Sprintf("git clone --depth 1 --branch %s %s %s && cd %s && git checkout %s && cp -r %s/* /manifests",
 				"dev", "https://github.com/operator-framework/combo", repositoryName, repositoryName, "dev", "./deploy"),
 		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository: "https://github.com/operator-framework/combo",
+				Ref: rukpakv1alpha1.GitRef{
+					Branch: "main",
+				},
+				Auth: &rukpakv1alpha1.GitAuth{SecretName: "combo-credentials"},
+			},
+			expected: fmt.Sprintf("git -c credential.https://github.com.helper='!f() { echo username=$GIT_USERNAME; echo password=$GIT_PASSWORD; }; f' clone --depth 1 --branch %s %s %s && cd %s && git checkout %s && cp -r %s/* /manifests",
+				"main", "https://github.com/operator-framework/combo", repositoryName, repositoryName, "main", "./manifests"),
+		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository: "https://github.com/operator-framework/combo",
+				Directory:  "./overlays/prod",
+				Ref: rukpakv1alpha1.GitRef{
+					Branch: "main",
+				},
+				Kustomize: true,
+			},
+			expected: "git clone --depth 1 --branch main https://github.com/operator-framework/combo /manifests/repo && cd /manifests/repo && git checkout main",
+		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository: "https://github.com/operator-framework/combo",
+				Directory:  "./deploy",
+				Ref: rukpakv1alpha1.GitRef{
+					Branch: "main",
+				},
+				CloneOptions: &rukpakv1alpha1.GitCloneOptions{Depth: &depth, RecurseSubmodules: true, SparseCheckout: true},
+			},
+			expected: "git clone --depth 10 --filter=blob:none --no-checkout --branch main https://github.com/operator-framework/combo repo && cd repo && " +
+				"git sparse-checkout set deploy && git checkout main && git submodule update --init --recursive --depth 10 && cp -r ./deploy/* /manifests",
+		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository: "https://github.com/operator-framework/combo",
+				Ref: rukpakv1alpha1.GitRef{
+					Commit: "4567031e158b42263e70a7c63e29f8981a4a6135",
+				},
+				CloneOptions: &rukpakv1alpha1.GitCloneOptions{Depth: &depth},
+			},
+			expected: "git init repo && cd repo && git remote add origin https://github.com/operator-framework/combo && " +
+				"git fetch --depth 10 origin 4567031e158b42263e70a7c63e29f8981a4a6135 && git checkout FETCH_HEAD && cp -r ./manifests/* /manifests",
+		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository: "https://github.com/operator-framework/combo",
+				Ref: rukpakv1alpha1.GitRef{
+					Tag: "v0.0.1",
+				},
+				CloneOptions: &rukpakv1alpha1.GitCloneOptions{RecurseSubmodules: true},
+			},
+			expected: "git clone --depth 1 --branch v0.0.1 https://github.com/operator-framework/combo repo && cd repo && " +
+				"git checkout tags/v0.0.1 && git submodule update --init --recursive && cp -r ./manifests/* /manifests",
+		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository:   "https://github.com/operator-framework/combo",
+				Ref:          rukpakv1alpha1.GitRef{Tag: "v0.0.1"},
+				Auth:         &rukpakv1alpha1.GitAuth{SecretName: "combo-credentials"},
+				CloneOptions: &rukpakv1alpha1.GitCloneOptions{RecurseSubmodules: true},
+			},
+			expected: "git -c credential.https://github.com.helper='!f() { echo username=$GIT_USERNAME; echo password=$GIT_PASSWORD; }; f' clone --depth 1 --branch v0.0.1 https://github.com/operator-framework/combo repo && cd repo && " +
+				"git checkout tags/v0.0.1 && git -c credential.https://github.com.helper='!f() { echo username=$GIT_USERNAME; echo password=$GIT_PASSWORD; }; f' submodule update --init --recursive && cp -r ./manifests/* /manifests",
+		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository:   "git@github.com:operator-framework/combo.git",
+				Ref:          rukpakv1alpha1.GitRef{Tag: "v0.0.1"},
+				Auth:         &rukpakv1alpha1.GitAuth{SecretName: "combo-credentials"},
+				CloneOptions: &rukpakv1alpha1.GitCloneOptions{RecurseSubmodules: true},
+			},
+			expected: "git -c credential.helper='!f() { echo username=$GIT_USERNAME; echo password=$GIT_PASSWORD; }; f' clone --depth 1 --branch v0.0.1 git@github.com:operator-framework/combo.git repo && cd repo && " +
+				"git checkout tags/v0.0.1 && git submodule update --init --recursive && cp -r ./manifests/* /manifests",
+		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository: "https://github.com/operator-framework/combo",
+				Ref: rukpakv1alpha1.GitRef{
+					Branch: "main",
+				},
+				Kustomize:    true,
+				CloneOptions: &rukpakv1alpha1.GitCloneOptions{SparseCheckout: true},
+			},
+			expected: "",
+			err:      errors.New("cannot use a sparse checkout with kustomize: the kustomization may refer to bases outside of the directory"),
+		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository:   "https://github.com/operator-framework/combo",
+				Directories:  []string{"./crds", "config/rbac/"},
+				Ref:          rukpakv1alpha1.GitRef{Branch: "main"},
+				CloneOptions: &rukpakv1alpha1.GitCloneOptions{SparseCheckout: true},
+			},
+			expected: "git clone --depth 1 --filter=blob:none --no-checkout --branch main https://github.com/operator-framework/combo repo && cd repo && " +
+				"git sparse-checkout set crds config/rbac && git checkout main && " +
+				"mkdir -p /manifests/crds && cp -r crds/* /manifests/crds && mkdir -p /manifests/config/rbac && cp -r config/rbac/* /manifests/config/rbac",
+		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository:  "https://github.com/operator-framework/combo",
+				Directory:   "./deploy",
+				Directories: []string{"./crds"},
+				Ref:         rukpakv1alpha1.GitRef{Branch: "main"},
+			},
+			expected: "",
+			err:      errors.New("cannot specify both directory and directories: only one is allowed"),
+		},
+		{
+			source: rukpakv1alpha1.GitSource{
+				Repository:  "https://github.com/operator-framework/combo",
+				Directories: []string{"../crds"},
+				Ref:         rukpakv1alpha1.GitRef{Branch: "main"},
+			},
+			expected: "",
+			err:      errors.New(`directory "../crds" is not in the repository`),
+		},
 		{
 			source: rukpakv1alpha1.GitSource{
 				Repository: "https://github.com/operator-framework/combo.git",
@@ -98,3 +220,71 @@ func TestCheckoutCommand(t *testing.T) {
 		}
 	}
 }
+
+func pktLine(s string) string {
+	return fmt.Sprintf("%04x%s", len(s)+4, s)
+}
+
+func TestResolveRef(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/combo/info/refs" || r.URL.Query().Get("service") != "git-upload-pack" {
+			http.NotFound(w, r)
+			return
+		}
+		if username, password, _ := r.BasicAuth(); username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, pktLine("# service=git-upload-pack\n")+"0000"+
+			pktLine("1111111111111111111111111111111111111111 HEAD\x00multi_ack symref=HEAD:refs/heads/main\n")+
+			pktLine("1111111111111111111111111111111111111111 refs/heads/main\n")+
+			pktLine("2222222222222222222222222222222222222222 refs/tags/v0.0.1\n")+
+			pktLine("3333333333333333333333333333333333333333 refs/tags/v0.0.2\n")+
+			pktLine("4444444444444444444444444444444444444444 refs/tags/v0.0.2^{}\n")+
+			"0000")
+	}))
+	defer server.Close()
+
+	var refs = []struct {
+		ref      rukpakv1alpha1.GitRef
+		password string
+		expected string
+		err      string
+	}{
+		{ref: rukpakv1alpha1.GitRef{Branch: "main"}, expected: "1111111111111111111111111111111111111111"},
+		{ref: rukpakv1alpha1.GitRef{Tag: "v0.0.1"}, expected: "2222222222222222222222222222222222222222"},
+		{ref: rukpakv1alpha1.GitRef{Tag: "v0.0.2"}, expected: "4444444444444444444444444444444444444444"},
+		{ref: rukpakv1alpha1.GitRef{Commit: "d40082c96e6f0d297aa316d84020d307f95dc453"}, expected: "d40082c96e6f0d297aa316d84020d307f95dc453"},
+		{ref: rukpakv1alpha1.GitRef{Branch: "dev"}, err: fmt.Sprintf(`branch "dev" not found in repository "%s/combo"`, server.URL)},
+		{ref: rukpakv1alpha1.GitRef{Branch: "main"}, password: "wrong", err: fmt.Sprintf(`list refs of "%s/combo": unexpected status 401 Unauthorized`, server.URL)},
+	}
+	for _, tt := range refs {
+		password := tt.password
+		if password == "" {
+			password = "pass"
+		}
+		source := rukpakv1alpha1.GitSource{Repository: server.URL + "/combo", Ref: tt.ref}
+		result, err := ResolveRef(context.Background(), server.Client(), source, "user", password)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("expected error %s, got %v", tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != tt.expected {
+			t.Fatalf("expected %s, got %s", tt.expected, result)
+		}
+	}
+}
+
+func TestKustomizationDir(t *testing.T) {
+	if dir := KustomizationDir(rukpakv1alpha1.GitSource{}); dir != "/manifests/repo/manifests" {
+		t.Fatalf("expected the default directory, got %q", dir)
+	}
+	if dir := KustomizationDir(rukpakv1alpha1.GitSource{Directory: "./overlays/prod"}); dir != "/manifests/repo/overlays/prod" {
+		t.Fatalf("expected the overlay directory, got %q", dir)
+	}
+}
@@ -0,0 +1,117 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+// ResolveRef returns the commit that the ref of s currently refers to, as
+// advertised by its repository over the smart HTTP protocol of git. A commit
+// ref resolves to itself. Only repositories with an http or https URL can be
+// resolved, with the given credentials when username is set.
+func ResolveRef(ctx context.Context, httpClient *http.Client, s rukpakv1alpha1.GitSource, username, password string) (string, error) {
+	if err := (&checkoutCmd{GitSource: s}).Validate(); err != nil {
+		return "", err
+	}
+	if s.Ref.Commit != "" {
+		return s.Ref.Commit, nil
+	}
+	if !strings.HasPrefix(s.Repository, "https://") && !strings.HasPrefix(s.Repository, "http://") {
+		return "", fmt.Errorf("repository %q cannot be resolved: only http and https repositories are supported", s.Repository)
+	}
+	if httpClient == nil {
+		httpClient = util.DefaultHTTPClient
+	}
+
+	u := strings.TrimSuffix(s.Repository, "/") + "/info/refs?service=git-upload-pack"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("list refs of %q: %w", s.Repository, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("list refs of %q: unexpected status %s", s.Repository, resp.Status)
+	}
+	refs, err := parseAdvertisedRefs(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("list refs of %q: %w", s.Repository, err)
+	}
+
+	if s.Ref.Tag != "" {
+		// Annotated tags are advertised along with the commit they peel to.
+		name := "refs/tags/" + s.Ref.Tag
+		if commit, ok := refs[name+"^{}"]; ok {
+			return commit, nil
+		}
+		if commit, ok := refs[name]; ok {
+			return commit, nil
+		}
+		return "", fmt.Errorf("tag %q not found in repository %q", s.Ref.Tag, s.Repository)
+	}
+	if commit, ok := refs["refs/heads/"+s.Ref.Branch]; ok {
+		return commit, nil
+	}
+	return "", fmt.Errorf("branch %q not found in repository %q", s.Ref.Branch, s.Repository)
+}
+
+// maxPktLineSize is the maximum size of a pkt-line of the git protocol.
+const maxPktLineSize = 65520
+
+// parseAdvertisedRefs parses the refs advertised in response to a request of
+// the info/refs of the git-upload-pack service, and returns their object IDs
+// by name.
+func parseAdvertisedRefs(r io.Reader) (map[string]string, error) {
+	br := bufio.NewReader(r)
+	refs := map[string]string{}
+	flushes := 0
+	for flushes < 2 {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(br, header); err != nil {
+			return nil, fmt.Errorf("read pkt-line: %w", err)
+		}
+		size, err := strconv.ParseUint(string(header), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pkt-line length %q", header)
+		}
+		if size == 0 {
+			flushes++
+			continue
+		}
+		if size < 4 || size > maxPktLineSize {
+			return nil, fmt.Errorf("invalid pkt-line length %d", size)
+		}
+		data := make([]byte, size-4)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, fmt.Errorf("read pkt-line: %w", err)
+		}
+		line := strings.TrimSuffix(string(data), "\n")
+		if strings.HasPrefix(line, "# service=") {
+			continue
+		}
+		// The first ref is followed by the capabilities of the server.
+		if i := strings.IndexByte(line, 0); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid ref advertisement %q", line)
+		}
+		refs[fields[1]] = fields[0]
+	}
+	return refs, nil
+}
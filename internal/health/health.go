@@ -0,0 +1,188 @@
+package health
+
+import (
+	"fmt"
+	"reflect"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// Result is the outcome of assessing the health of a single object.
+type Result struct {
+	Healthy bool
+	Message string
+}
+
+// checker assesses the health of an object of a particular kind.
+type checker func(obj *unstructured.Unstructured) (Result, error)
+
+var checkers = map[schema.GroupKind]checker{
+	{Group: appsv1.GroupName, Kind: "Deployment"}:                        deploymentHealth,
+	{Group: appsv1.GroupName, Kind: "StatefulSet"}:                       statefulSetHealth,
+	{Group: appsv1.GroupName, Kind: "DaemonSet"}:                         daemonSetHealth,
+	{Group: batchv1.GroupName, Kind: "Job"}:                              jobHealth,
+	{Group: apiextensionsv1.GroupName, Kind: "CustomResourceDefinition"}: crdHealth,
+}
+
+// HasChecker returns true if the health of objects of the given kind can be assessed.
+func HasChecker(gk schema.GroupKind) bool {
+	_, ok := checkers[gk]
+	return ok
+}
+
+// Assess returns the health of obj. Objects whose kind has no health check
+// are always considered healthy.
+func Assess(obj *unstructured.Unstructured) (Result, error) {
+	check, ok := checkers[obj.GroupVersionKind().GroupKind()]
+	if !ok {
+		return Result{Healthy: true}, nil
+	}
+	return check(obj)
+}
+
+// ChangedPredicate returns a predicate that only passes update events that
+// change the health of an object whose kind has a health check.
+func ChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			old, okOld := e.ObjectOld.(*unstructured.Unstructured)
+			new, okNew := e.ObjectNew.(*unstructured.Unstructured)
+			if !okOld || !okNew || !HasChecker(new.GroupVersionKind().GroupKind()) {
+				return false
+			}
+			oldResult, oldErr := Assess(old)
+			newResult, newErr := Assess(new)
+			if oldErr != nil || newErr != nil {
+				return true
+			}
+			return !reflect.DeepEqual(oldResult, newResult)
+		},
+	}
+}
+
+func fromUnstructured(obj *unstructured.Unstructured, into interface{}) error {
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, into); err != nil {
+		return fmt.Errorf("convert %s %q: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	return nil
+}
+
+func unhealthy(format string, a ...interface{}) Result {
+	return Result{Healthy: false, Message: fmt.Sprintf(format, a...)}
+}
+
+func deploymentHealth(obj *unstructured.Unstructured) (Result, error) {
+	d := &appsv1.Deployment{}
+	if err := fromUnstructured(obj, d); err != nil {
+		return Result{}, err
+	}
+	if d.Status.ObservedGeneration < d.Generation {
+		return unhealthy("deployment spec update has not been observed"), nil
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return unhealthy("deployment exceeded its progress deadline: %s", c.Message), nil
+		}
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	if d.Status.UpdatedReplicas < replicas {
+		return unhealthy("%d of %d replicas have been updated", d.Status.UpdatedReplicas, replicas), nil
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentAvailable {
+			if c.Status == corev1.ConditionTrue {
+				return Result{Healthy: true}, nil
+			}
+			return unhealthy("deployment is not available: %s", c.Message), nil
+		}
+	}
+	return unhealthy("deployment is not available"), nil
+}
+
+func statefulSetHealth(obj *unstructured.Unstructured) (Result, error) {
+	s := &appsv1.StatefulSet{}
+	if err := fromUnstructured(obj, s); err != nil {
+		return Result{}, err
+	}
+	if s.Status.ObservedGeneration < s.Generation {
+		return unhealthy("statefulset spec update has not been observed"), nil
+	}
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	if s.Status.ReadyReplicas < replicas {
+		return unhealthy("%d of %d replicas are ready", s.Status.ReadyReplicas, replicas), nil
+	}
+	if s.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType && s.Status.UpdateRevision != s.Status.CurrentRevision {
+		return unhealthy("%d of %d replicas have been updated", s.Status.UpdatedReplicas, replicas), nil
+	}
+	return Result{Healthy: true}, nil
+}
+
+func daemonSetHealth(obj *unstructured.Unstructured) (Result, error) {
+	ds := &appsv1.DaemonSet{}
+	if err := fromUnstructured(obj, ds); err != nil {
+		return Result{}, err
+	}
+	if ds.Status.ObservedGeneration < ds.Generation {
+		return unhealthy("daemonset spec update has not been observed"), nil
+	}
+	desired := ds.Status.DesiredNumberScheduled
+	if ds.Spec.UpdateStrategy.Type != appsv1.OnDeleteDaemonSetStrategyType && ds.Status.UpdatedNumberScheduled < desired {
+		return unhealthy("%d of %d scheduled pods have been updated", ds.Status.UpdatedNumberScheduled, desired), nil
+	}
+	if ds.Status.NumberReady < desired {
+		return unhealthy("%d of %d scheduled pods are ready", ds.Status.NumberReady, desired), nil
+	}
+	return Result{Healthy: true}, nil
+}
+
+func jobHealth(obj *unstructured.Unstructured) (Result, error) {
+	j := &batchv1.Job{}
+	if err := fromUnstructured(obj, j); err != nil {
+		return Result{}, err
+	}
+	for _, c := range j.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return Result{Healthy: true}, nil
+		case batchv1.JobFailed:
+			return unhealthy("job failed: %s", c.Message), nil
+		}
+	}
+	return unhealthy("job has not completed"), nil
+}
+
+func crdHealth(obj *unstructured.Unstructured) (Result, error) {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := fromUnstructured(obj, crd); err != nil {
+		return Result{}, err
+	}
+	for _, c := range crd.Status.Conditions {
+		if c.Type == apiextensionsv1.Established {
+			if c.Status == apiextensionsv1.ConditionTrue {
+				return Result{Healthy: true}, nil
+			}
+			return unhealthy("customresourcedefinition is not established: %s", c.Message), nil
+		}
+	}
+	return unhealthy("customresourcedefinition is not established"), nil
+}
@@ -0,0 +1,184 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestAssess(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		obj         map[string]interface{}
+		healthy     bool
+	}{
+		{
+			description: "object without a health check is healthy",
+			obj: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+			},
+			healthy: true,
+		},
+		{
+			description: "available deployment is healthy",
+			obj: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"generation": int64(1)},
+				"spec":       map[string]interface{}{"replicas": int64(2)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(1),
+					"updatedReplicas":    int64(2),
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Available", "status": "True"},
+					},
+				},
+			},
+			healthy: true,
+		},
+		{
+			description: "unavailable deployment is unhealthy",
+			obj: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"generation": int64(1)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(1),
+					"updatedReplicas":    int64(1),
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Available", "status": "False", "message": "MinimumReplicasUnavailable"},
+					},
+				},
+			},
+			healthy: false,
+		},
+		{
+			description: "deployment with unobserved generation is unhealthy",
+			obj: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"generation": int64(2)},
+				"status":     map[string]interface{}{"observedGeneration": int64(1)},
+			},
+			healthy: false,
+		},
+		{
+			description: "statefulset with all replicas ready is healthy",
+			obj: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "StatefulSet",
+				"spec":       map[string]interface{}{"replicas": int64(3)},
+				"status": map[string]interface{}{
+					"readyReplicas":   int64(3),
+					"currentRevision": "rev-1",
+					"updateRevision":  "rev-1",
+				},
+			},
+			healthy: true,
+		},
+		{
+			description: "daemonset with unready pods is unhealthy",
+			obj: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "DaemonSet",
+				"status": map[string]interface{}{
+					"desiredNumberScheduled": int64(3),
+					"updatedNumberScheduled": int64(3),
+					"numberReady":            int64(2),
+				},
+			},
+			healthy: false,
+		},
+		{
+			description: "completed job is healthy",
+			obj: map[string]interface{}{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Complete", "status": "True"},
+					},
+				},
+			},
+			healthy: true,
+		},
+		{
+			description: "failed job is unhealthy",
+			obj: map[string]interface{}{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Failed", "status": "True", "message": "BackoffLimitExceeded"},
+					},
+				},
+			},
+			healthy: false,
+		},
+		{
+			description: "established crd is healthy",
+			obj: map[string]interface{}{
+				"apiVersion": "apiextensions.k8s.io/v1",
+				"kind":       "CustomResourceDefinition",
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Established", "status": "True"},
+					},
+				},
+			},
+			healthy: true,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			result, err := Assess(&unstructured.Unstructured{Object: tt.obj})
+			require.NoError(t, err)
+			require.Equal(t, tt.healthy, result.Healthy, result.Message)
+		})
+	}
+}
+
+func TestChangedPredicateUpdate(t *testing.T) {
+	deployment := func(available string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"status": map[string]interface{}{
+				"updatedReplicas": int64(1),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Available", "status": available},
+				},
+			},
+		}}
+	}
+	for _, tt := range []struct {
+		description string
+		arg         event.UpdateEvent
+		result      bool
+	}{
+		{
+			description: "health changed - return true",
+			arg:         event.UpdateEvent{ObjectOld: deployment("False"), ObjectNew: deployment("True")},
+			result:      true,
+		},
+		{
+			description: "health unchanged - return false",
+			arg:         event.UpdateEvent{ObjectOld: deployment("True"), ObjectNew: deployment("True")},
+			result:      false,
+		},
+		{
+			description: "kind without a health check - return false",
+			arg: event.UpdateEvent{
+				ObjectOld: &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap"}},
+				ObjectNew: &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "data": map[string]interface{}{"a": "b"}}},
+			},
+			result: false,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			require.Equal(t, tt.result, ChangedPredicate().UpdateFunc(tt.arg))
+		})
+	}
+}
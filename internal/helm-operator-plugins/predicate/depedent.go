@@ -23,6 +23,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	crtpredicate "sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/operator-framework/rukpak/internal/util"
 )
 
 var log = logf.Log.WithName("predicate")
@@ -37,7 +39,7 @@ func DependentPredicateFuncs() crtpredicate.Funcs {
 		// reconciliation. Another reconcile would be redundant.
 		CreateFunc: func(e event.CreateEvent) bool {
 			o := e.Object.(*unstructured.Unstructured)
-			log.V(1).Info("Skipping reconciliation for dependent resource creation", "name", o.GetName(), "namespace", o.GetNamespace(), "apiVersion", o.GroupVersionKind().GroupVersion(), "kind", o.GroupVersionKind().Kind)
+			log.V(util.LogLevelDebug).Info("Skipping reconciliation for dependent resource creation", util.LogKeyObject, o.GetName(), util.LogKeyNamespace, o.GetNamespace(), util.LogKeyGVK, o.GroupVersionKind().String())
 			return false
 		},
 
@@ -45,37 +47,66 @@ func DependentPredicateFuncs() crtpredicate.Funcs {
 		// recreated.
 		DeleteFunc: func(e event.DeleteEvent) bool {
 			o := e.Object.(*unstructured.Unstructured)
-			log.V(1).Info("Reconciling due to dependent resource deletion", "name", o.GetName(), "namespace", o.GetNamespace(), "apiVersion", o.GroupVersionKind().GroupVersion(), "kind", o.GroupVersionKind().Kind)
+			log.V(util.LogLevelDebug).Info("Reconciling due to dependent resource deletion", util.LogKeyObject, o.GetName(), util.LogKeyNamespace, o.GetNamespace(), util.LogKeyGVK, o.GroupVersionKind().String())
 			return true
 		},
 
 		// Don't reconcile when a generic event is received for a dependent
 		GenericFunc: func(e event.GenericEvent) bool {
 			o := e.Object.(*unstructured.Unstructured)
-			log.V(1).Info("Skipping reconcile due to generic event", "name", o.GetName(), "namespace", o.GetNamespace(), "apiVersion", o.GroupVersionKind().GroupVersion(), "kind", o.GroupVersionKind().Kind)
+			log.V(util.LogLevelDebug).Info("Skipping reconcile due to generic event", util.LogKeyObject, o.GetName(), util.LogKeyNamespace, o.GetNamespace(), util.LogKeyGVK, o.GroupVersionKind().String())
 			return false
 		},
 
 		// Reconcile when a dependent resource is updated, so that it can
 		// be patched back to the resource managed by the CR, if
 		// necessary. Ignore updates that only change the status and
-		// resourceVersion.
+		// server-managed metadata, as frequently-updating dependents
+		// would otherwise cause a storm of redundant reconciles.
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			old := e.ObjectOld.(*unstructured.Unstructured).DeepCopy()
-			new := e.ObjectNew.(*unstructured.Unstructured).DeepCopy()
-
-			delete(old.Object, "status")
-			delete(new.Object, "status")
-			old.SetResourceVersion("")
-			new.SetResourceVersion("")
+			old := e.ObjectOld.(*unstructured.Unstructured)
+			new := e.ObjectNew.(*unstructured.Unstructured)
 
-			if reflect.DeepEqual(old.Object, new.Object) {
+			if !dependentChanged(old, new) {
 				return false
 			}
-			log.V(1).Info("Reconciling due to dependent resource update", "name", new.GetName(), "namespace", new.GetNamespace(), "apiVersion", new.GroupVersionKind().GroupVersion(), "kind", new.GroupVersionKind().Kind)
+			log.V(util.LogLevelDebug).Info("Reconciling due to dependent resource update", util.LogKeyObject, new.GetName(), util.LogKeyNamespace, new.GetNamespace(), util.LogKeyGVK, new.GroupVersionKind().String())
 			return true
 		},
 	}
 
 	return dependentPredicate
 }
+
+// dependentChanged reports whether an update of a dependent resource changed
+// anything other than its status and server-managed metadata.
+//
+// For resources that track a generation, the generation is only incremented
+// by the API server when the desired state (e.g. the spec) changes, so an
+// unchanged generation combined with unchanged user-managed metadata is
+// sufficient to ignore the update. Resources without a generation are
+// compared by their full content instead.
+func dependentChanged(old, new *unstructured.Unstructured) bool {
+	if old.GetGeneration() != 0 && old.GetGeneration() == new.GetGeneration() {
+		return !userMetadataEqual(old, new)
+	}
+
+	old = old.DeepCopy()
+	new = new.DeepCopy()
+	for _, u := range []*unstructured.Unstructured{old, new} {
+		delete(u.Object, "status")
+		u.SetResourceVersion("")
+		u.SetManagedFields(nil)
+	}
+	return !reflect.DeepEqual(old.Object, new.Object)
+}
+
+// userMetadataEqual reports whether the metadata that can change without
+// incrementing an object's generation is equal for a and b.
+func userMetadataEqual(a, b *unstructured.Unstructured) bool {
+	return reflect.DeepEqual(a.GetLabels(), b.GetLabels()) &&
+		reflect.DeepEqual(a.GetAnnotations(), b.GetAnnotations()) &&
+		reflect.DeepEqual(a.GetOwnerReferences(), b.GetOwnerReferences()) &&
+		reflect.DeepEqual(a.GetFinalizers(), b.GetFinalizers()) &&
+		reflect.DeepEqual(a.GetDeletionTimestamp(), b.GetDeletionTimestamp())
+}
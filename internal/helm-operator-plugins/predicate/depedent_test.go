@@ -122,6 +122,76 @@ func TestDependentPredicateFuncsUpdate(t *testing.T) {
 			},
 			result: true,
 		},
+		{
+			description: "No update with managedFields difference - return false ignoring server-managed metadata",
+			arg: event.UpdateEvent{
+				ObjectOld: &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"key":      "Value",
+						"metadata": map[string]interface{}{"resourceVersion": "1", "managedFields": []interface{}{"old"}},
+					},
+				},
+				ObjectNew: &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"key":      "Value",
+						"metadata": map[string]interface{}{"resourceVersion": "2", "managedFields": []interface{}{"new"}},
+					},
+				},
+			},
+			result: false,
+		},
+		{
+			description: "Unchanged generation - return false ignoring non-spec differences",
+			arg: event.UpdateEvent{
+				ObjectOld: &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"metadata": map[string]interface{}{"generation": int64(3)},
+						"status":   map[string]interface{}{"observedGeneration": int64(2)},
+					},
+				},
+				ObjectNew: &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"metadata": map[string]interface{}{"generation": int64(3)},
+						"status":   map[string]interface{}{"observedGeneration": int64(3)},
+					},
+				},
+			},
+			result: false,
+		},
+		{
+			description: "Unchanged generation with label difference - return true",
+			arg: event.UpdateEvent{
+				ObjectOld: &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"metadata": map[string]interface{}{"generation": int64(3), "labels": map[string]interface{}{"a": "b"}},
+					},
+				},
+				ObjectNew: &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"metadata": map[string]interface{}{"generation": int64(3)},
+					},
+				},
+			},
+			result: true,
+		},
+		{
+			description: "Changed generation - return true",
+			arg: event.UpdateEvent{
+				ObjectOld: &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"metadata": map[string]interface{}{"generation": int64(3)},
+						"spec":     map[string]interface{}{"replicas": int64(1)},
+					},
+				},
+				ObjectNew: &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"metadata": map[string]interface{}{"generation": int64(4)},
+						"spec":     map[string]interface{}{"replicas": int64(2)},
+					},
+				},
+			},
+			result: true,
+		},
 	} {
 		t.Run(tt.description, func(t *testing.T) {
 			funcs := DependentPredicateFuncs()
@@ -0,0 +1,193 @@
+// Package helmrepo downloads charts from Helm chart repositories, as the
+// content of Bundles with a helm source.
+package helmrepo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+const (
+	// maxIndexSize and maxChartSize limit the size of the index of a chart
+	// repository and of the chart archives that are downloaded from it.
+	maxIndexSize = 64 * 1024 * 1024
+	maxChartSize = 16 * 1024 * 1024
+	// maxDependencyDepth limits the nesting of the dependencies that are
+	// downloaded for a chart, which would otherwise be unbounded for
+	// dependencies that depend on each other.
+	maxDependencyDepth = 8
+)
+
+// Client downloads charts from a chart repository, with basic auth
+// credentials when Username is set.
+type Client struct {
+	httpClient *http.Client
+
+	Username string
+	Password string
+}
+
+// NewClient returns a Client that downloads charts with httpClient, or with
+// util.DefaultHTTPClient when httpClient is nil.
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = util.DefaultHTTPClient
+	}
+	return &Client{httpClient: httpClient}
+}
+
+// ResolveVersion returns the version of the chart of source that its version
+// currently resolves to, the latest that matches it, along with the URL of
+// the archive of that version.
+func (c *Client) ResolveVersion(ctx context.Context, source rukpakv1alpha1.HelmSource) (string, string, error) {
+	indexURL, err := repo.ResolveReferenceURL(source.Repository, "index.yaml")
+	if err != nil {
+		return "", "", fmt.Errorf("invalid chart repository %q: %w", source.Repository, err)
+	}
+	data, err := c.get(ctx, indexURL, true, maxIndexSize)
+	if err != nil {
+		return "", "", err
+	}
+	index := &repo.IndexFile{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return "", "", fmt.Errorf("parse index of chart repository %q: %w", source.Repository, err)
+	}
+	index.SortEntries()
+	cv, err := index.Get(source.Chart, source.Version)
+	if err != nil {
+		return "", "", fmt.Errorf("chart %q version %q in repository %q: %w", source.Chart, source.Version, source.Repository, err)
+	}
+	if len(cv.URLs) == 0 {
+		return "", "", fmt.Errorf("chart %q version %q in repository %q has no URLs", source.Chart, cv.Version, source.Repository)
+	}
+	chartURL, err := repo.ResolveReferenceURL(source.Repository, cv.URLs[0])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid URL of chart %q version %q: %w", source.Chart, cv.Version, err)
+	}
+	return cv.Version, chartURL, nil
+}
+
+// GetChart downloads the chart archive at chartURL, a URL returned by
+// ResolveVersion for source.
+func (c *Client) GetChart(ctx context.Context, source rukpakv1alpha1.HelmSource, chartURL string) ([]byte, error) {
+	// The credentials of the repository are only sent along to charts that
+	// are hosted by the repository itself.
+	return c.get(ctx, chartURL, sameHost(source.Repository, chartURL), maxChartSize)
+}
+
+// ResolveDependencies downloads the dependencies of chrt, a chart of source,
+// that are not vendored in its charts directory, from the chart repositories
+// that they name, and adds them to chrt along with their own dependencies.
+// Dependencies are downloaded at the version of the Chart.lock file of chrt
+// when it locks them. The credentials of source are only sent to dependencies
+// that are hosted by its repository.
+func (c *Client) ResolveDependencies(ctx context.Context, source rukpakv1alpha1.HelmSource, chrt *chart.Chart) error {
+	return c.resolveDependencies(ctx, source, chrt, 0)
+}
+
+func (c *Client) resolveDependencies(ctx context.Context, source rukpakv1alpha1.HelmSource, chrt *chart.Chart, depth int) error {
+	vendored := map[string]bool{}
+	for _, sub := range chrt.Dependencies() {
+		vendored[sub.Name()] = true
+	}
+	for _, dep := range chrt.Metadata.Dependencies {
+		if vendored[dep.Name] {
+			continue
+		}
+		if depth >= maxDependencyDepth {
+			return fmt.Errorf("dependency %q of chart %q: dependencies are nested more than %d levels deep", dep.Name, chrt.Name(), maxDependencyDepth)
+		}
+		if !strings.HasPrefix(dep.Repository, "https://") && !strings.HasPrefix(dep.Repository, "http://") {
+			return fmt.Errorf("dependency %q of chart %q is not vendored in its charts directory, and its repository %q is not the URL of a chart repository", dep.Name, chrt.Name(), dep.Repository)
+		}
+		depSource := rukpakv1alpha1.HelmSource{Repository: dep.Repository, Chart: dep.Name, Version: lockedVersion(chrt, dep)}
+		dc := c
+		if !sameHost(source.Repository, dep.Repository) {
+			dc = NewClient(c.httpClient)
+		}
+		_, chartURL, err := dc.ResolveVersion(ctx, depSource)
+		if err != nil {
+			return fmt.Errorf("dependency %q of chart %q: %w", dep.Name, chrt.Name(), err)
+		}
+		data, err := dc.GetChart(ctx, depSource, chartURL)
+		if err != nil {
+			return fmt.Errorf("download dependency %q of chart %q: %w", dep.Name, chrt.Name(), err)
+		}
+		sub, err := loader.LoadArchive(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("load dependency %q of chart %q: %w", dep.Name, chrt.Name(), err)
+		}
+		if err := c.resolveDependencies(ctx, source, sub, depth+1); err != nil {
+			return err
+		}
+		chrt.AddDependency(sub)
+		vendored[dep.Name] = true
+	}
+	return nil
+}
+
+// lockedVersion returns the version of dep in the Chart.lock file of chrt, or
+// the version constraint of dep when chrt does not lock it.
+func lockedVersion(chrt *chart.Chart, dep *chart.Dependency) string {
+	if chrt.Lock != nil {
+		for _, locked := range chrt.Lock.Dependencies {
+			if locked.Name == dep.Name && locked.Repository == dep.Repository {
+				return locked.Version
+			}
+		}
+	}
+	return dep.Version
+}
+
+func (c *Client) get(ctx context.Context, u string, withCredentials bool, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if withCredentials && c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: unexpected status %s", u, resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", u, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("get %s: response exceeds %d bytes", u, limit)
+	}
+	return data, nil
+}
+
+// sameHost reports whether the URLs a and b have the same scheme and host.
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
+}
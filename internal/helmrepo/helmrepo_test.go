@@ -0,0 +1,155 @@
+package helmrepo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+const index = `apiVersion: v1
+entries:
+  my-chart:
+  - name: my-chart
+    version: 1.2.0
+    urls:
+    - charts/my-chart-1.2.0.tgz
+  - name: my-chart
+    version: 1.1.0
+    urls:
+    - charts/my-chart-1.1.0.tgz
+  - name: my-chart
+    version: 2.0.0-rc.1
+    urls:
+    - https://other.example.com/my-chart-2.0.0-rc.1.tgz
+`
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, _ := r.BasicAuth(); username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/repo/index.yaml":
+			_, _ = w.Write([]byte(index))
+		case "/repo/charts/my-chart-1.2.0.tgz":
+			_, _ = w.Write([]byte("chart"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	source := rukpakv1alpha1.HelmSource{Repository: server.URL + "/repo", Chart: "my-chart"}
+
+	_, _, err := NewClient(nil).ResolveVersion(context.Background(), source)
+	require.EqualError(t, err, "get "+server.URL+"/repo/index.yaml: unexpected status 401 Unauthorized")
+
+	c := NewClient(server.Client())
+	c.Username, c.Password = "user", "pass"
+	for _, tt := range []struct {
+		version     string
+		wantVersion string
+		wantURL     string
+		wantErr     string
+	}{
+		{version: "", wantVersion: "1.2.0", wantURL: server.URL + "/repo/charts/my-chart-1.2.0.tgz"},
+		{version: "~1.1.0", wantVersion: "1.1.0", wantURL: server.URL + "/repo/charts/my-chart-1.1.0.tgz"},
+		{version: "2.0.0-rc.1", wantVersion: "2.0.0-rc.1", wantURL: "https://other.example.com/my-chart-2.0.0-rc.1.tgz"},
+		{version: "3.x", wantErr: `chart "my-chart" version "3.x" in repository "` + server.URL + `/repo": no chart version found for my-chart-3.x`},
+	} {
+		t.Run(tt.version, func(t *testing.T) {
+			source := source
+			source.Version = tt.version
+			version, chartURL, err := c.ResolveVersion(context.Background(), source)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantVersion, version)
+			require.Equal(t, tt.wantURL, chartURL)
+		})
+	}
+
+	data, err := c.GetChart(context.Background(), source, server.URL+"/repo/charts/my-chart-1.2.0.tgz")
+	require.NoError(t, err)
+	require.Equal(t, "chart", string(data))
+}
+
+func TestResolveDependencies(t *testing.T) {
+	archives := map[string][]byte{}
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		path, err := chartutil.Save(&chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "dep", Version: version}}, t.TempDir())
+		require.NoError(t, err)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		archives["/"+filepath.Base(path)] = data
+	}
+	var credentialsSent bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok {
+			credentialsSent = true
+		}
+		if r.URL.Path == "/index.yaml" {
+			_, _ = w.Write([]byte("apiVersion: v1\nentries:\n  dep:\n  - name: dep\n    version: 1.1.0\n    urls:\n    - dep-1.1.0.tgz\n  - name: dep\n    version: 1.0.0\n    urls:\n    - dep-1.0.0.tgz\n"))
+			return
+		}
+		if data, ok := archives[r.URL.Path]; ok {
+			_, _ = w.Write(data)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+	source := rukpakv1alpha1.HelmSource{Repository: "https://charts.example.com", Chart: "my-chart"}
+	c := NewClient(server.Client())
+	c.Username, c.Password = "user", "pass"
+	newChart := func(repository string) *chart.Chart {
+		return &chart.Chart{Metadata: &chart.Metadata{
+			APIVersion:   chart.APIVersionV2,
+			Name:         "my-chart",
+			Version:      "1.2.0",
+			Dependencies: []*chart.Dependency{{Name: "dep", Version: "^1.0.0", Repository: repository}},
+		}}
+	}
+
+	chrt := newChart(server.URL)
+	require.NoError(t, c.ResolveDependencies(context.Background(), source, chrt))
+	require.Len(t, chrt.Dependencies(), 1)
+	require.Equal(t, "1.1.0", chrt.Dependencies()[0].Metadata.Version)
+	require.False(t, credentialsSent, "the credentials of the repository should not be sent to other hosts")
+
+	// The version of a locked dependency is downloaded.
+	chrt = newChart(server.URL)
+	chrt.Lock = &chart.Lock{Dependencies: []*chart.Dependency{{Name: "dep", Version: "1.0.0", Repository: server.URL}}}
+	require.NoError(t, c.ResolveDependencies(context.Background(), source, chrt))
+	require.Equal(t, "1.0.0", chrt.Dependencies()[0].Metadata.Version)
+
+	// A vendored dependency is kept as it is.
+	chrt = newChart("file://../dep")
+	chrt.AddDependency(&chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "dep", Version: "0.1.0"}})
+	require.NoError(t, c.ResolveDependencies(context.Background(), source, chrt))
+	require.Len(t, chrt.Dependencies(), 1)
+	require.Equal(t, "0.1.0", chrt.Dependencies()[0].Metadata.Version)
+
+	for _, repository := range []string{"file://../dep", "@stable", ""} {
+		err := c.ResolveDependencies(context.Background(), source, newChart(repository))
+		require.EqualError(t, err, fmt.Sprintf(`dependency "dep" of chart "my-chart" is not vendored in its charts directory, and its repository %q is not the URL of a chart repository`, repository))
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	require.True(t, sameHost("https://charts.example.com/repo", "https://charts.example.com/repo/a.tgz"))
+	require.False(t, sameHost("https://charts.example.com/repo", "https://cdn.example.com/a.tgz"))
+	require.False(t, sameHost("https://charts.example.com/repo", "http://charts.example.com/a.tgz"))
+}
@@ -0,0 +1,103 @@
+package manifests
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Filter returns the bundle content of fsys whose manifests directory only
+// has the files that match any of the include patterns, or every file when
+// there are none, and that match none of the exclude patterns. Patterns are
+// globs in the syntax of path.Match, which match the paths of files relative
+// to the manifests directory and the paths of their parent directories, and,
+// when they have no slash, the names of the files and directories. Content
+// outside of the manifests directory is not filtered.
+func Filter(fsys fs.FS, include, exclude []string) fs.FS {
+	if len(include) == 0 && len(exclude) == 0 {
+		return fsys
+	}
+	return &filterFS{fsys: fsys, include: include, exclude: exclude}
+}
+
+type filterFS struct {
+	fsys    fs.FS
+	include []string
+	exclude []string
+}
+
+var _ fs.ReadDirFS = &filterFS{}
+
+func (f *filterFS) Open(name string) (fs.File, error) {
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !f.keep(name, info.IsDir()) {
+		file.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return file, nil
+}
+
+func (f *filterFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(f.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if !f.keep(name, true) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	kept := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		if f.keep(path.Join(name, e.Name()), e.IsDir()) {
+			kept = append(kept, e)
+		}
+	}
+	return kept, nil
+}
+
+// keep returns whether the file or directory at p in the bundle content is
+// kept. Directories are kept unless they are excluded, since the files that
+// they contain may be included.
+func (f *filterFS) keep(p string, dir bool) bool {
+	if !strings.HasPrefix(p, Dir+"/") {
+		return true
+	}
+	rel := strings.TrimPrefix(p, Dir+"/")
+	for _, pattern := range f.exclude {
+		if matchPath(pattern, rel) {
+			return false
+		}
+	}
+	if dir || len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if matchPath(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath returns whether pattern matches rel, the path of a file or
+// directory relative to the manifests directory, or one of its parent
+// directories. Patterns without a slash match their names as well.
+func matchPath(pattern, rel string) bool {
+	byName := !strings.Contains(pattern, "/")
+	for p := rel; p != "." && p != ""; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(p)); ok && byName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package manifests
+
+import (
+	"fmt"
+	"io/fs"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Format is the format of bundle content.
+type Format string
+
+const (
+	// FormatPlain is the format of plain bundles, whose manifests are in
+	// their manifests directory.
+	FormatPlain Format = "plain+v0"
+	// FormatRegistry is the format of OLM registry bundles, whose metadata
+	// annotations declare their media type.
+	FormatRegistry Format = "registry+v1"
+	// FormatHelm is the format of Helm charts.
+	FormatHelm Format = "helm"
+)
+
+// provisionerClasses are the provisioner classes that support the bundle
+// formats that the plain provisioner does not.
+var provisionerClasses = map[Format]string{
+	FormatRegistry: "core.rukpak.io/registry",
+	FormatHelm:     "core.rukpak.io/helm",
+}
+
+const (
+	registryAnnotationsFile = "metadata/annotations.yaml"
+	registryMediaTypeKey    = "operators.operatorframework.io.bundle.mediatype.v1"
+)
+
+// FormatError is returned by CheckFormat for bundle content of another
+// format than plain+v0.
+type FormatError struct {
+	Format Format
+}
+
+func (e *FormatError) Error() string {
+	msg := fmt.Sprintf("invalid bundle: the content is a %s bundle rather than a %s bundle", e.Format, FormatPlain)
+	if class, ok := provisionerClasses[e.Format]; ok {
+		msg += fmt.Sprintf(": use a provisioner class that supports %s bundles, such as %s", e.Format, class)
+	}
+	return msg
+}
+
+// DetectFormat returns the format of the bundle content in fsys, or an empty
+// format when it is not recognized. Registry bundles are detected from their
+// metadata annotations, and Helm charts from a Chart.yaml file at the root of
+// the content or of its manifests directory, where git sources are cloned.
+func DetectFormat(fsys fs.FS) Format {
+	if data, err := fs.ReadFile(fsys, registryAnnotationsFile); err == nil {
+		annotations := struct {
+			Annotations map[string]string `json:"annotations"`
+		}{}
+		if err := yaml.Unmarshal(data, &annotations); err == nil && annotations.Annotations[registryMediaTypeKey] != "" {
+			return Format(annotations.Annotations[registryMediaTypeKey])
+		}
+	}
+	for _, name := range []string{"Chart.yaml", Dir + "/Chart.yaml"} {
+		if _, err := fs.Stat(fsys, name); err == nil {
+			return FormatHelm
+		}
+	}
+	if info, err := fs.Stat(fsys, Dir); err == nil && info.IsDir() {
+		return FormatPlain
+	}
+	return ""
+}
+
+// CheckFormat returns a *FormatError when the bundle content in fsys is
+// recognized as another format than plain+v0, so that it is not mistaken for
+// malformed plain manifests.
+func CheckFormat(fsys fs.FS) error {
+	if format := DetectFormat(fsys); format != "" && format != FormatPlain {
+		return &FormatError{Format: format}
+	}
+	return nil
+}
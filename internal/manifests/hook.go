@@ -0,0 +1,54 @@
+package manifests
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HookAnnotation is the annotation of the Jobs and Pods of a plain bundle that
+// run as hooks rather than being installed with the other objects. Its value
+// is a comma-separated list of the events that run the hook.
+const HookAnnotation = "core.rukpak.io/hook"
+
+// The events that run hooks.
+const (
+	HookPreInstall  = "pre-install"
+	HookPostInstall = "post-install"
+	HookPreUpgrade  = "pre-upgrade"
+	HookPostUpgrade = "post-upgrade"
+)
+
+var hookEvents = []string{HookPreInstall, HookPostInstall, HookPreUpgrade, HookPostUpgrade}
+
+// HookEvents returns the events that run obj as a hook, or nil when obj is
+// not a hook.
+func HookEvents(obj client.Object) ([]string, error) {
+	value, ok := obj.GetAnnotations()[HookAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if !(gvk.Group == "batch" && gvk.Kind == "Job") && !(gvk.Group == "" && gvk.Kind == "Pod") {
+		return nil, fmt.Errorf("invalid %s annotation of %s %q: only Jobs and Pods can be hooks", HookAnnotation, gvk.Kind, obj.GetName())
+	}
+	var events []string
+	for _, event := range strings.Split(value, ",") {
+		event = strings.TrimSpace(event)
+		if !contains(hookEvents, event) {
+			return nil, fmt.Errorf("invalid %s annotation %q of %s %q: events must be one of %s", HookAnnotation, value, gvk.Kind, obj.GetName(), strings.Join(hookEvents, ", "))
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
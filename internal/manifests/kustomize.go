@@ -0,0 +1,19 @@
+package manifests
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// Kustomize renders the kustomization in dir of fsys, and returns the
+// rendered objects as a multi-document YAML manifest. Bases and components
+// may be anywhere in fsys, but remote bases cannot be fetched.
+func Kustomize(fsys filesys.FileSystem, dir string) ([]byte, error) {
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("render kustomization %q: %w", dir, err)
+	}
+	return resources.AsYaml()
+}
@@ -0,0 +1,79 @@
+package manifests
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Limits bounds the size of the objects of a bundle, so that one enormous
+// bundle cannot exhaust the bundle storage or stall the installs of others.
+// A zero limit is disabled.
+type Limits struct {
+	// MaxBytes is the maximum size of all objects of a bundle together, as
+	// serialized to JSON.
+	MaxBytes int64
+	// MaxObjects is the maximum number of objects of a bundle.
+	MaxObjects int
+	// MaxObjectBytes is the maximum size of a single object of a bundle, as
+	// serialized to JSON.
+	MaxObjectBytes int64
+}
+
+// BindFlags binds the limits to flags in fs.
+func (l *Limits) BindFlags(fs *flag.FlagSet) {
+	fs.Int64Var(&l.MaxBytes, "bundle-max-bytes", 0,
+		"The maximum size in bytes of the objects of a bundle together, as serialized to JSON. A value of 0 disables the limit.")
+	fs.IntVar(&l.MaxObjects, "bundle-max-objects", 0,
+		"The maximum number of objects of a bundle. A value of 0 disables the limit.")
+	fs.Int64Var(&l.MaxObjectBytes, "bundle-max-object-bytes", 0,
+		"The maximum size in bytes of a single object of a bundle, as serialized to JSON. A value of 0 disables the limit.")
+}
+
+// Validate returns an error when a limit of l is negative.
+func (l Limits) Validate() error {
+	if l.MaxBytes < 0 || l.MaxObjects < 0 || l.MaxObjectBytes < 0 {
+		return errors.New("bundle limits must not be negative")
+	}
+	return nil
+}
+
+// LimitError is returned by Limits.Check for the objects of a bundle that
+// exceed a limit.
+type LimitError struct {
+	msg string
+}
+
+func (e *LimitError) Error() string {
+	return "invalid bundle: " + e.msg
+}
+
+// Check returns a *LimitError when objects exceed a limit of l.
+func (l Limits) Check(objects []client.Object) error {
+	if l.MaxObjects > 0 && len(objects) > l.MaxObjects {
+		return &LimitError{msg: fmt.Sprintf("found %d objects, more than the limit of %d", len(objects), l.MaxObjects)}
+	}
+	if l.MaxBytes == 0 && l.MaxObjectBytes == 0 {
+		return nil
+	}
+	var total int64
+	for _, obj := range objects {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("marshal object %q: %w", obj.GetName(), err)
+		}
+		size := int64(len(data))
+		if l.MaxObjectBytes > 0 && size > l.MaxObjectBytes {
+			gvk := obj.GetObjectKind().GroupVersionKind()
+			return &LimitError{msg: fmt.Sprintf("%s %q is %d bytes, more than the limit of %d bytes per object", gvk.Kind, obj.GetName(), size, l.MaxObjectBytes)}
+		}
+		total += size
+		if l.MaxBytes > 0 && total > l.MaxBytes {
+			return &LimitError{msg: fmt.Sprintf("the objects are more than the limit of %d bytes", l.MaxBytes)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Package manifests reads and validates the manifests of plain bundles, both
+// in the plain provisioner and offline, before a bundle is published.
+package manifests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apimachyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/rukpak/internal/checksums"
+)
+
+// Dir is the directory of a plain bundle that contains its manifests.
+const Dir = "manifests"
+
+// ErrNoObjects is returned by Validate for bundles whose manifests do not
+// contain any objects.
+var ErrNoObjects = errors.New("invalid bundle: found zero objects: plain+v0 bundles are required to contain at least one object")
+
+// Validate runs the validation that the plain provisioner runs when it
+// unpacks the bundle in fsys, and returns the objects of the bundle.
+func Validate(fsys fs.FS) ([]client.Object, error) {
+	if err := CheckFormat(fsys); err != nil {
+		return nil, err
+	}
+	if err := checksums.Verify(fsys, Dir); err != nil {
+		return nil, fmt.Errorf("verify bundle manifests: %w", err)
+	}
+	objects, err := Objects(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("get objects from bundle manifests: %w", err)
+	}
+	if len(objects) == 0 {
+		return nil, ErrNoObjects
+	}
+	if _, err := Waves(objects); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	for _, obj := range objects {
+		if _, err := HookEvents(obj); err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+	}
+	return objects, nil
+}
+
+// Files returns the paths of the manifest files of the bundle in fsys,
+// relative to its manifests directory, in lexical order. The manifests
+// directory may contain nested directories, whose files are manifests as
+// well. Files and directories whose names start with a dot, such as .git,
+// and checksums files, in any directory, are not manifests.
+func Files(fsys fs.FS) ([]string, error) {
+	return files(fsys, "")
+}
+
+// files returns the paths of the manifest files in dir, a directory relative
+// to the manifests directory, and in its nested directories.
+func files(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, path.Join(Dir, dir))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") || (!e.IsDir() && e.Name() == checksums.FileName) {
+			continue
+		}
+		name := path.Join(dir, e.Name())
+		if !e.IsDir() {
+			names = append(names, name)
+			continue
+		}
+		nested, err := files(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, nested...)
+	}
+	return names, nil
+}
+
+// Objects returns the objects in the manifests of the bundle in fsys, in the
+// order of their files and of the YAML or JSON documents within them. The
+// manifests are not verified against their checksums.
+func Objects(fsys fs.FS) ([]client.Object, error) {
+	var objects []client.Object
+
+	files, err := Files(fsys)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range files {
+		fileData, err := fs.ReadFile(fsys, path.Join(Dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		dec := apimachyaml.NewYAMLOrJSONDecoder(bytes.NewReader(fileData), 1024)
+		for {
+			obj := unstructured.Unstructured{}
+			err := dec.Decode(&obj)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("read %q: %w", name, err)
+			}
+			objects = append(objects, &obj)
+		}
+	}
+	return objects, nil
+}
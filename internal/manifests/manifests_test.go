@@ -0,0 +1,375 @@
+package manifests
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const configMaps = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+`
+
+func TestDetectFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fsys fstest.MapFS
+		want Format
+	}{
+		{
+			name: "plain bundle",
+			fsys: fstest.MapFS{"manifests/objects.yaml": {Data: []byte(configMaps)}},
+			want: FormatPlain,
+		},
+		{
+			name: "registry bundle",
+			fsys: fstest.MapFS{
+				"manifests/csv.yaml":        {Data: []byte(configMaps)},
+				"metadata/annotations.yaml": {Data: []byte("annotations:\n  operators.operatorframework.io.bundle.mediatype.v1: registry+v1\n")},
+			},
+			want: FormatRegistry,
+		},
+		{
+			name: "metadata without a media type",
+			fsys: fstest.MapFS{
+				"manifests/objects.yaml":    {Data: []byte(configMaps)},
+				"metadata/annotations.yaml": {Data: []byte("annotations: {}\n")},
+			},
+			want: FormatPlain,
+		},
+		{
+			name: "helm chart",
+			fsys: fstest.MapFS{"Chart.yaml": {Data: []byte("name: a\n")}},
+			want: FormatHelm,
+		},
+		{
+			name: "helm chart in a git repository",
+			fsys: fstest.MapFS{"manifests/Chart.yaml": {Data: []byte("name: a\n")}},
+			want: FormatHelm,
+		},
+		{
+			name: "unknown",
+			fsys: fstest.MapFS{"Dockerfile": {Data: []byte("FROM scratch")}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, DetectFormat(tc.fsys))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		name    string
+		fsys    fstest.MapFS
+		objects []string
+		errMsg  string
+	}
+	for _, tc := range []testCase{
+		{
+			name:    "valid bundle",
+			fsys:    fstest.MapFS{"manifests/objects.yaml": {Data: []byte(configMaps)}},
+			objects: []string{"a", "b"},
+		},
+		{
+			name: "checksums file is not an object",
+			fsys: fstest.MapFS{
+				"manifests/objects.yaml": {Data: []byte(configMaps)},
+				"manifests/SHA256SUMS":   {Data: []byte(fmt.Sprintf("%x  objects.yaml\n", sha256.Sum256([]byte(configMaps))))},
+			},
+			objects: []string{"a", "b"},
+		},
+		{
+			name: "nested directories and documents in YAML and JSON",
+			fsys: fstest.MapFS{
+				"manifests/b.yaml":             {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: e\n")},
+				"manifests/a/objects.yaml":     {Data: []byte(configMaps)},
+				"manifests/a/b/c.json":         {Data: []byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "c"}}` + "\n" + `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "d"}}`)},
+				"manifests/.git/config":        {Data: []byte("[core]\n")},
+				"manifests/a/.hidden.yaml":     {Data: []byte("not: an object\n")},
+				"manifests/a/b/empty/.gitkeep": {},
+			},
+			objects: []string{"c", "d", "a", "b", "e"},
+		},
+		{
+			name: "modified manifests",
+			fsys: fstest.MapFS{
+				"manifests/objects.yaml": {Data: []byte(configMaps)},
+				"manifests/SHA256SUMS":   {Data: []byte(fmt.Sprintf("%x  objects.yaml\n", sha256.Sum256(nil)))},
+			},
+			errMsg: "verify bundle manifests: bundle content does not match SHA256SUMS: modified files objects.yaml",
+		},
+		{
+			name:   "no manifests directory",
+			fsys:   fstest.MapFS{"Dockerfile": {Data: []byte("FROM scratch")}},
+			errMsg: "get objects from bundle manifests: open manifests: file does not exist",
+		},
+		{
+			name:   "invalid manifest",
+			fsys:   fstest.MapFS{"manifests/objects.yaml": {Data: []byte("apiVersion: v1\nmetadata:\n  name: a\n")}},
+			errMsg: `get objects from bundle manifests: read "objects.yaml": error unmarshaling JSON: while decoding JSON: Object 'Kind' is missing in '{"apiVersion":"v1","metadata":{"name":"a"}}'`,
+		},
+		{
+			name:   "invalid wave",
+			fsys:   fstest.MapFS{"manifests/objects.yaml": {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n  annotations:\n    core.rukpak.io/wave: first\n")}},
+			errMsg: `invalid bundle: invalid core.rukpak.io/wave annotation "first" of ConfigMap "a": must be an integer`,
+		},
+		{
+			name:   "hook of another kind",
+			fsys:   fstest.MapFS{"manifests/objects.yaml": {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n  annotations:\n    core.rukpak.io/hook: pre-install\n")}},
+			errMsg: `invalid bundle: invalid core.rukpak.io/hook annotation of ConfigMap "a": only Jobs and Pods can be hooks`,
+		},
+		{
+			name:   "unknown hook event",
+			fsys:   fstest.MapFS{"manifests/objects.yaml": {Data: []byte("apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: a\n  annotations:\n    core.rukpak.io/hook: pre-install,pre-delete\n")}},
+			errMsg: `invalid bundle: invalid core.rukpak.io/hook annotation "pre-install,pre-delete" of Job "a": events must be one of pre-install, post-install, pre-upgrade, post-upgrade`,
+		},
+		{
+			name:   "no objects",
+			fsys:   fstest.MapFS{"manifests/empty.yaml": {Data: []byte("")}},
+			errMsg: ErrNoObjects.Error(),
+		},
+		{
+			name: "registry bundle",
+			fsys: fstest.MapFS{
+				"manifests/objects.yaml":    {Data: []byte(configMaps)},
+				"metadata/annotations.yaml": {Data: []byte("annotations:\n  operators.operatorframework.io.bundle.mediatype.v1: registry+v1\n")},
+			},
+			errMsg: "invalid bundle: the content is a registry+v1 bundle rather than a plain+v0 bundle: use a provisioner class that supports registry+v1 bundles, such as core.rukpak.io/registry",
+		},
+		{
+			name:   "helm chart",
+			fsys:   fstest.MapFS{"Chart.yaml": {Data: []byte("name: a\n")}, "templates/a.yaml": {Data: []byte(configMaps)}},
+			errMsg: "invalid bundle: the content is a helm bundle rather than a plain+v0 bundle: use a provisioner class that supports helm bundles, such as core.rukpak.io/helm",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			objects, err := Validate(tc.fsys)
+			if tc.errMsg != "" {
+				require.EqualError(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for _, obj := range objects {
+				names = append(names, obj.GetName())
+			}
+			require.Equal(t, tc.objects, names)
+		})
+	}
+}
+
+func TestWaves(t *testing.T) {
+	object := func(name, wave string) client.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetName(name)
+		if wave != "" {
+			obj.SetAnnotations(map[string]string{WaveAnnotation: wave})
+		}
+		return obj
+	}
+	waves, err := Waves([]client.Object{object("app", "1"), object("db", ""), object("crs", "2"), object("crds", "-1"), object("config", "0")})
+	require.NoError(t, err)
+
+	var names [][]string
+	for _, wave := range waves {
+		var waveNames []string
+		for _, obj := range wave {
+			waveNames = append(waveNames, obj.GetName())
+		}
+		names = append(names, waveNames)
+	}
+	require.Equal(t, [][]string{{"crds"}, {"db", "config"}, {"app"}, {"crs"}}, names)
+}
+
+func TestLimits(t *testing.T) {
+	object := func(name string, size int) client.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetKind("ConfigMap")
+		obj.SetName(name)
+		obj.SetAnnotations(map[string]string{"padding": strings.Repeat("x", size)})
+		return obj
+	}
+	objects := []client.Object{object("a", 100), object("b", 200)}
+	for _, tc := range []struct {
+		name    string
+		limits  Limits
+		wantErr string
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:   "within limits",
+			limits: Limits{MaxBytes: 1000, MaxObjects: 2, MaxObjectBytes: 500},
+		},
+		{
+			name:    "too many objects",
+			limits:  Limits{MaxObjects: 1},
+			wantErr: "invalid bundle: found 2 objects, more than the limit of 1",
+		},
+		{
+			name:    "object too large",
+			limits:  Limits{MaxObjectBytes: 250},
+			wantErr: `invalid bundle: ConfigMap "b" is 273 bytes, more than the limit of 250 bytes per object`,
+		},
+		{
+			name:    "too large",
+			limits:  Limits{MaxBytes: 300},
+			wantErr: "invalid bundle: the objects are more than the limit of 300 bytes",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.limits.Check(objects)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			var limitErr *LimitError
+			require.ErrorAs(t, err, &limitErr)
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestHookEvents(t *testing.T) {
+	job := &unstructured.Unstructured{}
+	job.SetAPIVersion("batch/v1")
+	job.SetKind("Job")
+	job.SetName("migrate")
+
+	events, err := HookEvents(job)
+	require.NoError(t, err)
+	require.Nil(t, events)
+
+	job.SetAnnotations(map[string]string{HookAnnotation: "pre-install, pre-upgrade"})
+	events, err = HookEvents(job)
+	require.NoError(t, err)
+	require.Equal(t, []string{HookPreInstall, HookPreUpgrade}, events)
+}
+
+func TestKustomize(t *testing.T) {
+	fsys := filesys.MakeFsInMemory()
+	require.NoError(t, fsys.WriteFile("repo/base/kustomization.yaml", []byte("resources:\n- configmaps.yaml\n")))
+	require.NoError(t, fsys.WriteFile("repo/base/configmaps.yaml", []byte(configMaps)))
+	require.NoError(t, fsys.WriteFile("repo/overlays/prod/kustomization.yaml", []byte("namePrefix: prod-\nresources:\n- ../../base\n")))
+
+	data, err := Kustomize(fsys, "repo/overlays/prod")
+	require.NoError(t, err)
+	require.Equal(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: prod-a\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: prod-b\n", string(data))
+
+	_, err = Kustomize(fsys, "repo/base/missing")
+	require.Error(t, err)
+}
+
+func TestSubstitute(t *testing.T) {
+	vars := map[string]string{"BUNDLE_INSTANCE_NAME": "prod", "TARGET_NAMESPACE": "apps"}
+	for _, tc := range []struct {
+		name   string
+		obj    map[string]interface{}
+		want   map[string]interface{}
+		errMsg string
+	}{
+		{
+			name: "variables",
+			obj: map[string]interface{}{
+				"kind":     "ConfigMap",
+				"metadata": map[string]interface{}{"name": "${BUNDLE_INSTANCE_NAME}-config"},
+				"data":     map[string]interface{}{"url": "https://${BUNDLE_INSTANCE_NAME}.${TARGET_NAMESPACE}.svc", "replicas": int64(3)},
+			},
+			want: map[string]interface{}{
+				"kind":     "ConfigMap",
+				"metadata": map[string]interface{}{"name": "prod-config"},
+				"data":     map[string]interface{}{"url": "https://prod.apps.svc", "replicas": int64(3)},
+			},
+		},
+		{
+			name: "escaped variable",
+			obj:  map[string]interface{}{"kind": "Pod", "args": []interface{}{"echo $${HOME} $HOME"}},
+			want: map[string]interface{}{"kind": "Pod", "args": []interface{}{"echo ${HOME} $HOME"}},
+		},
+		{
+			name:   "undefined variables",
+			obj:    map[string]interface{}{"kind": "ConfigMap", "metadata": map[string]interface{}{"name": "a"}, "data": map[string]interface{}{"a": "${B}", "b": "${A}"}},
+			errMsg: `ConfigMap "a" refers to undefined variables: A, B`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: tc.obj}
+			err := Substitute(obj, vars)
+			if tc.errMsg != "" {
+				require.EqualError(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, obj.Object)
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Dockerfile":                     {Data: []byte("FROM scratch")},
+		"manifests/README.md":            {Data: []byte("# bundle")},
+		"manifests/deployment.yaml":      {},
+		"manifests/crds/widgets.yaml":    {},
+		"manifests/crds/README.md":       {},
+		"manifests/tests/test.yaml":      {},
+		"manifests/env/dev/config.yaml":  {},
+		"manifests/env/prod/config.yaml": {},
+		"manifests/env/prod/tests/a.yml": {},
+	}
+	for _, tc := range []struct {
+		name             string
+		include, exclude []string
+		files            []string
+	}{
+		{
+			name:  "no patterns",
+			files: []string{"README.md", "crds/README.md", "crds/widgets.yaml", "deployment.yaml", "env/dev/config.yaml", "env/prod/config.yaml", "env/prod/tests/a.yml", "tests/test.yaml"},
+		},
+		{
+			name:    "include by name",
+			include: []string{"*.yaml"},
+			files:   []string{"crds/widgets.yaml", "deployment.yaml", "env/dev/config.yaml", "env/prod/config.yaml", "tests/test.yaml"},
+		},
+		{
+			name:    "exclude directories by name and path",
+			exclude: []string{"*.md", "tests", "env/dev"},
+			files:   []string{"crds/widgets.yaml", "deployment.yaml", "env/prod/config.yaml"},
+		},
+		{
+			name:    "include and exclude",
+			include: []string{"crds", "env/*/config.yaml"},
+			exclude: []string{"README.md", "env/prod"},
+			files:   []string{"crds/widgets.yaml", "env/dev/config.yaml"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filtered := Filter(fsys, tc.include, tc.exclude)
+			files, err := Files(filtered)
+			require.NoError(t, err)
+			require.Equal(t, tc.files, files)
+			_, err = fs.Stat(filtered, "Dockerfile")
+			require.NoError(t, err)
+		})
+	}
+
+	_, err := fs.ReadFile(Filter(fsys, nil, []string{"*.md"}), "manifests/README.md")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,63 @@
+package manifests
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// variablePattern matches ${NAME} variables, and $${NAME} escapes of them.
+var variablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Substitute replaces the ${NAME} variables in the string values of obj with
+// the values of vars, and $${NAME} with a literal ${NAME}. It fails when obj
+// refers to variables that vars does not define, and leaves obj unchanged in
+// that case.
+func Substitute(obj *unstructured.Unstructured, vars map[string]string) error {
+	undefined := map[string]struct{}{}
+	substituted := substitute(obj.Object, vars, undefined)
+	if len(undefined) > 0 {
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%s %q refers to undefined variables: %s", obj.GetKind(), obj.GetName(), strings.Join(names, ", "))
+	}
+	obj.Object = substituted.(map[string]interface{})
+	return nil
+}
+
+func substitute(value interface{}, vars map[string]string, undefined map[string]struct{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			out[key] = substitute(elem, vars, undefined)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			out[i] = substitute(elem, vars, undefined)
+		}
+		return out
+	case string:
+		return variablePattern.ReplaceAllStringFunc(v, func(match string) string {
+			if strings.HasPrefix(match, "$$") {
+				return match[1:]
+			}
+			name := match[2 : len(match)-1]
+			value, ok := vars[name]
+			if !ok {
+				undefined[name] = struct{}{}
+				return match
+			}
+			return value
+		})
+	}
+	return value
+}
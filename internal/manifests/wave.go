@@ -0,0 +1,49 @@
+package manifests
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WaveAnnotation is the annotation of the objects of a plain bundle that sets
+// the wave in which they are installed. Objects without it are in wave 0.
+const WaveAnnotation = "core.rukpak.io/wave"
+
+// Wave returns the wave of obj.
+func Wave(obj client.Object) (int, error) {
+	value, ok := obj.GetAnnotations()[WaveAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	wave, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation %q of %s %q: must be an integer", WaveAnnotation, value, obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName())
+	}
+	return wave, nil
+}
+
+// Waves groups objs by their wave, in ascending order of their waves. The
+// objects of each wave keep their order in objs.
+func Waves(objs []client.Object) ([][]client.Object, error) {
+	byWave := map[int][]client.Object{}
+	for _, obj := range objs {
+		wave, err := Wave(obj)
+		if err != nil {
+			return nil, err
+		}
+		byWave[wave] = append(byWave[wave], obj)
+	}
+	waves := make([]int, 0, len(byWave))
+	for wave := range byWave {
+		waves = append(waves, wave)
+	}
+	sort.Ints(waves)
+	grouped := make([][]client.Object, 0, len(waves))
+	for _, wave := range waves {
+		grouped = append(grouped, byWave[wave])
+	}
+	return grouped, nil
+}
@@ -0,0 +1,155 @@
+// Package policy admits the objects of bundles against the policies of
+// cluster admins before the objects are installed.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+// defaultTimeout is how long the response of a webhook without a timeout is
+// waited for.
+const defaultTimeout = 10 * time.Second
+
+// maxResponseBytes bounds the responses of webhooks that are read.
+const maxResponseBytes = 1 << 20
+
+// Violation is a violation of a policy by an object of a bundle.
+type Violation struct {
+	// Object identifies the object that violates the policy, such as
+	// "Deployment my-namespace/my-deployment".
+	Object string `json:"object"`
+	// Message describes the violation.
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Object, v.Message)
+}
+
+// Checker checks the objects of bundles against policies.
+type Checker interface {
+	// Check returns the violations of the policies of the checker by objs,
+	// the objects of the bundle that bi is about to install. An error is
+	// returned when the policies could not be checked.
+	Check(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, objs []client.Object) ([]Violation, error)
+}
+
+// Request is the request that a Webhook posts with the objects of a bundle.
+type Request struct {
+	// BundleInstance is the name of the BundleInstance that installs the
+	// objects.
+	BundleInstance string `json:"bundleInstance"`
+	// Bundle is the name of the Bundle of the objects.
+	Bundle string `json:"bundle"`
+	// Objects are the objects of the bundle.
+	Objects []client.Object `json:"objects"`
+}
+
+// Response is the response that a Webhook expects, with the violations of
+// the objects of its request.
+type Response struct {
+	// Violations are the violations of the policies of the webhook by the
+	// objects of the request.
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Webhook is a Checker that posts a Request with the objects of a bundle to
+// an external policy service, which responds with a Response that lists their
+// violations.
+type Webhook struct {
+	rukpakv1alpha1.PolicyWebhook
+	// Client posts the reviews, and defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+var _ Checker = &Webhook{}
+
+// WebhooksFor returns the Webhooks of the policy webhooks of config, which
+// trust their CA data along with the certificates of the system.
+func WebhooksFor(config rukpakv1alpha1.RukpakConfigSpec) ([]Checker, error) {
+	var checkers []Checker
+	for _, w := range config.PolicyWebhooks {
+		httpClient := http.DefaultClient
+		if len(w.CAData) > 0 {
+			var err error
+			if httpClient, err = util.HTTPClientWithCABundle(nil, w.CAData); err != nil {
+				return nil, fmt.Errorf("policy webhook %q: %w", w.Name, err)
+			}
+		}
+		checkers = append(checkers, &Webhook{PolicyWebhook: w, Client: httpClient})
+	}
+	return checkers, nil
+}
+
+// Check implements Checker. The violations of the response are attributed to
+// the webhook. Errors of a webhook whose failure policy is Ignore are logged
+// rather than returned.
+func (w *Webhook) Check(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, objs []client.Object) ([]Violation, error) {
+	violations, err := w.review(ctx, Request{BundleInstance: bi.Name, Bundle: bi.Spec.BundleName, Objects: objs})
+	if err != nil {
+		err = fmt.Errorf("policy webhook %q: %w", w.Name, err)
+		if w.FailurePolicy == rukpakv1alpha1.PolicyFailurePolicyIgnore {
+			log.FromContext(ctx).Error(err, "ignoring failed policy webhook")
+			return nil, nil
+		}
+		return nil, err
+	}
+	for i := range violations {
+		violations[i].Message = fmt.Sprintf("%s (policy webhook %q)", violations[i].Message, w.Name)
+	}
+	return violations, nil
+}
+
+// review posts req to the webhook, and returns the violations of its
+// response.
+func (w *Webhook) review(ctx context.Context, req Request) ([]Violation, error) {
+	timeout := defaultTimeout
+	if w.Timeout != nil && w.Timeout.Duration > 0 {
+		timeout = w.Timeout.Duration
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpClient := w.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status %q: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	var r Response
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return r.Violations, nil
+}
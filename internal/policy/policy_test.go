@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestWebhookCheck(t *testing.T) {
+	bi := &rukpakv1alpha1.BundleInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "bi"},
+		Spec:       rukpakv1alpha1.BundleInstanceSpec{BundleName: "bundle"},
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("Service")
+	obj.SetNamespace("ns")
+	obj.SetName("svc")
+	objs := []client.Object{obj}
+
+	for _, tt := range []struct {
+		name           string
+		failurePolicy  string
+		handler        http.HandlerFunc
+		timeout        time.Duration
+		wantViolations []Violation
+		wantErr        string
+	}{
+		{
+			name: "no violations",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{}`))
+			},
+		},
+		{
+			name: "violations",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					BundleInstance string                       `json:"bundleInstance"`
+					Bundle         string                       `json:"bundle"`
+					Objects        []*unstructured.Unstructured `json:"objects"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.BundleInstance != "bi" || req.Bundle != "bundle" || len(req.Objects) != 1 {
+					http.Error(w, "unexpected request", http.StatusBadRequest)
+					return
+				}
+				_, _ = w.Write([]byte(`{"violations":[{"object":"Service ns/svc","message":"services of type LoadBalancer are not allowed"}]}`))
+			},
+			wantViolations: []Violation{{Object: "Service ns/svc", Message: `services of type LoadBalancer are not allowed (policy webhook "opa")`}},
+		},
+		{
+			name: "error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "policies not loaded", http.StatusInternalServerError)
+			},
+			wantErr: `policy webhook "opa": unexpected response status "500 Internal Server Error": policies not loaded`,
+		},
+		{
+			name:          "ignored error",
+			failurePolicy: rukpakv1alpha1.PolicyFailurePolicyIgnore,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "policies not loaded", http.StatusInternalServerError)
+			},
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(time.Second)
+			},
+			timeout: 100 * time.Millisecond,
+			wantErr: "context deadline exceeded",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(tt.handler)
+			defer server.Close()
+			w := &Webhook{
+				PolicyWebhook: rukpakv1alpha1.PolicyWebhook{Name: "opa", URL: server.URL, FailurePolicy: tt.failurePolicy},
+				Client:        server.Client(),
+			}
+			if tt.timeout > 0 {
+				w.Timeout = &metav1.Duration{Duration: tt.timeout}
+			}
+			violations, err := w.Check(context.Background(), bi, objs)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantViolations, violations)
+		})
+	}
+}
+
+func TestWebhooksFor(t *testing.T) {
+	checkers, err := WebhooksFor(rukpakv1alpha1.RukpakConfigSpec{PolicyWebhooks: []rukpakv1alpha1.PolicyWebhook{
+		{Name: "a", URL: "https://a.example.com"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, checkers, 1)
+
+	_, err = WebhooksFor(rukpakv1alpha1.RukpakConfigSpec{PolicyWebhooks: []rukpakv1alpha1.PolicyWebhook{
+		{Name: "b", URL: "https://b.example.com", CAData: []byte("not a certificate")},
+	}})
+	require.EqualError(t, err, `policy webhook "b": CA bundle contains no PEM-encoded certificates`)
+}
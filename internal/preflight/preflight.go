@@ -0,0 +1,231 @@
+// Package preflight checks, before bundle content is installed, that the
+// provisioner is permitted to manage every object in the bundle.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+var (
+	// namespacedVerbs are the verbs needed to install, upgrade and uninstall
+	// an object.
+	namespacedVerbs = []string{"get", "create", "update", "patch", "delete"}
+	// clusterVerbs are the verbs needed to watch the installed objects of a
+	// resource, which are cached across all namespaces.
+	clusterVerbs = []string{"list", "watch"}
+)
+
+// Checker checks the permissions of the identity of Client with
+// SelfSubjectAccessReviews.
+type Checker struct {
+	Client client.Client
+	Mapper meta.RESTMapper
+
+	// ServiceAccount, when set, is the ServiceAccount that installs and
+	// upgrades objects on behalf of the identity of Client. The permissions
+	// needed to manage objects are checked for the ServiceAccount with
+	// SubjectAccessReviews, while the permissions needed to watch them are
+	// still checked for the identity of Client.
+	ServiceAccount *types.NamespacedName
+}
+
+// Missing are the missing permissions needed to manage bundle content.
+type Missing struct {
+	// Provisioner are the rules missing for the identity of the Checker.
+	Provisioner []rbacv1.PolicyRule
+	// ServiceAccount are the rules missing for the ServiceAccount of the
+	// Checker, if any.
+	ServiceAccount []rbacv1.PolicyRule
+}
+
+// Empty returns true if no permissions are missing.
+func (m Missing) Empty() bool {
+	return len(m.Provisioner) == 0 && len(m.ServiceAccount) == 0
+}
+
+type resourceKey struct {
+	group     string
+	resource  string
+	namespace string
+}
+
+// MissingPermissions returns the permissions that are needed, but not granted,
+// to manage objs. Namespace-scoped objects without a namespace are
+// checked in defaultNamespace. The resources of kinds that are defined by
+// CustomResourceDefinitions in objs are determined from those definitions.
+func (c *Checker) MissingPermissions(ctx context.Context, objs []client.Object, defaultNamespace string) (*Missing, error) {
+	bundleResources := map[schema.GroupKind]resourceKey{}
+	for _, obj := range objs {
+		if obj.GetObjectKind().GroupVersionKind().GroupKind() != apiextensionsv1.Kind("CustomResourceDefinition") {
+			continue
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).Object, crd); err != nil {
+			return nil, fmt.Errorf("convert CRD %q: %w", obj.GetName(), err)
+		}
+		key := resourceKey{group: crd.Spec.Group, resource: crd.Spec.Names.Plural}
+		if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+			key.namespace = defaultNamespace
+		}
+		bundleResources[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = key
+	}
+
+	var keys []resourceKey
+	seen := map[resourceKey]struct{}{}
+	for _, obj := range objs {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		key, ok := bundleResources[gvk.GroupKind()]
+		if !ok {
+			mapping, err := c.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				return nil, fmt.Errorf("determine resource of %s %q: %w", gvk, obj.GetName(), err)
+			}
+			key = resourceKey{group: gvk.Group, resource: mapping.Resource.Resource}
+			if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+				key.namespace = defaultNamespace
+			}
+		}
+		if key.namespace != "" && obj.GetNamespace() != "" {
+			key.namespace = obj.GetNamespace()
+		}
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	provisionerMissing := map[schema.GroupResource]map[string]struct{}{}
+	serviceAccountMissing := map[schema.GroupResource]map[string]struct{}{}
+	reviewed := map[reviewKey]bool{}
+	for _, key := range keys {
+		for _, check := range []struct {
+			namespace      string
+			verbs          []string
+			serviceAccount *types.NamespacedName
+		}{
+			{key.namespace, namespacedVerbs, c.ServiceAccount},
+			{"", clusterVerbs, nil},
+		} {
+			missing := provisionerMissing
+			if check.serviceAccount != nil {
+				missing = serviceAccountMissing
+			}
+			for _, verb := range check.verbs {
+				rk := reviewKey{resourceKey: resourceKey{group: key.group, resource: key.resource, namespace: check.namespace}, verb: verb}
+				allowed, ok := reviewed[rk]
+				if !ok {
+					var err error
+					if allowed, err = c.allowed(ctx, check.serviceAccount, rk.resourceKey, verb); err != nil {
+						return nil, err
+					}
+					reviewed[rk] = allowed
+				}
+				if allowed {
+					continue
+				}
+				gr := schema.GroupResource{Group: key.group, Resource: key.resource}
+				if missing[gr] == nil {
+					missing[gr] = map[string]struct{}{}
+				}
+				missing[gr][verb] = struct{}{}
+			}
+		}
+	}
+	return &Missing{
+		Provisioner:    toRules(provisionerMissing),
+		ServiceAccount: toRules(serviceAccountMissing),
+	}, nil
+}
+
+// reviewKey identifies an access review. The verbs of the reviews of the
+// ServiceAccount and of the identity of the Checker do not overlap, so the
+// subject of a review is not part of its key.
+type reviewKey struct {
+	resourceKey
+	verb string
+}
+
+func toRules(missing map[schema.GroupResource]map[string]struct{}) []rbacv1.PolicyRule {
+	var rules []rbacv1.PolicyRule
+	for gr, verbs := range missing {
+		rule := rbacv1.PolicyRule{APIGroups: []string{gr.Group}, Resources: []string{gr.Resource}}
+		for _, verb := range append(append([]string{}, namespacedVerbs...), clusterVerbs...) {
+			if _, ok := verbs[verb]; ok {
+				rule.Verbs = append(rule.Verbs, verb)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].APIGroups[0] != rules[j].APIGroups[0] {
+			return rules[i].APIGroups[0] < rules[j].APIGroups[0]
+		}
+		return rules[i].Resources[0] < rules[j].Resources[0]
+	})
+	return rules
+}
+
+// allowed reviews whether verb is allowed on the resource of key for the
+// given service account, or for the identity of Client when it is nil.
+func (c *Checker) allowed(ctx context.Context, serviceAccount *types.NamespacedName, key resourceKey, verb string) (bool, error) {
+	attrs := &authorizationv1.ResourceAttributes{
+		Namespace: key.namespace,
+		Verb:      verb,
+		Group:     key.group,
+		Resource:  key.resource,
+	}
+	gr := schema.GroupResource{Group: key.group, Resource: key.resource}
+	if serviceAccount != nil {
+		review := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: attrs,
+				User:               util.ServiceAccountUsername(serviceAccount.Namespace, serviceAccount.Name),
+				Groups:             util.ServiceAccountGroups(serviceAccount.Namespace),
+			},
+		}
+		if err := c.Client.Create(ctx, review); err != nil {
+			return false, fmt.Errorf("review access of service account %q to %s: %w", serviceAccount, gr, err)
+		}
+		return review.Status.Allowed, nil
+	}
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+	}
+	if err := c.Client.Create(ctx, review); err != nil {
+		return false, fmt.Errorf("review access to %s: %w", gr, err)
+	}
+	return review.Status.Allowed, nil
+}
+
+// FormatRules formats rules in the notation of the rules of a ClusterRole.
+func FormatRules(rules []rbacv1.PolicyRule) string {
+	formatted := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		formatted = append(formatted, fmt.Sprintf("{apiGroups: [%q], resources: [%q], verbs: [%s]}",
+			rule.APIGroups[0], rule.Resources[0], quoteAll(rule.Verbs)))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, fmt.Sprintf("%q", v))
+	}
+	return strings.Join(quoted, ", ")
+}
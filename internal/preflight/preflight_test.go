@@ -0,0 +1,128 @@
+package preflight
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeReviewer allows the SelfSubjectAccessReviews of the allowed attributes.
+type fakeReviewer struct {
+	client.Client
+	allowed  map[authorizationv1.ResourceAttributes]bool
+	reviewed int
+	// users are the users of the SubjectAccessReviews by verb.
+	users map[string]string
+}
+
+func (f *fakeReviewer) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	f.reviewed++
+	switch review := obj.(type) {
+	case *authorizationv1.SelfSubjectAccessReview:
+		review.Status.Allowed = f.allowed[*review.Spec.ResourceAttributes]
+	case *authorizationv1.SubjectAccessReview:
+		review.Status.Allowed = f.allowed[*review.Spec.ResourceAttributes]
+		if f.users == nil {
+			f.users = map[string]string{}
+		}
+		f.users[review.Spec.ResourceAttributes.Verb] = review.Spec.User
+	}
+	return nil
+}
+
+func object(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestMissingPermissions(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}, meta.RESTScopeRoot)
+
+	allowed := map[authorizationv1.ResourceAttributes]bool{}
+	allow := func(group, resource, namespace string, verbs ...string) {
+		for _, verb := range verbs {
+			allowed[authorizationv1.ResourceAttributes{Group: group, Resource: resource, Namespace: namespace, Verb: verb}] = true
+		}
+	}
+	allow("apps", "deployments", "release-ns", "get", "create", "update", "patch", "delete")
+	allow("apps", "deployments", "", "list", "watch")
+	allow("apps", "deployments", "other-ns", "get")
+	allow("rbac.authorization.k8s.io", "clusterroles", "", "get", "create", "update", "patch", "delete", "list", "watch")
+	allow("apiextensions.k8s.io", "customresourcedefinitions", "", "get", "create", "update", "patch", "delete", "list", "watch")
+	allow("example.com", "widgets", "release-ns", "get", "create", "update", "patch", "delete")
+
+	crd := object("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com")
+	crd.Object["spec"] = map[string]interface{}{
+		"group": "example.com",
+		"scope": "Namespaced",
+		"names": map[string]interface{}{"kind": "Widget", "plural": "widgets"},
+	}
+	objs := []client.Object{
+		object("apps/v1", "Deployment", "", "a"),
+		object("apps/v1", "Deployment", "", "b"),
+		object("apps/v1", "Deployment", "other-ns", "c"),
+		object("rbac.authorization.k8s.io/v1", "ClusterRole", "", "d"),
+		crd,
+		object("example.com/v1", "Widget", "", "e"),
+	}
+
+	reviewer := &fakeReviewer{allowed: allowed}
+	c := &Checker{Client: reviewer, Mapper: mapper}
+	missing, err := c.MissingPermissions(context.Background(), objs, "release-ns")
+	require.NoError(t, err)
+	require.False(t, missing.Empty())
+	require.Empty(t, missing.ServiceAccount)
+	rules := missing.Provisioner
+	require.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"create", "update", "patch", "delete"}},
+		{APIGroups: []string{"example.com"}, Resources: []string{"widgets"}, Verbs: []string{"list", "watch"}},
+	}, rules)
+	// Every verb is reviewed once per resource and namespace: in the 5
+	// namespaces of the objects, and across the namespaces of 4 resources.
+	require.Equal(t, 5*len(namespacedVerbs)+4*len(clusterVerbs), reviewer.reviewed)
+
+	require.Equal(t, `{apiGroups: ["apps"], resources: ["deployments"], verbs: ["create", "update", "patch", "delete"]}, `+
+		`{apiGroups: ["example.com"], resources: ["widgets"], verbs: ["list", "watch"]}`, FormatRules(rules))
+}
+
+func TestMissingPermissionsServiceAccount(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	reviewer := &fakeReviewer{allowed: map[authorizationv1.ResourceAttributes]bool{
+		{Group: "apps", Resource: "deployments", Namespace: "tenant", Verb: "get"}: true,
+		{Group: "apps", Resource: "deployments", Verb: "list"}:                     true,
+		{Group: "apps", Resource: "deployments", Verb: "watch"}:                    true,
+	}}
+	c := &Checker{Client: reviewer, Mapper: mapper, ServiceAccount: &types.NamespacedName{Namespace: "tenant", Name: "installer"}}
+	missing, err := c.MissingPermissions(context.Background(), []client.Object{object("apps/v1", "Deployment", "", "a")}, "tenant")
+	require.NoError(t, err)
+	require.Empty(t, missing.Provisioner)
+	require.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"create", "update", "patch", "delete"}},
+	}, missing.ServiceAccount)
+	require.Equal(t, "system:serviceaccount:tenant:installer", reviewer.users["get"])
+	require.Equal(t, "", reviewer.users["list"], "expected watch permissions to be reviewed for the provisioner")
+}
+
+func TestMissingPermissionsUnknownKind(t *testing.T) {
+	c := &Checker{Client: &fakeReviewer{}, Mapper: meta.NewDefaultRESTMapper(nil)}
+	_, err := c.MissingPermissions(context.Background(), []client.Object{object("example.com/v1", "Widget", "", "a")}, "release-ns")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `determine resource of example.com/v1, Kind=Widget "a"`)
+}
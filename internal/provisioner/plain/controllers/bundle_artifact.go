@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/manifests"
+	"github.com/operator-framework/rukpak/internal/registry"
+	"github.com/operator-framework/rukpak/internal/tracing"
+	"github.com/operator-framework/rukpak/internal/updater"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+// unpackArtifact unpacks the image source of bundle when it refers to an OCI
+// artifact, such as one pushed with oras, which cannot be run by an unpack
+// pod, and reports whether it does. The layers of the artifact are pulled by
+// the provisioner itself.
+//
+// Sources whose manifest cannot be fetched, for example because their image
+// is only available to the nodes, and sources that are already unpacked by a
+// pod, are left to the unpack pod.
+func (r *BundleReconciler) unpackArtifact(ctx context.Context, u *updater.Updater, bundle *rukpakv1alpha1.Bundle) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "Bundle.UnpackArtifact")
+	defer func() { tracing.End(span, err) }()
+	source := bundle.Spec.Source
+	if source.Image == nil {
+		return false, nil
+	}
+	pod := &corev1.Pod{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: r.PodNamespace, Name: util.PodName(plainBundleProvisionerName, bundle.Name)}, pod)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return false, client.IgnoreNotFound(err)
+	}
+	config, err := util.GetRukpakConfig(ctx, r.Client)
+	if err != nil {
+		return false, err
+	}
+	// Tags refused by the digestOnly policy are reported by ensureUnpackPod.
+	if config.DigestOnly && imageTag(source) {
+		return false, nil
+	}
+
+	rc, m, manifestDigest, err := r.getManifest(ctx, config, source)
+	if err != nil {
+		log.FromContext(ctx).V(util.LogLevelDebug).Info("unable to fetch image manifest, unpacking it with a pod", "error", err.Error())
+		return false, nil
+	}
+	if !registry.IsArtifact(m) {
+		return false, nil
+	}
+
+	bundleFS, err := rc.PullArtifact(ctx, m, manifests.Dir, r.Limits.MaxBytes)
+	if err != nil {
+		u.UpdateStatus(updater.SetBundleInfo(nil), updater.EnsureBundleDigest(""), updater.SetResolvedSource(nil))
+		return true, updateStatusUnpackFailing(u, bundle, fmt.Errorf("pull bundle artifact: %w", err))
+	}
+	imageID := imageName(source.Image.Ref) + "@" + manifestDigest
+	if err := r.storeContents(ctx, u, bundle, bundleFS, imageID); err != nil {
+		return true, err
+	}
+	u.UpdateStatus(updater.SetResolvedSource(resolvedSource(source, "", imageID)))
+	return true, nil
+}
+
+// getManifest returns the manifest that the image source refers to, along
+// with its digest and a client of its repository.
+func (r *BundleReconciler) getManifest(ctx context.Context, config rukpakv1alpha1.RukpakConfigSpec, source rukpakv1alpha1.BundleSource) (*registry.Client, *registry.Manifest, string, error) {
+	httpClient, err := r.httpClientFor(ctx, util.CABundleFor(config, source))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	rc, err := r.registryClient(ctx, *source.Image, httpClient)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	m, manifestDigest, err := rc.GetManifestDigest(ctx, registry.ParseReference(source.Image.Ref))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return rc, m, manifestDigest, nil
+}
+
+// imageName returns ref without its tag or digest.
+func imageName(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i]
+	}
+	return ref
+}
@@ -25,50 +25,126 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"path/filepath"
+	"math"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/nlepage/go-tarfs"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	apimachyaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/checksums"
+	"github.com/operator-framework/rukpak/internal/cosign"
 	"github.com/operator-framework/rukpak/internal/git"
+	"github.com/operator-framework/rukpak/internal/manifests"
+	"github.com/operator-framework/rukpak/internal/registry"
+	"github.com/operator-framework/rukpak/internal/source"
 	"github.com/operator-framework/rukpak/internal/storage"
+	"github.com/operator-framework/rukpak/internal/tracing"
 	"github.com/operator-framework/rukpak/internal/updater"
 	"github.com/operator-framework/rukpak/internal/util"
+	"github.com/operator-framework/rukpak/internal/validation"
+	"github.com/operator-framework/rukpak/pkg/status"
 )
 
 const (
 	bundleUnpackContainerName  = "bundle"
 	plainBundleProvisionerName = "plain"
+
+	// resolvedRevisionAnnotation records, on the unpack pod of a Bundle with
+	// an image tag or polled source, the image digest or git commit that the
+	// pod unpacks.
+	resolvedRevisionAnnotation = "core.rukpak.io/resolved-revision"
+
+	// caBundleDir and caBundleFile are where the CA bundle of a git source
+	// is mounted in the container that clones it.
+	caBundleDir  = "/etc/rukpak/ca-bundle"
+	caBundleFile = "ca.crt"
+
+	// storedContentFinalizer holds the deletion of a Bundle until its unpack
+	// pod and its stored content have been deleted, since not every storage
+	// backend garbage collects the content of deleted Bundles.
+	storedContentFinalizer = "core.rukpak.io/delete-stored-content"
 )
 
+// errDigestRequired is returned when the DigestOnly policy of the
+// RukpakConfig refuses to unpack an image tag.
+var errDigestRequired = errors.New("the digestOnly policy requires image sources to refer to a digest")
+
 // BundleReconciler reconciles a Bundle object
 type BundleReconciler struct {
 	client.Client
 	KubeClient kubernetes.Interface
 	Scheme     *runtime.Scheme
 	Storage    storage.Storage
+	// Unpackers unpack the sources of Bundles that are not unpacked by an
+	// unpack pod, keyed by their type. The sources of other types are
+	// unpacked by a pod, if they are images or git repositories.
+	Unpackers source.Registry
+	// Verifier verifies the signatures of bundle images that configure
+	// signature verification.
+	Verifier *cosign.Verifier
+	// HTTPClient is the client with which polled sources are resolved.
+	// util.DefaultHTTPClient is used when HTTPClient is nil.
+	HTTPClient *http.Client
+	// APIReader reads the ConfigMaps and Secrets of the CA bundles of
+	// sources, which are not labeled for the cache of the manager.
+	APIReader client.Reader
+	// ProxyEnv are the proxy environment variables of the containers that
+	// clone git sources.
+	ProxyEnv []corev1.EnvVar
+	// Recorder records events for the transitions of the Unpacked condition
+	// of Bundles. No events are recorded when it is nil.
+	Recorder record.EventRecorder
 
 	PodNamespace    string
 	UnpackImage     string
 	CopyBundleImage string
 	GitClientImage  string
+
+	// ReadOnly configures the reconciler to only verify and report the state
+	// of already unpacked Bundles, without creating unpack pods or persisting
+	// bundle contents.
+	ReadOnly bool
+
+	// MaxConcurrentReconciles is the number of Bundles that are reconciled in
+	// parallel. It defaults to 1.
+	MaxConcurrentReconciles int
+
+	// RateLimit is the rate limit of the retries of failed reconciles of
+	// Bundles. An unset RateLimit keeps the default rate limiter of
+	// controller-runtime.
+	RateLimit util.QueueRateLimit
+	// Limits bounds the objects of the Bundles that are unpacked. The objects
+	// of a Bundle that exceeds them are not stored.
+	Limits manifests.Limits
+	// Validators validate the manifests of the Bundles that are unpacked
+	// from an image, git repository or ConfigMap. The objects of a Bundle
+	// with invalid manifests are not stored.
+	Validators []validation.Validator
+	// Selector selects the Bundles that are unpacked by this instance of the
+	// provisioner among those of its provisioner class, so that several
+	// instances share them. A nil Selector selects every Bundle.
+	Selector labels.Selector
 }
 
 //+kubebuilder:rbac:groups=core.rukpak.io,resources=bundles,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.rukpak.io,resources=bundles/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core.rukpak.io,resources=bundles/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core.rukpak.io,resources=rukpakconfigs,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=pods;secrets;configmaps,verbs=get;list;watch;create;delete
 //+kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
 
@@ -78,54 +154,175 @@ type BundleReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (r *BundleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reconcileErr error) {
-	l := log.FromContext(ctx)
-	l.V(1).Info("starting reconciliation")
-	defer l.V(1).Info("ending reconciliation")
+	l := log.FromContext(ctx).WithValues(util.LogKeyBundle, req.Name, util.LogKeyReconcileID, util.NewReconcileID())
+	ctx = log.IntoContext(ctx, l)
+	l.V(util.LogLevelDebug).Info("starting reconciliation")
+	defer l.V(util.LogLevelDebug).Info("ending reconciliation")
+	ctx, span := tracing.Start(ctx, "Bundle.Reconcile", tracing.AttributeBundle.String(req.Name))
+	defer func() { tracing.End(span, reconcileErr) }()
 	bundle := &rukpakv1alpha1.Bundle{}
 	if err := r.Get(ctx, req.NamespacedName, bundle); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	// The pods and secrets of a Bundle that was relabeled into another shard
+	// still enqueue it here.
+	if !util.MatchesSelector(r.Selector, bundle) {
+		return ctrl.Result{}, nil
+	}
+	span.SetAttributes(tracing.AttributeSourceType.String(string(bundle.Spec.Source.Type)))
 
+	// In read-only mode, the finalizer is neither added nor handled, as
+	// handling it would delete the stored content.
+	if bundle.DeletionTimestamp != nil {
+		if r.ReadOnly || !controllerutil.ContainsFinalizer(bundle, storedContentFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, r.finalize(ctx, bundle)
+	}
+	if !r.ReadOnly && !controllerutil.ContainsFinalizer(bundle, storedContentFinalizer) {
+		patch := client.MergeFromWithOptions(bundle.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		controllerutil.AddFinalizer(bundle, storedContentFinalizer)
+		if err := r.Patch(ctx, bundle, patch); err != nil {
+			return ctrl.Result{}, fmt.Errorf("add finalizer: %w", err)
+		}
+	}
+
+	previous := unpackedState(bundle)
 	u := updater.New(r.Client)
 	defer func() {
+		u.UpdateStatus(updater.EnsureReadyCondition(bundle))
 		if err := u.Apply(ctx, bundle); err != nil {
 			l.Error(err, "failed to update status")
+			return
+		}
+		r.recordUnpackTransition(bundle, previous)
+		if reconcileErr != nil {
+			reconcileErr = util.ReconcileError(reconcileErr, util.UnreadyCondition(bundle.Status.Conditions, []string{rukpakv1alpha1.TypeUnpacked}, nil))
 		}
 	}()
 	u.UpdateStatus(updater.EnsureObservedGeneration(bundle.Generation))
 
+	if r.ReadOnly {
+		return ctrl.Result{}, r.verifyUnpacked(ctx, &u, bundle)
+	}
+
+	if _, ok := r.Unpackers[bundle.Spec.Source.Type]; ok {
+		if err := r.unpack(ctx, &u, bundle); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: pollInterval(bundle.Spec.Source)}, nil
+	}
+	switch bundle.Spec.Source.Type {
+	case rukpakv1alpha1.SourceTypeImage:
+		artifact, err := r.unpackArtifact(ctx, &u, bundle)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if artifact {
+			return ctrl.Result{RequeueAfter: pollInterval(bundle.Spec.Source)}, nil
+		}
+	}
+
 	pod := &corev1.Pod{}
-	if op, err := r.ensureUnpackPod(ctx, bundle, pod); err != nil {
-		u.UpdateStatus(updater.SetBundleInfo(nil), updater.EnsureBundleDigest(""))
-		return ctrl.Result{}, updateStatusUnpackFailing(&u, fmt.Errorf("ensure unpack pod: %w", err))
+	if op, err := r.ensureUnpackPod(ctx, bundle, pod); errors.Is(err, errDigestRequired) {
+		// The policy is only checked again when the Bundle is reconciled for
+		// another reason, so the error is not retried.
+		u.UpdateStatus(
+			updater.SetBundleInfo(nil),
+			updater.EnsureBundleDigest(""),
+			updater.SetResolvedSource(nil),
+			updater.SetPhase(rukpakv1alpha1.PhaseFailing),
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.DigestRequired, err.Error())),
+		)
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		u.UpdateStatus(updater.SetBundleInfo(nil), updater.EnsureBundleDigest(""), updater.SetResolvedSource(nil))
+		return ctrl.Result{}, updateStatusUnpackFailing(&u, bundle, fmt.Errorf("ensure unpack pod: %w", err))
 	} else if op == controllerutil.OperationResultCreated || op == controllerutil.OperationResultUpdated || pod.DeletionTimestamp != nil {
-		updateStatusUnpackPending(&u)
+		if !reunpacking(bundle) {
+			updateStatusUnpackPending(&u, bundle)
+		}
 		return ctrl.Result{}, nil
 	}
 
 	switch phase := pod.Status.Phase; phase {
 	case corev1.PodPending:
-		r.handlePendingPod(&u, pod)
+		if !reunpacking(bundle) {
+			r.handlePendingPod(&u, bundle, pod)
+		}
 		return ctrl.Result{}, nil
 	case corev1.PodRunning:
-		r.handleRunningPod(&u)
+		if !reunpacking(bundle) {
+			r.handleRunningPod(&u, bundle)
+		}
 		return ctrl.Result{}, nil
 	case corev1.PodFailed:
-		return ctrl.Result{}, r.handleFailedPod(ctx, &u, pod)
+		return r.handleFailedPod(ctx, &u, bundle, pod)
 	case corev1.PodSucceeded:
-		return ctrl.Result{}, r.handleCompletedPod(ctx, &u, bundle, pod)
+		if err := r.handleCompletedPod(ctx, &u, bundle, pod); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: pollInterval(bundle.Spec.Source)}, nil
 	default:
-		return ctrl.Result{}, r.handleUnexpectedPod(ctx, &u, pod)
+		return ctrl.Result{}, r.handleUnexpectedPod(ctx, &u, bundle, pod)
+	}
+}
+
+// finalize deletes the unpack pod of the deleted bundle, its stored objects
+// and the content that the unpacker of its source keeps, and removes its
+// finalizer once they are gone.
+func (r *BundleReconciler) finalize(ctx context.Context, bundle *rukpakv1alpha1.Bundle) (err error) {
+	ctx, span := tracing.Start(ctx, "Bundle.Finalize")
+	defer func() { tracing.End(span, err) }()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: r.PodNamespace,
+		Name:      util.PodName(plainBundleProvisionerName, bundle.Name),
+	}}
+	if err := r.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("delete unpack pod: %w", err)
+	}
+	if err := r.Storage.Delete(ctx, bundle); err != nil {
+		return fmt.Errorf("delete stored bundle objects: %w", err)
 	}
+	if deleter, ok := r.Unpackers[bundle.Spec.Source.Type].(source.Deleter); ok {
+		if err := deleter.Delete(ctx, bundle); err != nil {
+			return fmt.Errorf("delete %s source content: %w", bundle.Spec.Source.Type, err)
+		}
+	}
+	log.FromContext(ctx).V(util.LogLevelDebug).Info("deleted stored bundle content")
+
+	patch := client.MergeFromWithOptions(bundle.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.RemoveFinalizer(bundle, storedContentFinalizer)
+	return client.IgnoreNotFound(r.Patch(ctx, bundle, patch))
 }
 
-func (r *BundleReconciler) handleUnexpectedPod(ctx context.Context, u *updater.Updater, pod *corev1.Pod) error {
+// verifyUnpacked checks that the contents of an unpacked Bundle can still be
+// loaded from storage, and reports a failing status when they cannot. Bundles
+// that are not yet unpacked are left untouched, as unpacking them would require
+// mutating cluster state.
+func (r *BundleReconciler) verifyUnpacked(ctx context.Context, u *updater.Updater, bundle *rukpakv1alpha1.Bundle) error {
+	if bundle.Status.Phase != rukpakv1alpha1.PhaseUnpacked {
+		log.FromContext(ctx).V(util.LogLevelDebug).Info("skipping unpack in read-only mode", "phase", bundle.Status.Phase)
+		return nil
+	}
+	if _, err := r.Storage.Load(ctx, bundle); err != nil {
+		err = fmt.Errorf("verify stored bundle contents: %w", err)
+		u.UpdateStatus(
+			updater.SetPhase(rukpakv1alpha1.PhaseFailing),
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.UnpackVerificationFailed, err.Error())),
+		)
+		return err
+	}
+	return nil
+}
+
+func (r *BundleReconciler) handleUnexpectedPod(ctx context.Context, u *updater.Updater, bundle *rukpakv1alpha1.Bundle, pod *corev1.Pod) error {
 	err := fmt.Errorf("unexpected pod phase: %v", pod.Status.Phase)
 	_ = r.Delete(ctx, pod)
-	return updateStatusUnpackFailing(u, err)
+	return updateStatusUnpackFailing(u, bundle, err)
 }
 
-func (r *BundleReconciler) handlePendingPod(u *updater.Updater, pod *corev1.Pod) {
+func (r *BundleReconciler) handlePendingPod(u *updater.Updater, bundle *rukpakv1alpha1.Bundle, pod *corev1.Pod) {
 	var messages []string
 	for _, cStatus := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
 		if cStatus.State.Waiting != nil && cStatus.State.Waiting.Reason == "ErrImagePull" {
@@ -138,62 +335,150 @@ func (r *BundleReconciler) handlePendingPod(u *updater.Updater, pod *corev1.Pod)
 	u.UpdateStatus(
 		updater.SetBundleInfo(nil),
 		updater.EnsureBundleDigest(""),
+		updater.SetResolvedSource(nil),
 		updater.SetPhase(rukpakv1alpha1.PhasePending),
-		updater.EnsureCondition(metav1.Condition{
-			Type:    rukpakv1alpha1.TypeUnpacked,
-			Status:  metav1.ConditionFalse,
-			Reason:  rukpakv1alpha1.ReasonUnpackPending,
-			Message: strings.Join(messages, "; "),
-		}),
+		updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.UnpackPending, strings.Join(messages, "; "))),
 	)
 }
 
-func (r *BundleReconciler) handleRunningPod(u *updater.Updater) {
+func (r *BundleReconciler) handleRunningPod(u *updater.Updater, bundle *rukpakv1alpha1.Bundle) {
 	u.UpdateStatus(
 		updater.SetBundleInfo(nil),
 		updater.EnsureBundleDigest(""),
+		updater.SetResolvedSource(nil),
 		updater.SetPhase(rukpakv1alpha1.PhaseUnpacking),
-		updater.EnsureCondition(metav1.Condition{
-			Type:   rukpakv1alpha1.TypeUnpacked,
-			Status: metav1.ConditionFalse,
-			Reason: rukpakv1alpha1.ReasonUnpacking,
-		}),
+		updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.Unpacking, "")),
 	)
 }
 
-func (r *BundleReconciler) handleFailedPod(ctx context.Context, u *updater.Updater, pod *corev1.Pod) error {
+// handleFailedPod reports the failure of the unpack pod, and deletes it so
+// that it is recreated. When the Bundle has an unpack retry policy, the
+// failed pod is only deleted once its backoff has passed, and is kept in
+// place once the retries are exhausted.
+func (r *BundleReconciler) handleFailedPod(ctx context.Context, u *updater.Updater, bundle *rukpakv1alpha1.Bundle, pod *corev1.Pod) (ctrl.Result, error) {
 	u.UpdateStatus(
 		updater.SetBundleInfo(nil),
 		updater.EnsureBundleDigest(""),
 		updater.SetPhase(rukpakv1alpha1.PhaseFailing),
 	)
-	logs, err := r.getPodLogs(ctx, pod)
-	if err != nil {
-		err = fmt.Errorf("unpack failed: failed to retrieve failed pod logs: %w", err)
+	reason, logStr := status.UnpackFailed, ""
+	if pod.Status.Reason == podDeadlineExceeded {
+		// The containers of a timed out pod are killed, so their logs do not
+		// tell why the unpack did not complete.
+		reason = status.UnpackTimedOut
+		logStr = fmt.Sprintf("unpack did not complete within the timeout of %s", time.Duration(*pod.Spec.ActiveDeadlineSeconds)*time.Second)
+	} else {
+		logs, err := r.getPodLogs(ctx, pod)
+		if err != nil {
+			err = fmt.Errorf("unpack failed: failed to retrieve failed pod logs: %w", err)
+			u.UpdateStatus(
+				updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.UnpackFailed, err.Error())),
+			)
+			return ctrl.Result{}, err
+		}
+		logStr = string(logs)
+	}
+	policy := bundle.Spec.UnpackRetryPolicy
+	if policy == nil {
 		u.UpdateStatus(
-			updater.EnsureCondition(metav1.Condition{
-				Type:    rukpakv1alpha1.TypeUnpacked,
-				Status:  metav1.ConditionFalse,
-				Reason:  rukpakv1alpha1.ReasonUnpackFailed,
-				Message: err.Error(),
-			}),
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, reason, logStr)),
 		)
-		return err
+		_ = r.Delete(ctx, pod)
+		return ctrl.Result{}, fmt.Errorf("unpack failed: %v", logStr)
 	}
-	logStr := string(logs)
-	u.UpdateStatus(
-		updater.EnsureCondition(metav1.Condition{
-			Type:    rukpakv1alpha1.TypeUnpacked,
-			Status:  metav1.ConditionFalse,
-			Reason:  rukpakv1alpha1.ReasonUnpackFailed,
-			Message: logStr,
-		}),
-	)
-	_ = r.Delete(ctx, pod)
-	return fmt.Errorf("unpack failed: %v", logStr)
+
+	retries := bundle.Status.UnpackRetries
+	exhausted := ""
+	switch {
+	case policy.MaxRetries != nil && retries >= *policy.MaxRetries:
+		exhausted = fmt.Sprintf("unpack failed after %d retries", retries)
+	case policy.Deadline != nil && time.Since(bundle.CreationTimestamp.Time) > policy.Deadline.Duration:
+		exhausted = fmt.Sprintf("unpack failed after the deadline of %s", policy.Deadline.Duration)
+	}
+	if exhausted != "" {
+		u.UpdateStatus(
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.UnpackRetriesExhausted, fmt.Sprintf("%s: %s", exhausted, logStr))),
+		)
+		return ctrl.Result{}, nil
+	}
+
+	if delay := unpackBackoff(*policy, retries) - time.Since(podFinishedAt(pod)); delay > 0 {
+		u.UpdateStatus(
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, reason, fmt.Sprintf("retry %d of the unpack is in %s: %s", retries+1, delay.Round(time.Second), logStr))),
+		)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+	if err := r.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("delete failed unpack pod: %w", err)
+	}
+	u.UpdateStatus(updater.EnsureUnpackRetries(retries + 1))
+	return ctrl.Result{}, nil
+}
+
+// maxUnpackBackoff is the longest delay between the retries of failed unpacks,
+// unless the backoff of the retry policy is longer itself.
+const maxUnpackBackoff = 5 * time.Minute
+
+// unpackBackoff returns the delay before the next retry of a failed unpack
+// that has been retried the given number of times.
+func unpackBackoff(policy rukpakv1alpha1.UnpackRetryPolicy, retries int32) time.Duration {
+	backoff := 10 * time.Second
+	if policy.Backoff != nil {
+		backoff = policy.Backoff.Duration
+	}
+	limit := maxUnpackBackoff
+	if backoff > limit {
+		limit = backoff
+	}
+	delay := backoff
+	for i := int32(0); i < retries && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
 }
 
-func (r *BundleReconciler) ensureUnpackPod(ctx context.Context, bundle *rukpakv1alpha1.Bundle, pod *corev1.Pod) (controllerutil.OperationResult, error) {
+// podDeadlineExceeded is the reason of the status of a pod that was failed
+// because it ran for longer than its active deadline.
+const podDeadlineExceeded = "DeadlineExceeded"
+
+// unpackDeadlineSeconds returns the active deadline of the pod that unpacks
+// source, which is nil when source sets no timeout.
+func unpackDeadlineSeconds(source rukpakv1alpha1.BundleSource) *int64 {
+	if source.Timeout == nil {
+		return nil
+	}
+	seconds := int64(math.Ceil(source.Timeout.Duration.Seconds()))
+	return &seconds
+}
+
+// podFinishedAt returns when the last of the containers of a failed pod
+// terminated.
+func podFinishedAt(pod *corev1.Pod) time.Time {
+	var finishedAt time.Time
+	for _, cStatus := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if t := cStatus.State.Terminated; t != nil && t.FinishedAt.Time.After(finishedAt) {
+			finishedAt = t.FinishedAt.Time
+		}
+	}
+	if finishedAt.IsZero() && pod.Status.StartTime != nil {
+		finishedAt = pod.Status.StartTime.Time
+	}
+	return finishedAt
+}
+
+func (r *BundleReconciler) ensureUnpackPod(ctx context.Context, bundle *rukpakv1alpha1.Bundle, pod *corev1.Pod) (_ controllerutil.OperationResult, err error) {
+	ctx, span := tracing.Start(ctx, "Bundle.EnsureUnpackPod")
+	defer func() { tracing.End(span, err) }()
+	config, err := util.GetRukpakConfig(ctx, r.Client)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	if config.DigestOnly && imageTag(bundle.Spec.Source) && bundle.Status.Phase != rukpakv1alpha1.PhaseUnpacked {
+		return controllerutil.OperationResultNone, fmt.Errorf("%w: image %q refers to a tag", errDigestRequired, bundle.Spec.Source.Image.Ref)
+	}
 	controllerRef := metav1.NewControllerRef(bundle, bundle.GroupVersionKind())
 	automountServiceAccountToken := false
 	pod.SetName(util.PodName(plainBundleProvisionerName, bundle.Name))
@@ -205,80 +490,519 @@ func (r *BundleReconciler) ensureUnpackPod(ctx context.Context, bundle *rukpakv1
 			"core.rukpak.io/owner-name": bundle.Name,
 		})
 		pod.SetOwnerReferences([]metav1.OwnerReference{*controllerRef})
+		caBundle := util.CABundleFor(config, bundle.Spec.Source)
+		source, err := r.pinSource(ctx, bundle.Spec.Source, caBundle, pod)
+		if err != nil {
+			return err
+		}
 		pod.Spec.AutomountServiceAccountToken = &automountServiceAccountToken
 		pod.Spec.Volumes = []corev1.Volume{
 			{Name: "util", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
 			{Name: "manifests", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
 		}
 		pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+		pod.Spec.ActiveDeadlineSeconds = unpackDeadlineSeconds(bundle.Spec.Source)
 
-		switch bundle.Spec.Source.Type {
+		switch source.Type {
 		case rukpakv1alpha1.SourceTypeImage:
-			pod = bundleImagePod(pod, *bundle.Spec.Source.Image, r.UnpackImage)
-			return nil
+			pod = bundleImagePod(pod, *source.Image, r.UnpackImage)
 		case rukpakv1alpha1.SourceTypeGit:
-			var err error
-			pod, err = bundleGitRepoPod(pod, *bundle.Spec.Source.Git, r.UnpackImage, r.GitClientImage)
+			pod, err = bundleGitRepoPod(pod, *source.Git, caBundle, r.ProxyEnv, r.UnpackImage, r.GitClientImage)
 			if err != nil {
 				return err
 			}
-			return nil
 		default:
-			return fmt.Errorf("unsupported bundle source type %s", bundle.Spec.Source.Type)
+			return fmt.Errorf("unsupported bundle source type %s", source.Type)
 		}
+
+		// Only new pods get the configured overrides, so that a change of the
+		// configuration does not recreate the unpack pods of every Bundle.
+		if pod.CreationTimestamp.IsZero() {
+			setUnpackPodOverrides(pod, unpackPodOverrides(config, bundle.Spec.Source.UnpackPodOverrides))
+		}
+		return nil
 	})
 }
 
-func updateStatusUnpackPending(u *updater.Updater) {
+// pinSource returns source with an image tag or polled git branch replaced by
+// the image digest or git commit that it resolves to, and records that
+// revision in the annotations of pod.
+//
+// Polled sources are resolved whenever the pod is reconciled. When they cannot
+// be resolved, the revision that pod already unpacks is kept, so that an
+// unreachable registry or repository does not fail a Bundle that is already
+// unpacked. Other image tags are only resolved when the pod is created, and
+// are pulled by their tag as before when they cannot be resolved, for example
+// when the image is only available to the nodes. The registry or repository
+// is verified with the certificates of caBundle when it is set.
+func (r *BundleReconciler) pinSource(ctx context.Context, source rukpakv1alpha1.BundleSource, caBundle *rukpakv1alpha1.CABundleReference, pod *corev1.Pod) (rukpakv1alpha1.BundleSource, error) {
+	pinned := pod.Annotations[resolvedRevisionAnnotation]
+	revision := pinned
+	switch {
+	case pollInterval(source) > 0:
+		resolved, err := r.resolveRevision(ctx, source, caBundle)
+		if err != nil {
+			if pinned == "" {
+				return source, fmt.Errorf("resolve bundle source: %w", err)
+			}
+			log.FromContext(ctx).Error(err, "failed to resolve bundle source, keeping the unpacked revision", "revision", pinned)
+		} else {
+			revision = resolved
+		}
+	case imageTag(source) && pod.CreationTimestamp.IsZero():
+		resolved, err := r.resolveRevision(ctx, source, caBundle)
+		if err != nil {
+			log.FromContext(ctx).V(util.LogLevelDebug).Info("unable to resolve image tag, pulling it by tag", "error", err.Error())
+		}
+		revision = resolved
+	}
+	if revision == "" {
+		return source, nil
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[resolvedRevisionAnnotation] = revision
+	return pinnedSource(source, revision), nil
+}
+
+// pinnedSource returns source with its image tag or git ref replaced by
+// revision, an image digest or git commit.
+func pinnedSource(source rukpakv1alpha1.BundleSource, revision string) rukpakv1alpha1.BundleSource {
+	source = *source.DeepCopy()
+	source.PollInterval = nil
+	switch source.Type {
+	case rukpakv1alpha1.SourceTypeImage:
+		name := source.Image.Ref
+		if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+			name = name[:i]
+		}
+		source.Image.Ref = name + "@" + revision
+	case rukpakv1alpha1.SourceTypeGit:
+		source.Git.Ref = rukpakv1alpha1.GitRef{Commit: revision}
+	}
+	return source
+}
+
+// resolveRevision returns the image digest or git commit that source currently
+// resolves to, with the credentials of its pull or auth Secret.
+func (r *BundleReconciler) resolveRevision(ctx context.Context, source rukpakv1alpha1.BundleSource, caBundle *rukpakv1alpha1.CABundleReference) (string, error) {
+	httpClient, err := r.httpClientFor(ctx, caBundle)
+	if err != nil {
+		return "", err
+	}
+	switch source.Type {
+	case rukpakv1alpha1.SourceTypeImage:
+		rc, err := r.registryClient(ctx, *source.Image, httpClient)
+		if err != nil {
+			return "", err
+		}
+		return rc.ResolveDigest(ctx, registry.ParseReference(source.Image.Ref))
+	case rukpakv1alpha1.SourceTypeGit:
+		var username, password string
+		if source.Git.Auth != nil {
+			secret, err := r.getSecret(ctx, source.Git.Auth.SecretName)
+			if err != nil {
+				return "", err
+			}
+			username, password = string(secret.Data[corev1.BasicAuthUsernameKey]), string(secret.Data[corev1.BasicAuthPasswordKey])
+		}
+		return git.ResolveRef(ctx, httpClient, *source.Git, username, password)
+	default:
+		return "", fmt.Errorf("unsupported bundle source type %s", source.Type)
+	}
+}
+
+// registryClient returns a client of the repository of source, with the
+// credentials of its pull Secret.
+func (r *BundleReconciler) registryClient(ctx context.Context, source rukpakv1alpha1.ImageSource, httpClient *http.Client) (*registry.Client, error) {
+	rc, err := registry.NewClient(httpClient, source.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if source.PullSecret != "" {
+		secret, err := r.getSecret(ctx, source.PullSecret)
+		if err != nil {
+			return nil, err
+		}
+		rc.Username, rc.Password, err = registry.DockerConfigCredentials(secret.Data[corev1.DockerConfigJsonKey], rc.Registry())
+		if err != nil {
+			return nil, fmt.Errorf("image pull secret %q: %w", source.PullSecret, err)
+		}
+	}
+	return rc, nil
+}
+
+// httpClientFor returns HTTPClient, trusting the certificates of caBundle
+// when it is set.
+func (r *BundleReconciler) httpClientFor(ctx context.Context, caBundle *rukpakv1alpha1.CABundleReference) (*http.Client, error) {
+	if caBundle == nil {
+		return r.HTTPClient, nil
+	}
+	data, err := util.LoadCABundle(ctx, r.APIReader, r.PodNamespace, *caBundle)
+	if err != nil {
+		return nil, err
+	}
+	return util.HTTPClientWithCABundle(r.HTTPClient, data)
+}
+
+// getSecret gets a Secret of the provisioner namespace with the uncached
+// KubeClient, as it is not labeled for the cache of the manager.
+func (r *BundleReconciler) getSecret(ctx context.Context, name string) (*corev1.Secret, error) {
+	secret, err := r.KubeClient.CoreV1().Secrets(r.PodNamespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get secret %q: %w", name, err)
+	}
+	return secret, nil
+}
+
+// pollInterval returns the interval at which source is resolved again, which
+// is zero unless it is a mutable image tag, git branch or chart version with a
+// PollInterval.
+func pollInterval(source rukpakv1alpha1.BundleSource) time.Duration {
+	if source.PollInterval == nil {
+		return 0
+	}
+	localPath := source.Type == rukpakv1alpha1.SourceTypeLocal && source.Local != nil && source.Local.Path != ""
+	if !imageTag(source) && (source.Type != rukpakv1alpha1.SourceTypeGit || source.Git == nil || source.Git.Ref.Branch == "") && source.Type != rukpakv1alpha1.SourceTypeHelm && !localPath {
+		return 0
+	}
+	return source.PollInterval.Duration
+}
+
+// imageTag reports whether source is an image that is referred to by a tag
+// rather than a digest.
+func imageTag(source rukpakv1alpha1.BundleSource) bool {
+	return source.Type == rukpakv1alpha1.SourceTypeImage && source.Image != nil && !strings.Contains(source.Image.Ref, "@")
+}
+
+// sourcePaths returns the paths that select the manifests of source, or nil
+// when every file of its manifests directory is a manifest.
+func sourcePaths(source rukpakv1alpha1.BundleSource) *rukpakv1alpha1.BundlePaths {
+	switch {
+	case source.Type == rukpakv1alpha1.SourceTypeImage && source.Image != nil:
+		return source.Image.Paths
+	case source.Type == rukpakv1alpha1.SourceTypeGit && source.Git != nil:
+		return source.Git.Paths
+	}
+	return nil
+}
+
+// reunpacking reports whether bundle is unpacked again after its polled source
+// changed, in which case it keeps reporting its unpacked content until the new
+// content is unpacked, so that its BundleInstances keep their installed state.
+func reunpacking(bundle *rukpakv1alpha1.Bundle) bool {
+	return bundle.Status.Phase == rukpakv1alpha1.PhaseUnpacked && pollInterval(bundle.Spec.Source) > 0
+}
+
+// unpackPodOverrides returns the overrides of source, with the defaults of
+// config for the fields that source does not override.
+func unpackPodOverrides(config rukpakv1alpha1.RukpakConfigSpec, source *rukpakv1alpha1.UnpackPodOverrides) rukpakv1alpha1.UnpackPodOverrides {
+	overrides := rukpakv1alpha1.UnpackPodOverrides{
+		Resources:       config.UnpackResources,
+		NodeSelector:    config.UnpackNodeSelector,
+		Tolerations:     config.UnpackTolerations,
+		SecurityContext: config.UnpackSecurityContext,
+	}
+	if source == nil {
+		return overrides
+	}
+	if source.Resources != nil {
+		overrides.Resources = source.Resources
+	}
+	if source.NodeSelector != nil {
+		overrides.NodeSelector = source.NodeSelector
+	}
+	if source.Tolerations != nil {
+		overrides.Tolerations = source.Tolerations
+	}
+	if source.SecurityContext != nil {
+		overrides.SecurityContext = source.SecurityContext
+	}
+	return overrides
+}
+
+func setUnpackPodOverrides(pod *corev1.Pod, overrides rukpakv1alpha1.UnpackPodOverrides) {
+	if overrides.Resources != nil {
+		setResources(pod, *overrides.Resources)
+	}
+	if overrides.NodeSelector != nil {
+		pod.Spec.NodeSelector = util.MergeMaps(overrides.NodeSelector)
+	}
+	if overrides.Tolerations != nil {
+		pod.Spec.Tolerations = append([]corev1.Toleration{}, overrides.Tolerations...)
+	}
+	if overrides.SecurityContext != nil {
+		pod.Spec.SecurityContext = overrides.SecurityContext.DeepCopy()
+	}
+}
+
+func setResources(pod *corev1.Pod, resources corev1.ResourceRequirements) {
+	for i := range pod.Spec.InitContainers {
+		pod.Spec.InitContainers[i].Resources = *resources.DeepCopy()
+	}
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].Resources = *resources.DeepCopy()
+	}
+}
+
+func updateStatusUnpackPending(u *updater.Updater, bundle *rukpakv1alpha1.Bundle) {
 	u.UpdateStatus(
 		updater.SetBundleInfo(nil),
 		updater.EnsureBundleDigest(""),
+		updater.SetResolvedSource(nil),
 		updater.SetPhase(rukpakv1alpha1.PhasePending),
-		updater.EnsureCondition(metav1.Condition{
-			Type:   rukpakv1alpha1.TypeUnpacked,
-			Status: metav1.ConditionFalse,
-			Reason: rukpakv1alpha1.ReasonUnpackPending,
-		}),
+		updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.UnpackPending, "")),
 	)
 }
 
-func updateStatusUnpackFailing(u *updater.Updater, err error) error {
+// unpackState is the state of the unpack of a Bundle whose changes are
+// recorded as events.
+type unpackState struct {
+	reason string
+	digest string
+}
+
+func unpackedState(bundle *rukpakv1alpha1.Bundle) unpackState {
+	state := unpackState{digest: bundle.Status.Digest}
+	if cond := meta.FindStatusCondition(bundle.Status.Conditions, rukpakv1alpha1.TypeUnpacked); cond != nil {
+		state.reason = cond.Reason
+	}
+	return state
+}
+
+// recordUnpackTransition records an event when the reason of the Unpacked
+// condition of bundle differs from that of previous, or when bundle is
+// unpacked to another digest, so that the unpack history of a Bundle is
+// shown along with its latest condition.
+func (r *BundleReconciler) recordUnpackTransition(bundle *rukpakv1alpha1.Bundle, previous unpackState) {
+	cond := meta.FindStatusCondition(bundle.Status.Conditions, rukpakv1alpha1.TypeUnpacked)
+	if r.Recorder == nil || cond == nil {
+		return
+	}
+	current := unpackedState(bundle)
+	if current == previous || (current.reason == previous.reason && cond.Status != metav1.ConditionTrue) {
+		return
+	}
+	eventType, msg := corev1.EventTypeNormal, cond.Message
+	switch {
+	case bundle.Status.Phase == rukpakv1alpha1.PhaseFailing:
+		eventType = corev1.EventTypeWarning
+	case cond.Status == metav1.ConditionTrue:
+		msg = fmt.Sprintf("unpacked content with digest %s", bundle.Status.Digest)
+	}
+	if msg == "" {
+		msg = fmt.Sprintf("bundle phase is %s", bundle.Status.Phase)
+	}
+	r.Recorder.Event(bundle, eventType, cond.Reason, msg)
+}
+
+func updateStatusUnpackFailing(u *updater.Updater, bundle *rukpakv1alpha1.Bundle, err error) error {
 	u.UpdateStatus(
 		updater.SetPhase(rukpakv1alpha1.PhaseFailing),
-		updater.EnsureCondition(metav1.Condition{
-			Type:    rukpakv1alpha1.TypeUnpacked,
-			Status:  metav1.ConditionFalse,
-			Reason:  rukpakv1alpha1.ReasonUnpackFailed,
-			Message: err.Error(),
-		}),
+		updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.UnpackFailed, err.Error())),
 	)
 	return err
 }
 
-func (r *BundleReconciler) handleCompletedPod(ctx context.Context, u *updater.Updater, bundle *rukpakv1alpha1.Bundle, pod *corev1.Pod) error {
+func (r *BundleReconciler) handleCompletedPod(ctx context.Context, u *updater.Updater, bundle *rukpakv1alpha1.Bundle, pod *corev1.Pod) (err error) {
+	ctx, span := tracing.Start(ctx, "Bundle.HandleCompletedPod")
+	defer func() { tracing.End(span, err) }()
 	bundleFS, err := r.getBundleContents(ctx, pod)
 	if err != nil {
-		return updateStatusUnpackFailing(u, fmt.Errorf("get bundle contents: %w", err))
+		return updateStatusUnpackFailing(u, bundle, fmt.Errorf("get bundle contents: %w", err))
 	}
 
 	// TODO: generalize for other content sources
 	// See https://github.com/operator-framework/rukpak/issues/164
 	bundleImageDigest, err := r.getBundleImageDigest(pod)
 	if err != nil {
-		return updateStatusUnpackFailing(u, fmt.Errorf("get bundle image digest: %w", err))
+		return updateStatusUnpackFailing(u, bundle, fmt.Errorf("get bundle image digest: %w", err))
+	}
+	// The image of the unpack container of a git source does not change with
+	// its content, so the digest of a polled branch is the commit it unpacked.
+	revision := pod.Annotations[resolvedRevisionAnnotation]
+	if revision != "" && bundle.Spec.Source.Type == rukpakv1alpha1.SourceTypeGit {
+		bundleImageDigest = revision
 	}
+	if err := r.storeContents(ctx, u, bundle, bundleFS, bundleImageDigest); err != nil {
+		return err
+	}
+	u.UpdateStatus(updater.SetResolvedSource(resolvedSource(bundle.Spec.Source, revision, bundleImageDigest)))
+	return nil
+}
 
-	objects, err := getObjects(bundleFS)
+// unpack unpacks the source of bundle with the Unpacker of its type, without
+// an unpack pod, and stores its content.
+func (r *BundleReconciler) unpack(ctx context.Context, u *updater.Updater, bundle *rukpakv1alpha1.Bundle) error {
+	result, err := r.Unpackers.Unpack(ctx, bundle)
+	if errors.Is(err, source.ErrUnresolvedChartDependencies) {
+		u.UpdateStatus(
+			updater.SetPhase(rukpakv1alpha1.PhaseFailing),
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.UnresolvedChartDependencies, err.Error())),
+		)
+		return err
+	}
+	if err != nil {
+		return updateStatusUnpackFailing(u, bundle, err)
+	}
+	switch result.State {
+	case source.StateUnchanged:
+		return nil
+	case source.StatePending:
+		u.UpdateStatus(
+			updater.SetBundleInfo(nil),
+			updater.EnsureBundleDigest(""),
+			updater.SetPhase(rukpakv1alpha1.PhasePending),
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, result.Reason, result.Message)),
+		)
+		return nil
+	case source.StateUnpacked:
+	default:
+		return updateStatusUnpackFailing(u, bundle, fmt.Errorf("unexpected unpack state %q", result.State))
+	}
+	if result.Bundle != nil {
+		err = r.storeContents(ctx, u, bundle, result.Bundle, result.Digest)
+	} else {
+		err = r.storeObjects(ctx, u, bundle, result.Objects, result.Digest)
+	}
 	if err != nil {
-		return updateStatusUnpackFailing(u, fmt.Errorf("get objects from bundle manifests: %w", err))
+		return err
 	}
+	if result.ResolvedSource != nil {
+		u.UpdateStatus(updater.SetResolvedSource(result.ResolvedSource))
+	}
+	return nil
+}
+
+// resolvedSource returns source pinned to the revision that its unpack pod
+// was pinned to, or to the digest of the unpacked image ID when the pod pulled
+// an image by its tag. It returns nil when the unpacked revision is not known.
+func resolvedSource(source rukpakv1alpha1.BundleSource, revision, imageID string) *rukpakv1alpha1.BundleSource {
+	if revision == "" && imageTag(source) {
+		if i := strings.LastIndex(imageID, "@"); i >= 0 && strings.HasPrefix(imageID[i+1:], "sha256:") {
+			revision = imageID[i+1:]
+		}
+	}
+	switch {
+	case revision != "":
+		pinned := pinnedSource(source, revision)
+		return &pinned
+	case source.Type == rukpakv1alpha1.SourceTypeImage && source.Image != nil && !imageTag(source),
+		source.Type == rukpakv1alpha1.SourceTypeGit && source.Git != nil && source.Git.Ref.Commit != "":
+		return source.DeepCopy()
+	}
+	return nil
+}
+
+// storeContents verifies and stores the unpacked contents of bundle in
+// bundleFS, whose digest is bundleDigest, and reports the bundle as unpacked.
+func (r *BundleReconciler) storeContents(ctx context.Context, u *updater.Updater, bundle *rukpakv1alpha1.Bundle, bundleFS fs.FS, bundleDigest string) error {
+	// Content of another format would otherwise fail with an error about a
+	// missing or malformed manifests directory.
+	if err := manifests.CheckFormat(bundleFS); err != nil {
+		u.UpdateStatus(
+			updater.SetPhase(rukpakv1alpha1.PhaseFailing),
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.UnsupportedFormat, err.Error())),
+		)
+		return err
+	}
+	if err := checksums.Verify(bundleFS, manifests.Dir); err != nil {
+		err = fmt.Errorf("verify bundle manifests: %w", err)
+		u.UpdateStatus(
+			updater.SetPhase(rukpakv1alpha1.PhaseFailing),
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.ManifestVerificationFailed, err.Error())),
+		)
+		return err
+	}
+
+	if source := bundle.Spec.Source; source.Type == rukpakv1alpha1.SourceTypeImage && source.Image.Verification != nil {
+		if err := r.verifyImageSignature(ctx, source, bundleDigest); err != nil {
+			err = fmt.Errorf("verify bundle image signature: %w", err)
+			u.UpdateStatus(
+				updater.SetPhase(rukpakv1alpha1.PhaseFailing),
+				updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.SignatureVerificationFailed, err.Error())),
+			)
+			return err
+		}
+	}
+
+	// The manifests are verified against their checksums before they are
+	// filtered, since the checksums file lists the files of the source.
+	if paths := sourcePaths(bundle.Spec.Source); paths != nil {
+		bundleFS = manifests.Filter(bundleFS, paths.Include, paths.Exclude)
+	}
+	objects, err := validation.Validate(ctx, bundleFS, r.Validators...)
+	var validationErrs validation.Errors
+	if errors.As(err, &validationErrs) {
+		message := validationMessage(validationErrs)
+		u.UpdateStatus(
+			updater.SetPhase(rukpakv1alpha1.PhaseFailing),
+			updater.SetValidationErrors(bundleValidationErrors(validationErrs)),
+			updater.EnsureCondition(status.For(bundle).InvalidBundleContent(metav1.ConditionTrue, status.ValidationFailed, message)),
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.InvalidBundleContent, message)),
+		)
+		return err
+	}
+	if err != nil {
+		return updateStatusUnpackFailing(u, bundle, fmt.Errorf("get objects from bundle manifests: %w", err))
+	}
+	return r.storeObjects(ctx, u, bundle, objects, bundleDigest)
+}
+
+// maxValidationErrors is the maximum number of the validation errors of a
+// Bundle that are listed in its status.
+const maxValidationErrors = 100
+
+// bundleValidationErrors returns the first maxValidationErrors of errs, as
+// they are listed in the status of a Bundle.
+func bundleValidationErrors(errs validation.Errors) []rukpakv1alpha1.BundleValidationError {
+	if len(errs) > maxValidationErrors {
+		errs = errs[:maxValidationErrors]
+	}
+	statusErrs := make([]rukpakv1alpha1.BundleValidationError, 0, len(errs))
+	for _, err := range errs {
+		statusErrs = append(statusErrs, rukpakv1alpha1.BundleValidationError{File: err.File, Object: err.Object, Message: err.Message})
+	}
+	return statusErrs
+}
+
+// validationMessage returns the message of the conditions of a Bundle whose
+// manifests have the validation errors errs, which names the first of them
+// rather than all of them, as they are listed in the status of the Bundle.
+func validationMessage(errs validation.Errors) string {
+	if len(errs) == 1 {
+		return fmt.Sprintf("invalid bundle content: %v", errs[0])
+	}
+	return fmt.Sprintf("invalid bundle content: %v, and %d more listed in status.validationErrors", errs[0], len(errs)-1)
+}
+
+// storeObjects stores the objects of bundle, whose digest is bundleDigest, and
+// reports the bundle as unpacked.
+func (r *BundleReconciler) storeObjects(ctx context.Context, u *updater.Updater, bundle *rukpakv1alpha1.Bundle, objects []client.Object, bundleDigest string) (err error) {
+	ctx, span := tracing.Start(ctx, "Bundle.StoreObjects")
+	defer func() { tracing.End(span, err) }()
 	if len(objects) == 0 {
-		return updateStatusUnpackFailing(u, errors.New("invalid bundle: found zero objects: "+
-			"plain+v0 bundles are required to contain at least one object"))
+		return updateStatusUnpackFailing(u, bundle, manifests.ErrNoObjects)
+	}
+	if err := r.Limits.Check(objects); err != nil {
+		var limitErr *manifests.LimitError
+		if !errors.As(err, &limitErr) {
+			return updateStatusUnpackFailing(u, bundle, err)
+		}
+		u.UpdateStatus(
+			updater.SetPhase(rukpakv1alpha1.PhaseFailing),
+			updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionFalse, status.BundleTooLarge, err.Error())),
+		)
+		return err
 	}
 
-	if err := r.Storage.Store(ctx, bundle, objects); err != nil {
-		return updateStatusUnpackFailing(u, fmt.Errorf("persist bundle objects: %w", err))
+	// The objects are stored for the digest that they were unpacked from,
+	// which the status of the bundle reports once they are stored.
+	owner := bundle.DeepCopy()
+	owner.Status.Digest = bundleDigest
+	if err := r.Storage.Store(ctx, owner, objects); err != nil {
+		return updateStatusUnpackFailing(u, bundle, fmt.Errorf("persist bundle objects: %w", err))
 	}
 
 	info := &rukpakv1alpha1.BundleInfo{}
@@ -293,15 +1017,15 @@ func (r *BundleReconciler) handleCompletedPod(ctx context.Context, u *updater.Up
 		})
 	}
 
+	log.FromContext(ctx).V(util.LogLevelDebug).Info("unpacked bundle", util.LogKeyDigest, bundleDigest, "objects", len(objects))
 	u.UpdateStatus(
 		updater.SetBundleInfo(info),
-		updater.EnsureBundleDigest(bundleImageDigest),
+		updater.EnsureBundleDigest(bundleDigest),
+		updater.EnsureUnpackRetries(0),
+		updater.SetValidationErrors(nil),
+		updater.RemoveCondition(rukpakv1alpha1.TypeInvalidBundleContent),
 		updater.SetPhase(rukpakv1alpha1.PhaseUnpacked),
-		updater.EnsureCondition(metav1.Condition{
-			Type:   rukpakv1alpha1.TypeUnpacked,
-			Status: metav1.ConditionTrue,
-			Reason: rukpakv1alpha1.ReasonUnpackSuccessful,
-		}),
+		updater.EnsureCondition(status.For(bundle).Unpacked(metav1.ConditionTrue, status.UnpackSuccessful, "")),
 	)
 
 	return nil
@@ -349,37 +1073,35 @@ func (r *BundleReconciler) getBundleImageDigest(pod *corev1.Pod) (string, error)
 	return "", fmt.Errorf("bundle image digest not found")
 }
 
-func getObjects(bundleFS fs.FS) ([]client.Object, error) {
-	var objects []client.Object
-	const manifestsDir = "manifests"
-
-	entries, err := fs.ReadDir(bundleFS, manifestsDir)
+// verifyImageSignature verifies that the unpacked bundle image, identified by
+// the image ID reported in the status of the unpack pod, is signed by one of
+// the signers trusted by the image source.
+func (r *BundleReconciler) verifyImageSignature(ctx context.Context, source rukpakv1alpha1.BundleSource, imageID string) error {
+	if r.Verifier == nil {
+		return errors.New("signature verification is not configured")
+	}
+	config, err := util.GetRukpakConfig(ctx, r.Client)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		fileData, err := fs.ReadFile(bundleFS, filepath.Join(manifestsDir, e.Name()))
+	verifier := r.Verifier
+	if caBundle := util.CABundleFor(config, source); caBundle != nil {
+		httpClient, err := r.httpClientFor(ctx, caBundle)
 		if err != nil {
-			return nil, err
-		}
-
-		dec := apimachyaml.NewYAMLOrJSONDecoder(bytes.NewReader(fileData), 1024)
-		for {
-			obj := unstructured.Unstructured{}
-			err := dec.Decode(&obj)
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			if err != nil {
-				return nil, fmt.Errorf("read %q: %w", e.Name(), err)
-			}
-			objects = append(objects, &obj)
+			return err
 		}
+		copied := *r.Verifier
+		copied.HTTPClient = httpClient
+		verifier = &copied
+	}
+	digest := imageID
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		digest = imageID[i+1:]
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("unable to determine the digest of image %q", imageID)
 	}
-	return objects, nil
+	return verifier.Verify(ctx, source.Image.Ref, digest, *source.Image.Verification)
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -387,10 +1109,15 @@ func (r *BundleReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&rukpakv1alpha1.Bundle{}, builder.WithPredicates(
 			util.BundleProvisionerFilter(plainBundleProvisionerID),
+			util.SelectorFilter(r.Selector),
 		)).
 		Owns(&corev1.Secret{}).
 		Owns(&corev1.Pod{}).
 		Owns(&corev1.ConfigMap{}).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter:             r.RateLimit.RateLimiter(),
+		}).
 		Complete(r)
 }
 
@@ -410,10 +1137,15 @@ func bundleImagePod(pod *corev1.Pod, source rukpakv1alpha1.ImageSource, unpackIm
 	pod.Spec.Containers[0].Command = []string{"/bin/unpack", "--bundle-dir", "/"}
 	pod.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "util", MountPath: "/bin"}}
 
+	pod.Spec.ImagePullSecrets = nil
+	if source.PullSecret != "" {
+		pod.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: source.PullSecret}}
+	}
+
 	return pod
 }
 
-func bundleGitRepoPod(pod *corev1.Pod, source rukpakv1alpha1.GitSource, unpackImage, gitClientImage string) (*corev1.Pod, error) {
+func bundleGitRepoPod(pod *corev1.Pod, source rukpakv1alpha1.GitSource, caBundle *rukpakv1alpha1.CABundleReference, proxyEnv []corev1.EnvVar, unpackImage, gitClientImage string) (*corev1.Pod, error) {
 	if len(pod.Spec.InitContainers) != 2 {
 		pod.Spec.InitContainers = make([]corev1.Container, 2)
 	}
@@ -434,6 +1166,16 @@ func bundleGitRepoPod(pod *corev1.Pod, source rukpakv1alpha1.GitSource, unpackIm
 	}
 	pod.Spec.InitContainers[1].Command = []string{"/bin/sh", "-c", cmd}
 	pod.Spec.InitContainers[1].VolumeMounts = []corev1.VolumeMount{{Name: "manifests", MountPath: "/manifests"}}
+	pod.Spec.InitContainers[1].Env = append([]corev1.EnvVar(nil), proxyEnv...)
+	if source.Auth != nil {
+		pod.Spec.InitContainers[1].Env = append(pod.Spec.InitContainers[1].Env,
+			secretKeyEnv(git.UsernameEnv, source.Auth.SecretName, "username"),
+			secretKeyEnv(git.PasswordEnv, source.Auth.SecretName, "password"),
+		)
+	}
+	if caBundle != nil {
+		addCABundleVolume(pod, &pod.Spec.InitContainers[1], *caBundle)
+	}
 
 	if len(pod.Spec.Containers) != 1 {
 		pod.Spec.Containers = make([]corev1.Container, 1)
@@ -444,11 +1186,41 @@ func bundleGitRepoPod(pod *corev1.Pod, source rukpakv1alpha1.GitSource, unpackIm
 	pod.Spec.Containers[0].Image = unpackImage
 	pod.Spec.Containers[0].ImagePullPolicy = corev1.PullIfNotPresent
 	pod.Spec.Containers[0].Command = []string{"/bin/unpack", "--bundle-dir", "/"}
+	if source.Kustomize {
+		pod.Spec.Containers[0].Command = []string{"/bin/unpack", "--kustomize", git.KustomizationDir(source)}
+	}
 	pod.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "util", MountPath: "/bin"}, {Name: "manifests", MountPath: "/manifests"}}
 
 	return pod, nil
 }
 
+// addCABundleVolume mounts the certificates of caBundle in container of pod,
+// and configures git to verify the repository with them.
+func addCABundleVolume(pod *corev1.Pod, container *corev1.Container, caBundle rukpakv1alpha1.CABundleReference) {
+	items := []corev1.KeyToPath{{Key: util.CABundleKey(caBundle), Path: caBundleFile}}
+	volume := corev1.Volume{Name: "ca-bundle"}
+	if caBundle.Secret != "" {
+		volume.Secret = &corev1.SecretVolumeSource{SecretName: caBundle.Secret, Items: items}
+	} else {
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: caBundle.ConfigMap}, Items: items}
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: volume.Name, MountPath: caBundleDir, ReadOnly: true})
+	container.Env = append(container.Env, corev1.EnvVar{Name: "GIT_SSL_CAINFO", Value: caBundleDir + "/" + caBundleFile})
+}
+
+func secretKeyEnv(name, secretName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
 // addUnpackerInitContainer injects the install-unpacker init container into the given pod.
 // addUnpackerInitContainer assumes the pod has an array of init containers initialized.
 func addUnpackerInitContainer(pod *corev1.Pod, unpackImage string) *corev1.Pod {
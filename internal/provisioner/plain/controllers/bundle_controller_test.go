@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/storage"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+var _ = Describe("BundleReconciler", func() {
+	const (
+		podNamespace = "rukpak-system"
+		imageRef     = "quay.io/operator-framework/olm-crds@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	)
+
+	var (
+		ctx    context.Context
+		bundle *rukpakv1alpha1.Bundle
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		bundle = &rukpakv1alpha1.Bundle{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha1.GroupVersion.String(), Kind: "Bundle"},
+			ObjectMeta: metav1.ObjectMeta{Name: "olm-crds", UID: "bundle-uid"},
+			Spec: rukpakv1alpha1.BundleSpec{
+				ProvisionerClassName: plainBundleProvisionerID,
+				Source: rukpakv1alpha1.BundleSource{
+					Type:  rukpakv1alpha1.SourceTypeImage,
+					Image: &rukpakv1alpha1.ImageSource{Ref: imageRef},
+				},
+			},
+		}
+	})
+
+	Describe("ensureUnpackPod", func() {
+		var (
+			config *rukpakv1alpha1.RukpakConfig
+			r      *BundleReconciler
+		)
+
+		BeforeEach(func() {
+			config = &rukpakv1alpha1.RukpakConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: rukpakv1alpha1.RukpakConfigName},
+				Spec: rukpakv1alpha1.RukpakConfigSpec{
+					UnpackResources: &corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+					},
+					UnpackNodeSelector: map[string]string{"node-role.kubernetes.io/infra": ""},
+				},
+			}
+		})
+
+		unpackPod := func() *corev1.Pod {
+			r = &BundleReconciler{Client: newFakeClient(config), PodNamespace: podNamespace, UnpackImage: "quay.io/operator-framework/rukpak:main"}
+			_, err := r.ensureUnpackPod(ctx, bundle, &corev1.Pod{})
+			Expect(err).NotTo(HaveOccurred())
+			pod := &corev1.Pod{}
+			Expect(r.Get(ctx, types.NamespacedName{Namespace: podNamespace, Name: util.PodName(plainBundleProvisionerName, bundle.Name)}, pod)).To(Succeed())
+			return pod
+		}
+
+		It("applies the defaults of the RukpakConfig", func() {
+			pod := unpackPod()
+			Expect(pod.Spec.NodeSelector).To(Equal(map[string]string{"node-role.kubernetes.io/infra": ""}))
+			for _, c := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+				Expect(c.Resources).To(Equal(*config.Spec.UnpackResources), "container %s", c.Name)
+			}
+			Expect(pod.Spec.Tolerations).To(BeEmpty())
+			Expect(pod.Spec.SecurityContext).To(BeNil())
+		})
+
+		It("applies the overrides of the Bundle over the defaults", func() {
+			resources := corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			}
+			tolerations := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "unpack", Effect: corev1.TaintEffectNoSchedule}}
+			runAsNonRoot, runAsUser := true, int64(1000)
+			securityContext := &corev1.PodSecurityContext{RunAsNonRoot: &runAsNonRoot, RunAsUser: &runAsUser}
+			bundle.Spec.Source.UnpackPodOverrides = &rukpakv1alpha1.UnpackPodOverrides{
+				Resources:       &resources,
+				NodeSelector:    map[string]string{"kubernetes.io/os": "linux"},
+				Tolerations:     tolerations,
+				SecurityContext: securityContext,
+			}
+
+			pod := unpackPod()
+			Expect(pod.Spec.NodeSelector).To(Equal(map[string]string{"kubernetes.io/os": "linux"}))
+			for _, c := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+				Expect(c.Resources).To(Equal(resources), "container %s", c.Name)
+			}
+			Expect(pod.Spec.Tolerations).To(Equal(tolerations))
+			Expect(pod.Spec.SecurityContext).To(Equal(securityContext))
+		})
+	})
+
+	Describe("finalizer", func() {
+		// The pull secret of the Bundle was deleted after it was created, so
+		// that its spec fails validation.
+		BeforeEach(func() {
+			bundle.Spec.Source.Image.PullSecret = "deleted"
+		})
+
+		newReconciler := func() *BundleReconciler {
+			return &BundleReconciler{
+				Client:       newFakeClient(bundle),
+				KubeClient:   kubefake.NewSimpleClientset(),
+				Storage:      &storage.Filesystem{Root: GinkgoT().TempDir()},
+				PodNamespace: podNamespace,
+				UnpackImage:  "quay.io/operator-framework/rukpak:main",
+			}
+		}
+
+		It("is added to a Bundle whose spec no longer validates", func() {
+			r := newReconciler()
+			_, _ = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bundle)})
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(bundle), bundle)).To(Succeed())
+			Expect(controllerutil.ContainsFinalizer(bundle, storedContentFinalizer)).To(BeTrue())
+		})
+
+		It("is removed from a deleted Bundle whose spec no longer validates", func() {
+			now := metav1.Now()
+			bundle.DeletionTimestamp = &now
+			bundle.Finalizers = []string{storedContentFinalizer}
+			r := newReconciler()
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bundle)})
+			Expect(err).NotTo(HaveOccurred())
+			if err := r.Get(ctx, client.ObjectKeyFromObject(bundle), bundle); err == nil {
+				Expect(bundle.Finalizers).NotTo(ContainElement(storedContentFinalizer))
+			} else {
+				Expect(client.IgnoreNotFound(err)).To(Succeed())
+			}
+		})
+	})
+})
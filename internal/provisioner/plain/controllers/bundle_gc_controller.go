@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+// bundleGCKey is the request that every Bundle and BundleInstance event is
+// mapped to. The BundleGCReconciler ignores its requests, since the Bundles to
+// delete depend on all Bundles and BundleInstances together.
+var bundleGCKey = reconcile.Request{NamespacedName: types.NamespacedName{Name: "bundle-gc"}}
+
+// BundleGCReconciler deletes the Bundles of the plain provisioner that no
+// BundleInstance references, as configured by the bundleGC policy of the
+// RukpakConfig.
+type BundleGCReconciler struct {
+	client.Client
+	// Selector selects the Bundles that are garbage collected, as the
+	// Selector of the BundleReconciler does. A nil Selector selects every
+	// Bundle.
+	Selector labels.Selector
+}
+
+func (r *BundleGCReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	l := log.FromContext(ctx).WithValues(util.LogKeyReconcileID, util.NewReconcileID())
+
+	config, err := util.GetRukpakConfig(ctx, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if config.BundleGC == nil {
+		return ctrl.Result{}, nil
+	}
+
+	bundleInstances := &rukpakv1alpha1.BundleInstanceList{}
+	if err := r.List(ctx, bundleInstances); err != nil {
+		return ctrl.Result{}, fmt.Errorf("list bundleinstances: %w", err)
+	}
+	referenced := util.ReferencedBundleNames(bundleInstances.Items)
+
+	bundles := &rukpakv1alpha1.BundleList{}
+	if err := r.List(ctx, bundles); err != nil {
+		return ctrl.Result{}, fmt.Errorf("list bundles: %w", err)
+	}
+	var unreferenced []rukpakv1alpha1.Bundle
+	for _, b := range bundles.Items {
+		if b.Spec.ProvisionerClassName != plainBundleProvisionerID || !b.DeletionTimestamp.IsZero() || referenced.Has(b.Name) || !util.MatchesSelector(r.Selector, &b) {
+			continue
+		}
+		unreferenced = append(unreferenced, b)
+	}
+
+	collectable, requeueAfter := util.CollectableBundles(unreferenced, *config.BundleGC, time.Now())
+	for i := range collectable {
+		b := &collectable[i]
+		// The UID precondition keeps a Bundle that was recreated since it was
+		// listed from being deleted.
+		if err := r.Delete(ctx, b, client.Preconditions{UID: &b.UID}); err != nil {
+			if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+				continue
+			}
+			return ctrl.Result{}, fmt.Errorf("delete bundle %q: %w", b.Name, err)
+		}
+		l.Info("deleted unreferenced bundle", "bundle", b.Name)
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BundleGCReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	enqueue := handler.EnqueueRequestsFromMapFunc(func(client.Object) []reconcile.Request {
+		return []reconcile.Request{bundleGCKey}
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("bundle-gc").
+		For(&rukpakv1alpha1.RukpakConfig{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetName() == rukpakv1alpha1.RukpakConfigName
+		}))).
+		Watches(&source.Kind{Type: &rukpakv1alpha1.Bundle{}}, enqueue, builder.WithPredicates(
+			util.BundleProvisionerFilter(plainBundleProvisionerID),
+		)).
+		Watches(&source.Kind{Type: &rukpakv1alpha1.BundleInstance{}}, enqueue).
+		Complete(r)
+}
@@ -17,259 +17,1959 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
-	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"sync"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 	"sigs.k8s.io/yaml"
 
 	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/content"
+	"github.com/operator-framework/rukpak/internal/digest"
+	"github.com/operator-framework/rukpak/internal/health"
 	helmpredicate "github.com/operator-framework/rukpak/internal/helm-operator-plugins/predicate"
+	"github.com/operator-framework/rukpak/internal/manifests"
+	"github.com/operator-framework/rukpak/internal/policy"
+	"github.com/operator-framework/rukpak/internal/preflight"
+	"github.com/operator-framework/rukpak/internal/report"
 	"github.com/operator-framework/rukpak/internal/storage"
+	"github.com/operator-framework/rukpak/internal/tracing"
 	"github.com/operator-framework/rukpak/internal/util"
+	"github.com/operator-framework/rukpak/internal/watchmanager"
+	"github.com/operator-framework/rukpak/pkg/status"
 )
 
+// ProvisionerClassName is the provisioner class of the Bundles and
+// BundleInstances that the plain provisioner reconciles.
+const ProvisionerClassName = plainBundleProvisionerID
+
 const (
 	plainBundleProvisionerID = "core.rukpak.io/plain"
+
+	aggregateToProvisionerLabel = "rbac.rukpak.io/aggregate-to-plain-provisioner"
+
+	// helmReleaseNameAnnotation and helmReleaseNamespaceAnnotation are the
+	// ownership metadata that Helm sets on the objects of a release.
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+
+	// chartHashAnnotation and bundleDigestAnnotation annotate the chart of a
+	// release with the hash of its templates and the digest of the bundle
+	// that it was rendered from, which the release records when it is
+	// installed or upgraded.
+	chartHashAnnotation    = "core.rukpak.io/chart-hash"
+	bundleDigestAnnotation = "core.rukpak.io/bundle-digest"
+
+	// reconcileChunkSize is the number of release objects that are reconciled
+	// between checks of the reconcile budget.
+	reconcileChunkSize = 25
+	// reconcileResumeDelay is the delay after which a reconciliation that
+	// exhausted its budget is resumed, giving other BundleInstances a chance
+	// to be reconciled in the meantime.
+	reconcileResumeDelay = time.Second
+
+	// deletionPolicyFinalizer holds the deletion of a BundleInstance until its
+	// deletion policy has been applied.
+	deletionPolicyFinalizer = "core.rukpak.io/deletion-policy"
+	// uninstallPollInterval is the interval at which the deletion of the
+	// installed objects of a BundleInstance that is being uninstalled is
+	// checked.
+	uninstallPollInterval = 2 * time.Second
+	// crdEstablishedPollInterval is the interval at which the CRDs that are
+	// applied ahead of the release of a BundleInstance are checked for being
+	// established.
+	crdEstablishedPollInterval = time.Second
+	// wavePollInterval is the interval at which the health of the objects of
+	// a wave that has been applied ahead of the release is checked.
+	wavePollInterval = 5 * time.Second
+	// statusHeartbeatInterval is how long the status of a BundleInstance
+	// goes unwritten at most when its reconciles change nothing but the
+	// times of its last reconcile and install.
+	statusHeartbeatInterval = 10 * time.Minute
 )
 
-// BundleInstanceReconciler reconciles a BundleInstance object
-type BundleInstanceReconciler struct {
-	client.Client
-	Scheme     *runtime.Scheme
-	Controller controller.Controller
+// BundleInstanceReconciler reconciles a BundleInstance object
+type BundleInstanceReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Controller controller.Controller
+
+	ActionClientGetter helmclient.ActionClientGetter
+	ActionConfigGetter helmclient.ActionConfigGetter
+	// ContentConfig is the config from which the clients that manage the
+	// content of BundleInstances are created, so that they can capture API
+	// warnings and impersonate ServiceAccounts. When it is nil, the clients of
+	// ActionClientGetter are used, and BundleInstances with a ServiceAccount
+	// fail to install.
+	ContentConfig    *rest.Config
+	BundleStorage    storage.Storage
+	ReleaseNamespace string
+	// AllowedReleaseNamespaces are the namespaces, other than
+	// ReleaseNamespace, that BundleInstances may select as their release
+	// namespace. An entry of "*" allows every namespace. BundleInstances that
+	// select any other namespace fail to install.
+	AllowedReleaseNamespaces []string
+	// DigestAlgorithm is the algorithm of the digests that name the chart
+	// templates of releases. It defaults to SHA256.
+	DigestAlgorithm digest.Algorithm
+
+	// Recorder records events for the installs, upgrades and rollbacks of the
+	// content of BundleInstances, the transitions of its health, and the
+	// warnings returned by the API server while it is managed. No events are
+	// recorded when it is nil.
+	Recorder record.EventRecorder
+
+	// ReportStorage persists a signed InstallReport for every successful
+	// install or upgrade. Install reports are disabled when ReportStorage is nil.
+	ReportStorage    storage.Storage
+	ReportSigningKey []byte
+	// Applier is the identity that is recorded as having applied the
+	// contents of a BundleInstance in its install reports.
+	Applier string
+
+	// ReadOnly configures the reconciler to compute and report the release
+	// state of BundleInstances without installing, upgrading or reconciling
+	// any of their objects.
+	ReadOnly bool
+
+	// ReconcileBudget is the maximum amount of time spent reconciling the
+	// installed objects of a single BundleInstance in one reconcile. When the
+	// budget is exhausted, progress is recorded in the status of the
+	// BundleInstance and reconciliation resumes in a subsequent reconcile.
+	// A zero ReconcileBudget disables the budget.
+	ReconcileBudget time.Duration
+
+	// UninstallTimeout is how long the deletion of the installed objects of a
+	// deleted BundleInstance is waited for before its uninstall is reported
+	// as failed. The uninstall is still retried afterwards, so that the
+	// release is not leaked. A zero UninstallTimeout never reports a timeout.
+	UninstallTimeout time.Duration
+
+	// ResyncInterval is the interval at which the installed content of
+	// BundleInstances is reconciled in the absence of any changes, unless the
+	// RukpakConfig or the BundleInstance configure another interval. A zero
+	// ResyncInterval only reconciles content when it changes.
+	ResyncInterval time.Duration
+
+	// HookTimeout is how long each hook of a plain bundle may run before
+	// the install or upgrade that runs it fails. A zero HookTimeout lets
+	// hooks run indefinitely.
+	HookTimeout time.Duration
+
+	// InstallTimeout is how long an install or upgrade of a release may run
+	// before it is cancelled, and the reconcile that runs it fails with a
+	// timed out Installed condition. A zero InstallTimeout lets installs and
+	// upgrades run indefinitely.
+	InstallTimeout time.Duration
+
+	// DynamicWatchLimit is the rate limit of the requests made by the caches
+	// of the dynamic watches of installed objects.
+	DynamicWatchLimit util.ClientRateLimit
+
+	// MaxConcurrentReconciles is the number of BundleInstances that are
+	// reconciled in parallel. It defaults to 1.
+	MaxConcurrentReconciles int
+
+	// RateLimit is the rate limit of the retries of failed reconciles of
+	// BundleInstances. An unset RateLimit keeps the default rate limiter of
+	// controller-runtime.
+	RateLimit util.QueueRateLimit
+	// Policies are checked against the objects of bundles before they are
+	// installed, along with the policy webhooks of the RukpakConfig. Bundles
+	// with objects that violate any of them are not installed.
+	Policies []policy.Checker
+	// Selector selects the BundleInstances that are installed by this
+	// instance of the provisioner among those of its provisioner class, so
+	// that several instances share them. A nil Selector selects every
+	// BundleInstance.
+	Selector labels.Selector
+	// ServerSideApply installs the BundleInstances that select the
+	// ServerSideApply engine. Without it, they fail to install, but are
+	// still uninstalled with it.
+	ServerSideApply bool
+	// DriftCorrection reapplies the drifted objects of BundleInstances with
+	// the Correct drift policy. Without it, their drift is only detected.
+	DriftCorrection bool
+
+	watches *watchmanager.WatchManager
+}
+
+//+kubebuilder:rbac:groups=core.rukpak.io,resources=bundleinstances,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.rukpak.io,resources=bundleinstances/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core.rukpak.io,resources=bundleinstances/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core.rukpak.io,resources=rukpakconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=operators.coreos.com,resources=operatorgroups,verbs=get;list;watch
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=selfsubjectaccessreviews;subjectaccessreviews,verbs=create
+//+kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=impersonate
+//
+// The permissions required to install bundle content are not known up front.
+// They are granted by aggregating ClusterRoles labeled with the
+// rbac.rukpak.io/aggregate-to-plain-provisioner label into the provisioner's
+// ClusterRole, and missing permissions are reported in the Installed condition.
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+// TODO(user): Modify the Reconcile function to compare the state specified by
+// the BundleInstance object against the actual cluster state, and then
+// perform operations to make the cluster state reflect the state specified by
+// the user.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *BundleInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reconcileErr error) {
+	start := time.Now()
+	l := log.FromContext(ctx).WithValues(util.LogKeyBundleInstance, req.Name, util.LogKeyRelease, req.Name, util.LogKeyReconcileID, util.NewReconcileID())
+	l.V(util.LogLevelDebug).Info("starting reconciliation")
+	defer l.V(util.LogLevelDebug).Info("ending reconciliation")
+	ctx, span := tracing.Start(ctx, "BundleInstance.Reconcile", tracing.AttributeBundleInstance.String(req.Name))
+	defer func() { tracing.End(span, reconcileErr) }()
+
+	bi := &rukpakv1alpha1.BundleInstance{}
+	if err := r.Get(ctx, req.NamespacedName, bi); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.watches.Release(ctx, req.Name)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// Bundles and installed objects enqueue the BundleInstances of every
+	// shard. The watches of a BundleInstance that was relabeled into another
+	// shard are released, since that shard now installs it.
+	if !util.MatchesSelector(r.Selector, bi) {
+		r.watches.Release(ctx, req.Name)
+		return ctrl.Result{}, nil
+	}
+	l = l.WithValues(util.LogKeyBundle, bi.Spec.BundleName)
+	ctx = log.IntoContext(ctx, l)
+	span.SetAttributes(tracing.AttributeBundle.String(bi.Spec.BundleName))
+	previous := bi.Status.DeepCopy()
+
+	// In read-only mode, the finalizer is neither added nor handled, as
+	// handling it would change the installed objects.
+	if bi.DeletionTimestamp != nil {
+		if r.ReadOnly || !controllerutil.ContainsFinalizer(bi, deletionPolicyFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		return r.finalize(ctx, bi, previous)
+	}
+	if !r.ReadOnly && !controllerutil.ContainsFinalizer(bi, deletionPolicyFinalizer) {
+		patch := client.MergeFromWithOptions(bi.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		controllerutil.AddFinalizer(bi, deletionPolicyFinalizer)
+		if err := r.Patch(ctx, bi, patch); err != nil {
+			return ctrl.Result{}, fmt.Errorf("add finalizer: %w", err)
+		}
+	}
+
+	attempted := metav1.NewTime(start)
+	bi.Status.LastAttemptedAt = &attempted
+	bi.Status.ObservedGeneration = bi.Generation
+	defer func() {
+		recordOutcome(bi, attempted, reconcileErr)
+		r.patchStatus(ctx, bi, previous)
+		if reconcileErr != nil {
+			reconcileErr = util.ReconcileError(reconcileErr, util.UnreadyCondition(bi.Status.Conditions, readyRequiredConditions, readyBlockingConditions))
+		}
+	}()
+
+	if err := r.checkReleaseNamespace(bi); err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ReleaseNamespaceNotAllowed, err.Error()))
+		return ctrl.Result{}, nil
+	}
+	bi.Status.ReleaseNamespace = r.releaseNamespace(bi)
+	if err := r.checkServiceAccount(ctx, bi); err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.ServiceAccountNotAllowed, err))
+		return ctrl.Result{}, err
+	}
+
+	// The Bundle of a template is installed as if the BundleInstance referenced
+	// it by name. The spec of the BundleInstance itself is left unchanged.
+	if bi.Spec.Template != nil {
+		name := util.DesiredBundleName(bi)
+		if !r.ReadOnly {
+			if err := r.ensureTemplateBundle(ctx, bi); err != nil {
+				meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).HasValidBundle(metav1.ConditionFalse, status.TemplateBundleFailed, err.Error()))
+				return ctrl.Result{}, err
+			}
+		}
+		bi.Spec.BundleName = name
+		l = l.WithValues(util.LogKeyBundle, name)
+		ctx = log.IntoContext(ctx, l)
+		span.SetAttributes(tracing.AttributeBundle.String(name))
+	}
+
+	// A pivot occurs when the BundleInstance is changed to reference a different
+	// Bundle than the one that is currently installed. The currently installed
+	// content remains in place, and is reported as such, until the release has
+	// been upgraded to the contents of the new Bundle.
+	pivotFrom := ""
+	if bi.Status.InstalledBundleName != "" && bi.Status.InstalledBundleName != bi.Spec.BundleName {
+		pivotFrom = bi.Status.InstalledBundleName
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Pivoting(metav1.ConditionTrue, status.PivotInProgress, fmt.Sprintf("pivoting from bundle %q to bundle %q", pivotFrom, bi.Spec.BundleName)))
+	}
+
+	b := &rukpakv1alpha1.Bundle{}
+	if err := r.Get(ctx, types.NamespacedName{Name: bi.Spec.BundleName}, b); err != nil {
+		bundleStatus := metav1.ConditionUnknown
+		if apierrors.IsNotFound(err) {
+			bundleStatus = metav1.ConditionFalse
+		}
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).HasValidBundle(bundleStatus, status.BundleLookupFailed, err.Error()))
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	l = l.WithValues(util.LogKeyDigest, b.Status.Digest)
+	span.SetAttributes(tracing.AttributeDigest.String(b.Status.Digest))
+
+	objs, err := r.loadBundle(ctx, bi)
+	if err != nil {
+		var bnuErr *errBundleNotUnpacked
+		if errors.As(err, &bnuErr) {
+			reason := status.BundleNotUnpacked(b.Status.Phase)
+			if pivotFrom != "" {
+				// The previously installed bundle is still installed, so leave
+				// the Installed condition as-is while the new bundle unpacks.
+				meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Pivoting(metav1.ConditionTrue, status.PivotInProgress, fmt.Sprintf("pivoting from bundle %q to bundle %q: waiting for bundle to be unpacked: %s", pivotFrom, bi.Spec.BundleName, reason)))
+				return ctrl.Result{}, nil
+			}
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, reason, ""))
+			return ctrl.Result{}, nil
+		}
+		var integrityErr *storage.IntegrityError
+		if errors.As(err, &integrityErr) {
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).InvalidBundleContent(metav1.ConditionTrue, status.IntegrityCheckFailed, err.Error()))
+		}
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).HasValidBundle(metav1.ConditionFalse, status.BundleLoadFailed, err.Error()))
+		return ctrl.Result{}, err
+	}
+	// Hooks are run around installs and upgrades rather than installed, so
+	// they are neither applied in waves nor assessed along with the other
+	// objects.
+	desiredObjects, hooks, err := splitHooks(objs)
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).HasValidBundle(metav1.ConditionFalse, status.BundleLoadFailed, err.Error()))
+		return ctrl.Result{}, err
+	}
+	meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).HasValidBundle(metav1.ConditionTrue, status.BundleLoaded, ""))
+
+	// The warnings of the API server are reported once the content has been
+	// installed, upgraded or reconciled, whether that succeeded or not.
+	warnings := &util.WarningCollector{}
+	defer r.reportWarnings(bi, warnings)
+
+	if bi.Spec.Engine == rukpakv1alpha1.EngineServerSideApply {
+		if !r.ServerSideApply {
+			err := errors.New("the ServerSideApply engine is disabled: run the provisioner with the ServerSideApply feature enabled to install it")
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.InstallFailed, err.Error()))
+			return ctrl.Result{}, err
+		}
+		return r.reconcileServerSideApply(ctx, bi, b, desiredObjects, hooks, pivotFrom, warnings)
+	}
+
+	chrt, err := r.renderChart(ctx, bi, desiredObjects, b.Status.Digest)
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).InvalidBundleContent(metav1.ConditionTrue, status.ReadingContentFailed, err.Error()))
+		return ctrl.Result{}, err
+	}
+	chartHash := chrt.Metadata.Annotations[chartHashAnnotation]
+	if err := syncHookStatuses(bi, hooks); err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).HasValidBundle(metav1.ConditionFalse, status.BundleLoadFailed, err.Error()))
+		return ctrl.Result{}, err
+	}
+
+	bi.SetNamespace(r.releaseNamespace(bi))
+	cl, err := r.actionClientFor(bi, warnings)
+	bi.SetNamespace("")
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ErrorGettingClient, err.Error()))
+		return ctrl.Result{}, err
+	}
+
+	// Custom resources of the CRDs in the bundle cannot be rendered until the
+	// CRDs are established, so those CRDs, and the Namespaces of the bundle,
+	// are applied ahead of the release.
+	gated := false
+	if !r.ReadOnly && bi.Spec.InstallMode != rukpakv1alpha1.InstallModeDryRun {
+		// The CRDs of the bundle replace existing CRDs ahead of the release
+		// too, so upgrades that would lose or invalidate the custom resources
+		// stored for them are refused first.
+		contentClient, err := r.contentClient(bi, warnings)
+		if err != nil {
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ErrorGettingClient, err.Error()))
+			return ctrl.Result{}, err
+		}
+		if err := r.checkCRDUpgrades(ctx, contentClient, bi, desiredObjects); err != nil {
+			return ctrl.Result{}, err
+		}
+		var established bool
+		gated, established, err = r.installPrerequisites(ctx, contentClient, bi, objs, desiredObjects)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !established {
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.CRDsNotEstablished, "waiting for the custom resource definitions of the bundle to be established"))
+			return ctrl.Result{RequeueAfter: crdEstablishedPollInterval}, nil
+		}
+	}
+
+	rel, state, err := r.getReleaseState(ctx, cl, bi, chrt)
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.ErrorGettingReleaseState, err))
+		return ctrl.Result{}, err
+	}
+	l.V(util.LogLevelDebug).Info("computed release state", "state", state)
+	for _, obj := range desiredObjects {
+		l.V(util.LogLevelTrace).Info("applying object",
+			util.LogKeyGVK, obj.GetObjectKind().GroupVersionKind().String(),
+			util.LogKeyObject, obj.GetName(),
+			util.LogKeyNamespace, obj.GetNamespace(),
+		)
+	}
+
+	if r.ReadOnly && state != stateUnchanged {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ReadOnlyMode, fmt.Sprintf("release state is %q but the controller is running in read-only mode", state)))
+		return ctrl.Result{}, nil
+	}
+
+	dryRun := bi.Spec.InstallMode == rukpakv1alpha1.InstallModeDryRun
+	if dryRun && state != stateUnchanged {
+		changes, err := pendingChanges(rel, chrt)
+		if err != nil {
+			meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.ErrorGettingReleaseState, err))
+			return ctrl.Result{}, err
+		}
+		changes.BundleName = bi.Spec.BundleName
+		bi.Status.PendingChanges = changes
+		msg := fmt.Sprintf("release state is %q: %d objects would be added, %d removed and %d changed in the %s install mode",
+			state, len(changes.Added), len(changes.Removed), len(changes.Changed), rukpakv1alpha1.InstallModeApply)
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.DryRunMode, msg))
+		return ctrl.Result{}, nil
+	}
+	bi.Status.PendingChanges = nil
+
+	if state == stateNeedsInstall || state == stateNeedsUpgrade {
+		// The objects of every wave but the last are applied, and must be
+		// healthy, before the release is installed or upgraded with the rest.
+		contentClient, err := r.contentClient(bi, warnings)
+		if err != nil {
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ErrorGettingClient, err.Error()))
+			return ctrl.Result{}, err
+		}
+		if !gated {
+			if err := r.checkInstallGates(ctx, contentClient, bi, objs, desiredObjects); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		pending, err := r.applyWaves(ctx, contentClient, bi, desiredObjects, func(obj client.Object) error {
+			return r.applyAheadOfRelease(ctx, contentClient, bi, obj)
+		})
+		if err != nil {
+			meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.InstallFailed, err))
+			return ctrl.Result{}, err
+		}
+		if pending != "" {
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.WaveInProgress, pending))
+			return ctrl.Result{RequeueAfter: wavePollInterval}, nil
+		}
+		event := manifests.HookPreInstall
+		if state == stateNeedsUpgrade {
+			event = manifests.HookPreUpgrade
+		}
+		if result, err := r.reconcileHooks(ctx, contentClient, bi, hooks, event, chartHash); err != nil || !result.IsZero() {
+			return result, err
+		}
+	}
+
+	// The outcome for each object is computed from the release that is
+	// replaced, before it is.
+	var results []rukpakv1alpha1.InstalledObject
+	if state == stateNeedsInstall || state == stateNeedsUpgrade {
+		from := rel
+		if state == stateNeedsInstall {
+			from = nil
+		}
+		if results, err = r.releaseInstallResults(bi, from, chrt, desiredObjects); err != nil {
+			meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.ErrorGettingReleaseState, err))
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Drift is only reported once the installed content is reconciled.
+	var drifted []string
+	switch state {
+	case stateNeedsInstall:
+		_, installSpan := tracing.Start(ctx, "BundleInstance.Install", tracing.AttributeObjects.Int(len(desiredObjects)))
+		installStart := time.Now()
+		rel, err = r.runInstall(ctx, cl, bi, warnings, func(cl helmclient.ActionInterface) (*release.Release, error) {
+			return cl.Install(bi.Name, r.releaseNamespace(bi), chrt, nil, func(install *action.Install) error {
+				install.CreateNamespace = false
+				install.Timeout = r.InstallTimeout
+				return nil
+			})
+		})
+		tracing.End(installSpan, err)
+		r.observeInstall(ctx, bi, operationInstall, installStart, err)
+		if errors.Is(err, errInstallTimedOut) {
+			err = fmt.Errorf("install of bundle %q did not complete within %s", bi.Spec.BundleName, r.InstallTimeout)
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.InstallTimedOut, err.Error()))
+			r.eventf(bi, corev1.EventTypeWarning, rukpakv1alpha1.ReasonInstallTimedOut, "%v", err)
+			return ctrl.Result{}, err
+		}
+		if err != nil {
+			meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.InstallFailed, err))
+			r.eventf(bi, corev1.EventTypeWarning, rukpakv1alpha1.ReasonInstallFailed, "failed to install bundle %q: %v", bi.Spec.BundleName, err)
+			r.recordReleaseFailures(ctx, bi, desiredObjects)
+			return ctrl.Result{}, err
+		}
+		setInstallResults(bi, results)
+		r.eventf(bi, corev1.EventTypeNormal, rukpakv1alpha1.ReasonInstalled, "installed bundle %q", bi.Spec.BundleName)
+	case stateNeedsUpgrade:
+		_, upgradeSpan := tracing.Start(ctx, "BundleInstance.Upgrade", tracing.AttributeObjects.Int(len(desiredObjects)))
+		upgradeStart := time.Now()
+		rel, err = r.runInstall(ctx, cl, bi, warnings, func(cl helmclient.ActionInterface) (*release.Release, error) {
+			return cl.Upgrade(bi.Name, r.releaseNamespace(bi), chrt, nil, func(upgrade *action.Upgrade) error {
+				upgrade.Timeout = r.InstallTimeout
+				return nil
+			})
+		})
+		tracing.End(upgradeSpan, err)
+		r.observeInstall(ctx, bi, operationUpgrade, upgradeStart, err)
+		if errors.Is(err, errInstallTimedOut) {
+			err = fmt.Errorf("upgrade to bundle %q did not complete within %s", bi.Spec.BundleName, r.InstallTimeout)
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.UpgradeTimedOut, err.Error()))
+			r.eventf(bi, corev1.EventTypeWarning, rukpakv1alpha1.ReasonUpgradeTimedOut, "%v", err)
+			return ctrl.Result{}, err
+		}
+		if err != nil {
+			meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.UpgradeFailed, err))
+			r.eventf(bi, corev1.EventTypeWarning, rukpakv1alpha1.ReasonUpgradeFailed, "failed to upgrade to bundle %q: %v", bi.Spec.BundleName, err)
+			r.recordReleaseFailures(ctx, bi, desiredObjects)
+			if pivotFrom != "" {
+				meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Pivoting(metav1.ConditionTrue, status.PivotFailed, fmt.Sprintf("pivoting from bundle %q to bundle %q: %v", pivotFrom, bi.Spec.BundleName, err)))
+			}
+			if bi.Spec.UpgradePolicy != nil && bi.Spec.UpgradePolicy.RollbackOnFailure {
+				r.rollback(cl, bi, err, warnings)
+			}
+			return ctrl.Result{}, err
+		}
+		setInstallResults(bi, results)
+		if pivotFrom != "" {
+			r.eventf(bi, corev1.EventTypeNormal, rukpakv1alpha1.ReasonUpgraded, "upgraded from bundle %q to bundle %q", pivotFrom, bi.Spec.BundleName)
+		} else {
+			r.eventf(bi, corev1.EventTypeNormal, rukpakv1alpha1.ReasonUpgraded, "upgraded bundle %q", bi.Spec.BundleName)
+		}
+	case stateUnchanged:
+		if r.ReadOnly || dryRun {
+			break
+		}
+		if r.driftPolicy(bi) == rukpakv1alpha1.DriftPolicyIgnore {
+			break
+		}
+		if r.driftPolicy(bi) == rukpakv1alpha1.DriftPolicyDetect {
+			contentClient, err := r.contentClient(bi, warnings)
+			if err != nil {
+				meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ErrorGettingClient, err.Error()))
+				return ctrl.Result{}, err
+			}
+			if drifted, err = r.detectDrift(ctx, contentClient, bi, desiredObjects); err != nil {
+				meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Drifted(metav1.ConditionUnknown, status.DriftCheckFailed, err.Error()))
+				return ctrl.Result{}, err
+			}
+			break
+		}
+		done, err := r.reconcileRelease(cl, bi, rel, start)
+		if err != nil {
+			meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.ReconcileFailed, err))
+			return ctrl.Result{}, err
+		}
+		if !done {
+			l.V(util.LogLevelDebug).Info("reconcile budget exhausted",
+				"reconciled", bi.Status.ReconcileProgress.Reconciled,
+				"total", bi.Status.ReconcileProgress.Total,
+			)
+			return ctrl.Result{RequeueAfter: reconcileResumeDelay}, nil
+		}
+	default:
+		return ctrl.Result{}, fmt.Errorf("unexpected release state %q", state)
+	}
+	bi.Status.ReconcileProgress = nil
+	if !r.ReadOnly && !dryRun {
+		reportDrift(bi, r.driftPolicy(bi), drifted)
+	}
+	// The install or upgrade only completes once its post hooks succeed, which
+	// are checked on the reconciles that follow it.
+	if !r.ReadOnly && !dryRun {
+		event := pendingPostHookEvent(bi, chartHash)
+		switch state {
+		case stateNeedsInstall:
+			event = manifests.HookPostInstall
+		case stateNeedsUpgrade:
+			event = manifests.HookPostUpgrade
+		}
+		if event != "" {
+			contentClient, err := r.contentClient(bi, warnings)
+			if err != nil {
+				meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ErrorGettingClient, err.Error()))
+				return ctrl.Result{}, err
+			}
+			if result, err := r.reconcileHooks(ctx, contentClient, bi, hooks, event, chartHash); err != nil || !result.IsZero() {
+				return result, err
+			}
+		}
+	}
+	return r.completeInstall(ctx, bi, b, rel, desiredObjects, pivotFrom)
+}
+
+// reconcileHooks runs the hooks of bi for event with cl, and reports them in
+// the Installed condition of bi until they have all succeeded. It returns a
+// zero result and no error once they have.
+func (r *BundleInstanceReconciler) reconcileHooks(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, hooks []client.Object, event, hash string) (ctrl.Result, error) {
+	pending, err := r.runHooks(ctx, cl, bi, hooks, event, hash)
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.HookFailed, fmt.Errorf("run %s hooks: %w", event, err)))
+		return ctrl.Result{}, err
+	}
+	if pending != "" {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.HookInProgress, pending))
+		return ctrl.Result{RequeueAfter: hookPollInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// completeInstall watches and assesses the health of the installed objects
+// objs of bi, which has been installed or upgraded to rel, and reports the
+// content of its bundle b as installed.
+func (r *BundleInstanceReconciler) completeInstall(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, b *rukpakv1alpha1.Bundle, rel *release.Release, desiredObjects []client.Object, pivotFrom string) (ctrl.Result, error) {
+	// The installed objects of BundleInstances that ignore drift are not
+	// watched, so that their changes do not trigger reconciles.
+	var gvks []schema.GroupVersionKind
+	if r.driftPolicy(bi) != rukpakv1alpha1.DriftPolicyIgnore {
+		for _, obj := range desiredObjects {
+			gvks = append(gvks, obj.GetObjectKind().GroupVersionKind())
+		}
+	}
+	watchCtx, watchSpan := tracing.Start(ctx, "BundleInstance.SyncWatches")
+	err := r.watches.Sync(watchCtx, bi.Name, gvks)
+	tracing.End(watchSpan, err)
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.CreateDynamicWatchFailed, err.Error()))
+		return ctrl.Result{}, err
+	}
+	wasHealthy := metav1.ConditionUnknown
+	if cond := meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeHealthy); cond != nil {
+		wasHealthy = cond.Status
+	}
+	if err := r.assessHealth(ctx, bi, desiredObjects); err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Healthy(metav1.ConditionUnknown, status.HealthCheckFailed, err.Error()))
+	}
+	r.recordHealthTransition(bi, wasHealthy)
+	meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionTrue, status.InstallationSucceeded, ""))
+	meta.RemoveStatusCondition(&bi.Status.Conditions, rukpakv1alpha1.TypeRolledBack)
+	if pivotFrom != "" {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Pivoting(metav1.ConditionFalse, status.PivotSucceeded, fmt.Sprintf("pivoted from bundle %q to bundle %q", pivotFrom, bi.Spec.BundleName)))
+	}
+	// The outcome of an earlier pivot and preflight check still holds for the
+	// content that is now installed, even when neither had to be run again.
+	util.ObserveGeneration(bi.Status.Conditions, bi.Generation, rukpakv1alpha1.TypePivoting, rukpakv1alpha1.TypePreflightSucceeded)
+	bi.Status.InstalledBundleName = bi.Spec.BundleName
+	bi.Status.Release = releaseStatus(rel)
+	if !r.ReadOnly {
+		if err := r.deleteStaleTemplateBundles(ctx, bi); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.ensureInstallReport(ctx, bi, b, rel); err != nil {
+		return ctrl.Result{}, fmt.Errorf("store install report: %w", err)
+	}
+
+	config, err := util.GetRukpakConfig(ctx, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	interval := r.ResyncInterval
+	if config.ResyncInterval != nil && config.ResyncInterval.Duration > 0 {
+		interval = config.ResyncInterval.Duration
+	}
+	if bi.Spec.ResyncInterval != nil {
+		interval = bi.Spec.ResyncInterval.Duration
+	}
+	if interval > 0 {
+		return ctrl.Result{RequeueAfter: interval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// ensureTemplateBundle creates the Bundle of the template of bi, owned by bi,
+// unless it already exists.
+func (r *BundleInstanceReconciler) ensureTemplateBundle(ctx context.Context, bi *rukpakv1alpha1.BundleInstance) error {
+	desired := util.TemplateBundle(bi)
+	existing := &rukpakv1alpha1.Bundle{}
+	err := r.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	if err == nil {
+		if !metav1.IsControlledBy(existing, bi) {
+			return fmt.Errorf("bundle %q of the template already exists and is not owned by the bundleinstance", desired.Name)
+		}
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("get bundle %q of the template: %w", desired.Name, err)
+	}
+	if err := controllerutil.SetControllerReference(bi, desired, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, desired); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create bundle %q of the template: %w", desired.Name, err)
+	}
+	return nil
+}
+
+// deleteStaleTemplateBundles deletes the Bundles of previous templates of bi,
+// which are owned by bi, once another Bundle is installed.
+func (r *BundleInstanceReconciler) deleteStaleTemplateBundles(ctx context.Context, bi *rukpakv1alpha1.BundleInstance) error {
+	bundles := &rukpakv1alpha1.BundleList{}
+	if err := r.List(ctx, bundles); err != nil {
+		return fmt.Errorf("list bundles: %w", err)
+	}
+	for i := range bundles.Items {
+		b := &bundles.Items[i]
+		if b.Name == bi.Status.InstalledBundleName || !metav1.IsControlledBy(b, bi) {
+			continue
+		}
+		if err := r.Delete(ctx, b); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("delete bundle %q of a previous template: %w", b.Name, err)
+		}
+		log.FromContext(ctx).V(util.LogLevelDebug).Info("deleted bundle of a previous template", util.LogKeyBundle, b.Name)
+	}
+	return nil
+}
+
+// preflight checks that the provisioner is permitted to manage every object
+// in objs before they are installed, and reports the outcome in the
+// PreflightSucceeded condition. An error is returned when any permission is
+// missing, so that the check is retried once the permissions are granted.
+func (r *BundleInstanceReconciler) preflight(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, objs []client.Object) error {
+	checker := &preflight.Checker{Client: r.Client, Mapper: r.RESTMapper(), ServiceAccount: r.serviceAccount(bi)}
+	missing, err := checker.MissingPermissions(ctx, objs, r.releaseNamespace(bi))
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).PreflightSucceeded(metav1.ConditionUnknown, status.PreflightCheckFailed, err.Error()))
+		return err
+	}
+	if !missing.Empty() {
+		var messages []string
+		if len(missing.ServiceAccount) > 0 {
+			messages = append(messages, fmt.Sprintf("missing permissions of service account %q for bundle content: %s: grant the service account these rules",
+				checker.ServiceAccount, preflight.FormatRules(missing.ServiceAccount)))
+		}
+		if len(missing.Provisioner) > 0 {
+			messages = append(messages, fmt.Sprintf("missing permissions for bundle content: %s: grant the provisioner these rules by creating a ClusterRole labeled %s=true",
+				preflight.FormatRules(missing.Provisioner), aggregateToProvisionerLabel))
+		}
+		err := errors.New(strings.Join(messages, "; "))
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).PreflightSucceeded(metav1.ConditionFalse, status.MissingPermissions, err.Error()))
+		meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.InsufficientPermissions, err))
+		return err
+	}
+	meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).PreflightSucceeded(metav1.ConditionTrue, status.PermissionsGranted, ""))
+	return nil
+}
+
+// checkInstallGates runs the checks that the bundle of bi must pass before
+// any of its objects is applied: objs, all the objects of the bundle, must
+// pass the preflight check and the policies, and the objects desiredObjects
+// of the release must not conflict with existing objects that the conflict
+// policy of bi does not allow it to take over. The outcome of a failed check
+// is reported in the conditions of bi.
+func (r *BundleInstanceReconciler) checkInstallGates(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, objs, desiredObjects []client.Object) error {
+	if err := r.preflight(ctx, bi, objs); err != nil {
+		return err
+	}
+	if err := r.checkPolicies(ctx, bi, objs); err != nil {
+		return err
+	}
+	// Existing objects that the release does not own would fail the release
+	// with an error of Helm, so they are taken over or reported according to
+	// the conflict policy first.
+	conflicts, err := r.objectConflicts(ctx, cl, bi, desiredObjects)
+	if err == nil {
+		conflicts, err = r.resolveConflicts(ctx, cl, bi, conflicts)
+	}
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.InstallFailed, err))
+		return err
+	}
+	if len(conflicts) > 0 {
+		messages := make([]string, 0, len(conflicts))
+		for _, c := range conflicts {
+			messages = append(messages, c.String())
+		}
+		err := fmt.Errorf("objects of the bundle conflict with existing objects: %s", strings.Join(messages, "; "))
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ObjectConflict, err.Error()))
+		return err
+	}
+	return nil
+}
+
+// installPrerequisites applies the CRDs and Namespaces in desiredObjects
+// ahead of the release of bi with cl, when desiredObjects contain custom
+// resources of a CRD in desiredObjects that the cluster does not serve yet.
+// They are only applied once the bundle of bi, whose objects are objs, passes
+// checkInstallGates. It returns whether the gates were checked and passed, and
+// whether the CRDs are established, and reports failures in the conditions of
+// bi. The applied objects carry the ownership metadata of the release, so that
+// the release adopts them once it is installed or upgraded.
+func (r *BundleInstanceReconciler) installPrerequisites(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, objs, desiredObjects []client.Object) (bool, bool, error) {
+	failed := func(gated bool, err error) (bool, bool, error) {
+		meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.InstallFailed, err))
+		return gated, false, err
+	}
+	crds := map[schema.GroupKind]client.Object{}
+	var prerequisites []client.Object
+	for _, obj := range desiredObjects {
+		switch obj.GetObjectKind().GroupVersionKind().GroupKind() {
+		case apiextensionsv1.Kind("CustomResourceDefinition"):
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).Object, crd); err != nil {
+				return failed(false, fmt.Errorf("convert CRD %q: %w", obj.GetName(), err))
+			}
+			crds[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = obj
+			prerequisites = append(prerequisites, obj)
+		case corev1.SchemeGroupVersion.WithKind("Namespace").GroupKind():
+			prerequisites = append(prerequisites, obj)
+		}
+	}
+
+	var pending []string
+	for _, obj := range desiredObjects {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		crd, ok := crds[gvk.GroupKind()]
+		if !ok {
+			continue
+		}
+		if _, err := r.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			if !meta.IsNoMatchError(err) {
+				return failed(false, fmt.Errorf("determine mapping of %s %q: %w", gvk, obj.GetName(), err))
+			}
+			pending = append(pending, crd.GetName())
+		}
+	}
+	if len(pending) == 0 {
+		return false, true, nil
+	}
+
+	if err := r.checkInstallGates(ctx, cl, bi, objs, desiredObjects); err != nil {
+		return false, false, err
+	}
+	for _, obj := range prerequisites {
+		if err := r.applyAheadOfRelease(ctx, cl, bi, obj); err != nil {
+			return failed(true, err)
+		}
+	}
+
+	for _, name := range pending {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := cl.Get(ctx, types.NamespacedName{Name: name}, crd); err != nil {
+			return failed(true, fmt.Errorf("get CRD %q: %w", name, err))
+		}
+		if !crdEstablished(crd) {
+			return true, false, nil
+		}
+	}
+	return true, true, nil
+}
+
+// applyWaves applies the objects of every wave in objs but the last with
+// apply, in ascending order of their waves, and returns a description of the
+// first wave whose objects are not all healthy yet according to cl, if any.
+// The objects of the last wave are left to the caller.
+func (r *BundleInstanceReconciler) applyWaves(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, objs []client.Object, apply func(client.Object) error) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "BundleInstance.ApplyWaves")
+	defer func() { tracing.End(span, err) }()
+	waves, err := manifests.Waves(objs)
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < len(waves)-1; i++ {
+		var unhealthy []string
+		for _, obj := range waves[i] {
+			if err := apply(obj); err != nil {
+				return "", err
+			}
+			gvk := obj.GetObjectKind().GroupVersionKind()
+			if !health.HasChecker(gvk.GroupKind()) {
+				continue
+			}
+			key, err := r.installedObjectKey(bi, obj)
+			if err != nil {
+				return "", err
+			}
+			live := &unstructured.Unstructured{}
+			live.SetGroupVersionKind(gvk)
+			if err := cl.Get(ctx, key, live); err != nil {
+				return "", fmt.Errorf("get %s %q: %w", gvk.Kind, key, err)
+			}
+			result, err := health.Assess(live)
+			if err != nil {
+				return "", err
+			}
+			if !result.Healthy {
+				unhealthy = append(unhealthy, fmt.Sprintf("%s %q: %s", gvk.Kind, key, result.Message))
+			}
+		}
+		if len(unhealthy) > 0 {
+			wave, _ := manifests.Wave(waves[i][0])
+			return fmt.Sprintf("waiting for the objects of wave %d to be healthy: %s", wave, strings.Join(unhealthy, "; ")), nil
+		}
+	}
+	return "", nil
+}
+
+// objectConflict is an object of a bundle that already exists, but is not
+// installed by the release of the BundleInstance of the bundle.
+type objectConflict struct {
+	obj  client.Object
+	live *metav1.PartialObjectMetadata
+	// owner describes the release that installed the existing object. It is
+	// empty when no release installed it.
+	owner string
+}
+
+func (c objectConflict) String() string {
+	kind, key := c.obj.GetObjectKind().GroupVersionKind().Kind, client.ObjectKeyFromObject(c.live)
+	if c.owner == "" {
+		return fmt.Sprintf("%s %q already exists and is not installed by any release", kind, key)
+	}
+	return fmt.Sprintf("%s %q is installed by %s", kind, key, c.owner)
+}
+
+// objectConflicts returns the objects of objs that exist but are not
+// installed by the release of bi. Objects whose kind is not served yet cannot
+// exist, and are skipped.
+func (r *BundleInstanceReconciler) objectConflicts(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, objs []client.Object) ([]objectConflict, error) {
+	var conflicts []objectConflict
+	for _, obj := range objs {
+		key, err := r.installedObjectKey(bi, obj)
+		if meta.IsNoMatchError(errors.Unwrap(err)) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		live := &metav1.PartialObjectMetadata{}
+		live.SetGroupVersionKind(gvk)
+		if err := cl.Get(ctx, key, live); err != nil {
+			// The key of an object with a namespace is known without its
+			// mapping, so a kind of a CRD of the bundle that is not applied
+			// yet only fails the get.
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("get %s %q: %w", gvk.Kind, key, err)
+		}
+		annotations := live.GetAnnotations()
+		releaseName, releaseNamespace := annotations[helmReleaseNameAnnotation], annotations[helmReleaseNamespaceAnnotation]
+		if releaseName == bi.Name && releaseNamespace == r.releaseNamespace(bi) {
+			continue
+		}
+		c := objectConflict{obj: obj, live: live}
+		if releaseName != "" {
+			c.owner = fmt.Sprintf("release %s/%s", releaseNamespace, releaseName)
+			if labels := live.GetLabels(); labels["core.rukpak.io/owner-kind"] == "BundleInstance" && releaseNamespace == r.releaseNamespace(bi) {
+				c.owner = fmt.Sprintf("BundleInstance %q", labels["core.rukpak.io/owner-name"])
+			}
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, nil
+}
+
+// resolveConflicts takes over the objects of conflicts that the conflict
+// policy of bi allows it to take over, and returns the other conflicts.
+func (r *BundleInstanceReconciler) resolveConflicts(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, conflicts []objectConflict) ([]objectConflict, error) {
+	var unresolved []objectConflict
+	for _, c := range conflicts {
+		switch {
+		case bi.Spec.ConflictPolicy == rukpakv1alpha1.ConflictPolicyForce:
+			if err := removeOtherControllers(ctx, cl, bi, c.live); err != nil {
+				return nil, fmt.Errorf("take over %s: %w", c, err)
+			}
+			if err := r.applyAheadOfRelease(ctx, cl, bi, c.obj); err != nil {
+				return nil, fmt.Errorf("take over %s: %w", c, err)
+			}
+		case bi.Spec.ConflictPolicy == rukpakv1alpha1.ConflictPolicyAdopt && c.owner == "":
+			// The release adopts objects that carry its ownership metadata.
+			base := c.live.DeepCopy()
+			c.live.SetLabels(util.MergeMaps(c.live.GetLabels(), map[string]string{"app.kubernetes.io/managed-by": "Helm"}))
+			c.live.SetAnnotations(util.MergeMaps(c.live.GetAnnotations(), map[string]string{
+				helmReleaseNameAnnotation:      bi.Name,
+				helmReleaseNamespaceAnnotation: r.releaseNamespace(bi),
+			}))
+			if err := cl.Patch(ctx, c.live, client.MergeFrom(base)); err != nil {
+				return nil, fmt.Errorf("adopt %s: %w", c, err)
+			}
+		default:
+			unresolved = append(unresolved, c)
+		}
+	}
+	return unresolved, nil
+}
+
+// applyAheadOfRelease applies obj with cl before the release of bi is
+// installed or upgraded. obj carries the ownership metadata of the release,
+// so that the release adopts it, and is owned by bi like the objects of the
+// release.
+func (r *BundleInstanceReconciler) applyAheadOfRelease(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, obj client.Object) error {
+	obj = obj.DeepCopyObject().(client.Object)
+	obj.SetLabels(util.MergeMaps(obj.GetLabels(), map[string]string{"app.kubernetes.io/managed-by": "Helm"}))
+	obj.SetAnnotations(util.MergeMaps(obj.GetAnnotations(), map[string]string{
+		helmReleaseNameAnnotation:      bi.Name,
+		helmReleaseNamespaceAnnotation: r.releaseNamespace(bi),
+	}))
+	if err := r.applyObject(ctx, cl, bi, obj, client.FieldOwner(plainBundleProvisionerID), client.ForceOwnership); err != nil {
+		return fmt.Errorf("apply %s %q ahead of the release: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+	}
+	return nil
+}
+
+// applyObject applies obj with cl and opts, in the namespace that it is
+// installed into, and owned by bi unless it is kept on uninstall.
+func (r *BundleInstanceReconciler) applyObject(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, obj client.Object, opts ...client.PatchOption) error {
+	obj, err := r.installedObject(bi, obj)
+	if err != nil {
+		return err
+	}
+	return cl.Patch(ctx, obj, client.Apply, opts...)
+}
+
+// installedObject returns a copy of obj as it is installed for bi: in the
+// namespace that it is installed into, and owned by bi unless it is kept on
+// uninstall.
+func (r *BundleInstanceReconciler) installedObject(bi *rukpakv1alpha1.BundleInstance, obj client.Object) (client.Object, error) {
+	obj = obj.DeepCopyObject().(client.Object)
+	key, err := r.installedObjectKey(bi, obj)
+	if err != nil {
+		return nil, err
+	}
+	obj.SetNamespace(key.Namespace)
+	// Like the release, objects that are kept on uninstall are not owned.
+	if obj.GetAnnotations()[kube.ResourcePolicyAnno] != kube.KeepPolicy {
+		if err := controllerutil.SetControllerReference(bi, obj, r.Scheme); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+// removeOtherControllers removes the controller references of owners other
+// than bi from live, which can only have one controller, so that bi can take
+// it over.
+func removeOtherControllers(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, live *metav1.PartialObjectMetadata) error {
+	base := live.DeepCopy()
+	var refs []metav1.OwnerReference
+	for _, ref := range live.GetOwnerReferences() {
+		if ref.Controller == nil || !*ref.Controller || ref.UID == bi.UID {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == len(live.GetOwnerReferences()) {
+		return nil
+	}
+	live.SetOwnerReferences(refs)
+	return cl.Patch(ctx, live, client.MergeFrom(base))
+}
+
+// crdEstablished returns whether the Established condition of crd is true.
+func crdEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, c := range crd.Status.Conditions {
+		if c.Type == apiextensionsv1.Established {
+			return c.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// contentClient returns a client that manages the content of bi with the
+// identity of the clients of actionConfigGetter.
+func (r *BundleInstanceReconciler) contentClient(bi *rukpakv1alpha1.BundleInstance, warnings rest.WarningHandler) (client.Client, error) {
+	if r.ContentConfig == nil {
+		if r.serviceAccount(bi) != nil {
+			return nil, errors.New("service accounts are not supported by the provisioner")
+		}
+		return r.Client, nil
+	}
+	return client.New(r.contentRESTConfig(bi, warnings), client.Options{Scheme: r.Scheme, Mapper: r.RESTMapper()})
+}
+
+// contentRESTConfig returns the config of the clients that manage the content
+// of bi, which report the warnings of the API server to warnings, and
+// impersonate the ServiceAccount of bi, if any.
+func (r *BundleInstanceReconciler) contentRESTConfig(bi *rukpakv1alpha1.BundleInstance, warnings rest.WarningHandler) *rest.Config {
+	cfg := rest.CopyConfig(r.ContentConfig)
+	if sa := r.serviceAccount(bi); sa != nil {
+		cfg = util.ImpersonatedConfig(cfg, sa.Namespace, sa.Name)
+	}
+	cfg.WarningHandler = warnings
+	return cfg
+}
+
+// releaseNamespace returns the namespace in which the release of bi is stored,
+// and into which its namespace-scoped content without a namespace or a
+// target namespace is installed. BundleInstances that do not select a release
+// namespace keep the one that is recorded in their status, so that a change
+// of the default release namespace only applies to new BundleInstances.
+func (r *BundleInstanceReconciler) releaseNamespace(bi *rukpakv1alpha1.BundleInstance) string {
+	switch {
+	case bi.Spec.ReleaseNamespace != "":
+		return bi.Spec.ReleaseNamespace
+	case bi.Status.ReleaseNamespace != "":
+		return bi.Status.ReleaseNamespace
+	case bi.Status.Release != nil && bi.Status.Release.Namespace != "":
+		// The release was installed before its namespace was recorded.
+		return bi.Status.Release.Namespace
+	}
+	return r.ReleaseNamespace
+}
+
+// checkReleaseNamespace returns an error when bi selects a release namespace
+// that the provisioner does not allow, so that a tenant cannot have release
+// state written to namespaces that were not meant to receive it.
+func (r *BundleInstanceReconciler) checkReleaseNamespace(bi *rukpakv1alpha1.BundleInstance) error {
+	namespace := bi.Spec.ReleaseNamespace
+	if namespace == "" || namespace == r.ReleaseNamespace {
+		return nil
+	}
+	for _, allowed := range r.AllowedReleaseNamespaces {
+		if allowed == "*" || allowed == namespace {
+			return nil
+		}
+	}
+	return fmt.Errorf("release namespace %q is not allowed by the provisioner", namespace)
+}
+
+// checkServiceAccount returns an error when bi sets a ServiceAccount that the
+// RukpakConfig does not allow it to be installed as, so that the provisioner
+// does not impersonate ServiceAccounts that were not meant to install content.
+func (r *BundleInstanceReconciler) checkServiceAccount(ctx context.Context, bi *rukpakv1alpha1.BundleInstance) error {
+	sa := r.serviceAccount(bi)
+	if sa == nil {
+		return nil
+	}
+	config, err := util.GetRukpakConfig(ctx, r.Client)
+	if err != nil {
+		return fmt.Errorf("check service account %q: %w", sa, err)
+	}
+	for _, selector := range config.ServiceAccounts {
+		if selector.Namespace == sa.Namespace && (selector.Name == "" || selector.Name == sa.Name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("service account %q is not selected by the serviceAccounts of the RukpakConfig", sa)
+}
+
+// serviceAccount returns the key of the ServiceAccount that manages the
+// content of bi, or nil when the provisioner manages it with its own
+// permissions.
+func (r *BundleInstanceReconciler) serviceAccount(bi *rukpakv1alpha1.BundleInstance) *types.NamespacedName {
+	if bi.Spec.ServiceAccountName == "" {
+		return nil
+	}
+	namespace := bi.Spec.TargetNamespace
+	if namespace == "" {
+		namespace = r.releaseNamespace(bi)
+	}
+	return &types.NamespacedName{Namespace: namespace, Name: bi.Spec.ServiceAccountName}
+}
+
+// actionConfigGetter returns the ActionConfigGetter of bi. The clients that
+// manage its content report the warnings of the API server to warnings, and
+// impersonate the ServiceAccount of bi, if any, while its release state is
+// still stored by the provisioner. Warnings are discarded when the reconciler
+// has no ContentConfig.
+func (r *BundleInstanceReconciler) actionConfigGetter(bi *rukpakv1alpha1.BundleInstance, warnings rest.WarningHandler) helmclient.ActionConfigGetter {
+	if r.ContentConfig == nil {
+		return r.ActionConfigGetter
+	}
+	return r.contentActionConfigGetter(r.contentRESTConfig(bi, warnings))
+}
+
+// contentActionConfigGetter returns an ActionConfigGetter whose clients
+// manage content with cfg, while its release state is stored by the
+// provisioner.
+func (r *BundleInstanceReconciler) contentActionConfigGetter(cfg *rest.Config) helmclient.ActionConfigGetter {
+	return &contentConfigGetter{
+		release: r.ActionConfigGetter,
+		content: helmclient.NewActionConfigGetter(cfg, r.RESTMapper(), log.Log),
+	}
+}
+
+// actionClientFor returns the action client of bi, as configured by actionConfigGetter.
+func (r *BundleInstanceReconciler) actionClientFor(bi *rukpakv1alpha1.BundleInstance, warnings rest.WarningHandler) (helmclient.ActionInterface, error) {
+	if r.ContentConfig == nil {
+		if r.serviceAccount(bi) != nil {
+			return nil, errors.New("service accounts are not supported by the provisioner")
+		}
+		return r.ActionClientGetter.ActionClientFor(bi)
+	}
+	return helmclient.NewActionClientGetter(r.actionConfigGetter(bi, warnings)).ActionClientFor(bi)
+}
+
+// eventf records an event for bi, unless the reconciler has no Recorder.
+func (r *BundleInstanceReconciler) eventf(bi *rukpakv1alpha1.BundleInstance, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(bi, eventType, reason, messageFmt, args...)
+	}
+}
+
+// recordHealthTransition records an event when the Healthy condition of bi
+// becomes True or False, given its previous status. Failed health checks are
+// not recorded, as they do not reflect the health of the content.
+func (r *BundleInstanceReconciler) recordHealthTransition(bi *rukpakv1alpha1.BundleInstance, previous metav1.ConditionStatus) {
+	cond := meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeHealthy)
+	if cond == nil || cond.Status == metav1.ConditionUnknown || cond.Status == previous {
+		return
+	}
+	if cond.Status == metav1.ConditionTrue {
+		r.eventf(bi, corev1.EventTypeNormal, cond.Reason, "all installed objects are healthy")
+		return
+	}
+	r.eventf(bi, corev1.EventTypeWarning, cond.Reason, "%s", cond.Message)
+}
+
+// reportWarnings records an event for every warning collected by warnings
+// that is not yet reported in the status of bi, and reports the collected
+// warnings in its status.
+func (r *BundleInstanceReconciler) reportWarnings(bi *rukpakv1alpha1.BundleInstance, warnings *util.WarningCollector) {
+	collected := warnings.Warnings()
+	if r.Recorder != nil {
+		reported := sets.NewString(bi.Status.Warnings...)
+		for _, warning := range collected {
+			if !reported.Has(warning) {
+				r.Recorder.Event(bi, corev1.EventTypeWarning, rukpakv1alpha1.ReasonAPIWarning, warning)
+			}
+		}
+	}
+	bi.Status.Warnings = collected
+}
+
+// contentConfigGetter combines the release storage of one
+// ActionConfigGetter with the clients of another.
+type contentConfigGetter struct {
+	release helmclient.ActionConfigGetter
+	content helmclient.ActionConfigGetter
+}
+
+func (g *contentConfigGetter) ActionConfigFor(obj client.Object) (*action.Configuration, error) {
+	cfg, err := g.release.ActionConfigFor(obj)
+	if err != nil {
+		return nil, err
+	}
+	contentCfg, err := g.content.ActionConfigFor(obj)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RESTClientGetter = contentCfg.RESTClientGetter
+	cfg.KubeClient = contentCfg.KubeClient
+	return cfg, nil
+}
+
+// ensureInstallReport persists a signed install report for the given release
+// of the BundleInstance, unless a report for that release revision and bundle
+// digest has already been stored.
+func (r *BundleInstanceReconciler) ensureInstallReport(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, b *rukpakv1alpha1.Bundle, rel *release.Release) error {
+	if r.ReportStorage == nil || r.ReadOnly || rel == nil {
+		return nil
+	}
+	existing, err := r.ReportStorage.Load(ctx, bi)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if len(existing) == 1 {
+		if rpt, err := report.FromUnstructured(existing[0]); err == nil && rpt.Revision == rel.Version && rpt.Digest == b.Status.Digest {
+			return nil
+		}
+	}
+
+	installedAt := metav1.Now()
+	if rel.Info != nil && !rel.Info.LastDeployed.IsZero() {
+		installedAt = metav1.NewTime(rel.Info.LastDeployed.Time)
+	}
+	applier := r.Applier
+	if sa := r.serviceAccount(bi); sa != nil {
+		applier = util.ServiceAccountUsername(sa.Namespace, sa.Name)
+	}
+	rpt := report.New(bi, b, rel.Version, applier, installedAt)
+	if err := rpt.Sign(r.ReportSigningKey); err != nil {
+		return err
+	}
+	u, err := rpt.ToUnstructured()
+	if err != nil {
+		return err
+	}
+	log.FromContext(ctx).V(util.LogLevelDebug).Info("storing install report", "revision", rel.Version)
+	return r.ReportStorage.Store(ctx, bi, []client.Object{u})
+}
+
+// assessHealth reads the installed state of every object in objs whose kind
+// supports health assessment, and reports the per-object results and the
+// aggregated Healthy condition in the status of the BundleInstance.
+func (r *BundleInstanceReconciler) assessHealth(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, objs []client.Object) error {
+	var (
+		objectHealth []rukpakv1alpha1.ObjectHealth
+		unhealthy    []string
+	)
+	// Without watches, the installed objects are read from the API server.
+	get := r.watches.Get
+	if r.driftPolicy(bi) == rukpakv1alpha1.DriftPolicyIgnore {
+		get = r.Client.Get
+	}
+	for _, obj := range objs {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if !health.HasChecker(gvk.GroupKind()) {
+			continue
+		}
+		key, err := r.installedObjectKey(bi, obj)
+		if err != nil {
+			return err
+		}
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(gvk)
+		if err := get(ctx, key, live); err != nil {
+			return fmt.Errorf("get %s %q: %w", gvk.Kind, key, err)
+		}
+		result, err := health.Assess(live)
+		if err != nil {
+			return err
+		}
+		objectHealth = append(objectHealth, rukpakv1alpha1.ObjectHealth{
+			Group:     gvk.Group,
+			Version:   gvk.Version,
+			Kind:      gvk.Kind,
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Healthy:   result.Healthy,
+			Message:   result.Message,
+		})
+		if !result.Healthy {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s %q: %s", gvk.Kind, key, result.Message))
+		}
+	}
+	bi.Status.ObjectHealth = objectHealth
+
+	if len(unhealthy) > 0 {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Healthy(metav1.ConditionFalse, status.Unhealthy, strings.Join(unhealthy, "; ")))
+		return nil
+	}
+	meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Healthy(metav1.ConditionTrue, status.Healthy, ""))
+	return nil
+}
+
+// installedObjectKey returns the key of obj as installed on the cluster,
+// accounting for namespace-scoped objects that are defaulted into the
+// release namespace at install time.
+func (r *BundleInstanceReconciler) installedObjectKey(bi *rukpakv1alpha1.BundleInstance, obj client.Object) (types.NamespacedName, error) {
+	key := client.ObjectKeyFromObject(obj)
+	if key.Namespace != "" {
+		return key, nil
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	mapping, err := r.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return key, fmt.Errorf("determine scope of %s %q: %w", gvk, obj.GetName(), err)
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		key.Namespace = r.releaseNamespace(bi)
+	}
+	return key, nil
+}
+
+// reconcileRelease reconciles the objects of rel in chunks, resuming from the
+// progress recorded in the status of bi. It returns false, and records the
+// progress made, when the reconcile budget that started at start is exhausted
+// before all objects have been reconciled.
+func (r *BundleInstanceReconciler) reconcileRelease(cl helmclient.ActionInterface, bi *rukpakv1alpha1.BundleInstance, rel *release.Release, start time.Time) (bool, error) {
+	manifests := releaseutil.SplitManifests(rel.Manifest)
+	keys := make([]string, 0, len(manifests))
+	for k := range manifests {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	next := 0
+	if p := bi.Status.ReconcileProgress; p != nil && p.Revision == rel.Version && p.Total == len(keys) {
+		next = p.Reconciled
+	}
+	for next < len(keys) {
+		end := next + reconcileChunkSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		var chunk strings.Builder
+		for _, k := range keys[next:end] {
+			chunk.WriteString("---\n")
+			chunk.WriteString(manifests[k])
+			chunk.WriteString("\n")
+		}
+		partial := *rel
+		partial.Manifest = chunk.String()
+		if err := cl.Reconcile(&partial); err != nil {
+			return false, err
+		}
+		next = end
+
+		if next < len(keys) && r.ReconcileBudget > 0 && time.Since(start) >= r.ReconcileBudget {
+			bi.Status.ReconcileProgress = &rukpakv1alpha1.ReconcileProgress{
+				Revision:   rel.Version,
+				Reconciled: next,
+				Total:      len(keys),
+			}
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// rollback restores the release of the BundleInstance to its last successfully
+// deployed revision after a failed upgrade, and reports the outcome in the
+// RolledBack condition.
+func (r *BundleInstanceReconciler) rollback(cl helmclient.ActionInterface, bi *rukpakv1alpha1.BundleInstance, upgradeErr error, warnings rest.WarningHandler) {
+	revision, err := r.rollbackToLastDeployed(cl, bi, warnings)
+	if err != nil {
+		cond := status.For(bi).RolledBack(metav1.ConditionFalse, status.RollbackFailed, fmt.Sprintf("rollback failed: %v: original upgrade error: %v", err, upgradeErr))
+		meta.SetStatusCondition(&bi.Status.Conditions, cond)
+		r.eventf(bi, corev1.EventTypeWarning, cond.Reason, "%s", cond.Message)
+		return
+	}
+	cond := status.For(bi).RolledBack(metav1.ConditionTrue, status.RollbackSucceeded, fmt.Sprintf("rolled back to the contents of revision %d after upgrade error: %v", revision, upgradeErr))
+	meta.SetStatusCondition(&bi.Status.Conditions, cond)
+	r.eventf(bi, corev1.EventTypeNormal, cond.Reason, "%s", cond.Message)
+}
+
+func (r *BundleInstanceReconciler) rollbackToLastDeployed(cl helmclient.ActionInterface, bi *rukpakv1alpha1.BundleInstance, warnings rest.WarningHandler) (int, error) {
+	current, err := cl.Get(bi.Name)
+	if err != nil {
+		return 0, fmt.Errorf("get current release: %w", err)
+	}
+	if current.Info.Status == release.StatusDeployed {
+		// The action client already rolled back the failed upgrade
+		// as part of the upgrade itself.
+		return current.Version, nil
+	}
+
+	bi.SetNamespace(r.releaseNamespace(bi))
+	cfg, err := r.actionConfigGetter(bi, warnings).ActionConfigFor(bi)
+	bi.SetNamespace("")
+	if err != nil {
+		return 0, fmt.Errorf("get action config: %w", err)
+	}
+	history, err := action.NewHistory(cfg).Run(bi.Name)
+	if err != nil {
+		return 0, fmt.Errorf("get release history: %w", err)
+	}
+	var lastDeployed *release.Release
+	for _, rel := range history {
+		if rel.Version == current.Version {
+			continue
+		}
+		if rel.Info.Status != release.StatusDeployed && rel.Info.Status != release.StatusSuperseded {
+			continue
+		}
+		if lastDeployed == nil || rel.Version > lastDeployed.Version {
+			lastDeployed = rel
+		}
+	}
+	if lastDeployed == nil {
+		return 0, errors.New("no previously deployed release found")
+	}
+
+	rollback := action.NewRollback(cfg)
+	rollback.Version = lastDeployed.Version
+	if err := rollback.Run(bi.Name); err != nil {
+		return 0, err
+	}
+	return lastDeployed.Version, nil
+}
+
+// pendingChanges returns the object-level diff from the objects of rel, which
+// is nil when nothing is installed yet, to the templates of chrt.
+func pendingChanges(rel *release.Release, chrt *chart.Chart) (*rukpakv1alpha1.PendingChanges, error) {
+	d, err := releaseDiff(rel, chrt)
+	if err != nil {
+		return nil, err
+	}
+	changes := &rukpakv1alpha1.PendingChanges{}
+	for _, ref := range d.Added {
+		changes.Added = append(changes.Added, objectReference(ref))
+	}
+	for _, ref := range d.Removed {
+		changes.Removed = append(changes.Removed, objectReference(ref))
+	}
+	for _, od := range d.Changed {
+		changes.Changed = append(changes.Changed, objectReference(od.ObjectRef))
+	}
+	return changes, nil
+}
 
-	ActionClientGetter helmclient.ActionClientGetter
-	BundleStorage      storage.Storage
-	ReleaseNamespace   string
+// releaseDiff returns the diff from the objects of rel, which is nil when
+// nothing is installed yet, to the templates of chrt.
+func releaseDiff(rel *release.Release, chrt *chart.Chart) (content.BundleDiff, error) {
+	var from, to []unstructured.Unstructured
+	if rel != nil {
+		for name, manifest := range releaseutil.SplitManifests(rel.Manifest) {
+			obj := unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(manifest), &obj.Object); err != nil {
+				return content.BundleDiff{}, fmt.Errorf("parse release manifest %q: %w", name, err)
+			}
+			from = append(from, obj)
+		}
+	}
+	for _, tmpl := range chrt.Templates {
+		obj := unstructured.Unstructured{}
+		if err := yaml.Unmarshal(tmpl.Data, &obj.Object); err != nil {
+			return content.BundleDiff{}, fmt.Errorf("parse chart template %q: %w", tmpl.Name, err)
+		}
+		to = append(to, obj)
+	}
+	return content.Diff(from, to), nil
+}
 
-	dynamicWatchMutex sync.RWMutex
-	dynamicWatchGVKs  map[schema.GroupVersionKind]struct{}
+func objectReference(ref content.ObjectRef) rukpakv1alpha1.ObjectReference {
+	return rukpakv1alpha1.ObjectReference{
+		Group:     ref.Group,
+		Kind:      ref.Kind,
+		Namespace: ref.Namespace,
+		Name:      ref.Name,
+	}
 }
 
-//+kubebuilder:rbac:groups=core.rukpak.io,resources=bundleinstances,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=core.rukpak.io,resources=bundleinstances/status,verbs=get;update;patch
-//+kubebuilder:rbac:groups=core.rukpak.io,resources=bundleinstances/finalizers,verbs=update
-//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
-//+kubebuilder:rbac:groups=operators.coreos.com,resources=operatorgroups,verbs=get;list;watch
-//+kubebuilder:rbac:groups=*,resources=*,verbs=*
+// recordOutcome records the outcome of the reconcile of bi that was attempted
+// at attempted, and failed with err unless it is nil, in its status.
+func recordOutcome(bi *rukpakv1alpha1.BundleInstance, attempted metav1.Time, err error) {
+	if err != nil {
+		bi.Status.LastFailedAt = &attempted
+		bi.Status.ConsecutiveFailures++
+		bi.Status.TotalFailures++
+		return
+	}
+	if meta.IsStatusConditionTrue(bi.Status.Conditions, rukpakv1alpha1.TypeInstalled) {
+		bi.Status.LastSuccessfulInstallAt = &attempted
+		bi.Status.ConsecutiveFailures = 0
+	}
+}
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the BundleInstance object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
-func (r *BundleInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	l := log.FromContext(ctx)
-	l.V(1).Info("starting reconciliation")
-	defer l.V(1).Info("ending reconciliation")
+// patchStatus applies the status of bi, unless it is unchanged from previous.
+// The Ready condition of bi is derived from its other conditions first.
+func (r *BundleInstanceReconciler) patchStatus(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, previous *rukpakv1alpha1.BundleInstanceStatus) {
+	meta.SetStatusCondition(&bi.Status.Conditions, readyCondition(bi))
+	if !statusChanged(previous, &bi.Status) {
+		return
+	}
+	bi = bi.DeepCopy()
+	bi.ObjectMeta.ManagedFields = nil
+	if err := r.Status().Patch(ctx, bi, client.Apply, client.FieldOwner(plainBundleProvisionerID)); err != nil {
+		log.FromContext(ctx).Error(err, "failed to patch status")
+	}
+}
 
-	bi := &rukpakv1alpha1.BundleInstance{}
-	if err := r.Get(ctx, req.NamespacedName, bi); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+// The conditions that the Ready condition of a BundleInstance is derived
+// from: the conditions it requires to be True, and the conditions of the
+// operations that keep it from being ready while they are True.
+var (
+	readyRequiredConditions = []string{rukpakv1alpha1.TypeHasValidBundle, rukpakv1alpha1.TypeInstalled, rukpakv1alpha1.TypeHealthy}
+	readyBlockingConditions = []string{rukpakv1alpha1.TypeUninstalling, rukpakv1alpha1.TypePivoting}
+)
+
+// readyCondition returns the Ready condition of bi, in the kstatus sense: it
+// is True once the content of its valid bundle is installed and healthy, and
+// no pivot or uninstall is in progress.
+func readyCondition(bi *rukpakv1alpha1.BundleInstance) metav1.Condition {
+	return util.ReadyCondition(bi, bi.Status.Conditions, readyRequiredConditions, readyBlockingConditions)
+}
+
+// statusChanged returns whether current differs from previous in more than
+// the times of the last reconcile and install, which every reconcile advances.
+// Those times alone are only reported once previous is older than
+// statusHeartbeatInterval, so that reconciles that change nothing do not
+// write the status.
+func statusChanged(previous, current *rukpakv1alpha1.BundleInstanceStatus) bool {
+	if (previous.LastAttemptedAt == nil) != (current.LastAttemptedAt == nil) {
+		return true
 	}
-	defer func() {
-		bi := bi.DeepCopy()
-		bi.ObjectMeta.ManagedFields = nil
-		if err := r.Status().Patch(ctx, bi, client.Apply, client.FieldOwner(plainBundleProvisionerID)); err != nil {
-			l.Error(err, "failed to patch status")
+	if current.LastAttemptedAt != nil && current.LastAttemptedAt.Sub(previous.LastAttemptedAt.Time) >= statusHeartbeatInterval {
+		return true
+	}
+	previous, current = previous.DeepCopy(), current.DeepCopy()
+	previous.LastAttemptedAt, current.LastAttemptedAt = nil, nil
+	if previous.LastSuccessfulInstallAt != nil && current.LastSuccessfulInstallAt != nil {
+		previous.LastSuccessfulInstallAt, current.LastSuccessfulInstallAt = nil, nil
+	}
+	return !equality.Semantic.DeepEqual(previous, current)
+}
+
+// finalize applies the deletion policy of the deleted bi, and removes its
+// finalizer once the installed objects that are to be deleted are gone. The
+// progress of the uninstall is reported in the Uninstalling condition.
+func (r *BundleInstanceReconciler) finalize(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, previous *rukpakv1alpha1.BundleInstanceStatus) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+	if err := r.checkServiceAccount(ctx, bi); err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Uninstalling(metav1.ConditionTrue, status.UninstallFailed, err.Error()))
+		r.patchStatus(ctx, bi, previous)
+		return ctrl.Result{}, err
+	}
+	// The default deletion policy of the RukpakConfig is applied as if bi set
+	// it. The spec of the BundleInstance itself is left unchanged.
+	if bi.Spec.DeletionPolicy == "" {
+		config, err := util.GetRukpakConfig(ctx, r.Client)
+		if err != nil {
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Uninstalling(metav1.ConditionTrue, status.UninstallFailed, err.Error()))
+			r.patchStatus(ctx, bi, previous)
+			return ctrl.Result{}, err
 		}
-	}()
+		bi.Spec.DeletionPolicy = config.DefaultDeletionPolicy
+	}
+	remaining, err := r.applyDeletionPolicy(ctx, bi)
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Uninstalling(metav1.ConditionTrue, status.UninstallFailed, err.Error()))
+		r.patchStatus(ctx, bi, previous)
+		return ctrl.Result{}, fmt.Errorf("apply deletion policy: %w", err)
+	}
+	if len(remaining) > 0 {
+		condition := status.For(bi).Uninstalling(metav1.ConditionTrue, status.UninstallInProgress, fmt.Sprintf("waiting for %d objects to be deleted: %s", len(remaining), strings.Join(remaining, ", ")))
+		if waited := time.Since(bi.DeletionTimestamp.Time); r.UninstallTimeout > 0 && waited > r.UninstallTimeout {
+			condition.Reason = rukpakv1alpha1.ReasonUninstallFailed
+			condition.Message = fmt.Sprintf("timed out after %s %s", r.UninstallTimeout, condition.Message)
+		}
+		meta.SetStatusCondition(&bi.Status.Conditions, condition)
+		r.patchStatus(ctx, bi, previous)
+		l.V(util.LogLevelDebug).Info("waiting for installed objects to be deleted", "remaining", len(remaining))
+		return ctrl.Result{RequeueAfter: uninstallPollInterval}, nil
+	}
 
-	b := &rukpakv1alpha1.Bundle{}
-	if err := r.Get(ctx, types.NamespacedName{Name: bi.Spec.BundleName}, b); err != nil {
-		bundleStatus := metav1.ConditionUnknown
-		if apierrors.IsNotFound(err) {
-			bundleStatus = metav1.ConditionFalse
+	r.watches.Release(ctx, bi.Name)
+	patch := client.MergeFromWithOptions(bi.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.RemoveFinalizer(bi, deletionPolicyFinalizer)
+	return ctrl.Result{}, client.IgnoreNotFound(r.Patch(ctx, bi, patch))
+}
+
+// applyDeletionPolicy applies the deletion policy of bi to its installed
+// objects and its release. It returns the installed objects that are still
+// being deleted, until which the deletion policy has not been applied
+// completely.
+func (r *BundleInstanceReconciler) applyDeletionPolicy(ctx context.Context, bi *rukpakv1alpha1.BundleInstance) ([]string, error) {
+	if bi.Spec.Engine == rukpakv1alpha1.EngineServerSideApply {
+		return r.uninstallInventory(ctx, bi)
+	}
+	bi.SetNamespace(r.releaseNamespace(bi))
+	cfg, err := r.actionConfigGetter(bi, nil).ActionConfigFor(bi)
+	bi.SetNamespace("")
+	if err != nil {
+		return nil, fmt.Errorf("get action config: %w", err)
+	}
+	rel, err := cfg.Releases.Last(bi.Name)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get current release: %w", err)
+	}
+
+	switch bi.Spec.DeletionPolicy {
+	case rukpakv1alpha1.DeletionPolicyOrphan, rukpakv1alpha1.DeletionPolicyRetain:
+		if err := disownObjects(cfg, bi.UID, rel.Manifest); err != nil {
+			return nil, err
+		}
+		if bi.Spec.DeletionPolicy == rukpakv1alpha1.DeletionPolicyRetain {
+			return nil, r.disownReleases(bi)
+		}
+	default:
+		retainCRDs := bi.Spec.RetainCRDs == nil || *bi.Spec.RetainCRDs
+		if remaining, err := uninstall(cfg, bi.UID, rel, retainCRDs); err != nil || len(remaining) > 0 {
+			return remaining, err
 		}
-		meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-			Type:    rukpakv1alpha1.TypeHasValidBundle,
-			Status:  bundleStatus,
-			Reason:  rukpakv1alpha1.ReasonBundleLookupFailed,
-			Message: err.Error(),
-		})
-		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	desiredObjects, err := r.loadBundle(ctx, bi)
+	history, err := cfg.Releases.History(bi.Name)
 	if err != nil {
-		var bnuErr *errBundleNotUnpacked
-		if errors.As(err, &bnuErr) {
-			reason := fmt.Sprintf("BundleUnpack%s", b.Status.Phase)
-			if b.Status.Phase == rukpakv1alpha1.PhaseUnpacking {
-				reason = "BundleUnpackRunning"
-			}
-			meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-				Type:   rukpakv1alpha1.TypeInstalled,
-				Status: metav1.ConditionFalse,
-				Reason: reason,
-			})
-			return ctrl.Result{}, nil
+		return nil, fmt.Errorf("get release history: %w", err)
+	}
+	for _, rel := range history {
+		if _, err := cfg.Releases.Delete(rel.Name, rel.Version); err != nil {
+			return nil, fmt.Errorf("delete release revision %d: %w", rel.Version, err)
 		}
-		meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-			Type:    rukpakv1alpha1.TypeHasValidBundle,
-			Status:  metav1.ConditionFalse,
-			Reason:  rukpakv1alpha1.ReasonBundleLoadFailed,
-			Message: err.Error(),
-		})
-		return ctrl.Result{}, err
 	}
+	return nil, nil
+}
 
-	chrt := &chart.Chart{
-		Metadata: &chart.Metadata{},
+// uninstall deletes the objects of rel in the uninstall order of helm, except
+// that the custom resources of the CustomResourceDefinitions in rel are
+// deleted first, while the controllers that finalize them are still
+// installed. Each group of objects is deleted once the previous one is gone,
+// and the objects that are still being deleted are returned. The
+// CustomResourceDefinitions are deleted last, or only disowned by the
+// BundleInstance with uid when retainCRDs is set.
+func uninstall(cfg *action.Configuration, uid types.UID, rel *release.Release, retainCRDs bool) ([]string, error) {
+	_, files, err := releaseutil.SortManifests(releaseutil.SplitManifests(rel.Manifest), nil, releaseutil.UninstallOrder)
+	if err != nil {
+		return nil, fmt.Errorf("sort release manifests: %w", err)
 	}
-	for _, obj := range desiredObjects {
-		jsonData, err := yaml.Marshal(obj)
-		if err != nil {
-			meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-				Type:    rukpakv1alpha1.TypeInvalidBundleContent,
-				Status:  metav1.ConditionTrue,
-				Reason:  rukpakv1alpha1.ReasonReadingContentFailed,
-				Message: err.Error(),
-			})
-			return ctrl.Result{}, err
+	bundledKinds := map[schema.GroupKind]bool{}
+	for _, f := range files {
+		if manifestGroupKind(f) != apiextensionsv1.Kind("CustomResourceDefinition") {
+			continue
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := yaml.Unmarshal([]byte(f.Content), crd); err != nil {
+			return nil, fmt.Errorf("parse CRD %q: %w", f.Name, err)
+		}
+		bundledKinds[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = true
+	}
+	var crds, crs, others []string
+	for _, f := range files {
+		switch gk := manifestGroupKind(f); {
+		case gk == apiextensionsv1.Kind("CustomResourceDefinition"):
+			crds = append(crds, f.Content)
+		case bundledKinds[gk]:
+			crs = append(crs, f.Content)
+		default:
+			others = append(others, f.Content)
 		}
-		hash := sha256.Sum256(jsonData)
-		chrt.Templates = append(chrt.Templates, &chart.File{
-			Name: fmt.Sprintf("object-%x.yaml", hash[0:8]),
-			Data: jsonData,
-		})
 	}
 
-	bi.SetNamespace(r.ReleaseNamespace)
-	cl, err := r.ActionClientGetter.ActionClientFor(bi)
-	bi.SetNamespace("")
+	if remaining, err := deleteObjects(cfg, crs); err != nil || len(remaining) > 0 {
+		return remaining, err
+	}
+	if remaining, err := deleteObjects(cfg, others); err != nil || len(remaining) > 0 {
+		return remaining, err
+	}
+	if retainCRDs {
+		return nil, disownObjects(cfg, uid, strings.Join(crds, "\n---\n"))
+	}
+	return deleteObjects(cfg, crds)
+}
+
+func manifestGroupKind(m releaseutil.Manifest) schema.GroupKind {
+	if m.Head == nil {
+		return schema.GroupKind{}
+	}
+	gv, err := schema.ParseGroupVersion(m.Head.Version)
 	if err != nil {
-		meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-			Type:    rukpakv1alpha1.TypeInstalled,
-			Status:  metav1.ConditionFalse,
-			Reason:  rukpakv1alpha1.ReasonErrorGettingClient,
-			Message: err.Error(),
-		})
-		return ctrl.Result{}, err
+		return schema.GroupKind{}
 	}
+	return schema.GroupKind{Group: gv.Group, Kind: m.Head.Kind}
+}
 
-	rel, state, err := r.getReleaseState(cl, bi, chrt)
+// deleteObjects deletes the objects in manifests, and returns the ones that
+// still exist, typically because they are held by finalizers.
+func deleteObjects(cfg *action.Configuration, manifests []string) ([]string, error) {
+	if len(manifests) == 0 {
+		return nil, nil
+	}
+	infos, err := cfg.KubeClient.Build(bytes.NewBufferString(strings.Join(manifests, "\n---\n")), false)
 	if err != nil {
-		meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-			Type:    rukpakv1alpha1.TypeInstalled,
-			Status:  metav1.ConditionFalse,
-			Reason:  rukpakv1alpha1.ReasonErrorGettingReleaseState,
-			Message: err.Error(),
-		})
-		return ctrl.Result{}, err
+		return nil, fmt.Errorf("build release objects: %w", err)
+	}
+	if _, errs := cfg.KubeClient.Delete(infos); len(errs) > 0 {
+		return nil, fmt.Errorf("delete release objects: %w", utilerrors.NewAggregate(errs))
+	}
+	var remaining []string
+	for _, info := range infos {
+		_, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get %s %q: %w", info.Mapping.GroupVersionKind.Kind, info.Name, err)
+		}
+		remaining = append(remaining, fmt.Sprintf("%s %s", info.Mapping.GroupVersionKind.Kind, types.NamespacedName{Namespace: info.Namespace, Name: info.Name}))
 	}
+	return remaining, nil
+}
 
-	switch state {
-	case stateNeedsInstall:
-		_, err = cl.Install(bi.Name, r.ReleaseNamespace, chrt, nil, func(install *action.Install) error {
-			install.CreateNamespace = false
-			return nil
-		})
+// disownObjects removes the owner references to the BundleInstance with uid
+// from the objects in manifest, so that they are not garbage collected along
+// with it.
+func disownObjects(cfg *action.Configuration, uid types.UID, manifest string) error {
+	if strings.TrimSpace(manifest) == "" {
+		return nil
+	}
+	infos, err := cfg.KubeClient.Build(bytes.NewBufferString(manifest), false)
+	if err != nil {
+		return fmt.Errorf("build release objects: %w", err)
+	}
+	for _, info := range infos {
+		helper := resource.NewHelper(info.Client, info.Mapping)
+		obj, err := helper.Get(info.Namespace, info.Name)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
 		if err != nil {
-			meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-				Type:    rukpakv1alpha1.TypeInstalled,
-				Status:  metav1.ConditionFalse,
-				Reason:  rukpakv1alpha1.ReasonInstallFailed,
-				Message: err.Error(),
-			})
-			return ctrl.Result{}, err
+			return fmt.Errorf("get %s %q: %w", info.Mapping.GroupVersionKind.Kind, info.Name, err)
 		}
-	case stateNeedsUpgrade:
-		_, err = cl.Upgrade(bi.Name, r.ReleaseNamespace, chrt, nil)
+		accessor, err := meta.Accessor(obj)
 		if err != nil {
-			meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-				Type:    rukpakv1alpha1.TypeInstalled,
-				Status:  metav1.ConditionFalse,
-				Reason:  rukpakv1alpha1.ReasonUpgradeFailed,
-				Message: err.Error(),
-			})
-			return ctrl.Result{}, err
+			return err
 		}
-	case stateUnchanged:
-		if err := cl.Reconcile(rel); err != nil {
-			meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-				Type:    rukpakv1alpha1.TypeInstalled,
-				Status:  metav1.ConditionFalse,
-				Reason:  rukpakv1alpha1.ReasonReconcileFailed,
-				Message: err.Error(),
-			})
-			return ctrl.Result{}, err
+		refs := accessor.GetOwnerReferences()
+		kept := []metav1.OwnerReference{}
+		for _, ref := range refs {
+			if ref.UID != uid {
+				kept = append(kept, ref)
+			}
+		}
+		if len(kept) == len(refs) {
+			continue
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"ownerReferences": kept},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := helper.Patch(info.Namespace, info.Name, types.MergePatchType, patch, nil); err != nil {
+			return fmt.Errorf("remove owner reference from %s %q: %w", info.Mapping.GroupVersionKind.Kind, info.Name, err)
 		}
+	}
+	return nil
+}
+
+// disownReleases removes the owner references to bi from the Secrets or
+// ConfigMaps that store the revisions of its release, which the storage
+// drivers set so that the release is deleted along with bi. The objects are
+// updated with the clients of the provisioner rather than those of the service
+// account of bi, as the provisioner is the one that manages them.
+func (r *BundleInstanceReconciler) disownReleases(bi *rukpakv1alpha1.BundleInstance) error {
+	namespace := r.releaseNamespace(bi)
+	bi.SetNamespace(namespace)
+	cfg, err := r.ActionConfigGetter.ActionConfigFor(bi)
+	bi.SetNamespace("")
+	if err != nil {
+		return fmt.Errorf("get action config: %w", err)
+	}
+	var kind string
+	switch cfg.Releases.Name() {
+	case driver.SecretsDriverName, driver.ConfigMapsDriverName:
+		kind = cfg.Releases.Name()
 	default:
-		return ctrl.Result{}, fmt.Errorf("unexpected release state %q", state)
+		return nil
+	}
+	history, err := cfg.Releases.History(bi.Name)
+	if err != nil {
+		return fmt.Errorf("get release history: %w", err)
+	}
+	manifests := make([]string, 0, len(history))
+	for _, rel := range history {
+		manifests = append(manifests, fmt.Sprintf("apiVersion: v1\nkind: %s\nmetadata:\n  name: %s\n  namespace: %s\n", kind, releaseStorageName(rel), namespace))
 	}
+	return disownObjects(cfg, bi.UID, strings.Join(manifests, "---\n"))
+}
 
-	for _, obj := range desiredObjects {
-		uMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
-		if err != nil {
-			meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-				Type:    rukpakv1alpha1.TypeInstalled,
-				Status:  metav1.ConditionFalse,
-				Reason:  rukpakv1alpha1.ReasonCreateDynamicWatchFailed,
-				Message: err.Error(),
-			})
-			return ctrl.Result{}, err
-		}
+// releaseStorageName returns the name of the object in which the Secret and
+// ConfigMap storage drivers store the revision rel.
+func releaseStorageName(rel *release.Release) string {
+	return fmt.Sprintf("sh.helm.release.v1.%s.v%d", rel.Name, rel.Version)
+}
 
-		u := &unstructured.Unstructured{Object: uMap}
-		if err := func() error {
-			r.dynamicWatchMutex.Lock()
-			defer r.dynamicWatchMutex.Unlock()
-
-			_, isWatched := r.dynamicWatchGVKs[u.GroupVersionKind()]
-			if !isWatched {
-				if err := r.Controller.Watch(
-					&source.Kind{Type: u},
-					&handler.EnqueueRequestForOwner{OwnerType: bi, IsController: true},
-					helmpredicate.DependentPredicateFuncs()); err != nil {
-					return err
-				}
-				r.dynamicWatchGVKs[u.GroupVersionKind()] = struct{}{}
-			}
-			return nil
-		}(); err != nil {
-			meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-				Type:    rukpakv1alpha1.TypeInstalled,
-				Status:  metav1.ConditionFalse,
-				Reason:  rukpakv1alpha1.ReasonCreateDynamicWatchFailed,
-				Message: err.Error(),
-			})
-			return ctrl.Result{}, err
-		}
+// errInstallTimedOut is returned by runInstall when an install or upgrade
+// does not complete in time.
+var errInstallTimedOut = errors.New("install timed out")
+
+// runInstall runs install, an install or upgrade of the release of bi, with
+// cl. With an InstallTimeout, it runs install with a client of its own whose
+// requests to manage the content of bi are cancelled once the InstallTimeout
+// passes, so that the install fails on its pending request rather than
+// running on, and Helm records its release as failed rather than pending, as
+// the release state is still recorded with cl. errInstallTimedOut is then
+// returned. The content is managed with cl when the reconciler has no
+// ContentConfig, and only the waits of Helm are bounded.
+func (r *BundleInstanceReconciler) runInstall(ctx context.Context, cl helmclient.ActionInterface, bi *rukpakv1alpha1.BundleInstance, warnings rest.WarningHandler, install func(helmclient.ActionInterface) (*release.Release, error)) (*release.Release, error) {
+	if r.InstallTimeout <= 0 || r.ContentConfig == nil {
+		return install(cl)
 	}
-	meta.SetStatusCondition(&bi.Status.Conditions, metav1.Condition{
-		Type:   rukpakv1alpha1.TypeInstalled,
-		Status: metav1.ConditionTrue,
-		Reason: rukpakv1alpha1.ReasonInstallationSucceeded,
+	ctx, cancel := context.WithTimeout(ctx, r.InstallTimeout)
+	defer cancel()
+	cfg := r.contentRESTConfig(bi, warnings)
+	cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &contextRoundTripper{ctx: ctx, next: rt}
 	})
-	bi.Status.InstalledBundleName = bi.Spec.BundleName
-	return ctrl.Result{}, nil
+	bi.SetNamespace(r.releaseNamespace(bi))
+	timed, err := helmclient.NewActionClientGetter(r.contentActionConfigGetter(cfg)).ActionClientFor(bi)
+	bi.SetNamespace("")
+	if err != nil {
+		return nil, err
+	}
+	rel, err := install(timed)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, errInstallTimedOut
+	}
+	return rel, err
+}
+
+// contextRoundTripper sends the requests of next with ctx, so that they are
+// cancelled once ctx is done.
+type contextRoundTripper struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+func (t *contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(req.WithContext(t.ctx))
+}
+
+// installFailedCondition returns a failing Installed condition for err. When
+// err was caused by the provisioner lacking permissions for some of the
+// bundle content, the condition explains how those permissions can be granted.
+func installFailedCondition(bi *rukpakv1alpha1.BundleInstance, reason status.InstalledReason, err error) metav1.Condition {
+	c := status.For(bi).Installed(metav1.ConditionFalse, reason, err.Error())
+	if isForbidden(err) {
+		c.Reason = string(status.InsufficientPermissions)
+		c.Message = fmt.Sprintf("%v: grant the provisioner the missing permissions by creating a ClusterRole labeled %s=true", err, aggregateToProvisionerLabel)
+	}
+	return c
+}
+
+// isForbidden returns true if err was caused by an API request being
+// forbidden. Errors returned by helm do not always wrap the underlying API
+// error, so the error message is inspected as well.
+func isForbidden(err error) bool {
+	return apierrors.IsForbidden(err) || strings.Contains(err.Error(), "is forbidden")
 }
 
 type releaseState string
@@ -281,18 +1981,26 @@ const (
 	stateError        releaseState = "Error"
 )
 
-func (r *BundleInstanceReconciler) getReleaseState(cl helmclient.ActionInterface, obj metav1.Object, chrt *chart.Chart) (*release.Release, releaseState, error) {
-	currentRelease, err := cl.Get(obj.GetName())
+func (r *BundleInstanceReconciler) getReleaseState(ctx context.Context, cl helmclient.ActionInterface, bi *rukpakv1alpha1.BundleInstance, chrt *chart.Chart) (*release.Release, releaseState, error) {
+	currentRelease, err := cl.Get(bi.Name)
 	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
 		return nil, stateError, err
 	}
 	if errors.Is(err, driver.ErrReleaseNotFound) {
 		return nil, stateNeedsInstall, nil
 	}
-	desiredRelease, err := cl.Upgrade(obj.GetName(), r.ReleaseNamespace, chrt, nil, func(upgrade *action.Upgrade) error {
+	// A deployed release of a chart with the same hash has the manifest that
+	// a dry-run upgrade would render, so the dry-run is skipped.
+	if currentRelease.Info.Status == release.StatusDeployed && chartHash(currentRelease.Chart) != "" && chartHash(currentRelease.Chart) == chartHash(chrt) {
+		log.FromContext(ctx).V(util.LogLevelTrace).Info("skipped dry-run upgrade of unchanged chart")
+		return currentRelease, stateUnchanged, nil
+	}
+	_, span := tracing.Start(ctx, "BundleInstance.HelmDryRun")
+	desiredRelease, err := cl.Upgrade(bi.Name, r.releaseNamespace(bi), chrt, nil, func(upgrade *action.Upgrade) error {
 		upgrade.DryRun = true
 		return nil
 	})
+	tracing.End(span, err)
 	if err != nil {
 		return currentRelease, stateError, err
 	}
@@ -304,6 +2012,33 @@ func (r *BundleInstanceReconciler) getReleaseState(cl helmclient.ActionInterface
 	return currentRelease, stateUnchanged, nil
 }
 
+// templateName returns the name of the chart template of obj, without its
+// extension, which is made of its kind, group, namespace and name, such as
+// "deployment.apps_my-namespace_my-deployment". Its version is left out,
+// so that the template keeps its name when the object changes versions.
+func templateName(obj client.Object) string {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	parts := []string{strings.ToLower(gvk.Kind)}
+	if gvk.Group != "" {
+		parts[0] += "." + gvk.Group
+	}
+	if obj.GetNamespace() != "" {
+		parts = append(parts, obj.GetNamespace())
+	}
+	// Slashes would nest the template in a directory.
+	parts = append(parts, strings.ReplaceAll(obj.GetName(), "/", "-"))
+	return strings.Join(parts, "_")
+}
+
+// chartHash returns the hash that chrt is annotated with by renderChart, or an
+// empty hash when it is not annotated.
+func chartHash(chrt *chart.Chart) string {
+	if chrt == nil || chrt.Metadata == nil {
+		return ""
+	}
+	return chrt.Metadata.Annotations[chartHashAnnotation]
+}
+
 type errBundleNotUnpacked struct {
 	currentPhase string
 }
@@ -316,7 +2051,9 @@ func (err errBundleNotUnpacked) Error() string {
 	return fmt.Sprintf("%s, current phase=%s", baseError, err.currentPhase)
 }
 
-func (r *BundleInstanceReconciler) loadBundle(ctx context.Context, bi *rukpakv1alpha1.BundleInstance) ([]client.Object, error) {
+func (r *BundleInstanceReconciler) loadBundle(ctx context.Context, bi *rukpakv1alpha1.BundleInstance) (_ []client.Object, err error) {
+	ctx, span := tracing.Start(ctx, "BundleInstance.LoadBundle")
+	defer func() { tracing.End(span, err) }()
 	b := &rukpakv1alpha1.Bundle{}
 	if err := r.Get(ctx, types.NamespacedName{Name: bi.Spec.BundleName}, b); err != nil {
 		return nil, fmt.Errorf("get bundle %q: %w", bi.Spec.BundleName, err)
@@ -330,28 +2067,202 @@ func (r *BundleInstanceReconciler) loadBundle(ctx context.Context, bi *rukpakv1a
 		return nil, fmt.Errorf("load bundle objects: %w", err)
 	}
 
+	var vars map[string]string
+	if bi.Spec.SubstituteVariables {
+		vars = substitutionVariables(bi, r.releaseNamespace(bi))
+	}
 	objs := make([]client.Object, 0, len(objects))
 	for _, obj := range objects {
 		obj := obj
+		if vars != nil {
+			if err := manifests.Substitute(&obj, vars); err != nil {
+				return nil, fmt.Errorf("substitute variables: %w", err)
+			}
+		}
 		obj.SetLabels(util.MergeMaps(obj.GetLabels(), map[string]string{
 			"core.rukpak.io/owner-kind": "BundleInstance",
 			"core.rukpak.io/owner-name": bi.Name,
 		}))
 		objs = append(objs, &obj)
 	}
+	if bi.Spec.TargetNamespace != "" {
+		if err := setTargetNamespace(r.RESTMapper(), objs, bi.Spec.TargetNamespace); err != nil {
+			return nil, fmt.Errorf("set target namespace: %w", err)
+		}
+	}
 	return objs, nil
 }
 
+// renderChart renders objs, loaded from a bundle with bundleDigest, as the
+// templates of a chart, which is annotated with the hash of its templates.
+// Templates are named by the identity of their objects, so that the manifests
+// of successive releases only differ by the objects that changed.
+func (r *BundleInstanceReconciler) renderChart(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, objs []client.Object, bundleDigest string) (_ *chart.Chart, err error) {
+	_, span := tracing.Start(ctx, "BundleInstance.Render", tracing.AttributeObjects.Int(len(objs)))
+	defer func() { tracing.End(span, err) }()
+	alg := digest.OrDefault(r.DigestAlgorithm)
+	chrt := &chart.Chart{
+		Metadata: &chart.Metadata{},
+	}
+	// The manifest of a release depends on its templates and its namespace.
+	h := alg.New()
+	fmt.Fprintf(h, "%s\n", r.releaseNamespace(bi))
+	names := sets.NewString()
+	for _, obj := range objs {
+		jsonData, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		base := templateName(obj)
+		name := base + ".yaml"
+		// Objects with the same identity would otherwise replace each other.
+		for i := 2; names.Has(name); i++ {
+			name = fmt.Sprintf("%s-%d.yaml", base, i)
+		}
+		names.Insert(name)
+		chrt.Templates = append(chrt.Templates, &chart.File{
+			Name: name,
+			Data: jsonData,
+		})
+		fmt.Fprintf(h, "%s %s\n", name, digest.Sum(alg, jsonData))
+	}
+	chrt.Metadata.Annotations = map[string]string{
+		chartHashAnnotation:    fmt.Sprintf("%s:%x", alg.Name(), h.Sum(nil)),
+		bundleDigestAnnotation: bundleDigest,
+	}
+	return chrt, nil
+}
+
+// splitHooks returns the objects of objs that are installed and the objects
+// that run as hooks of their installs and upgrades.
+func splitHooks(objs []client.Object) ([]client.Object, []client.Object, error) {
+	var installed, hooks []client.Object
+	for _, obj := range objs {
+		events, err := manifests.HookEvents(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		if events == nil {
+			installed = append(installed, obj)
+			continue
+		}
+		hooks = append(hooks, obj)
+	}
+	return installed, hooks, nil
+}
+
+// releaseStatus returns the status that identifies rel, or nil when there is
+// no release.
+func releaseStatus(rel *release.Release) *rukpakv1alpha1.ReleaseStatus {
+	if rel == nil {
+		return nil
+	}
+	rs := &rukpakv1alpha1.ReleaseStatus{Name: rel.Name, Namespace: rel.Namespace, Version: rel.Version}
+	if rel.Info != nil {
+		rs.Status = rel.Info.Status.String()
+		rs.Notes = rel.Info.Notes
+		if !rel.Info.LastDeployed.IsZero() {
+			rs.LastDeployed = &metav1.Time{Time: rel.Info.LastDeployed.Time}
+		}
+	}
+	return rs
+}
+
+// substitutionVariables returns the variables substituted in the manifests
+// of the bundle of bi: its config, along with its name and the namespace that
+// its namespace-scoped objects are installed into.
+func substitutionVariables(bi *rukpakv1alpha1.BundleInstance, releaseNamespace string) map[string]string {
+	targetNamespace := bi.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = releaseNamespace
+	}
+	return util.MergeMaps(bi.Spec.Config, map[string]string{
+		rukpakv1alpha1.VariableBundleInstanceName: bi.Name,
+		rukpakv1alpha1.VariableTargetNamespace:    targetNamespace,
+	})
+}
+
+// setTargetNamespace sets the namespace of every namespace-scoped object in objs that
+// does not already declare one to targetNamespace. The scope of each object is determined
+// from the CRDs contained in objs, falling back to the cluster's REST mappings.
+func setTargetNamespace(mapper meta.RESTMapper, objs []client.Object, targetNamespace string) error {
+	bundleScopes := map[schema.GroupKind]apiextensionsv1.ResourceScope{}
+	for _, obj := range objs {
+		if obj.GetObjectKind().GroupVersionKind().GroupKind() != apiextensionsv1.Kind("CustomResourceDefinition") {
+			continue
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).Object, crd); err != nil {
+			return fmt.Errorf("convert CRD %q: %w", obj.GetName(), err)
+		}
+		bundleScopes[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = crd.Spec.Scope
+	}
+
+	for _, obj := range objs {
+		if obj.GetNamespace() != "" {
+			continue
+		}
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		var namespaced bool
+		if scope, ok := bundleScopes[gvk.GroupKind()]; ok {
+			namespaced = scope == apiextensionsv1.NamespaceScoped
+		} else {
+			mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				return fmt.Errorf("determine scope of %s %q: %w", gvk, obj.GetName(), err)
+			}
+			namespaced = mapping.Scope.Name() == meta.RESTScopeNameNamespace
+		}
+		if namespaced {
+			obj.SetNamespace(targetNamespace)
+		}
+	}
+	return nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *BundleInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &rukpakv1alpha1.BundleInstance{}, util.BundleInstanceBundleNameIndexKey, util.IndexBundleInstanceBundleName); err != nil {
+		return err
+	}
 	controller, err := ctrl.NewControllerManagedBy(mgr).
-		For(&rukpakv1alpha1.BundleInstance{}, builder.WithPredicates(util.BundleInstanceProvisionerFilter(plainBundleProvisionerID))).
-		Watches(&source.Kind{Type: &rukpakv1alpha1.Bundle{}}, handler.EnqueueRequestsFromMapFunc(util.MapBundleToBundleInstanceHandler(mgr.GetClient(), mgr.GetLogger()))).
+		For(&rukpakv1alpha1.BundleInstance{}, builder.WithPredicates(
+			util.BundleInstanceProvisionerFilter(plainBundleProvisionerID),
+			util.SelectorFilter(r.Selector),
+			// The status of every reconcile records when it was attempted.
+			util.IgnoreStatusUpdatesPredicate(),
+		)).
+		Watches(&source.Kind{Type: &rukpakv1alpha1.Bundle{}},
+			handler.EnqueueRequestsFromMapFunc(util.MapBundleToBundleInstanceHandler(mgr.GetClient(), mgr.GetLogger())),
+			builder.WithPredicates(util.BundleContentChangedPredicate()),
+		).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter:             r.RateLimit.RateLimiter(),
+		}).
 		Build(r)
 	if err != nil {
 		return err
 	}
 	r.Controller = controller
-	r.dynamicWatchGVKs = map[schema.GroupVersionKind]struct{}{}
-	return nil
+
+	// Dynamic watches only need to cache the objects installed by BundleInstances.
+	ownedRequirement, err := labels.NewRequirement("core.rukpak.io/owner-kind", selection.Equals, []string{"BundleInstance"})
+	if err != nil {
+		return err
+	}
+	ownedSelector := labels.NewSelector().Add(*ownedRequirement)
+	watchConfig := util.RateLimitedConfig(mgr.GetConfig(), r.DynamicWatchLimit)
+	newCache := func() (cache.Cache, error) {
+		return cache.New(watchConfig, cache.Options{
+			Scheme:          mgr.GetScheme(),
+			Mapper:          mgr.GetRESTMapper(),
+			DefaultSelector: cache.ObjectSelector{Label: ownedSelector},
+		})
+	}
+	r.watches = watchmanager.New(controller, newCache,
+		handler.EnqueueRequestsFromMapFunc(util.MapOwnerNameToBundleInstance()),
+		predicate.Or(helmpredicate.DependentPredicateFuncs(), health.ChangedPredicate()),
+	)
+	return mgr.Add(r.watches)
 }
@@ -0,0 +1,579 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/manifests"
+	"github.com/operator-framework/rukpak/internal/policy"
+	"github.com/operator-framework/rukpak/internal/util"
+	"github.com/operator-framework/rukpak/internal/watchmanager"
+)
+
+var _ = Describe("BundleInstanceReconciler", func() {
+	const releaseNamespace = "default"
+
+	var (
+		ctx context.Context
+		c   client.Client
+		r   *BundleInstanceReconciler
+		bi  *rukpakv1alpha1.BundleInstance
+	)
+
+	BeforeEach(func() {
+		requireAPIServer()
+		ctx = context.Background()
+		var err error
+		c, err = client.New(cfg, client.Options{Scheme: scheme})
+		Expect(err).NotTo(HaveOccurred())
+		mapper, err := apiutil.NewDynamicRESTMapper(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		r = &BundleInstanceReconciler{
+			Client:             c,
+			Scheme:             scheme,
+			ActionConfigGetter: helmclient.NewActionConfigGetter(cfg, mapper, log.Log),
+			ReleaseNamespace:   releaseNamespace,
+		}
+
+		bi = &rukpakv1alpha1.BundleInstance{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "retained-"},
+			Spec: rukpakv1alpha1.BundleInstanceSpec{
+				ProvisionerClassName: plainBundleProvisionerID,
+				BundleName:           "olm-crds",
+				DeletionPolicy:       rukpakv1alpha1.DeletionPolicyRetain,
+			},
+		}
+		Expect(c.Create(ctx, bi)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(client.IgnoreNotFound(c.Delete(ctx, bi))).To(Succeed())
+		})
+	})
+
+	Describe("applyDeletionPolicy", func() {
+		It("disowns the release Secrets of a BundleInstance with the Retain policy", func() {
+			// The storage drivers set the owner reference to the BundleInstance
+			// of the release on the Secrets that store it.
+			bi.SetGroupVersionKind(rukpakv1alpha1.GroupVersion.WithKind("BundleInstance"))
+			bi.SetNamespace(releaseNamespace)
+			actionConfig, err := r.ActionConfigGetter.ActionConfigFor(bi)
+			bi.SetNamespace("")
+			Expect(err).NotTo(HaveOccurred())
+			rel := &release.Release{
+				Name:      bi.Name,
+				Namespace: releaseNamespace,
+				Version:   1,
+				Info:      &release.Info{Status: release.StatusDeployed},
+			}
+			Expect(actionConfig.Releases.Create(rel)).To(Succeed())
+			secret := &corev1.Secret{}
+			key := types.NamespacedName{Namespace: releaseNamespace, Name: releaseStorageName(rel)}
+			Expect(c.Get(ctx, key, secret)).To(Succeed())
+			Expect(secret.OwnerReferences).To(ContainElement(HaveField("UID", bi.UID)))
+
+			remaining, err := r.applyDeletionPolicy(ctx, bi)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remaining).To(BeEmpty())
+
+			Expect(c.Get(ctx, key, secret)).To(Succeed())
+			Expect(secret.OwnerReferences).NotTo(ContainElement(HaveField("UID", bi.UID)))
+			_, err = actionConfig.Releases.Get(bi.Name, 1)
+			Expect(err).NotTo(HaveOccurred(), "the release of a retained BundleInstance should be kept")
+		})
+	})
+
+	Describe("finalize", func() {
+		var (
+			held *corev1.ConfigMap
+			rel  *release.Release
+		)
+
+		// refresh reads bi, which finalize patches, as it is being deleted.
+		refresh := func() {
+			deleted := bi.DeletionTimestamp
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(bi), bi)).To(Succeed())
+			bi.SetGroupVersionKind(rukpakv1alpha1.GroupVersion.WithKind("BundleInstance"))
+			bi.DeletionTimestamp = deleted
+		}
+
+		BeforeEach(func() {
+			bi.Spec.DeletionPolicy = rukpakv1alpha1.DeletionPolicyDelete
+			Expect(c.Update(ctx, bi)).To(Succeed())
+			bi.SetGroupVersionKind(rukpakv1alpha1.GroupVersion.WithKind("BundleInstance"))
+			watchCtx, cancel := context.WithCancel(ctx)
+			DeferCleanup(cancel)
+			r.watches = watchmanager.New(nopWatcher{}, func() (cache.Cache, error) { return &informertest.FakeInformers{}, nil }, &handler.EnqueueRequestForObject{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(r.watches.Start(watchCtx)).To(Succeed())
+			}()
+
+			// The finalizer of the ConfigMap of the release holds its deletion
+			// until it is removed, like the finalizer of a controller would.
+			held = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Namespace:    releaseNamespace,
+				GenerateName: "held-",
+				Finalizers:   []string{"example.com/hold"},
+			}}
+			Expect(c.Create(ctx, held)).To(Succeed())
+			DeferCleanup(func() {
+				if err := c.Get(ctx, client.ObjectKeyFromObject(held), held); err == nil {
+					held.Finalizers = nil
+					Expect(c.Update(ctx, held)).To(Succeed())
+				}
+			})
+			bi.SetNamespace(releaseNamespace)
+			actionConfig, err := r.ActionConfigGetter.ActionConfigFor(bi)
+			bi.SetNamespace("")
+			Expect(err).NotTo(HaveOccurred())
+			rel = &release.Release{
+				Name:      bi.Name,
+				Namespace: releaseNamespace,
+				Version:   1,
+				Info:      &release.Info{Status: release.StatusDeployed},
+				Manifest:  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + held.Name + "\n  namespace: " + releaseNamespace + "\n",
+			}
+			Expect(actionConfig.Releases.Create(rel)).To(Succeed())
+			now := metav1.Now()
+			bi.DeletionTimestamp = &now
+		})
+
+		uninstalling := func() *metav1.Condition {
+			return meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeUninstalling)
+		}
+
+		It("waits for the installed objects to be deleted before it removes the finalizer", func() {
+			result, err := r.finalize(ctx, bi, bi.Status.DeepCopy())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(uninstallPollInterval))
+			Expect(uninstalling()).To(HaveField("Reason", rukpakv1alpha1.ReasonUninstallInProgress))
+			Expect(uninstalling().Message).To(ContainSubstring("waiting for 1 objects to be deleted: ConfigMap " + releaseNamespace + "/" + held.Name))
+			Expect(meta.IsStatusConditionTrue(bi.Status.Conditions, rukpakv1alpha1.TypeReady)).To(BeFalse())
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(held), held)).To(Succeed())
+			Expect(held.DeletionTimestamp).NotTo(BeNil())
+			held.Finalizers = nil
+			Expect(c.Update(ctx, held)).To(Succeed())
+
+			refresh()
+			result, err = r.finalize(ctx, bi, bi.Status.DeepCopy())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+			bi.SetNamespace(releaseNamespace)
+			actionConfig, err := r.ActionConfigGetter.ActionConfigFor(bi)
+			bi.SetNamespace("")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = actionConfig.Releases.History(bi.Name)
+			Expect(err).To(HaveOccurred(), "the release of an uninstalled BundleInstance should be deleted")
+		})
+
+		It("reports an uninstall that does not complete within the uninstall timeout as failed", func() {
+			r.UninstallTimeout = time.Minute
+			deleted := metav1.NewTime(time.Now().Add(-time.Hour))
+			bi.DeletionTimestamp = &deleted
+
+			result, err := r.finalize(ctx, bi, bi.Status.DeepCopy())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(uninstallPollInterval), "a timed out uninstall should still be retried")
+			Expect(uninstalling()).To(HaveField("Reason", rukpakv1alpha1.ReasonUninstallFailed))
+			Expect(uninstalling().Message).To(HavePrefix("timed out after 1m0s waiting for 1 objects to be deleted"))
+		})
+	})
+
+	Describe("installPrerequisites", func() {
+		const crdName = "widgets.example.com"
+
+		var (
+			namespace string
+			objs      []client.Object
+		)
+
+		BeforeEach(func() {
+			namespace = "widgets-" + bi.Name
+			crd := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "apiextensions.k8s.io/v1",
+				"kind":       "CustomResourceDefinition",
+				"metadata":   map[string]interface{}{"name": crdName},
+				"spec": map[string]interface{}{
+					"group": "example.com",
+					"names": map[string]interface{}{"kind": "Widget", "plural": "widgets", "singular": "widget", "listKind": "WidgetList"},
+					"scope": "Namespaced",
+					"versions": []interface{}{map[string]interface{}{
+						"name": "v1", "served": true, "storage": true,
+						"schema": map[string]interface{}{"openAPIV3Schema": map[string]interface{}{"type": "object"}},
+					}},
+				},
+			}}
+			ns := &unstructured.Unstructured{}
+			ns.SetAPIVersion("v1")
+			ns.SetKind("Namespace")
+			ns.SetName(namespace)
+			widget := &unstructured.Unstructured{}
+			widget.SetAPIVersion("example.com/v1")
+			widget.SetKind("Widget")
+			widget.SetNamespace(namespace)
+			widget.SetName("widget")
+			objs = []client.Object{crd, ns, widget}
+		})
+
+		// expectNothingApplied expects that the CRD of the bundle was not
+		// created, and that the Namespace of the bundle, if it exists, was not
+		// taken over by the release of bi.
+		expectNothingApplied := func() {
+			err := c.Get(ctx, types.NamespacedName{Name: crdName}, &apiextensionsv1.CustomResourceDefinition{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the CRD of a rejected bundle should not be applied: %v", err)
+			ns := &corev1.Namespace{}
+			if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); !apierrors.IsNotFound(err) {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ns.Annotations).NotTo(HaveKey(helmReleaseNameAnnotation))
+			}
+		}
+
+		It("applies nothing when an object conflicts with an existing object", func() {
+			foreign := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+			Expect(c.Create(ctx, foreign)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(c.Delete(ctx, foreign))).To(Succeed())
+			})
+
+			gated, established, err := r.installPrerequisites(ctx, c, bi, objs, objs)
+			Expect(err).To(MatchError(ContainSubstring(`Namespace "` + namespace + `" already exists and is not installed by any release`)))
+			Expect(gated).To(BeFalse())
+			Expect(established).To(BeFalse())
+			Expect(meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeInstalled)).To(HaveField("Reason", rukpakv1alpha1.ReasonObjectConflict))
+			expectNothingApplied()
+		})
+
+		It("applies nothing when the bundle violates a policy", func() {
+			r.Policies = []policy.Checker{rejectingPolicy{}}
+
+			_, _, err := r.installPrerequisites(ctx, c, bi, objs, objs)
+			Expect(err).To(MatchError(ContainSubstring("bundle content violates policies")))
+			Expect(meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeInstalled)).To(HaveField("Reason", rukpakv1alpha1.ReasonPolicyViolation))
+			expectNothingApplied()
+		})
+
+		It("applies the CRDs and Namespaces of a bundle whose custom resources do not exist yet", func() {
+			DeferCleanup(func() {
+				crd := &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: crdName}}
+				Expect(client.IgnoreNotFound(c.Delete(ctx, crd))).To(Succeed())
+				Eventually(func() bool {
+					return apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Name: crdName}, crd))
+				}).Should(BeTrue())
+			})
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(c.Delete(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}))).To(Succeed())
+			})
+
+			gated, _, err := r.installPrerequisites(ctx, c, bi, objs, objs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gated).To(BeTrue())
+			Expect(meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeInstalled)).To(BeNil())
+			Expect(c.Get(ctx, types.NamespacedName{Name: crdName}, &apiextensionsv1.CustomResourceDefinition{})).To(Succeed())
+			ns := &corev1.Namespace{}
+			Expect(c.Get(ctx, types.NamespacedName{Name: namespace}, ns)).To(Succeed())
+			Expect(ns.Annotations).To(HaveKeyWithValue(helmReleaseNameAnnotation, bi.Name))
+		})
+	})
+})
+
+var _ = Describe("releaseNamespace", func() {
+	var r *BundleInstanceReconciler
+
+	BeforeEach(func() {
+		r = &BundleInstanceReconciler{ReleaseNamespace: "rukpak-plain-releases"}
+	})
+
+	It("keeps the release namespace that is recorded in the status", func() {
+		bi := &rukpakv1alpha1.BundleInstance{}
+		Expect(r.releaseNamespace(bi)).To(Equal("rukpak-plain-releases"))
+
+		bi.Status.Release = &rukpakv1alpha1.ReleaseStatus{Name: "olm-crds", Namespace: "rukpak-system"}
+		Expect(r.releaseNamespace(bi)).To(Equal("rukpak-system"))
+
+		bi.Status.ReleaseNamespace = "rukpak-previous"
+		Expect(r.releaseNamespace(bi)).To(Equal("rukpak-previous"))
+	})
+
+	It("prefers the release namespace of the spec", func() {
+		bi := &rukpakv1alpha1.BundleInstance{Spec: rukpakv1alpha1.BundleInstanceSpec{ReleaseNamespace: "workloads"}}
+		bi.Status.ReleaseNamespace = "rukpak-previous"
+		Expect(r.releaseNamespace(bi)).To(Equal("workloads"))
+	})
+})
+
+var _ = Describe("checkServiceAccount", func() {
+	var (
+		ctx    context.Context
+		bi     *rukpakv1alpha1.BundleInstance
+		config *rukpakv1alpha1.RukpakConfig
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		bi = &rukpakv1alpha1.BundleInstance{Spec: rukpakv1alpha1.BundleInstanceSpec{
+			TargetNamespace:    "team-a",
+			ServiceAccountName: "installer",
+		}}
+		config = &rukpakv1alpha1.RukpakConfig{ObjectMeta: metav1.ObjectMeta{Name: rukpakv1alpha1.RukpakConfigName}}
+	})
+
+	checkServiceAccount := func() error {
+		r := &BundleInstanceReconciler{Client: newFakeClient(config), ReleaseNamespace: "rukpak-system"}
+		return r.checkServiceAccount(ctx, bi)
+	}
+
+	It("allows BundleInstances without a ServiceAccount", func() {
+		bi.Spec.ServiceAccountName = ""
+		Expect(checkServiceAccount()).To(Succeed())
+	})
+
+	It("refuses ServiceAccounts that the RukpakConfig does not select", func() {
+		Expect(checkServiceAccount()).To(MatchError(`service account "team-a/installer" is not selected by the serviceAccounts of the RukpakConfig`))
+
+		config.Spec.ServiceAccounts = []rukpakv1alpha1.ServiceAccountSelector{{Namespace: "team-a", Name: "other"}, {Namespace: "team-b"}}
+		Expect(checkServiceAccount()).To(HaveOccurred())
+	})
+
+	It("allows the ServiceAccounts that the RukpakConfig selects", func() {
+		config.Spec.ServiceAccounts = []rukpakv1alpha1.ServiceAccountSelector{{Namespace: "team-a", Name: "installer"}}
+		Expect(checkServiceAccount()).To(Succeed())
+
+		config.Spec.ServiceAccounts = []rukpakv1alpha1.ServiceAccountSelector{{Namespace: "team-a"}}
+		Expect(checkServiceAccount()).To(Succeed())
+	})
+})
+
+var _ = Describe("template Bundles", func() {
+	var (
+		ctx context.Context
+		r   *BundleInstanceReconciler
+		bi  *rukpakv1alpha1.BundleInstance
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		r = &BundleInstanceReconciler{Client: newFakeClient(), Scheme: scheme}
+		bi = &rukpakv1alpha1.BundleInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "templated", UID: "bi-uid"},
+			Spec: rukpakv1alpha1.BundleInstanceSpec{
+				ProvisionerClassName: plainBundleProvisionerID,
+				Template: &rukpakv1alpha1.BundleTemplate{Spec: rukpakv1alpha1.BundleSpec{Source: rukpakv1alpha1.BundleSource{
+					Type:  rukpakv1alpha1.SourceTypeImage,
+					Image: &rukpakv1alpha1.ImageSource{Ref: "quay.io/example/bundle:v1"},
+				}}},
+			},
+		}
+	})
+
+	templateBundle := func() *rukpakv1alpha1.Bundle {
+		b := &rukpakv1alpha1.Bundle{}
+		Expect(r.Get(ctx, client.ObjectKey{Name: util.DesiredBundleName(bi)}, b)).To(Succeed())
+		return b
+	}
+
+	It("creates the Bundle of the template, owned by the BundleInstance", func() {
+		Expect(r.ensureTemplateBundle(ctx, bi)).To(Succeed())
+		b := templateBundle()
+		Expect(metav1.IsControlledBy(b, bi)).To(BeTrue())
+		Expect(b.Spec.ProvisionerClassName).To(Equal(plainBundleProvisionerID))
+		Expect(b.Spec.Source.Image.Ref).To(Equal("quay.io/example/bundle:v1"))
+
+		Expect(r.ensureTemplateBundle(ctx, bi)).To(Succeed())
+	})
+
+	It("creates a new Bundle when the template changes", func() {
+		Expect(r.ensureTemplateBundle(ctx, bi)).To(Succeed())
+		previous := templateBundle()
+
+		bi.Spec.Template.Spec.Source.Image.Ref = "quay.io/example/bundle:v2"
+		Expect(r.ensureTemplateBundle(ctx, bi)).To(Succeed())
+		Expect(templateBundle().Name).NotTo(Equal(previous.Name))
+		Expect(templateBundle().Spec.Source.Image.Ref).To(Equal("quay.io/example/bundle:v2"))
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(previous), &rukpakv1alpha1.Bundle{})).To(Succeed(), "the previous Bundle should be kept until the new one is installed")
+	})
+
+	It("refuses a Bundle of the same name that the BundleInstance does not own", func() {
+		Expect(r.Create(ctx, &rukpakv1alpha1.Bundle{ObjectMeta: metav1.ObjectMeta{Name: util.DesiredBundleName(bi)}})).To(Succeed())
+		Expect(r.ensureTemplateBundle(ctx, bi)).To(MatchError(ContainSubstring("already exists and is not owned by the bundleinstance")))
+	})
+
+	It("deletes the Bundles of previous templates once another Bundle is installed", func() {
+		Expect(r.ensureTemplateBundle(ctx, bi)).To(Succeed())
+		previous := templateBundle()
+		bi.Spec.Template.Spec.Source.Image.Ref = "quay.io/example/bundle:v2"
+		Expect(r.ensureTemplateBundle(ctx, bi)).To(Succeed())
+		current := templateBundle()
+		unowned := &rukpakv1alpha1.Bundle{ObjectMeta: metav1.ObjectMeta{Name: "unowned"}}
+		Expect(r.Create(ctx, unowned)).To(Succeed())
+
+		bi.Status.InstalledBundleName = current.Name
+		Expect(r.deleteStaleTemplateBundles(ctx, bi)).To(Succeed())
+		err := r.Get(ctx, client.ObjectKeyFromObject(previous), &rukpakv1alpha1.Bundle{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the Bundle of the previous template should be deleted: %v", err)
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(current), &rukpakv1alpha1.Bundle{})).To(Succeed())
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(unowned), &rukpakv1alpha1.Bundle{})).To(Succeed())
+	})
+})
+
+var _ = Describe("applyWaves", func() {
+	var (
+		ctx     context.Context
+		r       *BundleInstanceReconciler
+		bi      *rukpakv1alpha1.BundleInstance
+		objs    []client.Object
+		applied []string
+	)
+
+	newObject := func(kind, name, wave string) client.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		if kind == "Job" {
+			obj.SetAPIVersion("batch/v1")
+		}
+		obj.SetKind(kind)
+		obj.SetNamespace("default")
+		obj.SetName(name)
+		obj.SetAnnotations(map[string]string{manifests.WaveAnnotation: wave})
+		return obj
+	}
+	apply := func(obj client.Object) error {
+		applied = append(applied, obj.GetName())
+		installed, err := r.installedObject(bi, obj)
+		if err != nil {
+			return err
+		}
+		if err := r.Create(ctx, installed); !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		return nil
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		r = &BundleInstanceReconciler{Client: newFakeClient(), Scheme: scheme, ReleaseNamespace: "default"}
+		bi = &rukpakv1alpha1.BundleInstance{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha1.GroupVersion.String(), Kind: "BundleInstance"},
+			ObjectMeta: metav1.ObjectMeta{Name: "waves", UID: "bi-uid"},
+		}
+		// The objects are out of the order of their waves.
+		objs = []client.Object{newObject("ConfigMap", "app", "2"), newObject("ConfigMap", "settings", "1"), newObject("Job", "migrate", "-1")}
+		applied = nil
+	})
+
+	It("applies the waves in order, and waits for each to be healthy before the next", func() {
+		pending, err := r.applyWaves(ctx, r.Client, bi, objs, apply)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(Equal(`waiting for the objects of wave -1 to be healthy: Job "default/migrate": job has not completed`))
+		Expect(applied).To(Equal([]string{"migrate"}))
+
+		job := &batchv1.Job{}
+		Expect(r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "migrate"}, job)).To(Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(r.Status().Update(ctx, job)).To(Succeed())
+
+		applied = nil
+		pending, err = r.applyWaves(ctx, r.Client, bi, objs, apply)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+		Expect(applied).To(Equal([]string{"migrate", "settings"}), "the objects of the last wave should be left to the caller")
+	})
+
+	It("applies nothing when the bundle has a single wave", func() {
+		pending, err := r.applyWaves(ctx, r.Client, bi, objs[:1], apply)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+		Expect(applied).To(BeEmpty())
+	})
+
+	It("fails on an invalid wave", func() {
+		objs[1].SetAnnotations(map[string]string{manifests.WaveAnnotation: "first"})
+		_, err := r.applyWaves(ctx, r.Client, bi, objs, apply)
+		Expect(err).To(MatchError(ContainSubstring(`invalid core.rukpak.io/wave annotation "first" of ConfigMap "settings"`)))
+		Expect(applied).To(BeEmpty())
+	})
+})
+
+var _ = Describe("runInstall", func() {
+	It("cancels an install that does not complete within the install timeout", func() {
+		// The API server never responds, until the requests are cancelled.
+		var active, requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			atomic.AddInt32(&active, 1)
+			defer atomic.AddInt32(&active, -1)
+			<-req.Context().Done()
+		}))
+		DeferCleanup(server.Close)
+		r := &BundleInstanceReconciler{
+			Client:             newFakeClient(),
+			Scheme:             scheme,
+			ActionConfigGetter: memoryConfigGetter{},
+			ContentConfig:      &rest.Config{Host: server.URL},
+			ReleaseNamespace:   "default",
+			InstallTimeout:     200 * time.Millisecond,
+		}
+		bi := &rukpakv1alpha1.BundleInstance{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha1.GroupVersion.String(), Kind: "BundleInstance"},
+			ObjectMeta: metav1.ObjectMeta{Name: "stalled", UID: "bi-uid"},
+		}
+		chrt := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "stalled", Version: "0.1.0"}}
+
+		start := time.Now()
+		_, err := r.runInstall(context.Background(), nil, bi, nil, func(cl helmclient.ActionInterface) (*release.Release, error) {
+			return cl.Install(bi.Name, "default", chrt, nil)
+		})
+		Expect(err).To(MatchError(errInstallTimedOut))
+		Expect(time.Since(start)).To(BeNumerically("<", 10*time.Second))
+		Expect(atomic.LoadInt32(&requests)).To(BeNumerically(">", 0))
+		Eventually(func() int32 { return atomic.LoadInt32(&active) }).Should(BeZero(), "the requests of the install should be cancelled")
+		sent := atomic.LoadInt32(&requests)
+		Consistently(func() int32 { return atomic.LoadInt32(&requests) }, 500*time.Millisecond).Should(Equal(sent), "the install should not run on after it timed out")
+	})
+})
+
+// memoryConfigGetter is a helmclient.ActionConfigGetter that stores releases
+// in memory, and has no clients.
+type memoryConfigGetter struct{}
+
+func (memoryConfigGetter) ActionConfigFor(client.Object) (*action.Configuration, error) {
+	return &action.Configuration{Releases: storage.Init(driver.NewMemory()), Log: func(string, ...interface{}) {}}, nil
+}
+
+// rejectingPolicy is a policy.Checker that every object violates.
+type rejectingPolicy struct{}
+
+func (rejectingPolicy) Check(_ context.Context, _ *rukpakv1alpha1.BundleInstance, objs []client.Object) ([]policy.Violation, error) {
+	violations := make([]policy.Violation, 0, len(objs))
+	for _, obj := range objs {
+		violations = append(violations, policy.Violation{Object: obj.GetName(), Message: "rejected"})
+	}
+	return violations, nil
+}
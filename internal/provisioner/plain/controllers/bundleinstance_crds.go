@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/util"
+	"github.com/operator-framework/rukpak/pkg/status"
+)
+
+// checkCRDUpgrades checks that the CRDs in objs can replace the existing CRDs
+// of the same names, which cl reads along with their custom resources,
+// without losing or invalidating the custom resources stored for them. Unsafe
+// upgrades are reported in the Installed condition of bi instead of being
+// applied, and an error is returned so that the check is retried once the
+// custom resources or the bundle are fixed.
+func (r *BundleInstanceReconciler) checkCRDUpgrades(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, objs []client.Object) error {
+	var unsafe []string
+	for _, obj := range objs {
+		if obj.GetObjectKind().GroupVersionKind().GroupKind() != apiextensionsv1.Kind("CustomResourceDefinition") {
+			continue
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).Object, crd); err != nil {
+			err = fmt.Errorf("convert CRD %q: %w", obj.GetName(), err)
+			meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.InstallFailed, err))
+			return err
+		}
+		existing := &apiextensionsv1.CustomResourceDefinition{}
+		if err := cl.Get(ctx, client.ObjectKeyFromObject(crd), existing); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			err = fmt.Errorf("get CRD %q: %w", crd.Name, err)
+			meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.InstallFailed, err))
+			return err
+		}
+		if err := util.ValidateCRDUpgrade(ctx, cl, existing, crd); err != nil {
+			unsafe = append(unsafe, fmt.Sprintf("CRD %q: %v", crd.Name, err))
+		}
+	}
+	if len(unsafe) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("unsafe upgrade of the custom resource definitions of the bundle: %s", strings.Join(unsafe, "; "))
+	meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.CRDUpgradeUnsafe, err.Error()))
+	return err
+}
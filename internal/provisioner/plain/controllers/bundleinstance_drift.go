@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/content"
+	"github.com/operator-framework/rukpak/pkg/status"
+)
+
+// detectDrift returns a description of every object of objs whose installed
+// object at its key no longer matches the content of the bundle of bi,
+// because it was changed or deleted.
+func (r *BundleInstanceReconciler) detectDrift(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, objs []client.Object) ([]string, error) {
+	var drifted []string
+	for _, obj := range objs {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		key, err := r.installedObjectKey(bi, obj)
+		if meta.IsNoMatchError(errors.Unwrap(err)) {
+			drifted = append(drifted, fmt.Sprintf("%s %q is missing", kind, client.ObjectKeyFromObject(obj)))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields, found, err := r.dryRunApply(ctx, cl, bi, obj, key)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !found:
+			drifted = append(drifted, fmt.Sprintf("%s %q is missing", kind, key))
+		case len(fields) > 0:
+			drifted = append(drifted, fmt.Sprintf("%s %q differs in %s", kind, key, strings.Join(fields, ", ")))
+		}
+	}
+	return drifted, nil
+}
+
+// driftPolicy returns the drift policy that the installed objects of bi are
+// reconciled with, which is Detect rather than Correct when the reconciler
+// does not correct drift.
+func (r *BundleInstanceReconciler) driftPolicy(bi *rukpakv1alpha1.BundleInstance) string {
+	policy := bi.Spec.DriftPolicy
+	if policy == "" {
+		policy = rukpakv1alpha1.DriftPolicyCorrect
+	}
+	if policy == rukpakv1alpha1.DriftPolicyCorrect && !r.DriftCorrection {
+		return rukpakv1alpha1.DriftPolicyDetect
+	}
+	return policy
+}
+
+// reportDrift sets the Drifted condition of bi to the drifted objects when
+// its drift policy is Detect, and removes the condition otherwise, as drift
+// is not reported unless it is only detected.
+func reportDrift(bi *rukpakv1alpha1.BundleInstance, policy string, drifted []string) {
+	switch {
+	case policy != rukpakv1alpha1.DriftPolicyDetect:
+		meta.RemoveStatusCondition(&bi.Status.Conditions, rukpakv1alpha1.TypeDrifted)
+	case len(drifted) > 0:
+		msg := fmt.Sprintf("%d installed objects have drifted from the bundle: %s", len(drifted), strings.Join(drifted, "; "))
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Drifted(metav1.ConditionTrue, status.DriftDetected, msg))
+	default:
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Drifted(metav1.ConditionFalse, status.NoDrift, ""))
+	}
+}
+
+// dryRunApply applies obj for bi at key in dry-run mode, and returns the
+// fields of its live object that applying it would change, and whether the
+// live object exists.
+func (r *BundleInstanceReconciler) dryRunApply(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, obj client.Object, key types.NamespacedName) ([]string, bool, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, key, live); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("get %s %q: %w", gvk.Kind, key, err)
+	}
+	applied, err := r.installedObject(bi, obj)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := cl.Patch(ctx, applied, client.Apply, client.FieldOwner(fieldManager(bi)), client.ForceOwnership, client.DryRunAll); err != nil {
+		return nil, false, fmt.Errorf("apply %s %q in dry-run mode: %w", gvk.Kind, key, err)
+	}
+	return changedFields(live, applied.(*unstructured.Unstructured)), true, nil
+}
+
+// changedFields returns the paths of the fields in which applied, the result
+// of applying an object in dry-run mode, differs from its live object, other
+// than the metadata that every write changes.
+func changedFields(live, applied *unstructured.Unstructured) []string {
+	live, applied = live.DeepCopy(), applied.DeepCopy()
+	for _, obj := range []*unstructured.Unstructured{live, applied} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+		unstructured.RemoveNestedField(obj.Object, "metadata", "resourceVersion")
+		unstructured.RemoveNestedField(obj.Object, "metadata", "generation")
+	}
+	diff := content.Diff([]unstructured.Unstructured{*live}, []unstructured.Unstructured{*applied})
+	var fields []string
+	for _, changed := range diff.Changed {
+		for _, f := range changed.Fields {
+			fields = append(fields, f.Path)
+		}
+	}
+	return fields
+}
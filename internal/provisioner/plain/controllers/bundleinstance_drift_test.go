@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/watchmanager"
+)
+
+var _ = Describe("drift", func() {
+	Describe("changedFields", func() {
+		It("ignores the metadata that every write changes", func() {
+			live := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "settings", "namespace": "default", "resourceVersion": "1"},
+				"data":       map[string]interface{}{"key": "changed"},
+			}}
+			applied := live.DeepCopy()
+			applied.SetResourceVersion("2")
+			applied.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "rukpak"}})
+			Expect(changedFields(live, applied)).To(BeEmpty())
+
+			Expect(unstructured.SetNestedField(applied.Object, "value", "data", "key")).To(Succeed())
+			Expect(changedFields(live, applied)).To(ConsistOf(ContainSubstring("data")))
+		})
+	})
+
+	Describe("reportDrift", func() {
+		var bi *rukpakv1alpha1.BundleInstance
+
+		BeforeEach(func() {
+			bi = &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "drifted", Generation: 2}}
+		})
+
+		It("reports the drifted objects when drift is detected", func() {
+			reportDrift(bi, rukpakv1alpha1.DriftPolicyDetect, []string{`ConfigMap "default/settings" is missing`})
+			cond := meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeDrifted)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Reason).To(Equal(rukpakv1alpha1.ReasonDriftDetected))
+			Expect(cond.Message).To(ContainSubstring(`1 installed objects have drifted from the bundle: ConfigMap "default/settings" is missing`))
+			Expect(cond.ObservedGeneration).To(Equal(int64(2)))
+
+			reportDrift(bi, rukpakv1alpha1.DriftPolicyDetect, nil)
+			Expect(meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeDrifted)).To(HaveField("Reason", rukpakv1alpha1.ReasonNoDrift))
+		})
+
+		It("removes the condition when drift is not only detected", func() {
+			reportDrift(bi, rukpakv1alpha1.DriftPolicyDetect, []string{`ConfigMap "default/settings" is missing`})
+			reportDrift(bi, rukpakv1alpha1.DriftPolicyCorrect, nil)
+			Expect(meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeDrifted)).To(BeNil())
+		})
+	})
+
+	Describe("ServerSideApply engine", func() {
+		var (
+			ctx    context.Context
+			c      client.Client
+			r      *BundleInstanceReconciler
+			bi     *rukpakv1alpha1.BundleInstance
+			cm     *unstructured.Unstructured
+			bundle *rukpakv1alpha1.Bundle
+		)
+
+		reconcile := func() {
+			_, err := r.reconcileServerSideApply(ctx, bi, bundle, []client.Object{cm}, nil, "", rest.NoWarnings{})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		liveData := func() map[string]string {
+			live := &corev1.ConfigMap{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(cm), live)).To(Succeed())
+			return live.Data
+		}
+
+		BeforeEach(func() {
+			requireAPIServer()
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+			var err error
+			c, err = client.New(cfg, client.Options{Scheme: scheme})
+			Expect(err).NotTo(HaveOccurred())
+			watches := watchmanager.New(nopWatcher{}, func() (cache.Cache, error) { return &informertest.FakeInformers{}, nil }, &handler.EnqueueRequestForObject{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(watches.Start(ctx)).To(Succeed())
+			}()
+			r = &BundleInstanceReconciler{Client: c, Scheme: scheme, ReleaseNamespace: "default", watches: watches}
+
+			bi = &rukpakv1alpha1.BundleInstance{
+				ObjectMeta: metav1.ObjectMeta{GenerateName: "drift-"},
+				Spec: rukpakv1alpha1.BundleInstanceSpec{
+					ProvisionerClassName: plainBundleProvisionerID,
+					BundleName:           "drift-bundle",
+					Engine:               rukpakv1alpha1.EngineServerSideApply,
+					DriftPolicy:          rukpakv1alpha1.DriftPolicyDetect,
+				},
+			}
+			Expect(c.Create(ctx, bi)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(c.Delete(context.Background(), bi))).To(Succeed())
+			})
+			bundle = &rukpakv1alpha1.Bundle{ObjectMeta: metav1.ObjectMeta{Name: bi.Spec.BundleName}}
+
+			cm = &unstructured.Unstructured{}
+			cm.SetAPIVersion("v1")
+			cm.SetKind("ConfigMap")
+			cm.SetNamespace("default")
+			cm.SetName(bi.Name)
+			Expect(unstructured.SetNestedStringMap(cm.Object, map[string]string{"key": "value"}, "data")).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(c.Delete(context.Background(), cm.DeepCopy()))).To(Succeed())
+			})
+
+			reconcile()
+			Expect(meta.IsStatusConditionTrue(bi.Status.Conditions, rukpakv1alpha1.TypeInstalled)).To(BeTrue())
+			Expect(meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeDrifted)).To(HaveField("Reason", rukpakv1alpha1.ReasonNoDrift))
+
+			// The ConfigMap is changed out of band, by another field manager.
+			live := &corev1.ConfigMap{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(cm), live)).To(Succeed())
+			live.Data["key"] = "changed"
+			Expect(c.Update(ctx, live, client.FieldOwner("kubectl-edit"))).To(Succeed())
+		})
+
+		It("reports the drift of a changed object", func() {
+			key, err := r.installedObjectKey(bi, cm)
+			Expect(err).NotTo(HaveOccurred())
+			fields, found, err := r.dryRunApply(ctx, c, bi, cm, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(fields).To(ConsistOf(ContainSubstring("data")))
+
+			reconcile()
+			cond := meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeDrifted)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal(rukpakv1alpha1.ReasonDriftDetected))
+			Expect(cond.Message).To(ContainSubstring(`ConfigMap "default/` + bi.Name + `" differs in`))
+			Expect(liveData()).To(HaveKeyWithValue("key", "changed"), "detected drift should not be corrected")
+		})
+
+		It("corrects the drift of a changed object", func() {
+			r.DriftCorrection = true
+			bi.Spec.DriftPolicy = rukpakv1alpha1.DriftPolicyCorrect
+
+			reconcile()
+			Expect(liveData()).To(HaveKeyWithValue("key", "value"))
+			Expect(meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeDrifted)).To(BeNil())
+		})
+	})
+})
+
+// nopWatcher is a watchmanager.Watcher that drops the events of every source.
+type nopWatcher struct{}
+
+func (nopWatcher) Watch(source.Source, handler.EventHandler, ...predicate.Predicate) error {
+	return nil
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/release"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/manifests"
+	"github.com/operator-framework/rukpak/internal/tracing"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+const (
+	// hookRunAnnotation annotates the Jobs and Pods that run as hooks of a
+	// BundleInstance with the event and the content hash of their run, so
+	// that the hooks of earlier runs are replaced rather than waited for.
+	hookRunAnnotation = "core.rukpak.io/hook-run"
+	// hookPollInterval is the interval at which the hooks that run for a
+	// BundleInstance are checked for completion.
+	hookPollInterval = 2 * time.Second
+)
+
+// runHooks runs the hooks of bi that event runs, for the install or upgrade of
+// the content with the given hash, with cl. The hooks are created once for
+// the run and are checked on later calls rather than waited for: runHooks
+// returns a description of the hooks that are still running, if any, and an
+// error when a hook failed or timed out. Hooks that succeed are deleted. Hooks
+// that fail are kept, and only run again for the same content once they are
+// deleted.
+func (r *BundleInstanceReconciler) runHooks(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, hooks []client.Object, event, hash string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "BundleInstance.RunHooks")
+	defer func() { tracing.End(span, err) }()
+	var (
+		running []string
+		errs    []error
+	)
+	for _, hook := range hooks {
+		events, err := manifests.HookEvents(hook)
+		if err != nil {
+			return "", err
+		}
+		if !sets.NewString(events...).Has(event) {
+			continue
+		}
+		msg, err := r.runHook(ctx, cl, bi, hook, hookStatusFor(bi, hook), event, hash)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if msg != "" {
+			running = append(running, msg)
+		}
+	}
+	if len(errs) > 0 {
+		return "", utilerrors.NewAggregate(errs)
+	}
+	if len(running) > 0 {
+		return fmt.Sprintf("waiting for the %s hooks to complete: %s", event, strings.Join(running, "; ")), nil
+	}
+	return "", nil
+}
+
+// runHook creates hook for the run of event for the content with the given
+// hash, or checks the hook that it created earlier, and records its progress
+// in hs. It returns a description of the hook while it is running.
+func (r *BundleInstanceReconciler) runHook(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, hook client.Object, hs *rukpakv1alpha1.HookStatus, event, hash string) (string, error) {
+	sameRun := hs.Event == event && hs.ContentHash == hash
+	if sameRun && hs.Phase == release.HookPhaseSucceeded.String() {
+		return "", nil
+	}
+	run := fmt.Sprintf("%s/%s", event, hash)
+	obj, err := r.installedObject(bi, hook)
+	if err != nil {
+		return "", err
+	}
+	obj.SetAnnotations(util.MergeMaps(obj.GetAnnotations(), map[string]string{hookRunAnnotation: run}))
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	key := client.ObjectKeyFromObject(obj)
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, key, live); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("get hook %s %q: %w", gvk.Kind, key, err)
+		}
+		if err := cl.Create(ctx, obj); err != nil {
+			return "", fmt.Errorf("create hook %s %q: %w", gvk.Kind, key, err)
+		}
+		now := metav1.Now()
+		hs.Event, hs.ContentHash, hs.Phase = event, hash, release.HookPhaseRunning.String()
+		hs.StartedAt, hs.CompletedAt = &now, nil
+		return fmt.Sprintf("%s %q is running", gvk.Kind, key), nil
+	}
+	// The hook of an earlier run is deleted before the hook runs again.
+	if live.GetDeletionTimestamp() != nil || live.GetAnnotations()[hookRunAnnotation] != run {
+		if live.GetDeletionTimestamp() == nil {
+			if err := cl.Delete(ctx, live, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+				return "", fmt.Errorf("delete hook %s %q of an earlier run: %w", gvk.Kind, key, err)
+			}
+		}
+		return fmt.Sprintf("%s %q of an earlier run is being deleted", gvk.Kind, key), nil
+	}
+	if !sameRun {
+		// The status of the run was not recorded when the hook was created.
+		created := live.GetCreationTimestamp()
+		hs.Event, hs.ContentHash, hs.Phase = event, hash, release.HookPhaseRunning.String()
+		hs.StartedAt, hs.CompletedAt = &created, nil
+	}
+	if hs.Phase == release.HookPhaseFailed.String() {
+		return "", fmt.Errorf("hook %s %q failed: delete it to run it again", gvk.Kind, key)
+	}
+
+	phase, err := hookPhase(live)
+	if err != nil {
+		return "", err
+	}
+	now := metav1.Now()
+	switch phase {
+	case release.HookPhaseSucceeded:
+		if err := cl.Delete(ctx, live, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return "", fmt.Errorf("delete succeeded hook %s %q: %w", gvk.Kind, key, err)
+		}
+		hs.Phase, hs.CompletedAt = phase.String(), &now
+		return "", nil
+	case release.HookPhaseFailed:
+		hs.Phase, hs.CompletedAt = phase.String(), &now
+		return "", fmt.Errorf("hook %s %q failed", gvk.Kind, key)
+	}
+	if r.HookTimeout > 0 && hs.StartedAt != nil && now.Sub(hs.StartedAt.Time) >= r.HookTimeout {
+		hs.Phase, hs.CompletedAt = release.HookPhaseFailed.String(), &now
+		return "", fmt.Errorf("hook %s %q did not complete within %s", gvk.Kind, key, r.HookTimeout)
+	}
+	return fmt.Sprintf("%s %q is running", gvk.Kind, key), nil
+}
+
+// hookPhase returns the phase of the Job or Pod live that runs as a hook.
+func hookPhase(live *unstructured.Unstructured) (release.HookPhase, error) {
+	switch live.GetKind() {
+	case "Job":
+		job := &batchv1.Job{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(live.Object, job); err != nil {
+			return release.HookPhaseUnknown, err
+		}
+		for _, c := range job.Status.Conditions {
+			if c.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch c.Type {
+			case batchv1.JobComplete:
+				return release.HookPhaseSucceeded, nil
+			case batchv1.JobFailed:
+				return release.HookPhaseFailed, nil
+			}
+		}
+	case "Pod":
+		pod := &corev1.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(live.Object, pod); err != nil {
+			return release.HookPhaseUnknown, err
+		}
+		switch pod.Status.Phase {
+		case corev1.PodSucceeded:
+			return release.HookPhaseSucceeded, nil
+		case corev1.PodFailed:
+			return release.HookPhaseFailed, nil
+		}
+	}
+	return release.HookPhaseRunning, nil
+}
+
+// hookStatusFor returns the status of hook in the status of bi, which is added
+// when the hook has not run yet.
+func hookStatusFor(bi *rukpakv1alpha1.BundleInstance, hook client.Object) *rukpakv1alpha1.HookStatus {
+	kind := hook.GetObjectKind().GroupVersionKind().Kind
+	for i := range bi.Status.Hooks {
+		if hs := &bi.Status.Hooks[i]; hs.Kind == kind && hs.Name == hook.GetName() {
+			return hs
+		}
+	}
+	bi.Status.Hooks = append(bi.Status.Hooks, rukpakv1alpha1.HookStatus{Kind: kind, Name: hook.GetName()})
+	return &bi.Status.Hooks[len(bi.Status.Hooks)-1]
+}
+
+// syncHookStatuses sets the status of the hooks of bi to the statuses of
+// hooks, in their order, keeping the last run of the hooks that ran before.
+func syncHookStatuses(bi *rukpakv1alpha1.BundleInstance, hooks []client.Object) error {
+	var statuses []rukpakv1alpha1.HookStatus
+	for _, hook := range hooks {
+		events, err := manifests.HookEvents(hook)
+		if err != nil {
+			return err
+		}
+		hs := *hookStatusFor(bi, hook)
+		hs.Events = events
+		statuses = append(statuses, hs)
+	}
+	bi.Status.Hooks = statuses
+	return nil
+}
+
+// pendingPostHookEvent returns the post-install or post-upgrade event whose
+// hooks have not all succeeded yet for the installed content with the given
+// hash, if any.
+func pendingPostHookEvent(bi *rukpakv1alpha1.BundleInstance, hash string) string {
+	for _, hs := range bi.Status.Hooks {
+		if hs.ContentHash != hash || hs.Phase == release.HookPhaseSucceeded.String() {
+			continue
+		}
+		if hs.Event == manifests.HookPostInstall || hs.Event == manifests.HookPostUpgrade {
+			return hs.Event
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/release"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/manifests"
+)
+
+var _ = Describe("hooks", func() {
+	var (
+		ctx   context.Context
+		r     *BundleInstanceReconciler
+		bi    *rukpakv1alpha1.BundleInstance
+		hooks []client.Object
+		key   client.ObjectKey
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		r = &BundleInstanceReconciler{Client: newFakeClient(), Scheme: scheme, ReleaseNamespace: "default"}
+		bi = &rukpakv1alpha1.BundleInstance{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha1.GroupVersion.String(), Kind: "BundleInstance"},
+			ObjectMeta: metav1.ObjectMeta{Name: "hooked", UID: "bi-uid"},
+		}
+		job := &unstructured.Unstructured{}
+		job.SetAPIVersion("batch/v1")
+		job.SetKind("Job")
+		job.SetNamespace("default")
+		job.SetName("migrate")
+		job.SetAnnotations(map[string]string{manifests.HookAnnotation: "pre-install,pre-upgrade"})
+		hooks = []client.Object{job}
+		key = client.ObjectKeyFromObject(job)
+		Expect(syncHookStatuses(bi, hooks)).To(Succeed())
+	})
+
+	runHooks := func(event string) (string, error) {
+		return r.runHooks(ctx, r.Client, bi, hooks, event, "sha256:0000")
+	}
+	setJobCondition := func(condition batchv1.JobConditionType) {
+		job := &batchv1.Job{}
+		Expect(r.Get(ctx, key, job)).To(Succeed())
+		job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{Type: condition, Status: corev1.ConditionTrue})
+		Expect(r.Status().Update(ctx, job)).To(Succeed())
+	}
+	expectNoJob := func() {
+		err := r.Get(ctx, key, &batchv1.Job{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the Job should not exist: %v", err)
+	}
+
+	It("creates the hooks once and checks them until they succeed", func() {
+		pending, err := runHooks(manifests.HookPreInstall)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(ContainSubstring(`Job "default/migrate" is running`))
+		job := &batchv1.Job{}
+		Expect(r.Get(ctx, key, job)).To(Succeed())
+		Expect(job.Annotations).To(HaveKeyWithValue(hookRunAnnotation, "pre-install/sha256:0000"))
+		Expect(job.OwnerReferences).To(ContainElement(HaveField("UID", bi.UID)))
+		Expect(bi.Status.Hooks).To(ConsistOf(And(
+			HaveField("Event", manifests.HookPreInstall),
+			HaveField("Phase", release.HookPhaseRunning.String()),
+			HaveField("Events", []string{manifests.HookPreInstall, manifests.HookPreUpgrade}),
+		)))
+
+		pending, err = runHooks(manifests.HookPreInstall)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).NotTo(BeEmpty())
+
+		setJobCondition(batchv1.JobComplete)
+		pending, err = runHooks(manifests.HookPreInstall)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+		Expect(bi.Status.Hooks[0].Phase).To(Equal(release.HookPhaseSucceeded.String()))
+		Expect(bi.Status.Hooks[0].CompletedAt).NotTo(BeNil())
+		expectNoJob()
+
+		// A hook that succeeded is not run again for the same content.
+		pending, err = runHooks(manifests.HookPreInstall)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+		expectNoJob()
+	})
+
+	It("keeps a failed hook, and only runs it again once it is deleted", func() {
+		_, err := runHooks(manifests.HookPreInstall)
+		Expect(err).NotTo(HaveOccurred())
+		setJobCondition(batchv1.JobFailed)
+
+		for i := 0; i < 2; i++ {
+			_, err = runHooks(manifests.HookPreInstall)
+			Expect(err).To(MatchError(ContainSubstring(`hook Job "default/migrate" failed`)))
+			Expect(r.Get(ctx, key, &batchv1.Job{})).To(Succeed())
+		}
+		Expect(bi.Status.Hooks[0].Phase).To(Equal(release.HookPhaseFailed.String()))
+
+		Expect(r.Delete(ctx, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}})).To(Succeed())
+		pending, err := runHooks(manifests.HookPreInstall)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(ContainSubstring("is running"))
+		Expect(bi.Status.Hooks[0].Phase).To(Equal(release.HookPhaseRunning.String()))
+	})
+
+	It("fails a hook that runs for longer than the hook timeout", func() {
+		r.HookTimeout = time.Minute
+		_, err := runHooks(manifests.HookPreInstall)
+		Expect(err).NotTo(HaveOccurred())
+		startedAt := metav1.NewTime(time.Now().Add(-time.Hour))
+		bi.Status.Hooks[0].StartedAt = &startedAt
+
+		_, err = runHooks(manifests.HookPreInstall)
+		Expect(err).To(MatchError(ContainSubstring("did not complete within 1m0s")))
+		Expect(bi.Status.Hooks[0].Phase).To(Equal(release.HookPhaseFailed.String()))
+	})
+
+	It("deletes the hook of an earlier run before running it again", func() {
+		_, err := runHooks(manifests.HookPreInstall)
+		Expect(err).NotTo(HaveOccurred())
+
+		pending, err := runHooks(manifests.HookPreUpgrade)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(ContainSubstring("of an earlier run is being deleted"))
+		expectNoJob()
+
+		pending, err = runHooks(manifests.HookPreUpgrade)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(ContainSubstring("is running"))
+		Expect(bi.Status.Hooks[0].Event).To(Equal(manifests.HookPreUpgrade))
+	})
+
+	It("only runs the hooks of the event", func() {
+		pending, err := runHooks(manifests.HookPostInstall)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+		expectNoJob()
+	})
+
+	Describe("pendingPostHookEvent", func() {
+		It("returns the post event whose hooks have not succeeded for the content", func() {
+			bi.Status.Hooks = []rukpakv1alpha1.HookStatus{
+				{Kind: "Job", Name: "migrate", Event: manifests.HookPreUpgrade, ContentHash: "sha256:0000", Phase: release.HookPhaseSucceeded.String()},
+				{Kind: "Job", Name: "notify", Event: manifests.HookPostUpgrade, ContentHash: "sha256:0000", Phase: release.HookPhaseRunning.String()},
+			}
+			Expect(pendingPostHookEvent(bi, "sha256:0000")).To(Equal(manifests.HookPostUpgrade))
+			Expect(pendingPostHookEvent(bi, "sha256:1111")).To(BeEmpty())
+
+			bi.Status.Hooks[1].Phase = release.HookPhaseSucceeded.String()
+			Expect(pendingPostHookEvent(bi, "sha256:0000")).To(BeEmpty())
+		})
+	})
+})
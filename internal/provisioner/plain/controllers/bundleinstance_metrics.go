@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+// The operations and results of the installs of BundleInstances that are
+// recorded in their metrics.
+const (
+	operationInstall = "install"
+	operationUpgrade = "upgrade"
+
+	resultSucceeded = "succeeded"
+	resultFailed    = "failed"
+	resultTimedOut  = "timed_out"
+)
+
+// installDuration is the duration of the installs and upgrades of the
+// releases of BundleInstances. Its bundleinstance label is only set when the
+// RukpakConfig configures per-BundleInstance metrics.
+var installDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "rukpak_bundleinstance_install_duration_seconds",
+	Help:    "Duration of the installs and upgrades of the releases of BundleInstances.",
+	Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+}, []string{"bundleinstance", "operation", "result"})
+
+// RegisterMetrics registers the metrics of BundleInstances with registerer.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	return registerer.Register(installDuration)
+}
+
+// observeInstall records the duration of the install or upgrade of bi that
+// started at start and returned err, as configured by the metrics options of
+// the RukpakConfig.
+func (r *BundleInstanceReconciler) observeInstall(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, operation string, start time.Time, err error) {
+	result := resultSucceeded
+	switch {
+	case errors.Is(err, errInstallTimedOut):
+		result = resultTimedOut
+	case err != nil:
+		result = resultFailed
+	}
+	name := ""
+	config, err := util.GetRukpakConfig(ctx, r.Client)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "read metrics options, recording aggregated metrics")
+	} else if config.Metrics != nil && config.Metrics.PerBundleInstance {
+		name = bi.Name
+	}
+	installDuration.WithLabelValues(name, operation, result).Observe(time.Since(start).Seconds())
+}
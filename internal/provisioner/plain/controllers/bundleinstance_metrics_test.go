@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+var _ = Describe("observeInstall", func() {
+	var (
+		ctx context.Context
+		bi  *rukpakv1alpha1.BundleInstance
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		bi = &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "olm-crds"}}
+		installDuration.Reset()
+		DeferCleanup(installDuration.Reset)
+	})
+
+	It("aggregates the metrics of BundleInstances by default", func() {
+		r := &BundleInstanceReconciler{Client: newFakeClient()}
+		r.observeInstall(ctx, bi, operationInstall, time.Now(), nil)
+		r.observeInstall(ctx, &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "other"}}, operationInstall, time.Now(), nil)
+		Expect(testutil.CollectAndCount(installDuration)).To(Equal(1))
+	})
+
+	It("labels the metrics of each BundleInstance when the RukpakConfig configures it", func() {
+		config := &rukpakv1alpha1.RukpakConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: rukpakv1alpha1.RukpakConfigName},
+			Spec:       rukpakv1alpha1.RukpakConfigSpec{Metrics: &rukpakv1alpha1.MetricsConfig{PerBundleInstance: true}},
+		}
+		r := &BundleInstanceReconciler{Client: newFakeClient(config)}
+		r.observeInstall(ctx, bi, operationInstall, time.Now(), nil)
+		r.observeInstall(ctx, bi, operationUpgrade, time.Now(), errInstallTimedOut)
+		r.observeInstall(ctx, &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "other"}}, operationUpgrade, time.Now(), errors.New("failed"))
+		Expect(testutil.CollectAndCount(installDuration)).To(Equal(3))
+		Expect(installDuration.DeleteLabelValues(bi.Name, operationUpgrade, resultTimedOut)).To(BeTrue())
+		Expect(installDuration.DeleteLabelValues("other", operationUpgrade, resultFailed)).To(BeTrue())
+	})
+})
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/policy"
+	"github.com/operator-framework/rukpak/internal/util"
+	"github.com/operator-framework/rukpak/pkg/status"
+)
+
+// maxPolicyViolations is the number of policy violations that are listed in
+// the Installed condition of a BundleInstance.
+const maxPolicyViolations = 20
+
+// checkPolicies checks objs against the policies of the Policies of the
+// reconciler and the policy webhooks of the RukpakConfig before they are
+// installed. The objects that violate them are reported in the Installed
+// condition of bi instead of being installed, and an error is returned so
+// that the check is retried once the bundle or the policies are fixed.
+func (r *BundleInstanceReconciler) checkPolicies(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, objs []client.Object) error {
+	config, err := util.GetRukpakConfig(ctx, r.Client)
+	if err != nil {
+		return err
+	}
+	webhooks, err := policy.WebhooksFor(config)
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.PolicyCheckFailed, err.Error()))
+		return err
+	}
+	var violations []policy.Violation
+	for _, checker := range append(append([]policy.Checker{}, r.Policies...), webhooks...) {
+		found, err := checker.Check(ctx, bi, objs)
+		if err != nil {
+			err = fmt.Errorf("check policies: %w", err)
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.PolicyCheckFailed, err.Error()))
+			return err
+		}
+		violations = append(violations, found...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	var messages []string
+	for i, v := range violations {
+		if i == maxPolicyViolations {
+			messages = append(messages, fmt.Sprintf("and %d more", len(violations)-maxPolicyViolations))
+			break
+		}
+		messages = append(messages, v.String())
+	}
+	err = fmt.Errorf("bundle content violates policies: %s", strings.Join(messages, "; "))
+	meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.PolicyViolation, err.Error()))
+	return err
+}
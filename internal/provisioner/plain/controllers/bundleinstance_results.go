@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/content"
+)
+
+// installResult returns the outcome of applying obj of bi in state, with the error
+// message msg of a Failed object.
+func (r *BundleInstanceReconciler) installResult(bi *rukpakv1alpha1.BundleInstance, obj client.Object, state, msg string) rukpakv1alpha1.InstalledObject {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	namespace := obj.GetNamespace()
+	if key, err := r.installedObjectKey(bi, obj); err == nil {
+		namespace = key.Namespace
+	}
+	return rukpakv1alpha1.InstalledObject{
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+		Name:      obj.GetName(),
+		Namespace: namespace,
+		State:     state,
+		Message:   msg,
+	}
+}
+
+// applyObjectResult applies obj like applyObject, and returns whether the
+// apply created, updated or left unchanged its installed object.
+func (r *BundleInstanceReconciler) applyObjectResult(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, obj client.Object, opts ...client.PatchOption) (string, error) {
+	applied, err := r.installedObject(bi, obj)
+	if err != nil {
+		return "", err
+	}
+	live := &metav1.PartialObjectMetadata{}
+	live.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(applied), live); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", err
+		}
+		live = nil
+	}
+	if err := cl.Patch(ctx, applied, client.Apply, opts...); err != nil {
+		return "", err
+	}
+	switch {
+	case live == nil:
+		return rukpakv1alpha1.InstalledObjectCreated, nil
+	case live.GetResourceVersion() != applied.GetResourceVersion():
+		return rukpakv1alpha1.InstalledObjectUpdated, nil
+	default:
+		return rukpakv1alpha1.InstalledObjectUnchanged, nil
+	}
+}
+
+// releaseInstallResults returns the outcome of installing the templates of
+// chrt over the objects of rel, which is nil when nothing is installed yet,
+// for each object of objs of bi: Created when rel does not have it, Updated
+// when its template changed and Unchanged otherwise.
+func (r *BundleInstanceReconciler) releaseInstallResults(bi *rukpakv1alpha1.BundleInstance, rel *release.Release, chrt *chart.Chart, objs []client.Object) ([]rukpakv1alpha1.InstalledObject, error) {
+	d, err := releaseDiff(rel, chrt)
+	if err != nil {
+		return nil, err
+	}
+	states := map[content.ObjectRef]string{}
+	for _, ref := range d.Added {
+		states[ref] = rukpakv1alpha1.InstalledObjectCreated
+	}
+	for _, od := range d.Changed {
+		states[od.ObjectRef] = rukpakv1alpha1.InstalledObjectUpdated
+	}
+	results := make([]rukpakv1alpha1.InstalledObject, 0, len(objs))
+	for _, obj := range objs {
+		state, ok := states[content.ObjectRef{
+			Group:     obj.GetObjectKind().GroupVersionKind().Group,
+			Kind:      obj.GetObjectKind().GroupVersionKind().Kind,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+		}]
+		if !ok {
+			state = rukpakv1alpha1.InstalledObjectUnchanged
+		}
+		results = append(results, r.installResult(bi, obj, state, ""))
+	}
+	return results, nil
+}
+
+// recordReleaseFailures records the objects of objs that fail to apply in
+// dry-run mode as Failed in the install results of bi, after a release failed
+// to install or upgrade, as the error of Helm does not reliably identify the
+// object that it failed on.
+func (r *BundleInstanceReconciler) recordReleaseFailures(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, objs []client.Object) {
+	cl, err := r.contentClient(bi, nil)
+	if err != nil {
+		return
+	}
+	var failed []rukpakv1alpha1.InstalledObject
+	for _, obj := range objs {
+		applied, err := r.installedObject(bi, obj)
+		if err == nil {
+			err = cl.Patch(ctx, applied, client.Apply, client.FieldOwner(fieldManager(bi)), client.ForceOwnership, client.DryRunAll)
+		}
+		if err != nil {
+			failed = append(failed, r.installResult(bi, obj, rukpakv1alpha1.InstalledObjectFailed, err.Error()))
+		}
+	}
+	mergeInstallResults(bi, failed)
+}
+
+// setInstallResults replaces the install results of bi with results, the
+// outcome of applying every object of its bundle.
+func setInstallResults(bi *rukpakv1alpha1.BundleInstance, results []rukpakv1alpha1.InstalledObject) {
+	bi.Status.InstalledObjects = nil
+	mergeInstallResults(bi, results)
+}
+
+// mergeInstallResults records results in the install results of bi, which
+// keep the outcome of the earlier applies of the other objects, sorted by
+// group, kind, namespace and name.
+func mergeInstallResults(bi *rukpakv1alpha1.BundleInstance, results []rukpakv1alpha1.InstalledObject) {
+	if len(results) == 0 {
+		return
+	}
+	idx := map[content.ObjectRef]int{}
+	for i, o := range bi.Status.InstalledObjects {
+		idx[installResultRef(o)] = i
+	}
+	for _, o := range results {
+		if i, ok := idx[installResultRef(o)]; ok {
+			bi.Status.InstalledObjects[i] = o
+			continue
+		}
+		idx[installResultRef(o)] = len(bi.Status.InstalledObjects)
+		bi.Status.InstalledObjects = append(bi.Status.InstalledObjects, o)
+	}
+	sort.Slice(bi.Status.InstalledObjects, func(i, j int) bool {
+		return installResultRef(bi.Status.InstalledObjects[i]).String() < installResultRef(bi.Status.InstalledObjects[j]).String()
+	})
+}
+
+func installResultRef(o rukpakv1alpha1.InstalledObject) content.ObjectRef {
+	return content.ObjectRef{Group: o.Group, Kind: o.Kind, Namespace: o.Namespace, Name: o.Name}
+}
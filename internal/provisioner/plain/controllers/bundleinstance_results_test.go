@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+var _ = Describe("install results", func() {
+	var (
+		ctx context.Context
+		r   *BundleInstanceReconciler
+		bi  *rukpakv1alpha1.BundleInstance
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		r = &BundleInstanceReconciler{Scheme: scheme, ReleaseNamespace: "default"}
+		bi = &rukpakv1alpha1.BundleInstance{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha1.GroupVersion.String(), Kind: "BundleInstance"},
+			ObjectMeta: metav1.ObjectMeta{Name: "results", UID: "bi-uid"},
+		}
+	})
+
+	configMap := func(name, value string) *unstructured.Unstructured {
+		cm := &unstructured.Unstructured{}
+		cm.SetAPIVersion("v1")
+		cm.SetKind("ConfigMap")
+		cm.SetNamespace("default")
+		cm.SetName(name)
+		Expect(unstructured.SetNestedStringMap(cm.Object, map[string]string{"key": value}, "data")).To(Succeed())
+		return cm
+	}
+	manifest := func(objs ...*unstructured.Unstructured) string {
+		var out string
+		for _, obj := range objs {
+			data, err := obj.MarshalJSON()
+			Expect(err).NotTo(HaveOccurred())
+			out += "---\n" + string(data) + "\n"
+		}
+		return out
+	}
+	states := func(results []rukpakv1alpha1.InstalledObject) map[string]string {
+		byName := map[string]string{}
+		for _, o := range results {
+			byName[o.Name] = o.State
+		}
+		return byName
+	}
+
+	Describe("releaseInstallResults", func() {
+		It("compares the templates of the chart with the objects of the release", func() {
+			unchanged, updated, created := configMap("unchanged", "value"), configMap("updated", "new"), configMap("created", "value")
+			chrt := &chart.Chart{Metadata: &chart.Metadata{Name: "results"}}
+			for _, obj := range []*unstructured.Unstructured{unchanged, updated, created} {
+				chrt.Templates = append(chrt.Templates, &chart.File{Name: obj.GetName(), Data: []byte(manifest(obj))})
+			}
+			objs := []client.Object{unchanged, updated, created}
+
+			results, err := r.releaseInstallResults(bi, nil, chrt, objs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(states(results)).To(Equal(map[string]string{
+				"unchanged": rukpakv1alpha1.InstalledObjectCreated,
+				"updated":   rukpakv1alpha1.InstalledObjectCreated,
+				"created":   rukpakv1alpha1.InstalledObjectCreated,
+			}), "every object should be created by the first install")
+
+			rel := &release.Release{Manifest: manifest(unchanged, configMap("updated", "old"))}
+			results, err = r.releaseInstallResults(bi, rel, chrt, objs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(states(results)).To(Equal(map[string]string{
+				"unchanged": rukpakv1alpha1.InstalledObjectUnchanged,
+				"updated":   rukpakv1alpha1.InstalledObjectUpdated,
+				"created":   rukpakv1alpha1.InstalledObjectCreated,
+			}))
+			Expect(results[0]).To(Equal(rukpakv1alpha1.InstalledObject{Version: "v1", Kind: "ConfigMap", Namespace: "default", Name: "unchanged", State: rukpakv1alpha1.InstalledObjectUnchanged}))
+		})
+	})
+
+	Describe("mergeInstallResults", func() {
+		It("keeps the results of the other objects, sorted", func() {
+			setInstallResults(bi, []rukpakv1alpha1.InstalledObject{
+				r.installResult(bi, configMap("b", "value"), rukpakv1alpha1.InstalledObjectCreated, ""),
+				r.installResult(bi, configMap("a", "value"), rukpakv1alpha1.InstalledObjectCreated, ""),
+			})
+			mergeInstallResults(bi, []rukpakv1alpha1.InstalledObject{
+				r.installResult(bi, configMap("b", "value"), rukpakv1alpha1.InstalledObjectFailed, "admission webhook denied the request"),
+			})
+			Expect(bi.Status.InstalledObjects).To(HaveLen(2))
+			Expect(bi.Status.InstalledObjects[0]).To(And(HaveField("Name", "a"), HaveField("State", rukpakv1alpha1.InstalledObjectCreated)))
+			Expect(bi.Status.InstalledObjects[1]).To(And(
+				HaveField("Name", "b"),
+				HaveField("State", rukpakv1alpha1.InstalledObjectFailed),
+				HaveField("Message", "admission webhook denied the request"),
+			))
+
+			setInstallResults(bi, nil)
+			Expect(bi.Status.InstalledObjects).To(BeEmpty())
+		})
+	})
+
+	Describe("applyObjectResult", func() {
+		BeforeEach(func() {
+			requireAPIServer()
+			c, err := client.New(cfg, client.Options{Scheme: scheme})
+			Expect(err).NotTo(HaveOccurred())
+			r.Client = c
+		})
+
+		It("reports whether the apply created, updated or left the object unchanged", func() {
+			cm := configMap("applied-"+string(bi.UID), "value")
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(r.Delete(ctx, cm.DeepCopy()))).To(Succeed())
+			})
+			apply := func(obj client.Object) string {
+				state, err := r.applyObjectResult(ctx, r.Client, bi, obj, client.FieldOwner(fieldManager(bi)), client.ForceOwnership)
+				Expect(err).NotTo(HaveOccurred())
+				return state
+			}
+
+			Expect(apply(cm)).To(Equal(rukpakv1alpha1.InstalledObjectCreated))
+			Expect(apply(cm)).To(Equal(rukpakv1alpha1.InstalledObjectUnchanged))
+			Expect(apply(configMap(cm.GetName(), "changed"))).To(Equal(rukpakv1alpha1.InstalledObjectUpdated))
+		})
+	})
+})
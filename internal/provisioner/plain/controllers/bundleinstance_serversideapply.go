@@ -0,0 +1,557 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/manifests"
+	"github.com/operator-framework/rukpak/internal/tracing"
+	"github.com/operator-framework/rukpak/pkg/status"
+)
+
+// maxFieldManagerLength is the maximum length of the name of a field manager.
+const maxFieldManagerLength = 128
+
+// fieldManager returns the field manager with which the ServerSideApply
+// engine applies the objects of bi. Names that would be too long are
+// shortened, and suffixed with a hash of the name of bi to keep them unique.
+func fieldManager(bi *rukpakv1alpha1.BundleInstance) string {
+	manager := fmt.Sprintf("%s/%s", plainBundleProvisionerID, bi.Name)
+	if len(manager) <= maxFieldManagerLength {
+		return manager
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(bi.Name)))[:10]
+	return fmt.Sprintf("%s-%s", manager[:maxFieldManagerLength-len(hash)-1], hash)
+}
+
+// reconcileServerSideApply installs, upgrades and reconciles the objects objs
+// of the bundle b of bi with server-side apply rather than a release. The
+// applied objects are recorded in the inventory of bi, and the objects of the
+// inventory that are no longer in objs are deleted once objs are applied.
+func (r *BundleInstanceReconciler) reconcileServerSideApply(ctx context.Context, bi *rukpakv1alpha1.BundleInstance, b *rukpakv1alpha1.Bundle, objs, hooks []client.Object, pivotFrom string, warnings rest.WarningHandler) (_ ctrl.Result, err error) {
+	ctx, span := tracing.Start(ctx, "BundleInstance.ServerSideApply", tracing.AttributeObjects.Int(len(objs)))
+	defer func() { tracing.End(span, err) }()
+	bi.Status.ReconcileProgress = nil
+	bi.Status.Hooks = nil
+	if len(hooks) > 0 {
+		msg := fmt.Sprintf("the bundle has %d hooks, which the %s engine does not run", len(hooks), rukpakv1alpha1.EngineServerSideApply)
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.InstallFailed, msg))
+		return ctrl.Result{}, nil
+	}
+	cl, err := r.contentClient(bi, warnings)
+	if err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ErrorGettingClient, err.Error()))
+		return ctrl.Result{}, err
+	}
+
+	dryRun := bi.Spec.InstallMode == rukpakv1alpha1.InstallModeDryRun
+	if r.ReadOnly || dryRun {
+		changes, err := r.serverSideApplyChanges(ctx, cl, bi, objs)
+		if err != nil {
+			meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.ErrorGettingReleaseState, err))
+			return ctrl.Result{}, err
+		}
+		summary := fmt.Sprintf("%d objects would be added, %d removed and %d changed", len(changes.Added), len(changes.Removed), len(changes.Changed))
+		switch {
+		case len(changes.Added)+len(changes.Removed)+len(changes.Changed) == 0:
+			bi.Status.PendingChanges = nil
+			return r.completeInstall(ctx, bi, b, nil, objs, pivotFrom)
+		case r.ReadOnly:
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ReadOnlyMode, fmt.Sprintf("%s but the controller is running in read-only mode", summary)))
+		default:
+			changes.BundleName = bi.Spec.BundleName
+			bi.Status.PendingChanges = changes
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.DryRunMode, fmt.Sprintf("%s in the %s install mode", summary, rukpakv1alpha1.InstallModeApply)))
+		}
+		return ctrl.Result{}, nil
+	}
+	bi.Status.PendingChanges = nil
+
+	// Without a release to compare with, the applied content is unchanged
+	// when the same generation of bi has already installed the same bundle.
+	// Its drift is then only corrected, by applying it again, when the drift
+	// policy of bi is Correct.
+	installed := meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeInstalled)
+	unchanged := pivotFrom == "" && bi.Status.InstalledBundleName == bi.Spec.BundleName &&
+		installed != nil && installed.Status == metav1.ConditionTrue && installed.ObservedGeneration == bi.Generation
+	driftPolicy := r.driftPolicy(bi)
+	if unchanged && (driftPolicy == rukpakv1alpha1.DriftPolicyDetect || driftPolicy == rukpakv1alpha1.DriftPolicyIgnore) {
+		var drifted []string
+		if driftPolicy == rukpakv1alpha1.DriftPolicyDetect {
+			if drifted, err = r.detectDrift(ctx, cl, bi, objs); err != nil {
+				meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Drifted(metav1.ConditionUnknown, status.DriftCheckFailed, err.Error()))
+				return ctrl.Result{}, err
+			}
+		}
+		reportDrift(bi, driftPolicy, drifted)
+		return r.completeInstall(ctx, bi, b, nil, objs, pivotFrom)
+	}
+
+	if err := r.preflight(ctx, bi, objs); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.checkPolicies(ctx, bi, objs); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.checkCRDUpgrades(ctx, cl, bi, objs); err != nil {
+		return ctrl.Result{}, err
+	}
+	// Drift is corrected by taking the drifted fields back from the field
+	// managers that changed them, as detectDrift assumes.
+	opts := []client.PatchOption{client.FieldOwner(fieldManager(bi))}
+	if bi.Spec.ConflictPolicy == rukpakv1alpha1.ConflictPolicyForce || (unchanged && driftPolicy == rukpakv1alpha1.DriftPolicyCorrect) {
+		opts = append(opts, client.ForceOwnership)
+	}
+	results := make([]rukpakv1alpha1.InstalledObject, 0, len(objs))
+	// The objects that the apply creates are recorded as created by bi in its
+	// inventory, as only those are deleted once they are removed from the
+	// bundle or bi is uninstalled.
+	created := map[rukpakv1alpha1.ObjectReference]bool{}
+	for _, ref := range bi.Status.Inventory {
+		created[ref.ObjectReference] = ref.Created
+	}
+	apply := func(obj client.Object) error {
+		state, err := r.applyObjectResult(ctx, cl, bi, obj, opts...)
+		if err != nil {
+			err = fmt.Errorf("apply %s %q: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+			results = append(results, r.installResult(bi, obj, rukpakv1alpha1.InstalledObjectFailed, err.Error()))
+			return err
+		}
+		results = append(results, r.installResult(bi, obj, state, ""))
+		if state == rukpakv1alpha1.InstalledObjectCreated {
+			key, err := r.installedObjectKey(bi, obj)
+			if err != nil {
+				return err
+			}
+			created[inventoryReference(obj, key)] = true
+		}
+		return nil
+	}
+	inventoryOf := func(refs []rukpakv1alpha1.ObjectReference) []rukpakv1alpha1.InventoryReference {
+		inventory := make([]rukpakv1alpha1.InventoryReference, 0, len(refs))
+		for _, ref := range refs {
+			inventory = append(inventory, rukpakv1alpha1.InventoryReference{ObjectReference: ref, Created: created[ref]})
+		}
+		return inventory
+	}
+	inventory := make([]rukpakv1alpha1.ObjectReference, 0, len(objs))
+	applyFailed := func(err error) (ctrl.Result, error) {
+		// The objects that were applied before the failure are recorded too.
+		bi.Status.Inventory = mergeInventories(bi.Status.Inventory, inventoryOf(inventory))
+		mergeInstallResults(bi, results)
+		reason := status.InstallFailed
+		if apierrors.IsConflict(err) {
+			// Server-side apply reports the fields of the object that are
+			// managed by other field managers with different values.
+			reason = status.ObjectConflict
+		}
+		meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, reason, err))
+		return ctrl.Result{}, err
+	}
+
+	// Nothing is applied while objects of the bundle conflict with existing
+	// objects. The objects whose kind is not served yet, as their CRD is
+	// applied with the bundle, cannot exist.
+	var conflicts []string
+	for _, obj := range objs {
+		key, err := r.installedObjectKey(bi, obj)
+		if meta.IsNoMatchError(errors.Unwrap(err)) {
+			continue
+		}
+		if err != nil {
+			return applyFailed(err)
+		}
+		conflict, err := r.serverSideApplyConflict(ctx, cl, bi, obj, key)
+		if err != nil {
+			return applyFailed(err)
+		}
+		if conflict != "" {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	if len(conflicts) > 0 {
+		err := fmt.Errorf("objects of the bundle conflict with existing objects: %s", strings.Join(conflicts, "; "))
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.ObjectConflict, err.Error()))
+		return ctrl.Result{}, err
+	}
+
+	// The CRDs and Namespaces of the bundle are applied first, as the custom
+	// resources of the CRDs and the objects in the Namespaces cannot be
+	// applied before them.
+	var prerequisites, others []client.Object
+	for _, obj := range objs {
+		switch obj.GetObjectKind().GroupVersionKind().GroupKind() {
+		case apiextensionsv1.Kind("CustomResourceDefinition"), corev1.SchemeGroupVersion.WithKind("Namespace").GroupKind():
+			prerequisites = append(prerequisites, obj)
+		default:
+			others = append(others, obj)
+		}
+	}
+	for _, obj := range prerequisites {
+		inventory = append(inventory, inventoryReference(obj, client.ObjectKeyFromObject(obj)))
+		if err := apply(obj); err != nil {
+			return applyFailed(err)
+		}
+	}
+	bi.Status.Inventory = mergeInventories(bi.Status.Inventory, inventoryOf(inventory))
+	for _, obj := range others {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if _, err := r.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			if !meta.IsNoMatchError(err) {
+				return applyFailed(fmt.Errorf("determine mapping of %s %q: %w", gvk, obj.GetName(), err))
+			}
+			meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.CRDsNotEstablished, "waiting for the custom resource definitions of the bundle to be established"))
+			return ctrl.Result{RequeueAfter: crdEstablishedPollInterval}, nil
+		}
+	}
+
+	for _, obj := range others {
+		key, err := r.installedObjectKey(bi, obj)
+		if err != nil {
+			return applyFailed(err)
+		}
+		inventory = append(inventory, inventoryReference(obj, key))
+	}
+	bi.Status.Inventory = mergeInventories(bi.Status.Inventory, inventoryOf(inventory))
+
+	pending, err := r.applyWaves(ctx, cl, bi, others, apply)
+	if err != nil {
+		return applyFailed(err)
+	}
+	if pending != "" {
+		bi.Status.Inventory = mergeInventories(bi.Status.Inventory, inventoryOf(inventory))
+		mergeInstallResults(bi, results)
+		meta.SetStatusCondition(&bi.Status.Conditions, status.For(bi).Installed(metav1.ConditionFalse, status.WaveInProgress, pending))
+		return ctrl.Result{RequeueAfter: wavePollInterval}, nil
+	}
+	// applyWaves leaves the objects of the last wave to the caller.
+	waves, err := manifests.Waves(others)
+	if err != nil {
+		return applyFailed(err)
+	}
+	if len(waves) > 0 {
+		for _, obj := range waves[len(waves)-1] {
+			if err := apply(obj); err != nil {
+				return applyFailed(err)
+			}
+		}
+	}
+	if err := r.pruneInventory(ctx, cl, bi, inventory); err != nil {
+		meta.SetStatusCondition(&bi.Status.Conditions, installFailedCondition(bi, status.InstallFailed, err))
+		return ctrl.Result{}, err
+	}
+	bi.Status.Inventory = inventoryOf(inventory)
+	setInstallResults(bi, results)
+	reportDrift(bi, r.driftPolicy(bi), nil)
+
+	switch {
+	case bi.Status.InstalledBundleName == "":
+		r.eventf(bi, corev1.EventTypeNormal, rukpakv1alpha1.ReasonInstalled, "installed bundle %q", bi.Spec.BundleName)
+	case pivotFrom != "":
+		r.eventf(bi, corev1.EventTypeNormal, rukpakv1alpha1.ReasonUpgraded, "upgraded from bundle %q to bundle %q", pivotFrom, bi.Spec.BundleName)
+	}
+	return r.completeInstall(ctx, bi, b, nil, objs, pivotFrom)
+}
+
+// serverSideApplyConflict returns a description of the existing object of
+// obj at key when its conflict policy does not allow bi to apply it, or an
+// empty string when it can be applied. Objects that are labeled as owned by
+// bi have been applied for bi before.
+func (r *BundleInstanceReconciler) serverSideApplyConflict(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, obj client.Object, key types.NamespacedName) (string, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	live := &metav1.PartialObjectMetadata{}
+	live.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, key, live); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("get %s %q: %w", gvk.Kind, key, err)
+	}
+	owner := ""
+	if labels := live.GetLabels(); labels["core.rukpak.io/owner-kind"] == "BundleInstance" {
+		owner = labels["core.rukpak.io/owner-name"]
+	}
+	switch {
+	case owner == bi.Name:
+		return "", nil
+	case bi.Spec.ConflictPolicy == rukpakv1alpha1.ConflictPolicyForce:
+		if err := removeOtherControllers(ctx, cl, bi, live); err != nil {
+			return "", fmt.Errorf("take over %s %q: %w", gvk.Kind, key, err)
+		}
+		return "", nil
+	case bi.Spec.ConflictPolicy == rukpakv1alpha1.ConflictPolicyAdopt && owner == "":
+		return "", nil
+	case owner != "":
+		return fmt.Sprintf("%s %q is installed by BundleInstance %q", gvk.Kind, key, owner), nil
+	default:
+		return fmt.Sprintf("%s %q already exists and is not installed by any BundleInstance", gvk.Kind, key), nil
+	}
+}
+
+// serverSideApplyChanges returns the objects of objs that applying them for
+// bi would create or change, and the objects of the inventory of bi that it
+// would delete. Changes are detected by applying the objects in dry-run mode.
+func (r *BundleInstanceReconciler) serverSideApplyChanges(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, objs []client.Object) (*rukpakv1alpha1.PendingChanges, error) {
+	changes := &rukpakv1alpha1.PendingChanges{}
+	desired := make([]rukpakv1alpha1.ObjectReference, 0, len(objs))
+	for _, obj := range objs {
+		key, err := r.installedObjectKey(bi, obj)
+		if meta.IsNoMatchError(errors.Unwrap(err)) {
+			// Objects whose kind is not served yet cannot exist.
+			ref := inventoryReference(obj, client.ObjectKeyFromObject(obj))
+			desired = append(desired, ref)
+			changes.Added = append(changes.Added, ref)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ref := inventoryReference(obj, key)
+		desired = append(desired, ref)
+		fields, found, err := r.dryRunApply(ctx, cl, bi, obj, key)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !found:
+			changes.Added = append(changes.Added, ref)
+		case len(fields) > 0:
+			changes.Changed = append(changes.Changed, ref)
+		}
+	}
+	for _, ref := range removedObjects(bi.Status.Inventory, desired) {
+		if ref.Created {
+			changes.Removed = append(changes.Removed, ref.ObjectReference)
+		}
+	}
+	return changes, nil
+}
+
+// pruneInventory deletes the objects of the inventory of bi that are not in
+// desired and that bi created, unless they are kept on uninstall. The objects
+// that bi adopted are only removed from its ownership.
+func (r *BundleInstanceReconciler) pruneInventory(ctx context.Context, cl client.Client, bi *rukpakv1alpha1.BundleInstance, desired []rukpakv1alpha1.ObjectReference) error {
+	for _, ref := range removedObjects(bi.Status.Inventory, desired) {
+		live, err := r.inventoryObject(ref.ObjectReference)
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !ref.Created {
+			if err := disownInventoryObjects(ctx, cl, bi.UID, []*metav1.PartialObjectMetadata{live}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := cl.Get(ctx, client.ObjectKeyFromObject(live), live); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("get %s %q: %w", ref.Kind, client.ObjectKeyFromObject(live), err)
+		}
+		if live.GetAnnotations()[kube.ResourcePolicyAnno] == kube.KeepPolicy {
+			continue
+		}
+		if err := cl.Delete(ctx, live); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("delete %s %q removed from the bundle: %w", ref.Kind, client.ObjectKeyFromObject(live), err)
+		}
+	}
+	return nil
+}
+
+// uninstallInventory applies the deletion policy of bi to the objects of its
+// inventory, in the same order as the objects of a release are uninstalled,
+// and returns the objects that are still being deleted.
+func (r *BundleInstanceReconciler) uninstallInventory(ctx context.Context, bi *rukpakv1alpha1.BundleInstance) ([]string, error) {
+	cl, err := r.contentClient(bi, nil)
+	if err != nil {
+		return nil, err
+	}
+	crdNames := sets.NewString()
+	for _, ref := range bi.Status.Inventory {
+		if (schema.GroupKind{Group: ref.Group, Kind: ref.Kind}) == apiextensionsv1.Kind("CustomResourceDefinition") {
+			crdNames.Insert(ref.Name)
+		}
+	}
+	// The objects that bi adopted are only removed from its ownership,
+	// whatever its deletion policy.
+	var crds, crs, others, adopted []*metav1.PartialObjectMetadata
+	for _, ref := range bi.Status.Inventory {
+		obj, err := r.inventoryObject(ref.ObjectReference)
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		mapping, err := r.RESTMapper().RESTMapping(schema.GroupKind{Group: ref.Group, Kind: ref.Kind})
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !ref.Created:
+			adopted = append(adopted, obj)
+		case mapping.GroupVersionKind.GroupKind() == apiextensionsv1.Kind("CustomResourceDefinition"):
+			crds = append(crds, obj)
+		case crdNames.Has(fmt.Sprintf("%s.%s", mapping.Resource.Resource, mapping.Resource.Group)):
+			crs = append(crs, obj)
+		default:
+			others = append(others, obj)
+		}
+	}
+
+	if err := disownInventoryObjects(ctx, cl, bi.UID, adopted); err != nil {
+		return nil, err
+	}
+	switch bi.Spec.DeletionPolicy {
+	case rukpakv1alpha1.DeletionPolicyOrphan, rukpakv1alpha1.DeletionPolicyRetain:
+		for _, group := range [][]*metav1.PartialObjectMetadata{crs, others, crds} {
+			if err := disownInventoryObjects(ctx, cl, bi.UID, group); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	default:
+		if remaining, err := deleteInventoryObjects(ctx, cl, crs); err != nil || len(remaining) > 0 {
+			return remaining, err
+		}
+		if remaining, err := deleteInventoryObjects(ctx, cl, others); err != nil || len(remaining) > 0 {
+			return remaining, err
+		}
+		if bi.Spec.RetainCRDs == nil || *bi.Spec.RetainCRDs {
+			return nil, disownInventoryObjects(ctx, cl, bi.UID, crds)
+		}
+		return deleteInventoryObjects(ctx, cl, crds)
+	}
+}
+
+// deleteInventoryObjects deletes the objects of objs, except for the ones that
+// are kept on uninstall, and returns the ones that still exist.
+func deleteInventoryObjects(ctx context.Context, cl client.Client, objs []*metav1.PartialObjectMetadata) ([]string, error) {
+	var remaining []string
+	for _, obj := range objs {
+		key, kind := client.ObjectKeyFromObject(obj), obj.GetObjectKind().GroupVersionKind().Kind
+		if err := cl.Get(ctx, key, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("get %s %q: %w", kind, key, err)
+		}
+		if obj.GetAnnotations()[kube.ResourcePolicyAnno] == kube.KeepPolicy {
+			continue
+		}
+		if obj.GetDeletionTimestamp() == nil {
+			if err := cl.Delete(ctx, obj); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, fmt.Errorf("delete %s %q: %w", kind, key, err)
+			}
+		}
+		remaining = append(remaining, fmt.Sprintf("%s %s", kind, key))
+	}
+	return remaining, nil
+}
+
+// disownInventoryObjects removes the owner references to the BundleInstance
+// with uid from objs, so that they are not garbage collected along with it.
+func disownInventoryObjects(ctx context.Context, cl client.Client, uid types.UID, objs []*metav1.PartialObjectMetadata) error {
+	for _, obj := range objs {
+		key, kind := client.ObjectKeyFromObject(obj), obj.GetObjectKind().GroupVersionKind().Kind
+		if err := cl.Get(ctx, key, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("get %s %q: %w", kind, key, err)
+		}
+		base := obj.DeepCopy()
+		refs := obj.GetOwnerReferences()
+		kept := []metav1.OwnerReference{}
+		for _, ref := range refs {
+			if ref.UID != uid {
+				kept = append(kept, ref)
+			}
+		}
+		if len(kept) == len(refs) {
+			continue
+		}
+		obj.SetOwnerReferences(kept)
+		if err := cl.Patch(ctx, obj, client.MergeFrom(base)); err != nil {
+			return fmt.Errorf("remove owner reference from %s %q: %w", kind, key, err)
+		}
+	}
+	return nil
+}
+
+// inventoryObject returns the metadata of the object of ref, in the preferred
+// version of its kind. A NoMatch error is returned when its kind is not
+// served, in which case the object cannot exist.
+func (r *BundleInstanceReconciler) inventoryObject(ref rukpakv1alpha1.ObjectReference) (*metav1.PartialObjectMetadata, error) {
+	mapping, err := r.RESTMapper().RESTMapping(schema.GroupKind{Group: ref.Group, Kind: ref.Kind})
+	if err != nil {
+		return nil, err
+	}
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(mapping.GroupVersionKind)
+	obj.SetNamespace(ref.Namespace)
+	obj.SetName(ref.Name)
+	return obj, nil
+}
+
+// inventoryReference returns the reference to obj, installed at key, in the
+// inventory of its BundleInstance.
+func inventoryReference(obj client.Object, key types.NamespacedName) rukpakv1alpha1.ObjectReference {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	return rukpakv1alpha1.ObjectReference{Group: gvk.Group, Kind: gvk.Kind, Namespace: key.Namespace, Name: key.Name}
+}
+
+// mergeInventories returns the references of inventory followed by the
+// references of added that are not in inventory. References in both are
+// created when either of them is.
+func mergeInventories(inventory, added []rukpakv1alpha1.InventoryReference) []rukpakv1alpha1.InventoryReference {
+	index := map[rukpakv1alpha1.ObjectReference]int{}
+	merged := make([]rukpakv1alpha1.InventoryReference, 0, len(inventory)+len(added))
+	for _, ref := range append(append([]rukpakv1alpha1.InventoryReference{}, inventory...), added...) {
+		if i, ok := index[ref.ObjectReference]; ok {
+			merged[i].Created = merged[i].Created || ref.Created
+			continue
+		}
+		index[ref.ObjectReference] = len(merged)
+		merged = append(merged, ref)
+	}
+	return merged
+}
+
+// removedObjects returns the references of inventory that are not in desired.
+func removedObjects(inventory []rukpakv1alpha1.InventoryReference, desired []rukpakv1alpha1.ObjectReference) []rukpakv1alpha1.InventoryReference {
+	keep := map[rukpakv1alpha1.ObjectReference]bool{}
+	for _, ref := range desired {
+		keep[ref] = true
+	}
+	var removed []rukpakv1alpha1.InventoryReference
+	for _, ref := range inventory {
+		if !keep[ref.ObjectReference] {
+			removed = append(removed, ref)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/watchmanager"
+)
+
+var _ = Describe("ServerSideApply engine", func() {
+	var (
+		ctx context.Context
+		bi  *rukpakv1alpha1.BundleInstance
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		bi = &rukpakv1alpha1.BundleInstance{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha1.GroupVersion.String(), Kind: "BundleInstance"},
+			ObjectMeta: metav1.ObjectMeta{Name: "ssa", UID: "bi-uid"},
+			Spec: rukpakv1alpha1.BundleInstanceSpec{
+				ProvisionerClassName: plainBundleProvisionerID,
+				BundleName:           "ssa-bundle",
+				Engine:               rukpakv1alpha1.EngineServerSideApply,
+			},
+		}
+	})
+
+	Describe("inventory", func() {
+		var (
+			r        *BundleInstanceReconciler
+			created  *corev1.ConfigMap
+			adopted  *corev1.Namespace
+			ownerRef metav1.OwnerReference
+		)
+
+		BeforeEach(func() {
+			requireAPIServer()
+			c, err := client.New(cfg, client.Options{Scheme: scheme})
+			Expect(err).NotTo(HaveOccurred())
+			r = &BundleInstanceReconciler{Client: c, Scheme: scheme}
+
+			ownerRef = *metav1.NewControllerRef(bi, rukpakv1alpha1.GroupVersion.WithKind("BundleInstance"))
+			owned := metav1.ObjectMeta{
+				Labels:          map[string]string{"core.rukpak.io/owner-kind": "BundleInstance", "core.rukpak.io/owner-name": bi.Name},
+				OwnerReferences: []metav1.OwnerReference{ownerRef},
+			}
+			created = &corev1.ConfigMap{ObjectMeta: *owned.DeepCopy()}
+			created.Namespace, created.GenerateName = "default", "settings-"
+			// The Namespace existed before the BundleInstance adopted it, and
+			// carries its owner label and reference ever since.
+			adopted = &corev1.Namespace{ObjectMeta: *owned.DeepCopy()}
+			adopted.GenerateName = "team-a-"
+			for _, obj := range []client.Object{created, adopted} {
+				obj := obj
+				Expect(c.Create(ctx, obj)).To(Succeed())
+				DeferCleanup(func() {
+					Expect(client.IgnoreNotFound(c.Delete(ctx, obj))).To(Succeed())
+				})
+			}
+			bi.Status.Inventory = []rukpakv1alpha1.InventoryReference{
+				{ObjectReference: rukpakv1alpha1.ObjectReference{Kind: "Namespace", Name: adopted.Name}},
+				{ObjectReference: rukpakv1alpha1.ObjectReference{Kind: "ConfigMap", Namespace: created.Namespace, Name: created.Name}, Created: true},
+			}
+		})
+
+		expectDeleted := func(obj client.Object) {
+			err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "%s should be deleted: %v", obj.GetName(), err)
+		}
+		expectDisowned := func(obj client.Object) {
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(obj), obj)).To(Succeed())
+			Expect(obj.GetOwnerReferences()).NotTo(ContainElement(ownerRef))
+		}
+
+		It("only prunes the removed objects that the BundleInstance created", func() {
+			Expect(r.pruneInventory(ctx, r.Client, bi, nil)).To(Succeed())
+			expectDeleted(created)
+			expectDisowned(adopted)
+		})
+
+		It("only uninstalls the objects that the BundleInstance created", func() {
+			remaining, err := r.uninstallInventory(ctx, bi)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remaining).To(BeEmpty())
+			expectDeleted(created)
+			expectDisowned(adopted)
+		})
+
+		It("applies the default deletion policy of the RukpakConfig", func() {
+			config := &rukpakv1alpha1.RukpakConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: rukpakv1alpha1.RukpakConfigName},
+				Spec:       rukpakv1alpha1.RukpakConfigSpec{DefaultDeletionPolicy: rukpakv1alpha1.DeletionPolicyOrphan},
+			}
+			Expect(r.Create(ctx, config)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(r.Delete(ctx, config))).To(Succeed())
+			})
+			watchCtx, cancel := context.WithCancel(ctx)
+			DeferCleanup(cancel)
+			r.watches = watchmanager.New(nopWatcher{}, func() (cache.Cache, error) { return &informertest.FakeInformers{}, nil }, &handler.EnqueueRequestForObject{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(r.watches.Start(watchCtx)).To(Succeed())
+			}()
+			now := metav1.Now()
+			bi.DeletionTimestamp = &now
+
+			_, err := r.finalize(ctx, bi, bi.Status.DeepCopy())
+			Expect(err).NotTo(HaveOccurred())
+			expectDisowned(created)
+			expectDisowned(adopted)
+		})
+
+		It("leaves every object in place with the Orphan policy", func() {
+			bi.Spec.DeletionPolicy = rukpakv1alpha1.DeletionPolicyOrphan
+			_, err := r.uninstallInventory(ctx, bi)
+			Expect(err).NotTo(HaveOccurred())
+			expectDisowned(created)
+			expectDisowned(adopted)
+		})
+	})
+
+	Describe("mergeInventories", func() {
+		It("keeps the objects created in either inventory as created", func() {
+			ns := rukpakv1alpha1.ObjectReference{Kind: "Namespace", Name: "team-a"}
+			cm := rukpakv1alpha1.ObjectReference{Kind: "ConfigMap", Namespace: "team-a", Name: "settings"}
+			merged := mergeInventories(
+				[]rukpakv1alpha1.InventoryReference{{ObjectReference: ns, Created: true}, {ObjectReference: cm}},
+				[]rukpakv1alpha1.InventoryReference{{ObjectReference: ns}, {ObjectReference: cm, Created: true}},
+			)
+			Expect(merged).To(Equal([]rukpakv1alpha1.InventoryReference{{ObjectReference: ns, Created: true}, {ObjectReference: cm, Created: true}}))
+		})
+	})
+
+	Describe("reconcileServerSideApply", func() {
+		var r *BundleInstanceReconciler
+
+		BeforeEach(func() {
+			requireAPIServer()
+			c, err := client.New(cfg, client.Options{Scheme: scheme})
+			Expect(err).NotTo(HaveOccurred())
+			r = &BundleInstanceReconciler{Client: c, Scheme: scheme, ReleaseNamespace: "default"}
+			bi.ObjectMeta = metav1.ObjectMeta{GenerateName: "ssa-"}
+			Expect(c.Create(ctx, bi)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(c.Delete(ctx, bi))).To(Succeed())
+			})
+		})
+
+		It("applies nothing when a Namespace of the bundle already exists", func() {
+			foreign := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foreign-" + bi.Name}}
+			Expect(r.Create(ctx, foreign)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(r.Delete(ctx, foreign))).To(Succeed())
+			})
+			ns := &unstructured.Unstructured{}
+			ns.SetAPIVersion("v1")
+			ns.SetKind("Namespace")
+			ns.SetName(foreign.Name)
+
+			_, err := r.reconcileServerSideApply(ctx, bi, &rukpakv1alpha1.Bundle{}, []client.Object{ns}, nil, "", rest.NoWarnings{})
+			Expect(err).To(MatchError(ContainSubstring("already exists and is not installed by any BundleInstance")))
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(foreign), foreign)).To(Succeed())
+			Expect(foreign.Labels).NotTo(HaveKey("core.rukpak.io/owner-name"))
+			Expect(bi.Status.Inventory).To(BeEmpty())
+		})
+
+		It("records the objects that it creates as created", func() {
+			ns := &unstructured.Unstructured{}
+			ns.SetAPIVersion("v1")
+			ns.SetKind("Namespace")
+			ns.SetName("created-" + bi.Name)
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(r.Delete(ctx, ns))).To(Succeed())
+			})
+			// The apply of the ConfigMap fails, as its Namespace does not exist,
+			// once the Namespace of the bundle is applied.
+			cm := &unstructured.Unstructured{}
+			cm.SetAPIVersion("v1")
+			cm.SetKind("ConfigMap")
+			cm.SetNamespace("missing-" + bi.Name)
+			cm.SetName("settings")
+
+			_, err := r.reconcileServerSideApply(ctx, bi, &rukpakv1alpha1.Bundle{}, []client.Object{ns, cm}, nil, "", rest.NoWarnings{})
+			Expect(err).To(HaveOccurred())
+			Expect(bi.Status.Inventory).To(Equal([]rukpakv1alpha1.InventoryReference{
+				{ObjectReference: rukpakv1alpha1.ObjectReference{Kind: "Namespace", Name: ns.GetName()}, Created: true},
+				{ObjectReference: rukpakv1alpha1.ObjectReference{Kind: "ConfigMap", Namespace: cm.GetNamespace(), Name: cm.GetName()}},
+			}))
+		})
+	})
+})
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestControllers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Controllers Suite")
+}
+
+var (
+	scheme = runtime.NewScheme()
+
+	// testEnv and cfg are the API server of the specs that install objects
+	// with server-side apply or dry-run requests, which the fake client does
+	// not support. They are only started when the envtest binaries are
+	// available.
+	testEnv *envtest.Environment
+	cfg     *rest.Config
+)
+
+var _ = BeforeSuite(func() {
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(rukpakv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		return
+	}
+	testEnv = &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "..", "..", "manifests")},
+		ErrorIfCRDPathMissing: true,
+	}
+	var err error
+	cfg, err = testEnv.Start()
+	Expect(err).NotTo(HaveOccurred())
+})
+
+var _ = AfterSuite(func() {
+	if testEnv != nil {
+		Expect(testEnv.Stop()).To(Succeed())
+	}
+})
+
+// requireAPIServer skips the current spec when envtest is not running.
+func requireAPIServer() {
+	if cfg == nil {
+		Skip("KUBEBUILDER_ASSETS is not set: the spec requires an API server")
+	}
+}
+
+// newFakeClient returns a fake client of objs, with the scheme of the suite.
+func newFakeClient(objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
@@ -17,9 +17,16 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
+	"time"
 
 	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -31,13 +38,22 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/cosign"
+	"github.com/operator-framework/rukpak/internal/digest"
+	"github.com/operator-framework/rukpak/internal/features"
+	"github.com/operator-framework/rukpak/internal/manifests"
 	"github.com/operator-framework/rukpak/internal/provisioner/plain/controllers"
+	"github.com/operator-framework/rukpak/internal/source"
 	"github.com/operator-framework/rukpak/internal/storage"
+	"github.com/operator-framework/rukpak/internal/tracing"
 	"github.com/operator-framework/rukpak/internal/util"
+	"github.com/operator-framework/rukpak/internal/validation"
 	"github.com/operator-framework/rukpak/internal/version"
 )
 
@@ -46,6 +62,10 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// tracingShutdownTimeout is how long the spans that are not exported yet are
+// flushed for when the provisioner exits.
+const tracingShutdownTimeout = 5 * time.Second
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
@@ -55,25 +75,116 @@ func init() {
 
 func main() {
 	var metricsAddr string
-	var enableLeaderElection bool
+	var leaderElectionOpts util.LeaderElectionOptions
+	var tracingOpts tracing.Options
 	var probeAddr string
 	var systemNamespace string
+	var releaseNamespace string
+	var allowedReleaseNamespaces string
+	var disallowedKinds string
+	var validateSchemas bool
+	var releaseNamespaceOpts util.ReleaseNamespaceOptions
+	var helmStorageOpts util.HelmStorageOptions
 	var unpackImage string
 	var rukpakVersion bool
 	var gitClientImage string
+	var localPathRoot string
+	var readOnly bool
+	var filterByProvisionerClass bool
+	var bundleSelectorFlag, bundleInstanceSelectorFlag string
+	var installReportKeyFile string
+	var reconcileBudget time.Duration
+	var uninstallTimeout time.Duration
+	var hookTimeout time.Duration
+	var installTimeout time.Duration
+	var resyncInterval time.Duration
+	var maxConcurrentReconciles int
+	var fulcioRootsFile, rekorPublicKeyFile string
+	var storageOpts storage.Options
+	var bundleLimits manifests.Limits
+	var bundleCacheSize int
+	var digestOpts digest.Options
+	var kubeAPILimit, helmClientLimit, dynamicWatchLimit util.ClientRateLimit
+	bundleQueueLimit, bundleInstanceQueueLimit := util.DefaultQueueRateLimit(), util.DefaultQueueRateLimit()
+	featureGate := features.NewGate()
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&systemNamespace, "system-namespace", "rukpak-system", "Configures the namespace that gets used to deploy system resources.")
+	flag.StringVar(&releaseNamespace, "release-namespace", "",
+		"Configures the namespace in which release state is stored, and in which namespace-scoped bundle content without a namespace is installed. "+
+			"Defaults to the system namespace. Use a namespace dedicated to the provisioner to isolate its releases from other provisioners.")
+	flag.StringVar(&allowedReleaseNamespaces, "allowed-release-namespaces", "",
+		"A comma-separated list of the namespaces, other than the release namespace, that BundleInstances may store their release state in "+
+			"with spec.releaseNamespace. Use * to allow every namespace. By default, BundleInstances cannot select a release namespace.")
+	flag.StringVar(&disallowedKinds, "disallowed-kinds", "",
+		"A comma-separated list of the kinds, qualified by their groups such as ClusterRoleBinding.rbac.authorization.k8s.io, "+
+			"of the objects that bundles must not contain. Bundles with objects of these kinds fail to unpack.")
+	flag.BoolVar(&validateSchemas, "validate-schemas", true,
+		"Validate the objects of unpacked bundles against the OpenAPI schemas of the cluster, so that invalid objects fail the unpack of their bundle "+
+			"rather than the install of its BundleInstances.")
+	releaseNamespaceOpts.BindFlags(flag.CommandLine)
+	helmStorageOpts.BindFlags(flag.CommandLine)
 	flag.StringVar(&unpackImage, "unpack-image", "quay.io/operator-framework/plain-provisioner:latest", "Configures the container image that gets used to unpack Bundle contents.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
-		"Enable leader election for controller manager. "+
-			"Enabling this will ensure there is only one active controller manager.")
+	leaderElectionOpts.BindFlags(flag.CommandLine, "510f803c.olm.operatorframework.io")
+	tracingOpts.BindFlags(flag.CommandLine)
 	flag.BoolVar(&rukpakVersion, "version", false, "Displays rukpak version information")
 	flag.StringVar(&gitClientImage, "git-client-image", "alpine/git:v2.32.0", "Configures which git container image to use to clone bundle git repos")
-	opts := zap.Options{
-		Development: true,
-	}
-	opts.BindFlags(flag.CommandLine)
+	flag.StringVar(&localPathRoot, "local-path-root", "",
+		"The directory, mounted from a hostPath or PersistentVolumeClaim, that the paths of Bundles with a local path source are relative to. "+
+			"Local path sources are meant for development clusters, and fail to unpack unless this is set and the LocalPathSource feature is enabled.")
+	flag.BoolVar(&readOnly, "read-only", false,
+		"Run the controllers in read-only mode. "+
+			"Enabling this will ensure status is still computed and reported, but no bundle content is unpacked or installed.")
+	flag.BoolVar(&filterByProvisionerClass, "filter-by-provisioner-class", false,
+		"Only list and watch the Bundles and BundleInstances labeled with the provisioner class of the provisioner. "+
+			"Reduces the memory and API load of the provisioner when several provisioners run in the same cluster, "+
+			"but objects created before the rukpak core labeled them are not reconciled until they are updated.")
+	flag.StringVar(&bundleSelectorFlag, "bundle-selector", "",
+		"A label selector of the Bundles that this instance of the provisioner unpacks. "+
+			"Several instances with disjoint selectors, and distinct leader election IDs, share the Bundles of a cluster. Defaults to every Bundle.")
+	flag.StringVar(&bundleInstanceSelectorFlag, "bundleinstance-selector", "",
+		"A label selector of the BundleInstances that this instance of the provisioner installs. "+
+			"Several instances with disjoint selectors, and distinct leader election IDs, share the BundleInstances of a cluster. Defaults to every BundleInstance.")
+	flag.StringVar(&installReportKeyFile, "install-report-signing-key-file", "",
+		"Path to a file containing the key used to sign install reports. "+
+			"Install reports are only generated when a signing key is configured.")
+	flag.DurationVar(&reconcileBudget, "reconcile-budget", 30*time.Second,
+		"The maximum amount of time spent reconciling the installed objects of a single BundleInstance before yielding the worker. "+
+			"Reconciliation resumes where it left off in a subsequent reconcile. A value of 0 disables the budget.")
+	flag.DurationVar(&uninstallTimeout, "uninstall-timeout", 5*time.Minute,
+		"How long to wait for the installed objects of a deleted BundleInstance to be deleted before reporting its uninstall as failed. "+
+			"The uninstall is retried until it succeeds regardless. A value of 0 disables the timeout.")
+	flag.DurationVar(&hookTimeout, "hook-timeout", 5*time.Minute,
+		"How long to wait for each hook of a plain bundle to complete before failing the install or upgrade that runs it. "+
+			"A value of 0 disables the timeout.")
+	flag.DurationVar(&installTimeout, "install-timeout", 15*time.Minute,
+		"How long the install or upgrade of the release of a BundleInstance may run before it is cancelled and reported as timed out. "+
+			"A value of 0 disables the timeout.")
+	flag.DurationVar(&resyncInterval, "bundleinstance-resync-interval", 0,
+		"The interval at which the installed content of every BundleInstance is reconciled in the absence of any changes, "+
+			"unless the RukpakConfig or the BundleInstance set another interval. A value of 0 only reconciles content when it changes.")
+	flag.IntVar(&maxConcurrentReconciles, "provisioner-max-concurrent-reconciles", 1,
+		"The maximum number of Bundles, and separately of BundleInstances, that are reconciled in parallel. "+
+			"Raising it keeps slow unpacks and installs from delaying every other Bundle and BundleInstance in large clusters.")
+	flag.StringVar(&fulcioRootsFile, "fulcio-roots-file", "",
+		"Path to a file containing the PEM-encoded root certificates of the Fulcio certificate authority. "+
+			"Required to verify keyless signatures of bundle images.")
+	flag.StringVar(&rekorPublicKeyFile, "rekor-public-key-file", "",
+		"Path to a file containing the PEM-encoded public key of the Rekor transparency log. "+
+			"Required to verify keyless signatures of bundle images.")
+	storageOpts.BindFlags(flag.CommandLine)
+	bundleLimits.BindFlags(flag.CommandLine)
+	flag.IntVar(&bundleCacheSize, "bundle-cache-size", 64,
+		"The number of bundles whose loaded objects are kept in memory, so that their BundleInstances do not read them from storage on every reconcile. "+
+			"A value of 0 disables the cache.")
+	digestOpts.BindFlags(flag.CommandLine)
+	kubeAPILimit.BindFlags(flag.CommandLine, "kube-api", "all clients of the provisioner")
+	helmClientLimit.BindFlags(flag.CommandLine, "helm-client", "installs, upgrades and dry-run upgrades of releases")
+	dynamicWatchLimit.BindFlags(flag.CommandLine, "dynamic-watch", "the informers of installed objects")
+	bundleQueueLimit.BindFlags(flag.CommandLine, "bundle-queue", "failed reconciles of Bundles")
+	bundleInstanceQueueLimit.BindFlags(flag.CommandLine, "bundleinstance-queue", "failed reconciles of BundleInstances")
+	featureGate.BindFlags(flag.CommandLine)
+	logOpts := util.BindLogFlags(flag.CommandLine)
 	flag.Parse()
 
 	if rukpakVersion {
@@ -81,10 +192,30 @@ func main() {
 		os.Exit(0)
 	}
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
-	setupLog.Info("starting up the provisioner", "Git commit", version.String())
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(logOpts)))
+	setupLog.Info("starting up the provisioner", "Git commit", version.String(), "read-only", readOnly)
 
-	cfg := ctrl.GetConfigOrDie()
+	if err := bundleQueueLimit.Validate(); err != nil {
+		setupLog.Error(err, "invalid Bundle queue rate limit")
+		os.Exit(1)
+	}
+	if err := bundleInstanceQueueLimit.Validate(); err != nil {
+		setupLog.Error(err, "invalid BundleInstance queue rate limit")
+		os.Exit(1)
+	}
+	if localPathRoot != "" && !featureGate.Enabled(features.LocalPathSource) {
+		setupLog.Error(fmt.Errorf("the %s feature is disabled", features.LocalPathSource), "unable to unpack local path sources")
+		os.Exit(1)
+	}
+
+	digestAlgorithm, err := digestOpts.New()
+	if err != nil {
+		setupLog.Error(err, "unable to configure digest algorithm")
+		os.Exit(1)
+	}
+	storageOpts.Algorithm = digestAlgorithm
+
+	cfg := util.RateLimitedConfig(ctrl.GetConfigOrDie(), kubeAPILimit)
 	kubeClient, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
 		setupLog.Error(err, "unable to create kubernetes client")
@@ -96,59 +227,246 @@ func main() {
 		os.Exit(1)
 	}
 	dependentSelector := labels.NewSelector().Add(*dependentRequirement)
-	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+	// The Bundles and BundleInstances of other provisioner classes are
+	// filtered by predicates of the controllers regardless, so filtering them
+	// server-side only changes what is cached.
+	classSelector := cache.ObjectSelector{}
+	if filterByProvisionerClass {
+		classSelector.Label = util.ProvisionerClassSelector(controllers.ProvisionerClassName)
+	}
+	bundleSelector, err := labels.Parse(bundleSelectorFlag)
+	if err != nil {
+		setupLog.Error(err, "invalid bundle selector")
+		os.Exit(1)
+	}
+	bundleInstanceSelector, err := labels.Parse(bundleInstanceSelectorFlag)
+	if err != nil {
+		setupLog.Error(err, "invalid bundleinstance selector")
+		os.Exit(1)
+	}
+	if err := bundleLimits.Validate(); err != nil {
+		setupLog.Error(err, "invalid bundle limits")
+		os.Exit(1)
+	}
+	if err := leaderElectionOpts.Validate(); err != nil {
+		setupLog.Error(err, "invalid leader election options")
+		os.Exit(1)
+	}
+	if err := tracingOpts.Validate(); err != nil {
+		setupLog.Error(err, "invalid tracing options")
+		os.Exit(1)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingOpts, "plain-provisioner")
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	mgrOpts := ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
 		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "510f803c.olm.operatorframework.io",
 		NewCache: cache.BuilderWithOptions(cache.Options{
 			SelectorsByObject: cache.SelectorsByObject{
-				&rukpakv1alpha1.BundleInstance{}: {},
-				&rukpakv1alpha1.Bundle{}:         {},
+				&rukpakv1alpha1.BundleInstance{}: classSelector,
+				&rukpakv1alpha1.Bundle{}:         classSelector,
+				&rukpakv1alpha1.RukpakConfig{}:   {},
 			},
 			DefaultSelector: cache.ObjectSelector{
 				Label: dependentSelector,
 			},
 		}),
-	})
+	}
+	leaderElectionOpts.Apply(&mgrOpts)
+	mgr, err := ctrl.NewManager(cfg, mgrOpts)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
 	ns := util.PodNamespace(systemNamespace)
-	bundleStorage := &storage.ConfigMaps{
-		Client:     mgr.GetClient(),
-		Namespace:  ns,
-		NamePrefix: "bundle-",
+	bundleStorage, err := storageOpts.New(context.Background(), mgr.GetClient(), mgr.GetAPIReader(), ns, "bundle-")
+	if err != nil {
+		setupLog.Error(err, "unable to create bundle storage")
+		os.Exit(1)
+	}
+	if bundleCacheSize > 0 {
+		bundleStorage = storage.NewCache(bundleStorage, bundleCacheSize)
+	}
+	var uploadStorage storage.Storage
+	if featureGate.Enabled(features.UploadSource) {
+		uploadStorage, err = storageOpts.New(context.Background(), mgr.GetClient(), mgr.GetAPIReader(), ns, "upload-")
+		if err != nil {
+			setupLog.Error(err, "unable to create upload storage")
+			os.Exit(1)
+		}
 	}
 
+	verifier := &cosign.Verifier{}
+	if fulcioRootsFile != "" {
+		data, err := ioutil.ReadFile(fulcioRootsFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read Fulcio roots")
+			os.Exit(1)
+		}
+		verifier.FulcioRoots = x509.NewCertPool()
+		if !verifier.FulcioRoots.AppendCertsFromPEM(data) {
+			setupLog.Error(errors.New("no certificates found"), "unable to parse Fulcio roots")
+			os.Exit(1)
+		}
+	}
+	if rekorPublicKeyFile != "" {
+		data, err := ioutil.ReadFile(rekorPublicKeyFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read Rekor public key")
+			os.Exit(1)
+		}
+		key, err := cosign.ParsePublicKey(data)
+		if err != nil {
+			setupLog.Error(err, "unable to parse Rekor public key")
+			os.Exit(1)
+		}
+		verifier.RekorPublicKeys = []crypto.PublicKey{key}
+	}
+
+	validationOpts := validation.Options{
+		DisallowedKinds: validation.ParseKinds(disallowedKinds),
+		Mapper:          mgr.GetRESTMapper(),
+	}
+	if validateSchemas {
+		validationOpts.Schemas = kubeClient.Discovery()
+	}
+
+	// Images and git repositories are unpacked by unpack pods rather than by
+	// the provisioner itself.
+	unpackers := source.Registry{
+		rukpakv1alpha1.SourceTypeLocal: &source.Local{
+			KubeClient:      kubeClient,
+			PathRoot:        localPathRoot,
+			DigestAlgorithm: digestAlgorithm,
+		},
+		rukpakv1alpha1.SourceTypeUpload: &source.Upload{Storage: uploadStorage},
+		rukpakv1alpha1.SourceTypeHelm: &source.Helm{
+			Client:          mgr.GetClient(),
+			KubeClient:      kubeClient,
+			APIReader:       mgr.GetAPIReader(),
+			Namespace:       ns,
+			DigestAlgorithm: digestAlgorithm,
+		},
+	}
 	if err = (&controllers.BundleReconciler{
-		Client:         mgr.GetClient(),
-		KubeClient:     kubeClient,
-		Scheme:         mgr.GetScheme(),
-		PodNamespace:   ns,
-		Storage:        bundleStorage,
-		UnpackImage:    unpackImage,
-		GitClientImage: gitClientImage,
+		Client:                  mgr.GetClient(),
+		KubeClient:              kubeClient,
+		Scheme:                  mgr.GetScheme(),
+		PodNamespace:            ns,
+		Storage:                 bundleStorage,
+		Unpackers:               unpackers,
+		Verifier:                verifier,
+		APIReader:               mgr.GetAPIReader(),
+		ProxyEnv:                util.ProxyEnv(),
+		UnpackImage:             unpackImage,
+		GitClientImage:          gitClientImage,
+		Recorder:                mgr.GetEventRecorderFor("plain-provisioner"),
+		ReadOnly:                readOnly,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		RateLimit:               bundleQueueLimit,
+		Limits:                  bundleLimits,
+		Validators:              validationOpts.Validators(),
+		Selector:                bundleSelector,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Bundle")
 		os.Exit(1)
 	}
 
-	cfgGetter := helmclient.NewActionConfigGetter(mgr.GetConfig(), mgr.GetRESTMapper(), mgr.GetLogger())
+	var (
+		reportStorage    storage.Storage
+		reportSigningKey []byte
+	)
+	if installReportKeyFile != "" {
+		reportSigningKey, err = ioutil.ReadFile(installReportKeyFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read install report signing key")
+			os.Exit(1)
+		}
+		reportStorage, err = storageOpts.New(context.Background(), mgr.GetClient(), mgr.GetAPIReader(), ns, "install-report-")
+		if err != nil {
+			setupLog.Error(err, "unable to create install report storage")
+			os.Exit(1)
+		}
+	}
+
+	if releaseNamespace == "" {
+		releaseNamespace = ns
+	}
+	var allowedNamespaces []string
+	for _, namespace := range strings.Split(allowedReleaseNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			allowedNamespaces = append(allowedNamespaces, namespace)
+		}
+	}
+	setupClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		setupLog.Error(err, "unable to create setup client")
+		os.Exit(1)
+	}
+	if err := util.EnsureReleaseNamespace(context.Background(), setupClient, releaseNamespace, "plain", releaseNamespaceOpts); err != nil {
+		setupLog.Error(err, "unable to ensure release namespace")
+		os.Exit(1)
+	}
+
+	helmCfg := util.RateLimitedConfig(mgr.GetConfig(), helmClientLimit)
+	cfgGetter, err := util.HelmStorageConfigGetter(
+		helmclient.NewActionConfigGetter(helmCfg, mgr.GetRESTMapper(), mgr.GetLogger()),
+		kubeClient, releaseNamespace, helmStorageOpts,
+	)
+	if err != nil {
+		setupLog.Error(err, "unable to configure helm release storage")
+		os.Exit(1)
+	}
+	if err := controllers.RegisterMetrics(metrics.Registry); err != nil {
+		setupLog.Error(err, "unable to register BundleInstance metrics")
+		os.Exit(1)
+	}
 	if err = (&controllers.BundleInstanceReconciler{
-		Client:             mgr.GetClient(),
-		Scheme:             mgr.GetScheme(),
-		BundleStorage:      bundleStorage,
-		ReleaseNamespace:   ns,
-		ActionClientGetter: helmclient.NewActionClientGetter(cfgGetter),
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		BundleStorage:            bundleStorage,
+		ReleaseNamespace:         releaseNamespace,
+		AllowedReleaseNamespaces: allowedNamespaces,
+		ActionClientGetter:       helmclient.NewActionClientGetter(cfgGetter),
+		ActionConfigGetter:       cfgGetter,
+		ContentConfig:            helmCfg,
+		DigestAlgorithm:          digestAlgorithm,
+		Recorder:                 mgr.GetEventRecorderFor("plain-provisioner"),
+		ReadOnly:                 readOnly,
+		ReconcileBudget:          reconcileBudget,
+		UninstallTimeout:         uninstallTimeout,
+		HookTimeout:              hookTimeout,
+		InstallTimeout:           installTimeout,
+		ResyncInterval:           resyncInterval,
+		DynamicWatchLimit:        dynamicWatchLimit,
+		ReportStorage:            reportStorage,
+		ReportSigningKey:         reportSigningKey,
+		Applier:                  util.ClientIdentity(cfg, "plain-provisioner"),
+		MaxConcurrentReconciles:  maxConcurrentReconciles,
+		RateLimit:                bundleInstanceQueueLimit,
+		Selector:                 bundleInstanceSelector,
+		ServerSideApply:          featureGate.Enabled(features.ServerSideApply),
+		DriftCorrection:          featureGate.Enabled(features.DriftCorrection),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BundleInstance")
 		os.Exit(1)
 	}
+	// Bundles are not garbage collected by a read-only provisioner.
+	if !readOnly {
+		if err = (&controllers.BundleGCReconciler{
+			Client:   mgr.GetClient(),
+			Selector: bundleSelector,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "BundleGC")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -161,7 +479,14 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	// The spans of the last reconciles are flushed before the process exits.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		setupLog.Error(err, "failed to flush traces")
+	}
+	cancel()
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
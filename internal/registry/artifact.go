@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/nlepage/go-tarfs"
+)
+
+const (
+	MediaTypeOCIImageConfig    = "application/vnd.oci.image.config.v1+json"
+	MediaTypeDockerImageConfig = "application/vnd.docker.container.image.v1+json"
+
+	// AnnotationTitle is the annotation of the file name of a layer of an
+	// artifact, and AnnotationUnpack marks the layers that oras pushes for
+	// directories, which are gzipped tarballs of their content.
+	AnnotationTitle  = "org.opencontainers.image.title"
+	AnnotationUnpack = "io.deis.oras.content.unpack"
+
+	// maxLayerSize limits the size of the layers of artifacts that are read
+	// from a registry.
+	maxLayerSize = 64 * 1024 * 1024
+	// maxArtifactSize limits the size of the content of artifacts, once their
+	// layers are decompressed, when no other limit is given.
+	maxArtifactSize = 256 * 1024 * 1024
+)
+
+// IsArtifact reports whether m is the manifest of an OCI artifact, such as
+// one pushed with oras, rather than of a runnable container image: it has an
+// artifact type, or a config that is not the config of an image.
+func IsArtifact(m *Manifest) bool {
+	if m.ArtifactType != "" {
+		return true
+	}
+	switch m.Config.MediaType {
+	case "", MediaTypeOCIImageConfig, MediaTypeDockerImageConfig:
+		return false
+	}
+	return true
+}
+
+// PullArtifact returns the content of the layers of the artifact m as a
+// filesystem. The media type of each layer determines how it is laid out:
+// tarballs, such as the layers that oras pushes for directories, are
+// extracted at the root of the filesystem, and other layers are files named
+// by their title in fileDir.
+//
+// The content of the layers together, once decompressed, must not be larger
+// than maxBytes, or than 256MiB when maxBytes is 0, so that a small
+// compressed layer cannot exhaust the memory of the caller.
+func (c *Client) PullArtifact(ctx context.Context, m *Manifest, fileDir string, maxBytes int64) (fs.FS, error) {
+	if len(m.Layers) == 0 {
+		return nil, errors.New("artifact has no layers")
+	}
+	if maxBytes <= 0 {
+		maxBytes = maxArtifactSize
+	}
+	remaining := maxBytes
+	tooLarge := fmt.Errorf("the content of the artifact is more than the limit of %d bytes", maxBytes)
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, layer := range m.Layers {
+		data, err := c.getBlob(ctx, layer.Digest, maxLayerSize)
+		if err != nil {
+			return nil, fmt.Errorf("pull layer %s: %w", layer.Digest, err)
+		}
+		if isTarLayer(layer) {
+			n, err := copyTar(tw, data, remaining)
+			if errors.Is(err, errTarTooLarge) {
+				return nil, tooLarge
+			}
+			if err != nil {
+				return nil, fmt.Errorf("extract layer %s: %w", layer.Digest, err)
+			}
+			remaining -= n
+			continue
+		}
+		if remaining -= int64(len(data)); remaining < 0 {
+			return nil, tooLarge
+		}
+		title := layer.Annotations[AnnotationTitle]
+		if title == "" {
+			return nil, fmt.Errorf("layer %s of media type %q has no %s annotation", layer.Digest, layer.MediaType, AnnotationTitle)
+		}
+		name := path.Join(fileDir, path.Base(title))
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(data))}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return tarfs.New(buf)
+}
+
+// isTarLayer reports whether layer is a tarball, which may be gzipped.
+func isTarLayer(layer Descriptor) bool {
+	return layer.Annotations[AnnotationUnpack] == "true" || strings.Contains(layer.MediaType, ".tar")
+}
+
+// errTarTooLarge is returned by copyTar for tarballs that are larger than
+// their limit.
+var errTarTooLarge = errors.New("tarball is too large")
+
+// copyTar writes the entries of the tarball data, which may be gzipped, to tw,
+// and returns the size of the tarball once decompressed. errTarTooLarge is
+// returned when it is larger than limit.
+func copyTar(tw *tar.Writer, data []byte, limit int64) (int64, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gzr, err := gzip.NewReader(r)
+		if err != nil {
+			return 0, err
+		}
+		defer gzr.Close()
+		r = gzr
+	}
+	// One byte more than limit is read, to tell tarballs of exactly limit
+	// bytes from larger ones.
+	lr := &io.LimitedReader{R: r, N: limit + 1}
+	size := func() int64 { return limit + 1 - lr.N }
+	tr := tar.NewReader(lr)
+	for {
+		hdr, err := tr.Next()
+		if lr.N == 0 {
+			return 0, errTarTooLarge
+		}
+		if errors.Is(err, io.EOF) {
+			return size(), nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if name := path.Clean(hdr.Name); path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return 0, fmt.Errorf("entry %q is outside of the artifact", hdr.Name)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return 0, err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			if lr.N == 0 {
+				return 0, errTarTooLarge
+			}
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsArtifact(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		manifest Manifest
+		want     bool
+	}{
+		{
+			name:     "image",
+			manifest: Manifest{MediaType: MediaTypeOCIManifest, Config: Descriptor{MediaType: MediaTypeOCIImageConfig}},
+		},
+		{
+			name:     "docker image",
+			manifest: Manifest{MediaType: MediaTypeDockerManifest, Config: Descriptor{MediaType: MediaTypeDockerImageConfig}},
+		},
+		{
+			name:     "artifact type",
+			manifest: Manifest{MediaType: MediaTypeOCIManifest, ArtifactType: "application/vnd.cncf.rukpak.bundle", Config: Descriptor{MediaType: "application/vnd.oci.empty.v1+json"}},
+			want:     true,
+		},
+		{
+			name:     "unknown config",
+			manifest: Manifest{MediaType: MediaTypeOCIManifest, Config: Descriptor{MediaType: "application/vnd.unknown.config.v1+json"}},
+			want:     true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IsArtifact(&tt.manifest))
+		})
+	}
+}
+
+func TestPullArtifact(t *testing.T) {
+	file := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: file\n")
+	dir := &bytes.Buffer{}
+	gzw := gzip.NewWriter(dir)
+	tw := tar.NewWriter(gzw)
+	dirFile := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: dir\n")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "manifests/", Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "manifests/dir.yaml", Mode: 0644, Size: int64(len(dirFile))}))
+	_, err := tw.Write(dirFile)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	blobs := map[string][]byte{}
+	descriptor := func(mediaType string, data []byte, annotations map[string]string) Descriptor {
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+		blobs[digest] = data
+		return Descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(data)), Annotations: annotations}
+	}
+	manifest, err := json.Marshal(Manifest{
+		MediaType:    MediaTypeOCIManifest,
+		ArtifactType: "application/vnd.cncf.rukpak.bundle",
+		Config:       descriptor("application/vnd.oci.empty.v1+json", []byte("{}"), nil),
+		Layers: []Descriptor{
+			descriptor("application/yaml", file, map[string]string{AnnotationTitle: "file.yaml"}),
+			descriptor("application/vnd.oci.image.layer.v1.tar+gzip", dir.Bytes(), map[string]string{AnnotationTitle: "manifests", AnnotationUnpack: "true"}),
+		},
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/bundles/test/manifests/v1":
+			_, _ = w.Write(manifest)
+			return
+		}
+		for digest, data := range blobs {
+			if r.URL.Path == "/v2/bundles/test/blobs/"+digest {
+				_, _ = w.Write(data)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	c, err := NewClient(server.Client(), u.Host+"/bundles/test:v1")
+	require.NoError(t, err)
+	m, digest, err := c.GetManifestDigest(context.Background(), "v1")
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256(manifest)), digest)
+	require.True(t, IsArtifact(m))
+
+	artifactFS, err := c.PullArtifact(context.Background(), m, "manifests", 0)
+	require.NoError(t, err)
+	data, err := fs.ReadFile(artifactFS, "manifests/file.yaml")
+	require.NoError(t, err)
+	require.Equal(t, file, data)
+	data, err = fs.ReadFile(artifactFS, "manifests/dir.yaml")
+	require.NoError(t, err)
+	require.Equal(t, dirFile, data)
+
+	// The decompressed size of the layers is limited.
+	_, err = c.PullArtifact(context.Background(), m, "manifests", 1024)
+	require.EqualError(t, err, "the content of the artifact is more than the limit of 1024 bytes")
+}
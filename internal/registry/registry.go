@@ -0,0 +1,346 @@
+// Package registry is a minimal client of the OCI distribution API, as used to
+// verify the signatures of bundle images, to resolve their digests, and to
+// pull bundles that are published as OCI artifacts.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+const (
+	dockerHubRegistry = "registry-1.docker.io"
+
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	// maxBlobSize limits the size of the signature manifests and payloads
+	// that are read from a registry.
+	maxBlobSize = 4 * 1024 * 1024
+)
+
+// ErrNotFound is returned when a manifest or blob does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ParseRepository returns the registry host and repository of an image
+// reference, stripping any tag or digest.
+func ParseRepository(ref string) (string, string, error) {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	if ref == "" {
+		return "", "", fmt.Errorf("invalid image reference %q", ref)
+	}
+
+	registry, repository := "docker.io", ref
+	if i := strings.Index(ref, "/"); i >= 0 {
+		if first := ref[:i]; strings.ContainsAny(first, ".:") || first == "localhost" {
+			registry, repository = first, ref[i+1:]
+		}
+	}
+	if registry == "docker.io" {
+		registry = dockerHubRegistry
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	return registry, repository, nil
+}
+
+// Manifest is an image manifest.
+type Manifest struct {
+	MediaType    string       `json:"mediaType"`
+	ArtifactType string       `json:"artifactType,omitempty"`
+	Config       Descriptor   `json:"config"`
+	Layers       []Descriptor `json:"layers"`
+}
+
+// Descriptor describes the config or a layer of an image manifest.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Client is a minimal client of the OCI distribution API that pulls
+// manifests and blobs from a repository, authenticating with a bearer token
+// when a registry requests one.
+type Client struct {
+	httpClient *http.Client
+	registry   string
+	repository string
+	token      string
+	basicAuth  bool
+
+	// Username and Password are the credentials that are used to request
+	// bearer tokens, or that are sent to registries that request basic
+	// authentication. Tokens are requested anonymously when they are empty.
+	Username string
+	Password string
+}
+
+// NewClient returns a Client of the repository of the image reference ref.
+func NewClient(httpClient *http.Client, ref string) (*Client, error) {
+	registry, repository, err := ParseRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	if httpClient == nil {
+		httpClient = util.DefaultHTTPClient
+	}
+	return &Client{httpClient: httpClient, registry: registry, repository: repository}, nil
+}
+
+// Registry returns the host of the registry of the client.
+func (c *Client) Registry() string {
+	return c.registry
+}
+
+func (c *Client) GetManifest(ctx context.Context, reference string) (*Manifest, error) {
+	m, _, err := c.GetManifestDigest(ctx, reference)
+	return m, err
+}
+
+// GetManifestDigest returns the manifest that reference refers to, along
+// with its digest.
+func (c *Client) GetManifestDigest(ctx context.Context, reference string) (*Manifest, string, error) {
+	data, err := c.get(ctx, "manifests/"+reference, MediaTypeOCIManifest+", "+MediaTypeDockerManifest, maxBlobSize)
+	if err != nil {
+		return nil, "", err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, "", fmt.Errorf("parse manifest %q: %w", reference, err)
+	}
+	return m, fmt.Sprintf("sha256:%x", sha256.Sum256(data)), nil
+}
+
+// ResolveDigest returns the digest of the manifest, or of the index of
+// manifests of a multi-platform image, that reference currently refers to.
+func (c *Client) ResolveDigest(ctx context.Context, reference string) (string, error) {
+	data, err := c.get(ctx, "manifests/"+reference, strings.Join([]string{
+		MediaTypeOCIIndex, MediaTypeDockerManifestList, MediaTypeOCIManifest, MediaTypeDockerManifest,
+	}, ", "), maxBlobSize)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data)), nil
+}
+
+func (c *Client) GetBlob(ctx context.Context, digest string) ([]byte, error) {
+	return c.getBlob(ctx, digest, maxBlobSize)
+}
+
+// getBlob returns the blob with digest, which is at most limit bytes.
+func (c *Client) getBlob(ctx context.Context, digest string, limit int64) ([]byte, error) {
+	data, err := c.get(ctx, "blobs/"+digest, "", limit)
+	if err != nil {
+		return nil, err
+	}
+	if actual := fmt.Sprintf("sha256:%x", sha256.Sum256(data)); actual != digest {
+		return nil, fmt.Errorf("blob %s has unexpected digest %s", digest, actual)
+	}
+	return data, nil
+}
+
+func (c *Client) get(ctx context.Context, path, accept string, limit int64) ([]byte, error) {
+	u := fmt.Sprintf("https://%s/v2/%s/%s", c.registry, c.repository, path)
+	resp, err := c.do(ctx, u, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.token == "" && !c.basicAuth {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.authenticate(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(ctx, u, accept); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ioutil.ReadAll(io.LimitReader(resp.Body, limit))
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("get %s: %w", u, ErrNotFound)
+	default:
+		return nil, fmt.Errorf("get %s: unexpected status %s", u, resp.Status)
+	}
+}
+
+func (c *Client) do(ctx context.Context, u, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.basicAuth:
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	return c.httpClient.Do(req)
+}
+
+// authenticate requests a bearer token for the repository from the token
+// service named in a WWW-Authenticate challenge, or switches to basic
+// authentication when the challenge requests it and credentials are set.
+func (c *Client) authenticate(ctx context.Context, challenge string) error {
+	if strings.HasPrefix(challenge, "Basic ") && c.Username != "" {
+		c.basicAuth = true
+		return nil
+	}
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return fmt.Errorf("registry %s requires unsupported authentication %q", c.registry, challenge)
+	}
+	params := parseChallenge(strings.TrimPrefix(challenge, "Bearer "))
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("registry %s returned an invalid token realm %q", c.registry, params["realm"])
+	}
+	q := realm.Query()
+	if service := params["service"]; service != "" {
+		q.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", c.repository)
+	}
+	q.Set("scope", scope)
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request registry token: unexpected status %s", resp.Status)
+	}
+	tok := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBlobSize)).Decode(&tok); err != nil {
+		return fmt.Errorf("parse registry token: %w", err)
+	}
+	c.token = tok.Token
+	if c.token == "" {
+		c.token = tok.AccessToken
+	}
+	if c.token == "" {
+		return fmt.Errorf("registry %s returned an empty token", c.registry)
+	}
+	return nil
+}
+
+// parseChallenge parses the comma-separated key="value" parameters of a
+// WWW-Authenticate challenge.
+func parseChallenge(s string) map[string]string {
+	params := map[string]string{}
+	for s != "" {
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else if comma := strings.Index(s, ","); comma >= 0 {
+			value, s = s[:comma], s[comma:]
+		} else {
+			value, s = s, ""
+		}
+		params[key] = value
+		s = strings.TrimPrefix(strings.TrimSpace(s), ",")
+	}
+	return params
+}
+
+// ParseReference returns the tag or digest of an image reference, which
+// defaults to the latest tag.
+func ParseReference(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[i+1:]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[i+1:]
+	}
+	return "latest"
+}
+
+// DockerConfigCredentials returns the username and password for registry in
+// the .dockerconfigjson data of a Secret of type kubernetes.io/dockerconfigjson.
+// It returns empty credentials when data has none for registry.
+func DockerConfigCredentials(data []byte, registry string) (string, string, error) {
+	cfg := struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return "", "", fmt.Errorf("parse docker config: %w", err)
+	}
+	for server, auth := range cfg.Auths {
+		host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+		host = strings.SplitN(host, "/", 2)[0]
+		if host == "docker.io" || host == "index.docker.io" {
+			host = dockerHubRegistry
+		}
+		if host != registry {
+			continue
+		}
+		if auth.Username != "" {
+			return auth.Username, auth.Password, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("decode auth of %q: %w", server, err)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return "", "", fmt.Errorf("auth of %q is not of the form username:password", server)
+		}
+		return parts[0], parts[1], nil
+	}
+	return "", "", nil
+}
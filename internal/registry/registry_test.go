@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRepository(t *testing.T) {
+	type testCase struct {
+		ref        string
+		registry   string
+		repository string
+	}
+	for _, tc := range []testCase{
+		{ref: "busybox", registry: "registry-1.docker.io", repository: "library/busybox"},
+		{ref: "docker.io/foo/bar:v1", registry: "registry-1.docker.io", repository: "foo/bar"},
+		{ref: "quay.io/operator-framework/combo@sha256:abc", registry: "quay.io", repository: "operator-framework/combo"},
+		{ref: "localhost:5000/bundles/test:latest", registry: "localhost:5000", repository: "bundles/test"},
+	} {
+		t.Run(tc.ref, func(t *testing.T) {
+			registry, repository, err := ParseRepository(tc.ref)
+			require.NoError(t, err)
+			require.Equal(t, tc.registry, registry)
+			require.Equal(t, tc.repository, repository)
+		})
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	for ref, expected := range map[string]string{
+		"busybox":                                     "latest",
+		"quay.io/foo/bar:v1":                          "v1",
+		"localhost:5000/bundles/test":                 "latest",
+		"localhost:5000/bundles/test:v2":              "v2",
+		"quay.io/foo/bar:v1@sha256:abc":               "sha256:abc",
+		"quay.io/operator-framework/combo@sha256:def": "sha256:def",
+	} {
+		require.Equal(t, expected, ParseReference(ref), ref)
+	}
+}
+
+func TestResolveDigest(t *testing.T) {
+	index := []byte(`{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[]}`)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if username, password, _ := r.BasicAuth(); username != "user" || password != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"user-token"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer user-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="https://%s/token",service="test"`, r.Host))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v2/bundles/test/manifests/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(index)
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	c, err := NewClient(server.Client(), u.Host+"/bundles/test:latest")
+	require.NoError(t, err)
+	_, err = c.ResolveDigest(context.Background(), "latest")
+	require.Error(t, err)
+
+	c, err = NewClient(server.Client(), u.Host+"/bundles/test:latest")
+	require.NoError(t, err)
+	c.Username, c.Password = "user", "pass"
+	digest, err := c.ResolveDigest(context.Background(), "latest")
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256(index)), digest)
+
+	_, err = c.ResolveDigest(context.Background(), "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDockerConfigCredentials(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("encoded:secret"))
+	data := []byte(fmt.Sprintf(`{"auths":{
+		"https://index.docker.io/v1/":{"username":"hub","password":"hub-pass"},
+		"quay.io":{"auth":%q},
+		"broken.example.com":{"auth":"bm9jb2xvbg=="}
+	}}`, auth))
+	for _, tt := range []struct {
+		registry string
+		username string
+		password string
+		errMsg   string
+	}{
+		{registry: "registry-1.docker.io", username: "hub", password: "hub-pass"},
+		{registry: "quay.io", username: "encoded", password: "secret"},
+		{registry: "ghcr.io"},
+		{registry: "broken.example.com", errMsg: "not of the form username:password"},
+	} {
+		t.Run(tt.registry, func(t *testing.T) {
+			username, password, err := DockerConfigCredentials(data, tt.registry)
+			if tt.errMsg != "" {
+				require.ErrorContains(t, err, tt.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.username, username)
+			require.Equal(t, tt.password, password)
+		})
+	}
+}
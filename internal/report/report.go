@@ -0,0 +1,130 @@
+package report
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+const (
+	// Kind is the kind used when an InstallReport is persisted in bundle storage.
+	Kind = "InstallReport"
+)
+
+// InstallReport is an audit record of a successful BundleInstance install or
+// upgrade. It captures what was installed, from where, and by whom, and can be
+// signed so that it can be used as compliance evidence.
+type InstallReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	BundleInstance string                        `json:"bundleInstance"`
+	Bundle         string                        `json:"bundle"`
+	Digest         string                        `json:"digest"`
+	Source         rukpakv1alpha1.BundleSource   `json:"source"`
+	Objects        []rukpakv1alpha1.BundleObject `json:"objects,omitempty"`
+	Release        string                        `json:"release"`
+	Revision       int                           `json:"revision"`
+	Applier        string                        `json:"applier"`
+	InstalledAt    metav1.Time                   `json:"installedAt"`
+
+	// Signature is the hex-encoded HMAC-SHA256 of the report contents,
+	// computed with Signature unset.
+	Signature string `json:"signature,omitempty"`
+}
+
+// New returns an unsigned InstallReport for the given revision of the release
+// of bi, which installed the contents of b.
+func New(bi *rukpakv1alpha1.BundleInstance, b *rukpakv1alpha1.Bundle, revision int, applier string, installedAt metav1.Time) *InstallReport {
+	r := &InstallReport{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rukpakv1alpha1.GroupVersion.String(),
+			Kind:       Kind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: bi.Name,
+		},
+		BundleInstance: bi.Name,
+		Bundle:         b.Name,
+		Digest:         b.Status.Digest,
+		Source:         b.Spec.Source,
+		Release:        bi.Name,
+		Revision:       revision,
+		Applier:        applier,
+		InstalledAt:    installedAt,
+	}
+	if b.Status.Info != nil {
+		r.Objects = b.Status.Info.Objects
+	}
+	return r
+}
+
+// Sign sets the signature of the report using the given key.
+func (r *InstallReport) Sign(key []byte) error {
+	sig, err := r.signature(key)
+	if err != nil {
+		return err
+	}
+	r.Signature = sig
+	return nil
+}
+
+// Verify returns an error if the report is unsigned, or if its signature
+// does not match the given key.
+func (r *InstallReport) Verify(key []byte) error {
+	if r.Signature == "" {
+		return errors.New("install report is not signed")
+	}
+	expected, err := r.signature(key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(r.Signature)) {
+		return errors.New("install report signature does not match")
+	}
+	return nil
+}
+
+func (r *InstallReport) signature(key []byte) (string, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("marshal install report: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// ToUnstructured converts the report into an object that can be persisted
+// with a storage.Storage implementation.
+func (r *InstallReport) ToUnstructured() (*unstructured.Unstructured, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(r)
+	if err != nil {
+		return nil, fmt.Errorf("convert install report: %w", err)
+	}
+	return &unstructured.Unstructured{Object: u}, nil
+}
+
+// FromUnstructured converts an object loaded from a storage.Storage
+// implementation back into a report.
+func FromUnstructured(u unstructured.Unstructured) (*InstallReport, error) {
+	if u.GetKind() != Kind {
+		return nil, fmt.Errorf("unexpected kind %q: expected %q", u.GetKind(), Kind)
+	}
+	r := &InstallReport{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, r); err != nil {
+		return nil, fmt.Errorf("convert install report: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,45 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	bi := &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "combo"}}
+	b := &rukpakv1alpha1.Bundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "combo-v0.0.1"},
+		Spec: rukpakv1alpha1.BundleSpec{
+			Source: rukpakv1alpha1.BundleSource{
+				Type:  rukpakv1alpha1.SourceTypeImage,
+				Image: &rukpakv1alpha1.ImageSource{Ref: "quay.io/operator-framework/combo:v0.0.1"},
+			},
+		},
+		Status: rukpakv1alpha1.BundleStatus{
+			Digest: "sha256:0123",
+			Info: &rukpakv1alpha1.BundleInfo{
+				Objects: []rukpakv1alpha1.BundleObject{{Version: "v1", Kind: "Namespace", Name: "combo"}},
+			},
+		},
+	}
+	key := []byte("secret")
+
+	rpt := New(bi, b, 2, "system:serviceaccount:rukpak-system:plain-provisioner-admin", metav1.Now())
+	require.Error(t, rpt.Verify(key), "unsigned reports must not verify")
+	require.NoError(t, rpt.Sign(key))
+	require.NoError(t, rpt.Verify(key))
+	require.Error(t, rpt.Verify([]byte("other")), "reports must not verify with a different key")
+
+	u, err := rpt.ToUnstructured()
+	require.NoError(t, err)
+	roundTripped, err := FromUnstructured(*u)
+	require.NoError(t, err)
+	require.NoError(t, roundTripped.Verify(key), "reports must verify after being persisted")
+
+	roundTripped.Digest = "sha256:4567"
+	require.Error(t, roundTripped.Verify(key), "modified reports must not verify")
+}
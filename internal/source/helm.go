@@ -0,0 +1,156 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/convert"
+	"github.com/operator-framework/rukpak/internal/digest"
+	"github.com/operator-framework/rukpak/internal/helmrepo"
+	"github.com/operator-framework/rukpak/internal/tracing"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+// Helm unpacks helm sources by downloading their chart from its repository,
+// and rendering it with its default values. The digest of the unpacked
+// content is the digest of the chart archive.
+//
+// Like other sources, a helm source is only resolved again once the Bundle is
+// unpacked when it has a poll interval, so that a new version of the chart
+// that matches a version constraint does not change the Bundle unexpectedly.
+type Helm struct {
+	// Client reads the RukpakConfig, whose CA bundle is trusted when a source
+	// has none.
+	Client client.Reader
+	// KubeClient reads the auth Secrets of sources, and the version of the
+	// cluster that charts are rendered for. It is uncached, as the Secrets
+	// are not labeled for the cache of the manager.
+	KubeClient kubernetes.Interface
+	// APIReader reads the ConfigMaps and Secrets of the CA bundles of
+	// sources, which are not labeled for the cache of the manager.
+	APIReader client.Reader
+	// Namespace is the namespace of the Secrets and CA bundles of sources.
+	Namespace string
+	// HTTPClient is the client with which repositories are fetched.
+	// util.DefaultHTTPClient is used when HTTPClient is nil.
+	HTTPClient *http.Client
+	// DigestAlgorithm is the algorithm of the digests of the unpacked
+	// content. It defaults to SHA256.
+	DigestAlgorithm digest.Algorithm
+}
+
+var _ Unpacker = &Helm{}
+
+// ErrUnresolvedChartDependencies is returned by Helm.Unpack for the charts
+// whose dependencies cannot be downloaded.
+var ErrUnresolvedChartDependencies = errors.New("unresolved chart dependencies")
+
+// Unpack implements Unpacker.
+func (h *Helm) Unpack(ctx context.Context, bundle *rukpakv1alpha1.Bundle) (_ *Result, err error) {
+	ctx, span := tracing.Start(ctx, "Bundle.UnpackHelm")
+	defer func() { tracing.End(span, err) }()
+	source := bundle.Spec.Source.Helm
+	if source == nil {
+		return nil, errors.New("helm source is not set")
+	}
+	unpacked := meta.FindStatusCondition(bundle.Status.Conditions, rukpakv1alpha1.TypeUnpacked)
+	current := unpacked != nil && unpacked.Status == metav1.ConditionTrue && unpacked.ObservedGeneration == bundle.Generation
+	if current && bundle.Spec.Source.PollInterval == nil {
+		return &Result{State: StateUnchanged}, nil
+	}
+
+	hc, err := h.repositoryClient(ctx, bundle.Spec.Source)
+	if err != nil {
+		return nil, err
+	}
+	version, chartURL, err := hc.ResolveVersion(ctx, *source)
+	if err != nil && current {
+		// An unreachable repository does not fail a Bundle that is unpacked.
+		log.FromContext(ctx).Error(err, "failed to resolve chart version, keeping the unpacked version")
+		return &Result{State: StateUnchanged}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("resolve chart version: %w", err)
+	}
+	if resolved := bundle.Status.ResolvedSource; current && resolved != nil && resolved.Helm != nil && resolved.Helm.Version == version {
+		return &Result{State: StateUnchanged}, nil
+	}
+	archive, err := hc.GetChart(ctx, *source, chartURL)
+	if err != nil {
+		return nil, fmt.Errorf("download chart: %w", err)
+	}
+	chrt, err := loader.LoadArchive(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("load chart: %w", err)
+	}
+	// Helm renders a chart without the dependencies that are missing from
+	// its charts directory, rather than failing.
+	if err := hc.ResolveDependencies(ctx, *source, chrt); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnresolvedChartDependencies, err)
+	}
+	plain, err := convert.HelmChart(chrt, bundle.Name, h.kubeVersion())
+	if err != nil {
+		return nil, err
+	}
+	resolved := bundle.Spec.Source.DeepCopy()
+	resolved.PollInterval = nil
+	resolved.Helm.Version = version
+	return &Result{
+		State:          StateUnpacked,
+		Objects:        plain.Objects,
+		Digest:         digest.Digest(digest.OrDefault(h.DigestAlgorithm), archive),
+		ResolvedSource: resolved,
+	}, nil
+}
+
+// repositoryClient returns a client of the chart repository of source, which
+// trusts its CA bundle and authenticates with its auth Secret.
+func (h *Helm) repositoryClient(ctx context.Context, source rukpakv1alpha1.BundleSource) (*helmrepo.Client, error) {
+	config, err := util.GetRukpakConfig(ctx, h.Client)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := h.HTTPClient
+	if caBundle := util.CABundleFor(config, source); caBundle != nil {
+		data, err := util.LoadCABundle(ctx, h.APIReader, h.Namespace, *caBundle)
+		if err != nil {
+			return nil, err
+		}
+		if httpClient, err = util.HTTPClientWithCABundle(h.HTTPClient, data); err != nil {
+			return nil, err
+		}
+	}
+	hc := helmrepo.NewClient(httpClient)
+	if auth := source.Helm.Auth; auth != nil {
+		secret, err := h.KubeClient.CoreV1().Secrets(h.Namespace).Get(ctx, auth.SecretName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("get secret %q: %w", auth.SecretName, err)
+		}
+		hc.Username, hc.Password = string(secret.Data[corev1.BasicAuthUsernameKey]), string(secret.Data[corev1.BasicAuthPasswordKey])
+	}
+	return hc, nil
+}
+
+// kubeVersion returns the version of the cluster that charts are rendered
+// for, or nil to render them for the default version of Helm when it cannot
+// be determined.
+func (h *Helm) kubeVersion() *chartutil.KubeVersion {
+	info, err := h.KubeClient.Discovery().ServerVersion()
+	if err != nil {
+		return nil
+	}
+	return &chartutil.KubeVersion{Version: info.GitVersion, Major: info.Major, Minor: info.Minor}
+}
@@ -0,0 +1,160 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/digest"
+)
+
+func TestHelmUnpack(t *testing.T) {
+	path, err := chartutil.Save(&chart.Chart{
+		Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "my-chart", Version: "1.2.0"},
+		Templates: []*chart.File{{Name: "templates/service.yaml", Data: []byte(service)}},
+	}, t.TempDir())
+	require.NoError(t, err)
+	archive, err := os.ReadFile(path)
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, _ := r.BasicAuth(); username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/index.yaml":
+			_, _ = w.Write([]byte("apiVersion: v1\nentries:\n  my-chart:\n  - name: my-chart\n    version: 1.2.0\n    urls:\n    - " + filepath.Base(path) + "\n"))
+		case "/" + filepath.Base(path):
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, rukpakv1alpha1.AddToScheme(scheme))
+	kubeClient := kubefake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "repo-auth"},
+		Data:       map[string][]byte{corev1.BasicAuthUsernameKey: []byte("user"), corev1.BasicAuthPasswordKey: []byte("pass")},
+	})
+	kubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.23.1", Major: "1", Minor: "23"}
+	h := &Helm{
+		Client:     fake.NewClientBuilder().WithScheme(scheme).Build(),
+		KubeClient: kubeClient,
+		Namespace:  "rukpak-system",
+		HTTPClient: server.Client(),
+	}
+	source := rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeHelm, Helm: &rukpakv1alpha1.HelmSource{
+		Repository: server.URL,
+		Chart:      "my-chart",
+		Auth:       &rukpakv1alpha1.HelmAuth{SecretName: "repo-auth"},
+	}}
+
+	bundle := testBundle(source)
+	result, err := h.Unpack(context.Background(), bundle)
+	require.NoError(t, err)
+	require.Equal(t, StateUnpacked, result.State)
+	require.Len(t, result.Objects, 1)
+	require.Equal(t, "svc", result.Objects[0].GetName())
+	require.Regexp(t, "^sha256:[0-9a-f]{64}$", result.Digest)
+	require.Equal(t, "1.2.0", result.ResolvedSource.Helm.Version)
+
+	// The unpacked chart is not resolved again without a poll interval.
+	meta.SetStatusCondition(&bundle.Status.Conditions, metav1.Condition{Type: rukpakv1alpha1.TypeUnpacked, Status: metav1.ConditionTrue, Reason: rukpakv1alpha1.ReasonUnpackSuccessful, ObservedGeneration: 1})
+	bundle.Status.ResolvedSource = result.ResolvedSource
+	result, err = h.Unpack(context.Background(), bundle)
+	require.NoError(t, err)
+	require.Equal(t, &Result{State: StateUnchanged}, result)
+
+	// Nor is it unpacked again while the polled version is unchanged.
+	bundle.Spec.Source.PollInterval = &metav1.Duration{Duration: 1}
+	result, err = h.Unpack(context.Background(), bundle)
+	require.NoError(t, err)
+	require.Equal(t, &Result{State: StateUnchanged}, result)
+
+	_, err = h.Unpack(context.Background(), testBundle(rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeHelm, Helm: &rukpakv1alpha1.HelmSource{
+		Repository: server.URL,
+		Chart:      "my-chart",
+		Auth:       &rukpakv1alpha1.HelmAuth{SecretName: "missing"},
+	}}))
+	require.EqualError(t, err, `get secret "missing": secrets "missing" not found`)
+
+	h.DigestAlgorithm = digest.SHA512
+	result, err = h.Unpack(context.Background(), testBundle(source))
+	require.NoError(t, err)
+	require.Equal(t, digest.Digest(digest.SHA512, archive), result.Digest)
+}
+
+func TestHelmUnpackDependencies(t *testing.T) {
+	files := map[string][]byte{}
+	save := func(chrt *chart.Chart) {
+		path, err := chartutil.Save(chrt, t.TempDir())
+		require.NoError(t, err)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		files["/"+filepath.Base(path)] = data
+	}
+	save(&chart.Chart{
+		Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "dep", Version: "1.0.0"},
+		Templates: []*chart.File{{Name: "templates/service.yaml", Data: []byte(service)}},
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if data, ok := files[r.URL.Path]; ok {
+			_, _ = w.Write(data)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+	save(&chart.Chart{Metadata: &chart.Metadata{
+		APIVersion:   chart.APIVersionV2,
+		Name:         "my-chart",
+		Version:      "1.2.0",
+		Dependencies: []*chart.Dependency{{Name: "dep", Version: "1.0.0", Repository: server.URL}},
+	}})
+	save(&chart.Chart{Metadata: &chart.Metadata{
+		APIVersion:   chart.APIVersionV2,
+		Name:         "unvendored",
+		Version:      "1.0.0",
+		Dependencies: []*chart.Dependency{{Name: "dep", Version: "1.0.0", Repository: "file://../dep"}},
+	}})
+	files["/index.yaml"] = []byte("apiVersion: v1\nentries:\n" +
+		"  dep:\n  - name: dep\n    version: 1.0.0\n    urls:\n    - dep-1.0.0.tgz\n" +
+		"  my-chart:\n  - name: my-chart\n    version: 1.2.0\n    urls:\n    - my-chart-1.2.0.tgz\n" +
+		"  unvendored:\n  - name: unvendored\n    version: 1.0.0\n    urls:\n    - unvendored-1.0.0.tgz\n")
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, rukpakv1alpha1.AddToScheme(scheme))
+	h := &Helm{
+		Client:     fake.NewClientBuilder().WithScheme(scheme).Build(),
+		KubeClient: kubefake.NewSimpleClientset(),
+		Namespace:  "rukpak-system",
+		HTTPClient: server.Client(),
+	}
+
+	result, err := h.Unpack(context.Background(), testBundle(rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeHelm, Helm: &rukpakv1alpha1.HelmSource{Repository: server.URL, Chart: "my-chart"}}))
+	require.NoError(t, err)
+	require.Len(t, result.Objects, 1)
+	require.Equal(t, "svc", result.Objects[0].GetName())
+
+	_, err = h.Unpack(context.Background(), testBundle(rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeHelm, Helm: &rukpakv1alpha1.HelmSource{Repository: server.URL, Chart: "unvendored"}}))
+	require.ErrorIs(t, err, ErrUnresolvedChartDependencies)
+	require.ErrorContains(t, err, `dependency "dep" of chart "unvendored" is not vendored in its charts directory`)
+}
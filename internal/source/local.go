@@ -0,0 +1,143 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nlepage/go-tarfs"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/digest"
+	"github.com/operator-framework/rukpak/internal/manifests"
+	"github.com/operator-framework/rukpak/internal/tracing"
+)
+
+// Local unpacks local sources from their ConfigMap or path, without an
+// unpack pod.
+type Local struct {
+	// KubeClient reads the ConfigMaps of local sources. It is uncached, as
+	// the ConfigMaps are not labeled for the cache of the manager.
+	KubeClient kubernetes.Interface
+	// PathRoot is the directory, mounted from a hostPath or
+	// PersistentVolumeClaim, that the paths of local sources are relative
+	// to. Local sources with a path fail to unpack when it is empty.
+	PathRoot string
+	// DigestAlgorithm is the algorithm of the digests of the unpacked
+	// content. It defaults to SHA256.
+	DigestAlgorithm digest.Algorithm
+}
+
+var _ Unpacker = &Local{}
+
+// Unpack implements Unpacker.
+func (l *Local) Unpack(ctx context.Context, bundle *rukpakv1alpha1.Bundle) (_ *Result, err error) {
+	ctx, span := tracing.Start(ctx, "Bundle.UnpackLocal")
+	defer func() { tracing.End(span, err) }()
+	source := bundle.Spec.Source.Local
+	if source == nil {
+		return nil, errors.New("local source is not set")
+	}
+	if source.Path != "" {
+		return l.unpackPath(source.Path)
+	}
+	if source.ConfigMap == nil {
+		return nil, errors.New("local source sets neither a configmap nor a path")
+	}
+	cm, err := l.KubeClient.CoreV1().ConfigMaps(source.ConfigMap.Namespace).Get(ctx, source.ConfigMap.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get bundle configmap: %w", err)
+	}
+	bundleFS, bundleDigest, err := configMapContents(cm, digest.OrDefault(l.DigestAlgorithm))
+	if err != nil {
+		return nil, fmt.Errorf("read bundle configmap: %w", err)
+	}
+	return &Result{State: StateUnpacked, Bundle: bundleFS, Digest: bundleDigest}, nil
+}
+
+// unpackPath unpacks the bundle directory at p in the PathRoot.
+func (l *Local) unpackPath(p string) (*Result, error) {
+	if l.PathRoot == "" {
+		return nil, errors.New("local path sources are disabled: run the provisioner with a --local-path-root and the LocalPathSource feature enabled to unpack them")
+	}
+	if clean := path.Clean(p); path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return nil, fmt.Errorf("local source path %q is not in the local path root", p)
+	}
+	bundleFS := os.DirFS(filepath.Join(l.PathRoot, filepath.FromSlash(p)))
+	bundleDigest, err := filesDigest(bundleFS, digest.OrDefault(l.DigestAlgorithm))
+	if err != nil {
+		return nil, fmt.Errorf("read local bundle directory %q: %w", p, err)
+	}
+	return &Result{State: StateUnpacked, Bundle: bundleFS, Digest: bundleDigest}, nil
+}
+
+// filesDigest returns the digest of the manifest files of the bundle in
+// bundleFS, computed like the digests of the files of ConfigMaps.
+func filesDigest(bundleFS fs.FS, alg digest.Algorithm) (string, error) {
+	names, err := manifests.Files(bundleFS)
+	if err != nil {
+		return "", err
+	}
+	h := alg.New()
+	for _, name := range names {
+		data, err := fs.ReadFile(bundleFS, path.Join(manifests.Dir, name))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s  %s\n", digest.Sum(alg, data), name)
+	}
+	return fmt.Sprintf("%s:%x", alg.Name(), h.Sum(nil)), nil
+}
+
+// configMapContents returns the files in the data and binary data of cm in
+// the manifests directory of a bundle filesystem, and the digest of the files.
+func configMapContents(cm *corev1.ConfigMap, alg digest.Algorithm) (fs.FS, string, error) {
+	files := map[string][]byte{}
+	for name, data := range cm.Data {
+		files[name] = []byte(data)
+	}
+	for name, data := range cm.BinaryData {
+		files[name] = data
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	h := alg.New()
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: manifests.Dir + "/", Mode: 0755}); err != nil {
+		return nil, "", err
+	}
+	for _, name := range names {
+		data := files[name]
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: manifests.Dir + "/" + name, Mode: 0644, Size: int64(len(data))}); err != nil {
+			return nil, "", err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, "", err
+		}
+		fmt.Fprintf(h, "%s  %s\n", digest.Sum(alg, data), name)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, "", err
+	}
+	bundleFS, err := tarfs.New(buf)
+	if err != nil {
+		return nil, "", err
+	}
+	return bundleFS, fmt.Sprintf("%s:%x", alg.Name(), h.Sum(nil)), nil
+}
@@ -0,0 +1,85 @@
+package source
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/digest"
+)
+
+const service = `apiVersion: v1
+kind: Service
+metadata:
+  name: svc
+  namespace: ns
+`
+
+func TestLocalUnpackConfigMap(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "my-bundle"},
+		Data:       map[string]string{"service.yaml": service},
+	}
+	l := &Local{KubeClient: kubefake.NewSimpleClientset(cm)}
+
+	result, err := l.Unpack(context.Background(), testBundle(rukpakv1alpha1.BundleSource{
+		Type:  rukpakv1alpha1.SourceTypeLocal,
+		Local: &rukpakv1alpha1.LocalSource{ConfigMap: &rukpakv1alpha1.ConfigMapSource{Namespace: "rukpak-system", Name: "my-bundle"}},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, StateUnpacked, result.State)
+	data, err := fs.ReadFile(result.Bundle, "manifests/service.yaml")
+	require.NoError(t, err)
+	require.Equal(t, service, string(data))
+	require.Regexp(t, "^sha256:[0-9a-f]{64}$", result.Digest)
+
+	_, err = l.Unpack(context.Background(), testBundle(rukpakv1alpha1.BundleSource{
+		Type:  rukpakv1alpha1.SourceTypeLocal,
+		Local: &rukpakv1alpha1.LocalSource{ConfigMap: &rukpakv1alpha1.ConfigMapSource{Namespace: "rukpak-system", Name: "missing"}},
+	}))
+	require.EqualError(t, err, `get bundle configmap: configmaps "missing" not found`)
+}
+
+func TestLocalUnpackPath(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "my-bundle", "manifests"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "my-bundle", "manifests", "service.yaml"), []byte(service), 0600))
+	cm := &corev1.ConfigMap{Data: map[string]string{"service.yaml": service}}
+	_, configMapDigest, err := configMapContents(cm, digest.SHA256)
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name    string
+		root    string
+		path    string
+		wantErr string
+	}{
+		{name: "bundle directory", root: root, path: "my-bundle"},
+		{name: "disabled", path: "my-bundle", wantErr: "local path sources are disabled: run the provisioner with a --local-path-root and the LocalPathSource feature enabled to unpack them"},
+		{name: "outside of the root", root: root, path: "../my-bundle", wantErr: `local source path "../my-bundle" is not in the local path root`},
+		{name: "missing directory", root: root, path: "other", wantErr: `read local bundle directory "other": open manifests: no such file or directory`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Local{PathRoot: tt.root}
+			result, err := l.Unpack(context.Background(), testBundle(rukpakv1alpha1.BundleSource{
+				Type:  rukpakv1alpha1.SourceTypeLocal,
+				Local: &rukpakv1alpha1.LocalSource{Path: tt.path},
+			}))
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, StateUnpacked, result.State)
+			require.Equal(t, configMapDigest, result.Digest)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Package source unpacks the content of the sources of Bundles that are not
+// unpacked by an unpack pod. Each type of source has an Unpacker, which the
+// Bundle controller looks up in a Registry by the type of the source of a
+// Bundle, so that a source plugs in without changes to the controller.
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/pkg/status"
+)
+
+// State is the state of the content of a source once an Unpacker unpacked it.
+type State string
+
+const (
+	// StatePending is the state of a source whose content is not available
+	// yet, such as an upload source that nothing was uploaded to.
+	StatePending State = "Pending"
+	// StateUnchanged is the state of a source whose content was already
+	// unpacked for the current generation of its Bundle, and did not change.
+	StateUnchanged State = "Unchanged"
+	// StateUnpacked is the state of a source whose content was unpacked.
+	StateUnpacked State = "Unpacked"
+)
+
+// Result is the result of unpacking the source of a Bundle.
+type Result struct {
+	// State is the state of the content of the source.
+	State State
+	// Reason and Message explain the Unpacked condition of a Bundle whose
+	// source is pending.
+	Reason  status.UnpackedReason
+	Message string
+
+	// Bundle is the content of an unpacked source, with the manifests of
+	// the bundle in its manifests directory, which are verified and
+	// validated before its objects are stored.
+	Bundle fs.FS
+	// Objects are the objects of an unpacked source whose content is already
+	// decoded, such as an upload or a chart, which are stored as they are.
+	// Objects is only set when Bundle is not.
+	Objects []client.Object
+	// Digest is the digest of the unpacked content.
+	Digest string
+	// ResolvedSource is the source of the Bundle pinned to the revision of
+	// the unpacked content, or nil when the source has no revision.
+	ResolvedSource *rukpakv1alpha1.BundleSource
+}
+
+// Unpacker unpacks the content of the sources of a type.
+type Unpacker interface {
+	// Unpack unpacks the content of the source of bundle. Errors are
+	// reported as a failing unpack of bundle.
+	Unpack(ctx context.Context, bundle *rukpakv1alpha1.Bundle) (*Result, error)
+}
+
+// Deleter is implemented by the Unpackers that keep content of the Bundles
+// that they unpack, such as the staged objects of an upload, which the Bundle
+// controller deletes along with a deleted Bundle.
+type Deleter interface {
+	// Delete deletes the content that is kept for bundle. It succeeds when
+	// none is kept.
+	Delete(ctx context.Context, bundle *rukpakv1alpha1.Bundle) error
+}
+
+// ErrUnsupportedSourceType is returned by Registry.Unpack for the Bundles
+// whose type of source has no Unpacker.
+var ErrUnsupportedSourceType = errors.New("unsupported bundle source type")
+
+// Registry has the Unpacker of each type of source, keyed by the
+// rukpakv1alpha1.SourceType constant of its type.
+type Registry map[string]Unpacker
+
+// Unpack unpacks the source of bundle with the Unpacker of its type.
+func (r Registry) Unpack(ctx context.Context, bundle *rukpakv1alpha1.Bundle) (*Result, error) {
+	unpacker, ok := r[bundle.Spec.Source.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedSourceType, bundle.Spec.Source.Type)
+	}
+	return unpacker.Unpack(ctx, bundle)
+}
@@ -0,0 +1,54 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+// fakeUnpacker returns its result and error, and records the Bundles that it
+// unpacks.
+type fakeUnpacker struct {
+	result   *Result
+	err      error
+	unpacked []string
+}
+
+func (f *fakeUnpacker) Unpack(_ context.Context, bundle *rukpakv1alpha1.Bundle) (*Result, error) {
+	f.unpacked = append(f.unpacked, bundle.Name)
+	return f.result, f.err
+}
+
+func testBundle(source rukpakv1alpha1.BundleSource) *rukpakv1alpha1.Bundle {
+	return &rukpakv1alpha1.Bundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 1},
+		Spec:       rukpakv1alpha1.BundleSpec{ProvisionerClassName: "core.rukpak.io/plain", Source: source},
+	}
+}
+
+func TestRegistryUnpack(t *testing.T) {
+	local := &fakeUnpacker{result: &Result{State: StateUnpacked, Digest: "sha256:local"}}
+	upload := &fakeUnpacker{err: errors.New("upload failed")}
+	r := Registry{
+		rukpakv1alpha1.SourceTypeLocal:  local,
+		rukpakv1alpha1.SourceTypeUpload: upload,
+	}
+
+	result, err := r.Unpack(context.Background(), testBundle(rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeLocal}))
+	require.NoError(t, err)
+	require.Equal(t, &Result{State: StateUnpacked, Digest: "sha256:local"}, result)
+	require.Equal(t, []string{"test"}, local.unpacked)
+
+	_, err = r.Unpack(context.Background(), testBundle(rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeUpload}))
+	require.EqualError(t, err, "upload failed")
+	require.Equal(t, []string{"test"}, upload.unpacked)
+
+	_, err = r.Unpack(context.Background(), testBundle(rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeImage}))
+	require.ErrorIs(t, err, ErrUnsupportedSourceType)
+	require.EqualError(t, err, `unsupported bundle source type "image"`)
+}
@@ -0,0 +1,62 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/storage"
+	"github.com/operator-framework/rukpak/internal/tracing"
+	"github.com/operator-framework/rukpak/pkg/status"
+)
+
+// Upload unpacks upload sources from the storage in which the rukpak core
+// stages the validated objects of an upload, before it annotates the Bundle
+// with the digest of the upload.
+type Upload struct {
+	// Storage is the storage of the uploaded objects. Uploads are disabled,
+	// and fail to unpack, when it is nil.
+	Storage storage.Storage
+}
+
+var (
+	_ Unpacker = &Upload{}
+	_ Deleter  = &Upload{}
+)
+
+// Unpack implements Unpacker.
+func (up *Upload) Unpack(ctx context.Context, bundle *rukpakv1alpha1.Bundle) (_ *Result, err error) {
+	ctx, span := tracing.Start(ctx, "Bundle.UnpackUpload")
+	defer func() { tracing.End(span, err) }()
+	uploadedDigest, ok := bundle.Annotations[rukpakv1alpha1.AnnotationUploadedDigest]
+	if !ok {
+		return &Result{State: StatePending, Reason: status.WaitingForUpload, Message: "waiting for the bundle content to be uploaded"}, nil
+	}
+	if up.Storage == nil {
+		return nil, errors.New("uploads are disabled: run the provisioner with the UploadSource feature enabled to unpack uploaded content")
+	}
+	uploaded, err := up.Storage.Load(ctx, bundle)
+	if err != nil {
+		return nil, fmt.Errorf("load uploaded bundle objects: %w", err)
+	}
+	objects := make([]client.Object, 0, len(uploaded))
+	for i := range uploaded {
+		objects = append(objects, &uploaded[i])
+	}
+	return &Result{State: StateUnpacked, Objects: objects, Digest: uploadedDigest}, nil
+}
+
+// Delete implements Deleter, and deletes the staged objects of the upload of
+// bundle.
+func (up *Upload) Delete(ctx context.Context, bundle *rukpakv1alpha1.Bundle) error {
+	if up.Storage == nil {
+		return nil
+	}
+	if err := up.Storage.Delete(ctx, bundle); err != nil {
+		return fmt.Errorf("delete uploaded bundle objects: %w", err)
+	}
+	return nil
+}
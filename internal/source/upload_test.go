@@ -0,0 +1,146 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/pkg/status"
+)
+
+// fakeStorage loads and deletes its objects, or fails with its error.
+type fakeStorage struct {
+	objects []unstructured.Unstructured
+	err     error
+	deleted bool
+}
+
+func (s *fakeStorage) Load(context.Context, client.Object) ([]unstructured.Unstructured, error) {
+	return s.objects, s.err
+}
+
+func (s *fakeStorage) Store(context.Context, client.Object, []client.Object) error {
+	return errors.New("not implemented")
+}
+
+func (s *fakeStorage) LoadStream(context.Context, client.Object) (io.ReadCloser, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *fakeStorage) StoreStream(context.Context, client.Object, io.Reader) error {
+	return errors.New("not implemented")
+}
+
+func (s *fakeStorage) Delete(context.Context, client.Object) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.objects, s.deleted = nil, true
+	return nil
+}
+
+func (s *fakeStorage) Exists(context.Context, client.Object) (bool, error) {
+	return s.objects != nil, s.err
+}
+
+func TestUploadUnpack(t *testing.T) {
+	obj := unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("Service")
+	obj.SetName("svc")
+	source := rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeUpload}
+	uploaded := testBundle(source)
+	uploaded.Annotations = map[string]string{rukpakv1alpha1.AnnotationUploadedDigest: "sha256:uploaded"}
+
+	for _, tt := range []struct {
+		name       string
+		storage    *fakeStorage
+		bundle     *rukpakv1alpha1.Bundle
+		wantResult *Result
+		wantErr    string
+	}{
+		{
+			name:       "not uploaded",
+			storage:    &fakeStorage{},
+			bundle:     testBundle(source),
+			wantResult: &Result{State: StatePending, Reason: status.WaitingForUpload, Message: "waiting for the bundle content to be uploaded"},
+		},
+		{
+			name:       "uploaded",
+			storage:    &fakeStorage{objects: []unstructured.Unstructured{obj}},
+			bundle:     uploaded,
+			wantResult: &Result{State: StateUnpacked, Objects: []client.Object{&obj}, Digest: "sha256:uploaded"},
+		},
+		{
+			name:    "disabled",
+			bundle:  uploaded,
+			wantErr: "uploads are disabled: run the provisioner with the UploadSource feature enabled to unpack uploaded content",
+		},
+		{
+			name:    "storage error",
+			storage: &fakeStorage{err: errors.New("connection refused")},
+			bundle:  uploaded,
+			wantErr: "load uploaded bundle objects: connection refused",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			up := &Upload{}
+			if tt.storage != nil {
+				up.Storage = tt.storage
+			}
+			result, err := up.Unpack(context.Background(), tt.bundle)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantResult, result)
+		})
+	}
+}
+
+func TestUploadDelete(t *testing.T) {
+	bundle := testBundle(rukpakv1alpha1.BundleSource{Type: rukpakv1alpha1.SourceTypeUpload})
+	for _, tt := range []struct {
+		name        string
+		storage     *fakeStorage
+		wantDeleted bool
+		wantErr     string
+	}{
+		{
+			name:        "uploaded",
+			storage:     &fakeStorage{},
+			wantDeleted: true,
+		},
+		{
+			name: "disabled",
+		},
+		{
+			name:    "storage error",
+			storage: &fakeStorage{err: errors.New("connection refused")},
+			wantErr: "delete uploaded bundle objects: connection refused",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			up := &Upload{}
+			if tt.storage != nil {
+				up.Storage = tt.storage
+			}
+			err := up.Delete(context.Background(), bundle)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.storage != nil {
+				require.Equal(t, tt.wantDeleted, tt.storage.deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ Storage = &Cache{}
+
+// Cache is a Storage that keeps the objects of the most recently loaded
+// Bundles in memory, so that the BundleInstances of a Bundle do not read and
+// decode its objects from the underlying Storage on every reconcile. Objects
+// are cached by the name and the status.digest of their Bundle, and the
+// objects of owners without a digest are not cached. Storing the objects of an
+// owner evicts them.
+type Cache struct {
+	Storage Storage
+
+	mu      sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[cacheKey]*list.Element
+}
+
+type cacheKey struct {
+	name   string
+	digest string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	objects []unstructured.Unstructured
+}
+
+// NewCache returns a Cache of the objects of up to size owners in s.
+func NewCache(s Storage, size int) *Cache {
+	return &Cache{
+		Storage: s,
+		size:    size,
+		lru:     list.New(),
+		entries: map[cacheKey]*list.Element{},
+	}
+}
+
+// Load returns copies of the cached objects of owner, or loads them from the
+// underlying Storage. Objects are verified when they are loaded into the cache,
+// rather than every time they are returned.
+func (c *Cache) Load(ctx context.Context, owner client.Object) ([]unstructured.Unstructured, error) {
+	key := cacheKey{name: owner.GetName(), digest: ownerDigest(owner)}
+	if key.digest == "" {
+		return c.Storage.Load(ctx, owner)
+	}
+	if objects, ok := c.get(key); ok {
+		return objects, nil
+	}
+	objects, err := c.Storage.Load(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	c.add(key, objects)
+	return copyObjects(objects), nil
+}
+
+// Store stores objects in the underlying Storage, and evicts the cached
+// objects of owner.
+func (c *Cache) Store(ctx context.Context, owner client.Object, objects []client.Object) error {
+	c.evict(owner.GetName())
+	return c.Storage.Store(ctx, owner, objects)
+}
+
+// LoadStream streams the objects of owner from the underlying Storage. Cached
+// objects are not streamed, since they are only cached once they have been
+// decoded and verified as a whole.
+func (c *Cache) LoadStream(ctx context.Context, owner client.Object) (io.ReadCloser, error) {
+	return c.Storage.LoadStream(ctx, owner)
+}
+
+// StoreStream stores the objects in r in the underlying Storage, and evicts
+// the cached objects of owner.
+func (c *Cache) StoreStream(ctx context.Context, owner client.Object, r io.Reader) error {
+	c.evict(owner.GetName())
+	return c.Storage.StoreStream(ctx, owner, r)
+}
+
+// Delete deletes the objects of owner from the underlying Storage, and evicts
+// its cached objects.
+func (c *Cache) Delete(ctx context.Context, owner client.Object) error {
+	c.evict(owner.GetName())
+	return c.Storage.Delete(ctx, owner)
+}
+
+// Exists returns whether the underlying Storage has objects of owner.
+func (c *Cache) Exists(ctx context.Context, owner client.Object) (bool, error) {
+	return c.Storage.Exists(ctx, owner)
+}
+
+func (c *Cache) get(key cacheKey) ([]unstructured.Unstructured, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return copyObjects(elem.Value.(*cacheEntry).objects), true
+}
+
+func (c *Cache) add(key cacheKey, objects []unstructured.Unstructured) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size <= 0 {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).objects = objects
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, objects: objects})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *Cache) evict(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.entries {
+		if key.name == name {
+			c.lru.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// copyObjects returns deep copies of objects, so that callers can modify the
+// objects that they load without changing those in the cache.
+func copyObjects(objects []unstructured.Unstructured) []unstructured.Unstructured {
+	copies := make([]unstructured.Unstructured, 0, len(objects))
+	for i := range objects {
+		copies = append(copies, *objects[i].DeepCopy())
+	}
+	return copies
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+// countingStorage is a Storage that counts the loads of its objects.
+type countingStorage struct {
+	objects map[string][]unstructured.Unstructured
+	loads   int
+}
+
+func (s *countingStorage) Load(_ context.Context, owner client.Object) ([]unstructured.Unstructured, error) {
+	s.loads++
+	return s.objects[owner.GetName()], nil
+}
+
+func (s *countingStorage) Store(_ context.Context, owner client.Object, objects []client.Object) error {
+	stored := make([]unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		stored = append(stored, *obj.(*unstructured.Unstructured))
+	}
+	s.objects[owner.GetName()] = stored
+	return nil
+}
+
+func (s *countingStorage) LoadStream(context.Context, client.Object) (io.ReadCloser, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *countingStorage) StoreStream(context.Context, client.Object, io.Reader) error {
+	return errors.New("not implemented")
+}
+
+func (s *countingStorage) Delete(_ context.Context, owner client.Object) error {
+	delete(s.objects, owner.GetName())
+	return nil
+}
+
+func (s *countingStorage) Exists(_ context.Context, owner client.Object) (bool, error) {
+	_, ok := s.objects[owner.GetName()]
+	return ok, nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	bundle := func(name, digest string) *rukpakv1alpha1.Bundle {
+		return &rukpakv1alpha1.Bundle{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     rukpakv1alpha1.BundleStatus{Digest: digest},
+		}
+	}
+	underlying := &countingStorage{objects: map[string][]unstructured.Unstructured{}}
+	c := NewCache(underlying, 2)
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, c.Store(ctx, bundle(name, "sha256:1"), []client.Object{newTestObject("ConfigMap", name)}))
+	}
+
+	objects, err := c.Load(ctx, bundle("a", "sha256:1"))
+	require.NoError(t, err)
+	require.Equal(t, "a", objects[0].GetName())
+	objects[0].SetName("changed")
+	objects, err = c.Load(ctx, bundle("a", "sha256:1"))
+	require.NoError(t, err)
+	require.Equal(t, "a", objects[0].GetName(), "cached objects should not be changed by callers")
+	require.Equal(t, 1, underlying.loads)
+
+	// Another digest of the bundle is loaded from the underlying storage.
+	_, err = c.Load(ctx, bundle("a", "sha256:2"))
+	require.NoError(t, err)
+	require.Equal(t, 2, underlying.loads)
+
+	// Owners without a digest are not cached.
+	_, err = c.Load(ctx, bundle("b", ""))
+	require.NoError(t, err)
+	_, err = c.Load(ctx, bundle("b", ""))
+	require.NoError(t, err)
+	require.Equal(t, 4, underlying.loads)
+
+	// The least recently used bundle is evicted.
+	_, err = c.Load(ctx, bundle("c", "sha256:1"))
+	require.NoError(t, err)
+	_, err = c.Load(ctx, bundle("a", "sha256:1"))
+	require.NoError(t, err)
+	require.Equal(t, 6, underlying.loads)
+
+	// Storing the objects of a bundle evicts them.
+	_, err = c.Load(ctx, bundle("c", "sha256:1"))
+	require.NoError(t, err)
+	require.Equal(t, 6, underlying.loads)
+	require.NoError(t, c.Store(ctx, bundle("c", "sha256:1"), []client.Object{newTestObject("Secret", "c")}))
+	objects, err = c.Load(ctx, bundle("c", "sha256:1"))
+	require.NoError(t, err)
+	require.Equal(t, "Secret", objects[0].GetKind())
+	require.Equal(t, 7, underlying.loads)
+
+	// Deleting the objects of a bundle evicts them.
+	require.NoError(t, c.Delete(ctx, bundle("c", "sha256:1")))
+	exists, err := c.Exists(ctx, bundle("c", "sha256:1"))
+	require.NoError(t, err)
+	require.False(t, exists)
+	objects, err = c.Load(ctx, bundle("c", "sha256:1"))
+	require.NoError(t, err)
+	require.Empty(t, objects)
+	require.Equal(t, 8, underlying.loads)
+}
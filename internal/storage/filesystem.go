@@ -0,0 +1,406 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/rukpak/internal/digest"
+)
+
+var _ Storage = &Filesystem{}
+
+// Filesystem stores objects in a directory tree, typically on a
+// PersistentVolume mounted into the pod.
+//
+// The objects of each store are written to a content-addressed directory
+// under <Root>/content, named by the digest of the objects. A reference file
+// <Root>/refs/<NamePrefix><owner name> contains the digest of the directory
+// that holds the objects of an owner, followed by the digest of the owner when
+// they were stored. The digest of a content directory is the checksum of its
+// objects, which Load verifies. Both the content directories and the
+// reference files are written to temporary paths and renamed into place, so
+// that a concurrent Load never observes partially written content. Content
+// directories that are no longer referenced are removed when a reference is
+// replaced or deleted. Stores and deletes hold an exclusive flock of the file
+// <Root>/.lock, as the root may be shared by the processes of several pods.
+type Filesystem struct {
+	Root       string
+	NamePrefix string
+	// Algorithm is the algorithm of the digests that address stored content.
+	// It defaults to SHA256.
+	Algorithm digest.Algorithm
+}
+
+// filesystemStoreMu serializes the stores of all Filesystems, which may share
+// a root, so that a content directory that is about to be referenced is never
+// removed as unreferenced. The flock of the root does the same for the stores
+// of other processes.
+var filesystemStoreMu sync.Mutex
+
+// lockFileName is the name of the file under the root of a Filesystem that
+// stores and deletes lock.
+const lockFileName = ".lock"
+
+// lock serializes the stores and deletes of every Filesystem of the root of
+// s, in this process and in others, and returns the function that releases
+// the lock.
+func (s *Filesystem) lock() (func(), error) {
+	filesystemStoreMu.Lock()
+	if err := os.MkdirAll(s.Root, 0755); err != nil {
+		filesystemStoreMu.Unlock()
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(s.Root, lockFileName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		filesystemStoreMu.Unlock()
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		filesystemStoreMu.Unlock()
+		return nil, fmt.Errorf("lock %s: %w", f.Name(), err)
+	}
+	return func() {
+		// Closing the file releases its flock.
+		f.Close()
+		filesystemStoreMu.Unlock()
+	}, nil
+}
+
+func (s *Filesystem) Load(_ context.Context, owner client.Object) ([]unstructured.Unstructured, error) {
+	contentDigest, storedOwnerDigest, err := s.readRef(owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyOwnerDigest(owner, storedOwnerDigest); err != nil {
+		return nil, err
+	}
+	files, err := s.readContent(contentDigest)
+	if err != nil {
+		var integrityErr *IntegrityError
+		if errors.As(err, &integrityErr) {
+			integrityErr.Owner = owner.GetName()
+			return nil, integrityErr
+		}
+		return nil, fmt.Errorf("read content directory for %q: %w", owner.GetName(), err)
+	}
+
+	objects := []unstructured.Unstructured{}
+	for i, data := range files {
+		u := unstructured.Unstructured{}
+		if err := yaml.Unmarshal(data, &u); err != nil {
+			return nil, fmt.Errorf("unmarshal %q: %w", contentFileName(i), err)
+		}
+		objects = append(objects, u)
+	}
+	return objects, nil
+}
+
+// LoadStream streams the files of the content directory of owner as they are
+// read.
+func (s *Filesystem) LoadStream(_ context.Context, owner client.Object) (io.ReadCloser, error) {
+	contentDigest, storedOwnerDigest, err := s.readRef(owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyOwnerDigest(owner, storedOwnerDigest); err != nil {
+		return nil, err
+	}
+	return pipeDocuments(func(w io.Writer) error {
+		err := s.walkContent(contentDigest, func(data []byte) error {
+			return writeDocument(w, data)
+		})
+		var integrityErr *IntegrityError
+		if errors.As(err, &integrityErr) {
+			integrityErr.Owner = owner.GetName()
+		}
+		return err
+	}), nil
+}
+
+// readContent returns the files of the content directory of contentDigest in
+// order, or an *IntegrityError when they do not match the digest.
+func (s *Filesystem) readContent(contentDigest string) ([][]byte, error) {
+	var files [][]byte
+	if err := s.walkContent(contentDigest, func(data []byte) error {
+		files = append(files, data)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// walkContent calls fn with each file of the content directory of
+// contentDigest in order, and returns an *IntegrityError once all of them have
+// been read when they do not match the digest.
+func (s *Filesystem) walkContent(contentDigest string, fn func(data []byte) error) error {
+	alg, sum, err := parseContentDigest(contentDigest)
+	if err != nil {
+		return &IntegrityError{Reason: err.Error()}
+	}
+	dir := s.contentDir(contentDigest)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	h := alg.New()
+	for _, entry := range entries {
+		data, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\n", digest.Sum(alg, data))
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	if fmt.Sprintf("%x", h.Sum(nil)) != sum {
+		return &IntegrityError{Reason: "the stored objects do not match their checksum"}
+	}
+	return nil
+}
+
+func (s *Filesystem) Store(_ context.Context, owner client.Object, objects []client.Object) error {
+	return s.store(owner, eachObject(objects))
+}
+
+func (s *Filesystem) StoreStream(_ context.Context, owner client.Object, r io.Reader) error {
+	return s.store(owner, decodeObjects(r))
+}
+
+func (s *Filesystem) store(owner client.Object, objects objectIterator) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	contentDigest, err := s.writeContent(objects)
+	if err != nil {
+		return err
+	}
+	previous, _, err := s.readRef(owner)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err := s.writeRef(owner, contentDigest, ownerDigest(owner)); err != nil {
+		return err
+	}
+	if previous != "" && previous != contentDigest {
+		return s.removeIfUnreferenced(previous)
+	}
+	return nil
+}
+
+func (s *Filesystem) contentDir(digest string) string {
+	return filepath.Join(s.Root, "content", digest)
+}
+
+// contentFileName returns the name of the file of the i-th object in a content
+// directory.
+func contentFileName(i int) string {
+	return fmt.Sprintf("%06d.yaml", i)
+}
+
+func (s *Filesystem) refPath(owner client.Object) string {
+	return filepath.Join(s.Root, "refs", s.NamePrefix+owner.GetName())
+}
+
+// readRef returns the digest of the content directory of owner, and the
+// digest of owner when it was stored, which is empty for references written
+// before they recorded it.
+func (s *Filesystem) readRef(owner client.Object) (string, string, error) {
+	data, err := ioutil.ReadFile(s.refPath(owner))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", apierrors.NewNotFound(schema.GroupResource{Resource: "objects"}, owner.GetName())
+	}
+	if err != nil {
+		return "", "", err
+	}
+	contentDigest, storedOwnerDigest := parseRef(data)
+	return contentDigest, storedOwnerDigest, nil
+}
+
+// parseRef returns the digest of the content directory and the digest of the
+// owner in the reference file data.
+func parseRef(data []byte) (string, string) {
+	lines := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	if len(lines) < 2 {
+		return lines[0], ""
+	}
+	return lines[0], strings.TrimSpace(lines[1])
+}
+
+// parseContentDigest returns the algorithm and the hex-encoded checksum of
+// the content directory digest d, which is prefixed by the name of its
+// algorithm unless it is SHA256.
+func parseContentDigest(d string) (digest.Algorithm, string, error) {
+	if i := strings.Index(d, "-"); i >= 0 {
+		return digest.Parse(d[:i] + ":" + d[i+1:])
+	}
+	return digest.SHA256, d, nil
+}
+
+func (s *Filesystem) writeRef(owner client.Object, digest, storedOwnerDigest string) error {
+	dir := filepath.Dir(s.refPath(owner))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	ref := digest + "\n"
+	if storedOwnerDigest != "" {
+		ref += storedOwnerDigest + "\n"
+	}
+	if _, err := tmp.WriteString(ref); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.refPath(owner))
+}
+
+// writeContent writes each of objects to a temporary directory as they are
+// iterated, and atomically renames it to the content directory of their
+// digest, which it returns. The content directory is kept when it already
+// exists with content that matches the digest, and replaced when its content
+// was corrupted.
+func (s *Filesystem) writeContent(objects objectIterator) (string, error) {
+	parent := filepath.Join(s.Root, "content")
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempDir(parent, ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	alg := digest.OrDefault(s.Algorithm)
+	h := alg.New()
+	i := 0
+	if err := objects(func(obj client.Object) error {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmp, contentFileName(i)), data, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\n", digest.Sum(alg, data))
+		i++
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	contentDigest := fmt.Sprintf("%x", h.Sum(nil))
+	if alg != digest.SHA256 {
+		// Digests of the default algorithm are not prefixed, so that content
+		// stored before the algorithm was configurable is still shared.
+		contentDigest = alg.Name() + "-" + contentDigest
+	}
+
+	dir := s.contentDir(contentDigest)
+	if _, err := os.Stat(dir); err == nil {
+		if err := s.walkContent(contentDigest, func([]byte) error { return nil }); err == nil {
+			return contentDigest, nil
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		// Another writer may have stored the same content concurrently.
+		if _, statErr := os.Stat(dir); statErr == nil {
+			return contentDigest, nil
+		}
+		return "", err
+	}
+	return contentDigest, nil
+}
+
+// Exists returns whether the reference file of owner exists.
+func (s *Filesystem) Exists(_ context.Context, owner client.Object) (bool, error) {
+	if _, _, err := s.readRef(owner); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes the reference file of owner, and its content directory
+// unless another reference file refers to it.
+func (s *Filesystem) Delete(_ context.Context, owner client.Object) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	contentDigest, _, err := s.readRef(owner)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(s.refPath(owner)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return s.removeIfUnreferenced(contentDigest)
+}
+
+// removeIfUnreferenced removes the content directory of digest when no
+// reference file refers to it anymore.
+func (s *Filesystem) removeIfUnreferenced(digest string) error {
+	refsDir := filepath.Join(s.Root, "refs")
+	entries, err := ioutil.ReadDir(refsDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(refsDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if contentDigest, _ := parseRef(data); contentDigest == digest {
+			return nil
+		}
+	}
+	return os.RemoveAll(s.contentDir(digest))
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func newTestObject(kind, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind(kind)
+	u.SetName(name)
+	return u
+}
+
+func contentDirs(t *testing.T, root string) []string {
+	t.Helper()
+	entries, err := ioutil.ReadDir(filepath.Join(root, "content"))
+	require.NoError(t, err)
+	var dirs []string
+	for _, entry := range entries {
+		dirs = append(dirs, entry.Name())
+	}
+	return dirs
+}
+
+func TestFilesystemStoreAndLoad(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s := &Filesystem{Root: root, NamePrefix: "bundle-"}
+	owner := newTestObject("Bundle", "test-owner")
+
+	_, err := s.Load(ctx, owner)
+	require.True(t, apierrors.IsNotFound(err), "expected not found error, got %v", err)
+
+	require.NoError(t, s.Store(ctx, owner, []client.Object{newTestObject("ConfigMap", "b"), newTestObject("ConfigMap", "a")}))
+	actual, err := s.Load(ctx, owner)
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	require.Equal(t, "b", actual[0].GetName(), "objects should be loaded in the order they were stored")
+	require.Equal(t, "a", actual[1].GetName())
+	require.Len(t, contentDirs(t, root), 1)
+
+	require.NoError(t, s.Store(ctx, owner, []client.Object{newTestObject("ConfigMap", "c")}))
+	actual, err = s.Load(ctx, owner)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, "c", actual[0].GetName())
+	require.Len(t, contentDirs(t, root), 1, "unreferenced content should be removed")
+}
+
+func TestFilesystemSharedContent(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s := &Filesystem{Root: root, NamePrefix: "bundle-"}
+	objects := []client.Object{newTestObject("ConfigMap", "a")}
+
+	require.NoError(t, s.Store(ctx, newTestObject("Bundle", "one"), objects))
+	require.NoError(t, s.Store(ctx, newTestObject("Bundle", "two"), objects))
+	require.Len(t, contentDirs(t, root), 1, "identical content should be stored once")
+
+	require.NoError(t, s.Store(ctx, newTestObject("Bundle", "one"), nil))
+	actual, err := s.Load(ctx, newTestObject("Bundle", "two"))
+	require.NoError(t, err, "content that is still referenced should be kept")
+	require.Len(t, actual, 1)
+}
+
+func TestFilesystemLock(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s := &Filesystem{Root: root, NamePrefix: "bundle-"}
+
+	// A lock held by another process is held on another open file of the
+	// root's lock file.
+	f, err := os.OpenFile(filepath.Join(root, lockFileName), os.O_CREATE|os.O_RDWR, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX))
+
+	stored := make(chan error, 1)
+	go func() {
+		stored <- s.Store(ctx, newTestObject("Bundle", "test-owner"), []client.Object{newTestObject("ConfigMap", "a")})
+	}()
+	select {
+	case err := <-stored:
+		t.Fatalf("store completed while the root was locked by another process: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_UN))
+	require.NoError(t, <-stored)
+	require.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB), "the store should release the lock")
+}
+
+func TestFilesystemIntegrity(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s := &Filesystem{Root: root, NamePrefix: "bundle-"}
+	owner := &rukpakv1alpha1.Bundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-owner"},
+		Status:     rukpakv1alpha1.BundleStatus{Digest: "sha256:1234"},
+	}
+	objects := []client.Object{newTestObject("ConfigMap", "a")}
+	require.NoError(t, s.Store(ctx, owner, objects))
+	_, err := s.Load(ctx, owner)
+	require.NoError(t, err)
+
+	// Content stored for another digest of the bundle is rejected.
+	unpacked := owner.DeepCopy()
+	unpacked.Status.Digest = "sha256:5678"
+	var integrityErr *IntegrityError
+	_, err = s.Load(ctx, unpacked)
+	require.ErrorAs(t, err, &integrityErr)
+
+	// Tampering with a stored object fails the checksum of its content.
+	dirs := contentDirs(t, root)
+	require.Len(t, dirs, 1)
+	file := filepath.Join(root, "content", dirs[0], contentFileName(0))
+	require.NoError(t, os.Chmod(filepath.Dir(file), 0755))
+	require.NoError(t, ioutil.WriteFile(file, []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: a\n"), 0644))
+	_, err = s.Load(ctx, owner)
+	require.ErrorAs(t, err, &integrityErr)
+	require.Equal(t, "test-owner", integrityErr.Owner)
+
+	// Storing the content again replaces the corrupted directory.
+	require.NoError(t, s.Store(ctx, owner, objects))
+	actual, err := s.Load(ctx, owner)
+	require.NoError(t, err)
+	require.Equal(t, "ConfigMap", actual[0].GetKind())
+}
+
+func TestFilesystemStream(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s := &Filesystem{Root: root, NamePrefix: "bundle-"}
+	owner := newTestObject("Bundle", "test-owner")
+
+	_, err := s.LoadStream(ctx, owner)
+	require.True(t, apierrors.IsNotFound(err), "expected not found error, got %v", err)
+	exists, err := s.Exists(ctx, owner)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	require.NoError(t, s.StoreStream(ctx, owner, strings.NewReader(testManifests)))
+	exists, err = s.Exists(ctx, owner)
+	require.NoError(t, err)
+	require.True(t, exists)
+	streamed, err := loadStream(t, s, owner)
+	require.NoError(t, err)
+	require.Len(t, streamed, 2)
+	require.Equal(t, "a", streamed[0].GetName())
+	require.Equal(t, "Secret", streamed[1].GetKind())
+	actual, err := s.Load(ctx, owner)
+	require.NoError(t, err)
+	require.Equal(t, actual, streamed)
+
+	// Content stored as a stream is shared with the same objects stored as a
+	// slice.
+	other := newTestObject("Bundle", "other")
+	require.NoError(t, s.Store(ctx, other, []client.Object{&actual[0], &actual[1]}))
+	require.Len(t, contentDirs(t, root), 1)
+
+	// Tampering with a stored object fails the stream at its end.
+	file := filepath.Join(root, "content", contentDirs(t, root)[0], contentFileName(1))
+	require.NoError(t, ioutil.WriteFile(file, []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: c\n"), 0644))
+	var integrityErr *IntegrityError
+	_, err = loadStream(t, s, owner)
+	require.ErrorAs(t, err, &integrityErr)
+	require.Equal(t, "test-owner", integrityErr.Owner)
+
+	// Deleted content is kept while another owner refers to it.
+	require.NoError(t, s.Delete(ctx, owner))
+	exists, err = s.Exists(ctx, owner)
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.Len(t, contentDirs(t, root), 1)
+	require.NoError(t, s.Delete(ctx, other))
+	require.Empty(t, contentDirs(t, root))
+	require.NoError(t, s.Delete(ctx, other), "deleting objects that are not stored should succeed")
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/rukpak/internal/digest"
+)
+
+const (
+	// BackendConfigMaps selects the ConfigMaps storage backend.
+	BackendConfigMaps = "configmaps"
+	// BackendS3 selects the S3 storage backend.
+	BackendS3 = "s3"
+	// BackendFilesystem selects the Filesystem storage backend.
+	BackendFilesystem = "filesystem"
+)
+
+// Options selects and configures the storage backend of a manager at startup.
+type Options struct {
+	Backend string
+
+	// FilesystemRoot is the directory in which the Filesystem backend stores
+	// content. Content of different kinds is stored in the same directory
+	// tree, and is distinguished by its name prefix.
+	FilesystemRoot string
+
+	S3Endpoint string
+	S3Bucket   string
+	S3Region   string
+	S3Prefix   string
+	// S3CredentialsSecret is the name of the Secret, in the namespace of the
+	// storage, containing the accessKeyID, secretAccessKey and optional
+	// sessionToken used to access the object store. When it is unset, the
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+	// environment variables are used instead.
+	S3CredentialsSecret string
+
+	// Algorithm is the algorithm of the digests of stored content. It is not
+	// bound to a flag, and defaults to SHA256.
+	Algorithm digest.Algorithm
+}
+
+// BindFlags binds the storage options to flags in fs.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Backend, "storage-backend", BackendConfigMaps,
+		fmt.Sprintf("The backend used to store unpacked bundle content. One of %q, %q or %q.", BackendConfigMaps, BackendS3, BackendFilesystem))
+	fs.StringVar(&o.FilesystemRoot, "storage-root", "/var/lib/rukpak/storage",
+		"The directory in which bundle content is stored by the filesystem storage backend, typically the mount path of a PersistentVolume.")
+	fs.StringVar(&o.S3Endpoint, "s3-endpoint", "https://s3.amazonaws.com", "The URL of the S3-compatible object store.")
+	fs.StringVar(&o.S3Bucket, "s3-bucket", "", "The bucket in which bundle content is stored.")
+	fs.StringVar(&o.S3Region, "s3-region", "us-east-1", "The region of the S3 bucket.")
+	fs.StringVar(&o.S3Prefix, "s3-prefix", "rukpak", "The key prefix under which bundle content is stored in the S3 bucket.")
+	fs.StringVar(&o.S3CredentialsSecret, "s3-credentials-secret", "",
+		"The name of a Secret in the system namespace with the accessKeyID, secretAccessKey and optional sessionToken used to access the S3 bucket. "+
+			"The AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables are used when unset.")
+}
+
+// New returns the Storage of the configured backend that stores content with
+// the given name prefix in namespace. Secrets are read with reader, which is
+// typically the uncached API reader of a manager that has not been started yet.
+func (o *Options) New(ctx context.Context, cl client.Client, reader client.Reader, namespace, namePrefix string) (Storage, error) {
+	switch o.Backend {
+	case BackendConfigMaps:
+		return &ConfigMaps{
+			Client:     cl,
+			Namespace:  namespace,
+			NamePrefix: namePrefix,
+			Algorithm:  o.Algorithm,
+		}, nil
+	case BackendFilesystem:
+		if o.FilesystemRoot == "" {
+			return nil, fmt.Errorf("a storage root is required by the %q storage backend", BackendFilesystem)
+		}
+		return &Filesystem{
+			Root:       o.FilesystemRoot,
+			NamePrefix: namePrefix,
+			Algorithm:  o.Algorithm,
+		}, nil
+	case BackendS3:
+		if o.S3Bucket == "" {
+			return nil, fmt.Errorf("an S3 bucket is required by the %q storage backend", BackendS3)
+		}
+		endpoint, err := url.Parse(o.S3Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("parse S3 endpoint: %w", err)
+		}
+		creds, err := o.s3Credentials(ctx, reader, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &S3{
+			Endpoint:    endpoint,
+			Bucket:      o.S3Bucket,
+			Region:      o.S3Region,
+			Credentials: *creds,
+			Prefix:      o.S3Prefix,
+			NamePrefix:  namePrefix,
+			Algorithm:   o.Algorithm,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", o.Backend)
+	}
+}
+
+func (o *Options) s3Credentials(ctx context.Context, reader client.Reader, namespace string) (*S3Credentials, error) {
+	if o.S3CredentialsSecret == "" {
+		return &S3Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: o.S3CredentialsSecret}, secret); err != nil {
+		return nil, fmt.Errorf("get S3 credentials secret: %w", err)
+	}
+	creds := &S3Credentials{
+		AccessKeyID:     string(secret.Data["accessKeyID"]),
+		SecretAccessKey: string(secret.Data["secretAccessKey"]),
+		SessionToken:    string(secret.Data["sessionToken"]),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 credentials secret %q must contain accessKeyID and secretAccessKey", o.S3CredentialsSecret)
+	}
+	return creds, nil
+}
@@ -0,0 +1,405 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/rukpak/internal/digest"
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+var _ Storage = &S3{}
+
+// S3Credentials are the credentials used to sign requests to an S3-compatible
+// object store.
+type S3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3 stores the objects of each owner as a single gzipped JSON document in a
+// bucket of an S3-compatible object store, which is addressed path-style and
+// authenticated with AWS Signature Version 4.
+//
+// The checksum of a document and the digest of its owner are stored in its
+// user metadata. Unlike ConfigMaps, stored documents are not garbage collected
+// when their owner is deleted, and must be removed with Delete.
+type S3 struct {
+	// HTTPClient is the client used to make requests to the object store.
+	// util.DefaultHTTPClient is used when HTTPClient is nil.
+	HTTPClient  *http.Client
+	Endpoint    *url.URL
+	Bucket      string
+	Region      string
+	Credentials S3Credentials
+	// Prefix is the key prefix under which all documents are stored.
+	Prefix     string
+	NamePrefix string
+	// Algorithm is the algorithm of the checksums of stored documents. It
+	// defaults to SHA256.
+	Algorithm digest.Algorithm
+}
+
+const (
+	s3ChecksumHeader = "X-Amz-Meta-Rukpak-Checksum"
+	s3DigestHeader   = "X-Amz-Meta-Rukpak-Digest"
+)
+
+func (s *S3) Load(ctx context.Context, owner client.Object) ([]unstructured.Unstructured, error) {
+	key := s.key(owner)
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "objects"}, key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error(resp, key)
+	}
+
+	if err := verifyOwnerDigest(owner, resp.Header.Get(s3DigestHeader)); err != nil {
+		return nil, err
+	}
+	r, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("create gzip reader for %q: %w", key, err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read %q: %w", key, err)
+	}
+	// Documents stored before checksums were recorded are not verified.
+	if sum := resp.Header.Get(s3ChecksumHeader); sum != "" {
+		if err := digest.Verify(sum, data); err != nil {
+			return nil, &IntegrityError{Owner: owner.GetName(), Reason: err.Error()}
+		}
+	}
+	var objects []unstructured.Unstructured
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, fmt.Errorf("decode %q: %w", key, err)
+	}
+	return objects, nil
+}
+
+// LoadStream streams the objects of the document of owner as they are
+// decompressed and decoded.
+func (s *S3) LoadStream(ctx context.Context, owner client.Object) (io.ReadCloser, error) {
+	key := s.key(owner)
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "objects"}, key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s3Error(resp, key)
+	}
+	if err := verifyOwnerDigest(owner, resp.Header.Get(s3DigestHeader)); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return pipeDocuments(func(w io.Writer) error {
+		defer resp.Body.Close()
+		r, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("create gzip reader for %q: %w", key, err)
+		}
+		sum := resp.Header.Get(s3ChecksumHeader)
+		var h hash.Hash
+		var want string
+		var data io.Reader = r
+		// Documents stored before checksums were recorded are not verified.
+		if sum != "" {
+			alg, hexSum, err := digest.Parse(sum)
+			if err != nil {
+				return &IntegrityError{Owner: owner.GetName(), Reason: err.Error()}
+			}
+			h, want = alg.New(), hexSum
+			data = io.TeeReader(r, h)
+		}
+		if err := decodeJSONArray(data, func(obj json.RawMessage) error {
+			objData, err := yaml.JSONToYAML(obj)
+			if err != nil {
+				return err
+			}
+			return writeDocument(w, objData)
+		}); err != nil {
+			return fmt.Errorf("decode %q: %w", key, err)
+		}
+		// The decoder may not have read the end of the document.
+		if _, err := io.Copy(ioutil.Discard, data); err != nil {
+			return fmt.Errorf("read %q: %w", key, err)
+		}
+		if h != nil && hex.EncodeToString(h.Sum(nil)) != want {
+			return &IntegrityError{Owner: owner.GetName(), Reason: fmt.Sprintf("digest mismatch: expected %s", sum)}
+		}
+		return nil
+	}), nil
+}
+
+// decodeJSONArray calls fn with each element of the JSON array in r as it is
+// decoded. A null document has no elements.
+func decodeJSONArray(r io.Reader, fn func(elem json.RawMessage) error) error {
+	decoder := json.NewDecoder(r)
+	tok, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected a JSON array, found %v", tok)
+	}
+	for decoder.More() {
+		var elem json.RawMessage
+		if err := decoder.Decode(&elem); err != nil {
+			return err
+		}
+		if err := fn(elem); err != nil {
+			return err
+		}
+	}
+	_, err = decoder.Token()
+	return err
+}
+
+func (s *S3) Store(ctx context.Context, owner client.Object, objects []client.Object) error {
+	return s.store(ctx, owner, eachObject(objects))
+}
+
+func (s *S3) StoreStream(ctx context.Context, owner client.Object, r io.Reader) error {
+	return s.store(ctx, owner, decodeObjects(r))
+}
+
+// store encodes each of objects into the gzipped JSON array of the document of
+// owner as they are iterated, so that only its compressed data is held in
+// memory. The whole document is buffered before it is uploaded, since the
+// signature of the request covers its content.
+func (s *S3) store(ctx context.Context, owner client.Object, objects objectIterator) error {
+	alg := digest.OrDefault(s.Algorithm)
+	h := alg.New()
+	data := &bytes.Buffer{}
+	gzipper := gzip.NewWriter(data)
+	encoded := io.MultiWriter(gzipper, h)
+	i := 0
+	if _, err := io.WriteString(encoded, "["); err != nil {
+		return fmt.Errorf("gzip objects: %w", err)
+	}
+	if err := objects(func(obj client.Object) error {
+		objData, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("encode objects: %w", err)
+		}
+		if i > 0 {
+			objData = append([]byte(","), objData...)
+		}
+		i++
+		if _, err := encoded.Write(objData); err != nil {
+			return fmt.Errorf("gzip objects: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(encoded, "]"); err != nil {
+		return fmt.Errorf("gzip objects: %w", err)
+	}
+	if err := gzipper.Close(); err != nil {
+		return fmt.Errorf("close gzip writer: %w", err)
+	}
+	header := http.Header{}
+	header.Set(s3ChecksumHeader, fmt.Sprintf("%s:%x", alg.Name(), h.Sum(nil)))
+	if d := ownerDigest(owner); d != "" {
+		header.Set(s3DigestHeader, d)
+	}
+
+	key := s.key(owner)
+	resp, err := s.do(ctx, http.MethodPut, key, data.Bytes(), header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp, key)
+	}
+	return nil
+}
+
+// Exists returns whether the document of owner exists.
+func (s *S3) Exists(ctx context.Context, owner client.Object) (bool, error) {
+	key := s.key(owner)
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, s3Error(resp, key)
+	}
+}
+
+// Delete deletes the document of owner, which is not garbage collected when
+// owner is deleted.
+func (s *S3) Delete(ctx context.Context, owner client.Object) error {
+	key := s.key(owner)
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return s3Error(resp, key)
+	}
+}
+
+func (s *S3) key(owner client.Object) string {
+	return path.Join(s.Prefix, fmt.Sprintf("%s%s.json.gz", s.NamePrefix, owner.GetName()))
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body []byte, header http.Header) (*http.Response, error) {
+	u := *s.Endpoint
+	u.Path = path.Join("/", s.Endpoint.Path, s.Bucket, key)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/gzip")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	signS3Request(req, body, s.Region, s.Credentials, time.Now())
+
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = util.DefaultHTTPClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %w", method, key, err)
+	}
+	return resp, nil
+}
+
+func s3Error(resp *http.Response, key string) error {
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s %q: unexpected status %q: %s", resp.Request.Method, key, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// signS3Request signs req with AWS Signature Version 4.
+func signS3Request(req *http.Request, body []byte, region string, creds S3Credentials, now time.Time) {
+	const algorithm = "AWS4-HMAC-SHA256"
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if creds.SessionToken != "" {
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+	}
+	// The user metadata of a document is signed along with its content.
+	for h := range req.Header {
+		if h := strings.ToLower(h); strings.HasPrefix(h, "x-amz-meta-") {
+			signedHeaders = append(signedHeaders, h)
+		}
+	}
+	sort.Strings(signedHeaders)
+	canonicalHeaders := &strings.Builder{}
+	for _, h := range signedHeaders {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		fmt.Fprintf(canonicalHeaders, "%s:%s\n", h, strings.TrimSpace(v))
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{algorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, v := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, creds.AccessKeyID, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+// uriEncode encodes the path s as specified by AWS Signature Version 4, which
+// escapes every byte other than the unreserved characters and slashes.
+func uriEncode(s string) string {
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+// fakeObjectStore is a minimal in-memory S3-compatible object store, which
+// keeps the user metadata of its objects.
+type fakeObjectStore struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	metadata map[string]http.Header
+}
+
+func (f *fakeObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		http.Error(w, "missing signature", http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		f.objects[r.URL.Path] = data
+		metadata := http.Header{}
+		for k, v := range r.Header {
+			if strings.HasPrefix(k, "X-Amz-Meta-") {
+				metadata[k] = v
+			}
+		}
+		if f.metadata == nil {
+			f.metadata = map[string]http.Header{}
+		}
+		f.metadata[r.URL.Path] = metadata
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		for k, v := range f.metadata[r.URL.Path] {
+			w.Header()[k] = v
+		}
+		_, _ = w.Write(data)
+	case http.MethodHead:
+		if _, ok := f.objects[r.URL.Path]; !ok {
+			http.NotFound(w, r)
+		}
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		delete(f.metadata, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3StoreAndLoad(t *testing.T) {
+	objectStore := &fakeObjectStore{objects: map[string][]byte{}}
+	server := httptest.NewServer(objectStore)
+	defer server.Close()
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	s := &S3{
+		HTTPClient:  server.Client(),
+		Endpoint:    endpoint,
+		Bucket:      "bucket",
+		Region:      "us-east-1",
+		Credentials: S3Credentials{AccessKeyID: "access", SecretAccessKey: "secret"},
+		Prefix:      "rukpak",
+		NamePrefix:  "bundle-",
+	}
+	owner := &unstructured.Unstructured{}
+	owner.SetName("test-owner")
+
+	_, err = s.Load(context.Background(), owner)
+	require.True(t, apierrors.IsNotFound(err), "expected not found error, got %v", err)
+
+	deployment := &unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+	deployment.SetName("test-owned")
+	deployment.SetLabels(map[string]string{"app": "test"})
+	require.NoError(t, s.Store(context.Background(), owner, []client.Object{deployment}))
+	require.Contains(t, objectStore.objects, "/bucket/rukpak/bundle-test-owner.json.gz")
+
+	actual, err := s.Load(context.Background(), owner)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, "Deployment", actual[0].GetKind())
+	require.Equal(t, "test-owned", actual[0].GetName())
+	require.Equal(t, map[string]string{"app": "test"}, actual[0].GetLabels())
+}
+
+func TestS3Integrity(t *testing.T) {
+	objectStore := &fakeObjectStore{objects: map[string][]byte{}}
+	server := httptest.NewServer(objectStore)
+	defer server.Close()
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	s := &S3{
+		HTTPClient:  server.Client(),
+		Endpoint:    endpoint,
+		Bucket:      "bucket",
+		Credentials: S3Credentials{AccessKeyID: "access", SecretAccessKey: "secret"},
+	}
+	owner := &rukpakv1alpha1.Bundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-owner"},
+		Status:     rukpakv1alpha1.BundleStatus{Digest: "sha256:1234"},
+	}
+	require.NoError(t, s.Store(context.Background(), owner, []client.Object{newTestObject("ConfigMap", "a")}))
+	_, err = s.Load(context.Background(), owner)
+	require.NoError(t, err)
+
+	// Content stored for another digest of the bundle is rejected.
+	unpacked := owner.DeepCopy()
+	unpacked.Status.Digest = "sha256:5678"
+	var integrityErr *IntegrityError
+	_, err = s.Load(context.Background(), unpacked)
+	require.ErrorAs(t, err, &integrityErr)
+
+	// A document that was replaced without its checksum is rejected.
+	tampered := &bytes.Buffer{}
+	gzipper := gzip.NewWriter(tampered)
+	_, err = gzipper.Write([]byte(`[{"apiVersion":"v1","kind":"Secret","metadata":{"name":"a"}}]`))
+	require.NoError(t, err)
+	require.NoError(t, gzipper.Close())
+	objectStore.objects["/bucket/test-owner.json.gz"] = tampered.Bytes()
+	_, err = s.Load(context.Background(), owner)
+	require.ErrorAs(t, err, &integrityErr)
+	require.Equal(t, "test-owner", integrityErr.Owner)
+}
+
+func TestS3Stream(t *testing.T) {
+	ctx := context.Background()
+	objectStore := &fakeObjectStore{objects: map[string][]byte{}}
+	server := httptest.NewServer(objectStore)
+	defer server.Close()
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	s := &S3{
+		HTTPClient:  server.Client(),
+		Endpoint:    endpoint,
+		Bucket:      "bucket",
+		Credentials: S3Credentials{AccessKeyID: "access", SecretAccessKey: "secret"},
+	}
+	owner := &rukpakv1alpha1.Bundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-owner"},
+		Status:     rukpakv1alpha1.BundleStatus{Digest: "sha256:1234"},
+	}
+
+	_, err = s.LoadStream(ctx, owner)
+	require.True(t, apierrors.IsNotFound(err), "expected not found error, got %v", err)
+	exists, err := s.Exists(ctx, owner)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	require.NoError(t, s.StoreStream(ctx, owner, strings.NewReader(testManifests)))
+	exists, err = s.Exists(ctx, owner)
+	require.NoError(t, err)
+	require.True(t, exists)
+	actual, err := s.Load(ctx, owner)
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	streamed, err := loadStream(t, s, owner)
+	require.NoError(t, err)
+	require.Equal(t, actual, streamed)
+
+	// A document that was replaced without its checksum fails the stream at
+	// its end.
+	tampered := &bytes.Buffer{}
+	gzipper := gzip.NewWriter(tampered)
+	_, err = gzipper.Write([]byte(`[{"apiVersion":"v1","kind":"Secret","metadata":{"name":"a"}}]`))
+	require.NoError(t, err)
+	require.NoError(t, gzipper.Close())
+	objectStore.objects["/bucket/test-owner.json.gz"] = tampered.Bytes()
+	var integrityErr *IntegrityError
+	_, err = loadStream(t, s, owner)
+	require.ErrorAs(t, err, &integrityErr)
+	require.Equal(t, "test-owner", integrityErr.Owner)
+
+	require.NoError(t, s.Delete(ctx, owner))
+	require.Empty(t, objectStore.objects)
+	exists, err = s.Exists(ctx, owner)
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func TestS3Unauthorized(t *testing.T) {
+	server := httptest.NewServer(&fakeObjectStore{objects: map[string][]byte{}})
+	defer server.Close()
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	s := &S3{
+		HTTPClient:  server.Client(),
+		Endpoint:    endpoint,
+		Bucket:      "bucket",
+		Credentials: S3Credentials{AccessKeyID: "other", SecretAccessKey: "secret"},
+	}
+	owner := &unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"name": "test-owner"}}}
+	err = s.Store(context.Background(), owner, nil)
+	require.Error(t, err)
+	require.False(t, apierrors.IsNotFound(err))
+}
+
+func TestURIEncode(t *testing.T) {
+	require.Equal(t, "/bucket/a%20b/c~d_e-f.g%2Bh", uriEncode("/bucket/a b/c~d_e-f.g+h"))
+}
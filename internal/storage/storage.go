@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"sort"
+	"strconv"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
@@ -18,12 +22,88 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/yaml"
 
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/digest"
 	"github.com/operator-framework/rukpak/internal/util"
 )
 
+const (
+	// maxChunkSize is the maximum size of the compressed object data stored in
+	// a single ConfigMap. Binary data is base64-encoded when serialized, so
+	// this leaves room for the encoding and the metadata of the ConfigMap
+	// within the 1MiB size limit of a ConfigMap.
+	maxChunkSize = 512 * 1024
+
+	configMapTypeLabel = "core.rukpak.io/configmap-type"
+
+	objectChunkAnnotation  = "core.rukpak.io/object-chunk"
+	objectChunksAnnotation = "core.rukpak.io/object-chunks"
+)
+
+// Storage stores the objects of owners. Every backend records a checksum of
+// the objects of an owner, and the digest of the Bundle that they were
+// unpacked from, when it stores them. Load returns an *IntegrityError when the
+// stored objects no longer match their checksum, or were stored for another
+// digest than the current status.digest of their Bundle.
+//
+// StoreStream and LoadStream store and load the objects of an owner as a
+// stream of YAML or JSON documents, which the backends process one object at
+// a time rather than buffering all of them in memory. Since the checksum of
+// streamed objects can only be verified once all of them have been read,
+// reading a stream whose objects fail their integrity check fails with an
+// *IntegrityError at its end, and callers must not act on its objects before
+// they have read it to io.EOF. Delete removes the objects of an owner, and
+// succeeds when none are stored.
 type Storage interface {
 	Load(ctx context.Context, owner client.Object) ([]unstructured.Unstructured, error)
 	Store(ctx context.Context, owner client.Object, objects []client.Object) error
+	LoadStream(ctx context.Context, owner client.Object) (io.ReadCloser, error)
+	StoreStream(ctx context.Context, owner client.Object, r io.Reader) error
+	Delete(ctx context.Context, owner client.Object) error
+	Exists(ctx context.Context, owner client.Object) (bool, error)
+}
+
+// IntegrityError is returned by Load, and by the streams of LoadStream, when
+// the stored objects of an owner were tampered with or corrupted.
+type IntegrityError struct {
+	Owner  string
+	Reason string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("stored content of %q failed its integrity check: %s", e.Owner, e.Reason)
+}
+
+// ownerDigest returns the status.digest of owner when it is a Bundle, which
+// identifies the content that its objects were unpacked from. It returns an
+// empty digest for other owners.
+func ownerDigest(owner client.Object) string {
+	if b, ok := owner.(*rukpakv1alpha1.Bundle); ok {
+		return b.Status.Digest
+	}
+	return ""
+}
+
+// verifyOwnerDigest returns an *IntegrityError when stored, the digest of
+// owner when its objects were stored, is not its current digest. Objects that
+// were stored without a digest, or are loaded for an owner without one, are
+// not verified.
+func verifyOwnerDigest(owner client.Object, stored string) error {
+	current := ownerDigest(owner)
+	if stored == "" || current == "" || stored == current {
+		return nil
+	}
+	return &IntegrityError{Owner: owner.GetName(), Reason: fmt.Sprintf("the content was stored for digest %s rather than %s", stored, current)}
+}
+
+// checksum returns the checksum of the objects whose data has the digests
+// sums, in the form "<algorithm>:<hex>".
+func checksum(alg digest.Algorithm, sums []string) string {
+	h := alg.New()
+	for _, sum := range sums {
+		fmt.Fprintf(h, "%s\n", sum)
+	}
+	return fmt.Sprintf("%s:%x", alg.Name(), h.Sum(nil))
 }
 
 var _ Storage = &ConfigMaps{}
@@ -32,6 +112,10 @@ type ConfigMaps struct {
 	Client     client.Client
 	Namespace  string
 	NamePrefix string
+	// Algorithm is the algorithm of the digests that name and verify stored
+	// objects. It defaults to SHA256. Objects stored with any supported
+	// algorithm can be loaded.
+	Algorithm digest.Algorithm
 }
 
 func (s *ConfigMaps) Load(ctx context.Context, owner client.Object) ([]unstructured.Unstructured, error) {
@@ -39,20 +123,38 @@ func (s *ConfigMaps) Load(ctx context.Context, owner client.Object) ([]unstructu
 	if err != nil {
 		return nil, err
 	}
-	configMaps := []corev1.ConfigMap{}
+	if err := verifyOwnerDigest(owner, metadata.Digest); err != nil {
+		return nil, err
+	}
+
+	// Objects that do not fit in a single ConfigMap are split into chunks
+	// across multiple ConfigMaps, which are grouped by the hash of the object.
+	var hashes []string
+	chunks := map[string][]corev1.ConfigMap{}
 	for _, name := range metadata.Objects {
 		key := types.NamespacedName{Namespace: s.Namespace, Name: name}
 		cm := corev1.ConfigMap{}
 		if err := s.Client.Get(ctx, key, &cm); err != nil {
 			return nil, err
 		}
-		configMaps = append(configMaps, cm)
+		hash := objectDigest(cm)
+		if _, ok := chunks[hash]; !ok {
+			hashes = append(hashes, hash)
+		}
+		chunks[hash] = append(chunks[hash], cm)
+	}
+	if err := verifyChecksum(owner, metadata.Checksum, hashes); err != nil {
+		return nil, err
 	}
 
 	objects := []unstructured.Unstructured{}
-	for _, cm := range configMaps {
+	for _, hash := range hashes {
 		u := unstructured.Unstructured{}
-		if err := convertConfigMapToObject(cm, &u); err != nil {
+		if err := convertConfigMapsToObject(chunks[hash], hash, &u); err != nil {
+			var integrityErr *IntegrityError
+			if errors.As(err, &integrityErr) {
+				integrityErr.Owner = owner.GetName()
+			}
 			return nil, err
 		}
 		objects = append(objects, u)
@@ -60,12 +162,122 @@ func (s *ConfigMaps) Load(ctx context.Context, owner client.Object) ([]unstructu
 	return objects, nil
 }
 
+// LoadStream streams the objects of owner as they are read from their
+// ConfigMaps, so that only the chunks of a single object are held in memory.
+// The chunks of an object are listed next to each other in the metadata
+// ConfigMap, since their names only differ by their suffix.
+func (s *ConfigMaps) LoadStream(ctx context.Context, owner client.Object) (io.ReadCloser, error) {
+	metadata, err := s.getMetadata(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyOwnerDigest(owner, metadata.Digest); err != nil {
+		return nil, err
+	}
+	return pipeDocuments(func(w io.Writer) error {
+		var hashes []string
+		seen := map[string]bool{}
+		var chunks []corev1.ConfigMap
+		writeObject := func() error {
+			if len(chunks) == 0 {
+				return nil
+			}
+			objData, err := readObjectData(chunks, objectDigest(chunks[0]))
+			if err != nil {
+				var integrityErr *IntegrityError
+				if errors.As(err, &integrityErr) {
+					integrityErr.Owner = owner.GetName()
+				}
+				return err
+			}
+			chunks = nil
+			return writeDocument(w, objData)
+		}
+		for _, name := range metadata.Objects {
+			key := types.NamespacedName{Namespace: s.Namespace, Name: name}
+			cm := corev1.ConfigMap{}
+			if err := s.Client.Get(ctx, key, &cm); err != nil {
+				return err
+			}
+			hash := objectDigest(cm)
+			if len(chunks) > 0 && objectDigest(chunks[0]) != hash {
+				if err := writeObject(); err != nil {
+					return err
+				}
+			}
+			if !seen[hash] {
+				seen[hash] = true
+				hashes = append(hashes, hash)
+			}
+			chunks = append(chunks, cm)
+		}
+		if err := writeObject(); err != nil {
+			return err
+		}
+		return verifyChecksum(owner, metadata.Checksum, hashes)
+	}), nil
+}
+
+// verifyChecksum returns an *IntegrityError when the objects of owner whose
+// data has the digests hashes do not match sum, the checksum in their metadata
+// ConfigMap. Content stored before checksums were recorded is only verified by
+// the digests of its objects.
+func verifyChecksum(owner client.Object, sum string, hashes []string) error {
+	if sum == "" {
+		return nil
+	}
+	alg, _, err := digest.Parse(sum)
+	if err != nil {
+		return &IntegrityError{Owner: owner.GetName(), Reason: err.Error()}
+	}
+	sums := append([]string(nil), hashes...)
+	sort.Strings(sums)
+	if checksum(alg, sums) != sum {
+		return &IntegrityError{Owner: owner.GetName(), Reason: "the stored objects do not match their checksum"}
+	}
+	return nil
+}
+
+// Exists returns whether the metadata ConfigMap of owner exists.
+func (s *ConfigMaps) Exists(ctx context.Context, owner client.Object) (bool, error) {
+	key := types.NamespacedName{Namespace: s.Namespace, Name: s.metadataName(owner)}
+	if err := s.Client.Get(ctx, key, &corev1.ConfigMap{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete deletes the ConfigMaps that store the objects of owner, which are
+// otherwise only garbage collected once owner is deleted. The metadata
+// ConfigMap is deleted first, so that a concurrent Load finds no objects
+// rather than some of them.
+func (s *ConfigMaps) Delete(ctx context.Context, owner client.Object) error {
+	cms, err := s.getExistingConfigMaps(ctx, owner)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(cms, func(i, j int) bool {
+		return cms[i].Labels[configMapTypeLabel] == "metadata" && cms[j].Labels[configMapTypeLabel] != "metadata"
+	})
+	for i := range cms {
+		if err := s.Client.Delete(ctx, &cms[i]); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 type metadata struct {
-	Objects []string `json:"objects"`
+	Objects  []string `json:"objects"`
+	Checksum string   `json:"checksum,omitempty"`
+	Digest   string   `json:"digest,omitempty"`
 }
 
 func (s *ConfigMaps) getMetadata(ctx context.Context, owner client.Object) (*metadata, error) {
-	key := types.NamespacedName{Namespace: s.Namespace, Name: fmt.Sprintf("%smetadata-%s", s.NamePrefix, owner.GetName())}
+	key := types.NamespacedName{Namespace: s.Namespace, Name: s.metadataName(owner)}
 	cm := corev1.ConfigMap{}
 	if err := s.Client.Get(ctx, key, &cm); err != nil {
 		return nil, err
@@ -74,41 +286,135 @@ func (s *ConfigMaps) getMetadata(ctx context.Context, owner client.Object) (*met
 	if err := json.Unmarshal([]byte(cm.Data["objects"]), &m.Objects); err != nil {
 		return nil, err
 	}
+	m.Checksum, m.Digest = cm.Data["checksum"], cm.Data["digest"]
 	return &m, nil
 }
 
-func convertConfigMapToObject(cm corev1.ConfigMap, obj client.Object) error {
-	r, err := gzip.NewReader(bytes.NewReader(cm.BinaryData["object"]))
+// metadataName returns the name of the metadata ConfigMap of owner.
+func (s *ConfigMaps) metadataName(owner client.Object) string {
+	return fmt.Sprintf("%smetadata-%s", s.NamePrefix, owner.GetName())
+}
+
+// objectDigest returns the digest of the object data of cm, which is stored
+// under the key "object-<algorithm>".
+func objectDigest(cm corev1.ConfigMap) string {
+	for key, sum := range cm.Data {
+		if strings.HasPrefix(key, "object-") {
+			return strings.TrimPrefix(key, "object-") + ":" + sum
+		}
+	}
+	return ""
+}
+
+// convertConfigMapsToObject unmarshals the object data stored in cms into obj.
+func convertConfigMapsToObject(cms []corev1.ConfigMap, hash string, obj client.Object) error {
+	objData, err := readObjectData(cms, hash)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(objData, obj)
+}
+
+// readObjectData reassembles the compressed object data from its chunks in
+// cms, and verifies that it matches the expected hash.
+func readObjectData(cms []corev1.ConfigMap, hash string) ([]byte, error) {
+	sort.Slice(cms, func(i, j int) bool { return chunkIndex(cms[i]) < chunkIndex(cms[j]) })
+	compressed := &bytes.Buffer{}
+	for i, cm := range cms {
+		if chunkIndex(cm) != i || chunkCount(cm) != len(cms) {
+			return nil, fmt.Errorf("bundle object %s: expected %d chunks, found chunk %d of %d", hash, len(cms), chunkIndex(cm)+1, chunkCount(cm))
+		}
+		compressed.Write(cm.BinaryData["object"])
+	}
+
+	r, err := gzip.NewReader(compressed)
 	if err != nil {
-		return fmt.Errorf("create gzip reader for bundle object data: %w", err)
+		return nil, fmt.Errorf("create gzip reader for bundle object data: %w", err)
 	}
 	objData, err := ioutil.ReadAll(r)
 	if err != nil {
-		return fmt.Errorf("read gzip data for bundle object: %w", err)
+		return nil, fmt.Errorf("read gzip data for bundle object: %w", err)
 	}
-	return yaml.Unmarshal(objData, obj)
+	if hash != "" && digest.Verify(hash, objData) != nil {
+		return nil, &IntegrityError{Reason: fmt.Sprintf("bundle object %s does not match its digest", hash)}
+	}
+	return objData, nil
+}
+
+func chunkIndex(cm corev1.ConfigMap) int {
+	i, err := strconv.Atoi(cm.Annotations[objectChunkAnnotation])
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+func chunkCount(cm corev1.ConfigMap) int {
+	n, err := strconv.Atoi(cm.Annotations[objectChunksAnnotation])
+	if err != nil {
+		return 1
+	}
+	return n
 }
 
 func (s *ConfigMaps) Store(ctx context.Context, owner client.Object, objects []client.Object) error {
+	return s.store(ctx, owner, eachObject(objects))
+}
+
+func (s *ConfigMaps) StoreStream(ctx context.Context, owner client.Object, r io.Reader) error {
+	return s.store(ctx, owner, decodeObjects(r))
+}
+
+// store creates the ConfigMaps of each of objects as they are iterated, and
+// only keeps their names and digests for the metadata ConfigMap, which is
+// created last. The ConfigMaps of previously stored objects that are not
+// stored anymore are deleted once it has been created, so that the previous
+// objects can still be loaded when objects fail to be stored.
+func (s *ConfigMaps) store(ctx context.Context, owner client.Object, objects objectIterator) error {
 	actualConfigMaps, err := s.getExistingConfigMaps(ctx, owner)
 	if err != nil {
 		return err
 	}
+	acmMap := map[types.NamespacedName]corev1.ConfigMap{}
+	for _, acm := range actualConfigMaps {
+		acmMap[types.NamespacedName{Namespace: acm.Namespace, Name: acm.Name}] = acm
+	}
 
-	desiredConfigMaps := []corev1.ConfigMap{}
-	for _, obj := range objects {
-		cm, err := s.buildObject(obj, owner)
+	var cmNames, sums []string
+	seen := map[string]bool{}
+	if err := objects(func(obj client.Object) error {
+		cms, err := s.buildObject(obj, owner)
 		if err != nil {
 			return err
 		}
-		desiredConfigMaps = append(desiredConfigMaps, *cm)
+		for _, cm := range cms {
+			cmNames = append(cmNames, cm.Name)
+			if hash := objectDigest(cm); !seen[hash] {
+				seen[hash] = true
+				sums = append(sums, hash)
+			}
+			if err := s.createOrUpdateConfigMap(ctx, acmMap, cm); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
-	metadataCm, err := s.buildMetadata(desiredConfigMaps, owner)
+	metadataCm, err := s.buildMetadata(cmNames, sums, owner)
 	if err != nil {
 		return err
 	}
-	desiredConfigMaps = append(desiredConfigMaps, *metadataCm)
-	return s.createOrUpdateConfigMaps(ctx, actualConfigMaps, desiredConfigMaps)
+	if err := s.createOrUpdateConfigMap(ctx, acmMap, *metadataCm); err != nil {
+		return err
+	}
+	for _, acm := range acmMap {
+		acm := acm
+		if err := s.Client.Delete(ctx, &acm); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *ConfigMaps) getExistingConfigMaps(ctx context.Context, owner client.Object) ([]corev1.ConfigMap, error) {
@@ -123,12 +429,16 @@ func (s *ConfigMaps) getExistingConfigMaps(ctx context.Context, owner client.Obj
 	return cmList.Items, nil
 }
 
-func (s *ConfigMaps) buildObject(obj client.Object, owner client.Object) (*corev1.ConfigMap, error) {
+// buildObject builds the ConfigMaps that store the gzipped data of obj. Data
+// that exceeds maxChunkSize is split into chunks that are stored in separate
+// ConfigMaps, so that the size limit of a single ConfigMap is not exceeded.
+func (s *ConfigMaps) buildObject(obj client.Object, owner client.Object) ([]corev1.ConfigMap, error) {
 	objData, err := yaml.Marshal(obj)
 	if err != nil {
 		return nil, err
 	}
-	hash := fmt.Sprintf("%x", sha256.Sum256(objData))
+	alg := digest.OrDefault(s.Algorithm)
+	hash := digest.Sum(alg, objData)
 	objCompressed := &bytes.Buffer{}
 	gzipper := gzip.NewWriter(objCompressed)
 	if _, err := gzipper.Write(objData); err != nil {
@@ -139,47 +449,69 @@ func (s *ConfigMaps) buildObject(obj client.Object, owner client.Object) (*corev
 	}
 	gvk := obj.GetObjectKind().GroupVersionKind()
 
-	labels := map[string]string{
-		"core.rukpak.io/owner-kind":     owner.GetObjectKind().GroupVersionKind().Kind,
-		"core.rukpak.io/owner-name":     owner.GetName(),
-		"core.rukpak.io/configmap-type": "object",
-	}
-	annotations := map[string]string{
-		"core.rukpak.io/object-group":     gvk.Group,
-		"core.rukpak.io/object-version":   gvk.Version,
-		"core.rukpak.io/object-kind":      gvk.Kind,
-		"core.rukpak.io/object-name":      obj.GetName(),
-		"core.rukpak.io/object-namespace": obj.GetNamespace(),
+	var chunks [][]byte
+	for data := objCompressed.Bytes(); len(data) > 0; {
+		n := len(data)
+		if n > maxChunkSize {
+			n = maxChunkSize
+		}
+		chunks = append(chunks, data[:n])
+		data = data[n:]
 	}
 
-	immutable := true
-	cm := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:        fmt.Sprintf("%sobject-%s-%s", s.NamePrefix, owner.GetName(), hash[0:8]),
-			Namespace:   s.Namespace,
-			Labels:      labels,
-			Annotations: annotations,
-		},
-		Immutable: &immutable,
-		Data: map[string]string{
-			"object-sha256": hash,
-		},
-		BinaryData: map[string][]byte{
-			"object": objCompressed.Bytes(),
-		},
-	}
-	if err := controllerutil.SetControllerReference(owner, cm, s.Client.Scheme()); err != nil {
-		return nil, err
+	cms := make([]corev1.ConfigMap, 0, len(chunks))
+	for i, chunk := range chunks {
+		labels := map[string]string{
+			"core.rukpak.io/owner-kind": owner.GetObjectKind().GroupVersionKind().Kind,
+			"core.rukpak.io/owner-name": owner.GetName(),
+			configMapTypeLabel:          "object",
+		}
+		annotations := map[string]string{
+			"core.rukpak.io/object-group":     gvk.Group,
+			"core.rukpak.io/object-version":   gvk.Version,
+			"core.rukpak.io/object-kind":      gvk.Kind,
+			"core.rukpak.io/object-name":      obj.GetName(),
+			"core.rukpak.io/object-namespace": obj.GetNamespace(),
+		}
+		name := fmt.Sprintf("%sobject-%s-%s", s.NamePrefix, owner.GetName(), hash[0:8])
+		if len(chunks) > 1 {
+			name = fmt.Sprintf("%s-%d", name, i)
+			annotations[objectChunkAnnotation] = strconv.Itoa(i)
+			annotations[objectChunksAnnotation] = strconv.Itoa(len(chunks))
+		}
+
+		immutable := true
+		cm := corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   s.Namespace,
+				Labels:      labels,
+				Annotations: annotations,
+			},
+			Immutable: &immutable,
+			Data: map[string]string{
+				"object-" + alg.Name(): hash,
+			},
+			BinaryData: map[string][]byte{
+				"object": chunk,
+			},
+		}
+		if err := controllerutil.SetControllerReference(owner, &cm, s.Client.Scheme()); err != nil {
+			return nil, err
+		}
+		cms = append(cms, cm)
 	}
-	return cm, nil
+	return cms, nil
 }
 
-func (s *ConfigMaps) buildMetadata(dcms []corev1.ConfigMap, owner client.Object) (*corev1.ConfigMap, error) {
-	cmNames := []string{}
-	for _, dcm := range dcms {
-		cmNames = append(cmNames, dcm.Name)
-	}
+// buildMetadata builds the ConfigMap that lists the ConfigMaps named cmNames,
+// which store the objects of owner, with the checksum of the objects whose
+// data has the digests sums and the digest of owner.
+func (s *ConfigMaps) buildMetadata(cmNames, sums []string, owner client.Object) (*corev1.ConfigMap, error) {
+	cmNames = append([]string{}, cmNames...)
+	sums = append([]string(nil), sums...)
 	sort.Strings(cmNames)
+	sort.Strings(sums)
 	objectJSON, err := json.Marshal(cmNames)
 	if err != nil {
 		return nil, err
@@ -189,55 +521,47 @@ func (s *ConfigMaps) buildMetadata(dcms []corev1.ConfigMap, owner client.Object)
 	cm := corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: s.Namespace,
-			Name:      fmt.Sprintf("%smetadata-%s", s.NamePrefix, owner.GetName()),
+			Name:      s.metadataName(owner),
 			Labels: map[string]string{
-				"core.rukpak.io/owner-kind":     owner.GetObjectKind().GroupVersionKind().Kind,
-				"core.rukpak.io/owner-name":     owner.GetName(),
-				"core.rukpak.io/configmap-type": "metadata",
+				"core.rukpak.io/owner-kind": owner.GetObjectKind().GroupVersionKind().Kind,
+				"core.rukpak.io/owner-name": owner.GetName(),
+				configMapTypeLabel:          "metadata",
 			},
 		},
 		Immutable: &immutable,
 		Data: map[string]string{
-			"objects": string(objectJSON),
+			"objects":  string(objectJSON),
+			"checksum": checksum(digest.OrDefault(s.Algorithm), sums),
 		},
 	}
+	if d := ownerDigest(owner); d != "" {
+		cm.Data["digest"] = d
+	}
 	if err := controllerutil.SetControllerReference(owner, &cm, s.Client.Scheme()); err != nil {
 		return nil, err
 	}
 	return &cm, nil
 }
 
-func (s *ConfigMaps) createOrUpdateConfigMaps(ctx context.Context, acms, dcms []corev1.ConfigMap) error {
-	acmMap := map[types.NamespacedName]corev1.ConfigMap{}
-	for _, acm := range acms {
-		acmMap[types.NamespacedName{Namespace: acm.Namespace, Name: acm.Name}] = acm
-	}
-	for _, dcm := range dcms {
-		dcm := dcm
-		key := types.NamespacedName{Namespace: dcm.Namespace, Name: dcm.Name}
-		acm, ok := acmMap[key]
-		if ok {
-			if util.ConfigMapsEqual(acm, dcm) {
-				delete(acmMap, key)
-				continue
-			}
-		}
-		if err := s.Client.Get(ctx, client.ObjectKeyFromObject(&dcm), &acm); err == nil {
-			if err := s.Client.Delete(ctx, &acm); err != nil {
-				return err
-			}
-		}
-		if err := s.Client.Create(ctx, &dcm); err != nil {
-			return err
+// createOrUpdateConfigMap creates dcm, or replaces the ConfigMap of the same
+// name unless it is equal to dcm, since stored ConfigMaps are immutable. The
+// ConfigMap is removed from acmMap, the existing ConfigMaps that have not
+// been stored again.
+func (s *ConfigMaps) createOrUpdateConfigMap(ctx context.Context, acmMap map[types.NamespacedName]corev1.ConfigMap, dcm corev1.ConfigMap) error {
+	key := types.NamespacedName{Namespace: dcm.Namespace, Name: dcm.Name}
+	acm, ok := acmMap[key]
+	if ok {
+		delete(acmMap, key)
+		if util.ConfigMapsEqual(acm, dcm) {
+			return nil
 		}
 	}
-	for _, acm := range acmMap {
-		acm := acm
+	if err := s.Client.Get(ctx, client.ObjectKeyFromObject(&dcm), &acm); err == nil {
 		if err := s.Client.Delete(ctx, &acm); err != nil {
 			return err
 		}
 	}
-	return nil
+	return s.Client.Create(ctx, &dcm)
 }
 
 //func (s *ConfigMaps) Get(ctx context.Context, key types.NamespacedName, obj client.Object) error {
@@ -2,17 +2,24 @@ package storage
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/digest"
 	"github.com/operator-framework/rukpak/internal/unit"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	kscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestStoreAndLoad(t *testing.T) {
@@ -78,3 +85,207 @@ func TestStoreAndLoad(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreAndLoadChunked(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, kscheme.AddToScheme(scheme))
+	kubeclient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	cms := ConfigMaps{Client: kubeclient, Namespace: "default"}
+
+	owner := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-owner", Namespace: "default", UID: "test-uid"},
+	}
+	// Random data does not compress, so it is chunked across ConfigMaps.
+	data := make([]byte, maxChunkSize)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	large := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-large"},
+		BinaryData: map[string][]byte{"data": data},
+	}
+	small := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-small"},
+	}
+	require.NoError(t, cms.Store(context.Background(), owner, []client.Object{large, small}))
+
+	stored := &corev1.ConfigMapList{}
+	require.NoError(t, kubeclient.List(context.Background(), stored, client.MatchingLabels{"core.rukpak.io/configmap-type": "object"}))
+	require.Greater(t, len(stored.Items), 2, "expected the large object to be chunked")
+	for _, cm := range stored.Items {
+		require.LessOrEqual(t, len(cm.BinaryData["object"]), maxChunkSize)
+	}
+
+	actual, err := cms.Load(context.Background(), owner)
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	for _, u := range actual {
+		if u.GetName() != "test-large" {
+			continue
+		}
+		loaded := &corev1.ConfigMap{}
+		require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, loaded))
+		require.Equal(t, data, loaded.BinaryData["data"])
+	}
+
+	// Tampering with a chunk fails the integrity check.
+	chunk := stored.Items[0]
+	if chunk.Annotations[objectChunksAnnotation] == "" {
+		chunk = stored.Items[1]
+	}
+	chunk.BinaryData["object"] = chunk.BinaryData["object"][1:]
+	require.NoError(t, kubeclient.Update(context.Background(), &chunk))
+	_, err = cms.Load(context.Background(), owner)
+	require.Error(t, err)
+}
+
+func TestStoreAndLoadAlgorithm(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, kscheme.AddToScheme(scheme))
+	kubeclient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	owner := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-owner", Namespace: "default", UID: "test-uid"},
+	}
+	owned := []client.Object{&appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-owned"},
+	}}
+	require.NoError(t, (&ConfigMaps{Client: kubeclient, Namespace: "default", Algorithm: digest.SHA512}).Store(context.Background(), owner, owned))
+
+	stored := &corev1.ConfigMapList{}
+	require.NoError(t, kubeclient.List(context.Background(), stored, client.MatchingLabels{"core.rukpak.io/configmap-type": "object"}))
+	require.Len(t, stored.Items, 1)
+	require.Len(t, stored.Items[0].Data["object-sha512"], 128)
+
+	// Objects are loaded regardless of the configured algorithm.
+	actual, err := (&ConfigMaps{Client: kubeclient, Namespace: "default"}).Load(context.Background(), owner)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, "test-owned", actual[0].GetName())
+}
+
+func TestStoreAndLoadIntegrity(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, kscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha1.AddToScheme(scheme))
+	kubeclient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	cms := ConfigMaps{Client: kubeclient, Namespace: "default"}
+
+	owner := &rukpakv1alpha1.Bundle{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha1.GroupVersion.String(), Kind: "Bundle"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-owner", UID: "test-uid"},
+		Status:     rukpakv1alpha1.BundleStatus{Digest: "sha256:1234"},
+	}
+	owned := []client.Object{newTestObject("ConfigMap", "a"), newTestObject("ConfigMap", "b")}
+	require.NoError(t, cms.Store(context.Background(), owner, owned))
+	actual, err := cms.Load(context.Background(), owner)
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+
+	// Content stored for another digest of the bundle is rejected.
+	unpacked := owner.DeepCopy()
+	unpacked.Status.Digest = "sha256:5678"
+	var integrityErr *IntegrityError
+	_, err = cms.Load(context.Background(), unpacked)
+	require.ErrorAs(t, err, &integrityErr)
+	require.Equal(t, "test-owner", integrityErr.Owner)
+
+	// Removing an object from the metadata fails its checksum.
+	metadataCM := &corev1.ConfigMap{}
+	require.NoError(t, kubeclient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "metadata-test-owner"}, metadataCM))
+	var names []string
+	require.NoError(t, json.Unmarshal([]byte(metadataCM.Data["objects"]), &names))
+	objectsJSON, err := json.Marshal(names[1:])
+	require.NoError(t, err)
+	metadataCM.Data["objects"] = string(objectsJSON)
+	require.NoError(t, kubeclient.Update(context.Background(), metadataCM))
+	_, err = cms.Load(context.Background(), owner)
+	require.ErrorAs(t, err, &integrityErr)
+}
+
+// loadStream decodes the objects that s streams for owner.
+func loadStream(t *testing.T, s Storage, owner client.Object) ([]unstructured.Unstructured, error) {
+	t.Helper()
+	rc, err := s.LoadStream(context.Background(), owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var objects []unstructured.Unstructured
+	err = decodeObjects(rc)(func(obj client.Object) error {
+		objects = append(objects, *obj.(*unstructured.Unstructured))
+		return nil
+	})
+	return objects, err
+}
+
+// testManifests is a stream of manifests with an empty document and a JSON
+// document.
+const testManifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+---
+{"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "b"}}
+`
+
+func TestStoreStreamAndLoadStream(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	require.NoError(t, kscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha1.AddToScheme(scheme))
+	kubeclient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	cms := &ConfigMaps{Client: kubeclient, Namespace: "default"}
+	owner := &rukpakv1alpha1.Bundle{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha1.GroupVersion.String(), Kind: "Bundle"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-owner", UID: "test-uid"},
+		Status:     rukpakv1alpha1.BundleStatus{Digest: "sha256:1234"},
+	}
+
+	exists, err := cms.Exists(ctx, owner)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	require.NoError(t, cms.StoreStream(ctx, owner, strings.NewReader(testManifests)))
+	exists, err = cms.Exists(ctx, owner)
+	require.NoError(t, err)
+	require.True(t, exists)
+	actual, err := cms.Load(ctx, owner)
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	streamed, err := loadStream(t, cms, owner)
+	require.NoError(t, err)
+	require.ElementsMatch(t, actual, streamed)
+
+	// Storing fewer objects deletes the ConfigMaps of the others.
+	require.NoError(t, cms.StoreStream(ctx, owner, strings.NewReader("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: c\n")))
+	streamed, err = loadStream(t, cms, owner)
+	require.NoError(t, err)
+	require.Len(t, streamed, 1)
+	require.Equal(t, "c", streamed[0].GetName())
+	stored := &corev1.ConfigMapList{}
+	require.NoError(t, kubeclient.List(ctx, stored))
+	require.Len(t, stored.Items, 2)
+
+	// A stream whose objects fail their checksum ends with an integrity error.
+	metadataCM := &corev1.ConfigMap{}
+	require.NoError(t, kubeclient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "metadata-test-owner"}, metadataCM))
+	metadataCM.Data["checksum"] = "sha256:0000"
+	require.NoError(t, kubeclient.Update(ctx, metadataCM))
+	var integrityErr *IntegrityError
+	_, err = loadStream(t, cms, owner)
+	require.ErrorAs(t, err, &integrityErr)
+	require.Equal(t, "test-owner", integrityErr.Owner)
+
+	require.NoError(t, cms.Delete(ctx, owner))
+	require.NoError(t, kubeclient.List(ctx, stored))
+	require.Empty(t, stored.Items)
+	exists, err = cms.Exists(ctx, owner)
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.NoError(t, cms.Delete(ctx, owner), "deleting objects that are not stored should succeed")
+}
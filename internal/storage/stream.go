@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// objectIterator calls fn with each of a sequence of objects, and stops at the
+// first error. It lets the backends store a slice of objects and a stream of
+// manifests in the same way, one object at a time.
+type objectIterator func(fn func(obj client.Object) error) error
+
+// eachObject returns an objectIterator over objects.
+func eachObject(objects []client.Object) objectIterator {
+	return func(fn func(obj client.Object) error) error {
+		for _, obj := range objects {
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// decodeObjects returns an objectIterator over the YAML or JSON documents in
+// r, which are decoded as they are read. Empty documents are skipped.
+func decodeObjects(r io.Reader) objectIterator {
+	return func(fn func(obj client.Object) error) error {
+		decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+		for i := 0; ; i++ {
+			var obj map[string]interface{}
+			if err := decoder.Decode(&obj); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return fmt.Errorf("decode object %d: %w", i, err)
+			}
+			if len(obj) == 0 {
+				continue
+			}
+			if err := fn(&unstructured.Unstructured{Object: obj}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeDocument writes the YAML document data to w, preceded by a document
+// separator.
+func writeDocument(w io.Writer, data []byte) error {
+	if _, err := io.WriteString(w, "---\n"); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		_, err := io.WriteString(w, "\n")
+		return err
+	}
+	return nil
+}
+
+// pipeDocuments returns a stream of the YAML documents that write writes in a
+// separate goroutine. The stream fails with the error that write returns, so
+// that content that fails its integrity check once it has been read ends with
+// an *IntegrityError rather than io.EOF.
+func pipeDocuments(write func(w io.Writer) error) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(write(pw))
+	}()
+	return pr
+}
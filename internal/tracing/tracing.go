@@ -0,0 +1,101 @@
+// Package tracing traces the unpacks of Bundles and the installs of
+// BundleInstances with OpenTelemetry, and exports their spans over OTLP.
+package tracing
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/operator-framework/rukpak"
+
+// Attributes of the spans of the rukpak controllers.
+const (
+	AttributeBundle         = attribute.Key("rukpak.bundle")
+	AttributeBundleInstance = attribute.Key("rukpak.bundleinstance")
+	AttributeDigest         = attribute.Key("rukpak.digest")
+	AttributeSourceType     = attribute.Key("rukpak.source.type")
+	AttributeObjects        = attribute.Key("rukpak.objects")
+)
+
+// Options configures the export of the spans of a controller manager.
+type Options struct {
+	// Endpoint is the host and port of the OTLP gRPC receiver that spans are
+	// exported to. Tracing is disabled when it is empty.
+	Endpoint string
+	// Insecure exports spans without TLS.
+	Insecure bool
+	// SampleRatio is the ratio of the reconciles that are traced, unless a
+	// parent span decided otherwise.
+	SampleRatio float64
+}
+
+// BindFlags binds the tracing options to flags in fs.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Endpoint, "tracing-otlp-endpoint", "",
+		"The host:port of the OTLP gRPC receiver to export traces to. Tracing is disabled when empty.")
+	fs.BoolVar(&o.Insecure, "tracing-otlp-insecure", false,
+		"Export traces to the OTLP receiver without TLS.")
+	fs.Float64Var(&o.SampleRatio, "tracing-sample-ratio", 1,
+		"The ratio, between 0 and 1, of the reconciles that are traced.")
+}
+
+// Validate returns an error when o has an invalid sample ratio.
+func (o Options) Validate() error {
+	if o.SampleRatio < 0 || o.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio %v must be between 0 and 1", o.SampleRatio)
+	}
+	return nil
+}
+
+// Setup installs the global tracer provider, which exports the spans of
+// serviceName to the endpoint of o, and returns a function that flushes the
+// remaining spans and stops exporting them. Spans are not recorded when o has
+// no endpoint.
+func Setup(ctx context.Context, o Options, serviceName string) (func(context.Context) error, error) {
+	if o.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(o.Endpoint)}
+	if o.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP trace exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(o.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name, as a child of the span of ctx, and returns
+// a context that holds it.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, and records err as its error when it is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
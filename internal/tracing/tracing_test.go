@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			opts: Options{SampleRatio: 1},
+		},
+		{
+			name: "no sampling",
+			opts: Options{Endpoint: "localhost:4317"},
+		},
+		{
+			name:    "negative ratio",
+			opts:    Options{SampleRatio: -0.5},
+			wantErr: "tracing sample ratio -0.5 must be between 0 and 1",
+		},
+		{
+			name:    "ratio above one",
+			opts:    Options{SampleRatio: 2},
+			wantErr: "tracing sample ratio 2 must be between 0 and 1",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{SampleRatio: 1}, "test")
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+}
+
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	ctx, parent := Start(context.Background(), "parent", AttributeBundle.String("test"))
+	_, child := Start(ctx, "child")
+	End(child, errors.New("failed"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "child", spans[0].Name())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, "failed", spans[0].Status().Description)
+	require.Len(t, spans[0].Events(), 1)
+	require.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Equal(t, "parent", spans[1].Name())
+	require.Equal(t, codes.Unset, spans[1].Status().Code)
+	require.Contains(t, spans[1].Attributes(), AttributeBundle.String("test"))
+}
@@ -27,6 +27,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+	"github.com/operator-framework/rukpak/internal/util"
 )
 
 func New(client client.Client) Updater {
@@ -76,6 +77,14 @@ func EnsureCondition(condition metav1.Condition) UpdateStatusFunc {
 	}
 }
 
+// EnsureReadyCondition derives the Ready condition of the Bundle b from its
+// Unpacked condition, as left by the update status functions before it.
+func EnsureReadyCondition(b *rukpakv1alpha1.Bundle) UpdateStatusFunc {
+	return func(status *rukpakv1alpha1.BundleStatus) bool {
+		return EnsureCondition(util.ReadyCondition(b, status.Conditions, []string{rukpakv1alpha1.TypeUnpacked}, nil))(status)
+	}
+}
+
 func conditionsSemanticallyEqual(a, b metav1.Condition) bool {
 	return a.Type == b.Type && a.Status == b.Status && a.Reason == b.Reason && a.Message == b.Message && a.ObservedGeneration == b.ObservedGeneration
 }
@@ -123,3 +132,43 @@ func SetPhase(phase string) UpdateStatusFunc {
 		return true
 	}
 }
+
+func SetResolvedSource(source *rukpakv1alpha1.BundleSource) UpdateStatusFunc {
+	return func(status *rukpakv1alpha1.BundleStatus) bool {
+		if reflect.DeepEqual(status.ResolvedSource, source) {
+			return false
+		}
+		status.ResolvedSource = source
+		return true
+	}
+}
+
+func EnsureUnpackRetries(retries int32) UpdateStatusFunc {
+	return func(status *rukpakv1alpha1.BundleStatus) bool {
+		if status.UnpackRetries == retries {
+			return false
+		}
+		status.UnpackRetries = retries
+		return true
+	}
+}
+
+func SetValidationErrors(errs []rukpakv1alpha1.BundleValidationError) UpdateStatusFunc {
+	return func(status *rukpakv1alpha1.BundleStatus) bool {
+		if reflect.DeepEqual(status.ValidationErrors, errs) {
+			return false
+		}
+		status.ValidationErrors = errs
+		return true
+	}
+}
+
+func RemoveCondition(conditionType string) UpdateStatusFunc {
+	return func(status *rukpakv1alpha1.BundleStatus) bool {
+		if meta.FindStatusCondition(status.Conditions, conditionType) == nil {
+			return false
+		}
+		meta.RemoveStatusCondition(&status.Conditions, conditionType)
+		return true
+	}
+}
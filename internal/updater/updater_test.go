@@ -262,3 +262,101 @@ var _ = Describe("UnsetBundleInfo", func() {
 		Expect(status.Info).To(Equal((*rukpakv1alpha1.BundleInfo)(nil)))
 	})
 })
+
+var _ = Describe("EnsureUnpackRetries", func() {
+	var status *rukpakv1alpha1.BundleStatus
+
+	BeforeEach(func() {
+		status = &rukpakv1alpha1.BundleStatus{}
+	})
+
+	It("should set unpack retries if not present", func() {
+		Expect(updater.EnsureUnpackRetries(2)(status)).To(BeTrue())
+		Expect(status.UnpackRetries).To(Equal(int32(2)))
+	})
+
+	It("should return false for no update", func() {
+		status.UnpackRetries = 2
+		Expect(updater.EnsureUnpackRetries(2)(status)).To(BeFalse())
+		Expect(status.UnpackRetries).To(Equal(int32(2)))
+	})
+})
+
+var _ = Describe("SetResolvedSource", func() {
+	var (
+		status *rukpakv1alpha1.BundleStatus
+		source *rukpakv1alpha1.BundleSource
+	)
+
+	BeforeEach(func() {
+		status = &rukpakv1alpha1.BundleStatus{}
+		source = &rukpakv1alpha1.BundleSource{
+			Type:  rukpakv1alpha1.SourceTypeImage,
+			Image: &rukpakv1alpha1.ImageSource{Ref: "quay.io/a/b@sha256:1234"},
+		}
+	})
+
+	It("should set the resolved source if not present", func() {
+		Expect(updater.SetResolvedSource(source)(status)).To(BeTrue())
+		Expect(status.ResolvedSource).To(Equal(source))
+	})
+
+	It("should return false for no update", func() {
+		status.ResolvedSource = source.DeepCopy()
+		Expect(updater.SetResolvedSource(source)(status)).To(BeFalse())
+	})
+
+	It("should clear the resolved source", func() {
+		status.ResolvedSource = source
+		Expect(updater.SetResolvedSource(nil)(status)).To(BeTrue())
+		Expect(status.ResolvedSource).To(BeNil())
+	})
+})
+
+var _ = Describe("SetValidationErrors", func() {
+	var (
+		status *rukpakv1alpha1.BundleStatus
+		errs   []rukpakv1alpha1.BundleValidationError
+	)
+
+	BeforeEach(func() {
+		status = &rukpakv1alpha1.BundleStatus{}
+		errs = []rukpakv1alpha1.BundleValidationError{{File: "objects.yaml", Object: "ConfigMap a", Message: "is invalid"}}
+	})
+
+	It("should set the validation errors if not present", func() {
+		Expect(updater.SetValidationErrors(errs)(status)).To(BeTrue())
+		Expect(status.ValidationErrors).To(Equal(errs))
+	})
+
+	It("should return false for no update", func() {
+		status.ValidationErrors = errs
+		Expect(updater.SetValidationErrors(errs)(status)).To(BeFalse())
+	})
+
+	It("should clear the validation errors", func() {
+		status.ValidationErrors = errs
+		Expect(updater.SetValidationErrors(nil)(status)).To(BeTrue())
+		Expect(status.ValidationErrors).To(BeNil())
+	})
+})
+
+var _ = Describe("RemoveCondition", func() {
+	var status *rukpakv1alpha1.BundleStatus
+
+	BeforeEach(func() {
+		status = &rukpakv1alpha1.BundleStatus{
+			Conditions: []metav1.Condition{{Type: rukpakv1alpha1.TypeInvalidBundleContent, Status: metav1.ConditionTrue}},
+		}
+	})
+
+	It("should remove the condition if present", func() {
+		Expect(updater.RemoveCondition(rukpakv1alpha1.TypeInvalidBundleContent)(status)).To(BeTrue())
+		Expect(status.Conditions).To(BeEmpty())
+	})
+
+	It("should return false for no update", func() {
+		Expect(updater.RemoveCondition(rukpakv1alpha1.TypeUnpacked)(status)).To(BeFalse())
+		Expect(status.Conditions).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,123 @@
+package util
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+// proxyEnvNames are the environment variables that configure the proxy of
+// HTTP clients, in both the upper and lower case that git and curl honor.
+var proxyEnvNames = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"}
+
+// CABundleFor returns the CA bundle that source trusts: its own, or otherwise
+// the CA bundle of config. It returns nil when neither sets one.
+func CABundleFor(config rukpakv1alpha1.RukpakConfigSpec, source rukpakv1alpha1.BundleSource) *rukpakv1alpha1.CABundleReference {
+	if source.CABundle != nil {
+		return source.CABundle
+	}
+	return config.CABundle
+}
+
+// CABundleKey returns the key of the certificates that ref refers to.
+func CABundleKey(ref rukpakv1alpha1.CABundleReference) string {
+	if ref.Key == "" {
+		return rukpakv1alpha1.DefaultCABundleKey
+	}
+	return ref.Key
+}
+
+// LoadCABundle returns the PEM-encoded certificates that ref refers to in the
+// ConfigMap or Secret of namespace.
+func LoadCABundle(ctx context.Context, cl client.Reader, namespace string, ref rukpakv1alpha1.CABundleReference) ([]byte, error) {
+	key := CABundleKey(ref)
+	if ref.Secret != "" {
+		secret := &corev1.Secret{}
+		if err := cl.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Secret}, secret); err != nil {
+			return nil, fmt.Errorf("get CA bundle secret %q: %w", ref.Secret, err)
+		}
+		data, ok := secret.Data[key]
+		if !ok {
+			return nil, fmt.Errorf("CA bundle secret %q has no %q key", ref.Secret, key)
+		}
+		return data, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := cl.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.ConfigMap}, cm); err != nil {
+		return nil, fmt.Errorf("get CA bundle configmap %q: %w", ref.ConfigMap, err)
+	}
+	if data, ok := cm.Data[key]; ok {
+		return []byte(data), nil
+	}
+	if data, ok := cm.BinaryData[key]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("CA bundle configmap %q has no %q key", ref.ConfigMap, key)
+}
+
+// defaultHTTPTimeout bounds the requests of DefaultHTTPClient, including the
+// time taken to read their responses, which may be large image layers.
+const defaultHTTPTimeout = 5 * time.Minute
+
+// DefaultHTTPClient is the client with which sources, registries and
+// signatures are fetched when no client is configured. Unlike
+// http.DefaultClient, its requests time out, so that an unresponsive server
+// cannot block a reconcile indefinitely.
+var DefaultHTTPClient = &http.Client{Timeout: defaultHTTPTimeout}
+
+// HTTPClientWithCABundle returns a copy of base, or of DefaultHTTPClient when
+// base is nil, that trusts the certificates of caBundle along with the system
+// ones. Its transport keeps the proxy configuration of the transport of base.
+func HTTPClientWithCABundle(base *http.Client, caBundle []byte) (*http.Client, error) {
+	if base == nil {
+		base = DefaultHTTPClient
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, errors.New("CA bundle contains no PEM-encoded certificates")
+	}
+
+	var transport *http.Transport
+	switch t := base.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, fmt.Errorf("unable to add a CA bundle to a transport of type %T", t)
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	transport.TLSClientConfig.RootCAs = pool
+
+	c := *base
+	c.Transport = transport
+	return &c, nil
+}
+
+// ProxyEnv returns the proxy environment variables of the process, so that the
+// containers that fetch bundle content use the same proxy as the provisioner.
+func ProxyEnv() []corev1.EnvVar {
+	var env []corev1.EnvVar
+	for _, name := range proxyEnvNames {
+		if value := os.Getenv(name); value != "" {
+			env = append(env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
+	return env
+}
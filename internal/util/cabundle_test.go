@@ -0,0 +1,101 @@
+package util
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestLoadCABundle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, kscheme.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "rukpak-system"}, Data: map[string]string{"ca.crt": "configmap", "other.pem": "other"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "rukpak-system"}, Data: map[string][]byte{"ca.crt": []byte("secret")}},
+	).Build()
+
+	for _, tt := range []struct {
+		name    string
+		ref     rukpakv1alpha1.CABundleReference
+		want    string
+		wantErr string
+	}{
+		{
+			name: "configmap",
+			ref:  rukpakv1alpha1.CABundleReference{ConfigMap: "ca"},
+			want: "configmap",
+		},
+		{
+			name: "configmap key",
+			ref:  rukpakv1alpha1.CABundleReference{ConfigMap: "ca", Key: "other.pem"},
+			want: "other",
+		},
+		{
+			name: "secret",
+			ref:  rukpakv1alpha1.CABundleReference{Secret: "ca"},
+			want: "secret",
+		},
+		{
+			name:    "missing key",
+			ref:     rukpakv1alpha1.CABundleReference{Secret: "ca", Key: "other.pem"},
+			wantErr: `CA bundle secret "ca" has no "other.pem" key`,
+		},
+		{
+			name:    "missing configmap",
+			ref:     rukpakv1alpha1.CABundleReference{ConfigMap: "missing"},
+			wantErr: `get CA bundle configmap "missing": configmaps "missing" not found`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := LoadCABundle(context.Background(), cl, "rukpak-system", tt.ref)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestHTTPClientWithCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer srv.Close()
+
+	_, err := http.Get(srv.URL)
+	require.Error(t, err, "the certificate of the server is not trusted by default")
+
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	c, err := HTTPClientWithCABundle(nil, caBundle)
+	require.NoError(t, err)
+	resp, err := c.Get(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	_, err = HTTPClientWithCABundle(nil, []byte("not a certificate"))
+	require.EqualError(t, err, "CA bundle contains no PEM-encoded certificates")
+}
+
+func TestProxyEnv(t *testing.T) {
+	for _, name := range proxyEnvNames {
+		t.Setenv(name, "")
+	}
+	t.Setenv("HTTPS_PROXY", "http://proxy.example.com:3128")
+	t.Setenv("NO_PROXY", ".cluster.local")
+
+	require.Equal(t, []corev1.EnvVar{
+		{Name: "HTTPS_PROXY", Value: "http://proxy.example.com:3128"},
+		{Name: "NO_PROXY", Value: ".cluster.local"},
+	}, ProxyEnv())
+}
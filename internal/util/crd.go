@@ -34,17 +34,8 @@ func CreateOrUpdateCRD(ctx context.Context, cl client.Client, crd *apiextensions
 		}
 		crd.SetResourceVersion(currentCRD.GetResourceVersion())
 
-		if err := validateCRDCompatibility(ctx, cl, currentCRD, crd); err != nil {
-			return fmt.Errorf("error validating existing CRs against new CRD's schema for %q: %w", crd.Name, err)
-		}
-
-		// check to see if stored versions changed and whether the upgrade could cause potential data loss
-		safe, err := safeStorageVersionUpgrade(currentCRD, crd)
-		if !safe {
-			return fmt.Errorf("risk of data loss updating %q: %w", crd.Name, err)
-		}
-		if err != nil {
-			return fmt.Errorf("checking CRD for potential data loss updating %q: %w", crd.Name, err)
+		if err := ValidateCRDUpgrade(ctx, cl, currentCRD, crd); err != nil {
+			return fmt.Errorf("error validating upgrade of CRD %q: %w", crd.Name, err)
 		}
 
 		// Update CRD to new version
@@ -58,6 +49,26 @@ func CreateOrUpdateCRD(ctx context.Context, cl client.Client, crd *apiextensions
 	return controllerutil.OperationResultUpdated, nil
 }
 
+// ValidateCRDUpgrade returns an error when updating the existing CRD oldCRD to
+// newCRD could lose or invalidate the custom resources stored for it: when
+// newCRD removes a stored version of oldCRD, or when the existing custom
+// resources, listed with cl, do not validate against the changed schemas of
+// newCRD.
+func ValidateCRDUpgrade(ctx context.Context, cl client.Client, oldCRD, newCRD *apiextensionsv1.CustomResourceDefinition) error {
+	// check to see if stored versions changed and whether the upgrade could cause potential data loss
+	safe, err := safeStorageVersionUpgrade(oldCRD, newCRD)
+	if !safe {
+		return fmt.Errorf("risk of data loss: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("checking CRD for potential data loss: %w", err)
+	}
+	if err := validateCRDCompatibility(ctx, cl, oldCRD, newCRD); err != nil {
+		return fmt.Errorf("error validating existing CRs against new CRD's schema: %w", err)
+	}
+	return nil
+}
+
 func keys(m map[string]apiextensionsv1.CustomResourceDefinitionVersion) sets.String {
 	return sets.StringKeySet(m)
 }
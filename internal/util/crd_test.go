@@ -0,0 +1,96 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateCRDUpgrade(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+	version := func(name string, required ...string) apiextensionsv1.CustomResourceDefinitionVersion {
+		return apiextensionsv1.CustomResourceDefinitionVersion{
+			Name:    name,
+			Served:  true,
+			Storage: name == "v1",
+			Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"spec": {
+						Type:     "object",
+						Required: required,
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"size":  {Type: "integer"},
+							"color": {Type: "string"},
+						},
+					},
+				},
+			}},
+		}
+	}
+	crd := func(versions ...apiextensionsv1.CustomResourceDefinitionVersion) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Group:    "example.com",
+				Names:    apiextensionsv1.CustomResourceDefinitionNames{Plural: "widgets", Kind: "Widget", ListKind: "WidgetList"},
+				Scope:    apiextensionsv1.NamespaceScoped,
+				Versions: versions,
+			},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: []string{"v1"}},
+		}
+	}
+	widget := &unstructured.Unstructured{}
+	widget.SetAPIVersion("example.com/v1")
+	widget.SetKind("Widget")
+	widget.SetNamespace("default")
+	widget.SetName("small")
+	widget.Object["spec"] = map[string]interface{}{"size": int64(1)}
+
+	for _, tt := range []struct {
+		name    string
+		newCRD  *apiextensionsv1.CustomResourceDefinition
+		wantErr string
+	}{
+		{
+			name:   "unchanged",
+			newCRD: crd(version("v1")),
+		},
+		{
+			name:   "added version",
+			newCRD: crd(version("v1"), version("v2")),
+		},
+		{
+			name:   "compatible schema change",
+			newCRD: crd(version("v1", "size")),
+		},
+		{
+			name:    "removed stored version",
+			newCRD:  crd(version("v2")),
+			wantErr: "risk of data loss: new CRD removes version v1 that is listed as a stored version on the existing CRD",
+		},
+		{
+			name:    "incompatible schema change",
+			newCRD:  crd(version("v1", "color")),
+			wantErr: `existing custom object default/small failed validation for new schema version v1`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(widget.DeepCopy()).Build()
+			err := ValidateCRDUpgrade(context.Background(), cl, crd(version("v1")), tt.newCRD)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
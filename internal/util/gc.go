@@ -0,0 +1,73 @@
+package util
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+// ReferencedBundleNames returns the names of the Bundles that are referenced
+// by bundleInstances, either as the Bundle they install or as the Bundle
+// they have installed.
+func ReferencedBundleNames(bundleInstances []rukpakv1alpha1.BundleInstance) sets.String {
+	names := sets.NewString()
+	for i := range bundleInstances {
+		bi := &bundleInstances[i]
+		names.Insert(DesiredBundleName(bi))
+		if bi.Status.InstalledBundleName != "" {
+			names.Insert(bi.Status.InstalledBundleName)
+		}
+	}
+	return names
+}
+
+// CollectableBundles returns the Bundles that policy deletes among bundles,
+// none of which are referenced, at now. The returned duration is how long it
+// takes for the next of the remaining Bundles to leave its retention period,
+// or zero when no retention period will lapse.
+func CollectableBundles(bundles []rukpakv1alpha1.Bundle, policy rukpakv1alpha1.BundleGCPolicy, now time.Time) ([]rukpakv1alpha1.Bundle, time.Duration) {
+	if policy.RetentionPeriod == nil && policy.KeepLatest == nil {
+		return nil, 0
+	}
+	groups := map[string][]rukpakv1alpha1.Bundle{}
+	for _, b := range bundles {
+		group := ""
+		if policy.GroupByLabel != "" {
+			group = b.Labels[policy.GroupByLabel]
+		}
+		groups[group] = append(groups[group], b)
+	}
+
+	var (
+		collectable []rukpakv1alpha1.Bundle
+		next        time.Duration
+	)
+	for _, group := range groups {
+		// The most recently created Bundles of every group come first.
+		sort.Slice(group, func(i, j int) bool {
+			if !group[i].CreationTimestamp.Equal(&group[j].CreationTimestamp) {
+				return group[j].CreationTimestamp.Before(&group[i].CreationTimestamp)
+			}
+			return group[i].Name < group[j].Name
+		})
+		for i, b := range group {
+			if policy.KeepLatest != nil && i < int(*policy.KeepLatest) {
+				continue
+			}
+			if policy.RetentionPeriod != nil {
+				if remaining := b.CreationTimestamp.Add(policy.RetentionPeriod.Duration).Sub(now); remaining > 0 {
+					if next == 0 || remaining < next {
+						next = remaining
+					}
+					continue
+				}
+			}
+			collectable = append(collectable, b)
+		}
+	}
+	sort.Slice(collectable, func(i, j int) bool { return collectable[i].Name < collectable[j].Name })
+	return collectable, next
+}
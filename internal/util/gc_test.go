@@ -0,0 +1,93 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestReferencedBundleNames(t *testing.T) {
+	names := ReferencedBundleNames([]rukpakv1alpha1.BundleInstance{
+		{Spec: rukpakv1alpha1.BundleInstanceSpec{BundleName: "a"}},
+		{Spec: rukpakv1alpha1.BundleInstanceSpec{BundleName: "b"}, Status: rukpakv1alpha1.BundleInstanceStatus{InstalledBundleName: "c"}},
+	})
+	require.ElementsMatch(t, []string{"a", "b", "c"}, names.List())
+}
+
+func TestCollectableBundles(t *testing.T) {
+	now := time.Now()
+	bundle := func(name, app string, age time.Duration) rukpakv1alpha1.Bundle {
+		b := rukpakv1alpha1.Bundle{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-age))}}
+		if app != "" {
+			b.Labels = map[string]string{"app": app}
+		}
+		return b
+	}
+	bundles := []rukpakv1alpha1.Bundle{
+		bundle("a-1", "a", 3*time.Hour),
+		bundle("a-2", "a", 2*time.Hour),
+		bundle("a-3", "a", 30*time.Minute),
+		bundle("b-1", "b", 3*time.Hour),
+		bundle("none", "", 3*time.Hour),
+	}
+	keep := func(n int32) *int32 { return &n }
+	names := func(bundles []rukpakv1alpha1.Bundle) []string {
+		var names []string
+		for _, b := range bundles {
+			names = append(names, b.Name)
+		}
+		return names
+	}
+
+	for _, tt := range []struct {
+		name      string
+		policy    rukpakv1alpha1.BundleGCPolicy
+		want      []string
+		wantAfter time.Duration
+	}{
+		{
+			name: "no policy",
+		},
+		{
+			name:      "retention period",
+			policy:    rukpakv1alpha1.BundleGCPolicy{RetentionPeriod: &metav1.Duration{Duration: time.Hour}},
+			want:      []string{"a-1", "a-2", "b-1", "none"},
+			wantAfter: 30 * time.Minute,
+		},
+		{
+			name:   "keep latest",
+			policy: rukpakv1alpha1.BundleGCPolicy{KeepLatest: keep(2)},
+			want:   []string{"a-1", "b-1", "none"},
+		},
+		{
+			name:   "keep latest by label",
+			policy: rukpakv1alpha1.BundleGCPolicy{KeepLatest: keep(1), GroupByLabel: "app"},
+			want:   []string{"a-1", "a-2"},
+		},
+		{
+			name:   "keep none",
+			policy: rukpakv1alpha1.BundleGCPolicy{KeepLatest: keep(0), GroupByLabel: "app"},
+			want:   []string{"a-1", "a-2", "a-3", "b-1", "none"},
+		},
+		{
+			name: "retention period and keep latest",
+			policy: rukpakv1alpha1.BundleGCPolicy{
+				RetentionPeriod: &metav1.Duration{Duration: 150 * time.Minute},
+				KeepLatest:      keep(1),
+				GroupByLabel:    "app",
+			},
+			want:      []string{"a-1"},
+			wantAfter: 30 * time.Minute,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, after := CollectableBundles(bundles, tt.policy, now)
+			require.Equal(t, tt.want, names(got))
+			require.Equal(t, tt.wantAfter, after)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The drivers in which the release history of BundleInstances can be stored.
+const (
+	HelmDriverSecret    = "secret"
+	HelmDriverConfigMap = "configmap"
+	HelmDriverSQL       = "sql"
+)
+
+var helmDrivers = []string{HelmDriverSecret, HelmDriverConfigMap, HelmDriverSQL}
+
+// HelmStorageOptions configures how the release history of BundleInstances
+// is stored.
+type HelmStorageOptions struct {
+	// MaxHistory is the maximum number of releases that are kept for each
+	// BundleInstance. The history is unbounded when it is 0.
+	MaxHistory int
+	// Driver is the storage driver of the releases.
+	Driver string
+	// SQLConnectionString is the connection string of the PostgreSQL database
+	// of the sql driver.
+	SQLConnectionString string
+}
+
+// BindFlags binds the helm storage options to flags in fs.
+func (o *HelmStorageOptions) BindFlags(fs *flag.FlagSet) {
+	fs.IntVar(&o.MaxHistory, "helm-max-history", 10,
+		"The maximum number of releases kept in the history of each BundleInstance. A value of 0 keeps every release.")
+	fs.StringVar(&o.Driver, "helm-storage-driver", HelmDriverSecret,
+		fmt.Sprintf("The storage driver of the releases of BundleInstances. One of %s.", strings.Join(helmDrivers, ", ")))
+	fs.StringVar(&o.SQLConnectionString, "helm-sql-connection-string", "",
+		"The connection string of the PostgreSQL database in which releases are stored by the sql storage driver.")
+}
+
+// Validate returns an error when o is not a valid configuration.
+func (o HelmStorageOptions) Validate() error {
+	if o.MaxHistory < 0 || o.MaxHistory == 1 {
+		// The previous release is needed to roll back a failed upgrade.
+		return fmt.Errorf("invalid helm max history %d: must be 0 or at least 2", o.MaxHistory)
+	}
+	if !contains(helmDrivers, o.Driver) {
+		return fmt.Errorf("invalid helm storage driver %q: must be one of %s", o.Driver, strings.Join(helmDrivers, ", "))
+	}
+	if (o.Driver == HelmDriverSQL) != (o.SQLConnectionString != "") {
+		return errors.New("a helm sql connection string is required by, and only supported by, the sql storage driver")
+	}
+	return nil
+}
+
+// HelmStorageConfigGetter returns an ActionConfigGetter whose configurations
+// are those of getter, but store their releases as configured by o. Releases
+// stored in ConfigMaps are owned by the object they are the release of, like
+// the Secrets of getter. Releases stored in an SQL database are stored in the
+// namespace of the object they are the release of, like those stored in
+// Secrets and ConfigMaps, and namespace is the namespace of the connection to
+// the database that is made up front, so that an unreachable database fails
+// at startup.
+func HelmStorageConfigGetter(getter helmclient.ActionConfigGetter, kubeClient kubernetes.Interface, namespace string, o HelmStorageOptions) (helmclient.ActionConfigGetter, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	g := &helmStorageConfigGetter{ActionConfigGetter: getter, kubeClient: kubeClient, options: o}
+	if o.Driver == HelmDriverSQL {
+		if _, err := g.sqlDriver(namespace); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+type helmStorageConfigGetter struct {
+	helmclient.ActionConfigGetter
+	kubeClient kubernetes.Interface
+	options    HelmStorageOptions
+
+	// sql holds the sql driver of each release namespace, as a driver only
+	// stores and lists the releases of the namespace it is created for.
+	mu  sync.Mutex
+	sql map[string]*driver.SQL
+}
+
+// sqlDriver returns the sql driver of the releases in namespace, connecting
+// to the database the first time the namespace is used.
+func (g *helmStorageConfigGetter) sqlDriver(namespace string) (*driver.SQL, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if d, ok := g.sql[namespace]; ok {
+		return d, nil
+	}
+	d, err := driver.NewSQL(g.options.SQLConnectionString, func(string, ...interface{}) {}, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("connect to helm sql storage: %w", err)
+	}
+	if g.sql == nil {
+		g.sql = map[string]*driver.SQL{}
+	}
+	g.sql[namespace] = d
+	return d, nil
+}
+
+func (g *helmStorageConfigGetter) ActionConfigFor(obj client.Object) (*action.Configuration, error) {
+	cfg, err := g.ActionConfigGetter.ActionConfigFor(obj)
+	if err != nil {
+		return nil, err
+	}
+	switch g.options.Driver {
+	case HelmDriverConfigMap:
+		ownerRef := metav1.NewControllerRef(obj, obj.GetObjectKind().GroupVersionKind())
+		d := driver.NewConfigMaps(&ownerRefConfigMapClient{
+			ConfigMapInterface: g.kubeClient.CoreV1().ConfigMaps(obj.GetNamespace()),
+			refs:               []metav1.OwnerReference{*ownerRef},
+		})
+		d.Log = cfg.Log
+		cfg.Releases = storage.Init(d)
+	case HelmDriverSQL:
+		d, err := g.sqlDriver(obj.GetNamespace())
+		if err != nil {
+			return nil, err
+		}
+		cfg.Releases = storage.Init(d)
+	}
+	cfg.Releases.Log = cfg.Log
+	cfg.Releases.MaxHistory = g.options.MaxHistory
+	return cfg, nil
+}
+
+// ownerRefConfigMapClient sets refs as the owner references of the ConfigMaps
+// it creates and updates.
+type ownerRefConfigMapClient struct {
+	v1.ConfigMapInterface
+	refs []metav1.OwnerReference
+}
+
+func (c *ownerRefConfigMapClient) Create(ctx context.Context, in *corev1.ConfigMap, opts metav1.CreateOptions) (*corev1.ConfigMap, error) {
+	in.OwnerReferences = append(in.OwnerReferences, c.refs...)
+	return c.ConfigMapInterface.Create(ctx, in, opts)
+}
+
+func (c *ownerRefConfigMapClient) Update(ctx context.Context, in *corev1.ConfigMap, opts metav1.UpdateOptions) (*corev1.ConfigMap, error) {
+	in.OwnerReferences = append(in.OwnerReferences, c.refs...)
+	return c.ConfigMapInterface.Update(ctx, in, opts)
+}
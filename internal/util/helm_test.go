@@ -0,0 +1,96 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+type memoryConfigGetter struct{}
+
+func (memoryConfigGetter) ActionConfigFor(client.Object) (*action.Configuration, error) {
+	return &action.Configuration{Releases: storage.Init(driver.NewMemory()), Log: func(string, ...interface{}) {}}, nil
+}
+
+func TestHelmStorageOptionsValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		options HelmStorageOptions
+		wantErr string
+	}{
+		{
+			name:    "defaults",
+			options: HelmStorageOptions{MaxHistory: 10, Driver: HelmDriverSecret},
+		},
+		{
+			name:    "unbounded history",
+			options: HelmStorageOptions{Driver: HelmDriverConfigMap},
+		},
+		{
+			name:    "sql",
+			options: HelmStorageOptions{Driver: HelmDriverSQL, SQLConnectionString: "host=db"},
+		},
+		{
+			name:    "single release",
+			options: HelmStorageOptions{MaxHistory: 1, Driver: HelmDriverSecret},
+			wantErr: "invalid helm max history 1: must be 0 or at least 2",
+		},
+		{
+			name:    "unknown driver",
+			options: HelmStorageOptions{Driver: "memory"},
+			wantErr: `invalid helm storage driver "memory": must be one of secret, configmap, sql`,
+		},
+		{
+			name:    "sql without connection string",
+			options: HelmStorageOptions{Driver: HelmDriverSQL},
+			wantErr: "a helm sql connection string is required by, and only supported by, the sql storage driver",
+		},
+		{
+			name:    "connection string without sql",
+			options: HelmStorageOptions{Driver: HelmDriverSecret, SQLConnectionString: "host=db"},
+			wantErr: "a helm sql connection string is required by, and only supported by, the sql storage driver",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestHelmStorageConfigGetter(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset()
+	getter, err := HelmStorageConfigGetter(memoryConfigGetter{}, kubeClient, "rukpak-system", HelmStorageOptions{MaxHistory: 2, Driver: HelmDriverConfigMap})
+	require.NoError(t, err)
+
+	bi := &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "bi", Namespace: "rukpak-system", UID: "uid"}}
+	bi.SetGroupVersionKind(rukpakv1alpha1.GroupVersion.WithKind("BundleInstance"))
+	cfg, err := getter.ActionConfigFor(bi)
+	require.NoError(t, err)
+	require.Equal(t, 2, cfg.Releases.MaxHistory)
+
+	for version := 1; version <= 3; version++ {
+		require.NoError(t, cfg.Releases.Create(&release.Release{Name: "bi", Namespace: "rukpak-system", Version: version, Info: &release.Info{Status: release.StatusDeployed}}))
+	}
+	cms, err := kubeClient.CoreV1().ConfigMaps("rukpak-system").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, cms.Items, 2, "expected the least recent release to be removed")
+	for _, cm := range cms.Items {
+		require.Len(t, cm.OwnerReferences, 1)
+		require.Equal(t, "bi", cm.OwnerReferences[0].Name)
+	}
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"k8s.io/client-go/rest"
+)
+
+// ClientIdentity returns a best-effort description of the identity that the
+// given rest.Config authenticates as. For bearer tokens issued to service
+// accounts this is the subject of the token, e.g.
+// "system:serviceaccount:rukpak-system:plain-provisioner-admin". When no
+// identity can be determined, @defaultIdentity is returned.
+//
+// The token is not verified: the result is only suitable for informational
+// purposes such as auditing records, not for making authorization decisions.
+func ClientIdentity(cfg *rest.Config, defaultIdentity string) string {
+	if cfg.Username != "" {
+		return cfg.Username
+	}
+	token := cfg.BearerToken
+	if cfg.BearerTokenFile != "" {
+		if data, err := ioutil.ReadFile(cfg.BearerTokenFile); err == nil {
+			token = strings.TrimSpace(string(data))
+		}
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return defaultIdentity
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return defaultIdentity
+	}
+	claims := struct {
+		Subject string `json:"sub"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return defaultIdentity
+	}
+	return claims.Subject
+}
+
+// ServiceAccountUsername returns the username that the service account name
+// in namespace authenticates as.
+func ServiceAccountUsername(namespace, name string) string {
+	return fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name)
+}
+
+// ServiceAccountGroups returns the groups that every service account in
+// namespace is a member of.
+func ServiceAccountGroups(namespace string) []string {
+	return []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace, "system:authenticated"}
+}
+
+// ImpersonatedConfig returns a copy of cfg that impersonates the service
+// account name in namespace. The API server adds the groups of the service
+// account to the impersonated user. The copy shares the rate limiter of cfg.
+func ImpersonatedConfig(cfg *rest.Config, namespace, name string) *rest.Config {
+	cfg = rest.CopyConfig(cfg)
+	cfg.Impersonate = rest.ImpersonationConfig{
+		UserName: ServiceAccountUsername(namespace, name),
+	}
+	return cfg
+}
@@ -0,0 +1,85 @@
+package util
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// LeaderElectionOptions configures the leader election of a manager, so that
+// several replicas of it can run while only one reconciles at a time.
+type LeaderElectionOptions struct {
+	// Enabled enables leader election.
+	Enabled bool
+	// ID is the name of the Lease that the replicas compete for.
+	ID string
+	// Namespace is the namespace of the Lease. It defaults to the namespace
+	// of the pod of the manager.
+	Namespace string
+	// LeaseDuration, RenewDeadline and RetryPeriod are the timings of the
+	// leader election, as in the leaderelection package of client-go.
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+	// ReleaseOnCancel releases the Lease when the manager stops, so that
+	// another replica takes over without waiting for it to expire.
+	ReleaseOnCancel bool
+	// GracefulShutdownTimeout is how long a stopping manager waits for its
+	// reconciles to complete before giving up the Lease.
+	GracefulShutdownTimeout time.Duration
+}
+
+// BindFlags binds the leader election options to flags in fs, with defaultID
+// as the default name of the Lease.
+func (o *LeaderElectionOptions) BindFlags(fs *flag.FlagSet, defaultID string) {
+	fs.BoolVar(&o.Enabled, "leader-elect", false,
+		"Enable leader election for controller manager. "+
+			"Enabling this will ensure there is only one active controller manager.")
+	fs.StringVar(&o.ID, "leader-election-id", defaultID,
+		"The name of the Lease that the replicas of the controller manager compete for.")
+	fs.StringVar(&o.Namespace, "leader-election-namespace", "",
+		"The namespace of the leader election Lease. Defaults to the namespace of the pod.")
+	fs.DurationVar(&o.LeaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"How long replicas that are not the leader wait before trying to take over a Lease that was not renewed.")
+	fs.DurationVar(&o.RenewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"How long the leader tries to renew its Lease before it stops leading.")
+	fs.DurationVar(&o.RetryPeriod, "leader-election-retry-period", 2*time.Second,
+		"How long replicas wait between attempts to acquire or renew the Lease.")
+	fs.BoolVar(&o.ReleaseOnCancel, "leader-election-release-on-cancel", true,
+		"Release the Lease when the controller manager shuts down, so that another replica takes over immediately.")
+	fs.DurationVar(&o.GracefulShutdownTimeout, "graceful-shutdown-timeout", 30*time.Second,
+		"How long to wait for running reconciles to complete when the controller manager shuts down. A value of 0 does not wait.")
+}
+
+// Validate returns an error when the timings of o cannot elect a leader that
+// keeps its Lease.
+func (o LeaderElectionOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if o.RetryPeriod <= 0 {
+		return fmt.Errorf("leader election retry period %s must be positive", o.RetryPeriod)
+	}
+	if o.RenewDeadline <= o.RetryPeriod {
+		return fmt.Errorf("leader election renew deadline %s must be longer than the retry period %s", o.RenewDeadline, o.RetryPeriod)
+	}
+	if o.LeaseDuration <= o.RenewDeadline {
+		return fmt.Errorf("leader election lease duration %s must be longer than the renew deadline %s", o.LeaseDuration, o.RenewDeadline)
+	}
+	return nil
+}
+
+// Apply sets the leader election options of opts to o.
+func (o LeaderElectionOptions) Apply(opts *ctrl.Options) {
+	leaseDuration, renewDeadline, retryPeriod, shutdownTimeout := o.LeaseDuration, o.RenewDeadline, o.RetryPeriod, o.GracefulShutdownTimeout
+	opts.LeaderElection = o.Enabled
+	opts.LeaderElectionID = o.ID
+	opts.LeaderElectionNamespace = o.Namespace
+	opts.LeaseDuration = &leaseDuration
+	opts.RenewDeadline = &renewDeadline
+	opts.RetryPeriod = &retryPeriod
+	opts.LeaderElectionReleaseOnCancel = o.ReleaseOnCancel
+	opts.GracefulShutdownTimeout = &shutdownTimeout
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestLeaderElectionOptionsValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		opts    LeaderElectionOptions
+		wantErr string
+	}{
+		{
+			name: "disabled",
+			opts: LeaderElectionOptions{},
+		},
+		{
+			name: "defaults",
+			opts: LeaderElectionOptions{Enabled: true, LeaseDuration: 15 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: 2 * time.Second},
+		},
+		{
+			name:    "renew deadline longer than lease duration",
+			opts:    LeaderElectionOptions{Enabled: true, LeaseDuration: 10 * time.Second, RenewDeadline: 15 * time.Second, RetryPeriod: 2 * time.Second},
+			wantErr: "leader election lease duration 10s must be longer than the renew deadline 15s",
+		},
+		{
+			name:    "retry period longer than renew deadline",
+			opts:    LeaderElectionOptions{Enabled: true, LeaseDuration: 15 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: 10 * time.Second},
+			wantErr: "leader election renew deadline 10s must be longer than the retry period 10s",
+		},
+		{
+			name:    "no retry period",
+			opts:    LeaderElectionOptions{Enabled: true, LeaseDuration: 15 * time.Second, RenewDeadline: 10 * time.Second},
+			wantErr: "leader election retry period 0s must be positive",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestLeaderElectionOptionsApply(t *testing.T) {
+	opts := ctrl.Options{}
+	LeaderElectionOptions{
+		Enabled:                 true,
+		ID:                      "test.rukpak.io",
+		Namespace:               "rukpak-system",
+		LeaseDuration:           30 * time.Second,
+		RenewDeadline:           20 * time.Second,
+		RetryPeriod:             5 * time.Second,
+		ReleaseOnCancel:         true,
+		GracefulShutdownTimeout: time.Minute,
+	}.Apply(&opts)
+
+	require.True(t, opts.LeaderElection)
+	require.Equal(t, "test.rukpak.io", opts.LeaderElectionID)
+	require.Equal(t, "rukpak-system", opts.LeaderElectionNamespace)
+	require.Equal(t, 30*time.Second, *opts.LeaseDuration)
+	require.Equal(t, 20*time.Second, *opts.RenewDeadline)
+	require.Equal(t, 5*time.Second, *opts.RetryPeriod)
+	require.True(t, opts.LeaderElectionReleaseOnCancel)
+	require.Equal(t, time.Minute, *opts.GracefulShutdownTimeout)
+}
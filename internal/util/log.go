@@ -0,0 +1,73 @@
+package util
+
+import (
+	"flag"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// Structured logging keys shared by the rukpak controllers. Using the same
+// keys everywhere allows log aggregation systems to slice output by bundle,
+// bundle instance, release and object type regardless of which component
+// emitted the log line.
+const (
+	LogKeyBundle         = "bundle"
+	LogKeyBundleInstance = "bundleinstance"
+	LogKeyDigest         = "digest"
+	LogKeyRelease        = "release"
+	LogKeyGVK            = "gvk"
+	LogKeyObject         = "object"
+	LogKeyNamespace      = "namespace"
+)
+
+// Log verbosity levels used by the rukpak controllers. Reconcile-level
+// progress is logged at LogLevelDebug, and per-object apply detail, which
+// can be very noisy for large bundles, is logged at LogLevelTrace.
+const (
+	LogLevelDebug = 1
+	LogLevelTrace = 2
+)
+
+// Structured logging keys of the reconciles of the rukpak controllers. Every
+// log line of a reconcile carries the reconcile ID, so that the lines of one
+// reconcile can be told apart from those of concurrent reconciles of the same
+// object, and errors carry the condition type and reason that they set.
+const (
+	LogKeyReconcileID = "reconcileID"
+	LogKeyCondition   = "condition"
+	LogKeyReason      = "reason"
+)
+
+// NewReconcileID returns a random ID that correlates the log lines of a
+// reconcile.
+func NewReconcileID() string {
+	return string(uuid.NewUUID())
+}
+
+// ReconcileError returns err, the error of a failed reconcile, annotated with
+// the type and reason of cond, the condition that reports it, when there is
+// one. controller-runtime logs the errors that reconciles return, so the
+// annotation puts the condition and reason on the line that it logs rather
+// than logging the error a second time.
+func ReconcileError(err error, cond *metav1.Condition) error {
+	if err == nil || cond == nil {
+		return err
+	}
+	return fmt.Errorf("%w (%s=%s %s=%s)", err, LogKeyCondition, cond.Type, LogKeyReason, cond.Reason)
+}
+
+// BindLogFlags binds the flags of the zap logger to fs, and returns the
+// options that they set. Logs default to structured JSON output, so that log
+// aggregation systems can index the consistent keys emitted by the
+// controllers. Human-readable console output is still available with
+// --zap-devel.
+func BindLogFlags(fs *flag.FlagSet) *zap.Options {
+	opts := &zap.Options{
+		Development: false,
+	}
+	opts.BindFlags(fs)
+	return opts
+}
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestReconcileError(t *testing.T) {
+	failed := errors.New("install failed")
+	for _, tt := range []struct {
+		name string
+		err  error
+		cond *metav1.Condition
+		want string
+	}{
+		{
+			name: "condition",
+			err:  failed,
+			cond: &metav1.Condition{Type: rukpakv1alpha1.TypeInstalled, Reason: rukpakv1alpha1.ReasonInstallFailed},
+			want: "install failed (condition=Installed reason=InstallFailed)",
+		},
+		{
+			name: "no condition",
+			err:  failed,
+			want: "install failed",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ReconcileError(tt.err, tt.cond)
+			require.EqualError(t, err, tt.want)
+			require.ErrorIs(t, err, failed)
+		})
+	}
+	require.NoError(t, ReconcileError(nil, &metav1.Condition{Type: rukpakv1alpha1.TypeInstalled}))
+}
@@ -0,0 +1,134 @@
+package util
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// ProvisionerLabel is the label of a release namespace that names the
+	// provisioner whose releases are stored in it.
+	ProvisionerLabel = "core.rukpak.io/provisioner"
+
+	// releaseQuotaName is the name of the ResourceQuota of a release namespace.
+	releaseQuotaName = "release-quota"
+)
+
+var podSecurityLevels = []string{"privileged", "baseline", "restricted"}
+
+// ReleaseNamespaceOptions configures the automatic creation of the namespace
+// in which a provisioner stores its releases.
+type ReleaseNamespaceOptions struct {
+	// Create configures the namespace to be created and labeled when it does
+	// not exist, instead of requiring it to be created up front.
+	Create bool
+	// PodSecurityLevel is the Pod Security Standard that is enforced, audited
+	// and warned about in the namespace. No labels are set when it is empty.
+	PodSecurityLevel string
+	// Quota is the hard limit of the ResourceQuota of the namespace. No
+	// ResourceQuota is created when it is empty.
+	Quota corev1.ResourceList
+}
+
+// BindFlags binds the release namespace options to flags in fs.
+func (o *ReleaseNamespaceOptions) BindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Create, "create-release-namespace", false,
+		"Create and label the release namespace at startup if it does not exist.")
+	fs.StringVar(&o.PodSecurityLevel, "release-namespace-pod-security", "",
+		fmt.Sprintf("The Pod Security Standard enforced in a created release namespace. One of %s.", strings.Join(podSecurityLevels, ", ")))
+	fs.Var((*resourceListValue)(&o.Quota), "release-namespace-quota",
+		"A comma-separated list of resource=quantity pairs that limit the resources of a created release namespace, for example secrets=1000.")
+}
+
+// EnsureReleaseNamespace creates the release namespace name of provisioner,
+// and its ResourceQuota, when o configures it to be created. An existing
+// namespace is labeled, and its ResourceQuota updated, to match o.
+func EnsureReleaseNamespace(ctx context.Context, cl client.Client, name, provisioner string, o ReleaseNamespaceOptions) error {
+	if !o.Create {
+		return nil
+	}
+	if o.PodSecurityLevel != "" && !contains(podSecurityLevels, o.PodSecurityLevel) {
+		return fmt.Errorf("invalid pod security level %q: must be one of %s", o.PodSecurityLevel, strings.Join(podSecurityLevels, ", "))
+	}
+
+	ns := &corev1.Namespace{}
+	ns.SetName(name)
+	if _, err := controllerutil.CreateOrUpdate(ctx, cl, ns, func() error {
+		labels := ns.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[ProvisionerLabel] = provisioner
+		if o.PodSecurityLevel != "" {
+			for _, mode := range []string{"enforce", "audit", "warn"} {
+				labels["pod-security.kubernetes.io/"+mode] = o.PodSecurityLevel
+			}
+		}
+		ns.SetLabels(labels)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("ensure release namespace %q: %w", name, err)
+	}
+
+	if len(o.Quota) == 0 {
+		return nil
+	}
+	quota := &corev1.ResourceQuota{}
+	quota.SetName(releaseQuotaName)
+	quota.SetNamespace(name)
+	if _, err := controllerutil.CreateOrUpdate(ctx, cl, quota, func() error {
+		quota.Spec.Hard = o.Quota
+		return nil
+	}); err != nil {
+		return fmt.Errorf("ensure release namespace quota: %w", err)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type resourceListValue corev1.ResourceList
+
+func (v *resourceListValue) String() string {
+	pairs := make([]string, 0, len(*v))
+	for name, q := range *v {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, q.String()))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v *resourceListValue) Set(s string) error {
+	list := corev1.ResourceList{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid resource quota %q: expected resource=quantity", pair)
+		}
+		q, err := resource.ParseQuantity(kv[1])
+		if err != nil {
+			return fmt.Errorf("invalid quantity for resource %q: %w", kv[0], err)
+		}
+		list[corev1.ResourceName(strings.TrimSpace(kv[0]))] = q
+	}
+	*v = resourceListValue(list)
+	return nil
+}
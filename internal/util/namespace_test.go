@@ -0,0 +1,60 @@
+package util
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureReleaseNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, kscheme.AddToScheme(scheme))
+	existing := &corev1.Namespace{}
+	existing.SetName("existing")
+	existing.SetLabels(map[string]string{"team": "a"})
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
+
+	opts := ReleaseNamespaceOptions{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts.BindFlags(fs)
+	require.NoError(t, fs.Parse([]string{
+		"--create-release-namespace",
+		"--release-namespace-pod-security=restricted",
+		"--release-namespace-quota=secrets=100,requests.storage=1Gi",
+	}))
+
+	for _, name := range []string{"created", "existing"} {
+		require.NoError(t, EnsureReleaseNamespace(context.Background(), cl, name, "plain", opts))
+
+		ns := &corev1.Namespace{}
+		require.NoError(t, cl.Get(context.Background(), types.NamespacedName{Name: name}, ns))
+		require.Equal(t, "plain", ns.Labels[ProvisionerLabel])
+		require.Equal(t, "restricted", ns.Labels["pod-security.kubernetes.io/enforce"])
+		require.Equal(t, "restricted", ns.Labels["pod-security.kubernetes.io/warn"])
+
+		quota := &corev1.ResourceQuota{}
+		require.NoError(t, cl.Get(context.Background(), types.NamespacedName{Namespace: name, Name: releaseQuotaName}, quota))
+		require.Equal(t, corev1.ResourceList{
+			"secrets":          resource.MustParse("100"),
+			"requests.storage": resource.MustParse("1Gi"),
+		}, quota.Spec.Hard)
+	}
+
+	ns := &corev1.Namespace{}
+	require.NoError(t, cl.Get(context.Background(), types.NamespacedName{Name: "existing"}, ns))
+	require.Equal(t, "a", ns.Labels["team"], "expected existing labels to be preserved")
+
+	require.NoError(t, EnsureReleaseNamespace(context.Background(), cl, "disabled", "plain", ReleaseNamespaceOptions{}))
+	require.Error(t, cl.Get(context.Background(), types.NamespacedName{Name: "disabled"}, ns))
+
+	require.EqualError(t, EnsureReleaseNamespace(context.Background(), cl, "invalid", "plain", ReleaseNamespaceOptions{Create: true, PodSecurityLevel: "strict"}),
+		`invalid pod security level "strict": must be one of privileged, baseline, restricted`)
+}
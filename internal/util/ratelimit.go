@@ -0,0 +1,125 @@
+package util
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// ClientRateLimit is a client-side rate limit of requests to the API server.
+type ClientRateLimit struct {
+	QPS   float32
+	Burst int
+}
+
+// BindFlags binds the rate limit to the <prefix>-qps and <prefix>-burst flags
+// in fs, where purpose describes the requests that are subject to the limit.
+// The current values of l are used as the flag defaults.
+func (l *ClientRateLimit) BindFlags(fs *flag.FlagSet, prefix, purpose string) {
+	fs.Var((*float32Value)(&l.QPS), prefix+"-qps",
+		fmt.Sprintf("The maximum queries per second to the API server for %s. A value of 0 uses the default limit.", purpose))
+	fs.IntVar(&l.Burst, prefix+"-burst", l.Burst,
+		fmt.Sprintf("The maximum burst of queries to the API server for %s. A value of 0 uses the default limit.", purpose))
+}
+
+// RateLimitedConfig returns a copy of cfg that is subject to the rate limit l.
+// Non-positive values in l leave the corresponding limits of cfg unchanged,
+// and the rate limiting of cfg is left as-is when l is entirely unset.
+//
+// Otherwise, all clients created from the returned config share a single
+// rate limiter, so that the limit applies to their requests in aggregate.
+func RateLimitedConfig(cfg *rest.Config, l ClientRateLimit) *rest.Config {
+	cfg = rest.CopyConfig(cfg)
+	if l.QPS <= 0 && l.Burst <= 0 {
+		return cfg
+	}
+	if l.QPS > 0 {
+		cfg.QPS = l.QPS
+	}
+	if l.Burst > 0 {
+		cfg.Burst = l.Burst
+	}
+	cfg.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(cfg.QPS, cfg.Burst)
+	return cfg
+}
+
+// QueueRateLimit is the rate limit of the retries of the items of the
+// workqueue of a controller. Each item is retried after an exponential
+// backoff from BaseDelay up to MaxDelay, and all the retries together are
+// limited to QPS per second, in bursts of at most Burst retries.
+type QueueRateLimit struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	QPS       float64
+	Burst     int
+}
+
+// DefaultQueueRateLimit returns the rate limit of the default workqueue rate
+// limiter of controller-runtime.
+func DefaultQueueRateLimit() QueueRateLimit {
+	return QueueRateLimit{BaseDelay: 5 * time.Millisecond, MaxDelay: 1000 * time.Second, QPS: 10, Burst: 100}
+}
+
+// BindFlags binds the rate limit to the <prefix>-base-delay,
+// <prefix>-max-delay, <prefix>-qps and <prefix>-burst flags in fs, where
+// purpose describes the retried items. The current values of l are used as
+// the flag defaults.
+func (l *QueueRateLimit) BindFlags(fs *flag.FlagSet, prefix, purpose string) {
+	fs.DurationVar(&l.BaseDelay, prefix+"-base-delay", l.BaseDelay,
+		fmt.Sprintf("The delay before the first retry of %s, which doubles with every failed retry.", purpose))
+	fs.DurationVar(&l.MaxDelay, prefix+"-max-delay", l.MaxDelay,
+		fmt.Sprintf("The maximum delay between the retries of %s.", purpose))
+	fs.Float64Var(&l.QPS, prefix+"-qps", l.QPS,
+		fmt.Sprintf("The maximum retries per second of all %s together.", purpose))
+	fs.IntVar(&l.Burst, prefix+"-burst", l.Burst,
+		fmt.Sprintf("The maximum burst of retries of all %s together.", purpose))
+}
+
+// Validate returns an error when l cannot limit retries, because one of its
+// values is not positive or MaxDelay is shorter than BaseDelay.
+func (l QueueRateLimit) Validate() error {
+	switch {
+	case l.BaseDelay <= 0 || l.MaxDelay <= 0:
+		return errors.New("queue base and max delays must be positive")
+	case l.MaxDelay < l.BaseDelay:
+		return fmt.Errorf("queue max delay %s must not be shorter than its base delay %s", l.MaxDelay, l.BaseDelay)
+	case l.QPS <= 0 || l.Burst <= 0:
+		return errors.New("queue qps and burst must be positive")
+	}
+	return nil
+}
+
+// RateLimiter returns a workqueue rate limiter that is subject to l, or nil
+// when l is entirely unset, so that controllers keep their default rate
+// limiter.
+func (l QueueRateLimit) RateLimiter() workqueue.RateLimiter {
+	if l == (QueueRateLimit{}) {
+		return nil
+	}
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(l.BaseDelay, l.MaxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(l.QPS), l.Burst)},
+	)
+}
+
+type float32Value float32
+
+func (v *float32Value) String() string {
+	return strconv.FormatFloat(float64(*v), 'g', -1, 32)
+}
+
+func (v *float32Value) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return err
+	}
+	*v = float32Value(f)
+	return nil
+}
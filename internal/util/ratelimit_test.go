@@ -0,0 +1,100 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+func TestRateLimitedConfig(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		limit       ClientRateLimit
+		qps         float32
+		burst       int
+		shared      bool
+	}{
+		{
+			description: "unset limit - config unchanged",
+			limit:       ClientRateLimit{},
+			qps:         20,
+			burst:       30,
+			shared:      false,
+		},
+		{
+			description: "qps only - burst unchanged",
+			limit:       ClientRateLimit{QPS: 50},
+			qps:         50,
+			burst:       30,
+			shared:      true,
+		},
+		{
+			description: "qps and burst",
+			limit:       ClientRateLimit{QPS: 50, Burst: 100},
+			qps:         50,
+			burst:       100,
+			shared:      true,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			cfg := RateLimitedConfig(&rest.Config{QPS: 20, Burst: 30}, tt.limit)
+			require.Equal(t, tt.qps, cfg.QPS)
+			require.Equal(t, tt.burst, cfg.Burst)
+			require.Equal(t, tt.shared, cfg.RateLimiter != nil)
+			if tt.shared {
+				require.Equal(t, tt.qps, cfg.RateLimiter.QPS())
+			}
+		})
+	}
+}
+
+func TestQueueRateLimit(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		limit       QueueRateLimit
+		wantErr     string
+		delays      []time.Duration
+	}{
+		{
+			description: "default limit",
+			limit:       DefaultQueueRateLimit(),
+			delays:      []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond},
+		},
+		{
+			description: "capped backoff",
+			limit:       QueueRateLimit{BaseDelay: time.Second, MaxDelay: 3 * time.Second, QPS: 10, Burst: 100},
+			delays:      []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			description: "zero base delay",
+			limit:       QueueRateLimit{MaxDelay: time.Second, QPS: 10, Burst: 100},
+			wantErr:     "queue base and max delays must be positive",
+		},
+		{
+			description: "max delay shorter than base delay",
+			limit:       QueueRateLimit{BaseDelay: time.Minute, MaxDelay: time.Second, QPS: 10, Burst: 100},
+			wantErr:     "queue max delay 1s must not be shorter than its base delay 1m0s",
+		},
+		{
+			description: "zero burst",
+			limit:       QueueRateLimit{BaseDelay: time.Second, MaxDelay: time.Minute, QPS: 10},
+			wantErr:     "queue qps and burst must be positive",
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			err := tt.limit.Validate()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			limiter := tt.limit.RateLimiter()
+			for _, delay := range tt.delays {
+				require.Equal(t, delay, limiter.When("item"))
+			}
+		})
+	}
+	require.Nil(t, QueueRateLimit{}.RateLimiter())
+}
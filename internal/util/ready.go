@@ -0,0 +1,64 @@
+package util
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/rukpak/pkg/status"
+)
+
+// ReadyCondition returns the Ready condition of obj, which aggregates its
+// conditions: it is False while any condition of blocking is True, and
+// otherwise has the status of the first condition of required that is False
+// or Unknown. A required condition that is missing, or that was last observed
+// for an earlier generation of obj, makes it Unknown. It is True once every
+// required condition is True for the current generation of obj, and its
+// message names the condition that keeps obj from being ready otherwise.
+func ReadyCondition(obj metav1.Object, conditions []metav1.Condition, required, blocking []string) metav1.Condition {
+	if cond := UnreadyCondition(conditions, required, blocking); cond != nil {
+		readyStatus := cond.Status
+		if readyStatus == metav1.ConditionTrue {
+			readyStatus = metav1.ConditionFalse
+		}
+		return status.For(obj).Ready(readyStatus, status.NotReady, conditionSummary(cond))
+	}
+	for _, t := range required {
+		cond := meta.FindStatusCondition(conditions, t)
+		switch {
+		case cond == nil:
+			return status.For(obj).Ready(metav1.ConditionUnknown, status.NotReady, fmt.Sprintf("%s is not reported yet", t))
+		case cond.ObservedGeneration < obj.GetGeneration():
+			return status.For(obj).Ready(metav1.ConditionUnknown, status.NotReady, fmt.Sprintf("%s was last observed for generation %d", t, cond.ObservedGeneration))
+		}
+	}
+	return status.For(obj).Ready(metav1.ConditionTrue, status.Ready, "")
+}
+
+// UnreadyCondition returns the condition of conditions that keeps their
+// object from being ready, as ReadyCondition determines it: the first
+// condition of blocking that is True, or else the first condition of required
+// that is False or Unknown. It returns nil when there is none, even if
+// required conditions are missing or stale.
+func UnreadyCondition(conditions []metav1.Condition, required, blocking []string) *metav1.Condition {
+	for _, t := range blocking {
+		if cond := meta.FindStatusCondition(conditions, t); cond != nil && cond.Status == metav1.ConditionTrue {
+			return cond
+		}
+	}
+	for _, t := range required {
+		if cond := meta.FindStatusCondition(conditions, t); cond != nil && cond.Status != metav1.ConditionTrue {
+			return cond
+		}
+	}
+	return nil
+}
+
+// conditionSummary describes the status of cond, with its reason and message.
+func conditionSummary(cond *metav1.Condition) string {
+	if cond.Message == "" {
+		return fmt.Sprintf("%s is %s: %s", cond.Type, cond.Status, cond.Reason)
+	}
+	return fmt.Sprintf("%s is %s: %s: %s", cond.Type, cond.Status, cond.Reason, cond.Message)
+}
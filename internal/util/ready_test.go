@@ -0,0 +1,91 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestReadyCondition(t *testing.T) {
+	bi := &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 2}}
+	condition := func(conditionType string, status metav1.ConditionStatus, reason string, generation int64) metav1.Condition {
+		return metav1.Condition{Type: conditionType, Status: status, Reason: reason, ObservedGeneration: generation}
+	}
+	installed := condition(rukpakv1alpha1.TypeInstalled, metav1.ConditionTrue, rukpakv1alpha1.ReasonInstallationSucceeded, 2)
+	healthy := condition(rukpakv1alpha1.TypeHealthy, metav1.ConditionTrue, rukpakv1alpha1.ReasonHealthy, 2)
+
+	for _, tt := range []struct {
+		name        string
+		conditions  []metav1.Condition
+		wantStatus  metav1.ConditionStatus
+		wantMessage string
+	}{
+		{
+			name:       "all true",
+			conditions: []metav1.Condition{installed, healthy},
+			wantStatus: metav1.ConditionTrue,
+		},
+		{
+			name: "required false",
+			conditions: []metav1.Condition{
+				installed,
+				{Type: rukpakv1alpha1.TypeHealthy, Status: metav1.ConditionFalse, Reason: rukpakv1alpha1.ReasonUnhealthy, Message: "Deployment is unavailable", ObservedGeneration: 2},
+			},
+			wantStatus:  metav1.ConditionFalse,
+			wantMessage: "Healthy is False: Unhealthy: Deployment is unavailable",
+		},
+		{
+			name:        "required unknown",
+			conditions:  []metav1.Condition{installed, condition(rukpakv1alpha1.TypeHealthy, metav1.ConditionUnknown, rukpakv1alpha1.ReasonHealthCheckFailed, 2)},
+			wantStatus:  metav1.ConditionUnknown,
+			wantMessage: "Healthy is Unknown: HealthCheckFailed",
+		},
+		{
+			name:        "required missing",
+			conditions:  []metav1.Condition{healthy},
+			wantStatus:  metav1.ConditionUnknown,
+			wantMessage: "Installed is not reported yet",
+		},
+		{
+			name:        "false before missing",
+			conditions:  []metav1.Condition{condition(rukpakv1alpha1.TypeHealthy, metav1.ConditionFalse, rukpakv1alpha1.ReasonUnhealthy, 2)},
+			wantStatus:  metav1.ConditionFalse,
+			wantMessage: "Healthy is False: Unhealthy",
+		},
+		{
+			name:        "stale",
+			conditions:  []metav1.Condition{condition(rukpakv1alpha1.TypeInstalled, metav1.ConditionTrue, rukpakv1alpha1.ReasonInstallationSucceeded, 1), healthy},
+			wantStatus:  metav1.ConditionUnknown,
+			wantMessage: "Installed was last observed for generation 1",
+		},
+		{
+			name:        "blocking",
+			conditions:  []metav1.Condition{installed, healthy, condition(rukpakv1alpha1.TypePivoting, metav1.ConditionTrue, rukpakv1alpha1.ReasonPivotInProgress, 2)},
+			wantStatus:  metav1.ConditionFalse,
+			wantMessage: "Pivoting is True: PivotInProgress",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cond := ReadyCondition(bi, tt.conditions, []string{rukpakv1alpha1.TypeInstalled, rukpakv1alpha1.TypeHealthy}, []string{rukpakv1alpha1.TypePivoting})
+			require.Equal(t, rukpakv1alpha1.TypeReady, cond.Type)
+			require.Equal(t, tt.wantStatus, cond.Status)
+			require.Equal(t, tt.wantMessage, cond.Message)
+			require.Equal(t, int64(2), cond.ObservedGeneration)
+		})
+	}
+}
+
+func TestUnreadyCondition(t *testing.T) {
+	installed := metav1.Condition{Type: rukpakv1alpha1.TypeInstalled, Status: metav1.ConditionTrue}
+	unhealthy := metav1.Condition{Type: rukpakv1alpha1.TypeHealthy, Status: metav1.ConditionFalse, Reason: rukpakv1alpha1.ReasonUnhealthy}
+	pivoting := metav1.Condition{Type: rukpakv1alpha1.TypePivoting, Status: metav1.ConditionTrue, Reason: rukpakv1alpha1.ReasonPivotInProgress}
+	required := []string{rukpakv1alpha1.TypeInstalled, rukpakv1alpha1.TypeHealthy}
+	blocking := []string{rukpakv1alpha1.TypePivoting}
+
+	require.Nil(t, UnreadyCondition([]metav1.Condition{installed}, required, blocking))
+	require.Equal(t, &unhealthy, UnreadyCondition([]metav1.Condition{installed, unhealthy}, required, blocking))
+	require.Equal(t, &pivoting, UnreadyCondition([]metav1.Condition{installed, unhealthy, pivoting}, required, blocking))
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+// maxTemplateBundleNamePrefix is the length to which the name of a
+// BundleInstance is truncated in the names of the Bundles of its template, so
+// that they stay within the maximum length of Bundle names.
+const maxTemplateBundleNamePrefix = 29
+
+// TemplateBundle returns the Bundle declared by the template of bi, whose
+// provisioner class defaults to the one of bi. It returns nil when bi has no
+// template.
+func TemplateBundle(bi *rukpakv1alpha1.BundleInstance) *rukpakv1alpha1.Bundle {
+	if bi.Spec.Template == nil {
+		return nil
+	}
+	template := bi.Spec.Template.DeepCopy()
+	if template.Spec.ProvisionerClassName == "" {
+		template.Spec.ProvisionerClassName = bi.Spec.ProvisionerClassName
+	}
+	b := &rukpakv1alpha1.Bundle{Spec: template.Spec}
+	b.Labels = template.Metadata.Labels
+	b.Annotations = template.Metadata.Annotations
+
+	// The name of the Bundle depends on the template, so that every change of
+	// the template rolls the BundleInstance over to a new Bundle.
+	data, _ := json.Marshal(template)
+	prefix := bi.Name
+	if len(prefix) > maxTemplateBundleNamePrefix {
+		prefix = strings.TrimRight(prefix[:maxTemplateBundleNamePrefix], "-.")
+	}
+	b.Name = fmt.Sprintf("%s-%x", prefix, sha256.Sum256(data))[:len(prefix)+11]
+	return b
+}
+
+// DesiredBundleName returns the name of the Bundle that bi installs: the
+// Bundle of its template, if any, or the Bundle it references by name.
+func DesiredBundleName(bi *rukpakv1alpha1.BundleInstance) string {
+	if b := TemplateBundle(bi); b != nil {
+		return b.Name
+	}
+	return bi.Spec.BundleName
+}
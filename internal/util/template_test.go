@@ -0,0 +1,54 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestTemplateBundle(t *testing.T) {
+	bundleInstance := func(name, ref string) *rukpakv1alpha1.BundleInstance {
+		return &rukpakv1alpha1.BundleInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: rukpakv1alpha1.BundleInstanceSpec{
+				ProvisionerClassName: "core.rukpak.io/plain",
+				Template: &rukpakv1alpha1.BundleTemplate{
+					Metadata: rukpakv1alpha1.BundleTemplateMetadata{Labels: map[string]string{"app": "test"}},
+					Spec: rukpakv1alpha1.BundleSpec{Source: rukpakv1alpha1.BundleSource{
+						Type:  rukpakv1alpha1.SourceTypeImage,
+						Image: &rukpakv1alpha1.ImageSource{Ref: ref},
+					}},
+				},
+			},
+		}
+	}
+
+	t.Run("no template", func(t *testing.T) {
+		bi := &rukpakv1alpha1.BundleInstance{Spec: rukpakv1alpha1.BundleInstanceSpec{BundleName: "test"}}
+		require.Nil(t, TemplateBundle(bi))
+		require.Equal(t, "test", DesiredBundleName(bi))
+	})
+	t.Run("template", func(t *testing.T) {
+		bi := bundleInstance("test", "quay.io/a/b:v1")
+		b := TemplateBundle(bi)
+		require.Regexp(t, `^test-[0-9a-f]{10}$`, b.Name)
+		require.Equal(t, b.Name, DesiredBundleName(bi))
+		require.Equal(t, "core.rukpak.io/plain", b.Spec.ProvisionerClassName)
+		require.Equal(t, map[string]string{"app": "test"}, b.Labels)
+		require.Equal(t, b.Name, TemplateBundle(bundleInstance("test", "quay.io/a/b:v1")).Name)
+		require.NotEqual(t, b.Name, TemplateBundle(bundleInstance("test", "quay.io/a/b:v2")).Name)
+	})
+	t.Run("explicit provisioner class", func(t *testing.T) {
+		bi := bundleInstance("test", "quay.io/a/b:v1")
+		bi.Spec.Template.Spec.ProvisionerClassName = "core.rukpak.io/plain"
+		require.Equal(t, TemplateBundle(bundleInstance("test", "quay.io/a/b:v1")).Name, TemplateBundle(bi).Name)
+	})
+	t.Run("long name", func(t *testing.T) {
+		b := TemplateBundle(bundleInstance(strings.Repeat("a", 28)+"-"+strings.Repeat("b", 30), "quay.io/a/b:v1"))
+		require.Regexp(t, `^a{28}-[0-9a-f]{10}$`, b.Name)
+	})
+}
@@ -16,14 +16,29 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// GetRukpakConfig returns the spec of the cluster-wide RukpakConfig, or an
+// empty spec when no RukpakConfig has been created.
+func GetRukpakConfig(ctx context.Context, cl client.Reader) (rukpakv1alpha1.RukpakConfigSpec, error) {
+	config := &rukpakv1alpha1.RukpakConfig{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: rukpakv1alpha1.RukpakConfigName}, config); err != nil {
+		if apierrors.IsNotFound(err) {
+			return rukpakv1alpha1.RukpakConfigSpec{}, nil
+		}
+		return rukpakv1alpha1.RukpakConfigSpec{}, fmt.Errorf("get rukpak config: %w", err)
+	}
+	return config.Spec, nil
+}
+
 func BundleProvisionerFilter(provisionerClassName string) predicate.Predicate {
 	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
 		b := obj.(*rukpakv1alpha1.Bundle)
@@ -31,6 +46,15 @@ func BundleProvisionerFilter(provisionerClassName string) predicate.Predicate {
 	})
 }
 
+// ProvisionerClassSelector returns the selector of the Bundles and
+// BundleInstances of provisionerClassName, as labeled by the rukpak core, with
+// which caches can filter them server-side.
+func ProvisionerClassSelector(provisionerClassName string) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{
+		rukpakv1alpha1.LabelProvisionerClass: rukpakv1alpha1.ProvisionerClassLabelValue(provisionerClassName),
+	})
+}
+
 func BundleInstanceProvisionerFilter(provisionerClassName string) predicate.Predicate {
 	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
 		b := obj.(*rukpakv1alpha1.BundleInstance)
@@ -38,26 +62,117 @@ func BundleInstanceProvisionerFilter(provisionerClassName string) predicate.Pred
 	})
 }
 
+// MatchesSelector returns whether the labels of obj match selector. A nil
+// selector matches every object.
+func MatchesSelector(selector labels.Selector, obj client.Object) bool {
+	return selector == nil || selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// SelectorFilter returns a predicate that accepts the objects whose labels
+// match selector, with which several instances of a provisioner shard its
+// Bundles and BundleInstances between them.
+func SelectorFilter(selector labels.Selector) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return MatchesSelector(selector, obj)
+	})
+}
+
+// BundleInstanceBundleNameIndexKey is the name of the field index of
+// BundleInstances by the name of the Bundle they reference, or of the Bundle
+// of their template.
+const BundleInstanceBundleNameIndexKey = "spec.bundleName"
+
+// IndexBundleInstanceBundleName is the client.IndexerFunc of the
+// BundleInstanceBundleNameIndexKey field index.
+func IndexBundleInstanceBundleName(obj client.Object) []string {
+	return []string{DesiredBundleName(obj.(*rukpakv1alpha1.BundleInstance))}
+}
+
+// MapBundleToBundleInstanceHandler maps a Bundle to the BundleInstances that
+// reference it. It requires the BundleInstanceBundleNameIndexKey field index
+// to be registered with the cache of cl.
 func MapBundleToBundleInstanceHandler(cl client.Client, log logr.Logger) handler.MapFunc {
 	return func(object client.Object) []reconcile.Request {
 		b := object.(*rukpakv1alpha1.Bundle)
 		bundleInstances := &rukpakv1alpha1.BundleInstanceList{}
 		var requests []reconcile.Request
-		if err := cl.List(context.Background(), bundleInstances); err != nil {
-			log.WithName("mapBundleToBundleInstanceHandler").Error(err, "list bundles")
+		if err := cl.List(context.Background(), bundleInstances, client.MatchingFields{BundleInstanceBundleNameIndexKey: b.Name}); err != nil {
+			log.WithName("mapBundleToBundleInstanceHandler").Error(err, "list bundleinstances")
 			return requests
 		}
 		for _, bi := range bundleInstances.Items {
 			bi := bi
-			if bi.Spec.BundleName == b.Name {
-				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&bi)})
-			}
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&bi)})
+		}
+		if len(requests) > 0 {
+			log.WithName("mapBundleToBundleInstanceHandler").V(LogLevelDebug).Info("enqueuing bundleinstances for bundle",
+				LogKeyBundle, b.Name, LogKeyDigest, b.Status.Digest, "count", len(requests))
 		}
 		return requests
 	}
 }
 
-// GetPodNamespace checks whether the controller is running in a Pod vs.
+// MapOwnerNameToBundleInstance maps the objects installed by BundleInstances
+// to the BundleInstance named by their core.rukpak.io/owner-name label, so
+// that each BundleInstance is only triggered by its own objects when several
+// BundleInstances install the same Bundle.
+func MapOwnerNameToBundleInstance() handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		labels := object.GetLabels()
+		if labels["core.rukpak.io/owner-kind"] != "BundleInstance" || labels["core.rukpak.io/owner-name"] == "" {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: labels["core.rukpak.io/owner-name"]}}}
+	}
+}
+
+// BundleContentChangedPredicate filters out updates of Bundles that do not
+// change their unpack phase or the digest of their unpacked content, so that
+// a re-unpack of a Bundle promptly retriggers the BundleInstances that
+// reference it, while unrelated status updates do not.
+func BundleContentChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldBundle, newBundle := e.ObjectOld.(*rukpakv1alpha1.Bundle), e.ObjectNew.(*rukpakv1alpha1.Bundle)
+			return oldBundle.Status.Digest != newBundle.Status.Digest || oldBundle.Status.Phase != newBundle.Status.Phase
+		},
+	}
+}
+
+// IgnoreStatusUpdatesPredicate filters out the update events of objects that
+// only change their status, such as those of the status patches of their own
+// reconciler, so that changes to status fields that are set on every reconcile
+// do not trigger another reconcile.
+func IgnoreStatusUpdatesPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o, n := e.ObjectOld, e.ObjectNew
+			return o.GetGeneration() != n.GetGeneration() ||
+				!reflect.DeepEqual(o.GetLabels(), n.GetLabels()) ||
+				!reflect.DeepEqual(o.GetAnnotations(), n.GetAnnotations()) ||
+				!reflect.DeepEqual(o.GetFinalizers(), n.GetFinalizers()) ||
+				!reflect.DeepEqual(o.GetOwnerReferences(), n.GetOwnerReferences()) ||
+				!o.GetDeletionTimestamp().Equal(n.GetDeletionTimestamp())
+		},
+	}
+}
+
+// ObserveGeneration sets generation as the observed generation of the
+// conditions of conditionTypes, for conditions that were set in an earlier
+// reconcile and that the reconcile of generation has found to still hold.
+// Conditions of other types keep their observed generation, so that they can
+// be told apart as stale.
+func ObserveGeneration(conditions []metav1.Condition, generation int64, conditionTypes ...string) {
+	for i := range conditions {
+		for _, conditionType := range conditionTypes {
+			if conditions[i].Type == conditionType {
+				conditions[i].ObservedGeneration = generation
+			}
+		}
+	}
+}
+
+// PodNamespace checks whether the controller is running in a Pod vs.
 // being run locally by inspecting the namespace file that gets mounted
 // automatically for Pods at runtime. If that file doesn't exist, then
 // return the @defaultNamespace namespace parameter.
@@ -0,0 +1,171 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestGetRukpakConfig(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, rukpakv1alpha1.AddToScheme(scheme))
+
+	spec, err := GetRukpakConfig(context.Background(), fake.NewClientBuilder().WithScheme(scheme).Build())
+	require.NoError(t, err)
+	require.Equal(t, rukpakv1alpha1.RukpakConfigSpec{}, spec)
+
+	resync := &metav1.Duration{Duration: time.Minute}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&rukpakv1alpha1.RukpakConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: rukpakv1alpha1.RukpakConfigName},
+			Spec:       rukpakv1alpha1.RukpakConfigSpec{ResyncInterval: resync},
+		},
+		&rukpakv1alpha1.RukpakConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "ignored"},
+			Spec:       rukpakv1alpha1.RukpakConfigSpec{ResyncInterval: &metav1.Duration{Duration: time.Hour}},
+		},
+	).Build()
+	spec, err = GetRukpakConfig(context.Background(), cl)
+	require.NoError(t, err)
+	require.Equal(t, resync, spec.ResyncInterval)
+}
+
+func TestIgnoreStatusUpdatesPredicate(t *testing.T) {
+	now := metav1.Now()
+	base := &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 1}}
+	for _, tt := range []struct {
+		name   string
+		mutate func(bi *rukpakv1alpha1.BundleInstance)
+		want   bool
+	}{
+		{
+			name: "status",
+			mutate: func(bi *rukpakv1alpha1.BundleInstance) {
+				bi.ResourceVersion = "2"
+				bi.Status.LastAttemptedAt = &now
+			},
+			want: false,
+		},
+		{
+			name:   "generation",
+			mutate: func(bi *rukpakv1alpha1.BundleInstance) { bi.Generation = 2 },
+			want:   true,
+		},
+		{
+			name:   "labels",
+			mutate: func(bi *rukpakv1alpha1.BundleInstance) { bi.Labels = map[string]string{"a": "b"} },
+			want:   true,
+		},
+		{
+			name:   "finalizers",
+			mutate: func(bi *rukpakv1alpha1.BundleInstance) { bi.Finalizers = []string{"f"} },
+			want:   true,
+		},
+		{
+			name:   "deletion",
+			mutate: func(bi *rukpakv1alpha1.BundleInstance) { bi.DeletionTimestamp = &now },
+			want:   true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := base.DeepCopy()
+			tt.mutate(updated)
+			require.Equal(t, tt.want, IgnoreStatusUpdatesPredicate().Update(event.UpdateEvent{ObjectOld: base, ObjectNew: updated}))
+		})
+	}
+}
+
+func TestSelectorFilter(t *testing.T) {
+	shardA, err := labels.Parse("shard=a")
+	require.NoError(t, err)
+	for _, tt := range []struct {
+		name     string
+		selector labels.Selector
+		labels   map[string]string
+		want     bool
+	}{
+		{
+			name:   "nil selector",
+			labels: map[string]string{"shard": "b"},
+			want:   true,
+		},
+		{
+			name:     "everything",
+			selector: labels.Everything(),
+			want:     true,
+		},
+		{
+			name:     "matching",
+			selector: shardA,
+			labels:   map[string]string{"shard": "a"},
+			want:     true,
+		},
+		{
+			name:     "other shard",
+			selector: shardA,
+			labels:   map[string]string{"shard": "b"},
+		},
+		{
+			name:     "unlabeled",
+			selector: shardA,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &rukpakv1alpha1.Bundle{ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: tt.labels}}
+			require.Equal(t, tt.want, MatchesSelector(tt.selector, b))
+			require.Equal(t, tt.want, SelectorFilter(tt.selector).Create(event.CreateEvent{Object: b}))
+		})
+	}
+}
+
+func TestMapOwnerNameToBundleInstance(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		labels map[string]string
+		want   []reconcile.Request
+	}{
+		{
+			name:   "installed by a bundleinstance",
+			labels: map[string]string{"core.rukpak.io/owner-kind": "BundleInstance", "core.rukpak.io/owner-name": "a"},
+			want:   []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "a"}}},
+		},
+		{
+			name:   "stored by a bundle",
+			labels: map[string]string{"core.rukpak.io/owner-kind": "Bundle", "core.rukpak.io/owner-name": "a"},
+		},
+		{
+			name: "unlabeled",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: tt.labels}}
+			require.Equal(t, tt.want, MapOwnerNameToBundleInstance()(obj))
+		})
+	}
+}
+
+func TestObserveGeneration(t *testing.T) {
+	conditions := []metav1.Condition{
+		{Type: rukpakv1alpha1.TypeInstalled, ObservedGeneration: 3},
+		{Type: rukpakv1alpha1.TypePivoting, ObservedGeneration: 1},
+		{Type: rukpakv1alpha1.TypeHasValidBundle, ObservedGeneration: 2},
+	}
+	ObserveGeneration(conditions, 3, rukpakv1alpha1.TypePivoting, rukpakv1alpha1.TypePreflightSucceeded)
+	require.Equal(t, []metav1.Condition{
+		{Type: rukpakv1alpha1.TypeInstalled, ObservedGeneration: 3},
+		{Type: rukpakv1alpha1.TypePivoting, ObservedGeneration: 3},
+		{Type: rukpakv1alpha1.TypeHasValidBundle, ObservedGeneration: 2},
+	}, conditions)
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"sync"
+
+	"k8s.io/client-go/rest"
+)
+
+// WarningCollector is a rest.WarningHandler that collects the warnings that
+// the API server returns, such as deprecation notices and the warnings of
+// admission webhooks, instead of logging them.
+type WarningCollector struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+var _ rest.WarningHandler = &WarningCollector{}
+
+// HandleWarningHeader collects the text of warnings with the 299 warning
+// code. Warnings that were already collected are ignored.
+func (c *WarningCollector) HandleWarningHeader(code int, _ string, text string) {
+	if code != 299 || text == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if contains(c.warnings, text) {
+		return
+	}
+	c.warnings = append(c.warnings, text)
+}
+
+// Warnings returns the collected warnings in the order they were returned.
+func (c *WarningCollector) Warnings() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.warnings...)
+}
@@ -0,0 +1,22 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarningCollector(t *testing.T) {
+	c := &WarningCollector{}
+	require.Empty(t, c.Warnings())
+
+	c.HandleWarningHeader(299, "", "policy/v1beta1 PodDisruptionBudget is deprecated in v1.21+, unavailable in v1.25+")
+	c.HandleWarningHeader(299, "", "")
+	c.HandleWarningHeader(199, "", "miscellaneous warning")
+	c.HandleWarningHeader(299, "", "admission webhook warning")
+	c.HandleWarningHeader(299, "", "policy/v1beta1 PodDisruptionBudget is deprecated in v1.21+, unavailable in v1.25+")
+	require.Equal(t, []string{
+		"policy/v1beta1 PodDisruptionBudget is deprecated in v1.21+, unavailable in v1.25+",
+		"admission webhook warning",
+	}, c.Warnings())
+}
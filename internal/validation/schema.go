@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/kube-openapi/pkg/util/proto/validation"
+	"k8s.io/kubectl/pkg/util/openapi"
+)
+
+// Schemas returns a Validator that reports the objects that do not match the
+// OpenAPI schemas of their kinds served by source, such as the OpenAPI
+// schemas of a cluster. The schemas are retrieved on every validation, so that
+// the schemas of newly installed CRDs are validated against. Objects of kinds
+// without a schema, such as the kinds of the CRDs of the bundle, are not
+// validated.
+func Schemas(source discovery.OpenAPISchemaInterface) Validator {
+	return ValidatorFunc(func(_ context.Context, files []File) ([]Error, error) {
+		doc, err := source.OpenAPISchema()
+		if err != nil {
+			return nil, fmt.Errorf("get openapi schemas: %w", err)
+		}
+		resources, err := openapi.NewOpenAPIData(doc)
+		if err != nil {
+			return nil, fmt.Errorf("parse openapi schemas: %w", err)
+		}
+		var errs []Error
+		for _, f := range files {
+			for _, obj := range f.Objects {
+				gvk := obj.GroupVersionKind()
+				s := resources.LookupResource(gvk)
+				if s == nil {
+					continue
+				}
+				for _, err := range validation.ValidateModel(obj.Object, s, gvk.Kind) {
+					errs = append(errs, Error{File: f.Name, Object: objectName(obj), Message: err.Error()})
+				}
+			}
+		}
+		return errs, nil
+	})
+}
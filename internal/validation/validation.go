@@ -0,0 +1,206 @@
+// Package validation validates the manifests of plain bundles with pluggable
+// validators, both when the plain provisioner unpacks a bundle and offline,
+// with rukpakctl, before a bundle is published.
+package validation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apimachyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/rukpak/internal/manifests"
+)
+
+// File is a manifest file of a bundle and the objects that it contains.
+type File struct {
+	// Name is the path of the file relative to the manifests directory of the
+	// bundle, such as "crds/widgets.yaml".
+	Name string
+	// Objects are the objects of the file, in the order in which they appear
+	// in it.
+	Objects []*unstructured.Unstructured
+}
+
+// Error is an error found in a manifest file of a bundle.
+type Error struct {
+	// File is the name of the manifest file with the error.
+	File string
+	// Object identifies the object of the file with the error, such as
+	// "Deployment my-namespace/my-deployment". It is empty for errors that
+	// concern the file as a whole.
+	Object string
+	// Message describes the error.
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Object == "" {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.File, e.Object, e.Message)
+}
+
+// Errors are the errors found in the manifest files of a bundle, ordered by
+// file.
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("invalid bundle content: %s", strings.Join(messages, "; "))
+}
+
+// Validator validates the objects of the manifest files of a bundle.
+type Validator interface {
+	// Validate returns the errors found in files. An error is only returned
+	// when the validation itself failed, for example because the cluster the
+	// objects are validated against could not be reached.
+	Validate(ctx context.Context, files []File) ([]Error, error)
+}
+
+// ValidatorFunc is a Validator implemented by a function.
+type ValidatorFunc func(ctx context.Context, files []File) ([]Error, error)
+
+// Validate implements Validator.
+func (f ValidatorFunc) Validate(ctx context.Context, files []File) ([]Error, error) {
+	return f(ctx, files)
+}
+
+// Options configure the validators of bundle manifests.
+type Options struct {
+	// DisallowedKinds are the kinds of objects that bundles must not contain.
+	DisallowedKinds []schema.GroupKind
+	// Mapper maps kinds to their scopes, so that the namespaces of objects
+	// of cluster-scoped and namespaced kinds are checked. Without a Mapper,
+	// only the namespaces of objects of kinds defined by the CRDs of the
+	// bundle are checked.
+	Mapper meta.RESTMapper
+	// Schemas serves the OpenAPI schemas that objects are validated against.
+	// Without Schemas, objects are not validated against schemas.
+	Schemas discovery.OpenAPISchemaInterface
+}
+
+// ParseKinds parses a comma-separated list of kinds qualified by their
+// groups, such as "ClusterRoleBinding.rbac.authorization.k8s.io,Namespace",
+// for the DisallowedKinds of Options.
+func ParseKinds(s string) []schema.GroupKind {
+	var kinds []schema.GroupKind
+	for _, kind := range strings.Split(s, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds = append(kinds, schema.ParseGroupKind(kind))
+		}
+	}
+	return kinds
+}
+
+// Validators returns the validators configured by o, which detect duplicate
+// objects and inconsistent namespaces, along with disallowed kinds and
+// objects that do not match their schemas when o configures them.
+func (o Options) Validators() []Validator {
+	validators := []Validator{Duplicates(), Namespaces(o.Mapper)}
+	if len(o.DisallowedKinds) > 0 {
+		validators = append(validators, DisallowedKinds(o.DisallowedKinds...))
+	}
+	if o.Schemas != nil {
+		validators = append(validators, Schemas(o.Schemas))
+	}
+	return validators
+}
+
+// Validate parses the manifest files of the bundle in fsys, validates their
+// objects with validators, and returns the objects in the order of their
+// files. The manifests are not verified against their checksums.
+//
+// Files that cannot be parsed are reported along with the errors found by
+// validators, which only validate the files that could be parsed. All of
+// those are returned as Errors.
+func Validate(ctx context.Context, fsys fs.FS, validators ...Validator) ([]client.Object, error) {
+	files, errs, err := parse(fsys)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range validators {
+		found, err := v.Validate(ctx, files)
+		if err != nil {
+			return nil, fmt.Errorf("validate bundle manifests: %w", err)
+		}
+		errs = append(errs, found...)
+	}
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].File < errs[j].File })
+		return nil, errs
+	}
+	var objects []client.Object
+	for _, f := range files {
+		for _, obj := range f.Objects {
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+// parse returns the files of the manifests directory of the bundle in fsys,
+// including those of its nested directories, that could be parsed, and the
+// errors of those that could not.
+func parse(fsys fs.FS) ([]File, Errors, error) {
+	names, err := manifests.Files(fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []File
+	var errs Errors
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, path.Join(manifests.Dir, name))
+		if err != nil {
+			return nil, nil, err
+		}
+		objects, err := decode(data)
+		if err != nil {
+			errs = append(errs, Error{File: name, Message: err.Error()})
+			continue
+		}
+		files = append(files, File{Name: name, Objects: objects})
+	}
+	return files, errs, nil
+}
+
+// decode returns the objects of the YAML or JSON documents in data.
+func decode(data []byte) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	dec := apimachyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 1024)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := dec.Decode(obj)
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+}
+
+// objectName identifies obj in errors by its kind, namespace and name, such
+// as "Deployment my-namespace/my-deployment".
+func objectName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
@@ -0,0 +1,191 @@
+package validation
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	openapi_v2 "github.com/googleapis/gnostic/openapiv2"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const namespace = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: a
+`
+
+const configMaps = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+  namespace: a
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+`
+
+const widgetCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  scope: Cluster
+  names:
+    kind: Widget
+    plural: widgets
+  versions:
+  - name: v1
+    served: true
+    storage: true
+`
+
+// configMapSchema is an OpenAPI document with the schema of ConfigMaps.
+const configMapSchema = `swagger: "2.0"
+info:
+  title: Kubernetes
+  version: v1.23.0
+paths: {}
+definitions:
+  io.k8s.api.core.v1.ConfigMap:
+    type: object
+    properties:
+      apiVersion:
+        type: string
+      kind:
+        type: string
+      metadata:
+        type: object
+      data:
+        type: object
+        additionalProperties:
+          type: string
+    x-kubernetes-group-version-kind:
+    - group: ""
+      version: v1
+      kind: ConfigMap
+`
+
+type schemaSource string
+
+func (s schemaSource) OpenAPISchema() (*openapi_v2.Document, error) {
+	return openapi_v2.ParseDocument([]byte(s))
+}
+
+func TestValidate(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	opts := Options{
+		DisallowedKinds: []schema.GroupKind{{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}},
+		Mapper:          mapper,
+		Schemas:         schemaSource(configMapSchema),
+	}
+
+	for _, tt := range []struct {
+		name        string
+		fsys        fstest.MapFS
+		wantObjects int
+		wantErrs    Errors
+	}{
+		{
+			name: "valid bundle",
+			fsys: fstest.MapFS{
+				"manifests/configmaps.yaml": {Data: []byte(configMaps)},
+				"manifests/crd.yaml":        {Data: []byte(widgetCRD)},
+				"manifests/namespace.yaml":  {Data: []byte(namespace)},
+				"manifests/widget.yaml":     {Data: []byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: a\n")},
+			},
+			wantObjects: 5,
+		},
+		{
+			name: "unparsable file",
+			fsys: fstest.MapFS{
+				"manifests/broken.yaml":     {Data: []byte("kind: [")},
+				"manifests/configmaps.yaml": {Data: []byte(configMaps)},
+			},
+			wantErrs: Errors{{File: "broken.yaml", Message: "error converting YAML to JSON: yaml: line 1: did not find expected node content"}},
+		},
+		{
+			name: "duplicate objects in nested directories",
+			fsys: fstest.MapFS{
+				"manifests/a/configmaps.yaml": {Data: []byte(configMaps)},
+				"manifests/b/configmaps.yaml": {Data: []byte(configMaps)},
+			},
+			wantErrs: Errors{
+				{File: "b/configmaps.yaml", Object: "ConfigMap a/a", Message: "duplicates an object of a/configmaps.yaml"},
+				{File: "b/configmaps.yaml", Object: "ConfigMap b", Message: "duplicates an object of a/configmaps.yaml"},
+			},
+		},
+		{
+			name: "duplicate objects",
+			fsys: fstest.MapFS{
+				"manifests/a.yaml": {Data: []byte(configMaps)},
+				"manifests/b.yaml": {Data: []byte(configMaps)},
+			},
+			wantErrs: Errors{
+				{File: "b.yaml", Object: "ConfigMap a/a", Message: "duplicates an object of a.yaml"},
+				{File: "b.yaml", Object: "ConfigMap b", Message: "duplicates an object of a.yaml"},
+			},
+		},
+		{
+			name: "cluster-scoped objects with a namespace",
+			fsys: fstest.MapFS{
+				"manifests/crd.yaml":       {Data: []byte(widgetCRD)},
+				"manifests/namespace.yaml": {Data: []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: a\n  namespace: b\n")},
+				"manifests/widget.yaml":    {Data: []byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: a\n  namespace: b\n")},
+			},
+			wantErrs: Errors{
+				{File: "namespace.yaml", Object: "Namespace b/a", Message: "sets a namespace but kind Namespace is cluster-scoped"},
+				{File: "widget.yaml", Object: "Widget b/a", Message: "sets a namespace but kind Widget is cluster-scoped"},
+			},
+		},
+		{
+			name: "disallowed kind",
+			fsys: fstest.MapFS{
+				"manifests/binding.yaml": {Data: []byte("apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRoleBinding\nmetadata:\n  name: admin\n")},
+			},
+			wantErrs: Errors{{File: "binding.yaml", Object: "ClusterRoleBinding admin", Message: "objects of kind ClusterRoleBinding.rbac.authorization.k8s.io are not allowed in bundles"}},
+		},
+		{
+			name: "object that does not match its schema",
+			fsys: fstest.MapFS{
+				"manifests/configmap.yaml": {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\nspec:\n  key: value\n")},
+			},
+			wantErrs: Errors{{File: "configmap.yaml", Object: "ConfigMap a", Message: `ValidationError(ConfigMap): unknown field "spec" in io.k8s.api.core.v1.ConfigMap`}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := Validate(context.Background(), tt.fsys, opts.Validators()...)
+			if tt.wantErrs != nil {
+				require.Equal(t, tt.wantErrs, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, objects, tt.wantObjects)
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	errs := Errors{
+		{File: "a.yaml", Message: "cannot be parsed"},
+		{File: "b.yaml", Object: "ConfigMap a/b", Message: "is invalid"},
+	}
+	require.EqualError(t, errs, "invalid bundle content: a.yaml: cannot be parsed; b.yaml: ConfigMap a/b: is invalid")
+}
+
+func TestParseKinds(t *testing.T) {
+	require.Equal(t, []schema.GroupKind{
+		{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"},
+		{Kind: "Namespace"},
+	}, ParseKinds("ClusterRoleBinding.rbac.authorization.k8s.io, Namespace,"))
+	require.Nil(t, ParseKinds(""))
+}
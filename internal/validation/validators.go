@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DisallowedKinds returns a Validator that reports the objects of kinds.
+func DisallowedKinds(kinds ...schema.GroupKind) Validator {
+	disallowed := make(map[schema.GroupKind]bool, len(kinds))
+	for _, gk := range kinds {
+		disallowed[gk] = true
+	}
+	return ValidatorFunc(func(_ context.Context, files []File) ([]Error, error) {
+		var errs []Error
+		for _, f := range files {
+			for _, obj := range f.Objects {
+				if gk := obj.GroupVersionKind().GroupKind(); disallowed[gk] {
+					errs = append(errs, Error{File: f.Name, Object: objectName(obj), Message: fmt.Sprintf("objects of kind %s are not allowed in bundles", gk)})
+				}
+			}
+		}
+		return errs, nil
+	})
+}
+
+// Duplicates returns a Validator that reports the objects with the same
+// group, kind, namespace and name as an object before them, in the same file
+// or in an earlier one. Objects that differ only by the version of their kind
+// are duplicates, as they are the same object of the cluster.
+func Duplicates() Validator {
+	return ValidatorFunc(func(_ context.Context, files []File) ([]Error, error) {
+		type key struct {
+			gk              schema.GroupKind
+			namespace, name string
+		}
+		seen := map[key]string{}
+		var errs []Error
+		for _, f := range files {
+			for _, obj := range f.Objects {
+				k := key{gk: obj.GroupVersionKind().GroupKind(), namespace: obj.GetNamespace(), name: obj.GetName()}
+				if first, ok := seen[k]; ok {
+					errs = append(errs, Error{File: f.Name, Object: objectName(obj), Message: fmt.Sprintf("duplicates an object of %s", first)})
+					continue
+				}
+				seen[k] = f.Name
+			}
+		}
+		return errs, nil
+	})
+}
+
+// Namespaces returns a Validator that reports the objects of cluster-scoped
+// kinds that set a namespace. The scopes of kinds are those of the CRDs of
+// the bundle, or else those of mapper when it is set. Objects of namespaced
+// kinds may leave their namespace unset, to be installed in the namespace
+// chosen by the BundleInstance, and objects of unknown kinds are not checked.
+func Namespaces(mapper meta.RESTMapper) Validator {
+	return ValidatorFunc(func(_ context.Context, files []File) ([]Error, error) {
+		clusterScoped := map[schema.GroupKind]bool{}
+		for _, f := range files {
+			for _, obj := range f.Objects {
+				if obj.GroupVersionKind().GroupKind() != apiextensionsv1.Kind("CustomResourceDefinition") {
+					continue
+				}
+				crd := &apiextensionsv1.CustomResourceDefinition{}
+				if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, crd); err != nil {
+					continue
+				}
+				clusterScoped[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = crd.Spec.Scope == apiextensionsv1.ClusterScoped
+			}
+		}
+		var errs []Error
+		for _, f := range files {
+			for _, obj := range f.Objects {
+				if obj.GetNamespace() == "" {
+					continue
+				}
+				gvk := obj.GroupVersionKind()
+				cluster, ok := clusterScoped[gvk.GroupKind()]
+				if !ok && mapper != nil {
+					if mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err == nil {
+						cluster = mapping.Scope.Name() == meta.RESTScopeNameRoot
+					}
+				}
+				if cluster {
+					errs = append(errs, Error{File: f.Name, Object: objectName(obj), Message: fmt.Sprintf("sets a namespace but kind %s is cluster-scoped", gvk.Kind)})
+				}
+			}
+		}
+		return errs, nil
+	})
+}
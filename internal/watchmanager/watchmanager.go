@@ -0,0 +1,330 @@
+package watchmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/operator-framework/rukpak/internal/util"
+)
+
+// Watcher is the subset of controller.Controller that is needed to
+// establish watches.
+type Watcher interface {
+	Watch(src source.Source, eventhandler handler.EventHandler, predicates ...predicate.Predicate) error
+}
+
+// NewCacheFunc returns a new, unstarted, cache for a single watched GVK.
+type NewCacheFunc func() (cache.Cache, error)
+
+var _ manager.Runnable = &WatchManager{}
+
+// WatchManager manages the dynamic watches of a controller. Each watched GVK
+// is backed by its own cache, which is reference-counted by the owners that
+// need it, and stopped once no owner needs it anymore. This ensures that the
+// number of informers of a long-running controller does not grow unbounded as
+// the set of installed content changes over time.
+//
+// WatchManager must be added to a manager.Manager so that the lifetime of the
+// caches is bound to the lifetime of the manager.
+type WatchManager struct {
+	watcher    Watcher
+	newCache   NewCacheFunc
+	handler    handler.EventHandler
+	predicates []predicate.Predicate
+
+	startedCh chan struct{}
+
+	mu      sync.Mutex
+	ctx     context.Context
+	watches map[schema.GroupVersionKind]*watch
+	owners  map[string]map[schema.GroupVersionKind]struct{}
+}
+
+type watch struct {
+	cache   cache.Cache
+	cancel  context.CancelFunc
+	handler *stoppableHandler
+	owners  map[string]struct{}
+}
+
+// stop stops the cache of w, and drops the events that its source still
+// delivers, so that a stopped watch leaves nothing behind in the controller
+// once its GVK is watched again.
+func (w *watch) stop() {
+	w.handler.stop()
+	w.cancel()
+}
+
+// errStopped is returned by Sync when the WatchManager is stopped.
+var errStopped = errors.New("watch manager is stopped")
+
+// New returns a WatchManager that establishes watches with the given event
+// handler and predicates on w, using caches created by newCache.
+func New(w Watcher, newCache NewCacheFunc, h handler.EventHandler, predicates ...predicate.Predicate) *WatchManager {
+	return &WatchManager{
+		watcher:    w,
+		newCache:   newCache,
+		handler:    h,
+		predicates: predicates,
+		startedCh:  make(chan struct{}),
+		watches:    map[schema.GroupVersionKind]*watch{},
+		owners:     map[string]map[schema.GroupVersionKind]struct{}{},
+	}
+}
+
+// Start implements manager.Runnable. It blocks until ctx is done, at which
+// point all watches are stopped and forgotten. The WatchManager can then be
+// started again, such as by a manager whose replica regained the leadership
+// it lost, and the watches are re-established as their owners sync them
+// again. Syncs block until the WatchManager is started.
+func (m *WatchManager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	if m.ctx != nil {
+		m.mu.Unlock()
+		return errors.New("watch manager is already started")
+	}
+	m.ctx = ctx
+	close(m.startedCh)
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for gvk, w := range m.watches {
+		w.stop()
+		delete(m.watches, gvk)
+	}
+	m.owners = map[string]map[schema.GroupVersionKind]struct{}{}
+	m.ctx = nil
+	m.startedCh = make(chan struct{})
+	return nil
+}
+
+// Sync ensures that every GVK in gvks is watched on behalf of owner, and
+// releases any GVKs that were previously watched on behalf of owner but are
+// no longer in gvks.
+func (m *WatchManager) Sync(ctx context.Context, owner string, gvks []schema.GroupVersionKind) error {
+	m.mu.Lock()
+	startedCh := m.startedCh
+	m.mu.Unlock()
+	select {
+	case <-startedCh:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	desired := make(map[schema.GroupVersionKind]struct{}, len(gvks))
+	for _, gvk := range gvks {
+		desired[gvk] = struct{}{}
+		if err := m.ensureWatch(ctx, owner, gvk); err != nil {
+			return fmt.Errorf("watch %s: %w", gvk, err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ctx == nil {
+		return errStopped
+	}
+	for gvk := range m.owners[owner] {
+		if _, ok := desired[gvk]; !ok {
+			m.release(ctx, owner, gvk)
+		}
+	}
+	if len(desired) == 0 {
+		delete(m.owners, owner)
+		return nil
+	}
+	m.owners[owner] = desired
+	return nil
+}
+
+// Release releases all GVKs that are watched on behalf of owner.
+func (m *WatchManager) Release(ctx context.Context, owner string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for gvk := range m.owners[owner] {
+		m.release(ctx, owner, gvk)
+	}
+	delete(m.owners, owner)
+}
+
+// Get retrieves obj from the cache of the watch for its GVK. obj must have
+// its GVK set, and its GVK must be watched.
+func (m *WatchManager) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	m.mu.Lock()
+	w, ok := m.watches[gvk]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%s is not watched", gvk)
+	}
+	return w.cache.Get(ctx, key, obj)
+}
+
+// Watched returns the GVKs that are currently watched.
+func (m *WatchManager) Watched() []schema.GroupVersionKind {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	gvks := make([]schema.GroupVersionKind, 0, len(m.watches))
+	for gvk := range m.watches {
+		gvks = append(gvks, gvk)
+	}
+	return gvks
+}
+
+func (m *WatchManager) release(ctx context.Context, owner string, gvk schema.GroupVersionKind) {
+	w, ok := m.watches[gvk]
+	if !ok {
+		return
+	}
+	delete(w.owners, owner)
+	if len(w.owners) == 0 {
+		w.stop()
+		delete(m.watches, gvk)
+		log.FromContext(ctx).V(util.LogLevelDebug).Info("stopped dynamic watch", util.LogKeyGVK, gvk.String())
+	}
+}
+
+// ensureWatch ensures that gvk is watched on behalf of owner. The cache of a
+// GVK that is not watched yet is started and synced without holding the lock
+// of m, so that a slow sync does not block the syncs of other owners, nor Get.
+func (m *WatchManager) ensureWatch(ctx context.Context, owner string, gvk schema.GroupVersionKind) error {
+	m.mu.Lock()
+	managerCtx := m.ctx
+	if managerCtx == nil {
+		m.mu.Unlock()
+		return errStopped
+	}
+	if w, ok := m.watches[gvk]; ok {
+		m.addOwner(w, owner, gvk)
+		m.mu.Unlock()
+		return nil
+	}
+	m.mu.Unlock()
+
+	w, err := m.startCache(managerCtx, gvk)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ctx != managerCtx {
+		w.stop()
+		return errStopped
+	}
+	// The GVK may have been watched by a concurrent sync in the meantime, in
+	// which case its watch is shared rather than replaced.
+	if existing, ok := m.watches[gvk]; ok {
+		w.stop()
+		m.addOwner(existing, owner, gvk)
+		return nil
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	if err := m.watcher.Watch(source.NewKindWithCache(u, w.cache), w.handler, m.predicates...); err != nil {
+		w.stop()
+		return err
+	}
+	log.FromContext(ctx).V(util.LogLevelDebug).Info("started dynamic watch", util.LogKeyGVK, gvk.String())
+	m.watches[gvk] = w
+	m.addOwner(w, owner, gvk)
+	return nil
+}
+
+// addOwner records that w, the watch of gvk, is needed by owner. m.mu must be
+// held.
+func (m *WatchManager) addOwner(w *watch, owner string, gvk schema.GroupVersionKind) {
+	w.owners[owner] = struct{}{}
+	if m.owners[owner] == nil {
+		m.owners[owner] = map[schema.GroupVersionKind]struct{}{}
+	}
+	m.owners[owner][gvk] = struct{}{}
+}
+
+// startCache starts a cache of gvk that is stopped along with managerCtx, and
+// waits for it to sync.
+func (m *WatchManager) startCache(managerCtx context.Context, gvk schema.GroupVersionKind) (*watch, error) {
+	c, err := m.newCache()
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+
+	ctx, cancel := context.WithCancel(managerCtx)
+	// Register the informer before starting the cache so that it is started
+	// along with the cache.
+	if _, err := c.GetInformer(ctx, u); err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		if err := c.Start(ctx); err != nil {
+			log.FromContext(managerCtx).Error(err, "dynamic watch cache failed", util.LogKeyGVK, gvk.String())
+		}
+	}()
+	if !c.WaitForCacheSync(ctx) {
+		cancel()
+		return nil, fmt.Errorf("timed out waiting for cache to sync")
+	}
+	return &watch{cache: c, cancel: cancel, handler: &stoppableHandler{EventHandler: m.handler}, owners: map[string]struct{}{}}, nil
+}
+
+// stoppableHandler delivers the events of a watch to its EventHandler until
+// the watch is stopped. The sources of a controller cannot be removed from
+// it, so the events that the source of a stopped watch still delivers, such
+// as while its informer shuts down, are dropped.
+type stoppableHandler struct {
+	handler.EventHandler
+	stopped int32
+}
+
+func (h *stoppableHandler) stop() {
+	atomic.StoreInt32(&h.stopped, 1)
+}
+
+func (h *stoppableHandler) active() bool {
+	return atomic.LoadInt32(&h.stopped) == 0
+}
+
+func (h *stoppableHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	if h.active() {
+		h.EventHandler.Create(e, q)
+	}
+}
+
+func (h *stoppableHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	if h.active() {
+		h.EventHandler.Update(e, q)
+	}
+}
+
+func (h *stoppableHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	if h.active() {
+		h.EventHandler.Delete(e, q)
+	}
+}
+
+func (h *stoppableHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	if h.active() {
+		h.EventHandler.Generic(e, q)
+	}
+}
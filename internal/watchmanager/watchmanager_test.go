@@ -0,0 +1,171 @@
+package watchmanager
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+type fakeWatcher struct {
+	mu       sync.Mutex
+	watches  int
+	handlers []handler.EventHandler
+}
+
+func (w *fakeWatcher) Watch(_ source.Source, h handler.EventHandler, _ ...predicate.Predicate) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watches++
+	w.handlers = append(w.handlers, h)
+	return nil
+}
+
+// blockingCache is a cache that does not sync until synced is closed.
+type blockingCache struct {
+	informertest.FakeInformers
+	synced chan struct{}
+}
+
+func (c *blockingCache) WaitForCacheSync(ctx context.Context) bool {
+	select {
+	case <-c.synced:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+var (
+	deploymentGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	serviceGVK    = schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+)
+
+func startWatchManager(t *testing.T) (*WatchManager, *fakeWatcher) {
+	t.Helper()
+	w := &fakeWatcher{}
+	m := New(w, func() (cache.Cache, error) { return &informertest.FakeInformers{}, nil }, &handler.EnqueueRequestForObject{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, m.Start(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return m, w
+}
+
+func TestWatchManagerSync(t *testing.T) {
+	ctx := context.Background()
+	m, w := startWatchManager(t)
+
+	require.NoError(t, m.Sync(ctx, "a", []schema.GroupVersionKind{deploymentGVK, serviceGVK}))
+	require.NoError(t, m.Sync(ctx, "b", []schema.GroupVersionKind{deploymentGVK}))
+	require.ElementsMatch(t, []schema.GroupVersionKind{deploymentGVK, serviceGVK}, m.Watched())
+	require.Equal(t, 2, w.watches, "shared GVKs should only be watched once")
+
+	require.NoError(t, m.Sync(ctx, "a", []schema.GroupVersionKind{deploymentGVK}))
+	require.ElementsMatch(t, []schema.GroupVersionKind{deploymentGVK}, m.Watched(), "unused GVKs should be released")
+
+	m.Release(ctx, "a")
+	require.ElementsMatch(t, []schema.GroupVersionKind{deploymentGVK}, m.Watched(), "GVKs still used by other owners should be kept")
+
+	m.Release(ctx, "b")
+	require.Empty(t, m.Watched())
+
+	require.NoError(t, m.Sync(ctx, "b", []schema.GroupVersionKind{deploymentGVK}))
+	require.Equal(t, 3, w.watches, "released GVKs should be watched again when needed")
+}
+
+func TestWatchManagerSyncNotStarted(t *testing.T) {
+	m := New(&fakeWatcher{}, func() (cache.Cache, error) { return &informertest.FakeInformers{}, nil }, &handler.EnqueueRequestForObject{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, m.Sync(ctx, "a", []schema.GroupVersionKind{deploymentGVK}), context.Canceled)
+}
+
+func TestWatchManagerRestart(t *testing.T) {
+	w := &fakeWatcher{}
+	m := New(w, func() (cache.Cache, error) { return &informertest.FakeInformers{}, nil }, &handler.EnqueueRequestForObject{})
+	start := func() func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			require.NoError(t, m.Start(ctx))
+		}()
+		return func() {
+			cancel()
+			<-done
+		}
+	}
+
+	stop := start()
+	require.NoError(t, m.Sync(context.Background(), "a", []schema.GroupVersionKind{deploymentGVK}))
+	stop()
+	require.Empty(t, m.Watched(), "watches should be forgotten once stopped")
+
+	stop = start()
+	defer stop()
+	require.NoError(t, m.Sync(context.Background(), "a", []schema.GroupVersionKind{deploymentGVK}))
+	require.ElementsMatch(t, []schema.GroupVersionKind{deploymentGVK}, m.Watched())
+	require.Equal(t, 2, w.watches, "watches should be re-established after a restart")
+}
+
+func TestWatchManagerSyncDoesNotBlock(t *testing.T) {
+	ctx := context.Background()
+	synced := make(chan struct{})
+	w := &fakeWatcher{}
+	m := New(w, func() (cache.Cache, error) { return &informertest.FakeInformers{}, nil }, &handler.EnqueueRequestForObject{})
+	managerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() { _ = m.Start(managerCtx) }()
+	require.NoError(t, m.Sync(ctx, "a", []schema.GroupVersionKind{serviceGVK}))
+
+	m.newCache = func() (cache.Cache, error) { return &blockingCache{synced: synced}, nil }
+	done := make(chan error, 1)
+	go func() { done <- m.Sync(ctx, "b", []schema.GroupVersionKind{deploymentGVK}) }()
+
+	// The sync of the cache of b does not hold up the other owners.
+	require.NoError(t, m.Sync(ctx, "a", []schema.GroupVersionKind{serviceGVK}))
+	m.Release(ctx, "a")
+	require.Empty(t, m.Watched())
+
+	close(synced)
+	require.NoError(t, <-done)
+	require.ElementsMatch(t, []schema.GroupVersionKind{deploymentGVK}, m.Watched())
+}
+
+func TestWatchManagerReleaseStopsEvents(t *testing.T) {
+	ctx := context.Background()
+	m, w := startWatchManager(t)
+
+	require.NoError(t, m.Sync(ctx, "a", []schema.GroupVersionKind{deploymentGVK}))
+	m.Release(ctx, "a")
+	require.NoError(t, m.Sync(ctx, "a", []schema.GroupVersionKind{deploymentGVK}))
+	require.Len(t, w.handlers, 2)
+
+	obj := &unstructured.Unstructured{}
+	obj.SetName("test")
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+	w.handlers[0].Create(event.CreateEvent{Object: obj}, q)
+	require.Equal(t, 0, q.Len(), "the source of a released watch should not deliver events")
+	w.handlers[1].Create(event.CreateEvent{Object: obj}, q)
+	require.Equal(t, 1, q.Len(), "the source of the new watch should deliver events")
+}
@@ -0,0 +1,238 @@
+// Package status builds the conditions of Bundles and BundleInstances. It is
+// used by the rukpak provisioners, and lets third-party provisioners report
+// status that is consistent with theirs: the reason of every condition has the
+// reason type of its condition type, and every condition has the observed
+// generation of its object.
+//
+// The reason types only keep a reason of one condition type from being
+// passed to the builder of another. Untyped string constants convert to any
+// of them, so reasons should be taken from the constants of this package.
+package status
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+// UnpackedReason is a reason of the Unpacked condition of a Bundle.
+type UnpackedReason string
+
+const (
+	UnpackPending               UnpackedReason = rukpakv1alpha1.ReasonUnpackPending
+	Unpacking                   UnpackedReason = rukpakv1alpha1.ReasonUnpacking
+	UnpackSuccessful            UnpackedReason = rukpakv1alpha1.ReasonUnpackSuccessful
+	UnpackFailed                UnpackedReason = rukpakv1alpha1.ReasonUnpackFailed
+	UnpackRetriesExhausted      UnpackedReason = rukpakv1alpha1.ReasonUnpackRetriesExhausted
+	UnpackTimedOut              UnpackedReason = rukpakv1alpha1.ReasonUnpackTimedOut
+	WaitingForUpload            UnpackedReason = rukpakv1alpha1.ReasonWaitingForUpload
+	UnpackVerificationFailed    UnpackedReason = rukpakv1alpha1.ReasonUnpackVerificationFailed
+	ManifestVerificationFailed  UnpackedReason = rukpakv1alpha1.ReasonManifestVerificationFailed
+	SignatureVerificationFailed UnpackedReason = rukpakv1alpha1.ReasonSignatureVerificationFailed
+	DigestRequired              UnpackedReason = rukpakv1alpha1.ReasonDigestRequired
+	UnsupportedFormat           UnpackedReason = rukpakv1alpha1.ReasonUnsupportedFormat
+	BundleTooLarge              UnpackedReason = rukpakv1alpha1.ReasonBundleTooLarge
+	InvalidBundleContent        UnpackedReason = rukpakv1alpha1.ReasonInvalidBundleContent
+	UnresolvedChartDependencies UnpackedReason = rukpakv1alpha1.ReasonUnresolvedChartDependencies
+)
+
+// HasValidBundleReason is a reason of the HasValidBundle condition of a
+// BundleInstance.
+type HasValidBundleReason string
+
+const (
+	BundleLookupFailed   HasValidBundleReason = rukpakv1alpha1.ReasonBundleLookupFailed
+	BundleLoadFailed     HasValidBundleReason = rukpakv1alpha1.ReasonBundleLoadFailed
+	BundleLoaded         HasValidBundleReason = rukpakv1alpha1.ReasonBundleLoaded
+	TemplateBundleFailed HasValidBundleReason = rukpakv1alpha1.ReasonTemplateBundleFailed
+)
+
+// InvalidBundleContentReason is a reason of the InvalidBundleContent
+// condition of a BundleInstance.
+type InvalidBundleContentReason string
+
+const (
+	ReadingContentFailed InvalidBundleContentReason = rukpakv1alpha1.ReasonReadingContentFailed
+	IntegrityCheckFailed InvalidBundleContentReason = rukpakv1alpha1.ReasonIntegrityCheckFailed
+	ValidationFailed     InvalidBundleContentReason = rukpakv1alpha1.ReasonValidationFailed
+)
+
+// InstalledReason is a reason of the Installed condition of a BundleInstance.
+type InstalledReason string
+
+const (
+	ErrorGettingClient         InstalledReason = rukpakv1alpha1.ReasonErrorGettingClient
+	ErrorGettingReleaseState   InstalledReason = rukpakv1alpha1.ReasonErrorGettingReleaseState
+	InstallFailed              InstalledReason = rukpakv1alpha1.ReasonInstallFailed
+	InstallTimedOut            InstalledReason = rukpakv1alpha1.ReasonInstallTimedOut
+	InsufficientPermissions    InstalledReason = rukpakv1alpha1.ReasonInsufficientPermissions
+	ReleaseNamespaceNotAllowed InstalledReason = rukpakv1alpha1.ReasonReleaseNamespaceNotAllowed
+	ServiceAccountNotAllowed   InstalledReason = rukpakv1alpha1.ReasonServiceAccountNotAllowed
+	UpgradeFailed              InstalledReason = rukpakv1alpha1.ReasonUpgradeFailed
+	UpgradeTimedOut            InstalledReason = rukpakv1alpha1.ReasonUpgradeTimedOut
+	ReconcileFailed            InstalledReason = rukpakv1alpha1.ReasonReconcileFailed
+	CreateDynamicWatchFailed   InstalledReason = rukpakv1alpha1.ReasonCreateDynamicWatchFailed
+	InstallationSucceeded      InstalledReason = rukpakv1alpha1.ReasonInstallationSucceeded
+	ReadOnlyMode               InstalledReason = rukpakv1alpha1.ReasonReadOnlyMode
+	DryRunMode                 InstalledReason = rukpakv1alpha1.ReasonDryRunMode
+	CRDsNotEstablished         InstalledReason = rukpakv1alpha1.ReasonCRDsNotEstablished
+	CRDUpgradeUnsafe           InstalledReason = rukpakv1alpha1.ReasonCRDUpgradeUnsafe
+	PolicyViolation            InstalledReason = rukpakv1alpha1.ReasonPolicyViolation
+	PolicyCheckFailed          InstalledReason = rukpakv1alpha1.ReasonPolicyCheckFailed
+	WaveInProgress             InstalledReason = rukpakv1alpha1.ReasonWaveInProgress
+	HookInProgress             InstalledReason = rukpakv1alpha1.ReasonHookInProgress
+	HookFailed                 InstalledReason = rukpakv1alpha1.ReasonHookFailed
+	ObjectConflict             InstalledReason = rukpakv1alpha1.ReasonObjectConflict
+)
+
+// BundleNotUnpacked returns the reason of the Installed condition of a
+// BundleInstance whose Bundle is not unpacked yet and is in the given phase,
+// such as BundleUnpackPending or BundleUnpackFailing.
+func BundleNotUnpacked(phase string) InstalledReason {
+	if phase == rukpakv1alpha1.PhaseUnpacking {
+		return "BundleUnpackRunning"
+	}
+	return InstalledReason("BundleUnpack" + phase)
+}
+
+// RolledBackReason is a reason of the RolledBack condition of a
+// BundleInstance.
+type RolledBackReason string
+
+const (
+	RollbackSucceeded RolledBackReason = rukpakv1alpha1.ReasonRollbackSucceeded
+	RollbackFailed    RolledBackReason = rukpakv1alpha1.ReasonRollbackFailed
+)
+
+// HealthyReason is a reason of the Healthy condition of a BundleInstance.
+type HealthyReason string
+
+const (
+	Healthy           HealthyReason = rukpakv1alpha1.ReasonHealthy
+	Unhealthy         HealthyReason = rukpakv1alpha1.ReasonUnhealthy
+	HealthCheckFailed HealthyReason = rukpakv1alpha1.ReasonHealthCheckFailed
+)
+
+// PivotingReason is a reason of the Pivoting condition of a BundleInstance.
+type PivotingReason string
+
+const (
+	PivotInProgress PivotingReason = rukpakv1alpha1.ReasonPivotInProgress
+	PivotFailed     PivotingReason = rukpakv1alpha1.ReasonPivotFailed
+	PivotSucceeded  PivotingReason = rukpakv1alpha1.ReasonPivotSucceeded
+)
+
+// PreflightSucceededReason is a reason of the PreflightSucceeded condition of
+// a BundleInstance.
+type PreflightSucceededReason string
+
+const (
+	PermissionsGranted   PreflightSucceededReason = rukpakv1alpha1.ReasonPermissionsGranted
+	MissingPermissions   PreflightSucceededReason = rukpakv1alpha1.ReasonMissingPermissions
+	PreflightCheckFailed PreflightSucceededReason = rukpakv1alpha1.ReasonPreflightCheckFailed
+)
+
+// UninstallingReason is a reason of the Uninstalling condition of a
+// BundleInstance.
+type UninstallingReason string
+
+const (
+	UninstallInProgress UninstallingReason = rukpakv1alpha1.ReasonUninstallInProgress
+	UninstallFailed     UninstallingReason = rukpakv1alpha1.ReasonUninstallFailed
+)
+
+// DriftedReason is a reason of the Drifted condition of a BundleInstance.
+type DriftedReason string
+
+const (
+	DriftDetected    DriftedReason = rukpakv1alpha1.ReasonDriftDetected
+	NoDrift          DriftedReason = rukpakv1alpha1.ReasonNoDrift
+	DriftCheckFailed DriftedReason = rukpakv1alpha1.ReasonDriftCheckFailed
+)
+
+// ReadyReason is a reason of the Ready condition of a Bundle or
+// BundleInstance.
+type ReadyReason string
+
+const (
+	Ready    ReadyReason = rukpakv1alpha1.ReasonReady
+	NotReady ReadyReason = rukpakv1alpha1.ReasonNotReady
+)
+
+// Builder builds the conditions of an object, with its generation as their
+// observed generation.
+type Builder struct {
+	generation int64
+}
+
+// For returns a Builder of the conditions of obj.
+func For(obj metav1.Object) Builder {
+	return Builder{generation: obj.GetGeneration()}
+}
+
+func (b Builder) condition(conditionType string, status metav1.ConditionStatus, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: b.generation,
+	}
+}
+
+// Unpacked returns the Unpacked condition of a Bundle.
+func (b Builder) Unpacked(status metav1.ConditionStatus, reason UnpackedReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypeUnpacked, status, string(reason), message)
+}
+
+// HasValidBundle returns the HasValidBundle condition of a BundleInstance.
+func (b Builder) HasValidBundle(status metav1.ConditionStatus, reason HasValidBundleReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypeHasValidBundle, status, string(reason), message)
+}
+
+// InvalidBundleContent returns the InvalidBundleContent condition of a
+// BundleInstance.
+func (b Builder) InvalidBundleContent(status metav1.ConditionStatus, reason InvalidBundleContentReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypeInvalidBundleContent, status, string(reason), message)
+}
+
+// Installed returns the Installed condition of a BundleInstance.
+func (b Builder) Installed(status metav1.ConditionStatus, reason InstalledReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypeInstalled, status, string(reason), message)
+}
+
+// RolledBack returns the RolledBack condition of a BundleInstance.
+func (b Builder) RolledBack(status metav1.ConditionStatus, reason RolledBackReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypeRolledBack, status, string(reason), message)
+}
+
+// Healthy returns the Healthy condition of a BundleInstance.
+func (b Builder) Healthy(status metav1.ConditionStatus, reason HealthyReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypeHealthy, status, string(reason), message)
+}
+
+// Pivoting returns the Pivoting condition of a BundleInstance.
+func (b Builder) Pivoting(status metav1.ConditionStatus, reason PivotingReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypePivoting, status, string(reason), message)
+}
+
+// PreflightSucceeded returns the PreflightSucceeded condition of a
+// BundleInstance.
+func (b Builder) PreflightSucceeded(status metav1.ConditionStatus, reason PreflightSucceededReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypePreflightSucceeded, status, string(reason), message)
+}
+
+// Uninstalling returns the Uninstalling condition of a BundleInstance.
+func (b Builder) Uninstalling(status metav1.ConditionStatus, reason UninstallingReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypeUninstalling, status, string(reason), message)
+}
+
+// Drifted returns the Drifted condition of a BundleInstance.
+func (b Builder) Drifted(status metav1.ConditionStatus, reason DriftedReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypeDrifted, status, string(reason), message)
+}
+
+// Ready returns the Ready condition of a Bundle or BundleInstance.
+func (b Builder) Ready(status metav1.ConditionStatus, reason ReadyReason, message string) metav1.Condition {
+	return b.condition(rukpakv1alpha1.TypeReady, status, string(reason), message)
+}
@@ -0,0 +1,40 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func TestBuilder(t *testing.T) {
+	bi := &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 3}}
+	require.Equal(t, metav1.Condition{
+		Type:               rukpakv1alpha1.TypeInstalled,
+		Status:             metav1.ConditionTrue,
+		Reason:             rukpakv1alpha1.ReasonInstallationSucceeded,
+		Message:            "installed",
+		ObservedGeneration: 3,
+	}, For(bi).Installed(metav1.ConditionTrue, InstallationSucceeded, "installed"))
+
+	b := &rukpakv1alpha1.Bundle{ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 1}}
+	require.Equal(t, metav1.Condition{
+		Type:               rukpakv1alpha1.TypeUnpacked,
+		Status:             metav1.ConditionFalse,
+		Reason:             rukpakv1alpha1.ReasonUnpackFailed,
+		Message:            "failed",
+		ObservedGeneration: 1,
+	}, For(b).Unpacked(metav1.ConditionFalse, UnpackFailed, "failed"))
+}
+
+func TestBundleNotUnpacked(t *testing.T) {
+	for phase, expected := range map[string]InstalledReason{
+		rukpakv1alpha1.PhasePending:   "BundleUnpackPending",
+		rukpakv1alpha1.PhaseUnpacking: "BundleUnpackRunning",
+		rukpakv1alpha1.PhaseFailing:   "BundleUnpackFailing",
+	} {
+		require.Equal(t, expected, BundleNotUnpacked(phase), phase)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+// generatorLabel labels every resource created by the generator, so that they
+// can be cleaned up.
+const generatorLabel = "core.rukpak.io/scale-test"
+
+type generator struct {
+	client client.Client
+	options
+}
+
+func (g *generator) instanceName(i int) string {
+	return fmt.Sprintf("%s-%d", g.prefix, i)
+}
+
+func (g *generator) bundleName(i, round int) string {
+	return fmt.Sprintf("%s-%d-r%d", g.prefix, i, round)
+}
+
+func (g *generator) labels() map[string]string {
+	return map[string]string{generatorLabel: g.prefix}
+}
+
+func (g *generator) ensureTargetNamespace(ctx context.Context) error {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: g.targetNamespace, Labels: g.labels()}}
+	if err := g.client.Create(ctx, ns); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create target namespace: %w", err)
+	}
+	return nil
+}
+
+// apply creates the Bundles of round, and creates or pivots every
+// BundleInstance to them.
+func (g *generator) apply(ctx context.Context, round int) error {
+	for i := 0; i < g.count; i++ {
+		name := g.bundleName(i, round)
+		cm, err := g.manifestsConfigMap(i, round)
+		if err != nil {
+			return err
+		}
+		if err := g.client.Create(ctx, cm); err != nil {
+			return fmt.Errorf("create configmap %q: %w", cm.Name, err)
+		}
+		bundle := &rukpakv1alpha1.Bundle{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: g.labels()},
+			Spec: rukpakv1alpha1.BundleSpec{
+				ProvisionerClassName: g.provisionerClassName,
+				Source: rukpakv1alpha1.BundleSource{
+					Type: rukpakv1alpha1.SourceTypeLocal,
+					Local: &rukpakv1alpha1.LocalSource{
+						ConfigMap: &rukpakv1alpha1.ConfigMapSource{Name: cm.Name, Namespace: cm.Namespace},
+					},
+				},
+			},
+		}
+		if err := g.client.Create(ctx, bundle); err != nil {
+			return fmt.Errorf("create bundle %q: %w", name, err)
+		}
+
+		bi := &rukpakv1alpha1.BundleInstance{ObjectMeta: metav1.ObjectMeta{Name: g.instanceName(i)}}
+		if _, err := controllerutil.CreateOrPatch(ctx, g.client, bi, func() error {
+			bi.Labels = g.labels()
+			bi.Spec.ProvisionerClassName = g.provisionerClassName
+			bi.Spec.BundleName = name
+			bi.Spec.TargetNamespace = g.targetNamespace
+			return nil
+		}); err != nil {
+			return fmt.Errorf("apply bundleinstance %q: %w", bi.Name, err)
+		}
+	}
+	return nil
+}
+
+// manifestsConfigMap returns the ConfigMap with the manifests of the bundle of
+// the i-th BundleInstance in round: the configured number of ConfigMaps whose
+// data is padded to the configured size, and changes with every round.
+func (g *generator) manifestsConfigMap(i, round int) (*corev1.ConfigMap, error) {
+	immutable := true
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: g.bundleName(i, round), Namespace: g.namespace, Labels: g.labels()},
+		Immutable:  &immutable,
+		Data:       map[string]string{},
+	}
+	padding := strings.Repeat("x", g.objectSize)
+	for j := 0; j < g.objects; j++ {
+		obj := &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", g.instanceName(i), j)},
+			Data:       map[string]string{"round": fmt.Sprint(round), "padding": padding},
+		}
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		cm.Data[fmt.Sprintf("object-%d.yaml", j)] = string(data)
+	}
+	return cm, nil
+}
+
+// waitForInstalled waits until every BundleInstance has installed the Bundle
+// of round.
+func (g *generator) waitForInstalled(ctx context.Context, round int) error {
+	return wait.PollImmediate(time.Second, g.timeout, func() (bool, error) {
+		list := &rukpakv1alpha1.BundleInstanceList{}
+		if err := g.client.List(ctx, list, client.MatchingLabels(g.labels())); err != nil {
+			return false, err
+		}
+		installed := 0
+		for _, bi := range list.Items {
+			i := meta.FindStatusCondition(bi.Status.Conditions, rukpakv1alpha1.TypeInstalled)
+			if i != nil && i.Status == metav1.ConditionTrue && bi.Status.InstalledBundleName == bi.Spec.BundleName &&
+				strings.HasSuffix(bi.Spec.BundleName, fmt.Sprintf("-r%d", round)) {
+				installed++
+			}
+		}
+		return installed == g.count, nil
+	})
+}
+
+// cleanup deletes the BundleInstances, Bundles, ConfigMaps and target
+// namespace created by the generator.
+func (g *generator) cleanup(ctx context.Context) error {
+	labels := client.MatchingLabels(g.labels())
+	if err := g.client.DeleteAllOf(ctx, &rukpakv1alpha1.BundleInstance{}, labels); err != nil {
+		return fmt.Errorf("delete bundleinstances: %w", err)
+	}
+	if err := g.client.DeleteAllOf(ctx, &rukpakv1alpha1.Bundle{}, labels); err != nil {
+		return fmt.Errorf("delete bundles: %w", err)
+	}
+	if err := g.client.DeleteAllOf(ctx, &corev1.ConfigMap{}, labels, client.InNamespace(g.namespace)); err != nil {
+		return fmt.Errorf("delete configmaps: %w", err)
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: g.targetNamespace}}
+	if err := g.client.Delete(ctx, ns); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete target namespace: %w", err)
+	}
+	return nil
+}
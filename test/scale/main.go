@@ -0,0 +1,171 @@
+// Command scale generates synthetic load against a cluster running rukpak, and
+// reports how long the plain provisioner takes to converge and how many API
+// requests it makes while doing so.
+//
+// It creates a number of Bundles with local sources and a BundleInstance for
+// each of them, waits until every BundleInstance is installed, and then
+// pivots every BundleInstance to a new Bundle with changed content for each
+// round of churn.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha1 "github.com/operator-framework/rukpak/api/v1alpha1"
+)
+
+func main() {
+	o := options{}
+	flag.IntVar(&o.count, "count", 10, "number of Bundles and BundleInstances to create")
+	flag.IntVar(&o.objects, "objects", 10, "number of objects in each bundle")
+	flag.IntVar(&o.objectSize, "object-size", 1024, "size in bytes of the data of each object")
+	flag.IntVar(&o.churn, "churn", 1, "number of rounds in which every BundleInstance is pivoted to a new Bundle")
+	flag.StringVar(&o.prefix, "prefix", "scale", "prefix of the names of the created resources")
+	flag.StringVar(&o.namespace, "namespace", "rukpak-system", "namespace of the ConfigMaps that back the bundles")
+	flag.StringVar(&o.targetNamespace, "target-namespace", "rukpak-scale", "namespace that the objects of the bundles are installed into")
+	flag.StringVar(&o.provisionerClassName, "provisioner-class", "core.rukpak.io/plain", "provisioner class of the Bundles and BundleInstances")
+	flag.DurationVar(&o.timeout, "timeout", 10*time.Minute, "how long to wait for each round to converge")
+	flag.StringVar(&o.metricsURL, "metrics-url", "", "URL of the metrics endpoint of the provisioner, from which its API requests are counted")
+	flag.BoolVar(&o.cleanup, "cleanup", true, "delete the created resources once the run completes")
+	flag.Parse()
+
+	if err := run(context.Background(), o); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+type options struct {
+	count                int
+	objects              int
+	objectSize           int
+	churn                int
+	prefix               string
+	namespace            string
+	targetNamespace      string
+	provisionerClassName string
+	timeout              time.Duration
+	metricsURL           string
+	cleanup              bool
+}
+
+func run(ctx context.Context, o options) error {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	counter := &requestCounter{}
+	cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		counter.next = rt
+		return counter
+	})
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := rukpakv1alpha1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	cl, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	g := &generator{client: cl, options: o}
+	if o.cleanup {
+		defer func() {
+			if err := g.cleanup(context.Background()); err != nil {
+				fmt.Fprintf(os.Stderr, "cleanup: %v\n", err)
+			}
+		}()
+	}
+	if err := g.ensureTargetNamespace(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("%-8s %12s %16s %s\n", "ROUND", "CONVERGENCE", "HARNESS-REQUESTS", "PROVISIONER-REQUESTS")
+	for round := 0; round <= o.churn; round++ {
+		before, err := scrapeRequests(ctx, o.metricsURL)
+		if err != nil {
+			return err
+		}
+		counter.reset()
+		start := time.Now()
+		if err := g.apply(ctx, round); err != nil {
+			return fmt.Errorf("round %d: %w", round, err)
+		}
+		if err := g.waitForInstalled(ctx, round); err != nil {
+			return fmt.Errorf("round %d: %w", round, err)
+		}
+		elapsed := time.Since(start)
+		after, err := scrapeRequests(ctx, o.metricsURL)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-8d %12s %16d %s\n", round, elapsed.Round(time.Millisecond), counter.total(), formatRequests(after.sub(before)))
+	}
+	return nil
+}
+
+// requestCounter counts the API requests of the harness itself by method.
+type requestCounter struct {
+	next http.RoundTripper
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *requestCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	if c.counts == nil {
+		c.counts = map[string]int{}
+	}
+	c.counts[req.Method]++
+	c.mu.Unlock()
+	return c.next.RoundTrip(req)
+}
+
+func (c *requestCounter) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts = nil
+}
+
+func (c *requestCounter) total() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, count := range c.counts {
+		n += count
+	}
+	return n
+}
+
+func formatRequests(counts requestCounts) string {
+	if counts == nil {
+		return "-"
+	}
+	methods := make([]string, 0, len(counts))
+	for method := range counts {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	parts := make([]string, 0, len(methods))
+	for _, method := range methods {
+		parts = append(parts, fmt.Sprintf("%s=%.0f", method, counts[method]))
+	}
+	return strings.Join(parts, " ")
+}
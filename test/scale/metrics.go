@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// requestMetric is the client-go metric of the API requests of a controller,
+// which controller-runtime exposes on the metrics endpoint of a manager.
+const requestMetric = "rest_client_requests_total"
+
+// requestCounts are API request counts by method.
+type requestCounts map[string]float64
+
+func (c requestCounts) sub(other requestCounts) requestCounts {
+	if c == nil {
+		return nil
+	}
+	d := requestCounts{}
+	for method, n := range c {
+		d[method] = n - other[method]
+	}
+	return d
+}
+
+// scrapeRequests returns the API request counts of the metrics endpoint at
+// url. It returns nil counts when url is empty.
+func scrapeRequests(ctx context.Context, url string) (requestCounts, error) {
+	if url == "" {
+		return nil, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("scrape metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scrape metrics: %s", resp.Status)
+	}
+
+	counts := requestCounts{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		method, n, ok := parseRequestSample(scanner.Text())
+		if ok {
+			counts[method] += n
+		}
+	}
+	return counts, scanner.Err()
+}
+
+// parseRequestSample parses a sample of the request metric in the Prometheus
+// text format, such as:
+//
+//	rest_client_requests_total{code="200",host="10.96.0.1:443",method="GET"} 42
+func parseRequestSample(line string) (string, float64, bool) {
+	if !strings.HasPrefix(line, requestMetric+"{") {
+		return "", 0, false
+	}
+	end := strings.LastIndex(line, "}")
+	if end < 0 {
+		return "", 0, false
+	}
+	method := ""
+	for _, label := range strings.Split(line[len(requestMetric)+1:end], ",") {
+		if strings.HasPrefix(label, `method="`) {
+			method = strings.Trim(strings.TrimPrefix(label, "method="), `"`)
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(line[end+1:]), 64)
+	if err != nil || method == "" {
+		return "", 0, false
+	}
+	return method, n, true
+}